@@ -0,0 +1,108 @@
+package adminHandler
+
+import (
+	"net/http"
+	"test-va/cmd/middlewares/auditMiddleware"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/adminEntity"
+	"test-va/internals/entity/loggerEntity"
+	"test-va/internals/service/adminService"
+
+	"github.com/gin-gonic/gin"
+)
+
+type adminHandler struct {
+	srv adminService.AdminSrv
+}
+
+func NewAdminHandler(srv adminService.AdminSrv) *adminHandler {
+	return &adminHandler{srv: srv}
+}
+
+func (a *adminHandler) SearchUsers(c *gin.Context) {
+	var req adminEntity.SearchUsersReq
+	err := c.ShouldBindQuery(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "error decoding query", err, nil))
+		return
+	}
+
+	res, errRes := a.srv.SearchUsers(&req)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to search users", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Users returned successfully", res, nil))
+}
+
+func (a *adminHandler) GetAccount(c *gin.Context) {
+	res, errRes := a.srv.GetAccount(c.Param("user_id"))
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to get the account", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Account returned successfully", res, nil))
+}
+
+func (a *adminHandler) SuspendUser(c *gin.Context) {
+	userId := c.Param("user_id")
+	auditMiddleware.Target(c, loggerEntity.TargetUser, userId)
+
+	var req adminEntity.SuspendReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err, nil))
+		return
+	}
+
+	auditMiddleware.Detail(c, req.Reason)
+	errRes := a.srv.SuspendUser(userId, c.GetString("userId"), &req)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to suspend the account", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Account suspended", nil, nil))
+}
+
+func (a *adminHandler) ReactivateUser(c *gin.Context) {
+	userId := c.Param("user_id")
+	auditMiddleware.Target(c, loggerEntity.TargetUser, userId)
+
+	errRes := a.srv.ReactivateUser(userId, c.GetString("userId"))
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to reactivate the account", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Account reactivated", nil, nil))
+}
+
+func (a *adminHandler) ForceLogout(c *gin.Context) {
+	userId := c.Param("user_id")
+	auditMiddleware.Target(c, loggerEntity.TargetUser, userId)
+
+	errRes := a.srv.ForceLogout(userId)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to log the user out", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "User logged out", nil, nil))
+}
+
+func errorStatus(errRes *ResponseEntity.ServiceError) int {
+	switch errRes.Description {
+	case "BadInput Request":
+		return http.StatusBadRequest
+	case adminService.ErrNotFound:
+		return http.StatusNotFound
+	case adminService.ErrConflict:
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,49 @@
+package analyticsHandler
+
+import (
+	"log"
+	"net/http"
+
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/analyticsEntity"
+	"test-va/internals/service/analyticsService"
+
+	"github.com/gin-gonic/gin"
+)
+
+type analyticsHandler struct {
+	srv analyticsService.AnalyticsService
+}
+
+func NewAnalyticsHandler(srv analyticsService.AnalyticsService) *analyticsHandler {
+	return &analyticsHandler{srv: srv}
+}
+
+func (a *analyticsHandler) GetAnalytics(c *gin.Context) {
+	var req analyticsEntity.AnalyticsReq
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+	err := c.ShouldBindQuery(&req)
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "error decoding query", err, nil))
+		return
+	}
+	req.UserId = userId
+
+	res, errRes := a.srv.GetAnalytics(&req)
+	if errRes != nil {
+		status := http.StatusInternalServerError
+		if errRes.Description == "BadInput Request" {
+			status = http.StatusBadRequest
+		}
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "error getting analytics", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Analytics Retrieved Successfully", res, nil))
+}
@@ -0,0 +1,154 @@
+package attachmentHandler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/attachmentEntity"
+	"test-va/internals/service/attachmentService"
+	"test-va/internals/service/storageService"
+	"test-va/internals/service/storageService/localStorage"
+
+	"github.com/gin-gonic/gin"
+)
+
+type attachmentHandler struct {
+	srv     attachmentService.AttachmentService
+	storage localStorage.LocalStorage
+}
+
+// NewAttachmentHandler takes the local storage backend when files are served by the api itself, nil otherwise.
+func NewAttachmentHandler(srv attachmentService.AttachmentService, storage localStorage.LocalStorage) *attachmentHandler {
+	return &attachmentHandler{srv: srv, storage: storage}
+}
+
+func (a *attachmentHandler) CreateUpload(c *gin.Context) {
+	var req attachmentEntity.CreateUploadReq
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "error decoding into struct", err, nil))
+		return
+	}
+	req.UploaderId = userId
+
+	res, errRes := a.srv.CreateUpload(&req)
+	if errRes != nil {
+		a.abortWithServiceError(c, "error creating upload", errRes)
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Upload Created Successfully", res, nil))
+}
+
+func (a *attachmentHandler) CompleteUpload(c *gin.Context) {
+	res, errRes := a.srv.CompleteUpload(c.GetString("userId"), c.Param("attachmentId"))
+	if errRes != nil {
+		a.abortWithServiceError(c, "error completing upload", errRes)
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Upload Completed Successfully", res, nil))
+}
+
+func (a *attachmentHandler) GetDownloadUrl(c *gin.Context) {
+	res, errRes := a.srv.GetDownloadUrl(c.GetString("userId"), c.Param("attachmentId"))
+	if errRes != nil {
+		a.abortWithServiceError(c, "error getting attachment", errRes)
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Attachment Retrieved Successfully", res, nil))
+}
+
+func (a *attachmentHandler) ListByTask(c *gin.Context) {
+	res, errRes := a.srv.ListByTask(c.GetString("userId"), c.Param("taskId"))
+	if errRes != nil {
+		a.abortWithServiceError(c, "error getting attachments", errRes)
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Attachments Retrieved Successfully", res, nil))
+}
+
+func (a *attachmentHandler) ListByComment(c *gin.Context) {
+	res, errRes := a.srv.ListByComment(c.GetString("userId"), c.Param("commentId"))
+	if errRes != nil {
+		a.abortWithServiceError(c, "error getting attachments", errRes)
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Attachments Retrieved Successfully", res, nil))
+}
+
+func (a *attachmentHandler) DeleteAttachment(c *gin.Context) {
+	res, errRes := a.srv.DeleteAttachment(c.GetString("userId"), c.Param("attachmentId"))
+	if errRes != nil {
+		a.abortWithServiceError(c, "error deleting attachment", errRes)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// PutFile receives uploads made against a url signed by the local storage backend.
+func (a *attachmentHandler) PutFile(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, attachmentService.MaxAttachmentSize+1)
+
+	err := a.storage.Save(key, c.Request.URL.Query(), c.ContentType(), body)
+	if err != nil {
+		log.Println(err)
+		status := http.StatusBadRequest
+		if errors.Is(err, localStorage.ErrInvalidSignature) {
+			status = http.StatusForbidden
+		}
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "error uploading file", err.Error(), nil))
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// GetFile serves downloads made against a url signed by the local storage backend.
+func (a *attachmentHandler) GetFile(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	err := a.storage.Verify(http.MethodGet, key, c.Request.URL.Query())
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusForbidden,
+			ResponseEntity.BuildErrorResponse(http.StatusForbidden, "error downloading file", err.Error(), nil))
+		return
+	}
+
+	file, contentType, err := a.storage.Open(key)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storageService.ErrObjectNotFound) {
+			status = http.StatusNotFound
+		}
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "error downloading file", err.Error(), nil))
+		return
+	}
+	defer file.Close()
+
+	c.Header("Content-Type", contentType)
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, file)
+}
+
+func (a *attachmentHandler) abortWithServiceError(c *gin.Context, message string, errRes *ResponseEntity.ServiceError) {
+	status := http.StatusInternalServerError
+	switch errRes.Description {
+	case "BadInput Request":
+		status = http.StatusBadRequest
+	case attachmentService.ErrForbidden:
+		status = http.StatusForbidden
+	case attachmentService.ErrNotFound:
+		status = http.StatusNotFound
+	}
+	c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, message, errRes, nil))
+}
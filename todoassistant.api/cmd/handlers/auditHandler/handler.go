@@ -0,0 +1,67 @@
+package auditHandler
+
+import (
+	"fmt"
+	"net/http"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/loggerEntity"
+	"test-va/internals/service/auditService"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type auditHandler struct {
+	srv auditService.AuditSrv
+}
+
+func NewAuditHandler(srv auditService.AuditSrv) *auditHandler {
+	return &auditHandler{srv: srv}
+}
+
+func (a *auditHandler) GetLogs(c *gin.Context) {
+	var filter loggerEntity.AuditFilter
+	err := c.ShouldBindQuery(&filter)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "error decoding query", err, nil))
+		return
+	}
+
+	res, errRes := a.srv.GetLogs(&filter)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to get the audit log", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Audit log returned successfully", res, nil))
+}
+
+func (a *auditHandler) ExportLogs(c *gin.Context) {
+	var filter loggerEntity.AuditFilter
+	err := c.ShouldBindQuery(&filter)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "error decoding query", err, nil))
+		return
+	}
+
+	// the csv is streamed, the service only writes once it read the first logs
+	name := fmt.Sprintf("audit-log-%s.csv", time.Now().UTC().Format("2006-01-02"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	errRes := a.srv.ExportLogs(&filter, c.Writer)
+	if errRes != nil {
+		c.Writer.Header().Del("Content-Disposition")
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to export the audit log", errRes, nil))
+		return
+	}
+}
+
+func errorStatus(errRes *ResponseEntity.ServiceError) int {
+	if errRes.Description == "BadInput Request" {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,44 @@
+package loginAlertHandler
+
+import (
+	"net/http"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/userEntity"
+	"test-va/internals/service/loginAlertService"
+
+	"github.com/gin-gonic/gin"
+)
+
+type loginAlertHandler struct {
+	srv loginAlertService.LoginAlertSrv
+}
+
+func NewLoginAlertHandler(srv loginAlertService.LoginAlertSrv) *loginAlertHandler {
+	return &loginAlertHandler{srv: srv}
+}
+
+func (l *loginAlertHandler) Disown(c *gin.Context) {
+	var req userEntity.DisownLoginReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err, nil))
+		return
+	}
+
+	errRes := l.srv.Disown(&req)
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to log out the device", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK,
+		"The device was logged out, reset your password to log in again", nil, nil))
+}
+
+func errorStatus(errRes *ResponseEntity.ServiceError) int {
+	if errRes.Description == "BadInput Request" {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,49 @@
+package loginGuardHandler
+
+import (
+	"net/http"
+	"test-va/cmd/middlewares/auditMiddleware"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/loggerEntity"
+	"test-va/internals/entity/loginAttemptEntity"
+	"test-va/internals/service/loginGuardService"
+
+	"github.com/gin-gonic/gin"
+)
+
+type loginGuardHandler struct {
+	srv loginGuardService.LoginGuardSrv
+}
+
+func NewLoginGuardHandler(srv loginGuardService.LoginGuardSrv) *loginGuardHandler {
+	return &loginGuardHandler{srv: srv}
+}
+
+func (l *loginGuardHandler) Unlock(c *gin.Context) {
+	var req loginAttemptEntity.UnlockReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err, nil))
+		return
+	}
+
+	auditMiddleware.Target(c, loggerEntity.TargetEmail, req.Email)
+	errRes := l.srv.Unlock(&req)
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to unlock the account", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Account unlocked", nil, nil))
+}
+
+func errorStatus(errRes *ResponseEntity.ServiceError) int {
+	switch errRes.Description {
+	case "BadInput Request":
+		return http.StatusBadRequest
+	case loginGuardService.ErrNotFound:
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
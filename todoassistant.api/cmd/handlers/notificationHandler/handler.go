@@ -5,6 +5,7 @@ import (
 	"test-va/internals/entity/ResponseEntity"
 	"test-va/internals/entity/notificationEntity"
 	"test-va/internals/service/notificationService"
+	tokenservice "test-va/internals/service/tokenService"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,6 +28,9 @@ func (n *notificationHandler) RegisterForNotifications(c *gin.Context) {
 		return
 	}
 
+	if token, ok := c.Get("token"); ok {
+		req.SessionId = token.(*tokenservice.Token).Family
+	}
 	errorRes := n.srv.RegisterForNotifications(&req)
 	if errorRes != nil {
 		c.AbortWithStatusJSON(http.StatusUnauthorized,
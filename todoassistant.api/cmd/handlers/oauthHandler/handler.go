@@ -0,0 +1,167 @@
+package oauthHandler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/oauthEntity"
+	"test-va/internals/service/oauthService"
+
+	"github.com/gin-gonic/gin"
+)
+
+type oauthHandler struct {
+	srv oauthService.OAuthSrv
+}
+
+func NewOAuthHandler(srv oauthService.OAuthSrv) *oauthHandler {
+	return &oauthHandler{srv: srv}
+}
+
+func (o *oauthHandler) RegisterClient(c *gin.Context) {
+	var req oauthEntity.CreateClientReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err.Error(), nil))
+		return
+	}
+
+	res, errRes := o.srv.RegisterClient(c.GetString("userId"), &req)
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to register the app", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusCreated, ResponseEntity.BuildSuccessResponse(http.StatusCreated, "App registered", res, nil))
+}
+
+func (o *oauthHandler) GetClients(c *gin.Context) {
+	res, errRes := o.srv.GetClients(c.GetString("userId"))
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to get your apps", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Apps retrieved successfully", res, nil))
+}
+
+func (o *oauthHandler) DeleteClient(c *gin.Context) {
+	errRes := o.srv.DeleteClient(c.GetString("userId"), c.Param("clientId"))
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to delete the app", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "App deleted", nil, nil))
+}
+
+func (o *oauthHandler) GetConsent(c *gin.Context) {
+	var req oauthEntity.AuthorizeReq
+	err := c.ShouldBindQuery(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err.Error(), nil))
+		return
+	}
+
+	res, errRes := o.srv.GetConsent(c.GetString("userId"), &req)
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Invalid authorization request", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Authorization request", res, nil))
+}
+
+func (o *oauthHandler) Authorize(c *gin.Context) {
+	var req oauthEntity.ApproveReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err.Error(), nil))
+		return
+	}
+
+	res, errRes := o.srv.Authorize(c.GetString("userId"), &req)
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Invalid authorization request", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Authorization recorded", res, nil))
+}
+
+// Token and Revoke answer apps, so they are shaped as RFC 6749 asks rather than as the rest of the api.
+
+func (o *oauthHandler) Token(c *gin.Context) {
+	var req oauthEntity.TokenReq
+	err := c.ShouldBind(&req)
+	if err != nil {
+		oauthError(c, ResponseEntity.NewCustomServiceError(oauthEntity.ErrInvalidRequest, err))
+		return
+	}
+	req.ClientId, req.ClientSecret = clientCredentials(c, req.ClientId, req.ClientSecret)
+
+	res, errRes := o.srv.Token(&req)
+	if errRes != nil {
+		oauthError(c, errRes)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, res)
+}
+
+func (o *oauthHandler) Revoke(c *gin.Context) {
+	var req oauthEntity.RevokeReq
+	err := c.ShouldBind(&req)
+	if err != nil {
+		oauthError(c, ResponseEntity.NewCustomServiceError(oauthEntity.ErrInvalidRequest, err))
+		return
+	}
+	req.ClientId, req.ClientSecret = clientCredentials(c, req.ClientId, req.ClientSecret)
+
+	errRes := o.srv.Revoke(&req)
+	if errRes != nil {
+		oauthError(c, errRes)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// clientCredentials prefers the credentials of basic auth to those in the form.
+func clientCredentials(c *gin.Context, clientId, clientSecret string) (string, string) {
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		return id, secret
+	}
+	return clientId, clientSecret
+}
+
+func oauthError(c *gin.Context, errRes *ResponseEntity.ServiceError) {
+	status := http.StatusBadRequest
+	switch errRes.Description {
+	case oauthEntity.ErrInvalidClient:
+		status = http.StatusUnauthorized
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	case "Internal Service Error":
+		log.Println(errRes.Error)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, oauthEntity.ErrorRes{Error: "server_error"})
+		return
+	}
+	res := oauthEntity.ErrorRes{Error: errRes.Description}
+	if errRes.Error != nil {
+		res.ErrorDescription = fmt.Sprint(errRes.Error)
+	}
+	c.AbortWithStatusJSON(status, res)
+}
+
+func errorStatus(errRes *ResponseEntity.ServiceError) int {
+	switch errRes.Description {
+	case "BadInput Request", oauthEntity.ErrInvalidRequest, oauthEntity.ErrInvalidClient, oauthEntity.ErrInvalidScope:
+		return http.StatusBadRequest
+	case oauthService.ErrNotFound:
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,139 @@
+package passkeyHandler
+
+import (
+	"net/http"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/passkeyEntity"
+	"test-va/internals/service/passkeyService"
+	tokenservice "test-va/internals/service/tokenService"
+
+	"github.com/gin-gonic/gin"
+)
+
+type passkeyHandler struct {
+	srv passkeyService.PasskeySrv
+}
+
+func NewPasskeyHandler(srv passkeyService.PasskeySrv) *passkeyHandler {
+	return &passkeyHandler{srv: srv}
+}
+
+func (p *passkeyHandler) BeginRegistration(c *gin.Context) {
+	token, ok := tokenFromContext(c)
+	if !ok {
+		return
+	}
+
+	options, errRes := p.srv.BeginRegistration(accountType(token), token.Id, token.Email)
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to register a passkey", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Pass the options to navigator.credentials.create", options, nil))
+}
+
+func (p *passkeyHandler) FinishRegistration(c *gin.Context) {
+	token, ok := tokenFromContext(c)
+	if !ok {
+		return
+	}
+
+	var req passkeyEntity.RegistrationReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err, nil))
+		return
+	}
+
+	passkey, errRes := p.srv.FinishRegistration(accountType(token), token.Id, &req)
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to register a passkey", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Passkey registered", passkey, nil))
+}
+
+func (p *passkeyHandler) GetPasskeys(c *gin.Context) {
+	token, ok := tokenFromContext(c)
+	if !ok {
+		return
+	}
+
+	passkeys, errRes := p.srv.GetPasskeys(accountType(token), token.Id)
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to get passkeys", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Passkeys retrieved successfully", passkeys, nil))
+}
+
+func (p *passkeyHandler) RenamePasskey(c *gin.Context) {
+	token, ok := tokenFromContext(c)
+	if !ok {
+		return
+	}
+
+	var req passkeyEntity.RenameReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err, nil))
+		return
+	}
+
+	errRes := p.srv.RenamePasskey(accountType(token), token.Id, c.Param("credentialId"), req.Name)
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to rename passkey", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Passkey renamed", nil, nil))
+}
+
+func (p *passkeyHandler) DeletePasskey(c *gin.Context) {
+	token, ok := tokenFromContext(c)
+	if !ok {
+		return
+	}
+
+	errRes := p.srv.DeletePasskey(accountType(token), token.Id, c.Param("credentialId"))
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to remove passkey", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Passkey removed", nil, nil))
+}
+
+func tokenFromContext(c *gin.Context) (*tokenservice.Token, bool) {
+	token, ok := c.MustGet("token").(*tokenservice.Token)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+	}
+	return token, ok
+}
+
+// accountType tells users from VAs, whose tokens carry VA or MASTER.
+func accountType(token *tokenservice.Token) string {
+	if token.Status == "user" {
+		return passkeyEntity.AccountUser
+	}
+	return passkeyEntity.AccountVA
+}
+
+func errorStatus(errRes *ResponseEntity.ServiceError) int {
+	switch errRes.Description {
+	case "BadInput Request":
+		return http.StatusBadRequest
+	case passkeyService.ErrUnauthorized:
+		return http.StatusUnauthorized
+	case passkeyService.ErrNotFound:
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
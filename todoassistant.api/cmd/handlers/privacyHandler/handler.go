@@ -0,0 +1,74 @@
+package privacyHandler
+
+import (
+	"net/http"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/service/privacyService"
+
+	"github.com/gin-gonic/gin"
+)
+
+type privacyHandler struct {
+	srv privacyService.PrivacySrv
+}
+
+func NewPrivacyHandler(srv privacyService.PrivacySrv) *privacyHandler {
+	return &privacyHandler{srv: srv}
+}
+
+func (p *privacyHandler) RequestExport(c *gin.Context) {
+	res, errRes := p.srv.RequestExport(c.GetString("userId"))
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to export your data", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusAccepted, ResponseEntity.BuildSuccessResponse(http.StatusAccepted, "Export started", res, nil))
+}
+
+func (p *privacyHandler) GetExport(c *gin.Context) {
+	res, errRes := p.srv.GetExport(c.GetString("userId"), c.Param("requestId"))
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to get the export", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Export", res, nil))
+}
+
+// RequestErasure schedules the erasure of the user calling, of the user in the url when there is one.
+func (p *privacyHandler) RequestErasure(c *gin.Context) {
+	userId := c.Param("user_id")
+	if userId == "" {
+		userId = c.GetString("userId")
+	}
+
+	res, errRes := p.srv.RequestErasure(userId, c.GetString("userId"))
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to delete the account", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusAccepted, ResponseEntity.BuildSuccessResponse(http.StatusAccepted,
+		"The account will be deleted once the grace period is over", res, nil))
+}
+
+func (p *privacyHandler) CancelErasure(c *gin.Context) {
+	res, errRes := p.srv.CancelErasure(c.GetString("userId"))
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to cancel the deletion", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "The account will not be deleted", res, nil))
+}
+
+func errorStatus(errRes *ResponseEntity.ServiceError) int {
+	switch errRes.Description {
+	case "BadInput Request":
+		return http.StatusBadRequest
+	case privacyService.ErrNotFound:
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
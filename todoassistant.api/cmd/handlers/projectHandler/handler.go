@@ -3,6 +3,8 @@ package projectHandler
 import (
 	"log"
 	"net/http"
+	"test-va/cmd/middlewares/auditMiddleware"
+	"test-va/internals/entity/loggerEntity"
 
 	"test-va/internals/entity/ResponseEntity"
 	"test-va/internals/entity/projectEntity"
@@ -40,8 +42,9 @@ func (p *projectHandler) CreateProject(c *gin.Context) {
 
 	project, errRes := p.srv.PersistProject(&req)
 	if errRes != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "error creating Project", errRes, nil))
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status,
+			ResponseEntity.BuildErrorResponse(status, "error creating Project", errRes, nil))
 		return
 	}
 
@@ -58,18 +61,20 @@ func (p *projectHandler) GetAllUsersProjects(c *gin.Context) {
 			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "No userId found", nil, nil))
 		return
 	}
-	projects, errRes := p.srv.GetListOfUsersProjects(userId)
+	includeArchived := c.Query("archived") == "true"
+	projects, errRes := p.srv.GetListOfUsersProjects(userId, includeArchived, c.Query("view"))
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status,
+			ResponseEntity.BuildErrorResponse(status, "Failure To Find all users project", errRes, nil))
+		return
+	}
 	if projects == nil {
 		message := "user with id " + userId + " has no project"
 		c.AbortWithStatusJSON(http.StatusOK,
 			ResponseEntity.BuildSuccessResponse(http.StatusNoContent, message, projects, nil))
 		return
 	}
-	if errRes != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError, "Failure To Find all users project", errRes, nil))
-		return
-	}
 
 	c.JSON(http.StatusOK,
 		ResponseEntity.BuildSuccessResponse(http.StatusOK, "Users projects returned successfully", projects, nil))
@@ -125,12 +130,400 @@ func (p *projectHandler) DeleteProjectById(c *gin.Context) {
 			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Authentication Error, Invalid UserId", nil, nil))
 		return
 	}
-	_, errRes := p.srv.DeleteProjectByID(projectId)
+	_, errRes := p.srv.DeleteProjectByID(projectId, userId, c.Query("children"))
 	if errRes != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError, "Unable to delete project", errRes, nil))
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status,
+			ResponseEntity.BuildErrorResponse(status, "Unable to delete project", errRes, nil))
 		return
 	}
 	rd := ResponseEntity.BuildSuccessResponse(200, "Project deleted successfully", nil, nil)
 	c.JSON(http.StatusOK, rd)
 }
+
+func (p *projectHandler) MoveProject(c *gin.Context) {
+	var req projectEntity.MoveProjectReq
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+	err := c.ShouldBind(&req)
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "error decoding into struct", err, nil))
+		return
+	}
+	req.ProjectId = c.Params.ByName("projectId")
+	req.UserId = userId
+
+	res, errRes := p.srv.MoveProject(&req)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to move project", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (p *projectHandler) ArchiveProject(c *gin.Context) {
+	p.archiveProject(c, true)
+}
+
+func (p *projectHandler) UnarchiveProject(c *gin.Context) {
+	p.archiveProject(c, false)
+}
+
+func (p *projectHandler) archiveProject(c *gin.Context, archive bool) {
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+
+	res, errRes := p.srv.ArchiveProject(c.Params.ByName("projectId"), userId, archive)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to archive project", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (p *projectHandler) SaveAsTemplate(c *gin.Context) {
+	var req projectEntity.SaveTemplateReq
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+	// the body is optional
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBind(&req); err != nil {
+			log.Println(err)
+			c.AbortWithStatusJSON(http.StatusBadRequest,
+				ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "error decoding into struct", err, nil))
+			return
+		}
+	}
+	req.ProjectId = c.Params.ByName("projectId")
+	req.UserId = userId
+
+	template, errRes := p.srv.SaveAsTemplate(&req)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to save template", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Template saved successfully", template, nil))
+}
+
+func (p *projectHandler) GetTemplates(c *gin.Context) {
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+
+	templates, errRes := p.srv.GetTemplates(userId)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to get templates", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Templates returned successfully", templates, nil))
+}
+
+func (p *projectHandler) InstantiateTemplate(c *gin.Context) {
+	var req projectEntity.InstantiateTemplateReq
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+	err := c.ShouldBind(&req)
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "error decoding into struct", err, nil))
+		return
+	}
+	req.TemplateId = c.Params.ByName("templateId")
+	req.UserId = userId
+
+	project, errRes := p.srv.InstantiateTemplate(&req)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to create project from template", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Created Project Successfully", project, nil))
+}
+
+func (p *projectHandler) InviteMember(c *gin.Context) {
+	var req projectEntity.InviteMemberReq
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+	err := c.ShouldBind(&req)
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "error decoding into struct", err, nil))
+		return
+	}
+	req.ProjectId = c.Params.ByName("projectId")
+	req.UserId = userId
+
+	invitation, errRes := p.srv.InviteMember(&req)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to send invitation", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Invitation sent successfully", invitation, nil))
+}
+
+func (p *projectHandler) GetInvitations(c *gin.Context) {
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+
+	invitations, errRes := p.srv.GetInvitations(userId)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to get invitations", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Invitations returned successfully", invitations, nil))
+}
+
+func (p *projectHandler) AcceptInvitation(c *gin.Context) {
+	p.respondToInvitation(c, true)
+}
+
+func (p *projectHandler) DeclineInvitation(c *gin.Context) {
+	p.respondToInvitation(c, false)
+}
+
+func (p *projectHandler) respondToInvitation(c *gin.Context, accept bool) {
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+
+	res, errRes := p.srv.RespondToInvitation(c.Params.ByName("invitationId"), userId, accept)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to answer invitation", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (p *projectHandler) GetMembers(c *gin.Context) {
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+
+	members, errRes := p.srv.GetMembers(c.Params.ByName("projectId"), userId)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to get members", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Members returned successfully", members, nil))
+}
+
+func (p *projectHandler) UpdateMemberRole(c *gin.Context) {
+	var req projectEntity.UpdateMemberReq
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+	err := c.ShouldBind(&req)
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "error decoding into struct", err, nil))
+		return
+	}
+	req.ProjectId = c.Params.ByName("projectId")
+	req.MemberId = c.Params.ByName("memberId")
+	req.UserId = userId
+
+	auditMiddleware.Target(c, loggerEntity.TargetMember, req.MemberId)
+	auditMiddleware.Detail(c, req.Role)
+	res, errRes := p.srv.UpdateMemberRole(&req)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to update member", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (p *projectHandler) RemoveMember(c *gin.Context) {
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+
+	res, errRes := p.srv.RemoveMember(c.Params.ByName("projectId"), userId, c.Params.ByName("memberId"))
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to remove member", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (p *projectHandler) GetSections(c *gin.Context) {
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+
+	sections, errRes := p.srv.GetSections(c.Params.ByName("projectId"), userId)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to get sections", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Sections returned successfully", sections, nil))
+}
+
+func (p *projectHandler) CreateSection(c *gin.Context) {
+	var req projectEntity.CreateSectionReq
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+	err := c.ShouldBind(&req)
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "error decoding into struct", err, nil))
+		return
+	}
+	req.ProjectId = c.Params.ByName("projectId")
+	req.UserId = userId
+
+	section, errRes := p.srv.CreateSection(&req)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to create section", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Section created successfully", section, nil))
+}
+
+func (p *projectHandler) RenameSection(c *gin.Context) {
+	var req projectEntity.RenameSectionReq
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+	err := c.ShouldBind(&req)
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "error decoding into struct", err, nil))
+		return
+	}
+	req.ProjectId = c.Params.ByName("projectId")
+	req.SectionId = c.Params.ByName("sectionId")
+	req.UserId = userId
+
+	res, errRes := p.srv.RenameSection(&req)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to rename section", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (p *projectHandler) ReorderSections(c *gin.Context) {
+	var req projectEntity.ReorderSectionsReq
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+	err := c.ShouldBind(&req)
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "error decoding into struct", err, nil))
+		return
+	}
+	req.ProjectId = c.Params.ByName("projectId")
+	req.UserId = userId
+
+	sections, errRes := p.srv.ReorderSections(&req)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to reorder sections", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Sections reordered successfully", sections, nil))
+}
+
+func (p *projectHandler) DeleteSection(c *gin.Context) {
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+
+	res, errRes := p.srv.DeleteSection(c.Params.ByName("projectId"), c.Params.ByName("sectionId"), userId)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Unable to delete section", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// errorStatus maps a service error to the http status it is returned with
+func errorStatus(errRes *ResponseEntity.ServiceError) int {
+	switch errRes.Description {
+	case "BadInput Request":
+		return http.StatusBadRequest
+	case projectService.ErrForbidden:
+		return http.StatusForbidden
+	case projectService.ErrNotFound:
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
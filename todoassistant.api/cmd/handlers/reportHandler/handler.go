@@ -0,0 +1,54 @@
+package reportHandler
+
+import (
+	"log"
+	"net/http"
+
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/reportEntity"
+	"test-va/internals/service/reportService"
+
+	"github.com/gin-gonic/gin"
+)
+
+type reportHandler struct {
+	srv reportService.ReportService
+}
+
+func NewReportHandler(srv reportService.ReportService) *reportHandler {
+	return &reportHandler{srv: srv}
+}
+
+func (r *reportHandler) GetWeeklyReport(c *gin.Context) {
+	var req reportEntity.WeeklyReportReq
+	ownerId := c.GetString("userId")
+	if ownerId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+	err := c.ShouldBindQuery(&req)
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "error decoding query", err, nil))
+		return
+	}
+	req.OwnerId = ownerId
+	// users sign in with the user status, VAs with their account type
+	req.Role = reportEntity.RoleVA
+	if c.GetString("status") == reportEntity.RoleUser {
+		req.Role = reportEntity.RoleUser
+	}
+
+	res, errRes := r.srv.GetWeeklyReport(&req)
+	if errRes != nil {
+		status := http.StatusInternalServerError
+		if errRes.Description == "BadInput Request" {
+			status = http.StatusBadRequest
+		}
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "error getting weekly report", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Weekly Report Retrieved Successfully", res, nil))
+}
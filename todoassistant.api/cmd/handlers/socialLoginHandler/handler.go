@@ -3,8 +3,10 @@ package socialLoginHandler
 import (
 	"log"
 	"net/http"
+	"test-va/cmd/middlewares/auditMiddleware"
 	"test-va/internals/entity/ResponseEntity"
 	"test-va/internals/entity/userEntity"
+	"test-va/internals/service/policyService"
 	"test-va/internals/service/socialLoginService"
 
 	"github.com/gin-gonic/gin"
@@ -29,15 +31,18 @@ func (t *socialLoginHandler) GoogleLogin(c *gin.Context) {
 		return
 	}
 
+	req.UserAgent = c.Request.UserAgent()
+	req.IPAddress = c.ClientIP()
 	user, errorRes := t.srv.LoginResponse(&req)
 
 	if errorRes != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized,
-			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "Authorization Error", errorRes, nil))
+		c.AbortWithStatusJSON(errorStatus(errorRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errorRes), "Authorization Error", errorRes, nil))
 		return
 	}
 
 	log.Println("userid -", user.UserId)
+	auditMiddleware.Actor(c, user.UserId, policyService.RoleUser)
 	c.Set("userId", user.UserId)
 	println(c.GetString("userId"))
 
@@ -56,18 +61,31 @@ func (t *socialLoginHandler) FacebookLogin(c *gin.Context) {
 		return
 	}
 
+	req.UserAgent = c.Request.UserAgent()
+	req.IPAddress = c.ClientIP()
 	user, errorRes := t.srv.FacebookLoginResponse(&req)
 
 	if errorRes != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized,
-			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "Authorization Error", errorRes, nil))
+		c.AbortWithStatusJSON(errorStatus(errorRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errorRes), "Authorization Error", errorRes, nil))
 		return
 	}
 
 	log.Println("userid -", user.UserId)
+	auditMiddleware.Actor(c, user.UserId, policyService.RoleUser)
 	c.Set("userId", user.UserId)
 	println(c.GetString("userId"))
 
 	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "login successful", user, nil))
 
 }
+
+func errorStatus(errRes *ResponseEntity.ServiceError) int {
+	switch errRes.Description {
+	case socialLoginService.ErrUnauthorized:
+		return http.StatusUnauthorized
+	case socialLoginService.ErrForbidden:
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
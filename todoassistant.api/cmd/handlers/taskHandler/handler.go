@@ -97,6 +97,7 @@ func (t *taskHandler) SearchTask(c *gin.Context) {
 
 	title := taskEntity.SearchTitleParams{
 		SearchQuery: name,
+		UserId:      c.GetString("userId"),
 	}
 
 	searchedTasks, errRes := t.srv.SearchTask(&title)
@@ -131,7 +132,12 @@ func (t *taskHandler) GetTaskByID(c *gin.Context) {
 			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "no task id available", nil, nil))
 		return
 	}
-	task, errRes := t.srv.GetTaskByID(taskId)
+	task, errRes := t.srv.GetTaskByID(taskId, userId)
+	if errRes != nil && errRes.Description != "Internal Service Error" {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Failure To Find Task By Id", errRes, nil))
+		return
+	}
 
 	if task == nil {
 		message := "no Task with id " + taskId + " exists"
@@ -189,10 +195,11 @@ func (t *taskHandler) DeleteTaskById(c *gin.Context) {
 			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Authentication Error, Invalid UserId", nil, nil))
 		return
 	}
-	_, errRes := t.srv.DeleteTaskByID(taskId)
+	_, errRes := t.srv.DeleteTaskByID(taskId, userId)
 	if errRes != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError, "Unable to delete task by id", errRes, nil))
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status,
+			ResponseEntity.BuildErrorResponse(status, "Unable to delete task by id", errRes, nil))
 		return
 	}
 	rd := ResponseEntity.BuildSuccessResponse(200, "Task deleted successfully", nil, nil)
@@ -244,10 +251,11 @@ func (t *taskHandler) UpdateTaskStatus(c *gin.Context) {
 		return
 	}
 
-	_, errRes := t.srv.UpdateTaskStatusByID(param, &req)
+	_, errRes := t.srv.UpdateTaskStatusByID(param, c.GetString("userId"), &req)
 	if errRes != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError,
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status,
+			ResponseEntity.BuildErrorResponse(status,
 				"Error Setting Task to Done", errRes, nil))
 		return
 	}
@@ -274,9 +282,10 @@ func (t *taskHandler) EditTaskById(c *gin.Context) {
 		return
 	}
 	//log.Println(req)
-	task, errRes := t.srv.EditTaskByID(taskId, &req)
+	task, errRes := t.srv.EditTaskByID(taskId, c.GetString("userId"), &req)
 	if errRes != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, ResponseEntity.BuildErrorResponse(http.StatusInternalServerError, "Error when updating task", errRes, nil))
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Error when updating task", errRes, nil))
 		return
 	}
 
@@ -380,8 +389,9 @@ func (t *taskHandler) CreateComment(c *gin.Context) {
 	req.SenderId = value
 	comment, errRes := t.srv.PersistComment(&req)
 	if errRes != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "error saving comment", errRes, nil))
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status,
+			ResponseEntity.BuildErrorResponse(status, "error saving comment", errRes, nil))
 		return
 	}
 
@@ -390,6 +400,60 @@ func (t *taskHandler) CreateComment(c *gin.Context) {
 
 }
 
+// log time spent on a task
+func (t *taskHandler) LogTime(c *gin.Context) {
+	var req taskEntity.LogTimeReq
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+	err := c.ShouldBind(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "error decoding into struct", err, nil))
+		return
+	}
+
+	req.TaskId = c.Param("taskId")
+	req.UserId = userId
+	timeLog, errRes := t.srv.LogTime(&req)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status,
+			ResponseEntity.BuildErrorResponse(status, "error logging time", errRes, nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Time logged successfully", timeLog, nil))
+}
+
+// move a task into a section of its project
+func (t *taskHandler) SetTaskSection(c *gin.Context) {
+	var req taskEntity.TaskSectionReq
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+	err := c.ShouldBind(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "error decoding into struct", err, nil))
+		return
+	}
+
+	res, errRes := t.srv.SetTaskSection(c.Param("taskId"), userId, &req)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status,
+			ResponseEntity.BuildErrorResponse(status, "error moving task", errRes, nil))
+		return
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
 // get comments on a task
 func (t *taskHandler) GetComments(c *gin.Context) {
 	userId := c.GetString("userId")
@@ -405,7 +469,12 @@ func (t *taskHandler) GetComments(c *gin.Context) {
 			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "no task id available", nil, nil))
 		return
 	}
-	comments, errRes := t.srv.GetAllComments(taskId)
+	comments, errRes := t.srv.GetAllComments(taskId, userId)
+	if errRes != nil && errRes.Description != "Internal Service Error" {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "Failure To Find comments", errRes, nil))
+		return
+	}
 
 	if comments == nil {
 		message := "no comments belong to task id " + taskId
@@ -483,3 +552,35 @@ func (t *taskHandler) DeleteComment(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, comments)
 }
+
+// get all tasks in a project the user owns or is a member of
+func (t *taskHandler) GetProjectTasks(c *gin.Context) {
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+
+	projectId := c.Params.ByName("projectId")
+	tasks, errRes := t.srv.GetProjectTasks(projectId, userId)
+	if errRes != nil {
+		status := errorStatus(errRes)
+		c.AbortWithStatusJSON(status,
+			ResponseEntity.BuildErrorResponse(status, "Failure To Find project tasks", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "successfully fetched project tasks", tasks, nil))
+}
+
+// errorStatus maps a service error to the http status it is returned with
+func errorStatus(errRes *ResponseEntity.ServiceError) int {
+	switch errRes.Description {
+	case "BadInput Request":
+		return http.StatusBadRequest
+	case taskService.ErrForbidden:
+		return http.StatusForbidden
+	case taskService.ErrNotFound:
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,194 @@
+package tokenHandler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"test-va/cmd/middlewares/auditMiddleware"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/tokenEntity"
+	tokenservice "test-va/internals/service/tokenService"
+
+	"github.com/gin-gonic/gin"
+)
+
+type tokenHandler struct {
+	srv tokenservice.TokenSrv
+}
+
+func NewTokenHandler(srv tokenservice.TokenSrv) *tokenHandler {
+	return &tokenHandler{srv: srv}
+}
+
+func (t *tokenHandler) RefreshToken(c *gin.Context) {
+	var req tokenEntity.RefreshReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err, nil))
+		return
+	}
+
+	token, refreshToken, err := t.srv.RefreshToken(req.RefreshToken)
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "Unable to refresh token", err.Error(), nil))
+		return
+	}
+
+	if claims, err := t.srv.ValidateToken(token); err == nil {
+		auditMiddleware.Actor(c, claims.Id, claims.Status)
+	}
+
+	tokenData := &tokenEntity.TokenRes{
+		Token:        token,
+		RefreshToken: refreshToken,
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Token refreshed successfully", tokenData, nil))
+}
+
+func (t *tokenHandler) Logout(c *gin.Context) {
+	token, ok := c.MustGet("token").(*tokenservice.Token)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+
+	err := t.srv.Logout(token)
+	if errors.Is(err, tokenservice.ErrNotRevocable) {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Unable to log out", err.Error(), nil))
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError,
+			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError, "Unable to log out", nil, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Logged out successfully", nil, nil))
+}
+
+func (t *tokenHandler) LogoutAll(c *gin.Context) {
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+
+	err := t.srv.LogoutAll(userId)
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError,
+			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError, "Unable to log out of all devices", nil, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Logged out of all devices successfully", nil, nil))
+}
+
+func (t *tokenHandler) GetSessions(c *gin.Context) {
+	token, ok := c.MustGet("token").(*tokenservice.Token)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+
+	sessions, err := t.srv.GetSessions(token.Id, token.Family)
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError,
+			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError, "Unable to get sessions", nil, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Sessions retrieved successfully", sessions, nil))
+}
+
+func (t *tokenHandler) RevokeSession(c *gin.Context) {
+	token, ok := c.MustGet("token").(*tokenservice.Token)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+
+	err := t.srv.RevokeSession(token.Id, c.Param("sessionId"))
+	if errors.Is(err, tokenservice.ErrSessionNotFound) {
+		c.AbortWithStatusJSON(http.StatusNotFound,
+			ResponseEntity.BuildErrorResponse(http.StatusNotFound, "Unable to revoke session", err.Error(), nil))
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError,
+			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError, "Unable to revoke session", nil, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Session revoked successfully", nil, nil))
+}
+
+func (t *tokenHandler) CreateAccessToken(c *gin.Context) {
+	var req tokenEntity.CreateAccessTokenReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err.Error(), nil))
+		return
+	}
+
+	res, err := t.srv.CreateAccessToken(c.GetString("userId"), c.GetString("status"), &req)
+	if errors.Is(err, tokenservice.ErrTooManyAccessTokens) {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Unable to create token", err.Error(), nil))
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError,
+			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError, "Unable to create token", nil, nil))
+		return
+	}
+	c.JSON(http.StatusCreated, ResponseEntity.BuildSuccessResponse(http.StatusCreated,
+		"Token created, copy it now as it cannot be shown again", res, nil))
+}
+
+func (t *tokenHandler) GetAccessTokens(c *gin.Context) {
+	tokens, err := t.srv.GetAccessTokens(c.GetString("userId"))
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError,
+			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError, "Unable to get tokens", nil, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Tokens retrieved successfully", tokens, nil))
+}
+
+func (t *tokenHandler) RevokeAccessToken(c *gin.Context) {
+	err := t.srv.RevokeAccessToken(c.GetString("userId"), c.Param("tokenId"))
+	if errors.Is(err, tokenservice.ErrAccessTokenNotFound) {
+		c.AbortWithStatusJSON(http.StatusNotFound,
+			ResponseEntity.BuildErrorResponse(http.StatusNotFound, "Unable to revoke token", err.Error(), nil))
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError,
+			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError, "Unable to revoke token", nil, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Token revoked successfully", nil, nil))
+}
+
+func (t *tokenHandler) GetConnectedApps(c *gin.Context) {
+	apps, err := t.srv.GetConnectedApps(c.GetString("userId"))
+	if err != nil {
+		log.Println(err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError,
+			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError, "Unable to get connected apps", nil, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Connected apps retrieved successfully", apps, nil))
+}
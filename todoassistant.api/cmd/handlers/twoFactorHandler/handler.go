@@ -0,0 +1,98 @@
+package twoFactorHandler
+
+import (
+	"net/http"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/twoFactorEntity"
+	tokenservice "test-va/internals/service/tokenService"
+	"test-va/internals/service/twoFactorService"
+
+	"github.com/gin-gonic/gin"
+)
+
+type twoFactorHandler struct {
+	srv twoFactorService.TwoFactorSrv
+}
+
+func NewTwoFactorHandler(srv twoFactorService.TwoFactorSrv) *twoFactorHandler {
+	return &twoFactorHandler{srv: srv}
+}
+
+func (t *twoFactorHandler) Enroll(c *gin.Context) {
+	token, ok := c.MustGet("token").(*tokenservice.Token)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+
+	res, errRes := t.srv.Enroll(token.Id, token.Status, token.Email)
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to enroll in two-factor authentication", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Confirm a code from the authenticator app to finish", res, nil))
+}
+
+func (t *twoFactorHandler) Confirm(c *gin.Context) {
+	token, ok := c.MustGet("token").(*tokenservice.Token)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+
+	var req twoFactorEntity.CodeReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err, nil))
+		return
+	}
+
+	res, errRes := t.srv.Confirm(token.Id, req.Code)
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to enable two-factor authentication", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Two-factor authentication enabled", res, nil))
+}
+
+func (t *twoFactorHandler) Disable(c *gin.Context) {
+	token, ok := c.MustGet("token").(*tokenservice.Token)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized,
+			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "you are not allowed to access this resource", nil, nil))
+		return
+	}
+
+	var req twoFactorEntity.CodeReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err, nil))
+		return
+	}
+
+	errRes := t.srv.Disable(token.Id, token.Status, req.Code)
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to disable two-factor authentication", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Two-factor authentication disabled", nil, nil))
+}
+
+func errorStatus(errRes *ResponseEntity.ServiceError) int {
+	switch errRes.Description {
+	case "BadInput Request":
+		return http.StatusBadRequest
+	case twoFactorService.ErrUnauthorized:
+		return http.StatusUnauthorized
+	case twoFactorService.ErrForbidden:
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
@@ -5,8 +5,15 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"test-va/cmd/middlewares/auditMiddleware"
 	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/loggerEntity"
+	"test-va/internals/entity/passkeyEntity"
+	"test-va/internals/entity/twoFactorEntity"
 	"test-va/internals/entity/userEntity"
+	"test-va/internals/service/loginGuardService"
+	"test-va/internals/service/policyService"
+	"test-va/internals/service/twoFactorService"
 	"test-va/internals/service/userService"
 
 	"github.com/gin-gonic/gin"
@@ -30,9 +37,11 @@ func (u *userHandler) CreateUser(c *gin.Context) {
 		return
 	}
 
+	req.UserAgent = c.Request.UserAgent()
+	req.IPAddress = c.ClientIP()
 	user, errorRes := u.srv.SaveUser(&req)
 	if errorRes != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, ResponseEntity.BuildErrorResponse(http.StatusInternalServerError, "Failed To Save User", errorRes, nil))
+		c.AbortWithStatusJSON(errorStatus(errorRes), ResponseEntity.BuildErrorResponse(errorStatus(errorRes), "Failed To Save User", errorRes, nil))
 		return
 	}
 	//c.Set("userId", user.UserId)
@@ -49,18 +58,129 @@ func (u *userHandler) Login(c *gin.Context) {
 		return
 	}
 
+	req.UserAgent = c.Request.UserAgent()
+	req.IPAddress = c.ClientIP()
+	auditMiddleware.Target(c, loggerEntity.TargetEmail, req.Email)
 	user, errorRes := u.srv.Login(&req)
 	if errorRes != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized,
-			ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "Authorization Error", errorRes, nil))
+		status := loginStatus(errorRes, http.StatusUnauthorized)
+		c.AbortWithStatusJSON(status,
+			ResponseEntity.BuildErrorResponse(status, "Authorization Error", errorRes, nil))
 		return
 	}
 	log.Println("userid -", user.UserId)
+	auditMiddleware.Actor(c, user.UserId, policyService.RoleUser)
 	c.Set("userId", user.UserId)
 	println(c.GetString("userId"))
 	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusAccepted, "Login Successful", user, nil))
 }
 
+func (u *userHandler) LoginTwoFactor(c *gin.Context) {
+	var req twoFactorEntity.ChallengeReq
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err, nil))
+		return
+	}
+
+	req.UserAgent = c.Request.UserAgent()
+	req.IPAddress = c.ClientIP()
+	user, errorRes := u.srv.LoginTwoFactor(&req)
+	if errorRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errorRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errorRes), "Authorization Error", errorRes, nil))
+		return
+	}
+	auditMiddleware.Actor(c, user.UserId, policyService.RoleUser)
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusAccepted, "Login Successful", user, nil))
+}
+
+func (u *userHandler) RequestMagicLink(c *gin.Context) {
+	var req userEntity.MagicLinkReq
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err, nil))
+		return
+	}
+
+	req.IPAddress = c.ClientIP()
+	res, errRes := u.srv.RequestMagicLink(&req)
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to send login link", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "If the email has an account, a login link was sent to it", res, nil))
+}
+
+func (u *userHandler) RedeemMagicLink(c *gin.Context) {
+	var req userEntity.RedeemMagicLinkReq
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err, nil))
+		return
+	}
+
+	req.UserAgent = c.Request.UserAgent()
+	req.IPAddress = c.ClientIP()
+	user, errRes := u.srv.RedeemMagicLink(&req)
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Authorization Error", errRes, nil))
+		return
+	}
+	auditMiddleware.Actor(c, user.UserId, policyService.RoleUser)
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusAccepted, "Login Successful", user, nil))
+}
+
+func (u *userHandler) BeginPasskeyLogin(c *gin.Context) {
+	var req passkeyEntity.LoginBeginReq
+	// the email is optional, so an empty body is fine
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest,
+				ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err, nil))
+			return
+		}
+	}
+
+	options, errRes := u.srv.BeginPasskeyLogin(&req)
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to start passkey login", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Pass the options to navigator.credentials.get", options, nil))
+}
+
+func (u *userHandler) LoginPasskey(c *gin.Context) {
+	var req passkeyEntity.AssertionReq
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err, nil))
+		return
+	}
+
+	req.UserAgent = c.Request.UserAgent()
+	req.IPAddress = c.ClientIP()
+	user, errRes := u.srv.LoginPasskey(&req)
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes),
+			ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Authorization Error", errRes, nil))
+		return
+	}
+	auditMiddleware.Actor(c, user.UserId, policyService.RoleUser)
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusAccepted, "Login Successful", user, nil))
+}
+
 func (u *userHandler) GetUsers(c *gin.Context) {
 	page := c.Query("page")
 	if page == "" {
@@ -111,7 +231,7 @@ func (u *userHandler) UpdateUser(c *gin.Context) {
 	user, errorRes := u.srv.UpdateUser(&req, userFromRequest(c))
 	log.Println(errorRes)
 	if errorRes != nil {
-		c.AbortWithStatusJSON(http.StatusBadRequest, ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Cannot Update!", err, nil))
+		c.AbortWithStatusJSON(errorStatus(errorRes), ResponseEntity.BuildErrorResponse(errorStatus(errorRes), "Cannot Update!", errorRes, nil))
 		return
 	}
 
@@ -173,44 +293,44 @@ func (u *userHandler) ResetPassword(c *gin.Context) {
 		return
 	}
 
-	token, errRes := u.srv.ResetPassword(&req)
+	req.IPAddress = c.ClientIP()
+
+	data, errRes := u.srv.ResetPassword(&req)
 	if errRes != nil {
-		c.AbortWithStatusJSON(http.StatusNotFound, ResponseEntity.BuildErrorResponse(http.StatusNotFound, "User does not exist", errRes, nil))
+		c.AbortWithStatusJSON(errorStatus(errRes), ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Cannot Reset Password", errRes, nil))
 		return
 	}
 
-	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Email sent, check your inbox!", token, nil))
+	// the same answer whether or not the email has an account
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "If the email has an account, a reset link has been sent to it", data, nil))
 }
 
 func (u *userHandler) ResetPasswordWithToken(c *gin.Context) {
 	var req userEntity.ResetPasswordWithTokenReq
-	token := string(c.Query("token"))
-	userId := string(c.Query("user_id"))
 	err := c.ShouldBindJSON(&req)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusBadRequest, ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err, nil))
 		return
 	}
+	// links in older emails carry the token in the query
+	if req.Token == "" {
+		req.Token = c.Query("token")
+	}
 
-	errRes := u.srv.ResetPasswordWithToken(&req, token, userId)
+	userId, errRes := u.srv.ResetPasswordWithToken(&req)
+	if errRes != nil && errRes.Description == "BadInput Request" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Cannot Change Password", errRes, nil))
+		return
+	}
 	if errRes != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError, ResponseEntity.BuildErrorResponse(http.StatusForbidden, "Cannot Change Password", errRes, nil))
 		return
 	}
+	auditMiddleware.Target(c, loggerEntity.TargetUser, userId)
 
 	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Password changed successfully", nil, nil))
 }
 
-func (u *userHandler) DeleteUser(c *gin.Context) {
-	err := u.srv.DeleteUser(userFromRequest(c))
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, ResponseEntity.NewInternalServiceError(err))
-		return
-	}
-
-	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "User deleted successfully!", nil, nil))
-}
-
 // The Id of the Virtual Assistant is Sent Along With this Request
 func (u *userHandler) AssignVAToUser(c *gin.Context) {
 	user_id := c.GetString("userId")
@@ -221,14 +341,77 @@ func (u *userHandler) AssignVAToUser(c *gin.Context) {
 		return
 	}
 
+	auditMiddleware.Target(c, loggerEntity.TargetVA, va_id)
 	err := u.srv.AssignVAToUser(user_id, va_id)
 	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, ResponseEntity.NewInternalServiceError(err))
+		c.AbortWithStatusJSON(errorStatus(err), ResponseEntity.NewInternalServiceError(err))
 		return
 	}
 	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "VA Assigned", nil, nil))
 }
 
+func (u *userHandler) VerifyEmail(c *gin.Context) {
+	var req userEntity.VerifyEmailReq
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, ResponseEntity.BuildErrorResponse(http.StatusBadRequest, "Bad Request", err, nil))
+		return
+	}
+
+	errRes := u.srv.VerifyEmail(&req)
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes), ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to verify email", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Email verified successfully", nil, nil))
+}
+
+func (u *userHandler) ResendVerification(c *gin.Context) {
+	userId := c.GetString("userId")
+	if userId == "" {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, ResponseEntity.BuildErrorResponse(http.StatusUnauthorized, "You are not allowed to access this resource", nil, nil))
+		return
+	}
+
+	errRes := u.srv.ResendVerification(userId)
+	if errRes != nil {
+		c.AbortWithStatusJSON(errorStatus(errRes), ResponseEntity.BuildErrorResponse(errorStatus(errRes), "Unable to send verification email", errRes, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK, "Email sent, check your inbox!", nil, nil))
+}
+
+func errorStatus(errRes *ResponseEntity.ServiceError) int {
+	switch errRes.Description {
+	case "BadInput Request":
+		return http.StatusBadRequest
+	case userService.ErrForbidden:
+		return http.StatusForbidden
+	case userService.ErrTooManyRequests:
+		return http.StatusTooManyRequests
+	case loginGuardService.ErrLocked:
+		return http.StatusLocked
+	case twoFactorService.ErrUnauthorized:
+		return http.StatusUnauthorized
+	}
+	return http.StatusInternalServerError
+}
+
+// loginStatus maps a login error to the http status it is returned with, fallback for wrong credentials
+func loginStatus(errRes *ResponseEntity.ServiceError, fallback int) int {
+	switch errRes.Description {
+	case "BadInput Request":
+		return http.StatusBadRequest
+	case userService.ErrForbidden:
+		return http.StatusForbidden
+	case loginGuardService.ErrTooManyRequests:
+		return http.StatusTooManyRequests
+	case loginGuardService.ErrLocked:
+		return http.StatusLocked
+	}
+	return fallback
+}
+
 func userFromRequest(c *gin.Context) string {
 	return c.Param("user_id")
 }
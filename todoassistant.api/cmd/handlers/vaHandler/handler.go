@@ -4,11 +4,18 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"test-va/cmd/middlewares/auditMiddleware"
 	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/loggerEntity"
+	"test-va/internals/entity/passkeyEntity"
 	"test-va/internals/entity/tokenEntity"
+	"test-va/internals/entity/twoFactorEntity"
 	"test-va/internals/entity/vaEntity"
+	"test-va/internals/service/loginGuardService"
+	"test-va/internals/service/passkeyService"
 	"test-va/internals/service/taskService"
 	tokenservice "test-va/internals/service/tokenService"
+	"test-va/internals/service/twoFactorService"
 	"test-va/internals/service/userService"
 	"test-va/internals/service/vaService"
 
@@ -59,6 +66,7 @@ func (v *vaHandler) DeleteVA(c *gin.Context) {
 		c.AbortWithStatusJSON(http.StatusBadRequest, map[string]string{"error": "No id in url"})
 	}
 
+	auditMiddleware.Target(c, loggerEntity.TargetVA, param)
 	errRes := v.vaSrv.DeleteVA(param)
 	if errRes != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError,
@@ -83,11 +91,26 @@ func (v *vaHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
+	auditMiddleware.Target(c, loggerEntity.TargetVA, req.VaId)
 	errRes := v.vaSrv.ChangePassword(&req)
 	if errRes != nil {
+		status := http.StatusInternalServerError
+		if errRes.Description == "BadInput Request" {
+			status = http.StatusBadRequest
+		}
+		c.AbortWithStatusJSON(status,
+			ResponseEntity.BuildErrorResponse(status,
+				"Authorization Error", errRes, nil))
+		return
+	}
+
+	// sessions started with the old password are logged out
+	err = v.tokenSrv.LogoutAll(req.VaId)
+	if err != nil {
+		log.Println(err)
 		c.AbortWithStatusJSON(http.StatusInternalServerError,
 			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError,
-				"Authorization Error", errRes, nil))
+				"Password changed but could not log out other sessions", nil, nil))
 		return
 	}
 
@@ -124,15 +147,22 @@ func (v *vaHandler) SignUp(c *gin.Context) {
 		return
 	}
 
+	auditMiddleware.Detail(c, req.AccountType)
 	user, serviceError := v.vaSrv.SignUp(&req)
 	if serviceError != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError,
+		status := http.StatusInternalServerError
+		if serviceError.Description == "BadInput Request" {
+			status = http.StatusBadRequest
+		}
+		c.AbortWithStatusJSON(status,
+			ResponseEntity.BuildErrorResponse(status,
 				"Failed to Sign Up", serviceError, nil))
 		return
 	}
 
-	token, s, err := v.tokenSrv.CreateToken(user.VaId, user.AccountType, user.Email)
+	auditMiddleware.Target(c, loggerEntity.TargetVA, user.VaId)
+	session := &tokenEntity.SessionInfo{UserAgent: c.Request.UserAgent(), IPAddress: c.ClientIP()}
+	token, s, err := v.tokenSrv.CreateToken(user.VaId, user.AccountType, user.Email, session)
 	if err != nil {
 		return
 	}
@@ -158,15 +188,26 @@ func (v *vaHandler) Login(c *gin.Context) {
 		return
 	}
 
+	req.UserAgent = c.Request.UserAgent()
+	req.IPAddress = c.ClientIP()
+	auditMiddleware.Target(c, loggerEntity.TargetEmail, req.Email)
 	user, serviceError := v.vaSrv.Login(&req)
 	if serviceError != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError,
-			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError,
+		status := loginStatus(serviceError)
+		c.AbortWithStatusJSON(status,
+			ResponseEntity.BuildErrorResponse(status,
 				"Authorization Error", serviceError, nil))
 		return
 	}
+	auditMiddleware.Actor(c, user.VaId, user.AccountType)
 
-	token, s, err := v.tokenSrv.CreateToken(user.VaId, user.AccountType, user.Email)
+	if user.TwoFactorRequired {
+		c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK,
+			"Two-factor code required", user, nil))
+		return
+	}
+
+	token, s, err := v.tokenSrv.CreateToken(user.VaId, user.AccountType, user.Email, &req.SessionInfo)
 	if err != nil {
 		c.AbortWithStatusJSON(http.StatusInternalServerError,
 			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError, "Failed to create token", nil, nil))
@@ -185,6 +226,120 @@ func (v *vaHandler) Login(c *gin.Context) {
 		"Login user successful", user, tokenData))
 }
 
+func (v *vaHandler) LoginTwoFactor(c *gin.Context) {
+	var req twoFactorEntity.ChallengeReq
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest,
+				"Bad Input Data", err, nil))
+		return
+	}
+
+	req.UserAgent = c.Request.UserAgent()
+	req.IPAddress = c.ClientIP()
+	user, serviceError := v.vaSrv.LoginTwoFactor(&req)
+	if serviceError != nil {
+		status := loginStatus(serviceError)
+		if serviceError.Description == twoFactorService.ErrUnauthorized {
+			status = http.StatusUnauthorized
+		}
+		c.AbortWithStatusJSON(status,
+			ResponseEntity.BuildErrorResponse(status,
+				"Authorization Error", serviceError, nil))
+		return
+	}
+	auditMiddleware.Actor(c, user.VaId, user.AccountType)
+
+	req.TwoFactor = true
+	token, s, err := v.tokenSrv.CreateToken(user.VaId, user.AccountType, user.Email, &req.SessionInfo)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError,
+			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError, "Failed to create token", nil, nil))
+		return
+	}
+
+	var tokenData = &tokenEntity.TokenRes{
+		Token:        token,
+		RefreshToken: s,
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK,
+		"Login user successful", user, tokenData))
+}
+
+func (v *vaHandler) BeginPasskeyLogin(c *gin.Context) {
+	var req passkeyEntity.LoginBeginReq
+	// the email is optional, so an empty body is fine
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest,
+				ResponseEntity.BuildErrorResponse(http.StatusBadRequest,
+					"Bad Input Data", err, nil))
+			return
+		}
+	}
+
+	options, serviceError := v.vaSrv.BeginPasskeyLogin(&req)
+	if serviceError != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError,
+			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError,
+				"Unable to start passkey login", serviceError, nil))
+		return
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK,
+		"Pass the options to navigator.credentials.get", options, nil))
+}
+
+func (v *vaHandler) LoginPasskey(c *gin.Context) {
+	var req passkeyEntity.AssertionReq
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest,
+			ResponseEntity.BuildErrorResponse(http.StatusBadRequest,
+				"Bad Input Data", err, nil))
+		return
+	}
+
+	req.UserAgent = c.Request.UserAgent()
+	req.IPAddress = c.ClientIP()
+	user, serviceError := v.vaSrv.LoginPasskey(&req)
+	if serviceError != nil {
+		status := http.StatusInternalServerError
+		switch serviceError.Description {
+		case "BadInput Request":
+			status = http.StatusBadRequest
+		case passkeyService.ErrUnauthorized:
+			status = http.StatusUnauthorized
+		}
+		c.AbortWithStatusJSON(status,
+			ResponseEntity.BuildErrorResponse(status,
+				"Authorization Error", serviceError, nil))
+		return
+	}
+	auditMiddleware.Actor(c, user.VaId, user.AccountType)
+	if user.TwoFactorRequired {
+		c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK,
+			"Two-factor code required", user, nil))
+		return
+	}
+
+	token, s, err := v.tokenSrv.CreateToken(user.VaId, user.AccountType, user.Email, &req.SessionInfo)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError,
+			ResponseEntity.BuildErrorResponse(http.StatusInternalServerError, "Failed to create token", nil, nil))
+		return
+	}
+
+	var tokenData = &tokenEntity.TokenRes{
+		Token:        token,
+		RefreshToken: s,
+	}
+	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK,
+		"Login user successful", user, tokenData))
+}
+
 func (v *vaHandler) GetVAByID(c *gin.Context) {
 	vaId := c.Param("va_id")
 	if vaId == "" {
@@ -305,3 +460,14 @@ func (v *vaHandler) GetAllAssignedUsersTask(c *gin.Context) {
 	c.JSON(http.StatusOK, ResponseEntity.BuildSuccessResponse(http.StatusOK,
 		"Found Tasks Successfully", task, nil))
 }
+
+// loginStatus maps a login error to the http status it is returned with
+func loginStatus(errRes *ResponseEntity.ServiceError) int {
+	switch errRes.Description {
+	case loginGuardService.ErrTooManyRequests:
+		return http.StatusTooManyRequests
+	case loginGuardService.ErrLocked:
+		return http.StatusLocked
+	}
+	return http.StatusInternalServerError
+}
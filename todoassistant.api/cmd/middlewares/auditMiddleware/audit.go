@@ -0,0 +1,65 @@
+package auditMiddleware
+
+import (
+	"net/http"
+	"test-va/internals/entity/loggerEntity"
+	"test-va/internals/service/auditService"
+	tokenservice "test-va/internals/service/tokenService"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	actorKey      = "auditActor"
+	actorRoleKey  = "auditActorRole"
+	targetKey     = "auditTarget"
+	targetTypeKey = "auditTargetType"
+	detailKey     = "auditDetail"
+)
+
+// Record adds an audit log of action once the handler answered the request.
+// The actor is the caller of the jwt or VA middleware, handlers name it with Actor when nobody was logged in yet.
+func Record(srv auditService.AuditSrv, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		record := &loggerEntity.AuditLog{
+			Action:     action,
+			Outcome:    loggerEntity.OutcomeSuccess,
+			ActorId:    c.GetString(actorKey),
+			ActorRole:  c.GetString(actorRoleKey),
+			TargetType: c.GetString(targetTypeKey),
+			TargetId:   c.GetString(targetKey),
+			Detail:     c.GetString(detailKey),
+			IPAddress:  c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+			StatusCode: c.Writer.Status(),
+		}
+		if record.StatusCode >= http.StatusBadRequest {
+			record.Outcome = loggerEntity.OutcomeFailure
+		}
+		if value, ok := c.Get("token"); ok && record.ActorId == "" {
+			if token, ok := value.(*tokenservice.Token); ok {
+				record.ActorId, record.ActorRole = token.Id, token.Status
+			}
+		}
+		srv.Record(record)
+	}
+}
+
+// Actor names who acted, for requests made before a login.
+func Actor(c *gin.Context, id, role string) {
+	c.Set(actorKey, id)
+	c.Set(actorRoleKey, role)
+}
+
+// Target names the account or resource the action was done to.
+func Target(c *gin.Context, targetType, id string) {
+	c.Set(targetTypeKey, targetType)
+	c.Set(targetKey, id)
+}
+
+// Detail says what changed, as the role an account was given.
+func Detail(c *gin.Context, detail string) {
+	c.Set(detailKey, detail)
+}
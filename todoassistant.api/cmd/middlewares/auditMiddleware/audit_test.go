@@ -0,0 +1,60 @@
+package auditMiddleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"test-va/internals/entity/loggerEntity"
+	"test-va/internals/service/auditService"
+	tokenservice "test-va/internals/service/tokenService"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type memoryAudit struct {
+	auditService.AuditSrv
+	logs []*loggerEntity.AuditLog
+}
+
+func (m *memoryAudit) Record(log *loggerEntity.AuditLog) {
+	m.logs = append(m.logs, log)
+}
+
+func TestRecord(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	audit := &memoryAudit{}
+	r := gin.New()
+	// a failed login names the email it was for
+	r.POST("/login", Record(audit, loggerEntity.AuditLogin), func(c *gin.Context) {
+		Target(c, loggerEntity.TargetEmail, "sam@example.com")
+		c.AbortWithStatus(http.StatusUnauthorized)
+	})
+	// a master's action is done by the caller of the token
+	r.POST("/delete/:va_id", func(c *gin.Context) {
+		c.Set("token", &tokenservice.Token{Id: "master", Status: "MASTER"})
+	}, Record(audit, loggerEntity.AuditVADelete), func(c *gin.Context) {
+		Target(c, loggerEntity.TargetVA, c.Param("va_id"))
+		c.Status(http.StatusOK)
+	})
+
+	for _, path := range []string{"/login", "/delete/va1"} {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("User-Agent", "test-agent")
+		req.RemoteAddr = "10.0.0.1:1234"
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if len(audit.logs) != 2 {
+		t.Fatalf("recorded %d logs, want 2", len(audit.logs))
+	}
+	login := audit.logs[0]
+	if login.Outcome != loggerEntity.OutcomeFailure || login.ActorId != "" || login.TargetId != "sam@example.com" ||
+		login.StatusCode != http.StatusUnauthorized || login.IPAddress != "10.0.0.1" || login.UserAgent != "test-agent" {
+		t.Errorf("failed login log = %+v", login)
+	}
+	deleted := audit.logs[1]
+	if deleted.Outcome != loggerEntity.OutcomeSuccess || deleted.ActorId != "master" || deleted.ActorRole != "MASTER" ||
+		deleted.TargetType != loggerEntity.TargetVA || deleted.TargetId != "va1" {
+		t.Errorf("delete log = %+v", deleted)
+	}
+}
@@ -0,0 +1,76 @@
+package policyMiddleware
+
+import (
+	"net/http"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/service/policyService"
+	tokenservice "test-va/internals/service/tokenService"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Roles lets a request through when the caller has one of the roles.
+// It has to run after the jwt or VA middleware.
+func Roles(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor, ok := actorFromContext(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, "Invalid Token")
+			return
+		}
+		if !actor.HasRole(roles...) {
+			c.AbortWithStatusJSON(http.StatusForbidden,
+				ResponseEntity.BuildErrorResponse(http.StatusForbidden, "You are not Authorized to access this resource", nil, nil))
+			return
+		}
+		c.Next()
+	}
+}
+
+// Owns lets a request through when check allows the caller on the resource named by the url parameter.
+// It has to run after the jwt or VA middleware.
+func Owns(param string, check policyService.Check) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor, ok := actorFromContext(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, "Invalid Token")
+			return
+		}
+		errRes := check(actor, c.Param(param))
+		if errRes != nil {
+			status := errorStatus(errRes)
+			c.AbortWithStatusJSON(status, ResponseEntity.BuildErrorResponse(status, "You are not Authorized to access this resource", errRes, nil))
+			return
+		}
+		c.Next()
+	}
+}
+
+// actorFromContext builds the actor from the token the jwt or VA middleware stored.
+func actorFromContext(c *gin.Context) (*policyService.Actor, bool) {
+	value, ok := c.Get("token")
+	if !ok {
+		return nil, false
+	}
+	token, ok := value.(*tokenservice.Token)
+	if !ok {
+		return nil, false
+	}
+	role := token.Status
+	// masters only act as masters once they logged in with a second factor
+	if role == policyService.RoleMaster && !token.TwoFactor {
+		role = policyService.RoleVA
+	}
+	return &policyService.Actor{Id: token.Id, Role: role}, true
+}
+
+// errorStatus maps a policy error to the http status it is returned with
+func errorStatus(errRes *ResponseEntity.ServiceError) int {
+	switch errRes.Description {
+	case policyService.ErrForbidden:
+		return http.StatusForbidden
+	case policyService.ErrNotFound:
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
@@ -32,16 +32,22 @@ func (v *vaMiddleWare) MapMasterToReq(c *gin.Context) {
 	if err != nil {
 		log.Println(err)
 		c.AbortWithStatusJSON(http.StatusUnauthorized, fmt.Sprintf("invalid Token: %v", err))
-
+		return
 	}
 
 	if token.Status != "MASTER" {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, "You are not Authorized to access this resource")
 		return
 	}
+	// masters manage other accounts, so they have to log in with a second factor
+	if !token.TwoFactor {
+		c.AbortWithStatusJSON(http.StatusForbidden, "Enroll in two-factor authentication and log in with it to access this resource")
+		return
+	}
 	c.Set("id", token.Id)
 	c.Set("status", token.Status)
 	c.Set("email", token.Email)
+	c.Set("token", token)
 	c.Next()
 }
 
@@ -62,6 +68,7 @@ func (v *vaMiddleWare) MapVAToReq(c *gin.Context) {
 	if err != nil {
 		log.Println(err)
 		c.AbortWithStatusJSON(http.StatusUnauthorized, fmt.Sprintf("invalid Token: %v", err))
+		return
 	}
 
 	if token.Status != "VA" && token.Status != "MASTER" {
@@ -71,5 +78,6 @@ func (v *vaMiddleWare) MapVAToReq(c *gin.Context) {
 	c.Set("id", token.Id)
 	c.Set("status", token.Status)
 	c.Set("email", token.Email)
+	c.Set("token", token)
 	c.Next()
 }
@@ -18,7 +18,9 @@ func NewJWTMiddleWare(tokenSrv tokenservice.TokenSrv) *jwtMiddleWare {
 	return &jwtMiddleWare{tokenSrv: tokenSrv}
 }
 
-func (j *jwtMiddleWare) ValidateJWT() gin.HandlerFunc {
+// ValidateJWT accepts JWTs, and personal access tokens holding one of scopes. Routes without
+// scopes refuse personal access tokens, see tokenservice.Token.Allows.
+func (j *jwtMiddleWare) ValidateJWT(scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
 		// const BEARER_HEADER = "Bearer "
@@ -34,8 +36,14 @@ func (j *jwtMiddleWare) ValidateJWT() gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, fmt.Sprintf("invalid Token: %v", err))
 			return
 		}
+		if !token.Allows(c.Request.Method, scopes...) {
+			c.AbortWithStatusJSON(http.StatusForbidden, "This token does not have the scope to access this resource")
+			return
+		}
 
 		c.Set("userId", token.Id)
+		c.Set("status", token.Status)
+		c.Set("token", token)
 		c.Next()
 	}
 }
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"test-va/cmd/handlers/adminHandler"
+	"test-va/cmd/middlewares"
+	"test-va/cmd/middlewares/auditMiddleware"
+	"test-va/cmd/middlewares/policyMiddleware"
+	"test-va/internals/entity/loggerEntity"
+	"test-va/internals/service/adminService"
+	"test-va/internals/service/auditService"
+	"test-va/internals/service/policyService"
+	tokenservice "test-va/internals/service/tokenService"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AdminRoutes(v1 *gin.RouterGroup, service adminService.AdminSrv, srv tokenservice.TokenSrv, auditSrv auditService.AuditSrv) {
+	jwtMWare := middlewares.NewJWTMiddleWare(srv)
+	handler := adminHandler.NewAdminHandler(service)
+	audit := func(action string) gin.HandlerFunc { return auditMiddleware.Record(auditSrv, action) }
+
+	admin := v1.Group("/admin")
+	admin.Use(jwtMWare.ValidateJWT(), policyMiddleware.Roles(policyService.RoleMaster))
+	{
+		admin.GET("/users", handler.SearchUsers)
+		admin.GET("/users/:user_id", handler.GetAccount)
+		admin.POST("/users/:user_id/suspend", audit(loggerEntity.AuditUserSuspend), handler.SuspendUser)
+		admin.POST("/users/:user_id/reactivate", audit(loggerEntity.AuditUserReactivate), handler.ReactivateUser)
+		admin.POST("/users/:user_id/logout", audit(loggerEntity.AuditUserLogout), handler.ForceLogout)
+	}
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"test-va/cmd/handlers/analyticsHandler"
+	"test-va/cmd/middlewares"
+
+	"test-va/internals/service/analyticsService"
+	tokenservice "test-va/internals/service/tokenService"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AnalyticsRoutes(v1 *gin.RouterGroup, service analyticsService.AnalyticsService, srv tokenservice.TokenSrv) {
+
+	jwtMWare := middlewares.NewJWTMiddleWare(srv)
+
+	handler := analyticsHandler.NewAnalyticsHandler(service)
+	analytics := v1.Group("/analytics")
+
+	analytics.Use(jwtMWare.ValidateJWT())
+	{
+		analytics.GET("", handler.GetAnalytics)
+	}
+}
@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"test-va/cmd/handlers/attachmentHandler"
+	"test-va/cmd/middlewares"
+	"test-va/cmd/middlewares/policyMiddleware"
+
+	"test-va/internals/entity/tokenEntity"
+	"test-va/internals/service/attachmentService"
+	"test-va/internals/service/policyService"
+	"test-va/internals/service/storageService/localStorage"
+	tokenservice "test-va/internals/service/tokenService"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AttachmentRoutes registers the attachment api. When files are kept on local disk the
+// signed file routes are registered too; they are authorised by the url signature, not a jwt.
+func AttachmentRoutes(v1 *gin.RouterGroup, service attachmentService.AttachmentService, storage localStorage.LocalStorage, srv tokenservice.TokenSrv,
+	policySrv policyService.PolicySrv) {
+
+	jwtMWare := middlewares.NewJWTMiddleWare(srv)
+
+	handler := attachmentHandler.NewAttachmentHandler(service, storage)
+	attachment := v1.Group("/attachment")
+
+	attachment.Use(jwtMWare.ValidateJWT(tokenEntity.ScopeTasksRead, tokenEntity.ScopeTasksWrite))
+	{
+		attachment.POST("", handler.CreateUpload)
+		attachment.POST("/:attachmentId/complete", handler.CompleteUpload)
+		attachment.GET("/:attachmentId", handler.GetDownloadUrl)
+		attachment.DELETE("/:attachmentId", handler.DeleteAttachment)
+		attachment.GET("/task/:taskId", policyMiddleware.Owns("taskId", policySrv.CanReadTask), handler.ListByTask)
+		attachment.GET("/comment/:commentId", handler.ListByComment)
+	}
+
+	if storage != nil {
+		files := v1.Group("/files")
+		files.PUT("/*key", handler.PutFile)
+		files.GET("/*key", handler.GetFile)
+	}
+}
@@ -2,12 +2,19 @@ package routes
 
 import (
 	"test-va/cmd/handlers/callHandler"
+	"test-va/cmd/middlewares"
+	"test-va/cmd/middlewares/policyMiddleware"
 	"test-va/internals/service/callService"
+	"test-va/internals/service/policyService"
+	tokenservice "test-va/internals/service/tokenService"
 
 	"github.com/gin-gonic/gin"
 )
 
-func CallRoute(v1 *gin.RouterGroup, srv callService.CallService) {
+func CallRoute(v1 *gin.RouterGroup, srv callService.CallService, tokenSrv tokenservice.TokenSrv) {
 	callHandler := callHandler.NewCallHandler(srv)
-	v1.GET("/calls", callHandler.GetCalls)
+	jwtMWare := middlewares.NewJWTMiddleWare(tokenSrv)
+
+	// calls of every user are listed, so only masters may see them
+	v1.GET("/calls", jwtMWare.ValidateJWT(), policyMiddleware.Roles(policyService.RoleMaster), callHandler.GetCalls)
 }
@@ -3,6 +3,7 @@ package routes
 import (
 	"test-va/cmd/handlers/notificationHandler"
 	"test-va/cmd/middlewares"
+	"test-va/internals/entity/tokenEntity"
 	"test-va/internals/service/notificationService"
 	tokenservice "test-va/internals/service/tokenService"
 
@@ -15,7 +16,7 @@ func NotificationRoutes(v1 *gin.RouterGroup, service notificationService.Notific
 
 	not := v1.Group("/notification")
 
-	not.Use(jwtMWare.ValidateJWT())
+	not.Use(jwtMWare.ValidateJWT(tokenEntity.ScopeNotifications))
 	{
 		//Create a Notification
 		not.POST("", notificationHandler.RegisterForNotifications)
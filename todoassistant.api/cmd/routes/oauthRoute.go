@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"test-va/cmd/handlers/oauthHandler"
+	"test-va/cmd/middlewares"
+	"test-va/cmd/middlewares/policyMiddleware"
+	"test-va/internals/service/oauthService"
+	"test-va/internals/service/policyService"
+	tokenservice "test-va/internals/service/tokenService"
+
+	"github.com/gin-gonic/gin"
+)
+
+func OAuthRoutes(v1 *gin.RouterGroup, srv oauthService.OAuthSrv, tokenSrv tokenservice.TokenSrv) {
+	handler := oauthHandler.NewOAuthHandler(srv)
+	jwtMWare := middlewares.NewJWTMiddleWare(tokenSrv)
+	userOnly := policyMiddleware.Roles(policyService.RoleUser)
+
+	oauth := v1.Group("/oauth")
+	{
+		// apps authenticate themselves on these, with their client id and secret
+		oauth.POST("/token", handler.Token)
+		oauth.POST("/revoke", handler.Revoke)
+	}
+
+	// tokens of apps have no scope for these, an app cannot register apps or approve itself
+	users := oauth.Group("")
+	users.Use(jwtMWare.ValidateJWT(), userOnly)
+	{
+		users.POST("/clients", handler.RegisterClient)
+		users.GET("/clients", handler.GetClients)
+		users.DELETE("/clients/:clientId", handler.DeleteClient)
+		// the consent page of the web app checks the request, then posts the user's answer
+		users.GET("/authorize", handler.GetConsent)
+		users.POST("/authorize", handler.Authorize)
+	}
+}
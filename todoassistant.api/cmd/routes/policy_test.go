@@ -0,0 +1,370 @@
+package routes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"test-va/internals/Repository/projectRepo"
+	"test-va/internals/Repository/taskRepo"
+	"test-va/internals/entity/loggerEntity"
+	"test-va/internals/entity/projectEntity"
+	"test-va/internals/entity/taskEntity"
+	"test-va/internals/service/auditService"
+	"test-va/internals/service/policyService"
+	tokenservice "test-va/internals/service/tokenService"
+
+	"github.com/gin-gonic/gin"
+)
+
+// the callers of the matrix, each one is also the bearer token it sends
+const (
+	owner    = "owner"    // owns the task, the project and has the VA
+	viewer   = "viewer"   // can view the project and wrote the comment
+	stranger = "stranger" // a user with no relation to anything
+	va       = "va"       // the VA assigned to the owner and the task
+	otherVA  = "otherVA"  // a VA assigned to nobody
+	master   = "master"   // a master logged in with a second factor
+	master1f = "master1f" // a master logged in with a password only
+)
+
+var (
+	actors = []string{owner, viewer, stranger, va, otherVA, master, master1f}
+	users  = []string{owner, viewer, stranger}
+	vas    = []string{va, otherVA, master, master1f}
+)
+
+type fakeTokens struct {
+	tokenservice.TokenSrv
+}
+
+func (f *fakeTokens) ValidateToken(token string) (*tokenservice.Token, error) {
+	switch token {
+	case owner, viewer, stranger:
+		return &tokenservice.Token{Id: token, Status: policyService.RoleUser}, nil
+	case va, otherVA:
+		return &tokenservice.Token{Id: token, Status: policyService.RoleVA}, nil
+	case master:
+		return &tokenservice.Token{Id: token, Status: policyService.RoleMaster, TwoFactor: true}, nil
+	case master1f:
+		return &tokenservice.Token{Id: token, Status: policyService.RoleMaster}, nil
+	}
+	// personal access tokens of the owner are sent as "pat:" and their scopes
+	if strings.HasPrefix(token, "pat:") {
+		return &tokenservice.Token{Id: owner, Status: policyService.RoleUser, Type: tokenservice.TypePersonal,
+			Scopes: strings.Split(strings.TrimPrefix(token, "pat:"), ",")}, nil
+	}
+	// tokens the owner gave an OAuth app are sent as "app:" and their scopes
+	if strings.HasPrefix(token, "app:") {
+		return &tokenservice.Token{Id: owner, Status: policyService.RoleUser, Type: tokenservice.TypeAccess,
+			ClientId: "client", Scopes: strings.Split(strings.TrimPrefix(token, "app:"), ",")}, nil
+	}
+	return nil, errors.New("unknown token")
+}
+
+type fakeTasks struct {
+	taskRepo.TaskRepository
+}
+
+func (f *fakeTasks) GetTaskByID(ctx context.Context, taskId string) (*taskEntity.GetTasksByIdRes, error) {
+	if taskId != "task" {
+		return nil, sql.ErrNoRows
+	}
+	return &taskEntity.GetTasksByIdRes{TaskId: taskId, UserId: owner, VaId: va, ProjectId: "project"}, nil
+}
+
+func (f *fakeTasks) GetVADetails(ctx context.Context, userId string) (string, error) {
+	if userId != owner {
+		return "", sql.ErrNoRows
+	}
+	return va, nil
+}
+
+func (f *fakeTasks) GetCommentByID(ctx context.Context, commentId string) (*taskEntity.GetCommentRes, error) {
+	if commentId != "comment" {
+		return nil, sql.ErrNoRows
+	}
+	return &taskEntity.GetCommentRes{Id: commentId, TaskId: "task", SenderId: viewer}, nil
+}
+
+type fakeProjects struct {
+	projectRepo.ProjectRepository
+}
+
+func (f *fakeProjects) GetMemberRole(ctx context.Context, projectId, userId string) (string, error) {
+	switch {
+	case projectId == "project" && userId == owner:
+		return projectEntity.RoleOwner, nil
+	case projectId == "project" && userId == viewer:
+		return projectEntity.RoleViewer, nil
+	}
+	return "", sql.ErrNoRows
+}
+
+type fakeAudit struct {
+	auditService.AuditSrv
+}
+
+func (f *fakeAudit) Record(log *loggerEntity.AuditLog) {}
+
+// newPolicyRouter registers every route with the real middleware and policy, but without services.
+// A request the policy lets through reaches a handler and fails there instead of being refused.
+func newPolicyRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.RecoveryWithWriter(io.Discard))
+	v1 := r.Group("/api/v1")
+
+	tokens := &fakeTokens{}
+	policySrv := policyService.NewPolicySrv(&fakeTasks{}, &fakeProjects{})
+
+	UserRoutes(v1, nil, tokens, nil, nil, policySrv, nil, nil, &fakeAudit{})
+	CallRoute(v1, nil, tokens)
+	ProjectRoutes(v1, nil, tokens, policySrv, &fakeAudit{})
+	TaskRoutes(v1, nil, tokens, policySrv)
+	NotificationRoutes(v1, nil, tokens)
+	VARoutes(v1, nil, tokens, nil, nil, nil, nil, policySrv, nil, &fakeAudit{})
+	AttachmentRoutes(v1, nil, nil, tokens, policySrv)
+	AnalyticsRoutes(v1, nil, tokens)
+	ReportRoutes(v1, nil, tokens)
+	OAuthRoutes(v1, nil, tokens)
+	AdminRoutes(v1, nil, tokens, &fakeAudit{})
+	return r
+}
+
+func TestRoutePolicy(t *testing.T) {
+	tests := []struct {
+		method  string
+		path    string
+		allowed []string
+	}{
+		// tasks
+		{"POST", "/task", users},
+		{"GET", "/task/task", []string{owner, viewer, va}},
+		{"GET", "/task/missing", nil},
+		{"GET", "/task/pending/owner", []string{owner, va, master}},
+		{"GET", "/task/pending/viewer", []string{viewer, master}},
+		{"GET", "/task/project/project", []string{owner, viewer}},
+		{"GET", "/task/expired", []string{master}},
+		{"GET", "/task/", users},
+		{"DELETE", "/task/task", []string{owner, va}},
+		{"PATCH", "/task/task/status", []string{owner, va}},
+		{"POST", "/task/task/time", []string{owner, va}},
+		{"PATCH", "/task/task/section", []string{owner, va}},
+		{"PATCH", "/task/task", []string{owner, va}},
+		{"GET", "/task/search", users},
+		{"POST", "/task/assign/task", []string{owner}},
+		{"POST", "/task/comment", actors},
+		{"GET", "/task/comment/task", []string{owner, viewer, va}},
+		{"GET", "/task/comment/all", []string{master}},
+		{"DELETE", "/task/comment/comment", []string{owner, viewer}},
+		{"GET", "/task/all/va", vas},
+		{"GET", "/task/all", []string{master}},
+		{"GET", "/task/all/pendingtasks", []string{master}},
+
+		// users
+		{"GET", "/user", []string{master}},
+		{"GET", "/user/owner", []string{owner, va, master}},
+		{"GET", "/user/stranger", []string{stranger, master}},
+		{"PATCH", "/user/owner", []string{owner, master}},
+		{"DELETE", "/user/owner", []string{owner, master}},
+		{"POST", "/user/upload", users},
+		{"PUT", "/user/change-password", users},
+		{"POST", "/user/assign-va/va", users},
+		{"POST", "/user/verify-email/resend", users},
+		{"POST", "/user/logout", actors},
+		{"POST", "/user/logout-all", actors},
+		{"GET", "/user/sessions", users},
+		{"DELETE", "/user/sessions/session", users},
+		{"POST", "/user/2fa/enroll", users},
+		{"POST", "/user/2fa/confirm", users},
+		{"POST", "/user/2fa/disable", users},
+		{"POST", "/user/passkeys/register/begin", users},
+		{"POST", "/user/passkeys/register/finish", users},
+		{"GET", "/user/passkeys", users},
+		{"PATCH", "/user/passkeys/credential", users},
+		{"DELETE", "/user/passkeys/credential", users},
+		{"POST", "/user/tokens", users},
+		{"GET", "/user/tokens", users},
+		{"DELETE", "/user/tokens/token", users},
+		{"GET", "/user/apps", users},
+		{"DELETE", "/user/apps/session", users},
+		{"POST", "/user/privacy/export", users},
+		{"GET", "/user/privacy/export/request", users},
+		{"POST", "/user/privacy/erasure", users},
+		{"DELETE", "/user/privacy/erasure", users},
+		{"GET", "/user/settings/", users},
+		{"PATCH", "/user/settings/reminder-settings", users},
+		{"PATCH", "/user/settings/notification-settings", users},
+		{"PATCH", "/user/settings/product-email-settings", users},
+
+		// VAs
+		{"GET", "/va/va", []string{va, owner, master}},
+		{"POST", "/va/va", []string{va, master}},
+		{"GET", "/va/user/va", []string{va, master}},
+		{"GET", "/va/user/task/owner", []string{va, master}},
+		{"GET", "/va/user/profile/owner", []string{va, master}},
+		{"GET", "/va/user/assigned-tasks/va", []string{va, master}},
+		{"GET", "/va/sessions", vas},
+		{"DELETE", "/va/sessions/session", vas},
+		{"POST", "/va/2fa/enroll", vas},
+		{"POST", "/va/2fa/confirm", vas},
+		{"POST", "/va/2fa/disable", vas},
+		{"POST", "/va/passkeys/register/begin", vas},
+		{"POST", "/va/passkeys/register/finish", vas},
+		{"GET", "/va/passkeys", vas},
+		{"PATCH", "/va/passkeys/credential", vas},
+		{"DELETE", "/va/passkeys/credential", vas},
+		{"POST", "/va/signup", []string{master}},
+		{"POST", "/va/delete/va", []string{master}},
+		{"POST", "/va/change-password", []string{master}},
+		{"POST", "/va/unlock", []string{master}},
+		{"GET", "/va/audit", []string{master}},
+		{"GET", "/va/audit/export", []string{master}},
+
+		// admin console
+		{"GET", "/admin/users", []string{master}},
+		{"GET", "/admin/users/owner", []string{master}},
+		{"POST", "/admin/users/owner/suspend", []string{master}},
+		{"POST", "/admin/users/owner/reactivate", []string{master}},
+		{"POST", "/admin/users/owner/logout", []string{master}},
+
+		// projects
+		{"POST", "/project", users},
+		{"GET", "/project/", users},
+		{"PATCH", "/project/project", []string{owner, viewer}},
+		{"DELETE", "/project/project", []string{owner, viewer}},
+		{"POST", "/project/project/archive", []string{owner, viewer}},
+		{"POST", "/project/project/unarchive", []string{owner, viewer}},
+		{"PATCH", "/project/project/move", []string{owner, viewer}},
+		{"GET", "/project/project/sections", []string{owner, viewer}},
+		{"POST", "/project/project/sections", []string{owner, viewer}},
+		{"PUT", "/project/project/sections/order", []string{owner, viewer}},
+		{"PATCH", "/project/project/sections/section", []string{owner, viewer}},
+		{"DELETE", "/project/project/sections/section", []string{owner, viewer}},
+		{"GET", "/project/templates", users},
+		{"POST", "/project/project/template", []string{owner, viewer}},
+		{"POST", "/project/templates/template/instantiate", users},
+		{"GET", "/project/invitations", users},
+		{"POST", "/project/invitations/invitation/accept", users},
+		{"POST", "/project/invitations/invitation/decline", users},
+		{"POST", "/project/project/invitations", []string{owner, viewer}},
+		{"GET", "/project/project/members", []string{owner, viewer}},
+		{"PATCH", "/project/project/members/member", []string{owner, viewer}},
+		{"DELETE", "/project/project/members/member", []string{owner, viewer}},
+		{"GET", "/project/other/sections", nil},
+
+		// the rest
+		{"GET", "/calls", []string{master}},
+		{"POST", "/attachment", actors},
+		{"POST", "/attachment/attachment/complete", actors},
+		{"GET", "/attachment/attachment", actors},
+		{"DELETE", "/attachment/attachment", actors},
+		{"GET", "/attachment/task/task", []string{owner, viewer, va}},
+		{"GET", "/attachment/comment/comment", actors},
+		{"POST", "/notification", actors},
+		{"GET", "/notification", actors},
+		{"DELETE", "/notification", actors},
+		{"PATCH", "/notification/notification", actors},
+		{"GET", "/analytics", actors},
+		{"GET", "/reports/weekly", actors},
+		{"POST", "/oauth/clients", users},
+		{"GET", "/oauth/clients", users},
+		{"DELETE", "/oauth/clients/client", users},
+		{"GET", "/oauth/authorize", users},
+		{"POST", "/oauth/authorize", users},
+	}
+
+	r := newPolicyRouter()
+	for _, tt := range tests {
+		allowed := map[string]bool{}
+		for _, actor := range tt.allowed {
+			allowed[actor] = true
+		}
+		for _, actor := range actors {
+			t.Run(tt.method+" "+tt.path+" as "+actor, func(t *testing.T) {
+				req := httptest.NewRequest(tt.method, "/api/v1"+tt.path, nil)
+				req.Header.Set("Authorization", "Bearer "+actor)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+
+				refused := w.Code == http.StatusUnauthorized || w.Code == http.StatusForbidden || w.Code == http.StatusNotFound
+				if allowed[actor] && refused {
+					t.Errorf("want %s let through, got %d", actor, w.Code)
+				}
+				if !allowed[actor] && !refused {
+					t.Errorf("want %s refused, got %d", actor, w.Code)
+				}
+			})
+		}
+	}
+
+	t.Run("no token", func(t *testing.T) {
+		for _, tt := range tests {
+			req := httptest.NewRequest(tt.method, "/api/v1"+tt.path, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("%s %s: want 401 without a token, got %d", tt.method, tt.path, w.Code)
+			}
+		}
+	})
+}
+
+func TestPersonalAccessTokenScopes(t *testing.T) {
+	r := newPolicyRouter()
+	all := "pat:tasks:read,tasks:write,projects,notifications"
+
+	tests := []struct {
+		method  string
+		path    string
+		token   string
+		allowed bool
+	}{
+		{"GET", "/task/task", "pat:tasks:read", true},
+		{"PATCH", "/task/task", "pat:tasks:read", false},
+		{"PATCH", "/task/task", "pat:tasks:write", true},
+		{"GET", "/task/task", "pat:tasks:write", true},
+		{"GET", "/attachment/task/task", "pat:tasks:read", true},
+		{"GET", "/task/task", "pat:projects", false},
+		{"GET", "/project/", "pat:projects", true},
+		{"GET", "/project/", "pat:tasks:read,tasks:write", false},
+		{"GET", "/notification", "pat:notifications", true},
+		{"GET", "/notification", "pat:projects", false},
+		// routes without scopes refuse every personal access token
+		{"GET", "/user/owner", all, false},
+		{"POST", "/user/tokens", all, false},
+		{"PUT", "/user/change-password", all, false},
+		{"POST", "/user/logout-all", all, false},
+		{"GET", "/analytics", all, false},
+		// the tokens of OAuth apps are limited the same way, and cannot manage apps
+		{"GET", "/task/task", "app:tasks:read", true},
+		{"PATCH", "/task/task", "app:tasks:read", false},
+		{"GET", "/project/", "app:projects", true},
+		{"GET", "/notification", "app:projects", false},
+		{"GET", "/user/apps", "app:tasks:read,tasks:write,projects,notifications", false},
+		{"POST", "/oauth/clients", "app:tasks:read,tasks:write,projects,notifications", false},
+		{"POST", "/oauth/authorize", "app:tasks:read,tasks:write,projects,notifications", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path+" with "+tt.token, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1"+tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			refused := w.Code == http.StatusUnauthorized || w.Code == http.StatusForbidden || w.Code == http.StatusNotFound
+			if tt.allowed && refused {
+				t.Errorf("want let through, got %d", w.Code)
+			}
+			if !tt.allowed && !refused {
+				t.Errorf("want refused, got %d", w.Code)
+			}
+		})
+	}
+}
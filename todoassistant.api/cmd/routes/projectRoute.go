@@ -3,26 +3,59 @@ package routes
 import (
 	"test-va/cmd/handlers/projectHandler"
 	"test-va/cmd/middlewares"
+	"test-va/cmd/middlewares/auditMiddleware"
+	"test-va/cmd/middlewares/policyMiddleware"
 
+	"test-va/internals/entity/loggerEntity"
+	"test-va/internals/entity/tokenEntity"
+	"test-va/internals/service/auditService"
+	"test-va/internals/service/policyService"
 	"test-va/internals/service/projectService"
 	tokenservice "test-va/internals/service/tokenService"
 
 	"github.com/gin-gonic/gin"
 )
 
-func ProjectRoutes(v1 *gin.RouterGroup, service projectService.ProjectService, srv tokenservice.TokenSrv) {
+func ProjectRoutes(v1 *gin.RouterGroup, service projectService.ProjectService, srv tokenservice.TokenSrv, policySrv policyService.PolicySrv,
+	auditSrv auditService.AuditSrv) {
 
 	jwtMWare := middlewares.NewJWTMiddleWare(srv)
 
 	handler := projectHandler.NewProjectHandler(service)
 	project := v1.Group("/project")
 
-	project.Use(jwtMWare.ValidateJWT())
+	// projects belong to users, the service checks the role a member needs for each change
+	project.Use(jwtMWare.ValidateJWT(tokenEntity.ScopeProjects), policyMiddleware.Roles(policyService.RoleUser))
+	member := policyMiddleware.Owns("projectId", policySrv.CanReadProject)
 	{
 		project.POST("", handler.CreateProject)
-		project.PATCH("/:projectId", handler.EditProjectById)
+		project.PATCH("/:projectId", member, handler.EditProjectById)
 		project.GET("/", handler.GetAllUsersProjects)
-		project.DELETE("/:projectId", handler.DeleteProjectById)
+		project.DELETE("/:projectId", member, handler.DeleteProjectById)
+		project.POST("/:projectId/archive", member, handler.ArchiveProject)
+		project.POST("/:projectId/unarchive", member, handler.UnarchiveProject)
+		project.PATCH("/:projectId/move", member, handler.MoveProject)
+
+		//sections
+		project.GET("/:projectId/sections", member, handler.GetSections)
+		project.POST("/:projectId/sections", member, handler.CreateSection)
+		project.PUT("/:projectId/sections/order", member, handler.ReorderSections)
+		project.PATCH("/:projectId/sections/:sectionId", member, handler.RenameSection)
+		project.DELETE("/:projectId/sections/:sectionId", member, handler.DeleteSection)
+
+		//templates
+		project.GET("/templates", handler.GetTemplates)
+		project.POST("/:projectId/template", member, handler.SaveAsTemplate)
+		project.POST("/templates/:templateId/instantiate", handler.InstantiateTemplate)
+
+		//sharing
+		project.GET("/invitations", handler.GetInvitations)
+		project.POST("/invitations/:invitationId/accept", handler.AcceptInvitation)
+		project.POST("/invitations/:invitationId/decline", handler.DeclineInvitation)
+		project.POST("/:projectId/invitations", member, handler.InviteMember)
+		project.GET("/:projectId/members", member, handler.GetMembers)
+		project.PATCH("/:projectId/members/:memberId", member, auditMiddleware.Record(auditSrv, loggerEntity.AuditMemberRole), handler.UpdateMemberRole)
+		project.DELETE("/:projectId/members/:memberId", member, handler.RemoveMember)
 	}
 
 }
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"test-va/cmd/handlers/reportHandler"
+	"test-va/cmd/middlewares"
+
+	"test-va/internals/service/reportService"
+	tokenservice "test-va/internals/service/tokenService"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ReportRoutes(v1 *gin.RouterGroup, service reportService.ReportService, srv tokenservice.TokenSrv) {
+
+	jwtMWare := middlewares.NewJWTMiddleWare(srv)
+
+	handler := reportHandler.NewReportHandler(service)
+	reports := v1.Group("/reports")
+
+	reports.Use(jwtMWare.ValidateJWT())
+	{
+		reports.GET("/weekly", handler.GetWeeklyReport)
+	}
+}
@@ -2,15 +2,19 @@ package routes
 
 import (
 	"test-va/cmd/handlers/socialLoginHandler"
+	"test-va/cmd/middlewares/auditMiddleware"
+	"test-va/internals/entity/loggerEntity"
+	"test-va/internals/service/auditService"
 	"test-va/internals/service/socialLoginService"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SocialLoginRoute(v1 *gin.RouterGroup, srv socialLoginService.LoginSrv) {
+func SocialLoginRoute(v1 *gin.RouterGroup, srv socialLoginService.LoginSrv, auditSrv auditService.AuditSrv) {
 	loginHandler := socialLoginHandler.NewLoginHandler(srv)
+	audit := auditMiddleware.Record(auditSrv, loggerEntity.AuditLoginSocial)
 
-	v1.POST("/googlelogin", loginHandler.GoogleLogin)
-	v1.POST("/facebooklogin", loginHandler.FacebookLogin)
+	v1.POST("/googlelogin", audit, loginHandler.GoogleLogin)
+	v1.POST("/facebooklogin", audit, loginHandler.FacebookLogin)
 
 }
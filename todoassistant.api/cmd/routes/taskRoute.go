@@ -3,15 +3,18 @@ package routes
 import (
 	"test-va/cmd/handlers/taskHandler"
 	"test-va/cmd/middlewares"
+	"test-va/cmd/middlewares/policyMiddleware"
 	"test-va/cmd/middlewares/vaMiddleware"
 
+	"test-va/internals/entity/tokenEntity"
+	"test-va/internals/service/policyService"
 	"test-va/internals/service/taskService"
 	tokenservice "test-va/internals/service/tokenService"
 
 	"github.com/gin-gonic/gin"
 )
 
-func TaskRoutes(v1 *gin.RouterGroup, service taskService.TaskService, srv tokenservice.TokenSrv) {
+func TaskRoutes(v1 *gin.RouterGroup, service taskService.TaskService, srv tokenservice.TokenSrv, policySrv policyService.PolicySrv) {
 	mWare := vaMiddleware.NewVaMiddleWare(srv)
 	jwtMWare := middlewares.NewJWTMiddleWare(srv)
 
@@ -19,29 +22,32 @@ func TaskRoutes(v1 *gin.RouterGroup, service taskService.TaskService, srv tokens
 	task := v1.Group("/task")
 	task2 := v1.Group("/task")
 
-	task.Use(jwtMWare.ValidateJWT())
+	task.Use(jwtMWare.ValidateJWT(tokenEntity.ScopeTasksRead, tokenEntity.ScopeTasksWrite))
 	{
-		task.POST("", handler.CreateTask)
-		task.GET("/:taskId", handler.GetTaskByID)
-		task.GET("/pending/:userId", handler.GetPendingTasks)
-		task.GET("/expired", handler.GetListOfExpiredTasks)
-		task.GET("/", handler.GetAllTask)               //Get all task by a user
-		task.DELETE("/:taskId", handler.DeleteTaskById) //Delete Task By ID
+		task.POST("", policyMiddleware.Roles(policyService.RoleUser), handler.CreateTask)
+		task.GET("/:taskId", policyMiddleware.Owns("taskId", policySrv.CanReadTask), handler.GetTaskByID)
+		task.GET("/pending/:userId", policyMiddleware.Owns("userId", policySrv.CanViewUser), handler.GetPendingTasks)
+		task.GET("/project/:projectId", policyMiddleware.Owns("projectId", policySrv.CanReadProject), handler.GetProjectTasks) //Get all task in a shared project
+		task.GET("/expired", policyMiddleware.Roles(policyService.RoleMaster), handler.GetListOfExpiredTasks)
+		task.GET("/", policyMiddleware.Roles(policyService.RoleUser), handler.GetAllTask)                       //Get all task by a user
+		task.DELETE("/:taskId", policyMiddleware.Owns("taskId", policySrv.CanEditTask), handler.DeleteTaskById) //Delete Task By ID
 		//task.DELETE("/", handler.DeleteAllTask)               //Delete all task of a user
-		task.PATCH("/:taskId/status", handler.UpdateTaskStatus) //Update task status
+		task.PATCH("/:taskId/status", policyMiddleware.Owns("taskId", policySrv.CanEditTask), handler.UpdateTaskStatus) //Update task status
+		task.POST("/:taskId/time", policyMiddleware.Owns("taskId", policySrv.CanEditTask), handler.LogTime)             //Log time spent on task
+		task.PATCH("/:taskId/section", policyMiddleware.Owns("taskId", policySrv.CanEditTask), handler.SetTaskSection)  //Move task into a section of its project
 
 		//comments
-		task.POST("/comment", handler.CreateComment)              //comment on task
-		task.GET("/comment/:taskId", handler.GetComments)         //get all comment on task
-		task.GET("/comment/all", handler.GetAllComments)          //get all comment available
-		task.DELETE("/comment/:commentId", handler.DeleteComment) //delete comment
+		task.POST("/comment", handler.CreateComment)                                                                              //comment on task
+		task.GET("/comment/:taskId", policyMiddleware.Owns("taskId", policySrv.CanReadTask), handler.GetComments)                 //get all comment on task
+		task.GET("/comment/all", policyMiddleware.Roles(policyService.RoleMaster), handler.GetAllComments)                        //get all comment available
+		task.DELETE("/comment/:commentId", policyMiddleware.Owns("commentId", policySrv.CanDeleteComment), handler.DeleteComment) //delete comment
 
 		//task.PUT("/comment", handler.CreateComment)   //edit comment
-		task.PATCH("/:taskId", handler.EditTaskById) //EditTaskById
-		task.GET("/search", handler.SearchTask)
+		task.PATCH("/:taskId", policyMiddleware.Owns("taskId", policySrv.CanEditTask), handler.EditTaskById) //EditTaskById
+		task.GET("/search", policyMiddleware.Roles(policyService.RoleUser), handler.SearchTask)
 
 		//assign task to VA
-		task.POST("/assign/:taskId", handler.AssignTaskToVA)
+		task.POST("/assign/:taskId", policyMiddleware.Owns("taskId", policySrv.CanOwnTask), handler.AssignTaskToVA)
 	}
 
 	task2.Use(mWare.MapVAToReq)
@@ -49,9 +55,9 @@ func TaskRoutes(v1 *gin.RouterGroup, service taskService.TaskService, srv tokens
 		//list of all task assigned to VA
 		task2.GET("/all/va", handler.GetTasksAssignedToVa)
 		// get alllll task
-		task2.GET("/all", handler.GetAllTasksAssignedForVa)
+		task2.GET("/all", policyMiddleware.Roles(policyService.RoleMaster), handler.GetAllTasksAssignedForVa)
 		// Get list of user all Pending task for Va
-		task2.GET("/all/pendingtasks", handler.GetListOfPendingTasks)
+		task2.GET("/all/pendingtasks", policyMiddleware.Roles(policyService.RoleMaster), handler.GetListOfPendingTasks)
 	}
 
 }
@@ -1,49 +1,123 @@
 package routes
 
 import (
+	"test-va/cmd/handlers/loginAlertHandler"
+	"test-va/cmd/handlers/passkeyHandler"
+	"test-va/cmd/handlers/privacyHandler"
+	"test-va/cmd/handlers/tokenHandler"
+	"test-va/cmd/handlers/twoFactorHandler"
 	"test-va/cmd/handlers/userHandler"
 	"test-va/cmd/middlewares"
+	"test-va/cmd/middlewares/auditMiddleware"
+	"test-va/cmd/middlewares/policyMiddleware"
+	"test-va/internals/entity/loggerEntity"
+	"test-va/internals/service/auditService"
+	"test-va/internals/service/loginAlertService"
+	"test-va/internals/service/passkeyService"
+	"test-va/internals/service/policyService"
+	"test-va/internals/service/privacyService"
 	tokenservice "test-va/internals/service/tokenService"
+	"test-va/internals/service/twoFactorService"
 	"test-va/internals/service/userService"
 
 	"github.com/gin-gonic/gin"
 )
 
-func UserRoutes(v1 *gin.RouterGroup, srv userService.UserSrv, tokenSrv tokenservice.TokenSrv) {
+func UserRoutes(v1 *gin.RouterGroup, srv userService.UserSrv, tokenSrv tokenservice.TokenSrv, twoFactorSrv twoFactorService.TwoFactorSrv,
+	passkeySrv passkeyService.PasskeySrv, policySrv policyService.PolicySrv, loginAlertSrv loginAlertService.LoginAlertSrv,
+	privacySrv privacyService.PrivacySrv, auditSrv auditService.AuditSrv) {
 	userHandler := userHandler.NewUserHandler(srv)
+	tokenHandler := tokenHandler.NewTokenHandler(tokenSrv)
+	twoFactorHandler := twoFactorHandler.NewTwoFactorHandler(twoFactorSrv)
+	passkeyHandler := passkeyHandler.NewPasskeyHandler(passkeySrv)
+	loginAlertHandler := loginAlertHandler.NewLoginAlertHandler(loginAlertSrv)
+	privacyHandler := privacyHandler.NewPrivacyHandler(privacySrv)
 	jwtMWare := middlewares.NewJWTMiddleWare(tokenSrv)
+	userOnly := policyMiddleware.Roles(policyService.RoleUser)
+	audit := func(action string) gin.HandlerFunc { return auditMiddleware.Record(auditSrv, action) }
 
 	// Register a user
 
 	v1.POST("/user", userHandler.CreateUser)
 	// Login into the user account
-	v1.POST("/user/login", userHandler.Login)
+	v1.POST("/user/login", audit(loggerEntity.AuditLogin), userHandler.Login)
+	// Finish a login with a two-factor code
+	v1.POST("/user/login/2fa", audit(loggerEntity.AuditLoginTwoFactor), userHandler.LoginTwoFactor)
 	// Get a reset password token
 	v1.POST("/user/reset-password", userHandler.ResetPassword)
 	// Reset password with token id
-	v1.POST("/user/reset-password-token", userHandler.ResetPasswordWithToken)
+	v1.POST("/user/reset-password-token", audit(loggerEntity.AuditPasswordReset), userHandler.ResetPasswordWithToken)
+	// Email a link to log in without a password
+	v1.POST("/user/magic-link", userHandler.RequestMagicLink)
+	// Log in with the token from the link
+	v1.POST("/user/magic-link/redeem", audit(loggerEntity.AuditLoginMagicLink), userHandler.RedeemMagicLink)
+	// Log in with a passkey
+	v1.POST("/user/passkeys/login/begin", userHandler.BeginPasskeyLogin)
+	v1.POST("/user/passkeys/login/finish", audit(loggerEntity.AuditLoginPasskey), userHandler.LoginPasskey)
+	// Log out a device from the link in a new sign-in email
+	v1.POST("/user/login-alert/disown", loginAlertHandler.Disown)
+	// Trade a refresh token for a new token pair
+	v1.POST("/user/token/refresh", audit(loggerEntity.AuditTokenRefresh), tokenHandler.RefreshToken)
+	// Confirm an email address with the token sent to it
+	v1.POST("/user/verify-email", userHandler.VerifyEmail)
 
 	users := v1.Group("/user")
 	settings := users.Group("/settings")
 	users.Use(jwtMWare.ValidateJWT())
 	{
 		// Get all users
-		users.GET("", userHandler.GetUsers)
+		users.GET("", policyMiddleware.Roles(policyService.RoleMaster), userHandler.GetUsers)
 		// Get a specific user
-		users.GET("/:user_id", userHandler.GetUser)
+		users.GET("/:user_id", policyMiddleware.Owns("user_id", policySrv.CanViewUser), userHandler.GetUser)
 		// Update a specific user
-		users.PATCH("/:user_id", userHandler.UpdateUser)
+		users.PATCH("/:user_id", policyMiddleware.Owns("user_id", policySrv.CanManageUser), userHandler.UpdateUser)
 		// Update user image
-		users.POST("/upload", userHandler.UploadImage)
+		users.POST("/upload", userOnly, userHandler.UploadImage)
 		// Change user password
-		users.PUT("/change-password", userHandler.ChangePassword)
-		// Delete a user
-		users.DELETE("/:user_id", userHandler.DeleteUser)
+		users.PUT("/change-password", userOnly, audit(loggerEntity.AuditPasswordChange), userHandler.ChangePassword)
+		// Schedule the erasure of a user
+		users.DELETE("/:user_id", policyMiddleware.Owns("user_id", policySrv.CanManageUser), privacyHandler.RequestErasure)
 		// Assign VA to User
-		users.POST("/assign-va/:va_id", userHandler.AssignVAToUser)
+		users.POST("/assign-va/:va_id", userOnly, audit(loggerEntity.AuditVAAssign), userHandler.AssignVAToUser)
+		// Send the email verification link again
+		users.POST("/verify-email/resend", userOnly, userHandler.ResendVerification)
+		// Revoke the token of this session
+		users.POST("/logout", tokenHandler.Logout)
+		// Revoke every token of the user
+		users.POST("/logout-all", tokenHandler.LogoutAll)
+		// List the devices the user is logged in on
+		users.GET("/sessions", userOnly, tokenHandler.GetSessions)
+		// Log out of one device
+		users.DELETE("/sessions/:sessionId", userOnly, tokenHandler.RevokeSession)
+		// Start enrolling in two-factor authentication
+		users.POST("/2fa/enroll", userOnly, twoFactorHandler.Enroll)
+		// Turn two-factor authentication on with a first code
+		users.POST("/2fa/confirm", userOnly, twoFactorHandler.Confirm)
+		// Turn two-factor authentication off
+		users.POST("/2fa/disable", userOnly, twoFactorHandler.Disable)
+		// Register a passkey
+		users.POST("/passkeys/register/begin", userOnly, passkeyHandler.BeginRegistration)
+		users.POST("/passkeys/register/finish", userOnly, passkeyHandler.FinishRegistration)
+		// Manage the passkeys of the user
+		users.GET("/passkeys", userOnly, passkeyHandler.GetPasskeys)
+		users.PATCH("/passkeys/:credentialId", userOnly, passkeyHandler.RenamePasskey)
+		users.DELETE("/passkeys/:credentialId", userOnly, passkeyHandler.DeletePasskey)
+		// Manage the personal access tokens of the user
+		users.POST("/tokens", userOnly, tokenHandler.CreateAccessToken)
+		users.GET("/tokens", userOnly, tokenHandler.GetAccessTokens)
+		users.DELETE("/tokens/:tokenId", userOnly, tokenHandler.RevokeAccessToken)
+		// The OAuth apps the user connected, disconnecting one revokes its session
+		users.GET("/apps", userOnly, tokenHandler.GetConnectedApps)
+		users.DELETE("/apps/:sessionId", userOnly, tokenHandler.RevokeSession)
+		// Export all data of the user
+		users.POST("/privacy/export", userOnly, privacyHandler.RequestExport)
+		users.GET("/privacy/export/:requestId", userOnly, privacyHandler.GetExport)
+		// Erase the user after a grace period, or keep them
+		users.POST("/privacy/erasure", userOnly, privacyHandler.RequestErasure)
+		users.DELETE("/privacy/erasure", userOnly, privacyHandler.CancelErasure)
 
 	}
-	settings.Use(jwtMWare.ValidateJWT())
+	settings.Use(jwtMWare.ValidateJWT(), userOnly)
 	{
 		//get settings
 		settings.GET("/", userHandler.GetSettings)
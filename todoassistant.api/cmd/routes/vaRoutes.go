@@ -1,35 +1,92 @@
 package routes
 
 import (
+	"test-va/cmd/handlers/auditHandler"
+	"test-va/cmd/handlers/loginGuardHandler"
+	"test-va/cmd/handlers/passkeyHandler"
+	"test-va/cmd/handlers/tokenHandler"
+	"test-va/cmd/handlers/twoFactorHandler"
 	"test-va/cmd/handlers/vaHandler"
+	"test-va/cmd/middlewares"
+	"test-va/cmd/middlewares/auditMiddleware"
+	"test-va/cmd/middlewares/policyMiddleware"
 	"test-va/cmd/middlewares/vaMiddleware"
+	"test-va/internals/entity/loggerEntity"
+	"test-va/internals/service/auditService"
+	"test-va/internals/service/loginGuardService"
+	"test-va/internals/service/passkeyService"
+	"test-va/internals/service/policyService"
 	"test-va/internals/service/taskService"
 	tokenservice "test-va/internals/service/tokenService"
+	"test-va/internals/service/twoFactorService"
 	"test-va/internals/service/userService"
 	"test-va/internals/service/vaService"
 
 	"github.com/gin-gonic/gin"
 )
 
-func VARoutes(v1 *gin.RouterGroup, service vaService.VAService, srv tokenservice.TokenSrv, taskService taskService.TaskService, userService userService.UserSrv) {
+func VARoutes(v1 *gin.RouterGroup, service vaService.VAService, srv tokenservice.TokenSrv, taskService taskService.TaskService, userService userService.UserSrv,
+	twoFactorSrv twoFactorService.TwoFactorSrv, passkeySrv passkeyService.PasskeySrv, policySrv policyService.PolicySrv,
+	loginGuardSrv loginGuardService.LoginGuardSrv, auditSrv auditService.AuditSrv) {
 	handler := vaHandler.NewVaHandler(srv, service, taskService, userService)
+	tokenHandler := tokenHandler.NewTokenHandler(srv)
+	twoFactorHandler := twoFactorHandler.NewTwoFactorHandler(twoFactorSrv)
+	passkeyHandler := passkeyHandler.NewPasskeyHandler(passkeySrv)
+	loginGuardHandler := loginGuardHandler.NewLoginGuardHandler(loginGuardSrv)
+	auditHandler := auditHandler.NewAuditHandler(auditSrv)
+	audit := func(action string) gin.HandlerFunc { return auditMiddleware.Record(auditSrv, action) }
 	mWare := vaMiddleware.NewVaMiddleWare(srv)
+	jwtMWare := middlewares.NewJWTMiddleWare(srv)
 
 	va := v1.Group("/va")
-	va.POST("/:va_id", handler.UpdateVA)
-	va.GET("/:va_id", handler.GetVAByID)
-	va.POST("/login", handler.Login)
-	va.GET("/user/:va_id", handler.GetUserAssignedToVA)
-	va.GET("/user/task/:user_id", handler.GetTaskByUser)
-	va.GET("/user/profile/:user_id", handler.GetSingleUserProfile)
-	va.GET("/user/assigned-tasks/:va_id", handler.GetAllAssignedUsersTask)
+	va.POST("/login", audit(loggerEntity.AuditLogin), handler.Login)
+	va.POST("/login/2fa", audit(loggerEntity.AuditLoginTwoFactor), handler.LoginTwoFactor)
+	va.POST("/passkeys/login/begin", handler.BeginPasskeyLogin)
+	va.POST("/passkeys/login/finish", audit(loggerEntity.AuditLoginPasskey), handler.LoginPasskey)
+	// users see the profile of the VA assigned to them
+	va.GET("/:va_id", jwtMWare.ValidateJWT(), policyMiddleware.Owns("va_id", policySrv.CanViewVA), handler.GetVAByID)
+	va.POST("/:va_id", mWare.MapVAToReq, policyMiddleware.Owns("va_id", policySrv.CanManageVA), handler.UpdateVA)
+	va.GET("/user/:va_id", mWare.MapVAToReq, policyMiddleware.Owns("va_id", policySrv.CanManageVA), handler.GetUserAssignedToVA)
+	va.GET("/user/task/:user_id", mWare.MapVAToReq, policyMiddleware.Owns("user_id", policySrv.CanViewUser), handler.GetTaskByUser)
+	va.GET("/user/profile/:user_id", mWare.MapVAToReq, policyMiddleware.Owns("user_id", policySrv.CanViewUser), handler.GetSingleUserProfile)
+	va.GET("/user/assigned-tasks/:va_id", mWare.MapVAToReq, policyMiddleware.Owns("va_id", policySrv.CanManageVA), handler.GetAllAssignedUsersTask)
+
+	sessions := va.Group("/sessions")
+	sessions.Use(mWare.MapVAToReq)
+	{
+		sessions.GET("", tokenHandler.GetSessions)
+		sessions.DELETE("/:sessionId", tokenHandler.RevokeSession)
+	}
+
+	// masters have to enroll before they can reach the master routes
+	twoFactor := va.Group("/2fa")
+	twoFactor.Use(mWare.MapVAToReq)
+	{
+		twoFactor.POST("/enroll", twoFactorHandler.Enroll)
+		twoFactor.POST("/confirm", twoFactorHandler.Confirm)
+		twoFactor.POST("/disable", twoFactorHandler.Disable)
+	}
+
+	passkeys := va.Group("/passkeys")
+	passkeys.Use(mWare.MapVAToReq)
+	{
+		passkeys.POST("/register/begin", passkeyHandler.BeginRegistration)
+		passkeys.POST("/register/finish", passkeyHandler.FinishRegistration)
+		passkeys.GET("", passkeyHandler.GetPasskeys)
+		passkeys.PATCH("/:credentialId", passkeyHandler.RenamePasskey)
+		passkeys.DELETE("/:credentialId", passkeyHandler.DeletePasskey)
+	}
 
 	va.Use(mWare.MapMasterToReq)
 	{
 		//master middleware
-		va.POST("/signup", handler.SignUp)
-		va.POST("/delete/:va_id", handler.DeleteVA)
-		va.POST("/change-password", handler.ChangePassword)
+		va.POST("/signup", audit(loggerEntity.AuditVASignup), handler.SignUp)
+		va.POST("/delete/:va_id", audit(loggerEntity.AuditVADelete), handler.DeleteVA)
+		va.POST("/change-password", audit(loggerEntity.AuditVAPassword), handler.ChangePassword)
+		va.POST("/unlock", audit(loggerEntity.AuditAccountUnlock), loginGuardHandler.Unlock)
+		// the audit log, exporting it is audited too
+		va.GET("/audit", auditHandler.GetLogs)
+		va.GET("/audit/export", audit(loggerEntity.AuditExport), auditHandler.ExportLogs)
 	}
 
 }
@@ -11,33 +11,64 @@ import (
 	"test-va/cmd/handlers/paymentHandler"
 	"test-va/cmd/middlewares"
 	"test-va/cmd/routes"
+	mySqlAdminRepo "test-va/internals/Repository/adminRepo/mySqlRepo"
+	mySqlAnalyticsRepo "test-va/internals/Repository/analyticsRepo/mySqlRepo"
+	mySqlAttachmentRepo "test-va/internals/Repository/attachmentRepo/mySqlRepo"
+	mySqlAuditRepo "test-va/internals/Repository/auditRepo/mySqlRepo"
 	mySqlCallRepo "test-va/internals/Repository/callRepo/mySqlRepo"
 	mySqlRepo5 "test-va/internals/Repository/dataRepo/mySqlRepo"
+	mySqlDigestRepo "test-va/internals/Repository/digestRepo/mySqlRepo"
+	"test-va/internals/Repository/loginAttemptRepo"
+	memoryLoginAttemptRepo "test-va/internals/Repository/loginAttemptRepo/memoryRepo"
+	mySqlLoginAttemptRepo "test-va/internals/Repository/loginAttemptRepo/mySqlRepo"
 	mySqlNotifRepo "test-va/internals/Repository/notificationRepo/mysqlRepo"
+	mySqlOAuthRepo "test-va/internals/Repository/oauthRepo/mySqlRepo"
+	mySqlPasskeyRepo "test-va/internals/Repository/passkeyRepo/mySqlRepo"
+	mySqlPrivacyRepo "test-va/internals/Repository/privacyRepo/mySqlRepo"
 	projectMysqlRepo "test-va/internals/Repository/projectRepo/mySqlRepo"
 	mySqlRemindRepo "test-va/internals/Repository/reminderRepo/mySqlRepo"
+	mySqlReportRepo "test-va/internals/Repository/reportRepo/mySqlRepo"
 	mySqlRepo4 "test-va/internals/Repository/subscribeRepo/mySqlRepo"
 	"test-va/internals/Repository/taskRepo/mySqlRepo"
+	mySqlTokenRepo "test-va/internals/Repository/tokenRepo/mySqlRepo"
+	mySqlTwoFactorRepo "test-va/internals/Repository/twoFactorRepo/mySqlRepo"
 	mySqlRepo2 "test-va/internals/Repository/userRepo/mySqlRepo"
 	mySqlRepo3 "test-va/internals/Repository/vaRepo/mySqlRepo"
 	awss3 "test-va/internals/amazon/awsS3"
 	"test-va/internals/data-store/mysql"
 	firebaseinit "test-va/internals/firebase-init"
 	"test-va/internals/msg-queue/Emitter"
+	"test-va/internals/service/adminService"
+	"test-va/internals/service/analyticsService"
+	"test-va/internals/service/attachmentService"
+	"test-va/internals/service/auditService"
 	"test-va/internals/service/awsService"
 	"test-va/internals/service/callService"
 	"test-va/internals/service/cryptoService"
 	"test-va/internals/service/dataService"
+	"test-va/internals/service/digestService"
 	"test-va/internals/service/emailService"
 	log_4_go "test-va/internals/service/loggerService/log-4-go"
+	"test-va/internals/service/loginAlertService"
+	"test-va/internals/service/loginGuardService"
 	"test-va/internals/service/notificationService"
+	"test-va/internals/service/oauthService"
+	"test-va/internals/service/passkeyService"
+	"test-va/internals/service/passwordService"
+	"test-va/internals/service/policyService"
+	"test-va/internals/service/privacyService"
 	"test-va/internals/service/projectService"
 	"test-va/internals/service/reminderService"
+	"test-va/internals/service/reportService"
 	"test-va/internals/service/socialLoginService"
+	"test-va/internals/service/storageService"
+	"test-va/internals/service/storageService/localStorage"
+	"test-va/internals/service/storageService/s3Storage"
 	"test-va/internals/service/subscribeService"
 	"test-va/internals/service/taskService"
 	"test-va/internals/service/timeSrv"
 	tokenservice "test-va/internals/service/tokenService"
+	"test-va/internals/service/twoFactorService"
 	"test-va/internals/service/userService"
 	"test-va/internals/service/vaService"
 	"test-va/internals/service/validationService"
@@ -186,6 +217,38 @@ func Setup() {
 	// data repo
 	dataRepo := mySqlRepo5.NewDataSqlRepo(conn)
 
+	// attachment repo
+	attachmentRepo := mySqlAttachmentRepo.NewAttachmentSqlRepo(conn)
+
+	// analytics repo
+	analyticsRepo := mySqlAnalyticsRepo.NewAnalyticsSqlRepo(conn)
+
+	// digest repo
+	digestRepo := mySqlDigestRepo.NewDigestSqlRepo(conn)
+
+	// report repo
+	reportRepo := mySqlReportRepo.NewReportSqlRepo(conn)
+
+	// token repo
+	tokenRepo := mySqlTokenRepo.NewTokenSqlRepo(conn)
+
+	// two factor repo
+	twoFactorRepo := mySqlTwoFactorRepo.NewTwoFactorSqlRepo(conn)
+
+	// passkey repo
+	passkeyRepo := mySqlPasskeyRepo.NewPasskeySqlRepo(conn)
+
+	// privacy repo
+	privacyRepo := mySqlPrivacyRepo.NewPrivacySqlRepo(conn)
+
+	// login attempt repo, failed logins are only kept in memory when asked to
+	var attemptRepo loginAttemptRepo.LoginAttemptRepository
+	if config.LoginAttemptStore == "memory" {
+		attemptRepo = memoryLoginAttemptRepo.NewLoginAttemptMemoryRepo()
+	} else {
+		attemptRepo = mySqlLoginAttemptRepo.NewLoginAttemptSqlRepo(conn)
+	}
+
 	//SERVICES
 
 	//time service
@@ -237,15 +300,58 @@ func Setup() {
 		reminderSrv.SetReminderEvery30Min()
 	})
 
+	// daily digest, sent at each user's reminder time
+	digestSrv := digestService.NewDigestSrv(digestRepo, timeSrv, emitter)
+	s.Every(5).Minutes().Do(func() {
+		log.Println("checking for daily digests")
+		digestSrv.SendDueDigests()
+	})
+
+	// weekly review of the week that just ended
+	reportSrv := reportService.NewReportSrv(reportRepo, timeSrv, emitter)
+	s.Every(1).Monday().At("06:00").Do(func() {
+		log.Println("sending weekly reports")
+		reportSrv.SendWeeklyReports()
+	})
+
 	// run cron jobs
 	s.StartAsync()
 
 	// token service
-	srv := tokenservice.NewTokenSrv(secret)
+	srv := tokenservice.NewTokenSrv(secret, tokenRepo)
+	s.Every(1).Hour().Do(func() {
+		srv.PurgeRevocations()
+	})
+
+	// two factor service
+	twoFactorSrv := twoFactorService.NewTwoFactorSrv(twoFactorRepo, srv)
+	s.Every(1).Hour().Do(func() {
+		twoFactorSrv.PurgeChallenges()
+	})
+
+	// passkey service
+	rpId, origins := config.PasskeyScope()
+	passkeySrv := passkeyService.NewPasskeySrv(passkeyRepo, rpId, "Ticked", origins)
+	s.Every(1).Hour().Do(func() {
+		passkeySrv.PurgeChallenges()
+	})
+
+	// login guard service, slows down and locks out password guessing
+	loginGuardSrv := loginGuardService.NewLoginGuardSrv(attemptRepo, validationSrv, emitter)
+	s.Every(1).Hour().Do(func() {
+		loginGuardSrv.PurgeStale()
+	})
 
 	//logger service
 	logger := log_4_go.NewLogger()
 
+	// audit service, keeps an append-only log of logins and admin actions
+	auditRepo := mySqlAuditRepo.NewAuditSqlRepo(conn)
+	auditSrv := auditService.NewAuditSrv(auditRepo, logger, validationSrv)
+
+	// admin service, lets masters look up, suspend and reactivate accounts
+	adminSrv := adminService.NewAdminSrv(mySqlAdminRepo.NewAdminSqlRepo(conn), auditRepo, srv, validationSrv)
+
 	//crypto service
 	cryptoSrv := cryptoService.NewCryptoSrv()
 
@@ -258,24 +364,50 @@ func Setup() {
 	//Note Handle Unable to Connect to Firebase
 
 	//project service
-	projectSrv := projectService.NewProjectSrv(projectRepo, timeSrv, validationSrv, logger)
+	projectSrv := projectService.NewProjectSrv(projectRepo, timeSrv, validationSrv, logger, emitter)
+
+	// analytics service
+	analyticsSrv := analyticsService.NewAnalyticsSrv(analyticsRepo, timeSrv, validationSrv)
+	s.Every(1).Hour().Do(func() {
+		analyticsSrv.PurgeCache()
+	})
 
 	// task service
-	taskSrv := taskService.NewTaskSrv(taskRepo, timeSrv, validationSrv, logger, reminderSrv, notificationSrv)
+	taskSrv := taskService.NewTaskSrv(taskRepo, timeSrv, validationSrv, logger, reminderSrv, notificationSrv, analyticsSrv, projectRepo)
+
+	// policy service, decides who may reach which resource
+	policySrv := policyService.NewPolicySrv(taskRepo, projectRepo)
+
+	// login alert service, emails users about logins from new devices
+	loginAlertSrv := loginAlertService.NewLoginAlertSrv(userRepo, srv, validationSrv, emitter, config.AppBaseUrl)
+	s.Every(1).Day().Do(func() {
+		loginAlertSrv.PurgeExpired()
+	})
 
 	// user service
 
-	userSrv := userService.NewUserSrv(userRepo, validationSrv, timeSrv, cryptoSrv, emailSrv, awsSrv, srv, emitter)
+	// password service, every password set is checked against the policy and the bundled breached passwords
+	minLength, bannedPatterns := config.PasswordPolicy()
+	passwordPolicy, err := passwordService.NewPolicy(minLength, bannedPatterns)
+	if err != nil {
+		log.Fatal(err)
+	}
+	passwordSrv := passwordService.NewPasswordSrv(passwordPolicy, passwordService.NewOfflineList())
+
+	userSrv := userService.NewUserSrv(userRepo, validationSrv, timeSrv, cryptoSrv, emailSrv, awsSrv, srv, emitter, twoFactorSrv, passkeySrv, loginGuardSrv, loginAlertSrv, passwordSrv, config.AppBaseUrl)
 
 	//call service
 	callSrv := callService.NewCallSrv(callRepo, timeSrv, validationSrv, logger)
 
 	// social login service
 
-	loginSrv := socialLoginService.NewLoginSrv(userRepo, timeSrv, srv)
+	// Google ID tokens have to be issued to our OAuth client, CLIENT_ID is loaded into GoogleSecret
+	googleVerifier := socialLoginService.NewGoogleVerifier(config.GoogleSecret)
+	facebookVerifier := socialLoginService.NewFacebookVerifier(config.FacebookAppId, config.FacebookAppSecret)
+	loginSrv := socialLoginService.NewLoginSrv(userRepo, timeSrv, srv, twoFactorSrv, loginAlertSrv, googleVerifier, facebookVerifier)
 
 	// va service
-	vaSrv := vaService.NewVaService(vaRepo, validationSrv, timeSrv, cryptoSrv)
+	vaSrv := vaService.NewVaService(vaRepo, validationSrv, timeSrv, cryptoSrv, twoFactorSrv, passkeySrv, loginGuardSrv, passwordSrv)
 
 	// subscribe service
 	subscribeSrv := subscribeService.NewSubscribeSrv(subRepo, emailSrv, emitter)
@@ -283,6 +415,43 @@ func Setup() {
 	// data service
 	dataSrv := dataService.NewDataService(dataRepo)
 
+	// attachment storage, local disk is served by the api itself through signed urls
+	var attachmentStorage storageService.StorageSrv
+	var localFiles localStorage.LocalStorage
+	if config.AttachmentStorage == "local" {
+		dir := config.AttachmentLocalDir
+		if dir == "" {
+			dir = "./uploads"
+		}
+		baseUrl := config.AttachmentBaseUrl
+		if baseUrl == "" {
+			baseUrl = fmt.Sprintf("http://localhost:%s/api/v1/files", port)
+		}
+		localFiles = localStorage.NewLocalStorage(dir, baseUrl, secret)
+		attachmentStorage = localFiles
+	} else {
+		attachmentStorage = s3Storage.NewS3Storage(s3session, "ticked-v1-backend-bucket")
+	}
+
+	// attachment service
+	attachmentSrv := attachmentService.NewAttachmentSrv(attachmentRepo, taskRepo, attachmentStorage, timeSrv, validationSrv)
+
+	// privacy service, exports are built soon after they are asked for, erasures run once their grace period is over
+	privacySrv := privacyService.NewPrivacySrv(privacyRepo, userRepo, attachmentStorage, awsSrv, srv, emitter)
+	s.Every(5).Minutes().Do(func() {
+		privacySrv.ProcessExports()
+	})
+	s.Every(1).Hour().Do(func() {
+		privacySrv.ProcessErasures()
+	})
+
+	// oauth service, apps connect through it with the tokens of tokenSrv
+	oauthRepo := mySqlOAuthRepo.NewOAuthSqlRepo(conn)
+	oauthSrv := oauthService.NewOAuthSrv(oauthRepo, userRepo, srv, validationSrv)
+	s.Every(1).Hour().Do(func() {
+		oauthSrv.PurgeCodes()
+	})
+
 	r := gin.New()
 	r.MaxMultipartMemory = 1 << 20
 	r.Use(middlewares.CORS())
@@ -311,25 +480,28 @@ func Setup() {
 	})
 
 	//handle user routes
-	routes.UserRoutes(v1, userSrv, srv)
+	routes.UserRoutes(v1, userSrv, srv, twoFactorSrv, passkeySrv, policySrv, loginAlertSrv, privacySrv, auditSrv)
 
 	//handle call routes
-	routes.CallRoute(v1, callSrv)
+	routes.CallRoute(v1, callSrv, srv)
 
 	//handle social login route
-	routes.SocialLoginRoute(v1, loginSrv)
+	routes.SocialLoginRoute(v1, loginSrv, auditSrv)
+
+	//handle oauth apps
+	routes.OAuthRoutes(v1, oauthSrv, srv)
 
 	//project routes
-	routes.ProjectRoutes(v1, projectSrv, srv)
+	routes.ProjectRoutes(v1, projectSrv, srv, policySrv, auditSrv)
 
 	//handle task routes
-	routes.TaskRoutes(v1, taskSrv, srv)
+	routes.TaskRoutes(v1, taskSrv, srv, policySrv)
 
 	//handle Notifications
 	routes.NotificationRoutes(v1, notificationSrv, srv)
 
 	//handle VA
-	routes.VARoutes(v1, vaSrv, srv, taskSrv, userSrv)
+	routes.VARoutes(v1, vaSrv, srv, taskSrv, userSrv, twoFactorSrv, passkeySrv, policySrv, loginGuardSrv, auditSrv)
 
 	//handle subscribe route
 	routes.SubscribeRoutes(v1, subscribeSrv)
@@ -337,6 +509,18 @@ func Setup() {
 	//handle data route
 	routes.DataRoutes(v1, dataSrv)
 
+	//handle attachment routes
+	routes.AttachmentRoutes(v1, attachmentSrv, localFiles, srv, policySrv)
+
+	//handle analytics routes
+	routes.AnalyticsRoutes(v1, analyticsSrv, srv)
+
+	//handle report routes
+	routes.ReportRoutes(v1, reportSrv, srv)
+
+	//handle admin console routes
+	routes.AdminRoutes(v1, adminSrv, srv, auditSrv)
+
 	// Payment route
 	v1.POST("/checkout", paymentHandler.CheckoutCreator)
 	v1.POST("/eventService", paymentHandler.HandleEvent)
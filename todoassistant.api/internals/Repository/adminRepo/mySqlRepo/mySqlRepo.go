@@ -0,0 +1,197 @@
+package mySqlRepo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"test-va/internals/Repository/adminRepo"
+	"test-va/internals/entity/adminEntity"
+	"test-va/internals/entity/userEntity"
+)
+
+type sqlRepo struct {
+	conn *sql.DB
+}
+
+func NewAdminSqlRepo(conn *sql.DB) adminRepo.AdminRepository {
+	return &sqlRepo{conn: conn}
+}
+
+const selectUser = `SELECT user_id, first_name, last_name, email, COALESCE(phone, ''), COALESCE(account_status, ''),
+		COALESCE(payment_status, ''), COALESCE(date_created, '')
+	FROM Users`
+
+func (s *sqlRepo) SearchUsers(ctx context.Context, query, status string, limit, offset int) ([]*adminEntity.UserSummary, error) {
+	var where []string
+	var args []any
+	if query != "" {
+		// the query is matched as a prefix, its wildcards are taken literally
+		prefix := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(query) + "%"
+		where = append(where, "(email LIKE ? OR first_name LIKE ? OR last_name LIKE ? OR phone LIKE ?)")
+		args = append(args, prefix, prefix, prefix, prefix)
+	}
+	if status != "" {
+		where = append(where, "account_status = ?")
+		args = append(args, status)
+	}
+
+	stmt := selectUser
+	if len(where) > 0 {
+		stmt += " WHERE " + strings.Join(where, " AND ")
+	}
+	stmt += " ORDER BY email LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := s.conn.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*adminEntity.UserSummary{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (s *sqlRepo) GetUser(ctx context.Context, userId string) (*adminEntity.UserSummary, error) {
+	return scanUser(s.conn.QueryRowContext(ctx, selectUser+` WHERE user_id = ?`, userId))
+}
+
+func scanUser(row interface{ Scan(...any) error }) (*adminEntity.UserSummary, error) {
+	var user adminEntity.UserSummary
+	err := row.Scan(&user.UserId, &user.FirstName, &user.LastName, &user.Email, &user.Phone, &user.AccountStatus,
+		&user.PaymentStatus, &user.DateCreated)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (s *sqlRepo) GetAssignedVA(ctx context.Context, userId string) (*adminEntity.AssignedVA, error) {
+	var va adminEntity.AssignedVA
+	err := s.conn.QueryRowContext(ctx, `SELECT V.va_id, V.first_name, V.last_name, V.email
+		FROM Users U JOIN va_table V ON V.va_id = U.virtual_assistant_id
+		WHERE U.user_id = ?`, userId).Scan(&va.VaId, &va.FirstName, &va.LastName, &va.Email)
+	if err != nil {
+		return nil, err
+	}
+	return &va, nil
+}
+
+func (s *sqlRepo) GetActivity(ctx context.Context, userId, now string) (*adminEntity.Activity, error) {
+	activity := &adminEntity.Activity{Tasks: map[string]int{}}
+
+	rows, err := s.conn.QueryContext(ctx, `SELECT status, COUNT(*) FROM Tasks WHERE user_id = ? GROUP BY status`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		activity.Tasks[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// a session is active while it is not revoked and holds a refresh token that has not expired
+	err = s.conn.QueryRowContext(ctx, `SELECT
+			(SELECT COUNT(*) FROM Sessions S WHERE S.user_id = ? AND S.revoked_at IS NULL
+				AND EXISTS(SELECT 1 FROM Refresh_Tokens R WHERE R.family_id = S.session_id AND R.expires_at > ?
+					AND R.rotated_at IS NULL AND R.revoked_at IS NULL)),
+			COALESCE((SELECT MAX(created_at) FROM Sessions WHERE user_id = ?), ''),
+			COALESCE((SELECT MAX(last_seen_at) FROM Sessions WHERE user_id = ?), '')`,
+		userId, now, userId, userId).Scan(&activity.ActiveSessions, &activity.LastLoginAt, &activity.LastSeenAt)
+	if err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+func (s *sqlRepo) Suspend(ctx context.Context, suspension *adminEntity.Suspension) (bool, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(account_status, '') FROM Users WHERE user_id = ? FOR UPDATE`,
+		suspension.UserId).Scan(&status)
+	if err != nil {
+		return false, err
+	}
+	if status == userEntity.AccountSuspended {
+		return false, nil
+	}
+	suspension.PreviousStatus = status
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO Account_Suspensions(suspension_id, user_id, reason, previous_status,
+			suspended_by, suspended_at)
+		VALUES (?, ?, ?, ?, ?, ?)`, suspension.SuspensionId, suspension.UserId, suspension.Reason, suspension.PreviousStatus,
+		suspension.SuspendedBy, suspension.SuspendedAt)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE Users SET account_status = ? WHERE user_id = ?`,
+		userEntity.AccountSuspended, suspension.UserId)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (s *sqlRepo) GetSuspension(ctx context.Context, userId string) (*adminEntity.Suspension, error) {
+	var suspension adminEntity.Suspension
+	err := s.conn.QueryRowContext(ctx, `SELECT suspension_id, user_id, reason, previous_status, suspended_by, suspended_at
+		FROM Account_Suspensions
+		WHERE user_id = ? AND reactivated_at IS NULL
+		ORDER BY suspended_at DESC LIMIT 1`, userId).Scan(&suspension.SuspensionId, &suspension.UserId, &suspension.Reason,
+		&suspension.PreviousStatus, &suspension.SuspendedBy, &suspension.SuspendedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &suspension, nil
+}
+
+func (s *sqlRepo) Reactivate(ctx context.Context, userId, reactivatedBy, reactivatedAt string) (bool, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var suspensionId, previousStatus string
+	err = tx.QueryRowContext(ctx, `SELECT suspension_id, previous_status FROM Account_Suspensions
+		WHERE user_id = ? AND reactivated_at IS NULL
+		ORDER BY suspended_at DESC LIMIT 1 FOR UPDATE`, userId).Scan(&suspensionId, &previousStatus)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE Account_Suspensions SET reactivated_by = ?, reactivated_at = ?
+		WHERE suspension_id = ?`, reactivatedBy, reactivatedAt, suspensionId)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE Users SET account_status = ? WHERE user_id = ? AND account_status = ?`,
+		previousStatus, userId, userEntity.AccountSuspended)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
@@ -0,0 +1,23 @@
+package adminRepo
+
+import (
+	"context"
+	"test-va/internals/entity/adminEntity"
+)
+
+type AdminRepository interface {
+	// SearchUsers lists the users matching query and status, ordered by email.
+	SearchUsers(ctx context.Context, query, status string, limit, offset int) ([]*adminEntity.UserSummary, error)
+	GetUser(ctx context.Context, userId string) (*adminEntity.UserSummary, error)
+	// GetAssignedVA returns sql.ErrNoRows when no VA is assigned to the user.
+	GetAssignedVA(ctx context.Context, userId string) (*adminEntity.AssignedVA, error)
+	// GetActivity fills in everything of the activity but the audit logs. now tells the active sessions apart.
+	GetActivity(ctx context.Context, userId, now string) (*adminEntity.Activity, error)
+
+	// Suspend records the suspension and suspends the account, it returns false when the account is suspended already.
+	Suspend(ctx context.Context, suspension *adminEntity.Suspension) (bool, error)
+	// GetSuspension returns the suspension the account is under, or sql.ErrNoRows.
+	GetSuspension(ctx context.Context, userId string) (*adminEntity.Suspension, error)
+	// Reactivate gives the account back the status it had before the suspension, it returns false when it is not suspended.
+	Reactivate(ctx context.Context, userId, reactivatedBy, reactivatedAt string) (bool, error)
+}
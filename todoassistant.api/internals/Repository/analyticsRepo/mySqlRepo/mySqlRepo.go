@@ -0,0 +1,80 @@
+package mySqlRepo
+
+import (
+	"context"
+	"database/sql"
+
+	"test-va/internals/Repository/analyticsRepo"
+	"test-va/internals/entity/analyticsEntity"
+)
+
+type sqlRepo struct {
+	conn *sql.DB
+}
+
+func NewAnalyticsSqlRepo(conn *sql.DB) analyticsRepo.AnalyticsRepository {
+	return &sqlRepo{conn: conn}
+}
+
+func (s *sqlRepo) GetTaskRecords(ctx context.Context, userId string) ([]*analyticsEntity.TaskRecord, error) {
+	stmt := `SELECT T.task_id, COALESCE(T.project_id, ''), COALESCE(P.title, ''), T.status, T.created_at,
+				COALESCE(T.end_time, ''), COALESCE(T.completed_at, ''), COALESCE(T.va_id, ''),
+				COALESCE((SELECT MIN(C.created_at) FROM Comments C
+					WHERE C.task_id = T.task_id AND C.sender_id = T.va_id), '')
+			FROM Tasks T
+			LEFT JOIN Projects P ON P.project_id = T.project_id
+			WHERE T.user_id = ?`
+
+	rows, err := s.conn.QueryContext(ctx, stmt, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*analyticsEntity.TaskRecord
+	byId := map[string]*analyticsEntity.TaskRecord{}
+	for rows.Next() {
+		var record analyticsEntity.TaskRecord
+		err := rows.Scan(
+			&record.TaskId,
+			&record.ProjectId,
+			&record.ProjectTitle,
+			&record.Status,
+			&record.CreatedAt,
+			&record.EndTime,
+			&record.CompletedAt,
+			&record.VaId,
+			&record.FirstResponseAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, &record)
+		byId[record.TaskId] = &record
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	labelRows, err := s.conn.QueryContext(ctx, `SELECT L.task_id, L.label FROM Task_Labels L
+			JOIN Tasks T ON T.task_id = L.task_id
+			WHERE T.user_id = ?`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer labelRows.Close()
+
+	for labelRows.Next() {
+		var taskId, label string
+		if err := labelRows.Scan(&taskId, &label); err != nil {
+			return nil, err
+		}
+		if record, ok := byId[taskId]; ok {
+			record.Labels = append(record.Labels, label)
+		}
+	}
+	if err = labelRows.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,10 @@
+package analyticsRepo
+
+import (
+	"context"
+	"test-va/internals/entity/analyticsEntity"
+)
+
+type AnalyticsRepository interface {
+	GetTaskRecords(ctx context.Context, userId string) ([]*analyticsEntity.TaskRecord, error)
+}
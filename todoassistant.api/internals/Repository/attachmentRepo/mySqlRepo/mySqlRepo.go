@@ -0,0 +1,131 @@
+package mySqlRepo
+
+import (
+	"context"
+	"database/sql"
+
+	"test-va/internals/Repository/attachmentRepo"
+	"test-va/internals/entity/attachmentEntity"
+)
+
+type sqlRepo struct {
+	conn *sql.DB
+}
+
+func NewAttachmentSqlRepo(conn *sql.DB) attachmentRepo.AttachmentRepository {
+	return &sqlRepo{conn: conn}
+}
+
+const attachmentColumns = `attachment_id, COALESCE(task_id, ''), COALESCE(comment_id, ''), uploader_id,
+	file_name, mime_type, size, checksum, storage_key, status, created_at`
+
+func (s *sqlRepo) Persist(ctx context.Context, req *attachmentEntity.CreateUploadReq) error {
+	stmt := `INSERT INTO Attachments(
+				attachment_id,
+				task_id,
+				comment_id,
+				uploader_id,
+				file_name,
+				mime_type,
+				size,
+				checksum,
+				storage_key,
+				status,
+				created_at
+			) VALUES (?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := s.conn.ExecContext(ctx, stmt, req.AttachmentId, req.TaskId, req.CommentId, req.UploaderId,
+		req.FileName, req.MimeType, req.Size, req.Checksum, req.StorageKey, req.Status, req.CreatedAt)
+	return err
+}
+
+func (s *sqlRepo) GetById(ctx context.Context, attachmentId string) (*attachmentEntity.AttachmentRes, error) {
+	stmt := `SELECT ` + attachmentColumns + ` FROM Attachments WHERE attachment_id = ?`
+
+	var res attachmentEntity.AttachmentRes
+	err := s.conn.QueryRowContext(ctx, stmt, attachmentId).Scan(
+		&res.AttachmentId,
+		&res.TaskId,
+		&res.CommentId,
+		&res.UploaderId,
+		&res.FileName,
+		&res.MimeType,
+		&res.Size,
+		&res.Checksum,
+		&res.StorageKey,
+		&res.Status,
+		&res.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func (s *sqlRepo) MarkUploaded(ctx context.Context, attachmentId string) error {
+	_, err := s.conn.ExecContext(ctx, `UPDATE Attachments SET status = ? WHERE attachment_id = ?`,
+		attachmentEntity.StatusUploaded, attachmentId)
+	return err
+}
+
+func (s *sqlRepo) ListByTask(ctx context.Context, taskId string) ([]*attachmentEntity.AttachmentRes, error) {
+	stmt := `SELECT ` + attachmentColumns + ` FROM Attachments
+			WHERE task_id = ? AND status = ?
+			ORDER BY created_at`
+	return s.list(ctx, stmt, taskId, attachmentEntity.StatusUploaded)
+}
+
+func (s *sqlRepo) ListByComment(ctx context.Context, commentId string) ([]*attachmentEntity.AttachmentRes, error) {
+	stmt := `SELECT ` + attachmentColumns + ` FROM Attachments
+			WHERE comment_id = ? AND status = ?
+			ORDER BY created_at`
+	return s.list(ctx, stmt, commentId, attachmentEntity.StatusUploaded)
+}
+
+func (s *sqlRepo) GetCommentTaskId(ctx context.Context, commentId string) (string, error) {
+	var taskId string
+	err := s.conn.QueryRowContext(ctx, `SELECT task_id FROM Comments WHERE id = ?`, commentId).Scan(&taskId)
+	if err != nil {
+		return "", err
+	}
+	return taskId, nil
+}
+
+func (s *sqlRepo) Delete(ctx context.Context, attachmentId string) error {
+	_, err := s.conn.ExecContext(ctx, `DELETE FROM Attachments WHERE attachment_id = ?`, attachmentId)
+	return err
+}
+
+func (s *sqlRepo) list(ctx context.Context, stmt string, args ...any) ([]*attachmentEntity.AttachmentRes, error) {
+	rows, err := s.conn.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*attachmentEntity.AttachmentRes
+	for rows.Next() {
+		var res attachmentEntity.AttachmentRes
+		err := rows.Scan(
+			&res.AttachmentId,
+			&res.TaskId,
+			&res.CommentId,
+			&res.UploaderId,
+			&res.FileName,
+			&res.MimeType,
+			&res.Size,
+			&res.Checksum,
+			&res.StorageKey,
+			&res.Status,
+			&res.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, &res)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
@@ -0,0 +1,16 @@
+package attachmentRepo
+
+import (
+	"context"
+	"test-va/internals/entity/attachmentEntity"
+)
+
+type AttachmentRepository interface {
+	Persist(ctx context.Context, req *attachmentEntity.CreateUploadReq) error
+	GetById(ctx context.Context, attachmentId string) (*attachmentEntity.AttachmentRes, error)
+	MarkUploaded(ctx context.Context, attachmentId string) error
+	ListByTask(ctx context.Context, taskId string) ([]*attachmentEntity.AttachmentRes, error)
+	ListByComment(ctx context.Context, commentId string) ([]*attachmentEntity.AttachmentRes, error)
+	GetCommentTaskId(ctx context.Context, commentId string) (string, error)
+	Delete(ctx context.Context, attachmentId string) error
+}
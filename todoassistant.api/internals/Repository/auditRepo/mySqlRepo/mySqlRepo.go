@@ -0,0 +1,82 @@
+package mySqlRepo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"test-va/internals/Repository/auditRepo"
+	"test-va/internals/entity/loggerEntity"
+)
+
+type sqlRepo struct {
+	conn *sql.DB
+}
+
+func NewAuditSqlRepo(conn *sql.DB) auditRepo.AuditRepository {
+	return &sqlRepo{conn: conn}
+}
+
+func (s *sqlRepo) AddLog(ctx context.Context, log *loggerEntity.AuditLog) error {
+	_, err := s.conn.ExecContext(ctx, `INSERT INTO Audit_Logs(action, outcome, actor_id, actor_role, target_type,
+			target_id, detail, ip_address, user_agent, status_code, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, log.Action, log.Outcome, log.ActorId, log.ActorRole, log.TargetType,
+		log.TargetId, log.Detail, log.IPAddress, log.UserAgent, log.StatusCode, log.CreatedAt)
+	return err
+}
+
+func (s *sqlRepo) GetLogs(ctx context.Context, filter *loggerEntity.AuditFilter, limit int) ([]*loggerEntity.AuditLog, error) {
+	var where []string
+	var args []any
+	for _, field := range []struct {
+		condition string
+		value     string
+	}{
+		{"action = ?", filter.Action},
+		{"outcome = ?", filter.Outcome},
+		{"actor_id = ?", filter.ActorId},
+		{"target_id = ?", filter.TargetId},
+		{"ip_address = ?", filter.IPAddress},
+		{"created_at >= ?", filter.From},
+	} {
+		if field.value != "" {
+			where = append(where, field.condition)
+			args = append(args, field.value)
+		}
+	}
+	// to is a day, every log of it is included
+	if filter.To != "" {
+		where = append(where, "created_at <= ?")
+		args = append(args, filter.To+"T23:59:59Z")
+	}
+	if filter.Before != 0 {
+		where = append(where, "id < ?")
+		args = append(args, filter.Before)
+	}
+
+	query := `SELECT id, action, outcome, actor_id, actor_role, target_type, target_id, detail, ip_address,
+		user_agent, status_code, created_at FROM Audit_Logs`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := s.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	logs := []*loggerEntity.AuditLog{}
+	for rows.Next() {
+		var log loggerEntity.AuditLog
+		err := rows.Scan(&log.Id, &log.Action, &log.Outcome, &log.ActorId, &log.ActorRole, &log.TargetType,
+			&log.TargetId, &log.Detail, &log.IPAddress, &log.UserAgent, &log.StatusCode, &log.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, &log)
+	}
+	return logs, rows.Err()
+}
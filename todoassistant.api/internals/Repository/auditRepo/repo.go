@@ -0,0 +1,13 @@
+package auditRepo
+
+import (
+	"context"
+	"test-va/internals/entity/loggerEntity"
+)
+
+// AuditRepository keeps the audit log. It has no way to change or delete a log on purpose.
+type AuditRepository interface {
+	AddLog(ctx context.Context, log *loggerEntity.AuditLog) error
+	// GetLogs returns up to limit logs matching filter, newest first.
+	GetLogs(ctx context.Context, filter *loggerEntity.AuditFilter, limit int) ([]*loggerEntity.AuditLog, error)
+}
@@ -0,0 +1,120 @@
+package mySqlRepo
+
+import (
+	"context"
+	"database/sql"
+
+	"test-va/internals/Repository/digestRepo"
+	"test-va/internals/entity/digestEntity"
+)
+
+type sqlRepo struct {
+	conn *sql.DB
+}
+
+func NewDigestSqlRepo(conn *sql.DB) digestRepo.DigestRepository {
+	return &sqlRepo{conn: conn}
+}
+
+func (s *sqlRepo) GetRecipients(ctx context.Context) ([]*digestEntity.Recipient, error) {
+	stmt := `SELECT U.user_id, U.email, U.first_name,
+				COALESCE(R.reminderTime, ''), COALESCE(R.time_zone, 'UTC'), COALESCE(D.last_sent, '')
+			FROM Users U
+			JOIN Product_Email_Settings P ON P.user_id = U.user_id
+			LEFT JOIN Reminder_Settings R ON R.user_id = U.user_id
+			LEFT JOIN (SELECT user_id, MAX(digest_date) AS last_sent FROM Digest_Log GROUP BY user_id) D
+				ON D.user_id = U.user_id
+			WHERE P.tips_daily_digest = 1`
+
+	rows, err := s.conn.QueryContext(ctx, stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []*digestEntity.Recipient
+	for rows.Next() {
+		var r digestEntity.Recipient
+		if err := rows.Scan(&r.UserId, &r.Email, &r.FirstName, &r.ReminderTime, &r.TimeZone, &r.LastSentOn); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, &r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return recipients, nil
+}
+
+func (s *sqlRepo) GetTasks(ctx context.Context, userId, completedSince string) ([]*digestEntity.DigestTask, error) {
+	stmt := `SELECT task_id, title, status, COALESCE(end_time, ''), COALESCE(completed_at, '')
+			FROM Tasks
+			WHERE user_id = ? AND (status <> 'COMPLETED' OR completed_at >= ?)
+			ORDER BY end_time`
+
+	rows, err := s.conn.QueryContext(ctx, stmt, userId, completedSince)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []*digestEntity.DigestTask
+	for rows.Next() {
+		var t digestEntity.DigestTask
+		if err := rows.Scan(&t.TaskId, &t.Title, &t.Status, &t.EndTime, &t.CompletedAt); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, &t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (s *sqlRepo) GetVAComments(ctx context.Context, userId, since string) ([]*digestEntity.DigestComment, error) {
+	stmt := `SELECT T.task_id, T.title, CONCAT(V.first_name, ' ', V.last_name), C.comment, C.created_at
+			FROM Comments C
+			JOIN Tasks T ON T.task_id = C.task_id
+			JOIN va_table V ON V.va_id = C.sender_id
+			WHERE T.user_id = ? AND C.sender_id = T.va_id AND C.created_at >= ?
+			ORDER BY C.created_at DESC
+			LIMIT 20`
+
+	rows, err := s.conn.QueryContext(ctx, stmt, userId, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*digestEntity.DigestComment
+	for rows.Next() {
+		var c digestEntity.DigestComment
+		if err := rows.Scan(&c.TaskId, &c.TaskTitle, &c.VaName, &c.Comment, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		comments = append(comments, &c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (s *sqlRepo) ClaimDigest(ctx context.Context, userId, date, sentAt string) (bool, error) {
+	res, err := s.conn.ExecContext(ctx, `INSERT IGNORE INTO Digest_Log(user_id, digest_date, sent_at) VALUES (?, ?, ?)`,
+		userId, date, sentAt)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (s *sqlRepo) ReleaseDigest(ctx context.Context, userId, date string) error {
+	_, err := s.conn.ExecContext(ctx, `DELETE FROM Digest_Log WHERE user_id = ? AND digest_date = ?`, userId, date)
+	return err
+}
@@ -0,0 +1,15 @@
+package digestRepo
+
+import (
+	"context"
+	"test-va/internals/entity/digestEntity"
+)
+
+type DigestRepository interface {
+	GetRecipients(ctx context.Context) ([]*digestEntity.Recipient, error)
+	GetTasks(ctx context.Context, userId, completedSince string) ([]*digestEntity.DigestTask, error)
+	GetVAComments(ctx context.Context, userId, since string) ([]*digestEntity.DigestComment, error)
+	// ClaimDigest records the digest of a user for a local date, it returns false when one was already recorded.
+	ClaimDigest(ctx context.Context, userId, date, sentAt string) (bool, error)
+	ReleaseDigest(ctx context.Context, userId, date string) error
+}
@@ -0,0 +1,85 @@
+package memoryRepo
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"test-va/internals/Repository/loginAttemptRepo"
+	"test-va/internals/entity/loginAttemptEntity"
+)
+
+// memoryRepo keeps failed logins in the process. Every instance counts on its own and
+// forgets on restart, so it suits a single instance or development.
+type memoryRepo struct {
+	mu       sync.Mutex
+	attempts map[string]*loginAttemptEntity.Attempts
+}
+
+func NewLoginAttemptMemoryRepo() loginAttemptRepo.LoginAttemptRepository {
+	return &memoryRepo{attempts: map[string]*loginAttemptEntity.Attempts{}}
+}
+
+func (m *memoryRepo) Get(ctx context.Context, key string) (*loginAttemptEntity.Attempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempts, ok := m.attempts[key]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *attempts
+	return &copied, nil
+}
+
+func (m *memoryRepo) RecordFailure(ctx context.Context, key, failedAt, windowStart string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempts, ok := m.attempts[key]
+	if !ok {
+		attempts = &loginAttemptEntity.Attempts{Key: key}
+		m.attempts[key] = attempts
+	}
+	if attempts.LastFailureAt < windowStart {
+		attempts.Failures = 0
+	}
+	attempts.Failures++
+	attempts.LastFailureAt = failedAt
+	return attempts.Failures, nil
+}
+
+func (m *memoryRepo) Block(ctx context.Context, key, blockedUntil, lockedUntil string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempts, ok := m.attempts[key]
+	if !ok {
+		return nil
+	}
+	attempts.BlockedUntil = blockedUntil
+	if lockedUntil != "" {
+		attempts.LockedUntil = lockedUntil
+	}
+	return nil
+}
+
+func (m *memoryRepo) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+	return nil
+}
+
+func (m *memoryRepo) DeleteStale(ctx context.Context, before string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, attempts := range m.attempts {
+		if attempts.LastFailureAt < before && attempts.BlockedUntil < before && attempts.LockedUntil < before {
+			delete(m.attempts, key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package mySqlRepo
+
+import (
+	"context"
+	"database/sql"
+
+	"test-va/internals/Repository/loginAttemptRepo"
+	"test-va/internals/entity/loginAttemptEntity"
+)
+
+type sqlRepo struct {
+	conn *sql.DB
+}
+
+func NewLoginAttemptSqlRepo(conn *sql.DB) loginAttemptRepo.LoginAttemptRepository {
+	return &sqlRepo{conn: conn}
+}
+
+func (s *sqlRepo) Get(ctx context.Context, key string) (*loginAttemptEntity.Attempts, error) {
+	var attempts loginAttemptEntity.Attempts
+	err := s.conn.QueryRowContext(ctx, `SELECT attempt_key, failures, last_failure_at,
+			COALESCE(blocked_until, ''), COALESCE(locked_until, '')
+		FROM Login_Attempts WHERE attempt_key = ?`, key).Scan(
+		&attempts.Key, &attempts.Failures, &attempts.LastFailureAt, &attempts.BlockedUntil, &attempts.LockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	return &attempts, nil
+}
+
+func (s *sqlRepo) RecordFailure(ctx context.Context, key, failedAt, windowStart string) (int, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	// the count is read back in the same transaction, so concurrent failures each see their own count
+	_, err = tx.ExecContext(ctx, `INSERT INTO Login_Attempts(attempt_key, failures, last_failure_at)
+		VALUES (?, 1, ?)
+		ON DUPLICATE KEY UPDATE
+			failures = IF(last_failure_at < ?, 1, failures + 1),
+			last_failure_at = VALUES(last_failure_at)`,
+		key, failedAt, windowStart)
+	if err != nil {
+		return 0, err
+	}
+
+	var failures int
+	err = tx.QueryRowContext(ctx, `SELECT failures FROM Login_Attempts WHERE attempt_key = ?`, key).Scan(&failures)
+	if err != nil {
+		return 0, err
+	}
+	return failures, nil
+}
+
+func (s *sqlRepo) Block(ctx context.Context, key, blockedUntil, lockedUntil string) error {
+	_, err := s.conn.ExecContext(ctx, `UPDATE Login_Attempts
+		SET blocked_until = NULLIF(?, ''), locked_until = COALESCE(NULLIF(?, ''), locked_until)
+		WHERE attempt_key = ?`, blockedUntil, lockedUntil, key)
+	return err
+}
+
+func (s *sqlRepo) Delete(ctx context.Context, key string) error {
+	_, err := s.conn.ExecContext(ctx, `DELETE FROM Login_Attempts WHERE attempt_key = ?`, key)
+	return err
+}
+
+func (s *sqlRepo) DeleteStale(ctx context.Context, before string) error {
+	_, err := s.conn.ExecContext(ctx, `DELETE FROM Login_Attempts
+		WHERE last_failure_at < ?
+			AND (blocked_until IS NULL OR blocked_until < ?)
+			AND (locked_until IS NULL OR locked_until < ?)`, before, before, before)
+	return err
+}
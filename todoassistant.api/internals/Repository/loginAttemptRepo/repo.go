@@ -0,0 +1,19 @@
+package loginAttemptRepo
+
+import (
+	"context"
+	"test-va/internals/entity/loginAttemptEntity"
+)
+
+// LoginAttemptRepository stores failed logins. It is kept in MySQL, or in memory for a single instance.
+type LoginAttemptRepository interface {
+	// Get returns the failures recorded for a key, sql.ErrNoRows when there are none.
+	Get(ctx context.Context, key string) (*loginAttemptEntity.Attempts, error)
+	// RecordFailure counts a failure and returns the count, starting over when the last one was before windowStart.
+	RecordFailure(ctx context.Context, key, failedAt, windowStart string) (int, error)
+	// Block sets when the key may be tried again, and until when the account is locked.
+	Block(ctx context.Context, key, blockedUntil, lockedUntil string) error
+	Delete(ctx context.Context, key string) error
+	// DeleteStale forgets keys that last failed before and are not blocked or locked anymore.
+	DeleteStale(ctx context.Context, before string) error
+}
@@ -26,14 +26,15 @@ type mySql struct {
 // }
 
 func (m *mySql) Persist(req *notificationEntity.CreateNotification) error {
-	stmt := fmt.Sprintf(` 
+	// a device registering again moves to the session it registered from
+	_, err := m.conn.Exec(`
 		INSERT INTO Notification_Tokens(
         	notification_token_id,
         	user_id,
-			device_id
-            ) VALUES ('%v', '%v', '%v')`, req.NotificationId, req.UserId, req.DeviceId)
-
-	_, err := m.conn.Exec(stmt)
+			device_id,
+			session_id
+            ) VALUES (?, ?, ?, NULLIF(?, ''))
+		ON DUPLICATE KEY UPDATE session_id = VALUES(session_id)`, req.NotificationId, req.UserId, req.DeviceId, req.SessionId)
 	if err != nil {
 		if strings.Contains(err.Error(), "Duplicate entry") {
 			return nil
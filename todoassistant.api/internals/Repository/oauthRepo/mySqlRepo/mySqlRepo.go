@@ -0,0 +1,116 @@
+package mySqlRepo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"test-va/internals/Repository/oauthRepo"
+	"test-va/internals/entity/oauthEntity"
+)
+
+type sqlRepo struct {
+	conn *sql.DB
+}
+
+func NewOAuthSqlRepo(conn *sql.DB) oauthRepo.OAuthRepository {
+	return &sqlRepo{conn: conn}
+}
+
+func (s *sqlRepo) AddClient(ctx context.Context, client *oauthEntity.Client) error {
+	_, err := s.conn.ExecContext(ctx, `INSERT INTO OAuth_Clients(client_id, owner_id, name, redirect_uris,
+			confidential, secret_hash, created_at)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), ?)`, client.ClientId, client.OwnerId, client.Name,
+		strings.Join(client.RedirectUris, "\n"), client.Confidential, client.SecretHash, client.CreatedAt)
+	return err
+}
+
+const selectClient = `SELECT client_id, owner_id, name, redirect_uris, confidential, COALESCE(secret_hash, ''), created_at
+	FROM OAuth_Clients`
+
+func (s *sqlRepo) GetClient(ctx context.Context, clientId string) (*oauthEntity.Client, error) {
+	return scanClient(s.conn.QueryRowContext(ctx, selectClient+` WHERE client_id = ?`, clientId))
+}
+
+func (s *sqlRepo) GetClients(ctx context.Context, ownerId string) ([]*oauthEntity.Client, error) {
+	rows, err := s.conn.QueryContext(ctx, selectClient+` WHERE owner_id = ? ORDER BY created_at`, ownerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	clients := []*oauthEntity.Client{}
+	for rows.Next() {
+		client, err := scanClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return clients, rows.Err()
+}
+
+func scanClient(row interface{ Scan(...any) error }) (*oauthEntity.Client, error) {
+	var client oauthEntity.Client
+	var redirectUris string
+	err := row.Scan(&client.ClientId, &client.OwnerId, &client.Name, &redirectUris, &client.Confidential,
+		&client.SecretHash, &client.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	client.RedirectUris = strings.Split(redirectUris, "\n")
+	return &client, nil
+}
+
+func (s *sqlRepo) DeleteClient(ctx context.Context, ownerId, clientId string) (bool, error) {
+	res, err := s.conn.ExecContext(ctx, `DELETE FROM OAuth_Clients WHERE client_id = ? AND owner_id = ?`,
+		clientId, ownerId)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (s *sqlRepo) AddCode(ctx context.Context, code *oauthEntity.AuthCode) error {
+	_, err := s.conn.ExecContext(ctx, `INSERT INTO OAuth_Codes(code_hash, client_id, user_id, redirect_uri, scopes,
+			code_challenge, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`, code.CodeHash, code.ClientId, code.UserId, code.RedirectUri,
+		strings.Join(code.Scopes, " "), code.CodeChallenge, code.ExpiresAt, code.CreatedAt)
+	return err
+}
+
+func (s *sqlRepo) GetCode(ctx context.Context, codeHash string) (*oauthEntity.AuthCode, error) {
+	var code oauthEntity.AuthCode
+	var scopes string
+	err := s.conn.QueryRowContext(ctx, `SELECT code_hash, client_id, user_id, redirect_uri, scopes, code_challenge,
+			expires_at, created_at, COALESCE(used_at, '')
+		FROM OAuth_Codes WHERE code_hash = ?`, codeHash).Scan(&code.CodeHash, &code.ClientId, &code.UserId,
+		&code.RedirectUri, &scopes, &code.CodeChallenge, &code.ExpiresAt, &code.CreatedAt, &code.UsedAt)
+	if err != nil {
+		return nil, err
+	}
+	code.Scopes = strings.Fields(scopes)
+	return &code, nil
+}
+
+func (s *sqlRepo) UseCode(ctx context.Context, codeHash, usedAt string) (bool, error) {
+	res, err := s.conn.ExecContext(ctx, `UPDATE OAuth_Codes SET used_at = ? WHERE code_hash = ? AND used_at IS NULL`,
+		usedAt, codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (s *sqlRepo) DeleteExpiredCodes(ctx context.Context, before string) error {
+	_, err := s.conn.ExecContext(ctx, `DELETE FROM OAuth_Codes WHERE expires_at < ?`, before)
+	return err
+}
@@ -0,0 +1,20 @@
+package oauthRepo
+
+import (
+	"context"
+	"test-va/internals/entity/oauthEntity"
+)
+
+type OAuthRepository interface {
+	AddClient(ctx context.Context, client *oauthEntity.Client) error
+	GetClient(ctx context.Context, clientId string) (*oauthEntity.Client, error)
+	GetClients(ctx context.Context, ownerId string) ([]*oauthEntity.Client, error)
+	// DeleteClient returns false when the owner has no such client.
+	DeleteClient(ctx context.Context, ownerId, clientId string) (bool, error)
+
+	AddCode(ctx context.Context, code *oauthEntity.AuthCode) error
+	GetCode(ctx context.Context, codeHash string) (*oauthEntity.AuthCode, error)
+	// UseCode spends a code, it returns false when the code was used already.
+	UseCode(ctx context.Context, codeHash, usedAt string) (bool, error)
+	DeleteExpiredCodes(ctx context.Context, before string) error
+}
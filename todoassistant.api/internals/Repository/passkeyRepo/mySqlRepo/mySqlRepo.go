@@ -0,0 +1,128 @@
+package mySqlRepo
+
+import (
+	"context"
+	"database/sql"
+
+	"test-va/internals/Repository/passkeyRepo"
+	"test-va/internals/entity/passkeyEntity"
+)
+
+type sqlRepo struct {
+	conn *sql.DB
+}
+
+func NewPasskeySqlRepo(conn *sql.DB) passkeyRepo.PasskeyRepository {
+	return &sqlRepo{conn: conn}
+}
+
+func (s *sqlRepo) Persist(ctx context.Context, passkey *passkeyEntity.Passkey) error {
+	_, err := s.conn.ExecContext(ctx, `INSERT INTO Passkeys(credential_id, account_id, account_type, name, public_key,
+			algorithm, sign_count, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		passkey.CredentialId, passkey.AccountId, passkey.AccountType, passkey.Name, passkey.PublicKey,
+		passkey.Algorithm, passkey.SignCount, passkey.CreatedAt)
+	return err
+}
+
+const passkeyColumns = `credential_id, account_id, account_type, name, public_key, algorithm, sign_count, created_at,
+	COALESCE(last_used_at, '')`
+
+func scanPasskey(row interface{ Scan(...interface{}) error }) (*passkeyEntity.Passkey, error) {
+	var passkey passkeyEntity.Passkey
+	err := row.Scan(&passkey.CredentialId, &passkey.AccountId, &passkey.AccountType, &passkey.Name, &passkey.PublicKey,
+		&passkey.Algorithm, &passkey.SignCount, &passkey.CreatedAt, &passkey.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &passkey, nil
+}
+
+func (s *sqlRepo) Get(ctx context.Context, credentialId string) (*passkeyEntity.Passkey, error) {
+	row := s.conn.QueryRowContext(ctx, `SELECT `+passkeyColumns+` FROM Passkeys WHERE credential_id = ?`, credentialId)
+	return scanPasskey(row)
+}
+
+func (s *sqlRepo) GetByAccount(ctx context.Context, accountId, accountType string) ([]*passkeyEntity.Passkey, error) {
+	rows, err := s.conn.QueryContext(ctx, `SELECT `+passkeyColumns+` FROM Passkeys
+		WHERE account_id = ? AND account_type = ? ORDER BY created_at`, accountId, accountType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passkeys := []*passkeyEntity.Passkey{}
+	for rows.Next() {
+		passkey, err := scanPasskey(rows)
+		if err != nil {
+			return nil, err
+		}
+		passkeys = append(passkeys, passkey)
+	}
+	return passkeys, rows.Err()
+}
+
+func (s *sqlRepo) UpdateSignCount(ctx context.Context, credentialId string, signCount uint32, usedAt string) (bool, error) {
+	// a count of 0 means the authenticator keeps no counter
+	res, err := s.conn.ExecContext(ctx, `UPDATE Passkeys SET sign_count = ?, last_used_at = ?
+		WHERE credential_id = ? AND (sign_count < ? OR (sign_count = 0 AND ? = 0))`,
+		signCount, usedAt, credentialId, signCount, signCount)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (s *sqlRepo) Rename(ctx context.Context, credentialId, name string) error {
+	_, err := s.conn.ExecContext(ctx, `UPDATE Passkeys SET name = ? WHERE credential_id = ?`, name, credentialId)
+	return err
+}
+
+func (s *sqlRepo) Delete(ctx context.Context, credentialId string) error {
+	_, err := s.conn.ExecContext(ctx, `DELETE FROM Passkeys WHERE credential_id = ?`, credentialId)
+	return err
+}
+
+func (s *sqlRepo) AddChallenge(ctx context.Context, challenge *passkeyEntity.Challenge) error {
+	_, err := s.conn.ExecContext(ctx, `INSERT INTO Passkey_Challenges(challenge_hash, account_id, account_type, ceremony, expires_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		challenge.ChallengeHash, challenge.AccountId, challenge.AccountType, challenge.Ceremony, challenge.ExpiresAt)
+	return err
+}
+
+func (s *sqlRepo) TakeChallenge(ctx context.Context, challengeHash string) (*passkeyEntity.Challenge, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	var challenge passkeyEntity.Challenge
+	err = tx.QueryRowContext(ctx, `SELECT challenge_hash, account_id, account_type, ceremony, expires_at
+		FROM Passkey_Challenges WHERE challenge_hash = ? FOR UPDATE`, challengeHash).Scan(
+		&challenge.ChallengeHash, &challenge.AccountId, &challenge.AccountType, &challenge.Ceremony, &challenge.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM Passkey_Challenges WHERE challenge_hash = ?`, challengeHash)
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+func (s *sqlRepo) DeleteExpiredChallenges(ctx context.Context, now string) error {
+	_, err := s.conn.ExecContext(ctx, `DELETE FROM Passkey_Challenges WHERE expires_at < ?`, now)
+	return err
+}
@@ -0,0 +1,20 @@
+package passkeyRepo
+
+import (
+	"context"
+	"test-va/internals/entity/passkeyEntity"
+)
+
+type PasskeyRepository interface {
+	Persist(ctx context.Context, passkey *passkeyEntity.Passkey) error
+	Get(ctx context.Context, credentialId string) (*passkeyEntity.Passkey, error)
+	GetByAccount(ctx context.Context, accountId, accountType string) ([]*passkeyEntity.Passkey, error)
+	// UpdateSignCount records a login, it returns false when another login stored a higher count meanwhile.
+	UpdateSignCount(ctx context.Context, credentialId string, signCount uint32, usedAt string) (bool, error)
+	Rename(ctx context.Context, credentialId, name string) error
+	Delete(ctx context.Context, credentialId string) error
+	AddChallenge(ctx context.Context, challenge *passkeyEntity.Challenge) error
+	// TakeChallenge returns the challenge and deletes it, so it cannot be answered twice.
+	TakeChallenge(ctx context.Context, challengeHash string) (*passkeyEntity.Challenge, error)
+	DeleteExpiredChallenges(ctx context.Context, now string) error
+}
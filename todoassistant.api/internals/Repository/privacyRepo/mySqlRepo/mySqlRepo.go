@@ -0,0 +1,380 @@
+package mySqlRepo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"test-va/internals/Repository/privacyRepo"
+	"test-va/internals/entity/privacyEntity"
+)
+
+type sqlRepo struct {
+	conn *sql.DB
+}
+
+func NewPrivacySqlRepo(conn *sql.DB) privacyRepo.PrivacyRepository {
+	return &sqlRepo{conn: conn}
+}
+
+const requestColumns = `request_id, user_id, kind, status, requested_by, archive_key, requested_at,
+	scheduled_for, COALESCE(completed_at, ''), COALESCE(expires_at, '')`
+
+func (s *sqlRepo) AddRequest(ctx context.Context, req *privacyEntity.Request) error {
+	_, err := s.conn.ExecContext(ctx, `INSERT INTO Privacy_Requests(request_id, user_id, kind, status, requested_by,
+			archive_key, requested_at, scheduled_for, completed_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))`,
+		req.RequestId, req.UserId, req.Kind, req.Status, req.RequestedBy, req.ArchiveKey, req.RequestedAt,
+		req.ScheduledFor, req.CompletedAt, req.ExpiresAt)
+	return err
+}
+
+func (s *sqlRepo) GetRequest(ctx context.Context, requestId string) (*privacyEntity.Request, error) {
+	row := s.conn.QueryRowContext(ctx, `SELECT `+requestColumns+` FROM Privacy_Requests WHERE request_id = ?`, requestId)
+	return scanRequest(row)
+}
+
+func (s *sqlRepo) GetOpenRequest(ctx context.Context, userId, kind string) (*privacyEntity.Request, error) {
+	row := s.conn.QueryRowContext(ctx, `SELECT `+requestColumns+` FROM Privacy_Requests
+		WHERE user_id = ? AND kind = ? AND status = ?
+		ORDER BY requested_at DESC LIMIT 1`, userId, kind, privacyEntity.StatusPending)
+	return scanRequest(row)
+}
+
+func (s *sqlRepo) DueRequests(ctx context.Context, kind, now string) ([]*privacyEntity.Request, error) {
+	return s.list(ctx, `SELECT `+requestColumns+` FROM Privacy_Requests
+		WHERE kind = ? AND status = ? AND scheduled_for <= ?
+		ORDER BY scheduled_for`, kind, privacyEntity.StatusPending, now)
+}
+
+func (s *sqlRepo) ExpiredExports(ctx context.Context, now string) ([]*privacyEntity.Request, error) {
+	return s.list(ctx, `SELECT `+requestColumns+` FROM Privacy_Requests
+		WHERE kind = ? AND status = ? AND expires_at < ?`, privacyEntity.KindExport, privacyEntity.StatusReady, now)
+}
+
+func (s *sqlRepo) UpdateRequest(ctx context.Context, req *privacyEntity.Request) error {
+	_, err := s.conn.ExecContext(ctx, `UPDATE Privacy_Requests SET status = ?, archive_key = ?,
+			completed_at = NULLIF(?, ''), expires_at = NULLIF(?, '')
+		WHERE request_id = ?`,
+		req.Status, req.ArchiveKey, req.CompletedAt, req.ExpiresAt, req.RequestId)
+	return err
+}
+
+func (s *sqlRepo) list(ctx context.Context, stmt string, args ...any) ([]*privacyEntity.Request, error) {
+	rows, err := s.conn.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []*privacyEntity.Request
+	for rows.Next() {
+		req, err := scanRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	return requests, rows.Err()
+}
+
+func scanRequest(row interface{ Scan(...any) error }) (*privacyEntity.Request, error) {
+	var req privacyEntity.Request
+	err := row.Scan(
+		&req.RequestId,
+		&req.UserId,
+		&req.Kind,
+		&req.Status,
+		&req.RequestedBy,
+		&req.ArchiveKey,
+		&req.RequestedAt,
+		&req.ScheduledFor,
+		&req.CompletedAt,
+		&req.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// userTasks and userProjects pick the rows that belong to the user, every ? in them is the user id
+const (
+	userTasks     = `SELECT task_id FROM Tasks WHERE user_id = ?`
+	userProjects  = `SELECT project_id FROM Projects WHERE user_id = ?`
+	userTemplates = `SELECT template_id FROM Project_Templates WHERE user_id = ?`
+	// the user's attachments, and those of others on the user's tasks and their comments
+	userAttachments = `uploader_id = ? OR task_id IN (` + userTasks + `)
+		OR comment_id IN (SELECT id FROM Comments WHERE task_id IN (` + userTasks + `))`
+)
+
+// exportQueries reads the user's data, table by table. Every ? is the user id.
+var exportQueries = []struct {
+	table string
+	query string
+}{
+	{"Users", `SELECT * FROM Users WHERE user_id = ?`},
+	{"User_Settings", `SELECT * FROM User_Settings WHERE user_id = ?`},
+	{"Reminder_Settings", `SELECT * FROM Reminder_Settings WHERE user_id = ?`},
+	{"Notification_Settings", `SELECT * FROM Notification_Settings WHERE user_id = ?`},
+	{"Product_Email_Settings", `SELECT * FROM Product_Email_Settings WHERE user_id = ?`},
+	{"Tasks", `SELECT * FROM Tasks WHERE user_id = ?`},
+	{"Task_Labels", `SELECT * FROM Task_Labels WHERE task_id IN (` + userTasks + `)`},
+	{"Taskfiles", `SELECT * FROM Taskfiles WHERE task_id IN (` + userTasks + `)`},
+	{"Task_Time_Logs", `SELECT * FROM Task_Time_Logs WHERE user_id = ?`},
+	{"Comments", `SELECT * FROM Comments WHERE sender_id = ? OR task_id IN (` + userTasks + `)`},
+	{"Attachments", `SELECT * FROM Attachments WHERE ` + userAttachments},
+	{"Projects", `SELECT * FROM Projects WHERE user_id = ?`},
+	{"Project_Sections", `SELECT * FROM Project_Sections WHERE project_id IN (` + userProjects + `)`},
+	{"Project_Members", `SELECT * FROM Project_Members WHERE user_id = ? OR project_id IN (` + userProjects + `)`},
+	{"Project_Invitations", `SELECT * FROM Project_Invitations WHERE invited_by = ?
+		OR email = (SELECT email FROM Users WHERE user_id = ?)`},
+	{"Project_Templates", `SELECT * FROM Project_Templates WHERE user_id = ?`},
+	{"Project_Template_Tasks", `SELECT * FROM Project_Template_Tasks WHERE template_id IN (` + userTemplates + `)`},
+	{"Notifications", `SELECT * FROM Notifications WHERE user_id = ?`},
+	{"Notification_Tokens", `SELECT * FROM Notification_Tokens WHERE user_id = ?`},
+	{"Weekly_Reports", `SELECT * FROM Weekly_Reports WHERE owner_id = ?`},
+	{"Sessions", `SELECT * FROM Sessions WHERE user_id = ?`},
+	{"Personal_Access_Tokens", `SELECT * FROM Personal_Access_Tokens WHERE user_id = ?`},
+	{"OAuth_Clients", `SELECT * FROM OAuth_Clients WHERE owner_id = ?`},
+	{"Social_Identities", `SELECT * FROM Social_Identities WHERE user_id = ?`},
+	{"Passkeys", `SELECT * FROM Passkeys WHERE account_id = ?`},
+	{"Two_Factor", `SELECT * FROM Two_Factor WHERE account_id = ?`},
+	{"Calls", `SELECT * FROM Calls WHERE user_id = ?`},
+	{"Account_Suspensions", `SELECT * FROM Account_Suspensions WHERE user_id = ?`},
+	{"Privacy_Requests", `SELECT * FROM Privacy_Requests WHERE user_id = ?`},
+}
+
+// secretColumns are never exported, they let whoever holds them log in or read a token.
+var secretColumns = map[string]bool{
+	"password":       true,
+	"token":          true,
+	"token_hash":     true,
+	"secret":         true,
+	"secret_hash":    true,
+	"code_hash":      true,
+	"challenge_hash": true,
+	"device_hash":    true,
+	"device_id":      true,
+	"fingerprint":    true,
+	"public_key":     true,
+	"credential_id":  true,
+}
+
+func (s *sqlRepo) ExportUserData(ctx context.Context, userId string) (map[string]privacyEntity.Table, error) {
+	data := make(map[string]privacyEntity.Table, len(exportQueries))
+	for _, q := range exportQueries {
+		table, err := s.table(ctx, q.query, repeat(userId, strings.Count(q.query, "?"))...)
+		if err != nil {
+			return nil, err
+		}
+		data[q.table] = table
+	}
+	return data, nil
+}
+
+// table reads the rows of a query as text, leaving out secret columns.
+func (s *sqlRepo) table(ctx context.Context, stmt string, args ...any) (privacyEntity.Table, error) {
+	rows, err := s.conn.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	table := privacyEntity.Table{}
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			if secretColumns[strings.ToLower(column)] {
+				continue
+			}
+			if values[i].Valid {
+				row[column] = values[i].String
+			} else {
+				row[column] = nil
+			}
+		}
+		table = append(table, row)
+	}
+	return table, rows.Err()
+}
+
+func (s *sqlRepo) StorageKeys(ctx context.Context, userId string) ([]string, error) {
+	stmt := `SELECT storage_key FROM Attachments WHERE ` + userAttachments + `
+		UNION SELECT archive_key FROM Privacy_Requests WHERE user_id = ? AND archive_key <> ''`
+	rows, err := s.conn.QueryContext(ctx, stmt, repeat(userId, strings.Count(stmt, "?"))...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// eraseQueries delete the user's data, children before their parents. Every ? is the user id.
+// Revoked_Tokens and Token_Cutoffs are kept so tokens issued to the user stay refused.
+var eraseQueries = []struct {
+	table string
+	query string
+}{
+	{"Attachments", `DELETE FROM Attachments WHERE ` + userAttachments},
+	{"Task_Labels", `DELETE FROM Task_Labels WHERE task_id IN (` + userTasks + `)`},
+	{"Taskfiles", `DELETE FROM Taskfiles WHERE task_id IN (` + userTasks + `)`},
+	{"Task_Time_Logs", `DELETE FROM Task_Time_Logs WHERE user_id = ? OR task_id IN (` + userTasks + `)`},
+	{"Comments", `DELETE FROM Comments WHERE sender_id = ? OR task_id IN (` + userTasks + `)`},
+	{"Tasks", `DELETE FROM Tasks WHERE user_id = ?`},
+	{"Project_Sections", `DELETE FROM Project_Sections WHERE project_id IN (` + userProjects + `)`},
+	{"Project_Members", `DELETE FROM Project_Members WHERE user_id = ? OR project_id IN (` + userProjects + `)`},
+	{"Project_Invitations", `DELETE FROM Project_Invitations WHERE invited_by = ? OR project_id IN (` + userProjects + `)
+		OR email = (SELECT email FROM Users WHERE user_id = ?)`},
+	{"Project_Template_Tasks", `DELETE FROM Project_Template_Tasks WHERE template_id IN (` + userTemplates + `)`},
+	{"Project_Templates", `DELETE FROM Project_Templates WHERE user_id = ?`},
+	{"Projects", `DELETE FROM Projects WHERE user_id = ?`},
+	{"Notifications", `DELETE FROM Notifications WHERE user_id = ?`},
+	{"Notification_Tokens", `DELETE FROM Notification_Tokens WHERE user_id = ?`},
+	{"Reminder_Settings", `DELETE FROM Reminder_Settings WHERE user_id = ?`},
+	{"Notification_Settings", `DELETE FROM Notification_Settings WHERE user_id = ?`},
+	{"Product_Email_Settings", `DELETE FROM Product_Email_Settings WHERE user_id = ?`},
+	{"User_Settings", `DELETE FROM User_Settings WHERE user_id = ?`},
+	{"Digest_Log", `DELETE FROM Digest_Log WHERE user_id = ?`},
+	{"Weekly_Reports", `DELETE FROM Weekly_Reports WHERE owner_id = ?`},
+	{"Password_Resets", `DELETE FROM Password_Resets WHERE user_id = ? OR email = (SELECT email FROM Users WHERE user_id = ?)`},
+	{"Sessions", `DELETE FROM Sessions WHERE user_id = ?`},
+	{"Refresh_Tokens", `DELETE FROM Refresh_Tokens WHERE user_id = ?`},
+	{"Personal_Access_Tokens", `DELETE FROM Personal_Access_Tokens WHERE user_id = ?`},
+	// once its app is gone the users of an app cannot refresh its tokens, and its codes cannot be traded
+	{"OAuth_Codes", `DELETE FROM OAuth_Codes WHERE user_id = ?
+		OR client_id IN (SELECT client_id FROM OAuth_Clients WHERE owner_id = ?)`},
+	{"OAuth_Clients", `DELETE FROM OAuth_Clients WHERE owner_id = ?`},
+	{"Email_Verifications", `DELETE FROM Email_Verifications WHERE user_id = ?`},
+	{"Magic_Links", `DELETE FROM Magic_Links WHERE user_id = ?`},
+	{"Login_Alerts", `DELETE FROM Login_Alerts WHERE user_id = ?`},
+	{"Social_Identities", `DELETE FROM Social_Identities WHERE user_id = ?`},
+	{"Two_Factor", `DELETE FROM Two_Factor WHERE account_id = ?`},
+	{"Recovery_Codes", `DELETE FROM Recovery_Codes WHERE account_id = ?`},
+	{"Two_Factor_Challenges", `DELETE FROM Two_Factor_Challenges WHERE account_id = ?`},
+	{"Passkeys", `DELETE FROM Passkeys WHERE account_id = ?`},
+	{"Passkey_Challenges", `DELETE FROM Passkey_Challenges WHERE account_id = ?`},
+	{"Calls", `DELETE FROM Calls WHERE user_id = ?`},
+	{"Account_Suspensions", `DELETE FROM Account_Suspensions WHERE user_id = ?`},
+	{"Subscribers", `DELETE FROM Subscribers WHERE email = (SELECT email FROM Users WHERE user_id = ?)`},
+	{"Login_Attempts", `DELETE FROM Login_Attempts
+		WHERE attempt_key = (SELECT CONCAT('user:', LOWER(email)) FROM Users WHERE user_id = ?)`},
+	{"Users", `DELETE FROM Users WHERE user_id = ?`},
+}
+
+func (s *sqlRepo) EraseUser(ctx context.Context, userId string) (deleted map[string]int64, err error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+
+	// comments of the user on tasks of others no longer count
+	_, err = tx.ExecContext(ctx, `UPDATE Tasks T JOIN (
+			SELECT task_id, COUNT(*) AS n FROM Comments WHERE sender_id = ? GROUP BY task_id
+		) C ON C.task_id = T.task_id
+		SET T.comment_count = GREATEST(T.comment_count - C.n, 0)
+		WHERE T.user_id <> ?`, userId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	// tasks and sub projects of others in projects of the user are kept, outside of any project
+	projectIds, err := projectsOf(ctx, tx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if len(projectIds) > 0 {
+		in := `(?` + strings.Repeat(", ?", len(projectIds)-1) + `)`
+		args := append([]any{userId}, projectIds...)
+		_, err = tx.ExecContext(ctx, `UPDATE Tasks SET project_id = NULL, section_id = NULL
+			WHERE user_id <> ? AND project_id IN `+in, args...)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE Projects SET parent_id = NULL
+			WHERE user_id <> ? AND parent_id IN `+in, args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	deleted = make(map[string]int64, len(eraseQueries))
+	for _, q := range eraseQueries {
+		var res sql.Result
+		res, err = tx.ExecContext(ctx, q.query, repeat(userId, strings.Count(q.query, "?"))...)
+		if err != nil {
+			return nil, err
+		}
+		var n int64
+		n, err = res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		deleted[q.table] = n
+	}
+	return deleted, nil
+}
+
+func projectsOf(ctx context.Context, tx *sql.Tx, userId string) ([]any, error) {
+	rows, err := tx.QueryContext(ctx, userProjects, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projectIds []any
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		projectIds = append(projectIds, id)
+	}
+	return projectIds, rows.Err()
+}
+
+func (s *sqlRepo) AddErasureRecord(ctx context.Context, record *privacyEntity.ErasureRecord) error {
+	_, err := s.conn.ExecContext(ctx, `INSERT INTO Erasure_Records(record_id, user_id, request_id, requested_by,
+			requested_at, erased_at, deleted_rows, storage_objects)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		record.RecordId, record.UserId, record.RequestId, record.RequestedBy, record.RequestedAt, record.ErasedAt,
+		record.Rows, record.StorageObjects)
+	return err
+}
+
+func repeat(userId string, n int) []any {
+	args := make([]any, n)
+	for i := range args {
+		args[i] = userId
+	}
+	return args
+}
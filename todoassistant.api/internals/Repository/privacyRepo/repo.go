@@ -0,0 +1,30 @@
+package privacyRepo
+
+import (
+	"context"
+	"test-va/internals/entity/privacyEntity"
+)
+
+type PrivacyRepository interface {
+	AddRequest(ctx context.Context, req *privacyEntity.Request) error
+	GetRequest(ctx context.Context, requestId string) (*privacyEntity.Request, error)
+	// GetOpenRequest returns the user's PENDING request of the kind, or sql.ErrNoRows.
+	GetOpenRequest(ctx context.Context, userId, kind string) (*privacyEntity.Request, error)
+	// DueRequests lists the PENDING requests of the kind scheduled for now or earlier.
+	DueRequests(ctx context.Context, kind, now string) ([]*privacyEntity.Request, error)
+	// ExpiredExports lists READY exports whose archive expired before now.
+	ExpiredExports(ctx context.Context, now string) ([]*privacyEntity.Request, error)
+	UpdateRequest(ctx context.Context, req *privacyEntity.Request) error
+
+	// ExportUserData reads everything stored about the user, by table. Secrets such as password
+	// hashes, two-factor secrets and token hashes are left out.
+	ExportUserData(ctx context.Context, userId string) (map[string]privacyEntity.Table, error)
+	// StorageKeys lists the stored files erasing the user deletes: their attachments,
+	// those on their tasks and the archives of their exports.
+	StorageKeys(ctx context.Context, userId string) ([]string, error)
+	// EraseUser deletes the user and everything stored about them in one transaction, and
+	// returns how many rows were deleted from each table. Tasks of other users in projects of
+	// the user are kept and taken out of the project.
+	EraseUser(ctx context.Context, userId string) (map[string]int64, error)
+	AddErasureRecord(ctx context.Context, record *privacyEntity.ErasureRecord) error
+}
@@ -6,20 +6,30 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strings"
 
 	"test-va/internals/Repository/projectRepo"
 	"test-va/internals/entity/projectEntity"
+	"test-va/internals/entity/taskEntity"
+	"test-va/internals/entity/userEntity"
 )
 
 type sqlRepo struct {
 	conn *sql.DB
 }
 
+// nullable stores an empty string as NULL.
+func nullable(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
 func (s *sqlRepo) PersistProject(ctx context.Context, req *projectEntity.CreateProjectReq) error {
-	stmt := fmt.Sprintf(`INSERT INTO Projects(project_id, title, color, user_id, date_created)
-						VALUES ('%v','%v','%v','%v','%v')`,
-		req.ProjectId, req.Title, req.Color, req.UserId, req.CreatedAt)
-	_, err := s.conn.Exec(stmt)
+	_, err := s.conn.ExecContext(ctx, `INSERT INTO Projects(project_id, title, color, user_id, parent_id, date_created)
+						VALUES (?, ?, ?, ?, ?, ?)`,
+		req.ProjectId, req.Title, req.Color, req.UserId, nullable(req.ParentId), req.CreatedAt)
 
 	if err != nil {
 		log.Println(err)
@@ -28,13 +38,22 @@ func (s *sqlRepo) PersistProject(ctx context.Context, req *projectEntity.CreateP
 	return nil
 }
 
-func (s *sqlRepo) GetListOfProjects(ctx context.Context, userId string) ([]*projectEntity.GetProjectRes, error) {
-	stmt := fmt.Sprintf(`
-		SELECT project_id, title, color, user_id FROM Projects
-		WHERE user_id = '%s'
-	`, userId)
+func (s *sqlRepo) GetListOfProjects(ctx context.Context, userId string, includeArchived bool) ([]*projectEntity.GetProjectRes, error) {
+	archived := ` AND P.archived_at IS NULL`
+	if includeArchived {
+		archived = ""
+	}
+	stmt := `
+		SELECT P.project_id, P.title, P.color, P.user_id, 'owner', COALESCE(P.archived_at, ''), COALESCE(P.parent_id, '')
+		FROM Projects P
+		WHERE P.user_id = ?` + archived + `
+		UNION ALL
+		SELECT P.project_id, P.title, P.color, P.user_id, M.role, COALESCE(P.archived_at, ''), COALESCE(P.parent_id, '')
+		FROM Projects P
+		JOIN Project_Members M ON M.project_id = P.project_id
+		WHERE M.user_id = ?` + archived
 
-	rows, err := s.conn.QueryContext(ctx, stmt)
+	rows, err := s.conn.QueryContext(ctx, stmt, userId, userId)
 	if err != nil {
 		return nil, err
 	}
@@ -49,6 +68,9 @@ func (s *sqlRepo) GetListOfProjects(ctx context.Context, userId string) ([]*proj
 			&project.Title,
 			&project.Color,
 			&project.UserId,
+			&project.Role,
+			&project.ArchivedAt,
+			&project.ParentId,
 		)
 		if err != nil {
 			return nil, err
@@ -88,8 +110,9 @@ func (s *sqlRepo) GetProject(ctx context.Context, projectId, userId string) (*pr
 	return &project, nil
 }
 
-// Delete project by id
-func (s *sqlRepo) DeleteProjectByID(ctx context.Context, projectId string) error {
+// Delete project by id. Sub projects are deleted with it when cascade is set,
+// otherwise they move up to the deleted project's parent.
+func (s *sqlRepo) DeleteProjectByID(ctx context.Context, projectId string, cascade bool) error {
 
 	tx, err := s.conn.BeginTx(ctx, nil)
 	if err != nil {
@@ -103,17 +126,89 @@ func (s *sqlRepo) DeleteProjectByID(ctx context.Context, projectId string) error
 			tx.Commit()
 		}
 	}()
-	_, err = tx.ExecContext(ctx, fmt.Sprintf(`DELETE from Tasks WHERE project_id = '%s'`, projectId))
-	if err != nil {
-		return err
+
+	projectIds := []string{projectId}
+	if cascade {
+		projectIds, err = descendants(ctx, tx, projectId)
+		if err != nil {
+			return err
+		}
+	} else {
+		var parentId sql.NullString
+		err = tx.QueryRowContext(ctx, `SELECT parent_id FROM Projects WHERE project_id = ?`, projectId).Scan(&parentId)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE Projects SET parent_id = ? WHERE parent_id = ?`, parentId, projectId)
+		if err != nil {
+			return err
+		}
 	}
-	_, err = tx.ExecContext(ctx, fmt.Sprintf(`DELETE from Projects WHERE project_id = '%s'`, projectId))
-	if err != nil {
-		return err
+
+	args := make([]any, len(projectIds))
+	for i, id := range projectIds {
+		args[i] = id
+	}
+	in := `(?` + strings.Repeat(", ?", len(projectIds)-1) + `)`
+	for _, table := range []string{"Tasks", "Project_Sections", "Project_Members", "Project_Invitations", "Projects"} {
+		_, err = tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE project_id IN `+in, args...)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// descendants returns the project and every project below it, level by level.
+func descendants(ctx context.Context, tx *sql.Tx, projectId string) ([]string, error) {
+	projectIds := []string{projectId}
+	seen := map[string]bool{projectId: true}
+	for level := []string{projectId}; len(level) > 0; {
+		args := make([]any, len(level))
+		for i, id := range level {
+			args[i] = id
+		}
+		rows, err := tx.QueryContext(ctx, `SELECT project_id FROM Projects
+			WHERE parent_id IN (?`+strings.Repeat(", ?", len(level)-1)+`)`, args...)
+		if err != nil {
+			return nil, err
+		}
+		level = nil
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if !seen[id] {
+				seen[id] = true
+				level = append(level, id)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		projectIds = append(projectIds, level...)
+	}
+	return projectIds, nil
+}
+
+func (s *sqlRepo) GetParentId(ctx context.Context, projectId string) (string, error) {
+	var parentId string
+	err := s.conn.QueryRowContext(ctx, `SELECT COALESCE(parent_id, '') FROM Projects WHERE project_id = ?`,
+		projectId).Scan(&parentId)
+	if err != nil {
+		return "", err
+	}
+	return parentId, nil
+}
+
+func (s *sqlRepo) SetParent(ctx context.Context, projectId, parentId string) error {
+	_, err := s.conn.ExecContext(ctx, `UPDATE Projects SET parent_id = ? WHERE project_id = ?`, nullable(parentId), projectId)
+	return err
+}
+
 func (m *sqlRepo) EditProject(ctx context.Context, req *projectEntity.EditProjectReq) (*projectEntity.EditProjectRes, error) {
 	tx, err := m.conn.BeginTx(ctx, nil)
 	if err != nil {
@@ -150,6 +245,437 @@ func (m *sqlRepo) EditProject(ctx context.Context, req *projectEntity.EditProjec
 	}, nil
 }
 
+func (s *sqlRepo) SetArchived(ctx context.Context, projectId, archivedAt string) error {
+	var value any
+	if archivedAt != "" {
+		value = archivedAt
+	}
+	_, err := s.conn.ExecContext(ctx, `UPDATE Projects SET archived_at = ? WHERE project_id = ?`, value, projectId)
+	return err
+}
+
+func (s *sqlRepo) GetTaskSummaries(ctx context.Context, projectIds []string) ([]*projectEntity.TaskSummary, error) {
+	if len(projectIds) == 0 {
+		return nil, nil
+	}
+	args := make([]any, len(projectIds))
+	for i, id := range projectIds {
+		args[i] = id
+	}
+	stmt := `SELECT project_id, status, end_time FROM Tasks
+			WHERE project_id IN (?` + strings.Repeat(", ?", len(projectIds)-1) + `)`
+
+	rows, err := s.conn.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []*projectEntity.TaskSummary
+	for rows.Next() {
+		var summary projectEntity.TaskSummary
+		if err := rows.Scan(&summary.ProjectId, &summary.Status, &summary.EndTime); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, &summary)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
+
+func (s *sqlRepo) GetMemberRole(ctx context.Context, projectId, userId string) (string, error) {
+	stmt := `SELECT 'owner' FROM Projects WHERE project_id = ? AND user_id = ?
+			UNION ALL
+			SELECT role FROM Project_Members WHERE project_id = ? AND user_id = ?
+			LIMIT 1`
+
+	var role string
+	err := s.conn.QueryRowContext(ctx, stmt, projectId, userId, projectId, userId).Scan(&role)
+	if err != nil {
+		return "", err
+	}
+	return role, nil
+}
+
+func (s *sqlRepo) GetMembers(ctx context.Context, projectId string) ([]*projectEntity.ProjectMember, error) {
+	stmt := `SELECT P.project_id, U.user_id, CONCAT(U.first_name, ' ', U.last_name), U.email, 'owner', COALESCE(P.date_created, '')
+			FROM Projects P JOIN Users U ON U.user_id = P.user_id
+			WHERE P.project_id = ?
+			UNION ALL
+			SELECT M.project_id, U.user_id, CONCAT(U.first_name, ' ', U.last_name), U.email, M.role, M.created_at
+			FROM Project_Members M JOIN Users U ON U.user_id = M.user_id
+			WHERE M.project_id = ?`
+
+	rows, err := s.conn.QueryContext(ctx, stmt, projectId, projectId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []*projectEntity.ProjectMember
+	for rows.Next() {
+		var member projectEntity.ProjectMember
+		err := rows.Scan(&member.ProjectId, &member.UserId, &member.Name, &member.Email, &member.Role, &member.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, &member)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (s *sqlRepo) UpdateMemberRole(ctx context.Context, projectId, memberId, role string) error {
+	_, err := s.conn.ExecContext(ctx, `UPDATE Project_Members SET role = ? WHERE project_id = ? AND user_id = ?`,
+		role, projectId, memberId)
+	return err
+}
+
+func (s *sqlRepo) RemoveMember(ctx context.Context, projectId, memberId string) error {
+	_, err := s.conn.ExecContext(ctx, `DELETE FROM Project_Members WHERE project_id = ? AND user_id = ?`,
+		projectId, memberId)
+	return err
+}
+
+func (s *sqlRepo) GetUserEmail(ctx context.Context, userId string) (string, error) {
+	var email string
+	err := s.conn.QueryRowContext(ctx, `SELECT email FROM Users WHERE user_id = ? AND account_status <> ?`,
+		userId, userEntity.AccountUnverified).Scan(&email)
+	if err != nil {
+		return "", err
+	}
+	return email, nil
+}
+
+func (s *sqlRepo) PersistInvitation(ctx context.Context, invitation *projectEntity.Invitation) error {
+	_, err := s.conn.ExecContext(ctx, `INSERT INTO Project_Invitations(invitation_id, project_id, email, role, invited_by, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		invitation.InvitationId, invitation.ProjectId, invitation.Email, invitation.Role,
+		invitation.InvitedBy, invitation.Status, invitation.CreatedAt)
+	return err
+}
+
+const invitationColumns = `I.invitation_id, I.project_id, P.title, I.email, I.role, I.invited_by, I.status, I.created_at`
+
+func (s *sqlRepo) GetInvitation(ctx context.Context, invitationId string) (*projectEntity.Invitation, error) {
+	stmt := `SELECT ` + invitationColumns + `
+			FROM Project_Invitations I JOIN Projects P ON P.project_id = I.project_id
+			WHERE I.invitation_id = ?`
+
+	var invitation projectEntity.Invitation
+	err := s.conn.QueryRowContext(ctx, stmt, invitationId).Scan(
+		&invitation.InvitationId, &invitation.ProjectId, &invitation.ProjectTitle, &invitation.Email,
+		&invitation.Role, &invitation.InvitedBy, &invitation.Status, &invitation.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (s *sqlRepo) GetPendingInvitations(ctx context.Context, email string) ([]*projectEntity.Invitation, error) {
+	stmt := `SELECT ` + invitationColumns + `
+			FROM Project_Invitations I JOIN Projects P ON P.project_id = I.project_id
+			WHERE I.email = ? AND I.status = ?
+			ORDER BY I.created_at`
+
+	rows, err := s.conn.QueryContext(ctx, stmt, email, projectEntity.InvitationPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invitations []*projectEntity.Invitation
+	for rows.Next() {
+		var invitation projectEntity.Invitation
+		err := rows.Scan(&invitation.InvitationId, &invitation.ProjectId, &invitation.ProjectTitle, &invitation.Email,
+			&invitation.Role, &invitation.InvitedBy, &invitation.Status, &invitation.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, &invitation)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (s *sqlRepo) AcceptInvitation(ctx context.Context, invitation *projectEntity.Invitation, userId, acceptedAt string) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO Project_Members(project_id, user_id, role, created_at)
+		VALUES (?, ?, ?, ?) ON DUPLICATE KEY UPDATE role = VALUES(role)`,
+		invitation.ProjectId, userId, invitation.Role, acceptedAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE Project_Invitations SET status = ?, responded_at = ? WHERE invitation_id = ?`,
+		projectEntity.InvitationAccepted, acceptedAt, invitation.InvitationId)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *sqlRepo) DeclineInvitation(ctx context.Context, invitationId, declinedAt string) error {
+	_, err := s.conn.ExecContext(ctx, `UPDATE Project_Invitations SET status = ?, responded_at = ? WHERE invitation_id = ?`,
+		projectEntity.InvitationDeclined, declinedAt, invitationId)
+	return err
+}
+
+func (s *sqlRepo) GetTemplateSource(ctx context.Context, projectId string) ([]*projectEntity.TemplateSourceTask, error) {
+	stmt := `SELECT title, COALESCE(description, ''), COALESCE(repeat_frequency, 'never'), start_time, end_time
+			FROM Tasks WHERE project_id = ?
+			ORDER BY start_time`
+
+	rows, err := s.conn.QueryContext(ctx, stmt, projectId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []*projectEntity.TemplateSourceTask
+	for rows.Next() {
+		var task projectEntity.TemplateSourceTask
+		if err := rows.Scan(&task.Title, &task.Description, &task.Repeat, &task.StartTime, &task.EndTime); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, &task)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (s *sqlRepo) PersistTemplate(ctx context.Context, template *projectEntity.ProjectTemplate) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO Project_Templates(template_id, user_id, title, color, created_at)
+		VALUES (?, ?, ?, ?, ?)`, template.TemplateId, template.UserId, template.Title, template.Color, template.CreatedAt)
+	if err != nil {
+		return err
+	}
+	for i, task := range template.Tasks {
+		_, err = tx.ExecContext(ctx, `INSERT INTO Project_Template_Tasks(template_id, position, title, description,
+			repeat_frequency, start_offset_minutes, due_offset_minutes) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			template.TemplateId, i, task.Title, task.Description, task.Repeat, task.StartOffsetMinutes, task.DueOffsetMinutes)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqlRepo) GetTemplates(ctx context.Context, userId string) ([]*projectEntity.ProjectTemplate, error) {
+	rows, err := s.conn.QueryContext(ctx, `SELECT template_id, user_id, title, color, created_at
+		FROM Project_Templates WHERE user_id = ? ORDER BY created_at`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var templates []*projectEntity.ProjectTemplate
+	for rows.Next() {
+		var template projectEntity.ProjectTemplate
+		err := rows.Scan(&template.TemplateId, &template.UserId, &template.Title, &template.Color, &template.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, &template)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (s *sqlRepo) GetTemplate(ctx context.Context, templateId string) (*projectEntity.ProjectTemplate, error) {
+	var template projectEntity.ProjectTemplate
+	err := s.conn.QueryRowContext(ctx, `SELECT template_id, user_id, title, color, created_at
+		FROM Project_Templates WHERE template_id = ?`, templateId).Scan(
+		&template.TemplateId, &template.UserId, &template.Title, &template.Color, &template.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn.QueryContext(ctx, `SELECT title, description, repeat_frequency, start_offset_minutes, due_offset_minutes
+		FROM Project_Template_Tasks WHERE template_id = ? ORDER BY position`, templateId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var task projectEntity.TemplateTask
+		err := rows.Scan(&task.Title, &task.Description, &task.Repeat, &task.StartOffsetMinutes, &task.DueOffsetMinutes)
+		if err != nil {
+			return nil, err
+		}
+		template.Tasks = append(template.Tasks, task)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (s *sqlRepo) PersistProjectWithTasks(ctx context.Context, project *projectEntity.CreateProjectReq, tasks []*taskEntity.CreateTaskReq) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO Projects(project_id, title, color, user_id, date_created)
+		VALUES (?, ?, ?, ?, ?)`, project.ProjectId, project.Title, project.Color, project.UserId, project.CreatedAt)
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		_, err = tx.ExecContext(ctx, `INSERT INTO Tasks(task_id, user_id, title, description, start_time, end_time,
+			created_at, va_option, repeat_frequency, notify, project_id, scheduled_date, status)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			task.TaskId, task.UserId, task.Title, task.Description, task.StartTime, task.EndTime,
+			task.CreatedAt, task.VAOption, task.Repeat, task.Notify, task.ProjectId, task.ScheduledDate, task.Status)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqlRepo) PersistSection(ctx context.Context, section *projectEntity.Section) error {
+	// new sections go to the end of the project
+	err := s.conn.QueryRowContext(ctx, `SELECT COALESCE(MAX(position) + 1, 0) FROM Project_Sections WHERE project_id = ?`,
+		section.ProjectId).Scan(&section.Position)
+	if err != nil {
+		return err
+	}
+	_, err = s.conn.ExecContext(ctx, `INSERT INTO Project_Sections(section_id, project_id, title, position, created_at)
+		VALUES (?, ?, ?, ?, ?)`, section.SectionId, section.ProjectId, section.Title, section.Position, section.CreatedAt)
+	return err
+}
+
+func (s *sqlRepo) GetSections(ctx context.Context, projectId string) ([]*projectEntity.Section, error) {
+	rows, err := s.conn.QueryContext(ctx, `SELECT section_id, project_id, title, position, created_at
+		FROM Project_Sections WHERE project_id = ? ORDER BY position`, projectId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sections []*projectEntity.Section
+	for rows.Next() {
+		var section projectEntity.Section
+		err := rows.Scan(&section.SectionId, &section.ProjectId, &section.Title, &section.Position, &section.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, &section)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+func (s *sqlRepo) GetSection(ctx context.Context, sectionId string) (*projectEntity.Section, error) {
+	var section projectEntity.Section
+	err := s.conn.QueryRowContext(ctx, `SELECT section_id, project_id, title, position, created_at
+		FROM Project_Sections WHERE section_id = ?`, sectionId).Scan(
+		&section.SectionId, &section.ProjectId, &section.Title, &section.Position, &section.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &section, nil
+}
+
+func (s *sqlRepo) RenameSection(ctx context.Context, sectionId, title string) error {
+	_, err := s.conn.ExecContext(ctx, `UPDATE Project_Sections SET title = ? WHERE section_id = ?`, title, sectionId)
+	return err
+}
+
+func (s *sqlRepo) ReorderSections(ctx context.Context, projectId string, sectionIds []string) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	for position, sectionId := range sectionIds {
+		_, err = tx.ExecContext(ctx, `UPDATE Project_Sections SET position = ? WHERE section_id = ? AND project_id = ?`,
+			position, sectionId, projectId)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqlRepo) DeleteSection(ctx context.Context, sectionId string) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	// the tasks stay in the project, just outside of any section
+	_, err = tx.ExecContext(ctx, `UPDATE Tasks SET section_id = NULL WHERE section_id = ?`, sectionId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM Project_Sections WHERE section_id = ?`, sectionId)
+	return err
+}
+
 func NewProjectSqlRepo(conn *sql.DB) projectRepo.ProjectRepository {
 	return &sqlRepo{conn: conn}
 }
@@ -3,12 +3,56 @@ package projectRepo
 import (
 	"context"
 	"test-va/internals/entity/projectEntity"
+	"test-va/internals/entity/taskEntity"
 )
 
 type ProjectRepository interface {
 	PersistProject(ctx context.Context, req *projectEntity.CreateProjectReq) error
-	GetListOfProjects(ctx context.Context, userId string) ([]*projectEntity.GetProjectRes, error)
+	GetListOfProjects(ctx context.Context, userId string, includeArchived bool) ([]*projectEntity.GetProjectRes, error)
 	GetProject(ctx context.Context, projectId, userId string) (*projectEntity.GetProjectRes, error)
 	EditProject(ctx context.Context, req *projectEntity.EditProjectReq) (*projectEntity.EditProjectRes, error)
-	DeleteProjectByID(ctx context.Context, projectId string) error
+	// DeleteProjectByID deletes the project, and its sub projects when cascade is set.
+	// Otherwise the sub projects move up to the deleted project's parent.
+	DeleteProjectByID(ctx context.Context, projectId string, cascade bool) error
+	// GetParentId returns the parent of a project, empty for a top level project.
+	GetParentId(ctx context.Context, projectId string) (string, error)
+	SetParent(ctx context.Context, projectId, parentId string) error
+	// SetArchived archives a project at the given time, or restores it when archivedAt is empty.
+	SetArchived(ctx context.Context, projectId, archivedAt string) error
+	GetTaskSummaries(ctx context.Context, projectIds []string) ([]*projectEntity.TaskSummary, error)
+
+	//Members
+	// GetMemberRole returns the role of a user on a project, sql.ErrNoRows when they have none.
+	GetMemberRole(ctx context.Context, projectId, userId string) (string, error)
+	GetMembers(ctx context.Context, projectId string) ([]*projectEntity.ProjectMember, error)
+	UpdateMemberRole(ctx context.Context, projectId, memberId, role string) error
+	RemoveMember(ctx context.Context, projectId, memberId string) error
+	// GetUserEmail returns the user's email once it is verified, and sql.ErrNoRows before.
+	GetUserEmail(ctx context.Context, userId string) (string, error)
+
+	//Invitations
+	PersistInvitation(ctx context.Context, invitation *projectEntity.Invitation) error
+	GetInvitation(ctx context.Context, invitationId string) (*projectEntity.Invitation, error)
+	GetPendingInvitations(ctx context.Context, email string) ([]*projectEntity.Invitation, error)
+	// AcceptInvitation adds the user to the project and closes the invitation.
+	AcceptInvitation(ctx context.Context, invitation *projectEntity.Invitation, userId, acceptedAt string) error
+	DeclineInvitation(ctx context.Context, invitationId, declinedAt string) error
+
+	//Sections
+	// PersistSection adds the section after the project's last one and sets its Position.
+	PersistSection(ctx context.Context, section *projectEntity.Section) error
+	GetSections(ctx context.Context, projectId string) ([]*projectEntity.Section, error)
+	GetSection(ctx context.Context, sectionId string) (*projectEntity.Section, error)
+	RenameSection(ctx context.Context, sectionId, title string) error
+	ReorderSections(ctx context.Context, projectId string, sectionIds []string) error
+	// DeleteSection removes the section, its tasks stay in the project.
+	DeleteSection(ctx context.Context, sectionId string) error
+
+	//Templates
+	GetTemplateSource(ctx context.Context, projectId string) ([]*projectEntity.TemplateSourceTask, error)
+	PersistTemplate(ctx context.Context, template *projectEntity.ProjectTemplate) error
+	GetTemplates(ctx context.Context, userId string) ([]*projectEntity.ProjectTemplate, error)
+	GetTemplate(ctx context.Context, templateId string) (*projectEntity.ProjectTemplate, error)
+	// PersistProjectWithTasks creates a project and all of its tasks at once.
+	PersistProjectWithTasks(ctx context.Context, project *projectEntity.CreateProjectReq, tasks []*taskEntity.CreateTaskReq) error
 }
@@ -0,0 +1,159 @@
+package mySqlRepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"test-va/internals/Repository/reportRepo"
+	"test-va/internals/entity/reportEntity"
+)
+
+type sqlRepo struct {
+	conn *sql.DB
+}
+
+func NewReportSqlRepo(conn *sql.DB) reportRepo.ReportRepository {
+	return &sqlRepo{conn: conn}
+}
+
+// ownerFilter limits Tasks T to the owner, or to the users assigned to a VA.
+func ownerFilter(role string) string {
+	if role == reportEntity.RoleVA {
+		return `T.user_id IN (SELECT user_id FROM Users WHERE virtual_assistant_id = ?)`
+	}
+	return `T.user_id = ?`
+}
+
+func (s *sqlRepo) GetOwners(ctx context.Context) ([]*reportEntity.ReportOwner, error) {
+	stmt := `SELECT user_id, email, first_name, ? FROM Users
+			UNION ALL
+			SELECT va_id, email, first_name, ? FROM va_table`
+
+	rows, err := s.conn.QueryContext(ctx, stmt, reportEntity.RoleUser, reportEntity.RoleVA)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var owners []*reportEntity.ReportOwner
+	for rows.Next() {
+		var owner reportEntity.ReportOwner
+		if err := rows.Scan(&owner.Id, &owner.Email, &owner.FirstName, &owner.Role); err != nil {
+			return nil, err
+		}
+		owners = append(owners, &owner)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return owners, nil
+}
+
+func (s *sqlRepo) GetTasks(ctx context.Context, ownerId, role, completedSince string) ([]*reportEntity.ReportTask, error) {
+	stmt := `SELECT T.task_id, T.title, T.user_id, CONCAT(U.first_name, ' ', U.last_name), T.status,
+				COALESCE(T.end_time, ''), COALESCE(T.completed_at, '')
+			FROM Tasks T
+			JOIN Users U ON U.user_id = T.user_id
+			WHERE ` + ownerFilter(role) + ` AND (T.status <> 'COMPLETED' OR T.completed_at >= ?)
+			ORDER BY T.end_time`
+
+	rows, err := s.conn.QueryContext(ctx, stmt, ownerId, completedSince)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []*reportEntity.ReportTask
+	for rows.Next() {
+		var task reportEntity.ReportTask
+		err := rows.Scan(&task.TaskId, &task.Title, &task.UserId, &task.UserName, &task.Status, &task.EndTime, &task.CompletedAt)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, &task)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+func (s *sqlRepo) GetMinutesLogged(ctx context.Context, ownerId, role, from, to string) (int, error) {
+	// a user sees all time logged on their tasks, a VA the time they logged themselves
+	stmt := `SELECT COALESCE(SUM(L.minutes), 0) FROM Task_Time_Logs L
+			JOIN Tasks T ON T.task_id = L.task_id
+			WHERE T.user_id = ? AND L.logged_at >= ? AND L.logged_at < ?`
+	if role == reportEntity.RoleVA {
+		stmt = `SELECT COALESCE(SUM(L.minutes), 0) FROM Task_Time_Logs L
+			WHERE L.user_id = ? AND L.logged_at >= ? AND L.logged_at < ?`
+	}
+
+	var minutes int
+	err := s.conn.QueryRowContext(ctx, stmt, ownerId, from, to).Scan(&minutes)
+	if err != nil {
+		return 0, err
+	}
+	return minutes, nil
+}
+
+func (s *sqlRepo) GetLatestComments(ctx context.Context, ownerId, role string) ([]*reportEntity.ReportComment, error) {
+	stmt := `SELECT C.task_id, T.title, C.sender_id, C.comment, C.created_at
+			FROM Comments C
+			JOIN Tasks T ON T.task_id = C.task_id
+			WHERE ` + ownerFilter(role) + ` AND T.status <> 'COMPLETED'
+				AND C.created_at = (SELECT MAX(C2.created_at) FROM Comments C2 WHERE C2.task_id = C.task_id)
+			ORDER BY C.created_at`
+
+	rows, err := s.conn.QueryContext(ctx, stmt, ownerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []*reportEntity.ReportComment
+	for rows.Next() {
+		var comment reportEntity.ReportComment
+		err := rows.Scan(&comment.TaskId, &comment.TaskTitle, &comment.SenderId, &comment.Comment, &comment.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, &comment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+func (s *sqlRepo) SaveReport(ctx context.Context, report *reportEntity.WeeklyReport) (bool, error) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return false, err
+	}
+	res, err := s.conn.ExecContext(ctx, `INSERT IGNORE INTO Weekly_Reports(owner_id, week_start, role, report, created_at)
+		VALUES (?, ?, ?, ?, ?)`, report.OwnerId, report.WeekStart, report.Role, string(data), report.GeneratedAt)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (s *sqlRepo) GetReport(ctx context.Context, ownerId, weekStart string) (*reportEntity.WeeklyReport, error) {
+	var data string
+	err := s.conn.QueryRowContext(ctx, `SELECT report FROM Weekly_Reports WHERE owner_id = ? AND week_start = ?`,
+		ownerId, weekStart).Scan(&data)
+	if err != nil {
+		return nil, err
+	}
+
+	var report reportEntity.WeeklyReport
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,18 @@
+package reportRepo
+
+import (
+	"context"
+	"test-va/internals/entity/reportEntity"
+)
+
+type ReportRepository interface {
+	GetOwners(ctx context.Context) ([]*reportEntity.ReportOwner, error)
+	// GetTasks returns the open tasks of the owner, and those completed since the given time.
+	// For a VA the tasks of every user assigned to them are returned.
+	GetTasks(ctx context.Context, ownerId, role, completedSince string) ([]*reportEntity.ReportTask, error)
+	GetMinutesLogged(ctx context.Context, ownerId, role, from, to string) (int, error)
+	// GetLatestComments returns the most recent comment on each open task of the owner.
+	GetLatestComments(ctx context.Context, ownerId, role string) ([]*reportEntity.ReportComment, error)
+	SaveReport(ctx context.Context, report *reportEntity.WeeklyReport) (bool, error)
+	GetReport(ctx context.Context, ownerId, weekStart string) (*reportEntity.WeeklyReport, error)
+}
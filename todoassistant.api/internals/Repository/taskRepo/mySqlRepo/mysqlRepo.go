@@ -28,19 +28,23 @@ func (s *sqlRepo) AssignTaskToVa(ctx context.Context, vaId, taskId string) error
 }
 
 func (s *sqlRepo) GetVADetails(ctx context.Context, userId string) (string, error) {
-	var vaId *string
-	stmt := fmt.Sprintf(`
+	var vaId sql.NullString
+	stmt := `
 		SELECT
 			virtual_Assistant_id from Users
-		WHERE user_id = '%v'
-		`, userId)
-	row := s.conn.QueryRowContext(ctx, stmt)
+		WHERE user_id = ?
+		`
+	row := s.conn.QueryRowContext(ctx, stmt, userId)
 	err := row.Scan(&vaId)
 	if err != nil {
 		return "", err
 	}
+	// a user without a VA is treated like one that does not exist
+	if !vaId.Valid || vaId.String == "" {
+		return "", sql.ErrNoRows
+	}
 
-	return *vaId, nil
+	return vaId.String, nil
 }
 
 func (s *sqlRepo) GetAllTaskAssignedToVA(ctx context.Context, vaId string) ([]*vaEntity.VATask, error) {
@@ -123,6 +127,7 @@ func (s *sqlRepo) GetPendingTasks(userId string, ctx context.Context) ([]*taskEn
 		SELECT task_id, user_id, title, description, start_time, end_time, status
 		FROM Tasks
 		WHERE user_id = '%s' AND status = 'PENDING'
+		AND NOT EXISTS (SELECT 1 FROM Projects P WHERE P.project_id = Tasks.project_id AND P.archived_at IS NOT NULL)
 	`, userId)
 
 	rows, err := s.conn.QueryContext(ctx, query)
@@ -219,6 +224,12 @@ func (s *sqlRepo) PersistAndAssign(ctx context.Context, req *taskEntity.CreateTa
 		}
 	}
 
+	err = insertLabels(ctx, tx, req.TaskId, req.Labels)
+	if err != nil {
+		log.Println("4", err)
+		return err
+	}
+
 	return nil
 }
 
@@ -250,10 +261,11 @@ func (s *sqlRepo) Persist(ctx context.Context, req *taskEntity.CreateTaskReq) er
 				repeat_frequency,
 				notify,
 				project_id,
+				section_id,
 				scheduled_date
 			)
-		VALUES ('%v','%v','%v','%v','%v','%v','%v', '%v', '%v',%t, '%v', '%v')`, req.TaskId, req.UserId, req.Title, req.Description,
-		req.StartTime, req.EndTime, req.CreatedAt, req.VAOption, req.Repeat, req.Notify, req.ProjectId, req.ScheduledDate)
+		VALUES ('%v','%v','%v','%v','%v','%v','%v', '%v', '%v',%t, '%v', NULLIF('%v', ''), '%v')`, req.TaskId, req.UserId, req.Title, req.Description,
+		req.StartTime, req.EndTime, req.CreatedAt, req.VAOption, req.Repeat, req.Notify, req.ProjectId, req.SectionId, req.ScheduledDate)
 
 	_, err = tx.ExecContext(ctx, stmt)
 	if err != nil {
@@ -276,6 +288,22 @@ func (s *sqlRepo) Persist(ctx context.Context, req *taskEntity.CreateTaskReq) er
 		}
 	}
 
+	err = insertLabels(ctx, tx, req.TaskId, req.Labels)
+	if err != nil {
+		log.Println("err", err)
+		return err
+	}
+
+	return nil
+}
+
+func insertLabels(ctx context.Context, tx *sql.Tx, taskId string, labels []string) error {
+	for _, label := range labels {
+		_, err := tx.ExecContext(ctx, `INSERT IGNORE INTO Task_Labels(task_id, label) VALUES (?, ?)`, taskId, label)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -296,13 +324,13 @@ func (s *sqlRepo) SearchTasks(title *taskEntity.SearchTitleParams, ctx context.C
 	// 	}
 	// }()
 
-	stmt := fmt.Sprintf(`
+	stmt := `
 		SELECT task_id, user_id, title, start_time
 		FROM Tasks
-		WHERE title LIKE '%s%%'
-	`, title.SearchQuery)
+		WHERE user_id = ? AND title LIKE CONCAT(?, '%')
+	`
 
-	rows, err := db.QueryContext(ctx, stmt)
+	rows, err := db.QueryContext(ctx, stmt, title.UserId, title.SearchQuery)
 	if err != nil {
 		return nil, err
 	}
@@ -346,7 +374,7 @@ func (s *sqlRepo) GetTaskByID(ctx context.Context, taskId string) (*taskEntity.G
 	}()
 
 	stmt := fmt.Sprintf(`
-		SELECT task_id, user_id, title, description, status, start_time, repeat_frequency, end_time, created_at, COALESCE(updated_at, ""), COALESCE(va_id,""), notify, COALESCE(project_id,""), COALESCE(scheduled_date,"")
+		SELECT task_id, user_id, title, description, status, start_time, repeat_frequency, end_time, created_at, COALESCE(updated_at, ""), COALESCE(va_id,""), notify, COALESCE(project_id,""), COALESCE(section_id,""), COALESCE(scheduled_date,"")
 		FROM Tasks T
 		WHERE task_id = '%s'`, taskId)
 
@@ -365,6 +393,7 @@ func (s *sqlRepo) GetTaskByID(ctx context.Context, taskId string) (*taskEntity.G
 		&task.VaId,
 		&task.Notify,
 		&task.ProjectId,
+		&task.SectionId,
 		&task.ScheduledDate,
 	); err != nil {
 		return nil, err
@@ -498,6 +527,56 @@ func (s *sqlRepo) GetListOfPendingTasks(ctx context.Context) ([]*taskEntity.GetA
 }
 
 // Get All task
+func (s *sqlRepo) GetProjectTasks(ctx context.Context, projectId string) ([]*taskEntity.GetAllTaskRes, error) {
+	tim := timeSrv.NewTimeStruct()
+	stmt := `
+		SELECT task_id, title, description, status, start_time, repeat_frequency, end_time, created_at, COALESCE(updated_at, ""), COALESCE(va_id,""), notify, COALESCE(project_id,""), COALESCE(section_id,""), COALESCE(scheduled_date,"")
+		FROM Tasks T WHERE project_id = ?`
+
+	rows, err := s.conn.QueryContext(ctx, stmt, projectId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []*taskEntity.GetAllTaskRes
+	for rows.Next() {
+		var task taskEntity.GetAllTaskRes
+		if err := rows.Scan(
+			&task.TaskId,
+			&task.Title,
+			&task.Description,
+			&task.Status,
+			&task.StartTime,
+			&task.Repeat,
+			&task.EndTime,
+			&task.CreatedAt,
+			&task.UpdatedAt,
+			&task.VaId,
+			&task.Notify,
+			&task.ProjectId,
+			&task.SectionId,
+			&task.ScheduledDate,
+		); err != nil {
+			return nil, err
+		}
+
+		var features taskEntity.TaskFeatures
+		features.IsAssigned = task.VaId != ""
+		features.IsScheduled = task.ScheduledDate != ""
+		features.IsCompleted = task.Status == "COMPLETED"
+		if end, err := time.Parse(time.RFC3339, task.EndTime); err == nil && tim.TimeBefore(end) {
+			features.IsExpired = true
+		}
+		task.TaskFeatures = features
+		tasks = append(tasks, &task)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
 func (s *sqlRepo) GetAllTasks(ctx context.Context, userId string) ([]*taskEntity.GetAllTaskRes, error) {
 	tim := timeSrv.NewTimeStruct()
 	//tx, err := s.conn.BeginTx(ctx, nil)
@@ -507,8 +586,9 @@ func (s *sqlRepo) GetAllTasks(ctx context.Context, userId string) ([]*taskEntity
 	}
 	log.Println("HERE ", userId)
 	stmt := fmt.Sprintf(`
-		SELECT task_id, title, description, status, start_time, repeat_frequency, end_time, created_at, COALESCE(updated_at, ""), COALESCE(va_id,""), notify, COALESCE(project_id,""), COALESCE(scheduled_date,"")
-		FROM Tasks T WHERE user_id = '%s'`, userId)
+		SELECT task_id, title, description, status, start_time, repeat_frequency, end_time, created_at, COALESCE(updated_at, ""), COALESCE(va_id,""), notify, COALESCE(project_id,""), COALESCE(section_id,""), COALESCE(scheduled_date,"")
+		FROM Tasks T WHERE user_id = '%s'
+		AND NOT EXISTS (SELECT 1 FROM Projects P WHERE P.project_id = T.project_id AND P.archived_at IS NOT NULL)`, userId)
 
 	rows, err := db.QueryContext(ctx, stmt)
 	if err != nil {
@@ -534,6 +614,7 @@ func (s *sqlRepo) GetAllTasks(ctx context.Context, userId string) ([]*taskEntity
 			&singleTask.VaId,
 			&singleTask.Notify,
 			&singleTask.ProjectId,
+			&singleTask.SectionId,
 			&singleTask.ScheduledDate,
 		); err != nil {
 			log.Println("error ", err)
@@ -631,17 +712,36 @@ func (s *sqlRepo) EditTaskById(ctx context.Context, taskId string, req *taskEnti
 							repeat_frequency = '%s',
 							end_time = '%s',
 							updated_at = '%s',
+							completed_at = CASE WHEN status = 'COMPLETED' THEN COALESCE(completed_at, updated_at) ELSE NULL END,
 							notify = '%d',
+							section_id = IF(project_id <=> '%s', section_id, NULL),
 							project_id ='%s',
 							scheduled_date= '%s'
 							WHERE task_id = '%s'
-						`, req.Title, req.Description, req.Status, req.StartTime, req.Repeat, req.EndTime, req.UpdatedAt, notifyInt, req.ProjectId, req.ScheduledDate, taskId)
+						`, req.Title, req.Description, req.Status, req.StartTime, req.Repeat, req.EndTime, req.UpdatedAt, notifyInt, req.ProjectId, req.ProjectId, req.ScheduledDate, taskId)
 
 	log.Println(req.ProjectId)
 	_, err := s.conn.ExecContext(ctx, stmt)
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	// labels are only replaced when the client sends them
+	if req.Labels != nil {
+		tx, err := s.conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM Task_Labels WHERE task_id = ?`, taskId)
+		if err == nil {
+			err = insertLabels(ctx, tx, taskId, req.Labels)
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}
 	return nil
 }
 
@@ -658,7 +758,11 @@ func (s *sqlRepo) UpdateTaskStatusByID(ctx context.Context, taskId string, req *
 			tx.Commit()
 		}
 	}()
-	_, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE Tasks SET status = '%s' WHERE task_id = '%s'`, req.Status, taskId))
+	_, err = tx.ExecContext(ctx, `UPDATE Tasks SET
+			status = ?,
+			updated_at = ?,
+			completed_at = CASE WHEN status = 'COMPLETED' THEN COALESCE(completed_at, updated_at) ELSE NULL END
+		WHERE task_id = ?`, req.Status, req.UpdatedAt, taskId)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -791,6 +895,26 @@ func (s *sqlRepo) GetComments(ctx context.Context) ([]*taskEntity.GetCommentRes,
 	return AllComment, nil
 }
 
+// get a comment by id
+func (s *sqlRepo) GetCommentByID(ctx context.Context, commentId string) (*taskEntity.GetCommentRes, error) {
+	stmt := `SELECT id, sender_id, task_id, comment, created_at, status, isEmoji FROM Comments WHERE id = ?`
+
+	var comment taskEntity.GetCommentRes
+	err := s.conn.QueryRowContext(ctx, stmt, commentId).Scan(
+		&comment.Id,
+		&comment.SenderId,
+		&comment.TaskId,
+		&comment.Comment,
+		&comment.CreatedAt,
+		&comment.Status,
+		&comment.IsEmoji,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
 // Delete comment by id
 func (s *sqlRepo) DeleteCommentByID(ctx context.Context, commentId string) error {
 	log.Println("hererer", commentId)
@@ -802,6 +926,21 @@ func (s *sqlRepo) DeleteCommentByID(ctx context.Context, commentId string) error
 	return nil
 }
 
+func (s *sqlRepo) SetSection(ctx context.Context, taskId, sectionId string) error {
+	var value any
+	if sectionId != "" {
+		value = sectionId
+	}
+	_, err := s.conn.ExecContext(ctx, `UPDATE Tasks SET section_id = ? WHERE task_id = ?`, value, taskId)
+	return err
+}
+
+func (s *sqlRepo) PersistTimeLog(ctx context.Context, req *taskEntity.LogTimeReq) error {
+	_, err := s.conn.ExecContext(ctx, `INSERT INTO Task_Time_Logs(task_id, user_id, minutes, note, logged_at)
+		VALUES (?, ?, ?, ?, ?)`, req.TaskId, req.UserId, req.Minutes, req.Note, req.LoggedAt)
+	return err
+}
+
 func NewSqlRepo(conn *sql.DB) taskRepo.TaskRepository {
 	return &sqlRepo{conn: conn}
 }
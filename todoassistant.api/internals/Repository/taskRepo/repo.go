@@ -16,14 +16,18 @@ type TaskRepository interface {
 	GetListOfPendingTasks(ctx context.Context) ([]*taskEntity.GetAllPendingRes, error)
 
 	GetAllTasks(ctx context.Context, userId string) ([]*taskEntity.GetAllTaskRes, error)
+	GetProjectTasks(ctx context.Context, projectId string) ([]*taskEntity.GetAllTaskRes, error)
 	DeleteTaskByID(ctx context.Context, taskId string) error
 	DeleteAllTask(ctx context.Context, userId string) error
 	UpdateTaskStatusByID(ctx context.Context, taskId string, req *taskEntity.UpdateTaskStatus) error
 	EditTaskById(ctx context.Context, taskId string, req *taskEntity.EditTaskReq) error
+	// SetSection moves a task into a section, an empty sectionId takes it out of any section.
+	SetSection(ctx context.Context, taskId, sectionId string) error
 
 	//VA
 	GetAllTaskAssignedToVA(ctx context.Context, vaId string) ([]*vaEntity.VATask, error)
 	GetAllTaskForVA(ctx context.Context) ([]*vaEntity.VATaskAll, error)
+	// GetVADetails returns the VA assigned to a user, sql.ErrNoRows when they have none.
 	GetVADetails(ctx context.Context, userId string) (string, error)
 	AssignTaskToVa(ctx context.Context, vaId, taskId string) error
 
@@ -31,5 +35,9 @@ type TaskRepository interface {
 	PersistComment(ctx context.Context, req *taskEntity.CreateCommentReq) error
 	GetAllComments(ctx context.Context, taskId string) ([]*taskEntity.GetCommentRes, error)
 	GetComments(ctx context.Context) ([]*taskEntity.GetCommentRes, error)
+	GetCommentByID(ctx context.Context, commentId string) (*taskEntity.GetCommentRes, error)
 	DeleteCommentByID(ctx context.Context, commentId string) error
+
+	//Time log
+	PersistTimeLog(ctx context.Context, req *taskEntity.LogTimeReq) error
 }
@@ -0,0 +1,315 @@
+package mySqlRepo
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"test-va/internals/Repository/tokenRepo"
+	"test-va/internals/entity/tokenEntity"
+	"test-va/internals/entity/userEntity"
+)
+
+type sqlRepo struct {
+	conn *sql.DB
+}
+
+func NewTokenSqlRepo(conn *sql.DB) tokenRepo.TokenRepository {
+	return &sqlRepo{conn: conn}
+}
+
+func (s *sqlRepo) PersistRefreshToken(ctx context.Context, token *tokenEntity.RefreshToken) error {
+	_, err := s.conn.ExecContext(ctx, `INSERT INTO Refresh_Tokens(token_hash, family_id, user_id, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)`, token.TokenHash, token.FamilyId, token.UserId, token.ExpiresAt, token.CreatedAt)
+	return err
+}
+
+func (s *sqlRepo) GetRefreshToken(ctx context.Context, tokenHash string) (*tokenEntity.RefreshToken, error) {
+	var token tokenEntity.RefreshToken
+	err := s.conn.QueryRowContext(ctx, `SELECT token_hash, family_id, user_id, expires_at, created_at,
+			COALESCE(rotated_at, ''), COALESCE(revoked_at, '')
+		FROM Refresh_Tokens WHERE token_hash = ?`, tokenHash).Scan(
+		&token.TokenHash, &token.FamilyId, &token.UserId, &token.ExpiresAt, &token.CreatedAt, &token.RotatedAt, &token.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (s *sqlRepo) RotateRefreshToken(ctx context.Context, tokenHash, rotatedAt string, next *tokenEntity.RefreshToken) (bool, error) {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	// only one of two concurrent refreshes with the same token wins
+	res, err := tx.ExecContext(ctx, `UPDATE Refresh_Tokens SET rotated_at = ?
+		WHERE token_hash = ? AND rotated_at IS NULL AND revoked_at IS NULL`, rotatedAt, tokenHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO Refresh_Tokens(token_hash, family_id, user_id, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)`, next.TokenHash, next.FamilyId, next.UserId, next.ExpiresAt, next.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *sqlRepo) RevokeToken(ctx context.Context, jti, userId string, expiresAt int64) error {
+	_, err := s.conn.ExecContext(ctx, `INSERT IGNORE INTO Revoked_Tokens(jti, user_id, expires_at) VALUES (?, ?, ?)`,
+		jti, userId, expiresAt)
+	return err
+}
+
+func (s *sqlRepo) SetRevokedBefore(ctx context.Context, userId string, cutoff int64) error {
+	_, err := s.conn.ExecContext(ctx, `INSERT INTO Token_Cutoffs(user_id, revoked_before) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE revoked_before = GREATEST(revoked_before, VALUES(revoked_before))`, userId, cutoff)
+	return err
+}
+
+func (s *sqlRepo) IsRevoked(ctx context.Context, jti, sessionId, userId string, issuedAt int64) (bool, error) {
+	var revoked bool
+	err := s.conn.QueryRowContext(ctx, `SELECT
+			EXISTS(SELECT 1 FROM Revoked_Tokens WHERE jti = ?)
+			OR EXISTS(SELECT 1 FROM Sessions WHERE session_id = ? AND revoked_at IS NOT NULL)
+			OR EXISTS(SELECT 1 FROM Token_Cutoffs WHERE user_id = ? AND revoked_before > ?)
+			OR EXISTS(SELECT 1 FROM Users WHERE user_id = ? AND account_status = ?)`,
+		jti, sessionId, userId, issuedAt, userId, userEntity.AccountSuspended).Scan(&revoked)
+	if err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
+
+func (s *sqlRepo) DeleteExpiredRevocations(ctx context.Context, now int64) error {
+	_, err := s.conn.ExecContext(ctx, `DELETE FROM Revoked_Tokens WHERE expires_at < ?`, now)
+	return err
+}
+
+func (s *sqlRepo) PersistSession(ctx context.Context, session *tokenEntity.Session) error {
+	_, err := s.conn.ExecContext(ctx, `INSERT INTO Sessions(session_id, user_id, account_type, device_name,
+			user_agent, ip_address, fingerprint, client_id, created_at, last_seen_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, ?)`, session.SessionId, session.UserId, session.AccountType,
+		session.DeviceName, session.UserAgent, session.IPAddress, session.Fingerprint, session.ClientId, session.CreatedAt,
+		session.LastSeenAt)
+	return err
+}
+
+const selectSession = `SELECT S.session_id, S.user_id, S.account_type, S.device_name, S.user_agent, S.ip_address,
+		COALESCE((SELECT N.device_id FROM Notification_Tokens N WHERE N.session_id = S.session_id LIMIT 1), ''), S.fingerprint,
+		COALESCE(S.client_id, ''), S.created_at, S.last_seen_at, COALESCE(S.revoked_at, '')
+	FROM Sessions S`
+
+func scanSession(row interface{ Scan(...any) error }) (*tokenEntity.Session, error) {
+	var session tokenEntity.Session
+	err := row.Scan(&session.SessionId, &session.UserId, &session.AccountType, &session.DeviceName, &session.UserAgent,
+		&session.IPAddress, &session.DeviceId, &session.Fingerprint, &session.ClientId, &session.CreatedAt, &session.LastSeenAt,
+		&session.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *sqlRepo) GetSession(ctx context.Context, sessionId string) (*tokenEntity.Session, error) {
+	return scanSession(s.conn.QueryRowContext(ctx, selectSession+` WHERE S.session_id = ?`, sessionId))
+}
+
+func (s *sqlRepo) GetSessions(ctx context.Context, userId, now string) ([]*tokenEntity.Session, error) {
+	rows, err := s.conn.QueryContext(ctx, selectSession+` WHERE S.user_id = ? AND S.revoked_at IS NULL
+		AND EXISTS(SELECT 1 FROM Refresh_Tokens R WHERE R.family_id = S.session_id
+			AND R.rotated_at IS NULL AND R.revoked_at IS NULL AND R.expires_at > ?)
+		ORDER BY S.last_seen_at DESC`, userId, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*tokenEntity.Session
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *sqlRepo) TouchSession(ctx context.Context, sessionId, lastSeenAt string) error {
+	_, err := s.conn.ExecContext(ctx, `UPDATE Sessions SET last_seen_at = ? WHERE session_id = ?`, lastSeenAt, sessionId)
+	return err
+}
+
+func (s *sqlRepo) RevokeSession(ctx context.Context, sessionId, revokedAt string) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	// logins from before sessions were recorded only have refresh tokens
+	_, err = tx.ExecContext(ctx, `UPDATE Sessions SET revoked_at = ?
+		WHERE session_id = ? AND revoked_at IS NULL`, revokedAt, sessionId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE Refresh_Tokens SET revoked_at = ?
+		WHERE family_id = ? AND revoked_at IS NULL`, revokedAt, sessionId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM Notification_Tokens WHERE session_id = ?`, sessionId)
+	return err
+}
+
+func (s *sqlRepo) RevokeUserSessions(ctx context.Context, userId, revokedAt string) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM Notification_Tokens WHERE session_id IN (
+		SELECT session_id FROM Sessions WHERE user_id = ? AND revoked_at IS NULL)`, userId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE Sessions SET revoked_at = ?
+		WHERE user_id = ? AND revoked_at IS NULL`, revokedAt, userId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE Refresh_Tokens SET revoked_at = ?
+		WHERE user_id = ? AND revoked_at IS NULL`, revokedAt, userId)
+	return err
+}
+
+func (s *sqlRepo) CountDeviceSessions(ctx context.Context, userId, fingerprint, exceptSessionId string) (int, int, error) {
+	var known, fingerprinted int
+	err := s.conn.QueryRowContext(ctx, `SELECT COALESCE(SUM(fingerprint = ?), 0), COUNT(*)
+		FROM Sessions WHERE user_id = ? AND session_id <> ? AND fingerprint <> ''`,
+		fingerprint, userId, exceptSessionId).Scan(&known, &fingerprinted)
+	if err != nil {
+		return 0, 0, err
+	}
+	return known, fingerprinted, nil
+}
+
+func (s *sqlRepo) ForgetSessionDevice(ctx context.Context, sessionId string) error {
+	_, err := s.conn.ExecContext(ctx, `UPDATE Sessions SET fingerprint = '' WHERE session_id = ?`, sessionId)
+	return err
+}
+
+func (s *sqlRepo) GetClientSessionIds(ctx context.Context, clientId string) ([]string, error) {
+	rows, err := s.conn.QueryContext(ctx, `SELECT session_id FROM Sessions WHERE client_id = ? AND revoked_at IS NULL`,
+		clientId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessionIds []string
+	for rows.Next() {
+		var sessionId string
+		if err := rows.Scan(&sessionId); err != nil {
+			return nil, err
+		}
+		sessionIds = append(sessionIds, sessionId)
+	}
+	return sessionIds, rows.Err()
+}
+
+func (s *sqlRepo) PersistAccessToken(ctx context.Context, token *tokenEntity.AccessToken) error {
+	_, err := s.conn.ExecContext(ctx, `INSERT INTO Personal_Access_Tokens(token_id, user_id, account_type, name,
+			token_hash, hint, scopes, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''))`, token.TokenId, token.UserId, token.AccountType, token.Name,
+		token.TokenHash, token.Hint, strings.Join(token.Scopes, ","), token.CreatedAt, token.ExpiresAt)
+	return err
+}
+
+const selectAccessToken = `SELECT token_id, user_id, account_type, name, token_hash, hint, scopes, created_at,
+		COALESCE(expires_at, ''), COALESCE(last_used_at, ''), COALESCE(revoked_at, '')
+	FROM Personal_Access_Tokens`
+
+func (s *sqlRepo) GetAccessToken(ctx context.Context, tokenHash string) (*tokenEntity.AccessToken, error) {
+	return scanAccessToken(s.conn.QueryRowContext(ctx, selectAccessToken+` WHERE token_hash = ?`, tokenHash))
+}
+
+func (s *sqlRepo) GetAccessTokens(ctx context.Context, userId string) ([]*tokenEntity.AccessToken, error) {
+	rows, err := s.conn.QueryContext(ctx, selectAccessToken+` WHERE user_id = ? AND revoked_at IS NULL
+		ORDER BY created_at DESC`, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*tokenEntity.AccessToken{}
+	for rows.Next() {
+		token, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+func scanAccessToken(row interface{ Scan(...any) error }) (*tokenEntity.AccessToken, error) {
+	var token tokenEntity.AccessToken
+	var scopes string
+	err := row.Scan(&token.TokenId, &token.UserId, &token.AccountType, &token.Name, &token.TokenHash, &token.Hint,
+		&scopes, &token.CreatedAt, &token.ExpiresAt, &token.LastUsedAt, &token.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	token.Scopes = strings.Split(scopes, ",")
+	return &token, nil
+}
+
+func (s *sqlRepo) RevokeAccessToken(ctx context.Context, userId, tokenId, revokedAt string) (bool, error) {
+	res, err := s.conn.ExecContext(ctx, `UPDATE Personal_Access_Tokens SET revoked_at = ?
+		WHERE token_id = ? AND user_id = ? AND revoked_at IS NULL`, revokedAt, tokenId, userId)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (s *sqlRepo) TouchAccessToken(ctx context.Context, tokenId, lastUsedAt string) error {
+	_, err := s.conn.ExecContext(ctx, `UPDATE Personal_Access_Tokens SET last_used_at = ? WHERE token_id = ?`,
+		lastUsedAt, tokenId)
+	return err
+}
@@ -0,0 +1,50 @@
+package tokenRepo
+
+import (
+	"context"
+	"test-va/internals/entity/tokenEntity"
+)
+
+type TokenRepository interface {
+	PersistRefreshToken(ctx context.Context, token *tokenEntity.RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (*tokenEntity.RefreshToken, error)
+	// RotateRefreshToken marks a token as used and stores the one replacing it.
+	// It returns false when the token was already rotated or revoked.
+	RotateRefreshToken(ctx context.Context, tokenHash, rotatedAt string, next *tokenEntity.RefreshToken) (bool, error)
+
+	//Sessions
+	PersistSession(ctx context.Context, session *tokenEntity.Session) error
+	GetSession(ctx context.Context, sessionId string) (*tokenEntity.Session, error)
+	// GetSessions lists the user's sessions that still hold a refresh token valid at now.
+	GetSessions(ctx context.Context, userId, now string) ([]*tokenEntity.Session, error)
+	TouchSession(ctx context.Context, sessionId, lastSeenAt string) error
+	// RevokeSession revokes the session's refresh tokens and removes the push tokens registered from it.
+	RevokeSession(ctx context.Context, sessionId, revokedAt string) error
+	// RevokeUserSessions does the same as RevokeSession for every session of the user.
+	RevokeUserSessions(ctx context.Context, userId, revokedAt string) error
+	// CountDeviceSessions counts the user's sessions other than exceptSessionId that were started from
+	// the device with fingerprint, and those with any fingerprint.
+	CountDeviceSessions(ctx context.Context, userId, fingerprint, exceptSessionId string) (int, int, error)
+	// ForgetSessionDevice clears the fingerprint of a session, so its device is not known from it anymore.
+	ForgetSessionDevice(ctx context.Context, sessionId string) error
+	// GetClientSessionIds lists the sessions of an OAuth app that are not revoked, of every user.
+	GetClientSessionIds(ctx context.Context, clientId string) ([]string, error)
+
+	//Access tokens
+	RevokeToken(ctx context.Context, jti, userId string, expiresAt int64) error
+	// SetRevokedBefore revokes every token of the user issued before the cutoff, in unix seconds.
+	SetRevokedBefore(ctx context.Context, userId string, cutoff int64) error
+	// IsRevoked reports whether the token was revoked on its own, with its session or by a cutoff for its user,
+	// or its user is suspended.
+	IsRevoked(ctx context.Context, jti, sessionId, userId string, issuedAt int64) (bool, error)
+	DeleteExpiredRevocations(ctx context.Context, now int64) error
+
+	//Personal access tokens
+	PersistAccessToken(ctx context.Context, token *tokenEntity.AccessToken) error
+	GetAccessToken(ctx context.Context, tokenHash string) (*tokenEntity.AccessToken, error)
+	// GetAccessTokens lists the user's tokens that were not revoked, newest first.
+	GetAccessTokens(ctx context.Context, userId string) ([]*tokenEntity.AccessToken, error)
+	// RevokeAccessToken returns false when the user has no such token, or it was revoked already.
+	RevokeAccessToken(ctx context.Context, userId, tokenId, revokedAt string) (bool, error)
+	TouchAccessToken(ctx context.Context, tokenId, lastUsedAt string) error
+}
@@ -0,0 +1,158 @@
+package mySqlRepo
+
+import (
+	"context"
+	"database/sql"
+
+	"test-va/internals/Repository/twoFactorRepo"
+	"test-va/internals/entity/twoFactorEntity"
+)
+
+type sqlRepo struct {
+	conn *sql.DB
+}
+
+func NewTwoFactorSqlRepo(conn *sql.DB) twoFactorRepo.TwoFactorRepository {
+	return &sqlRepo{conn: conn}
+}
+
+func (s *sqlRepo) Get(ctx context.Context, accountId string) (*twoFactorEntity.TwoFactor, error) {
+	var twoFactor twoFactorEntity.TwoFactor
+	err := s.conn.QueryRowContext(ctx, `SELECT account_id, account_type, secret, last_used_step, created_at,
+			COALESCE(enabled_at, '')
+		FROM Two_Factor WHERE account_id = ?`, accountId).Scan(
+		&twoFactor.AccountId, &twoFactor.AccountType, &twoFactor.Secret, &twoFactor.LastUsedStep, &twoFactor.CreatedAt,
+		&twoFactor.EnabledAt)
+	if err != nil {
+		return nil, err
+	}
+	return &twoFactor, nil
+}
+
+func (s *sqlRepo) SaveSecret(ctx context.Context, twoFactor *twoFactorEntity.TwoFactor) error {
+	// an enabled secret is only replaced after two-factor authentication is turned off
+	_, err := s.conn.ExecContext(ctx, `INSERT INTO Two_Factor(account_id, account_type, secret, created_at)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			secret = IF(enabled_at IS NULL, VALUES(secret), secret),
+			created_at = IF(enabled_at IS NULL, VALUES(created_at), created_at)`,
+		twoFactor.AccountId, twoFactor.AccountType, twoFactor.Secret, twoFactor.CreatedAt)
+	return err
+}
+
+func (s *sqlRepo) Enable(ctx context.Context, accountId, enabledAt string, step int64, recoveryCodeHashes []string) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, `UPDATE Two_Factor SET enabled_at = ?, last_used_step = ? WHERE account_id = ?`,
+		enabledAt, step, accountId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM Recovery_Codes WHERE account_id = ?`, accountId)
+	if err != nil {
+		return err
+	}
+	for _, hash := range recoveryCodeHashes {
+		_, err = tx.ExecContext(ctx, `INSERT INTO Recovery_Codes(code_hash, account_id) VALUES (?, ?)`, hash, accountId)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *sqlRepo) UseStep(ctx context.Context, accountId string, step int64) (bool, error) {
+	res, err := s.conn.ExecContext(ctx, `UPDATE Two_Factor SET last_used_step = ?
+		WHERE account_id = ? AND last_used_step < ?`, step, accountId, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *sqlRepo) UseRecoveryCode(ctx context.Context, accountId, codeHash, usedAt string) (bool, error) {
+	res, err := s.conn.ExecContext(ctx, `UPDATE Recovery_Codes SET used_at = ?
+		WHERE code_hash = ? AND account_id = ? AND used_at IS NULL`, usedAt, codeHash, accountId)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *sqlRepo) Delete(ctx context.Context, accountId string) error {
+	tx, err := s.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM Recovery_Codes WHERE account_id = ?`, accountId)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM Two_Factor WHERE account_id = ?`, accountId)
+	return err
+}
+
+func (s *sqlRepo) AttemptChallenge(ctx context.Context, challenge *twoFactorEntity.Challenge) (*twoFactorEntity.Challenge, error) {
+	_, err := s.conn.ExecContext(ctx, `INSERT INTO Two_Factor_Challenges(jti, account_id, attempts, expires_at)
+		VALUES (?, ?, 1, ?)
+		ON DUPLICATE KEY UPDATE attempts = attempts + 1`,
+		challenge.Jti, challenge.AccountId, challenge.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored twoFactorEntity.Challenge
+	err = s.conn.QueryRowContext(ctx, `SELECT jti, account_id, attempts, expires_at, COALESCE(completed_at, '')
+		FROM Two_Factor_Challenges WHERE jti = ?`, challenge.Jti).Scan(
+		&stored.Jti, &stored.AccountId, &stored.Attempts, &stored.ExpiresAt, &stored.CompletedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+func (s *sqlRepo) CompleteChallenge(ctx context.Context, jti, completedAt string) (bool, error) {
+	res, err := s.conn.ExecContext(ctx, `UPDATE Two_Factor_Challenges SET completed_at = ?
+		WHERE jti = ? AND completed_at IS NULL`, completedAt, jti)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *sqlRepo) DeleteExpiredChallenges(ctx context.Context, now string) error {
+	_, err := s.conn.ExecContext(ctx, `DELETE FROM Two_Factor_Challenges WHERE expires_at < ?`, now)
+	return err
+}
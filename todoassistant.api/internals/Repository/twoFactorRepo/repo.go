@@ -0,0 +1,25 @@
+package twoFactorRepo
+
+import (
+	"context"
+	"test-va/internals/entity/twoFactorEntity"
+)
+
+type TwoFactorRepository interface {
+	Get(ctx context.Context, accountId string) (*twoFactorEntity.TwoFactor, error)
+	// SaveSecret starts enrolling, replacing a secret that was never confirmed.
+	SaveSecret(ctx context.Context, twoFactor *twoFactorEntity.TwoFactor) error
+	// Enable confirms enrollment with the step of the first code and stores the hashed recovery codes.
+	Enable(ctx context.Context, accountId, enabledAt string, step int64, recoveryCodeHashes []string) error
+	// UseStep records that a code for step was used, it returns false when a code for the same or a later step was used already.
+	UseStep(ctx context.Context, accountId string, step int64) (bool, error)
+	// UseRecoveryCode spends a recovery code, it returns false when the code does not exist or was used.
+	UseRecoveryCode(ctx context.Context, accountId, codeHash, usedAt string) (bool, error)
+	Delete(ctx context.Context, accountId string) error
+
+	// AttemptChallenge counts a code tried for the challenge and returns the challenge with the count.
+	AttemptChallenge(ctx context.Context, challenge *twoFactorEntity.Challenge) (*twoFactorEntity.Challenge, error)
+	// CompleteChallenge marks the challenge used, it returns false when it was used already.
+	CompleteChallenge(ctx context.Context, jti, completedAt string) (bool, error)
+	DeleteExpiredChallenges(ctx context.Context, now string) error
+}
@@ -99,7 +99,8 @@ func (m *mySql) GetUsers(page int) ([]*userEntity.UsersRes, error) {
 
 func (m *mySql) GetByEmail(email string) (*userEntity.GetByEmailRes, error) {
 	query := fmt.Sprintf(`
-		SELECT user_id, email, password, first_name, last_name, phone, COALESCE(gender, ''), avatar,COALESCE(occupation, ''), COALESCE(country_id, 0)
+		SELECT user_id, email, password, first_name, last_name, phone, COALESCE(gender, ''), avatar,COALESCE(occupation, ''), COALESCE(country_id, 0),
+			COALESCE(account_status, ''), password_reset_required
 		FROM Users
 		WHERE email = '%s'
 	`, email)
@@ -116,6 +117,8 @@ func (m *mySql) GetByEmail(email string) (*userEntity.GetByEmailRes, error) {
 		&user.Avatar,
 		&user.Occupation,
 		&user.CountryId,
+		&user.AccountStatus,
+		&user.PasswordResetRequired,
 	)
 	if err != nil {
 		fmt.Println(err)
@@ -126,7 +129,8 @@ func (m *mySql) GetByEmail(email string) (*userEntity.GetByEmailRes, error) {
 
 func (m *mySql) GetById(user_id string) (*userEntity.GetByIdRes, error) {
 	query := fmt.Sprintf(`
-		SELECT user_id, password, email, first_name, last_name, phone, COALESCE(gender, ''), avatar
+		SELECT user_id, password, email, first_name, last_name, phone, COALESCE(gender, ''), avatar,
+			COALESCE(account_status, ''), COALESCE(pending_email, ''), password_reset_required
 		FROM Users
 		WHERE user_id = '%s'
 	`, user_id)
@@ -142,6 +146,9 @@ func (m *mySql) GetById(user_id string) (*userEntity.GetByIdRes, error) {
 		&user.Phone,
 		&user.Gender,
 		&user.Avatar,
+		&user.AccountStatus,
+		&user.PendingEmail,
+		&user.PasswordResetRequired,
 	)
 
 	if err != nil {
@@ -160,7 +167,7 @@ func (m *mySql) Persist(req *userEntity.CreateUserReq) error {
 	}
 	defer tx.Rollback()
 
-	stmt := fmt.Sprintf(` INSERT INTO Users(
+	stmt := ` INSERT INTO Users(
                    user_id,
                    first_name,
                    last_name,
@@ -168,10 +175,10 @@ func (m *mySql) Persist(req *userEntity.CreateUserReq) error {
                    phone,
                    password,
                    account_status
-                   ) VALUES ('%v', '%v', '%v', '%v', '%v', '%v', '%v')`,
-		req.UserId, req.FirstName, req.LastName, req.Email, req.Phone, req.Password, req.AccountStatus)
+                   ) VALUES (?, ?, ?, ?, ?, ?, ?)`
 
-	_, err = tx.ExecContext(ctx, stmt)
+	_, err = tx.ExecContext(ctx, stmt,
+		req.UserId, req.FirstName, req.LastName, req.Email, req.Phone, req.Password, req.AccountStatus)
 	if err != nil {
 		return err
 	}
@@ -318,7 +325,7 @@ func (m *mySql) UpdateImage(userId, fileName string) error {
 // }
 
 func (m *mySql) ChangePassword(user_id, newPassword string) error {
-	query := fmt.Sprintf(`UPDATE Users SET password = '%v' WHERE user_id = '%v'`, newPassword, user_id)
+	query := fmt.Sprintf(`UPDATE Users SET password = '%v', password_reset_required = FALSE WHERE user_id = '%v'`, newPassword, user_id)
 	_, err := m.conn.Exec(query)
 	if err != nil {
 		fmt.Println(err)
@@ -328,66 +335,93 @@ func (m *mySql) ChangePassword(user_id, newPassword string) error {
 	return nil
 }
 
-func (m *mySql) DeleteUser(user_id string) error {
-	query := fmt.Sprintf(`DELETE FROM Users WHERE user_id = "%s"`, user_id)
-	_, err := m.conn.Exec(query)
+func (m *mySql) RequirePasswordReset(userId string) error {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
+
+	_, err := m.conn.ExecContext(ctx, `UPDATE Users SET password_reset_required = TRUE WHERE user_id = ?`, userId)
+	return err
+}
+
+func (m *mySql) AddPasswordReset(req *userEntity.PasswordReset) error {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
+
+	tx, err := m.conn.BeginTx(ctx, nil)
 	if err != nil {
-		fmt.Println(err)
 		return err
 	}
+	defer tx.Rollback()
 
-	return nil
+	if req.UserId != "" {
+		_, err = tx.ExecContext(ctx, `UPDATE Password_Resets SET used_at = ? WHERE user_id = ? AND used_at IS NULL`,
+			req.CreatedAt, req.UserId)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO Password_Resets(reset_id, user_id, email, secret_hash, ip_address,
+			attempts, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, 0, ?, ?)`,
+		req.ResetId, req.UserId, req.Email, req.SecretHash, req.IPAddress, req.ExpiresAt, req.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (m *mySql) AddToken(req *userEntity.ResetPasswordRes) error {
-	stmt := fmt.Sprintf(` INSERT INTO Reset_Token(
-                   token_id,
-                   user_id,
-                   token,
-                   expiry
-                   ) VALUES ('%v', '%v', '%v', '%v')`,
-		req.TokenId, req.UserId, req.Token, req.Expiry)
+func (m *mySql) GetPasswordReset(resetId string) (*userEntity.PasswordReset, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
 
-	_, err := m.conn.Exec(stmt)
+	var reset userEntity.PasswordReset
+	err := m.conn.QueryRowContext(ctx, `SELECT reset_id, user_id, email, secret_hash, ip_address, attempts, expires_at,
+		created_at, COALESCE(used_at, '') FROM Password_Resets WHERE reset_id = ?`, resetId).Scan(
+		&reset.ResetId, &reset.UserId, &reset.Email, &reset.SecretHash, &reset.IPAddress, &reset.Attempts,
+		&reset.ExpiresAt, &reset.CreatedAt, &reset.UsedAt)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	return &reset, nil
 }
 
-func (m *mySql) GetTokenById(token, userId string) (*userEntity.ResetPasswordWithTokenRes, error) {
-	query := fmt.Sprintf(`
-		SELECT token_id, user_id, token, expiry
-		FROM Reset_Token
-		WHERE token = '%s'
-		AND user_id = '%s'
-	`, token, userId)
+func (m *mySql) FailPasswordReset(resetId string) error {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
 
-	var tokenRes userEntity.ResetPasswordWithTokenRes
-	ctx := context.Background()
-	err := m.conn.QueryRowContext(ctx, query).Scan(
-		&tokenRes.TokenId,
-		&tokenRes.UserId,
-		&tokenRes.Token,
-		&tokenRes.Expiry,
-	)
+	_, err := m.conn.ExecContext(ctx, `UPDATE Password_Resets SET attempts = attempts + 1 WHERE reset_id = ?`, resetId)
+	return err
+}
+
+func (m *mySql) UsePasswordReset(resetId, usedAt string) (bool, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
 
+	result, err := m.conn.ExecContext(ctx, `UPDATE Password_Resets SET used_at = ? WHERE reset_id = ? AND used_at IS NULL`,
+		usedAt, resetId)
 	if err != nil {
-		fmt.Println(err)
-		return nil, err
+		return false, err
 	}
-	return &tokenRes, nil
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
 }
 
-func (m *mySql) DeleteToken(userId string) error {
-	query := fmt.Sprintf(`DELETE FROM Reset_Token WHERE user_id = "%s"`, userId)
-	_, err := m.conn.Exec(query)
+func (m *mySql) CountPasswordResets(email, ipAddress, since string) (int, int, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
+
+	var byEmail, byIP int
+	err := m.conn.QueryRowContext(ctx, `SELECT
+		(SELECT COUNT(*) FROM Password_Resets WHERE email = ? AND created_at >= ?),
+		(SELECT COUNT(*) FROM Password_Resets WHERE ip_address = ? AND created_at >= ?)`,
+		email, since, ipAddress, since).Scan(&byEmail, &byIP)
 	if err != nil {
-		fmt.Println(err)
-		return err
+		return 0, 0, err
 	}
-
-	return nil
+	return byEmail, byIP, nil
 }
 
 // get user notification settings
@@ -470,9 +504,10 @@ func (m *mySql) SetReminderSettings(req *userEntity.ReminderSettingsReq, userId
 			whenSnooze = '%v',
 			autoReminder = '%v',
 			reminderTime = '%v',
-			refresh = '%v'
+			refresh = '%v',
+			time_zone = '%v'
 			WHERE user_id = '%v'`,
-			req.RemindMeVia, req.WhenSnooze, req.AutoReminder, req.ReminderTime, req.Refresh, userId)
+			req.RemindMeVia, req.WhenSnooze, req.AutoReminder, req.ReminderTime, req.Refresh, timeZone(req.TimeZone), userId)
 	} else {
 		// Insert a new record
 		stmt = fmt.Sprintf(`INSERT INTO Reminder_Settings(
@@ -481,9 +516,10 @@ func (m *mySql) SetReminderSettings(req *userEntity.ReminderSettingsReq, userId
 			autoReminder,
 			reminderTime,
 			refresh,
+			time_zone,
 			user_id
-		) VALUES ('%v', '%v', '%v', '%v', '%v', '%v')`,
-			req.RemindMeVia, req.WhenSnooze, req.AutoReminder, req.ReminderTime, req.Refresh, userId)
+		) VALUES ('%v', '%v', '%v', '%v', '%v', '%v', '%v')`,
+			req.RemindMeVia, req.WhenSnooze, req.AutoReminder, req.ReminderTime, req.Refresh, timeZone(req.TimeZone), userId)
 	}
 
 	_, err = tx.ExecContext(ctx, stmt)
@@ -500,7 +536,7 @@ func (m *mySql) SetReminderSettings(req *userEntity.ReminderSettingsReq, userId
 // get reminder settings for a user
 func (m *mySql) GetReminderSettings(userId string) (*userEntity.ReminderSettingsRes, error) {
 	stmt := fmt.Sprintf(`
-		SELECT remindMeVia, whenSnooze, autoReminder, reminderTime, refresh
+		SELECT remindMeVia, whenSnooze, autoReminder, reminderTime, refresh, time_zone
 		FROM Reminder_Settings
 		WHERE user_id = '%s'
 	`, userId)
@@ -512,6 +548,7 @@ func (m *mySql) GetReminderSettings(userId string) (*userEntity.ReminderSettings
 		&reminderSettings.AutoReminder,
 		&reminderSettings.ReminderTime,
 		&reminderSettings.Refresh,
+		&reminderSettings.TimeZone,
 	)
 	if err != nil {
 		fmt.Println(err)
@@ -528,8 +565,9 @@ func (m *mySql) UpdateReminderSettings(req *userEntity.ReminderSettingsReq, user
                  remindMeVia ='%s',
                  whenSnooze='%s',
                  autoReminder ='%s',
-                 refresh='%s' WHERE user_id ='%s'
-                 `, req.RemindMeVia, req.WhenSnooze, req.AutoReminder, req.Refresh, userId)
+                 refresh='%s',
+                 time_zone='%s' WHERE user_id ='%s'
+                 `, req.RemindMeVia, req.WhenSnooze, req.AutoReminder, req.Refresh, timeZone(req.TimeZone), userId)
 
 	_, err := m.conn.ExecContext(ctx, stmt)
 	log.Println("from repo", err)
@@ -539,6 +577,14 @@ func (m *mySql) UpdateReminderSettings(req *userEntity.ReminderSettingsReq, user
 	return nil
 }
 
+// timeZone defaults reminder settings saved without a time zone to UTC
+func timeZone(tz string) string {
+	if tz == "" {
+		return "UTC"
+	}
+	return tz
+}
+
 func (m *mySql) UpdateProductEmailSettings(req *userEntity.ProductEmailSettingsReq, userId string) error {
 	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
 	defer cancelFunc()
@@ -597,3 +643,201 @@ func (m *mySql) UpdateNotificationSettings(req *userEntity.NotificationSettingsR
 func convertToBool(value bool) bool {
 	return value == true
 }
+
+func (m *mySql) SetPendingEmail(userId, email string) error {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
+
+	_, err := m.conn.ExecContext(ctx, `UPDATE Users SET pending_email = ? WHERE user_id = ?`, email, userId)
+	return err
+}
+
+func (m *mySql) AddEmailVerification(req *userEntity.EmailVerification) error {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
+
+	_, err := m.conn.ExecContext(ctx, `INSERT INTO Email_Verifications(token_hash, user_id, email, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)`, req.TokenHash, req.UserId, req.Email, req.ExpiresAt, req.CreatedAt)
+	return err
+}
+
+func (m *mySql) GetEmailVerification(tokenHash string) (*userEntity.EmailVerification, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
+
+	var verification userEntity.EmailVerification
+	err := m.conn.QueryRowContext(ctx, `SELECT token_hash, user_id, email, expires_at, created_at
+		FROM Email_Verifications WHERE token_hash = ?`, tokenHash).Scan(
+		&verification.TokenHash, &verification.UserId, &verification.Email, &verification.ExpiresAt, &verification.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+func (m *mySql) AddMagicLink(req *userEntity.MagicLink) error {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
+
+	_, err := m.conn.ExecContext(ctx, `INSERT INTO Magic_Links(token_hash, user_id, email, device_hash, ip_address, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		req.TokenHash, req.UserId, req.Email, req.DeviceHash, req.IPAddress, req.ExpiresAt, req.CreatedAt)
+	return err
+}
+
+func (m *mySql) GetMagicLink(tokenHash string) (*userEntity.MagicLink, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
+
+	var link userEntity.MagicLink
+	err := m.conn.QueryRowContext(ctx, `SELECT token_hash, user_id, email, device_hash, ip_address, expires_at, created_at,
+		COALESCE(used_at, '') FROM Magic_Links WHERE token_hash = ?`, tokenHash).Scan(
+		&link.TokenHash, &link.UserId, &link.Email, &link.DeviceHash, &link.IPAddress, &link.ExpiresAt, &link.CreatedAt, &link.UsedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+func (m *mySql) UseMagicLink(tokenHash, usedAt string) (bool, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
+
+	result, err := m.conn.ExecContext(ctx, `UPDATE Magic_Links SET used_at = ? WHERE token_hash = ? AND used_at IS NULL`,
+		usedAt, tokenHash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (m *mySql) CountMagicLinks(email, ipAddress, since string) (int, int, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
+
+	var byEmail, byIP int
+	err := m.conn.QueryRowContext(ctx, `SELECT
+		(SELECT COUNT(*) FROM Magic_Links WHERE email = ? AND created_at >= ?),
+		(SELECT COUNT(*) FROM Magic_Links WHERE ip_address = ? AND created_at >= ?)`,
+		email, since, ipAddress, since).Scan(&byEmail, &byIP)
+	if err != nil {
+		return 0, 0, err
+	}
+	return byEmail, byIP, nil
+}
+
+func (m *mySql) AddLoginAlert(req *userEntity.LoginAlert) error {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
+
+	_, err := m.conn.ExecContext(ctx, `INSERT INTO Login_Alerts(token_hash, user_id, session_id, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?)`, req.TokenHash, req.UserId, req.SessionId, req.ExpiresAt, req.CreatedAt)
+	return err
+}
+
+func (m *mySql) GetLoginAlert(tokenHash string) (*userEntity.LoginAlert, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
+
+	var alert userEntity.LoginAlert
+	err := m.conn.QueryRowContext(ctx, `SELECT token_hash, user_id, session_id, expires_at, created_at, COALESCE(used_at, '')
+		FROM Login_Alerts WHERE token_hash = ?`, tokenHash).Scan(
+		&alert.TokenHash, &alert.UserId, &alert.SessionId, &alert.ExpiresAt, &alert.CreatedAt, &alert.UsedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+func (m *mySql) UseLoginAlert(tokenHash, usedAt string) (bool, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
+
+	result, err := m.conn.ExecContext(ctx, `UPDATE Login_Alerts SET used_at = ? WHERE token_hash = ? AND used_at IS NULL`,
+		usedAt, tokenHash)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (m *mySql) DeleteExpiredLoginAlerts(before string) error {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
+
+	_, err := m.conn.ExecContext(ctx, `DELETE FROM Login_Alerts WHERE expires_at < ?`, before)
+	return err
+}
+
+func (m *mySql) GetSocialIdentity(provider, subject string) (*userEntity.SocialIdentity, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
+
+	var identity userEntity.SocialIdentity
+	err := m.conn.QueryRowContext(ctx, `SELECT provider, subject, user_id, email, created_at
+		FROM Social_Identities WHERE provider = ? AND subject = ?`, provider, subject).Scan(
+		&identity.Provider, &identity.Subject, &identity.UserId, &identity.Email, &identity.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (m *mySql) LinkSocialIdentity(req *userEntity.SocialIdentity) error {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
+
+	_, err := m.conn.ExecContext(ctx, `INSERT INTO Social_Identities(provider, subject, user_id, email, created_at)
+		VALUES (?, ?, ?, ?, ?)`, req.Provider, req.Subject, req.UserId, req.Email, req.CreatedAt)
+	return err
+}
+
+func (m *mySql) CountEmailVerifications(userId, since string) (int, string, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
+
+	var count int
+	var latest string
+	err := m.conn.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(MAX(created_at), '')
+		FROM Email_Verifications WHERE user_id = ? AND created_at >= ?`, userId, since).Scan(&count, &latest)
+	if err != nil {
+		return 0, "", err
+	}
+	return count, latest, nil
+}
+
+func (m *mySql) VerifyEmail(userId, email string) error {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*60)
+	defer cancelFunc()
+
+	tx, err := m.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		} else {
+			tx.Commit()
+		}
+	}()
+
+	_, err = tx.ExecContext(ctx, `UPDATE Users SET email = ?, pending_email = NULL,
+			account_status = IF(account_status = ?, ?, account_status)
+		WHERE user_id = ?`, email, userEntity.AccountUnverified, userEntity.AccountActive, userId)
+	if err != nil {
+		return err
+	}
+	// links sent before are spent once any of them is used
+	_, err = tx.ExecContext(ctx, `DELETE FROM Email_Verifications WHERE user_id = ?`, userId)
+	return err
+}
@@ -11,12 +11,43 @@ type UserRepository interface {
 	GetById(user_id string) (*userEntity.GetByIdRes, error)
 	UpdateUser(req *userEntity.UpdateUserReq, userId string) error
 	UpdateImage(userId, fileName string) error
-	DeleteUser(user_id string) error
+	// ChangePassword also lifts a password reset RequirePasswordReset asked for.
 	ChangePassword(userId, newPassword string) error
-	AddToken(req *userEntity.ResetPasswordRes) error
-	GetTokenById(token, userId string) (*userEntity.ResetPasswordWithTokenRes, error)
-	DeleteToken(tokenId string) error
+	RequirePasswordReset(userId string) error
+	// AddPasswordReset stores a reset, spending the resets of the same user that are still open.
+	AddPasswordReset(req *userEntity.PasswordReset) error
+	GetPasswordReset(resetId string) (*userEntity.PasswordReset, error)
+	// FailPasswordReset counts a wrong secret tried for the reset.
+	FailPasswordReset(resetId string) error
+	// UsePasswordReset spends a reset, it returns false when the reset was spent already.
+	UsePasswordReset(resetId, usedAt string) (bool, error)
+	// CountPasswordResets returns how many resets were asked for since, for the email and from the IP address.
+	CountPasswordResets(email, ipAddress, since string) (int, int, error)
 	AssignVAToUser(user_id, token_id string) error
+	//email verification
+	SetPendingEmail(userId, email string) error
+	AddEmailVerification(req *userEntity.EmailVerification) error
+	GetEmailVerification(tokenHash string) (*userEntity.EmailVerification, error)
+	// CountEmailVerifications returns how many links were sent to the user since, and when the last one was.
+	CountEmailVerifications(userId, since string) (int, string, error)
+	// VerifyEmail makes email the user's address, activates the account and spends every link sent to the user.
+	VerifyEmail(userId, email string) error
+	//magic links
+	AddMagicLink(req *userEntity.MagicLink) error
+	GetMagicLink(tokenHash string) (*userEntity.MagicLink, error)
+	// UseMagicLink spends a link, it returns false when the link was used already.
+	UseMagicLink(tokenHash, usedAt string) (bool, error)
+	// CountMagicLinks returns how many links were asked for since, for the email and from the IP address.
+	CountMagicLinks(email, ipAddress, since string) (int, int, error)
+	//login alerts
+	AddLoginAlert(req *userEntity.LoginAlert) error
+	GetLoginAlert(tokenHash string) (*userEntity.LoginAlert, error)
+	// UseLoginAlert spends an alert's link, it returns false when the link was used already.
+	UseLoginAlert(tokenHash, usedAt string) (bool, error)
+	DeleteExpiredLoginAlerts(before string) error
+	//social login
+	GetSocialIdentity(provider, subject string) (*userEntity.SocialIdentity, error)
+	LinkSocialIdentity(req *userEntity.SocialIdentity) error
 	//user settings functions
 	GetNotificationSettingsById(userId string) (*userEntity.NotificationSettingsRes, error)
 	GetProductEmailSettingsById(userId string) (*userEntity.ProductEmailSettingsRes, error)
@@ -19,3 +19,10 @@ func NewInternalServiceError(error any) *ServiceError {
 func NewValidatingError(error any) *ServiceError {
 	return &ServiceError{Time: time.Now().Format(time.RFC3339), Description: "BadInput Request", Error: error}
 }
+
+// FieldError is a rule a field of a request breaks, for a form to show next to the field
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
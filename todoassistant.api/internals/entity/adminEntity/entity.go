@@ -0,0 +1,70 @@
+package adminEntity
+
+import "test-va/internals/entity/loggerEntity"
+
+type SearchUsersReq struct {
+	// Query matches the start of the email, first name, last name or phone
+	Query  string `form:"q" json:"q" validate:"max=100"`
+	Status string `form:"status" json:"status" validate:"omitempty,oneof=ACTIVE UNVERIFIED SUSPENDED"`
+	Page   int    `form:"page" json:"page" validate:"omitempty,min=1"`
+}
+
+type UserSummary struct {
+	UserId        string `json:"user_id"`
+	FirstName     string `json:"first_name"`
+	LastName      string `json:"last_name"`
+	Email         string `json:"email"`
+	Phone         string `json:"phone"`
+	AccountStatus string `json:"account_status"`
+	PaymentStatus string `json:"payment_status"`
+	DateCreated   string `json:"date_created"`
+}
+
+type SearchUsersRes struct {
+	Users []*UserSummary `json:"users"`
+	Page  int            `json:"page"`
+}
+
+// Account is what a master sees of a user.
+type Account struct {
+	UserSummary
+	// VA is nil when no VA is assigned to the user
+	VA *AssignedVA `json:"va"`
+	// Suspension is the suspension the account is under, nil when it is not suspended
+	Suspension *Suspension `json:"suspension"`
+	Activity   *Activity   `json:"activity"`
+}
+
+type AssignedVA struct {
+	VaId      string `json:"va_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+}
+
+type Activity struct {
+	// Tasks counts the user's tasks by status
+	Tasks          map[string]int `json:"tasks"`
+	ActiveSessions int            `json:"active_sessions"`
+	LastLoginAt    string         `json:"last_login_at"`
+	LastSeenAt     string         `json:"last_seen_at"`
+	// RecentEvents are the latest audit logs of the user's own actions
+	RecentEvents []*loggerEntity.AuditLog `json:"recent_events"`
+}
+
+// Suspension is kept after the account is reactivated, so past suspensions can be looked up.
+type Suspension struct {
+	SuspensionId string `json:"suspension_id"`
+	UserId       string `json:"-"`
+	Reason       string `json:"reason"`
+	// PreviousStatus is the status the account gets back when it is reactivated
+	PreviousStatus string `json:"-"`
+	SuspendedBy    string `json:"suspended_by"`
+	SuspendedAt    string `json:"suspended_at"`
+	ReactivatedBy  string `json:"-"`
+	ReactivatedAt  string `json:"-"`
+}
+
+type SuspendReq struct {
+	Reason string `json:"reason" validate:"required,max=255"`
+}
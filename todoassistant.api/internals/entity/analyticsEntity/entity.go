@@ -0,0 +1,61 @@
+package analyticsEntity
+
+type AnalyticsReq struct {
+	UserId   string `json:"user_id"`
+	Period   string `form:"period" json:"period" validate:"omitempty,oneof=day week"`
+	From     string `form:"from" json:"from" validate:"omitempty,datetime=2006-01-02"`
+	To       string `form:"to" json:"to" validate:"omitempty,datetime=2006-01-02"`
+	TimeZone string `form:"tz" json:"tz"`
+}
+
+// TaskRecord is the slice of a task the aggregates are computed from.
+type TaskRecord struct {
+	TaskId          string
+	ProjectId       string
+	ProjectTitle    string
+	Status          string
+	CreatedAt       string
+	EndTime         string
+	CompletedAt     string
+	VaId            string
+	FirstResponseAt string
+	Labels          []string
+}
+
+type SeriesPoint struct {
+	Date      string `json:"date"`
+	Created   int    `json:"created"`
+	Completed int    `json:"completed"`
+}
+
+type Breakdown struct {
+	Id         string  `json:"id"`
+	Name       string  `json:"name"`
+	Total      int     `json:"total"`
+	Completed  int     `json:"completed"`
+	OnTimeRate float64 `json:"on_time_rate"`
+}
+
+type VATurnaround struct {
+	AssignedTasks             int     `json:"assigned_tasks"`
+	CompletedTasks            int     `json:"completed_tasks"`
+	AverageFirstResponseHours float64 `json:"average_first_response_hours"`
+	AverageCompletionHours    float64 `json:"average_completion_hours"`
+}
+
+type AnalyticsRes struct {
+	Period                 string        `json:"period"`
+	From                   string        `json:"from"`
+	To                     string        `json:"to"`
+	TimeZone               string        `json:"tz"`
+	TotalCreated           int           `json:"total_created"`
+	TotalCompleted         int           `json:"total_completed"`
+	OnTimeRate             float64       `json:"on_time_rate"`
+	AverageLatenessMinutes float64       `json:"average_lateness_minutes"`
+	CurrentStreak          int           `json:"current_streak"`
+	LongestStreak          int           `json:"longest_streak"`
+	Series                 []SeriesPoint `json:"series"`
+	Projects               []Breakdown   `json:"projects"`
+	Labels                 []Breakdown   `json:"labels"`
+	VATurnaround           VATurnaround  `json:"va_turnaround"`
+}
@@ -0,0 +1,49 @@
+package attachmentEntity
+
+const (
+	StatusPending  = "PENDING"
+	StatusUploaded = "UPLOADED"
+)
+
+type CreateUploadReq struct {
+	AttachmentId string `json:"attachment_id"`
+	TaskId       string `json:"task_id"`
+	CommentId    string `json:"comment_id"`
+	UploaderId   string `json:"uploader_id"`
+	FileName     string `json:"file_name" validate:"required,max=255"`
+	MimeType     string `json:"mime_type" validate:"required"`
+	Size         int64  `json:"size" validate:"required,gt=0"`
+	Checksum     string `json:"checksum" validate:"required,len=64,hexadecimal"`
+	StorageKey   string `json:"-"`
+	Status       string `json:"status"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type CreateUploadRes struct {
+	AttachmentId string            `json:"attachment_id"`
+	UploadUrl    string            `json:"upload_url"`
+	Method       string            `json:"method"`
+	Headers      map[string]string `json:"headers"`
+	ExpiresAt    string            `json:"expires_at"`
+}
+
+type AttachmentRes struct {
+	AttachmentId string `json:"attachment_id"`
+	TaskId       string `json:"task_id"`
+	CommentId    string `json:"comment_id"`
+	UploaderId   string `json:"uploader_id"`
+	FileName     string `json:"file_name"`
+	MimeType     string `json:"mime_type"`
+	Size         int64  `json:"size"`
+	Checksum     string `json:"checksum"`
+	StorageKey   string `json:"-"`
+	Status       string `json:"status"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type DownloadRes struct {
+	AttachmentId string `json:"attachment_id"`
+	FileName     string `json:"file_name"`
+	DownloadUrl  string `json:"download_url"`
+	ExpiresAt    string `json:"expires_at"`
+}
@@ -0,0 +1,28 @@
+package digestEntity
+
+// Recipient is a user who opted into the daily digest.
+type Recipient struct {
+	UserId       string
+	Email        string
+	FirstName    string
+	ReminderTime string
+	TimeZone     string
+	// LastSentOn is the local date (YYYY-MM-DD) of the last digest sent, empty if none was sent.
+	LastSentOn string
+}
+
+type DigestTask struct {
+	TaskId      string
+	Title       string
+	Status      string
+	EndTime     string
+	CompletedAt string
+}
+
+type DigestComment struct {
+	TaskId    string
+	TaskTitle string
+	VaName    string
+	Comment   string
+	CreatedAt string
+}
@@ -8,16 +8,77 @@ type LogRecord struct {
 	Category string // The log group
 }
 
-// AuditLog Audit log
+// Actions recorded in the audit log
+const (
+	AuditLogin          = "login"
+	AuditLoginTwoFactor = "login.2fa"
+	AuditLoginMagicLink = "login.magic_link"
+	AuditLoginPasskey   = "login.passkey"
+	AuditLoginSocial    = "login.social"
+	AuditTokenRefresh   = "token.refresh"
+	AuditPasswordChange = "password.change"
+	AuditPasswordReset  = "password.reset"
+	AuditVASignup       = "va.signup"
+	AuditVADelete       = "va.delete"
+	AuditVAPassword     = "va.password_change"
+	AuditVAAssign       = "va.assign"
+	AuditAccountUnlock  = "account.unlock"
+	AuditMemberRole     = "project.member_role"
+	AuditUserSuspend    = "user.suspend"
+	AuditUserReactivate = "user.reactivate"
+	AuditUserLogout     = "user.force_logout"
+	AuditExport         = "audit.export"
+)
+
+// Outcomes of an audited request, a failure is a request answered with an error status
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Kinds of target an audited action is done to
+const (
+	TargetUser   = "user"
+	TargetVA     = "va"
+	TargetEmail  = "email"
+	TargetMember = "project_member"
+)
+
+// AuditLog is an authentication or admin event. Audit logs are only ever added, never changed.
 type AuditLog struct {
-	Date           string  `json:"date"`
-	Username       string  `json:"Username"`
-	RequestHeader  any     `json:"request_header"`
-	Request        any     `json:"request"`
-	StatusCode     int     `json:"status_code"`
-	ResponseHeader any     `json:"response_header"`
-	Response       any     `json:"response"`
-	ClientID       string  `json:"client_id"`
-	Route          string  `json:"route"`
-	Duration       float64 `json:"duration (seconds)"`
+	// Id orders the logs, a later log always has a higher id
+	Id      int64  `json:"id"`
+	Action  string `json:"action"`
+	Outcome string `json:"outcome"`
+	// ActorId is empty when nobody was logged in, as for a failed login
+	ActorId    string `json:"actor_id"`
+	ActorRole  string `json:"actor_role"`
+	TargetType string `json:"target_type"`
+	TargetId   string `json:"target_id"`
+	// Detail says what changed, as the role given
+	Detail     string `json:"detail"`
+	IPAddress  string `json:"ip_address"`
+	UserAgent  string `json:"user_agent"`
+	StatusCode int    `json:"status_code"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// AuditFilter narrows the audit logs listed, empty fields match every log.
+type AuditFilter struct {
+	Action    string `form:"action" json:"action"`
+	Outcome   string `form:"outcome" json:"outcome" validate:"omitempty,oneof=success failure"`
+	ActorId   string `form:"actor_id" json:"actor_id"`
+	TargetId  string `form:"target_id" json:"target_id"`
+	IPAddress string `form:"ip_address" json:"ip_address"`
+	From      string `form:"from" json:"from" validate:"omitempty,datetime=2006-01-02"`
+	To        string `form:"to" json:"to" validate:"omitempty,datetime=2006-01-02"`
+	// Before pages through the logs, it is the next_before of the previous page
+	Before int64 `form:"before" json:"before" validate:"omitempty,min=1"`
+	Limit  int   `form:"limit" json:"limit" validate:"omitempty,min=1,max=500"`
+}
+
+type AuditLogsRes struct {
+	Logs []*AuditLog `json:"logs"`
+	// NextBefore is zero on the last page
+	NextBefore int64 `json:"next_before"`
 }
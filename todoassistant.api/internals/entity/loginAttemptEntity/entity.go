@@ -0,0 +1,18 @@
+package loginAttemptEntity
+
+// Attempts are the failed logins recorded for an account or an IP address.
+type Attempts struct {
+	// Key names an account, by its type and email, or an IP address
+	Key           string
+	Failures      int
+	LastFailureAt string
+	// BlockedUntil is when the key may be tried again
+	BlockedUntil string
+	// LockedUntil is set while the account is locked, it is only lifted by time or a master
+	LockedUntil string
+}
+
+type UnlockReq struct {
+	AccountType string `json:"account_type" validate:"required,oneof=user va"`
+	Email       string `json:"email" validate:"required,email"`
+}
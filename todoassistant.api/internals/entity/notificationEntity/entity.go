@@ -12,6 +12,8 @@ type CreateNotification struct {
 	NotificationId string `json:"notification_id"`
 	UserId         string `json:"user_id"`
 	DeviceId       string `json:"device_id"`
+	// SessionId is the login the device registered from, the device is unregistered when it is revoked
+	SessionId string `json:"-"`
 }
 
 type NotificationBody struct {
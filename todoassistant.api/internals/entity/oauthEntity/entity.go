@@ -0,0 +1,113 @@
+package oauthEntity
+
+// OAuth error codes, as RFC 6749 names them
+const (
+	ErrInvalidRequest       = "invalid_request"
+	ErrInvalidClient        = "invalid_client"
+	ErrInvalidGrant         = "invalid_grant"
+	ErrInvalidScope         = "invalid_scope"
+	ErrUnsupportedGrantType = "unsupported_grant_type"
+	ErrAccessDenied         = "access_denied"
+)
+
+// Client is an app registered to use the api for its users.
+type Client struct {
+	ClientId string `json:"client_id"`
+	// OwnerId is the user who registered the app
+	OwnerId      string   `json:"-"`
+	Name         string   `json:"name"`
+	RedirectUris []string `json:"redirect_uris"`
+	// Confidential clients, such as a web server, have a secret. Public ones, such as a browser
+	// extension, cannot keep one and rely on PKCE alone.
+	Confidential bool   `json:"confidential"`
+	SecretHash   string `json:"-"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type CreateClientReq struct {
+	Name         string   `json:"name" validate:"required,max=100"`
+	RedirectUris []string `json:"redirect_uris" validate:"required,min=1,max=10,dive,url"`
+	Confidential bool     `json:"confidential"`
+}
+
+type CreateClientRes struct {
+	*Client
+	// ClientSecret is only set for confidential clients and cannot be shown again
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// AuthorizeReq is the authorization request an app sends the user to the consent page of the web app with.
+type AuthorizeReq struct {
+	ResponseType        string `form:"response_type" json:"response_type" validate:"required"`
+	ClientId            string `form:"client_id" json:"client_id" validate:"required"`
+	RedirectUri         string `form:"redirect_uri" json:"redirect_uri" validate:"required"`
+	Scope               string `form:"scope" json:"scope" validate:"required"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge" validate:"required"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method" validate:"required"`
+}
+
+// ConsentRes is what the consent page shows the user.
+type ConsentRes struct {
+	ClientId    string   `json:"client_id"`
+	ClientName  string   `json:"client_name"`
+	RedirectUri string   `json:"redirect_uri"`
+	Scopes      []string `json:"scopes"`
+}
+
+type ApproveReq struct {
+	AuthorizeReq
+	// Approve is false when the user denied the app access
+	Approve bool `json:"approve"`
+}
+
+type ApproveRes struct {
+	// RedirectTo is where the web app sends the user back to the app, with a code or an error
+	RedirectTo string `json:"redirect_to"`
+}
+
+// AuthCode is an authorization code waiting to be traded for tokens, only its hash is stored.
+type AuthCode struct {
+	CodeHash      string
+	ClientId      string
+	UserId        string
+	RedirectUri   string
+	Scopes        []string
+	CodeChallenge string
+	ExpiresAt     string
+	CreatedAt     string
+	UsedAt        string
+}
+
+// TokenReq is a token request, form encoded as the spec asks.
+type TokenReq struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectUri  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	ClientId     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+type TokenRes struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// RevokeReq is a token revocation request as in RFC 7009.
+type RevokeReq struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientId      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// ErrorRes is an error from the token endpoints, shaped as the spec asks.
+type ErrorRes struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
@@ -0,0 +1,125 @@
+package passkeyEntity
+
+import "test-va/internals/entity/tokenEntity"
+
+const (
+	AccountUser = "user"
+	AccountVA   = "va"
+
+	CeremonyRegistration = "registration"
+	CeremonyLogin        = "login"
+)
+
+// Passkey is a WebAuthn credential registered to an account.
+type Passkey struct {
+	CredentialId string `json:"credential_id"`
+	AccountId    string `json:"-"`
+	AccountType  string `json:"-"`
+	Name         string `json:"name"`
+	// PublicKey is the COSE encoded key from the authenticator
+	PublicKey []byte `json:"-"`
+	Algorithm int64  `json:"-"`
+	// SignCount only grows on authenticators that keep a counter, a lower value means a cloned key
+	SignCount  uint32 `json:"-"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at"`
+}
+
+// Challenge is kept between the start and the end of a ceremony.
+type Challenge struct {
+	ChallengeHash string
+	// AccountId is empty for logins that let the authenticator pick the passkey
+	AccountId   string
+	AccountType string
+	Ceremony    string
+	ExpiresAt   string
+}
+
+type RelyingParty struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserInfo struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions are passed to navigator.credentials.create, binary values are base64url encoded.
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	RP                     RelyingParty           `json:"rp"`
+	User                   UserInfo               `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are passed to navigator.credentials.get, binary values are base64url encoded.
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	RPId             string                 `json:"rpId"`
+	Timeout          int64                  `json:"timeout"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+type AttestationResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+	AttestationObject string `json:"attestationObject" binding:"required"`
+}
+
+// RegistrationReq is the credential navigator.credentials.create returned, with a name to tell it apart.
+type RegistrationReq struct {
+	Id       string              `json:"id" binding:"required"`
+	Name     string              `json:"name"`
+	Response AttestationResponse `json:"response" binding:"required"`
+}
+
+type AssertionResponse struct {
+	ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+	AuthenticatorData string `json:"authenticatorData" binding:"required"`
+	Signature         string `json:"signature" binding:"required"`
+	UserHandle        string `json:"userHandle"`
+}
+
+// AssertionReq is the credential navigator.credentials.get returned.
+type AssertionReq struct {
+	Id       string            `json:"id" binding:"required"`
+	Response AssertionResponse `json:"response" binding:"required"`
+	tokenEntity.SessionInfo
+}
+
+// Assertion is who logged in with a passkey.
+type Assertion struct {
+	AccountId string
+	// UserVerified is set when the authenticator checked a PIN or biometric, the login then has two factors
+	UserVerified bool
+}
+
+type LoginBeginReq struct {
+	// Email limits the login to the passkeys of that account, without it the authenticator offers its passkeys
+	Email string `json:"email"`
+}
+
+type RenameReq struct {
+	Name string `json:"name" binding:"required"`
+}
@@ -0,0 +1,70 @@
+package privacyEntity
+
+// Kinds of privacy requests
+const (
+	KindExport  = "EXPORT"
+	KindErasure = "ERASURE"
+)
+
+// Statuses of privacy requests. Exports go from PENDING to READY, or FAILED, and EXPIRED once
+// their archive is deleted. Erasures stay PENDING for the grace period, then become DONE unless CANCELLED.
+const (
+	StatusPending   = "PENDING"
+	StatusReady     = "READY"
+	StatusFailed    = "FAILED"
+	StatusExpired   = "EXPIRED"
+	StatusCancelled = "CANCELLED"
+	StatusDone      = "DONE"
+)
+
+// Request is an export or erasure of a user's data.
+type Request struct {
+	RequestId string
+	UserId    string
+	Kind      string
+	Status    string
+	// RequestedBy is the user themselves or the master who asked for an erasure
+	RequestedBy string
+	// ArchiveKey is where the archive of a ready export is stored
+	ArchiveKey  string
+	RequestedAt string
+	// ScheduledFor is when an erasure runs, the end of its grace period
+	ScheduledFor string
+	CompletedAt  string
+	// ExpiresAt is when the archive of an export is deleted
+	ExpiresAt string
+}
+
+type ExportRes struct {
+	RequestId   string `json:"request_id"`
+	Status      string `json:"status"`
+	RequestedAt string `json:"requested_at"`
+	// DownloadUrl is only set once the export is READY, it works for an hour
+	DownloadUrl string `json:"download_url,omitempty"`
+	ExpiresAt   string `json:"expires_at,omitempty"`
+}
+
+type ErasureRes struct {
+	RequestId    string `json:"request_id"`
+	Status       string `json:"status"`
+	RequestedAt  string `json:"requested_at"`
+	ScheduledFor string `json:"scheduled_for"`
+}
+
+// Table is the rows of one table in a user's data, every value as text.
+type Table []map[string]any
+
+// ErasureRecord is kept after an erasure to show it happened, it holds no personal data.
+type ErasureRecord struct {
+	RecordId  string
+	UserId    string
+	RequestId string
+	// RequestedBy is the user or the master who asked for the erasure
+	RequestedBy string
+	RequestedAt string
+	ErasedAt    string
+	// Rows is how many rows were deleted from each table, as json
+	Rows string
+	// StorageObjects is how many stored files were deleted
+	StorageObjects int
+}
@@ -5,6 +5,7 @@ type CreateProjectReq struct {
 	Title     string `json:"title" validate:"required,min=3,max=20"`
 	Color     string `json:"color" validate:"required,min=3"`
 	UserId    string `json:"user_id" validate:"required"`
+	ParentId  string `json:"parent_id"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
 }
@@ -14,6 +15,7 @@ type CreateProjectRes struct {
 	UserId    string `json:"user_id" validate:"required"`
 	Title     string `json:"title" validate:"required,min=3"`
 	Color     string `json:"color" validate:"required,min=3"`
+	ParentId  string `json:"parent_id,omitempty"`
 }
 
 type EditProjectReq struct {
@@ -33,8 +35,168 @@ type EditProjectRes struct {
 }
 
 type GetProjectRes struct {
+	ProjectId  string       `json:"project_id"`
+	Title      string       `json:"title"`
+	Color      string       `json:"color"`
+	UserId     string       `json:"user_id"`
+	Role       string       `json:"role"`
+	ArchivedAt string       `json:"archived_at,omitempty"`
+	Stats      ProjectStats `json:"stats"`
+	ParentId   string       `json:"parent_id,omitempty"`
+	// Path and Depth place the project in the flattened view, e.g. "Work / Client A / Q3 launch"
+	Path     string           `json:"path,omitempty"`
+	Depth    int              `json:"depth"`
+	Children []*GetProjectRes `json:"children,omitempty"`
+}
+
+// What happens to the sub projects of a deleted project.
+const (
+	DeleteReparent = "reparent"
+	DeleteCascade  = "cascade"
+)
+
+type MoveProjectReq struct {
+	ProjectId string `json:"project_id"`
+	UserId    string `json:"user_id"`
+	// ParentId is the new parent, empty moves the project to the top level
+	ParentId string `json:"parent_id"`
+}
+
+type ProjectStats struct {
+	OpenTasks      int    `json:"open_tasks"`
+	CompletedTasks int    `json:"completed_tasks"`
+	OverdueTasks   int    `json:"overdue_tasks"`
+	NextDueDate    string `json:"next_due_date,omitempty"`
+}
+
+// TaskSummary is the slice of a task the project stats are computed from.
+type TaskSummary struct {
+	ProjectId string
+	Status    string
+	EndTime   string
+}
+
+// Member roles. Viewers can read tasks and comment on them, editors can also
+// create and change tasks, only the owner manages the project and its members.
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+const (
+	InvitationPending  = "PENDING"
+	InvitationAccepted = "ACCEPTED"
+	InvitationDeclined = "DECLINED"
+)
+
+type InviteMemberReq struct {
+	ProjectId string `json:"project_id"`
+	UserId    string `json:"user_id"`
+	Email     string `json:"email" validate:"required,email"`
+	Role      string `json:"role" validate:"required,oneof=editor viewer"`
+}
+
+type Invitation struct {
+	InvitationId string `json:"invitation_id"`
+	ProjectId    string `json:"project_id"`
+	ProjectTitle string `json:"project_title"`
+	Email        string `json:"email"`
+	Role         string `json:"role"`
+	InvitedBy    string `json:"invited_by"`
+	Status       string `json:"status"`
+	CreatedAt    string `json:"created_at"`
+}
+
+type ProjectMember struct {
+	ProjectId string `json:"project_id"`
+	UserId    string `json:"user_id"`
+	Name      string `json:"name"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	CreatedAt string `json:"created_at"`
+}
+
+type UpdateMemberReq struct {
+	ProjectId string `json:"project_id"`
+	UserId    string `json:"user_id"`
+	MemberId  string `json:"member_id"`
+	Role      string `json:"role" validate:"required,oneof=editor viewer"`
+}
+
+// TemplateSourceTask is a task of the project a template is saved from.
+type TemplateSourceTask struct {
+	Title       string
+	Description string
+	Repeat      string
+	StartTime   string
+	EndTime     string
+}
+
+type SaveTemplateReq struct {
+	ProjectId string `json:"project_id"`
+	UserId    string `json:"user_id"`
+	// Title defaults to the title of the project
+	Title string `json:"title" validate:"omitempty,min=3,max=50"`
+}
+
+// TemplateTask times are stored as minutes after midnight of the day the template is started on.
+type TemplateTask struct {
+	Title              string `json:"title"`
+	Description        string `json:"description"`
+	Repeat             string `json:"repeat"`
+	StartOffsetMinutes int    `json:"start_offset_minutes"`
+	DueOffsetMinutes   int    `json:"due_offset_minutes"`
+}
+
+type ProjectTemplate struct {
+	TemplateId string         `json:"template_id"`
+	UserId     string         `json:"user_id"`
+	Title      string         `json:"title"`
+	Color      string         `json:"color"`
+	CreatedAt  string         `json:"created_at"`
+	Tasks      []TemplateTask `json:"tasks"`
+}
+
+type InstantiateTemplateReq struct {
+	TemplateId string `json:"template_id"`
+	UserId     string `json:"user_id"`
+	StartDate  string `json:"start_date" validate:"required,datetime=2006-01-02"`
+	// Title defaults to the title of the template
+	Title string `json:"title" validate:"omitempty,min=3,max=20"`
+}
+
+type InstantiateTemplateRes struct {
+	ProjectId string   `json:"project_id"`
+	Title     string   `json:"title"`
+	Color     string   `json:"color"`
+	TaskIds   []string `json:"task_ids"`
+}
+
+type Section struct {
+	SectionId string `json:"section_id"`
 	ProjectId string `json:"project_id"`
 	Title     string `json:"title"`
-	Color     string `json:"color"`
+	Position  int    `json:"position"`
+	CreatedAt string `json:"created_at"`
+}
+
+type CreateSectionReq struct {
+	ProjectId string `json:"project_id"`
+	UserId    string `json:"user_id"`
+	Title     string `json:"title" validate:"required,max=100"`
+}
+
+type RenameSectionReq struct {
+	SectionId string `json:"section_id"`
+	ProjectId string `json:"project_id"`
+	UserId    string `json:"user_id"`
+	Title     string `json:"title" validate:"required,max=100"`
+}
+
+type ReorderSectionsReq struct {
+	ProjectId string `json:"project_id"`
 	UserId    string `json:"user_id"`
+	// SectionIds lists every section of the project in its new order
+	SectionIds []string `json:"section_ids" validate:"required,min=1,unique"`
 }
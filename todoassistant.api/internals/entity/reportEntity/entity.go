@@ -0,0 +1,52 @@
+package reportEntity
+
+const (
+	RoleUser = "user"
+	RoleVA   = "VA"
+)
+
+type WeeklyReportReq struct {
+	OwnerId string `json:"owner_id"`
+	Role    string `json:"role"`
+	// Week is an ISO week (2023-W10) or any date inside the week (2023-03-08), defaults to last week
+	Week string `form:"week" json:"week"`
+}
+
+// ReportOwner is a user or VA a weekly review is generated for.
+type ReportOwner struct {
+	Id        string
+	Email     string
+	FirstName string
+	Role      string
+}
+
+type ReportTask struct {
+	TaskId      string `json:"task_id"`
+	Title       string `json:"title"`
+	UserId      string `json:"user_id"`
+	UserName    string `json:"user_name"`
+	Status      string `json:"status"`
+	EndTime     string `json:"end_time"`
+	CompletedAt string `json:"completed_at,omitempty"`
+}
+
+type ReportComment struct {
+	TaskId    string `json:"task_id"`
+	TaskTitle string `json:"task_title"`
+	SenderId  string `json:"sender_id"`
+	Comment   string `json:"comment"`
+	CreatedAt string `json:"created_at"`
+}
+
+type WeeklyReport struct {
+	OwnerId       string          `json:"owner_id"`
+	Role          string          `json:"role"`
+	WeekStart     string          `json:"week_start"`
+	WeekEnd       string          `json:"week_end"`
+	Completed     []ReportTask    `json:"completed"`
+	Slipped       []ReportTask    `json:"slipped"`
+	Upcoming      []ReportTask    `json:"upcoming"`
+	MinutesLogged int             `json:"minutes_logged"`
+	AwaitingReply []ReportComment `json:"awaiting_reply"`
+	GeneratedAt   string          `json:"generated_at"`
+}
@@ -13,10 +13,12 @@ type CreateTaskReq struct {
 	Repeat        string     `json:"repeat"`
 	Assigned      string     `json:"assigned"`
 	Files         []TaskFile `json:"files"`
+	Labels        []string   `json:"labels"`
 	StartTime     string     `json:"start_time"`
 	EndTime       string     `json:"end_time"`
 	VAOption      string     `json:"va_option"`
 	ProjectId     string     `json:"project_id"`
+	SectionId     string     `json:"section_id"`
 	Notify        bool       `json:"notify"`
 	Status        string     `json:"status"`
 	CreatedAt     string     `json:"created_at"`
@@ -30,6 +32,7 @@ type EditTaskReq struct {
 	Repeat        string     `json:"repeat"`
 	Assigned      string     `json:"assigned"`
 	Files         []TaskFile `json:"files"`
+	Labels        []string   `json:"labels"`
 	StartTime     string     `json:"start_time"`
 	EndTime       string     `json:"end_time"`
 	ProjectId     string     `json:"project_id"`
@@ -67,6 +70,7 @@ type CreateTaskRes struct {
 	Assigned      string       `json:"assigned"`
 	Files         []TaskFile   `json:"files"`
 	ProjectId     string       `json:"project_id"`
+	SectionId     string       `json:"section_id"`
 	Notify        bool         `json:"notify"`
 	Status        string       `json:"status"`
 	CreatedAt     string       `json:"created_at"`
@@ -108,6 +112,7 @@ type GetTasksByIdRes struct {
 	Assigned      string       `json:"assigned"`
 	Files         []TaskFile   `json:"files"`
 	ProjectId     string       `json:"project_id"`
+	SectionId     string       `json:"section_id"`
 	Notify        bool         `json:"notify"`
 	Status        string       `json:"status"`
 	CreatedAt     string       `json:"created_at"`
@@ -132,6 +137,8 @@ type SearchTitleReq struct {
 // params for searched task
 type SearchTitleParams struct {
 	SearchQuery string `json:"search_query"`
+	// UserId limits the search to the tasks of one user
+	UserId string `json:"-"`
 }
 
 // response for searched task
@@ -157,6 +164,11 @@ type GetAllPendingRes struct {
 	EndTime string `json:"end_time"`
 }
 
+// TaskSectionReq moves a task into a section of its project, an empty SectionId takes it out of any section
+type TaskSectionReq struct {
+	SectionId string `json:"section_id"`
+}
+
 // GetAllTaskRes is the struct for task assocaited with a user
 type GetAllTaskRes struct {
 	TaskId        string       `json:"task_id"`
@@ -170,6 +182,7 @@ type GetAllTaskRes struct {
 	Assigned      string       `json:"assigned"`
 	Files         []TaskFile   `json:"files"`
 	ProjectId     string       `json:"project_id"`
+	SectionId     string       `json:"section_id"`
 	Notify        bool         `json:"notify"`
 	Status        string       `json:"status"`
 	CreatedAt     string       `json:"created_at"`
@@ -221,5 +234,14 @@ type GetCommentRes struct {
 }
 
 type UpdateTaskStatus struct {
-	Status string `json:"status" validate:"required,oneof=COMPLETED PENDING"`
+	Status    string `json:"status" validate:"required,oneof=COMPLETED PENDING"`
+	UpdatedAt string `json:"-"`
+}
+
+type LogTimeReq struct {
+	TaskId   string `json:"task_id"`
+	UserId   string `json:"user_id"`
+	Minutes  int    `json:"minutes" validate:"required,gt=0,lte=1440"`
+	Note     string `json:"note" validate:"max=255"`
+	LoggedAt string `json:"logged_at"`
 }
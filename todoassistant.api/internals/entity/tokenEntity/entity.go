@@ -4,3 +4,95 @@ type TokenRes struct {
 	Token        string `json:"token"`
 	RefreshToken string `json:"refresh_token"`
 }
+
+type RefreshReq struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshToken is the server side record of an issued refresh token.
+// Only a hash of the token's id is stored, never the token itself.
+type RefreshToken struct {
+	TokenHash string
+	// FamilyId is shared by every token rotated from the same login
+	FamilyId  string
+	UserId    string
+	ExpiresAt string
+	CreatedAt string
+	RotatedAt string
+	RevokedAt string
+}
+
+// SessionInfo describes the device a login happens from.
+// Only the device name is sent by clients, the rest is filled in from the request.
+type SessionInfo struct {
+	DeviceName string `json:"device_name"`
+	UserAgent  string `json:"-"`
+	IPAddress  string `json:"-"`
+	// TwoFactor is set when the login was confirmed with a second factor
+	TwoFactor bool `json:"-"`
+	// SessionId is filled in by CreateToken with the session the login started
+	SessionId string `json:"-"`
+	// ClientId and Scopes are set when an OAuth app is connected, DeviceName is then the app's name
+	ClientId string   `json:"-"`
+	Scopes   []string `json:"-"`
+}
+
+// Session is a login on one device, it lives as long as the refresh tokens issued for it.
+type Session struct {
+	SessionId   string `json:"session_id"`
+	UserId      string `json:"-"`
+	AccountType string `json:"account_type"`
+	DeviceName  string `json:"device_name"`
+	UserAgent   string `json:"user_agent"`
+	IPAddress   string `json:"ip_address"`
+	// DeviceId is the push token registered from the session, if any
+	DeviceId string `json:"device_id"`
+	// Fingerprint identifies the device by its user agent and network, it is empty for sessions
+	// from before fingerprints and those the user said were not theirs
+	Fingerprint string `json:"-"`
+	// ClientId is the OAuth app the session was started for, it is empty for logins
+	ClientId   string `json:"client_id,omitempty"`
+	CreatedAt  string `json:"created_at"`
+	LastSeenAt string `json:"last_seen_at"`
+	RevokedAt  string `json:"-"`
+	// Current marks the session the request was made from
+	Current bool `json:"current"`
+}
+
+// Scopes a personal access token or OAuth app can be given, each opens the routes of one part of the api
+const (
+	ScopeTasksRead     = "tasks:read"
+	ScopeTasksWrite    = "tasks:write"
+	ScopeProjects      = "projects"
+	ScopeNotifications = "notifications"
+)
+
+type CreateAccessTokenReq struct {
+	Name   string   `json:"name" binding:"required,max=100"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=tasks:read tasks:write projects notifications"`
+	// ExpiresInDays is optional, a token without it works until it is revoked
+	ExpiresInDays int `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+// AccessToken is a personal access token a user made for a script or integration.
+// Only a hash of the token is stored, it is shown once when it is made.
+type AccessToken struct {
+	TokenId     string `json:"token_id"`
+	UserId      string `json:"-"`
+	AccountType string `json:"-"`
+	Name        string `json:"name"`
+	TokenHash   string `json:"-"`
+	// Hint is the end of the token, to tell tokens apart
+	Hint       string   `json:"hint"`
+	Scopes     []string `json:"scopes"`
+	CreatedAt  string   `json:"created_at"`
+	ExpiresAt  string   `json:"expires_at"`
+	LastUsedAt string   `json:"last_used_at"`
+	RevokedAt  string   `json:"-"`
+}
+
+type CreateAccessTokenRes struct {
+	// Token cannot be shown again
+	Token string `json:"token"`
+	*AccessToken
+}
@@ -0,0 +1,49 @@
+package twoFactorEntity
+
+import "test-va/internals/entity/tokenEntity"
+
+// TwoFactor is the TOTP enrollment of a user or VA.
+type TwoFactor struct {
+	AccountId   string
+	AccountType string
+	// Secret is base32 encoded, as authenticator apps expect it
+	Secret       string
+	LastUsedStep int64
+	CreatedAt    string
+	// EnabledAt is empty until enrollment is confirmed with a first code
+	EnabledAt string
+}
+
+// Challenge counts the codes tried for a login challenge, it is kept until the challenge token expires.
+type Challenge struct {
+	// Jti is the id of the challenge token
+	Jti       string
+	AccountId string
+	Attempts  int
+	ExpiresAt string
+	// CompletedAt is set once a code was accepted, a challenge only logs in once
+	CompletedAt string
+}
+
+type EnrollRes struct {
+	Secret string `json:"secret"`
+	// URI is shown as a QR code for authenticator apps to scan
+	URI string `json:"otpauth_uri"`
+}
+
+type CodeReq struct {
+	// Code is a code from the authenticator app, or a recovery code where allowed
+	Code string `json:"code" binding:"required"`
+}
+
+type RecoveryCodesRes struct {
+	// RecoveryCodes are shown once, each of them can replace a code from the app one time
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// ChallengeReq is the second step of logging in with two-factor authentication.
+type ChallengeReq struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+	tokenEntity.SessionInfo
+}
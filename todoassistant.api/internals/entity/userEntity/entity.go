@@ -1,32 +1,46 @@
 package userEntity
 
+import "test-va/internals/entity/tokenEntity"
+
+// Account statuses
+const (
+	AccountActive = "ACTIVE"
+	// AccountUnverified accounts have not confirmed their email yet
+	AccountUnverified = "UNVERIFIED"
+	// AccountSuspended accounts were suspended by a master, they cannot log in and their tokens are refused
+	AccountSuspended = "SUSPENDED"
+)
+
 type CreateUserReq struct {
 	UserId        string `json:"user_id"`
 	FirstName     string `json:"first_name" validate:"required"`
 	LastName      string `json:"last_name"  validate:"required"`
 	Email         string `json:"email" validate:"email"`
 	Phone         string `json:"phone"`
-	Password      string `json:"password" validate:"required,min=6"`
+	Password      string `json:"password" validate:"required"`
 	Gender        string `json:"gender"`
 	DateOfBirth   string `json:"date_of_birth"`
 	AccountStatus string `json:"account_status"`
 	PaymentStatus string `json:"payment_status"`
 	DateCreated   string `json:"date_created"`
+	tokenEntity.SessionInfo
 }
 
 type CreateUserRes struct {
-	UserId       string `json:"user_id"`
-	FirstName    string `json:"first_name"`
-	LastName     string `json:"last_name"`
-	Email        string `json:"email"`
-	Phone        string `json:"phone"`
-	Token        string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	UserId        string `json:"user_id"`
+	FirstName     string `json:"first_name"`
+	LastName      string `json:"last_name"`
+	Email         string `json:"email"`
+	Phone         string `json:"phone"`
+	EmailVerified bool   `json:"email_verified"`
+	Token         string `json:"access_token"`
+	RefreshToken  string `json:"refresh_token"`
 }
 
 type LoginReq struct {
 	Email    string `json:"email" validate:"email"`
 	Password string `json:"password" validate:"required"`
+	tokenEntity.SessionInfo
 }
 
 type NotificationSettingsRes struct {
@@ -67,6 +81,7 @@ type ReminderSettingsReq struct {
 	AutoReminder string `json:"auto_reminder"`
 	ReminderTime string `json:"reminder_time"`
 	Refresh      string `json:"refresh"`
+	TimeZone     string `json:"time_zone"`
 }
 
 type UserSettingsRes struct {
@@ -80,6 +95,7 @@ type ReminderSettingsRes struct {
 	AutoReminder string `json:"auto_reminder"`
 	ReminderTime string `json:"reminder_time"`
 	Refresh      string `json:"refresh"`
+	TimeZone     string `json:"time_zone"`
 }
 
 type LoginRes struct {
@@ -94,39 +110,53 @@ type LoginRes struct {
 	Occupation           string                  `json:"occupation"`
 	NotificationSettings NotificationSettingsRes `json:"notification_settings"`
 	ProductEmailSettings ProductEmailSettingsRes `json:"product_email_settings"`
+	EmailVerified        bool                    `json:"email_verified"`
 	Token                string                  `json:"access_token"`
 	RefreshToken         string                  `json:"refresh_token"`
+	// TwoFactorRequired is set instead of the tokens when the login needs a two-factor code,
+	// ChallengeToken and the code are then sent to /user/login/2fa
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
 }
 
 type GetByEmailRes struct {
-	UserId     string `json:"user_id"`
-	Email      string `json:"email"`
-	Password   string `json:"password"`
-	FirstName  string `json:"first_name"`
-	LastName   string `json:"last_name"`
-	Phone      string `json:"phone"`
-	Gender     string `json:"gender"`
-	Avatar     string `json:"avatar"`
-	CountryId  int    `json:"country_id"`
-	Occupation string `json:"occupation"`
+	UserId        string `json:"user_id"`
+	Email         string `json:"email"`
+	Password      string `json:"password"`
+	FirstName     string `json:"first_name"`
+	LastName      string `json:"last_name"`
+	Phone         string `json:"phone"`
+	Gender        string `json:"gender"`
+	Avatar        string `json:"avatar"`
+	CountryId     int    `json:"country_id"`
+	Occupation    string `json:"occupation"`
+	AccountStatus string `json:"account_status"`
+	// PasswordResetRequired is set when a login was reported as not the user's,
+	// nobody can log in to the account until the password is reset
+	PasswordResetRequired bool `json:"-"`
 }
 
 type GetByIdRes struct {
-	UserId      string `json:"user_id"`
-	Password    string `json:"password"`
-	Email       string `json:"email"`
-	FirstName   string `json:"first_name"`
-	LastName    string `json:"last_name"`
-	Phone       string `json:"phone"`
-	Gender      string `json:"gender"`
-	Avatar      string `json:"avatar"`
-	DateOfBirth string `json:"date_of_birth"`
+	UserId        string `json:"user_id"`
+	Password      string `json:"password"`
+	Email         string `json:"email"`
+	FirstName     string `json:"first_name"`
+	LastName      string `json:"last_name"`
+	Phone         string `json:"phone"`
+	Gender        string `json:"gender"`
+	Avatar        string `json:"avatar"`
+	DateOfBirth   string `json:"date_of_birth"`
+	AccountStatus string `json:"account_status"`
+	// PendingEmail replaces Email once it is confirmed
+	PendingEmail string `json:"pending_email"`
+	// PasswordResetRequired is the same as on GetByEmailRes
+	PasswordResetRequired bool `json:"-"`
 }
 
 type UpdateUserReq struct {
 	FirstName     string `json:"first_name"`
 	LastName      string `json:"last_name"`
-	Email         string `json:"email"`
+	Email         string `json:"email" validate:"omitempty,email"`
 	Phone         string `json:"phone"`
 	Gender        string `json:"gender"`
 	DateOfBirth   string `json:"date_of_birth"`
@@ -146,6 +176,8 @@ type UpdateUserRes struct {
 	Avatar     string `json:"avatar"`
 	CountryId  int    `json:"country_id"`
 	Occupation string `json:"occupation"`
+	// PendingEmail is set when the email was changed and the new address is waiting to be confirmed
+	PendingEmail string `json:"pending_email,omitempty"`
 }
 
 type UsersRes struct {
@@ -165,39 +197,99 @@ type ChangePasswordReq struct {
 }
 
 type ResetPasswordReq struct {
-	Email string `json:"email" validate:"email,required"`
+	Email     string `json:"email" validate:"email,required"`
+	IPAddress string `json:"-"`
 }
 
+// ResetPasswordRes is returned whether or not the email has an account, the token is only sent by email.
 type ResetPasswordRes struct {
-	UserId  string `json:"user_id"`
-	TokenId string `json:"token_id"`
-	Token   string `json:"token"`
-	Expiry  string `json:"expiry"`
+	ExpiresAt string `json:"expires_at"`
 }
 
 type ResetPasswordWithTokenReq struct {
+	// Token is the one from the emailed link, it can also be sent in the query
+	Token    string `json:"token" validate:"required"`
 	Password string `json:"password" validate:"required"`
 }
 
-type ResetPasswordWithTokenRes struct {
-	UserId  string `json:"user_id"`
-	TokenId string `json:"token_id"`
-	Token   string `json:"token"`
-	Expiry  string `json:"expiry"`
+// PasswordReset is a single use password reset sent to Email. Its token is the reset id and a secret,
+// only the hash of the secret is stored. Requests for unknown emails have no UserId and are never sent.
+type PasswordReset struct {
+	ResetId    string
+	UserId     string
+	Email      string
+	SecretHash string
+	IPAddress  string
+	// Attempts counts the tokens of this reset tried with a wrong secret
+	Attempts  int
+	ExpiresAt string
+	CreatedAt string
+	UsedAt    string
 }
 
+// GoogleLoginReq carries the ID token Google Sign-In returns, the profile is read from it.
 type GoogleLoginReq struct {
-	Id        string `json:"googleId"`
-	FirstName string `json:"givenName" binding:"required"`
-	LastName  string `json:"familyName" binding:"required"`
-	Email     string `json:"email" binding:"required,email"`
-	Profile   string `json:"imageUrl"`
-	Name      string `json:"name" binding:"required"`
+	IdToken string `json:"id_token" binding:"required"`
+	tokenEntity.SessionInfo
 }
 
+// FacebookLoginReq carries the user access token Facebook Login returns.
 type FacebookLoginReq struct {
-	Email string `json:"email" binding:"required,email"`
-	Name  string `json:"name" binding:"required"`
+	AccessToken string `json:"access_token" binding:"required"`
+	tokenEntity.SessionInfo
+}
+
+type MagicLinkReq struct {
+	Email     string `json:"email" validate:"required,email"`
+	IPAddress string `json:"-"`
+}
+
+// MagicLinkRes is returned whether or not the email has an account. DeviceCode has to be sent
+// along with the token from the link, so the link only works on the device that asked for it.
+type MagicLinkRes struct {
+	DeviceCode string `json:"device_code"`
+	ExpiresAt  string `json:"expires_at"`
+}
+
+type RedeemMagicLinkReq struct {
+	Token      string `json:"token" validate:"required"`
+	DeviceCode string `json:"device_code" validate:"required"`
+	tokenEntity.SessionInfo
+}
+
+// MagicLink is a single use login link sent to Email, only its hashes are stored.
+type MagicLink struct {
+	TokenHash  string
+	UserId     string
+	Email      string
+	DeviceHash string
+	IPAddress  string
+	ExpiresAt  string
+	CreatedAt  string
+	UsedAt     string
+}
+
+// LoginAlert is the "this wasn't me" link sent when a session starts from a new device, only its hash is stored.
+type LoginAlert struct {
+	TokenHash string
+	UserId    string
+	SessionId string
+	ExpiresAt string
+	CreatedAt string
+	UsedAt    string
+}
+
+type DisownLoginReq struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// SocialIdentity links an account at a login provider to a user, by the provider's id for it.
+type SocialIdentity struct {
+	Provider  string
+	Subject   string
+	UserId    string
+	Email     string
+	CreatedAt string
 }
 
 type ProfileImageRes struct {
@@ -205,3 +297,17 @@ type ProfileImageRes struct {
 	Size     int64  `json:"size"`
 	FileType string `json:"fileType"`
 }
+
+// EmailVerification is a link sent to prove the user owns Email.
+// Only a hash of the token in the link is stored.
+type EmailVerification struct {
+	TokenHash string
+	UserId    string
+	Email     string
+	ExpiresAt string
+	CreatedAt string
+}
+
+type VerifyEmailReq struct {
+	Token string `json:"token" validate:"required"`
+}
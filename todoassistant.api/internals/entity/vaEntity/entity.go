@@ -1,5 +1,7 @@
 package vaEntity
 
+import "test-va/internals/entity/tokenEntity"
+
 type CreateVAReq struct {
 	VaId           string `json:"va_id"`
 	FirstName      string `json:"first_name"`
@@ -25,6 +27,7 @@ type CreateVARes struct {
 type LoginReq struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
+	tokenEntity.SessionInfo
 }
 
 type LoginRes struct {
@@ -60,6 +63,10 @@ type FindByEmailRes struct {
 	ProfilePicture string `json:"profile_picture"`
 	CreatedAt      string `json:"created_at"`
 	AccountType    string `json:"account_type"`
+	// TwoFactorRequired is set when the login needs a two-factor code,
+	// ChallengeToken and the code are then sent to /va/login/2fa
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
 }
 
 type EditVaReq struct {
@@ -0,0 +1,256 @@
+package adminService
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"test-va/internals/Repository/adminRepo"
+	"test-va/internals/Repository/auditRepo"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/adminEntity"
+	"test-va/internals/entity/loggerEntity"
+	"test-va/internals/service/tokenService"
+	"test-va/internals/service/validationService"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	ErrNotFound = "Not Found"
+	ErrConflict = "Conflict"
+)
+
+const (
+	usersPerPage = 20
+	// recentEvents is how many audit logs an account shows
+	recentEvents = 20
+)
+
+type AdminSrv interface {
+	SearchUsers(req *adminEntity.SearchUsersReq) (*adminEntity.SearchUsersRes, *ResponseEntity.ServiceError)
+	GetAccount(userId string) (*adminEntity.Account, *ResponseEntity.ServiceError)
+	// SuspendUser suspends the account and logs the user out everywhere.
+	SuspendUser(userId, masterId string, req *adminEntity.SuspendReq) *ResponseEntity.ServiceError
+	ReactivateUser(userId, masterId string) *ResponseEntity.ServiceError
+	// ForceLogout revokes every token issued to the user so far.
+	ForceLogout(userId string) *ResponseEntity.ServiceError
+}
+
+type adminSrv struct {
+	repo      adminRepo.AdminRepository
+	auditRepo auditRepo.AuditRepository
+	tokenSrv  tokenservice.TokenSrv
+	validator validationService.ValidationSrv
+	now       func() time.Time
+}
+
+func NewAdminSrv(repo adminRepo.AdminRepository, auditRepo auditRepo.AuditRepository, tokenSrv tokenservice.TokenSrv,
+	validator validationService.ValidationSrv) AdminSrv {
+	return &adminSrv{repo: repo, auditRepo: auditRepo, tokenSrv: tokenSrv, validator: validator, now: time.Now}
+}
+
+// Search Users godoc
+// @Summary	Search users
+// @Description	Users whose email, first name, last name or phone starts with q, ordered by email, 20 per page. Only masters can search users.
+// @Tags	VA - Master
+// @Produce	json
+// @Param	q	query	string	false	"Start of the email, name or phone"
+// @Param	status	query	string	false	"ACTIVE, UNVERIFIED or SUSPENDED"
+// @Param	page	query	int	false	"Page, defaults to 1"
+// @Success	200  {object}  adminEntity.SearchUsersRes
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/admin/users [get]
+func (a *adminSrv) SearchUsers(req *adminEntity.SearchUsersReq) (*adminEntity.SearchUsersRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	err := a.validator.Validate(req)
+	if err != nil {
+		return nil, ResponseEntity.NewValidatingError(err)
+	}
+	page := req.Page
+	if page == 0 {
+		page = 1
+	}
+
+	users, err := a.repo.SearchUsers(ctx, req.Query, req.Status, usersPerPage, (page-1)*usersPerPage)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if users == nil {
+		users = []*adminEntity.UserSummary{}
+	}
+	return &adminEntity.SearchUsersRes{Users: users, Page: page}, nil
+}
+
+// Get Account godoc
+// @Summary	View a user's account
+// @Description	The user's details and plan, the VA assigned to them, the suspension they are under and their recent activity. Only masters can view accounts.
+// @Tags	VA - Master
+// @Produce	json
+// @Param	user_id	path	string	true	"User Id"
+// @Success	200  {object}  adminEntity.Account
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/admin/users/{user_id} [get]
+func (a *adminSrv) GetAccount(userId string) (*adminEntity.Account, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	user, errRes := a.getUser(ctx, userId)
+	if errRes != nil {
+		return nil, errRes
+	}
+	account := &adminEntity.Account{UserSummary: *user}
+
+	va, err := a.repo.GetAssignedVA(ctx, userId)
+	switch {
+	case err == nil:
+		account.VA = va
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	suspension, err := a.repo.GetSuspension(ctx, userId)
+	switch {
+	case err == nil:
+		account.Suspension = suspension
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	activity, err := a.repo.GetActivity(ctx, userId, a.now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	activity.RecentEvents, err = a.auditRepo.GetLogs(ctx, &loggerEntity.AuditFilter{ActorId: userId}, recentEvents)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if activity.RecentEvents == nil {
+		activity.RecentEvents = []*loggerEntity.AuditLog{}
+	}
+	account.Activity = activity
+	return account, nil
+}
+
+// Suspend User godoc
+// @Summary	Suspend a user
+// @Description	The user cannot log in anymore and every token issued to them is revoked, until a master reactivates the account. Only masters can suspend users.
+// @Tags	VA - Master
+// @Accept	json
+// @Produce	json
+// @Param	user_id	path	string	true	"User Id"
+// @Param	request	body	adminEntity.SuspendReq	true	"Why the account is suspended"
+// @Success	200  {string}  string  "Account suspended"
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	409  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/admin/users/{user_id}/suspend [post]
+func (a *adminSrv) SuspendUser(userId, masterId string, req *adminEntity.SuspendReq) *ResponseEntity.ServiceError {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	err := a.validator.Validate(req)
+	if err != nil {
+		return ResponseEntity.NewValidatingError(err)
+	}
+	_, errRes := a.getUser(ctx, userId)
+	if errRes != nil {
+		return errRes
+	}
+
+	suspended, err := a.repo.Suspend(ctx, &adminEntity.Suspension{
+		SuspensionId: uuid.New().String(),
+		UserId:       userId,
+		Reason:       req.Reason,
+		SuspendedBy:  masterId,
+		SuspendedAt:  a.now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	if !suspended {
+		return ResponseEntity.NewCustomServiceError(ErrConflict, "the account is suspended already")
+	}
+
+	// ValidateJWT refuses the tokens of a suspended user already, revoking them also ends the sessions
+	err = a.tokenSrv.LogoutAll(userId)
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	return nil
+}
+
+// Reactivate User godoc
+// @Summary	Reactivate a suspended user
+// @Description	The account gets back the status it had before it was suspended. The user has to log in again. Only masters can reactivate users.
+// @Tags	VA - Master
+// @Produce	json
+// @Param	user_id	path	string	true	"User Id"
+// @Success	200  {string}  string  "Account reactivated"
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	409  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/admin/users/{user_id}/reactivate [post]
+func (a *adminSrv) ReactivateUser(userId, masterId string) *ResponseEntity.ServiceError {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	_, errRes := a.getUser(ctx, userId)
+	if errRes != nil {
+		return errRes
+	}
+	reactivated, err := a.repo.Reactivate(ctx, userId, masterId, a.now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	if !reactivated {
+		return ResponseEntity.NewCustomServiceError(ErrConflict, "the account is not suspended")
+	}
+	return nil
+}
+
+// Force Logout godoc
+// @Summary	Log a user out everywhere
+// @Description	Revokes every token issued to the user so far, on every device. Only masters can log users out.
+// @Tags	VA - Master
+// @Produce	json
+// @Param	user_id	path	string	true	"User Id"
+// @Success	200  {string}  string  "User logged out"
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/admin/users/{user_id}/logout [post]
+func (a *adminSrv) ForceLogout(userId string) *ResponseEntity.ServiceError {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	_, errRes := a.getUser(ctx, userId)
+	if errRes != nil {
+		return errRes
+	}
+	err := a.tokenSrv.LogoutAll(userId)
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	return nil
+}
+
+func (a *adminSrv) getUser(ctx context.Context, userId string) (*adminEntity.UserSummary, *ResponseEntity.ServiceError) {
+	user, err := a.repo.GetUser(ctx, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ResponseEntity.NewCustomServiceError(ErrNotFound, "no user has this id")
+	}
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return user, nil
+}
@@ -0,0 +1,225 @@
+package adminService
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"test-va/internals/Repository/adminRepo"
+	"test-va/internals/Repository/auditRepo"
+	"test-va/internals/entity/adminEntity"
+	"test-va/internals/entity/loggerEntity"
+	"test-va/internals/entity/userEntity"
+	"test-va/internals/service/tokenService"
+	"test-va/internals/service/validationService"
+	"testing"
+	"time"
+)
+
+// memoryRepo searches on the start of the email only, the rest of the search is up to the sql.
+type memoryRepo struct {
+	adminRepo.AdminRepository
+	users       []*adminEntity.UserSummary
+	suspensions []*adminEntity.Suspension
+}
+
+func (m *memoryRepo) SearchUsers(ctx context.Context, query, status string, limit, offset int) ([]*adminEntity.UserSummary, error) {
+	var users []*adminEntity.UserSummary
+	for _, user := range m.users {
+		if !strings.HasPrefix(user.Email, query) || (status != "" && user.AccountStatus != status) {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if len(users) < limit {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (m *memoryRepo) GetUser(ctx context.Context, userId string) (*adminEntity.UserSummary, error) {
+	for _, user := range m.users {
+		if user.UserId == userId {
+			return user, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryRepo) GetAssignedVA(ctx context.Context, userId string) (*adminEntity.AssignedVA, error) {
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryRepo) GetActivity(ctx context.Context, userId, now string) (*adminEntity.Activity, error) {
+	return &adminEntity.Activity{Tasks: map[string]int{"PENDING": 2}}, nil
+}
+
+func (m *memoryRepo) Suspend(ctx context.Context, suspension *adminEntity.Suspension) (bool, error) {
+	user, _ := m.GetUser(ctx, suspension.UserId)
+	if user.AccountStatus == userEntity.AccountSuspended {
+		return false, nil
+	}
+	suspension.PreviousStatus = user.AccountStatus
+	user.AccountStatus = userEntity.AccountSuspended
+	m.suspensions = append(m.suspensions, suspension)
+	return true, nil
+}
+
+func (m *memoryRepo) GetSuspension(ctx context.Context, userId string) (*adminEntity.Suspension, error) {
+	for _, suspension := range m.suspensions {
+		if suspension.UserId == userId && suspension.ReactivatedAt == "" {
+			return suspension, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryRepo) Reactivate(ctx context.Context, userId, reactivatedBy, reactivatedAt string) (bool, error) {
+	suspension, err := m.GetSuspension(ctx, userId)
+	if err != nil {
+		return false, nil
+	}
+	user, _ := m.GetUser(ctx, userId)
+	user.AccountStatus = suspension.PreviousStatus
+	suspension.ReactivatedBy, suspension.ReactivatedAt = reactivatedBy, reactivatedAt
+	return true, nil
+}
+
+type memoryAudit struct {
+	auditRepo.AuditRepository
+	filters []*loggerEntity.AuditFilter
+}
+
+func (m *memoryAudit) GetLogs(ctx context.Context, filter *loggerEntity.AuditFilter, limit int) ([]*loggerEntity.AuditLog, error) {
+	m.filters = append(m.filters, filter)
+	return []*loggerEntity.AuditLog{{Id: 1, Action: loggerEntity.AuditLogin, ActorId: filter.ActorId}}, nil
+}
+
+type memoryTokens struct {
+	tokenservice.TokenSrv
+	loggedOut []string
+}
+
+func (m *memoryTokens) LogoutAll(userId string) error {
+	m.loggedOut = append(m.loggedOut, userId)
+	return nil
+}
+
+func newTestSrv(repo *memoryRepo, tokens *memoryTokens) *adminSrv {
+	now := time.Date(2023, 3, 7, 12, 0, 0, 0, time.UTC)
+	return &adminSrv{repo: repo, auditRepo: &memoryAudit{}, tokenSrv: tokens, validator: validationService.NewValidationStruct(),
+		now: func() time.Time { return now }}
+}
+
+func TestSearchUsersPages(t *testing.T) {
+	repo := &memoryRepo{}
+	for i := 0; i < usersPerPage+5; i++ {
+		repo.users = append(repo.users, &adminEntity.UserSummary{UserId: string(rune('a' + i)), Email: "sam@example.com",
+			AccountStatus: userEntity.AccountActive})
+	}
+	repo.users = append(repo.users, &adminEntity.UserSummary{UserId: "other", Email: "kim@example.com"})
+	srv := newTestSrv(repo, &memoryTokens{})
+
+	res, errRes := srv.SearchUsers(&adminEntity.SearchUsersReq{Query: "sam"})
+	if errRes != nil {
+		t.Fatalf("SearchUsers() error = %v", errRes)
+	}
+	if res.Page != 1 || len(res.Users) != usersPerPage {
+		t.Errorf("first page = page %d with %d users, want page 1 with %d", res.Page, len(res.Users), usersPerPage)
+	}
+	res, _ = srv.SearchUsers(&adminEntity.SearchUsersReq{Query: "sam", Page: 2})
+	if len(res.Users) != 5 {
+		t.Errorf("second page has %d users, want 5", len(res.Users))
+	}
+	res, _ = srv.SearchUsers(&adminEntity.SearchUsersReq{Query: "nobody"})
+	if res.Users == nil {
+		t.Error("an empty search should return an empty list, not null")
+	}
+	if _, errRes := srv.SearchUsers(&adminEntity.SearchUsersReq{Status: "GONE"}); errRes == nil {
+		t.Error("SearchUsers() should refuse an unknown status")
+	}
+}
+
+func TestSuspendAndReactivate(t *testing.T) {
+	repo := &memoryRepo{users: []*adminEntity.UserSummary{{UserId: "u1", Email: "sam@example.com", AccountStatus: userEntity.AccountUnverified}}}
+	tokens := &memoryTokens{}
+	srv := newTestSrv(repo, tokens)
+
+	if errRes := srv.SuspendUser("u1", "m1", &adminEntity.SuspendReq{}); errRes == nil {
+		t.Error("SuspendUser() should need a reason")
+	}
+	if errRes := srv.SuspendUser("u2", "m1", &adminEntity.SuspendReq{Reason: "spam"}); errRes == nil || errRes.Description != ErrNotFound {
+		t.Errorf("SuspendUser() of an unknown user = %v, want not found", errRes)
+	}
+	if errRes := srv.SuspendUser("u1", "m1", &adminEntity.SuspendReq{Reason: "spam"}); errRes != nil {
+		t.Fatalf("SuspendUser() error = %v", errRes)
+	}
+	if repo.users[0].AccountStatus != userEntity.AccountSuspended {
+		t.Errorf("account status = %q, want suspended", repo.users[0].AccountStatus)
+	}
+	if len(tokens.loggedOut) != 1 || tokens.loggedOut[0] != "u1" {
+		t.Errorf("logged out %v, want the suspended user logged out", tokens.loggedOut)
+	}
+	if errRes := srv.SuspendUser("u1", "m1", &adminEntity.SuspendReq{Reason: "spam"}); errRes == nil || errRes.Description != ErrConflict {
+		t.Errorf("suspending twice = %v, want a conflict", errRes)
+	}
+
+	account, errRes := srv.GetAccount("u1")
+	if errRes != nil {
+		t.Fatalf("GetAccount() error = %v", errRes)
+	}
+	if account.Suspension == nil || account.Suspension.Reason != "spam" || account.Suspension.SuspendedBy != "m1" {
+		t.Errorf("suspension = %+v, want the one by m1", account.Suspension)
+	}
+
+	if errRes := srv.ReactivateUser("u1", "m1"); errRes != nil {
+		t.Fatalf("ReactivateUser() error = %v", errRes)
+	}
+	if repo.users[0].AccountStatus != userEntity.AccountUnverified {
+		t.Errorf("account status = %q, want the status from before the suspension", repo.users[0].AccountStatus)
+	}
+	if errRes := srv.ReactivateUser("u1", "m1"); errRes == nil || errRes.Description != ErrConflict {
+		t.Errorf("reactivating an active account = %v, want a conflict", errRes)
+	}
+}
+
+func TestGetAccount(t *testing.T) {
+	repo := &memoryRepo{users: []*adminEntity.UserSummary{{UserId: "u1", Email: "sam@example.com", AccountStatus: userEntity.AccountActive}}}
+	srv := newTestSrv(repo, &memoryTokens{})
+
+	if _, errRes := srv.GetAccount("u2"); errRes == nil || errRes.Description != ErrNotFound {
+		t.Errorf("GetAccount() of an unknown user = %v, want not found", errRes)
+	}
+	account, errRes := srv.GetAccount("u1")
+	if errRes != nil {
+		t.Fatalf("GetAccount() error = %v", errRes)
+	}
+	if account.VA != nil || account.Suspension != nil {
+		t.Errorf("account = %+v, want no VA and no suspension", account)
+	}
+	if account.Activity.Tasks["PENDING"] != 2 || len(account.Activity.RecentEvents) != 1 {
+		t.Errorf("activity = %+v, want the task counts and the recent events", account.Activity)
+	}
+	filters := srv.auditRepo.(*memoryAudit).filters
+	if len(filters) != 1 || filters[0].ActorId != "u1" {
+		t.Errorf("audit filters = %v, want the user's own actions", filters)
+	}
+}
+
+func TestForceLogout(t *testing.T) {
+	repo := &memoryRepo{users: []*adminEntity.UserSummary{{UserId: "u1"}}}
+	tokens := &memoryTokens{}
+	srv := newTestSrv(repo, tokens)
+
+	if errRes := srv.ForceLogout("u2"); errRes == nil || errRes.Description != ErrNotFound {
+		t.Errorf("ForceLogout() of an unknown user = %v, want not found", errRes)
+	}
+	if errRes := srv.ForceLogout("u1"); errRes != nil {
+		t.Fatalf("ForceLogout() error = %v", errRes)
+	}
+	if len(tokens.loggedOut) != 1 || tokens.loggedOut[0] != "u1" {
+		t.Errorf("logged out %v, want u1", tokens.loggedOut)
+	}
+}
@@ -0,0 +1,404 @@
+package analyticsService
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"test-va/internals/Repository/analyticsRepo"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/analyticsEntity"
+	"test-va/internals/service/timeSrv"
+	"test-va/internals/service/validationService"
+	"time"
+)
+
+const (
+	cacheTTL   = time.Minute * 15
+	dateLayout = "2006-01-02"
+)
+
+type AnalyticsService interface {
+	GetAnalytics(req *analyticsEntity.AnalyticsReq) (*analyticsEntity.AnalyticsRes, *ResponseEntity.ServiceError)
+	// Invalidate drops the cached aggregates of a user, it is called whenever one of their tasks changes.
+	Invalidate(userId string)
+	// PurgeCache forgets cached aggregates that have expired.
+	PurgeCache()
+}
+
+type cacheEntry struct {
+	res       *analyticsEntity.AnalyticsRes
+	expiresAt time.Time
+}
+
+type analyticsSrv struct {
+	repo          analyticsRepo.AnalyticsRepository
+	timeSrv       timeSrv.TimeService
+	validationSrv validationService.ValidationSrv
+
+	mu    sync.Mutex
+	cache map[string]map[string]cacheEntry
+}
+
+func NewAnalyticsSrv(repo analyticsRepo.AnalyticsRepository, timeSrv timeSrv.TimeService, validationSrv validationService.ValidationSrv) AnalyticsService {
+	return &analyticsSrv{repo: repo, timeSrv: timeSrv, validationSrv: validationSrv, cache: map[string]map[string]cacheEntry{}}
+}
+
+// Get Analytics godoc
+// @Summary	Get productivity analytics for the logged in user
+// @Description	Tasks created and completed per day or week, on time rate, lateness, streaks, project and label breakdown and VA turnaround
+// @Tags	Analytics
+// @Produce	json
+// @Param	period	query	string	false	"day or week, defaults to day"
+// @Param	from	query	string	false	"Start date, YYYY-MM-DD"
+// @Param	to	query	string	false	"End date, YYYY-MM-DD"
+// @Param	tz	query	string	false	"IANA time zone used to bucket the series, defaults to UTC"
+// @Success	200  {object}  analyticsEntity.AnalyticsRes
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security BearerAuth
+// @Router	/analytics [get]
+func (a *analyticsSrv) GetAnalytics(req *analyticsEntity.AnalyticsReq) (*analyticsEntity.AnalyticsRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	err := a.validationSrv.Validate(req)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewValidatingError("Bad Data Input")
+	}
+
+	if req.Period == "" {
+		req.Period = "day"
+	}
+	if req.TimeZone == "" {
+		req.TimeZone = "UTC"
+	}
+	loc, err := time.LoadLocation(req.TimeZone)
+	if err != nil {
+		return nil, ResponseEntity.NewValidatingError(fmt.Sprintf("unknown time zone %s", req.TimeZone))
+	}
+
+	from, to, err := dateRange(req, a.timeSrv.CurrentTime().In(loc), loc)
+	if err != nil {
+		return nil, ResponseEntity.NewValidatingError(err.Error())
+	}
+
+	key := fmt.Sprintf("%s|%s|%s|%s", req.Period, from.Format(dateLayout), to.Format(dateLayout), req.TimeZone)
+	if res := a.cached(req.UserId, key); res != nil {
+		return res, nil
+	}
+
+	records, err := a.repo.GetTaskRecords(ctx, req.UserId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	res := Compute(records, req.Period, from, to, a.timeSrv.CurrentTime().In(loc))
+	res.TimeZone = req.TimeZone
+	a.store(req.UserId, key, res)
+	return res, nil
+}
+
+func (a *analyticsSrv) Invalidate(userId string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.cache, userId)
+}
+
+func (a *analyticsSrv) PurgeCache() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.timeSrv.CurrentTime()
+	for userId, entries := range a.cache {
+		for key, entry := range entries {
+			if now.After(entry.expiresAt) {
+				delete(entries, key)
+			}
+		}
+		if len(entries) == 0 {
+			delete(a.cache, userId)
+		}
+	}
+}
+
+func (a *analyticsSrv) cached(userId, key string) *analyticsEntity.AnalyticsRes {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entries := a.cache[userId]
+	entry, ok := entries[key]
+	if !ok {
+		return nil
+	}
+	if a.timeSrv.CurrentTime().After(entry.expiresAt) {
+		delete(entries, key)
+		if len(entries) == 0 {
+			delete(a.cache, userId)
+		}
+		return nil
+	}
+	return entry.res
+}
+
+func (a *analyticsSrv) store(userId, key string, res *analyticsEntity.AnalyticsRes) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.cache[userId] == nil {
+		a.cache[userId] = map[string]cacheEntry{}
+	}
+	a.cache[userId][key] = cacheEntry{res: res, expiresAt: a.timeSrv.CurrentTime().Add(cacheTTL)}
+}
+
+// dateRange resolves the requested range to midnights in loc, defaulting to the last 30 days or 12 weeks.
+func dateRange(req *analyticsEntity.AnalyticsReq, now time.Time, loc *time.Location) (time.Time, time.Time, error) {
+	to := startOfDay(now)
+	if req.To != "" {
+		t, err := time.ParseInLocation(dateLayout, req.To, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		to = t
+	}
+
+	from := to.AddDate(0, 0, -29)
+	if req.Period == "week" {
+		from = startOfWeek(to).AddDate(0, 0, -7*11)
+	}
+	if req.From != "" {
+		f, err := time.ParseInLocation(dateLayout, req.From, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		from = f
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must not be after to")
+	}
+	if to.Sub(from) > time.Hour*24*366 {
+		return time.Time{}, time.Time{}, fmt.Errorf("range cannot be longer than a year")
+	}
+	return from, to, nil
+}
+
+// Compute aggregates the records over [from, to] (both whole days in the location of from).
+// now decides which streak is current.
+func Compute(records []*analyticsEntity.TaskRecord, period string, from, to, now time.Time) *analyticsEntity.AnalyticsRes {
+	loc := from.Location()
+	end := to.AddDate(0, 0, 1)
+	inRange := func(t time.Time) bool { return !t.Before(from) && t.Before(end) }
+
+	bucket := startOfDay
+	step := func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	if period == "week" {
+		bucket = startOfWeek
+		step = func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }
+	}
+
+	var series []analyticsEntity.SeriesPoint
+	index := map[string]int{}
+	for t := bucket(from); t.Before(end); t = step(t) {
+		index[t.Format(dateLayout)] = len(series)
+		series = append(series, analyticsEntity.SeriesPoint{Date: t.Format(dateLayout)})
+	}
+
+	res := &analyticsEntity.AnalyticsRes{
+		Period: period,
+		From:   from.Format(dateLayout),
+		To:     to.Format(dateLayout),
+	}
+
+	var onTime, withDeadline, late int
+	var lateness time.Duration
+	var responseCount, completionCount int
+	var responseTotal, completionTotal time.Duration
+	completionDays := map[string]bool{}
+	projects := map[string]*analyticsEntity.Breakdown{}
+	labels := map[string]*analyticsEntity.Breakdown{}
+	projectOnTime := map[string][2]int{}
+	labelOnTime := map[string][2]int{}
+
+	for _, r := range records {
+		created, createdOk := parseTime(r.CreatedAt, loc)
+		completed, completedOk := parseTime(r.CompletedAt, loc)
+		deadline, deadlineOk := parseTime(r.EndTime, loc)
+		isCompleted := r.Status == "COMPLETED" && completedOk
+
+		if isCompleted {
+			completionDays[startOfDay(completed).Format(dateLayout)] = true
+		}
+
+		if createdOk && inRange(created) {
+			res.TotalCreated++
+			series[index[bucket(created).Format(dateLayout)]].Created++
+		}
+
+		wasOnTime := isCompleted && deadlineOk && !completed.After(deadline)
+		if isCompleted && inRange(completed) {
+			res.TotalCompleted++
+			series[index[bucket(completed).Format(dateLayout)]].Completed++
+			if deadlineOk {
+				withDeadline++
+				if wasOnTime {
+					onTime++
+				} else {
+					late++
+					lateness += completed.Sub(deadline)
+				}
+			}
+		}
+
+		if !createdOk || !inRange(created) {
+			continue
+		}
+
+		addBreakdown(projects, projectOnTime, r.ProjectId, projectName(r), isCompleted, deadlineOk, wasOnTime)
+		for _, label := range r.Labels {
+			addBreakdown(labels, labelOnTime, label, label, isCompleted, deadlineOk, wasOnTime)
+		}
+
+		if r.VaId != "" {
+			res.VATurnaround.AssignedTasks++
+			if responded, ok := parseTime(r.FirstResponseAt, loc); ok && !responded.Before(created) {
+				responseCount++
+				responseTotal += responded.Sub(created)
+			}
+			if isCompleted && !completed.Before(created) {
+				res.VATurnaround.CompletedTasks++
+				completionCount++
+				completionTotal += completed.Sub(created)
+			}
+		}
+	}
+
+	res.Series = series
+	res.OnTimeRate = ratio(onTime, withDeadline)
+	if late > 0 {
+		res.AverageLatenessMinutes = round(lateness.Minutes() / float64(late))
+	}
+	if responseCount > 0 {
+		res.VATurnaround.AverageFirstResponseHours = round(responseTotal.Hours() / float64(responseCount))
+	}
+	if completionCount > 0 {
+		res.VATurnaround.AverageCompletionHours = round(completionTotal.Hours() / float64(completionCount))
+	}
+	res.CurrentStreak, res.LongestStreak = streaks(completionDays, startOfDay(now))
+	res.Projects = sortedBreakdown(projects, projectOnTime)
+	res.Labels = sortedBreakdown(labels, labelOnTime)
+	return res
+}
+
+func addBreakdown(m map[string]*analyticsEntity.Breakdown, onTime map[string][2]int, key, name string, completed, hasDeadline, wasOnTime bool) {
+	b, ok := m[key]
+	if !ok {
+		b = &analyticsEntity.Breakdown{Id: key, Name: name}
+		m[key] = b
+	}
+	b.Total++
+	if completed {
+		b.Completed++
+		if hasDeadline {
+			counts := onTime[key]
+			counts[1]++
+			if wasOnTime {
+				counts[0]++
+			}
+			onTime[key] = counts
+		}
+	}
+}
+
+func sortedBreakdown(m map[string]*analyticsEntity.Breakdown, onTime map[string][2]int) []analyticsEntity.Breakdown {
+	list := make([]analyticsEntity.Breakdown, 0, len(m))
+	for key, b := range m {
+		b.OnTimeRate = ratio(onTime[key][0], onTime[key][1])
+		list = append(list, *b)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Total != list[j].Total {
+			return list[i].Total > list[j].Total
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// streaks counts consecutive days with at least one completion. A streak is still current
+// when the last completion was yesterday, today is not over yet.
+func streaks(days map[string]bool, today time.Time) (current, longest int) {
+	if len(days) == 0 {
+		return 0, 0
+	}
+	sorted := make([]string, 0, len(days))
+	for d := range days {
+		sorted = append(sorted, d)
+	}
+	sort.Strings(sorted)
+
+	run := 0
+	var prev time.Time
+	for i, d := range sorted {
+		day, _ := time.ParseInLocation(dateLayout, d, today.Location())
+		if i > 0 && prev.AddDate(0, 0, 1).Equal(day) {
+			run++
+		} else {
+			run = 1
+		}
+		if run > longest {
+			longest = run
+		}
+		prev = day
+	}
+
+	if prev.Equal(today) || prev.Equal(today.AddDate(0, 0, -1)) {
+		current = run
+	}
+	return current, longest
+}
+
+func projectName(r *analyticsEntity.TaskRecord) string {
+	if r.ProjectId == "" {
+		return "No Project"
+	}
+	if r.ProjectTitle == "" {
+		return r.ProjectId
+	}
+	return r.ProjectTitle
+}
+
+func parseTime(value string, loc *time.Location) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", dateLayout} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.In(loc), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// startOfWeek returns the Monday the week of t starts on.
+func startOfWeek(t time.Time) time.Time {
+	day := startOfDay(t)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return round(float64(n) / float64(d))
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
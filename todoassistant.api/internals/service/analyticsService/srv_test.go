@@ -0,0 +1,143 @@
+package analyticsService
+
+import (
+	"context"
+	"test-va/internals/entity/analyticsEntity"
+	"test-va/internals/service/timeSrv"
+	"test-va/internals/service/validationService"
+	"testing"
+	"time"
+)
+
+func TestCompute(t *testing.T) {
+	from := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 3, 7, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2023, 3, 7, 12, 0, 0, 0, time.UTC)
+
+	records := []*analyticsEntity.TaskRecord{
+		// on time, completed day 1
+		{TaskId: "1", ProjectId: "p1", ProjectTitle: "Work", Status: "COMPLETED", Labels: []string{"urgent"},
+			CreatedAt: "2023-03-01T08:00:00Z", EndTime: "2023-03-01T18:00:00Z", CompletedAt: "2023-03-01T10:00:00Z"},
+		// two hours late, completed day 6, assigned to a VA who answered after an hour
+		{TaskId: "2", ProjectId: "p1", ProjectTitle: "Work", Status: "COMPLETED", Labels: []string{"urgent", "calls"}, VaId: "va",
+			CreatedAt: "2023-03-05T08:00:00Z", EndTime: "2023-03-06T08:00:00Z", CompletedAt: "2023-03-06T10:00:00Z",
+			FirstResponseAt: "2023-03-05T09:00:00Z"},
+		// completed today without a deadline
+		{TaskId: "3", Status: "COMPLETED", CreatedAt: "2023-03-07T08:00:00Z", CompletedAt: "2023-03-07T09:00:00Z"},
+		// still pending
+		{TaskId: "4", ProjectId: "p2", Status: "PENDING", CreatedAt: "2023-03-07T09:00:00Z", EndTime: "2023-03-08T09:00:00Z"},
+		// created before the range
+		{TaskId: "5", Status: "COMPLETED", CreatedAt: "2023-02-01T08:00:00Z", CompletedAt: "2023-03-05T08:00:00Z"},
+	}
+
+	res := Compute(records, "day", from, to, now)
+
+	if len(res.Series) != 7 {
+		t.Fatalf("series length = %d, want 7", len(res.Series))
+	}
+	if res.TotalCreated != 4 || res.TotalCompleted != 4 {
+		t.Errorf("created/completed = %d/%d, want 4/4", res.TotalCreated, res.TotalCompleted)
+	}
+	if res.Series[0].Created != 1 || res.Series[0].Completed != 1 || res.Series[6].Created != 2 {
+		t.Errorf("unexpected series %+v", res.Series)
+	}
+	if res.OnTimeRate != 0.5 {
+		t.Errorf("OnTimeRate = %v, want 0.5", res.OnTimeRate)
+	}
+	if res.AverageLatenessMinutes != 120 {
+		t.Errorf("AverageLatenessMinutes = %v, want 120", res.AverageLatenessMinutes)
+	}
+	// completions on the 1st, 5th, 6th and 7th
+	if res.CurrentStreak != 3 || res.LongestStreak != 3 {
+		t.Errorf("streaks = %d/%d, want 3/3", res.CurrentStreak, res.LongestStreak)
+	}
+	if len(res.Projects) != 3 || res.Projects[0].Name != "Work" || res.Projects[0].Total != 2 || res.Projects[0].OnTimeRate != 0.5 {
+		t.Errorf("unexpected projects %+v", res.Projects)
+	}
+	if len(res.Labels) != 2 || res.Labels[0].Name != "urgent" || res.Labels[0].Completed != 2 {
+		t.Errorf("unexpected labels %+v", res.Labels)
+	}
+	va := res.VATurnaround
+	if va.AssignedTasks != 1 || va.CompletedTasks != 1 || va.AverageFirstResponseHours != 1 || va.AverageCompletionHours != 26 {
+		t.Errorf("unexpected va turnaround %+v", va)
+	}
+}
+
+func TestComputeWeekly(t *testing.T) {
+	// Wednesday to the Tuesday two weeks later spans three weeks starting on Mondays
+	from := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 3, 14, 0, 0, 0, 0, time.UTC)
+	records := []*analyticsEntity.TaskRecord{
+		{TaskId: "1", Status: "PENDING", CreatedAt: "2023-03-01T08:00:00Z"},
+		{TaskId: "2", Status: "PENDING", CreatedAt: "2023-03-13T08:00:00Z"},
+	}
+
+	res := Compute(records, "week", from, to, to)
+	if len(res.Series) != 3 || res.Series[0].Date != "2023-02-27" || res.Series[2].Created != 1 {
+		t.Errorf("unexpected series %+v", res.Series)
+	}
+	if res.CurrentStreak != 0 || res.LongestStreak != 0 {
+		t.Errorf("streaks = %d/%d, want 0/0", res.CurrentStreak, res.LongestStreak)
+	}
+}
+
+type countingRepo struct {
+	calls int
+}
+
+func (c *countingRepo) GetTaskRecords(ctx context.Context, userId string) ([]*analyticsEntity.TaskRecord, error) {
+	c.calls++
+	return nil, nil
+}
+
+type fixedTime struct {
+	timeSrv.TimeService
+	now time.Time
+}
+
+func (f fixedTime) CurrentTime() time.Time { return f.now }
+
+func TestGetAnalyticsCache(t *testing.T) {
+	repo := &countingRepo{}
+	srv := NewAnalyticsSrv(repo, fixedTime{now: time.Date(2023, 3, 7, 12, 0, 0, 0, time.UTC)}, validationService.NewValidationStruct())
+
+	for i := 0; i < 2; i++ {
+		if _, errRes := srv.GetAnalytics(&analyticsEntity.AnalyticsReq{UserId: "user"}); errRes != nil {
+			t.Fatalf("GetAnalytics() error = %v", errRes)
+		}
+	}
+	if repo.calls != 1 {
+		t.Errorf("repo calls = %d, want 1", repo.calls)
+	}
+
+	srv.Invalidate("user")
+	srv.GetAnalytics(&analyticsEntity.AnalyticsReq{UserId: "user"})
+	if repo.calls != 2 {
+		t.Errorf("repo calls after invalidate = %d, want 2", repo.calls)
+	}
+
+	if _, errRes := srv.GetAnalytics(&analyticsEntity.AnalyticsReq{UserId: "user", TimeZone: "Nowhere/Land"}); errRes == nil {
+		t.Error("GetAnalytics() with unknown time zone should fail")
+	}
+	if _, errRes := srv.GetAnalytics(&analyticsEntity.AnalyticsReq{UserId: "user", From: "2023-03-08", To: "2023-03-01"}); errRes == nil {
+		t.Error("GetAnalytics() with reversed range should fail")
+	}
+}
+
+func TestPurgeCache(t *testing.T) {
+	clock := &fixedTime{now: time.Date(2023, 3, 7, 12, 0, 0, 0, time.UTC)}
+	srv := NewAnalyticsSrv(&countingRepo{}, clock, validationService.NewValidationStruct()).(*analyticsSrv)
+
+	srv.GetAnalytics(&analyticsEntity.AnalyticsReq{UserId: "user"})
+	clock.now = clock.now.Add(cacheTTL / 2)
+	srv.GetAnalytics(&analyticsEntity.AnalyticsReq{UserId: "other"})
+
+	clock.now = clock.now.Add(cacheTTL/2 + time.Second)
+	srv.PurgeCache()
+	if _, ok := srv.cache["user"]; ok {
+		t.Error("the expired aggregates of user should be purged")
+	}
+	if _, ok := srv.cache["other"]; !ok {
+		t.Error("the aggregates of other have not expired yet")
+	}
+}
@@ -0,0 +1,396 @@
+package attachmentService
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"test-va/internals/Repository/attachmentRepo"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/attachmentEntity"
+	"test-va/internals/entity/taskEntity"
+	"test-va/internals/service/storageService"
+	"test-va/internals/service/timeSrv"
+	"test-va/internals/service/validationService"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MaxAttachmentSize = 10 << 20
+	uploadExpiry      = time.Minute * 15
+	downloadExpiry    = time.Minute * 5
+)
+
+// service error descriptions the handler maps to status codes
+const (
+	ErrNotFound  = "Attachment Not Found"
+	ErrForbidden = "Forbidden"
+)
+
+var allowedMimeTypes = map[string]bool{
+	"image/jpeg":         true,
+	"image/png":          true,
+	"image/gif":          true,
+	"image/webp":         true,
+	"application/pdf":    true,
+	"text/plain":         true,
+	"text/csv":           true,
+	"application/msword": true,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": true,
+	"application/vnd.ms-excel": true,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": true,
+}
+
+type AttachmentService interface {
+	CreateUpload(req *attachmentEntity.CreateUploadReq) (*attachmentEntity.CreateUploadRes, *ResponseEntity.ServiceError)
+	CompleteUpload(userId, attachmentId string) (*attachmentEntity.AttachmentRes, *ResponseEntity.ServiceError)
+	GetDownloadUrl(userId, attachmentId string) (*attachmentEntity.DownloadRes, *ResponseEntity.ServiceError)
+	ListByTask(userId, taskId string) ([]*attachmentEntity.AttachmentRes, *ResponseEntity.ServiceError)
+	ListByComment(userId, commentId string) ([]*attachmentEntity.AttachmentRes, *ResponseEntity.ServiceError)
+	DeleteAttachment(userId, attachmentId string) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError)
+}
+
+// taskFinder is the part of the task repository needed to check who may see a task.
+type taskFinder interface {
+	GetTaskByID(ctx context.Context, taskId string) (*taskEntity.GetTasksByIdRes, error)
+}
+
+type attachmentSrv struct {
+	repo          attachmentRepo.AttachmentRepository
+	taskRepo      taskFinder
+	storage       storageService.StorageSrv
+	timeSrv       timeSrv.TimeService
+	validationSrv validationService.ValidationSrv
+}
+
+func NewAttachmentSrv(repo attachmentRepo.AttachmentRepository, taskRepo taskFinder, storage storageService.StorageSrv,
+	timeSrv timeSrv.TimeService, validationSrv validationService.ValidationSrv) AttachmentService {
+	return &attachmentSrv{repo: repo, taskRepo: taskRepo, storage: storage, timeSrv: timeSrv, validationSrv: validationSrv}
+}
+
+// Create Upload godoc
+// @Summary	Request a presigned url to upload an attachment to a task or comment
+// @Description	Returns a short lived url the client uploads the file to, followed by a call to complete the upload
+// @Tags	Attachments
+// @Accept	json
+// @Produce	json
+// @Param	request	body	attachmentEntity.CreateUploadReq	true	"Attachment details"
+// @Success	200  {object}  attachmentEntity.CreateUploadRes
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security BearerAuth
+// @Router	/attachment [post]
+func (a *attachmentSrv) CreateUpload(req *attachmentEntity.CreateUploadReq) (*attachmentEntity.CreateUploadRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	err := a.validationSrv.Validate(req)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewValidatingError("Bad Data Input")
+	}
+	if (req.TaskId == "") == (req.CommentId == "") {
+		return nil, ResponseEntity.NewValidatingError("exactly one of task_id or comment_id is required")
+	}
+	if !allowedMimeTypes[req.MimeType] {
+		return nil, ResponseEntity.NewValidatingError(fmt.Sprintf("file type %s is not allowed", req.MimeType))
+	}
+	if req.Size > MaxAttachmentSize {
+		return nil, ResponseEntity.NewValidatingError(fmt.Sprintf("file is larger than %d bytes", MaxAttachmentSize))
+	}
+
+	if req.CommentId != "" {
+		taskId, err := a.repo.GetCommentTaskId(ctx, req.CommentId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ResponseEntity.NewCustomServiceError(ErrNotFound, "comment does not exist")
+			}
+			log.Println(err)
+			return nil, ResponseEntity.NewInternalServiceError(err)
+		}
+		if errRes := a.checkTaskAccess(ctx, req.UploaderId, taskId); errRes != nil {
+			return nil, errRes
+		}
+	} else if errRes := a.checkTaskAccess(ctx, req.UploaderId, req.TaskId); errRes != nil {
+		return nil, errRes
+	}
+
+	req.Checksum = strings.ToLower(req.Checksum)
+	req.AttachmentId = uuid.New().String()
+	req.StorageKey = fmt.Sprintf("attachments/%s/%s", req.UploaderId, req.AttachmentId)
+	req.Status = attachmentEntity.StatusPending
+	req.CreatedAt = a.timeSrv.CurrentTimeString()
+
+	presigned, err := a.storage.PresignUpload(req.StorageKey, req.MimeType, req.Size, req.Checksum, uploadExpiry)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	err = a.repo.Persist(ctx, req)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	return &attachmentEntity.CreateUploadRes{
+		AttachmentId: req.AttachmentId,
+		UploadUrl:    presigned.Url,
+		Method:       presigned.Method,
+		Headers:      presigned.Headers,
+		ExpiresAt:    a.timeSrv.CurrentTime().Add(uploadExpiry).Format(time.RFC3339),
+	}, nil
+}
+
+// Complete Upload godoc
+// @Summary	Confirm an attachment has been uploaded
+// @Description	Checks the stored object against the declared size, type and checksum before attaching it
+// @Tags	Attachments
+// @Produce	json
+// @Param	attachmentId	path	string	true	"Attachment Id"
+// @Success	200  {object}  attachmentEntity.AttachmentRes
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security BearerAuth
+// @Router	/attachment/{attachmentId}/complete [post]
+func (a *attachmentSrv) CompleteUpload(userId, attachmentId string) (*attachmentEntity.AttachmentRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	attachment, errRes := a.getAttachment(ctx, attachmentId)
+	if errRes != nil {
+		return nil, errRes
+	}
+	if attachment.UploaderId != userId {
+		return nil, ResponseEntity.NewCustomServiceError(ErrForbidden, "only the uploader can complete an upload")
+	}
+	if attachment.Status == attachmentEntity.StatusUploaded {
+		return attachment, nil
+	}
+
+	info, err := a.storage.Stat(attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storageService.ErrObjectNotFound) {
+			return nil, ResponseEntity.NewValidatingError("file has not been uploaded yet")
+		}
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	if mismatch := verifyObject(attachment, info); mismatch != "" {
+		if err := a.storage.Delete(attachment.StorageKey); err != nil {
+			log.Println(err)
+		}
+		if err := a.repo.Delete(ctx, attachment.AttachmentId); err != nil {
+			log.Println(err)
+		}
+		return nil, ResponseEntity.NewValidatingError(mismatch)
+	}
+
+	err = a.repo.MarkUploaded(ctx, attachment.AttachmentId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	attachment.Status = attachmentEntity.StatusUploaded
+	return attachment, nil
+}
+
+// Get Download Url godoc
+// @Summary	Get an expiring url to download an attachment
+// @Tags	Attachments
+// @Produce	json
+// @Param	attachmentId	path	string	true	"Attachment Id"
+// @Success	200  {object}  attachmentEntity.DownloadRes
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security BearerAuth
+// @Router	/attachment/{attachmentId} [get]
+func (a *attachmentSrv) GetDownloadUrl(userId, attachmentId string) (*attachmentEntity.DownloadRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	attachment, errRes := a.getAttachment(ctx, attachmentId)
+	if errRes != nil {
+		return nil, errRes
+	}
+	if attachment.Status != attachmentEntity.StatusUploaded {
+		return nil, ResponseEntity.NewCustomServiceError(ErrNotFound, "attachment has not been uploaded")
+	}
+	if errRes := a.checkAttachmentAccess(ctx, userId, attachment); errRes != nil {
+		return nil, errRes
+	}
+
+	presigned, err := a.storage.PresignDownload(attachment.StorageKey, attachment.FileName, downloadExpiry)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	return &attachmentEntity.DownloadRes{
+		AttachmentId: attachment.AttachmentId,
+		FileName:     attachment.FileName,
+		DownloadUrl:  presigned.Url,
+		ExpiresAt:    a.timeSrv.CurrentTime().Add(downloadExpiry).Format(time.RFC3339),
+	}, nil
+}
+
+// List Task Attachments godoc
+// @Summary	List the attachments on a task
+// @Tags	Attachments
+// @Produce	json
+// @Param	taskId	path	string	true	"Task Id"
+// @Success	200  {object}  []attachmentEntity.AttachmentRes
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security BearerAuth
+// @Router	/attachment/task/{taskId} [get]
+func (a *attachmentSrv) ListByTask(userId, taskId string) ([]*attachmentEntity.AttachmentRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	if errRes := a.checkTaskAccess(ctx, userId, taskId); errRes != nil {
+		return nil, errRes
+	}
+
+	attachments, err := a.repo.ListByTask(ctx, taskId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return attachments, nil
+}
+
+// List Comment Attachments godoc
+// @Summary	List the attachments on a comment
+// @Tags	Attachments
+// @Produce	json
+// @Param	commentId	path	string	true	"Comment Id"
+// @Success	200  {object}  []attachmentEntity.AttachmentRes
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security BearerAuth
+// @Router	/attachment/comment/{commentId} [get]
+func (a *attachmentSrv) ListByComment(userId, commentId string) ([]*attachmentEntity.AttachmentRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	taskId, err := a.repo.GetCommentTaskId(ctx, commentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ResponseEntity.NewCustomServiceError(ErrNotFound, "comment does not exist")
+		}
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if errRes := a.checkTaskAccess(ctx, userId, taskId); errRes != nil {
+		return nil, errRes
+	}
+
+	attachments, err := a.repo.ListByComment(ctx, commentId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return attachments, nil
+}
+
+// Delete Attachment godoc
+// @Summary	Delete an attachment
+// @Tags	Attachments
+// @Produce	json
+// @Param	attachmentId	path	string	true	"Attachment Id"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security BearerAuth
+// @Router	/attachment/{attachmentId} [delete]
+func (a *attachmentSrv) DeleteAttachment(userId, attachmentId string) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	attachment, errRes := a.getAttachment(ctx, attachmentId)
+	if errRes != nil {
+		return nil, errRes
+	}
+	if attachment.UploaderId != userId {
+		return nil, ResponseEntity.NewCustomServiceError(ErrForbidden, "only the uploader can delete an attachment")
+	}
+
+	err := a.storage.Delete(attachment.StorageKey)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	err = a.repo.Delete(ctx, attachment.AttachmentId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return ResponseEntity.BuildSuccessResponse(200, "Deleted Attachment Successfully", nil, nil), nil
+}
+
+func (a *attachmentSrv) getAttachment(ctx context.Context, attachmentId string) (*attachmentEntity.AttachmentRes, *ResponseEntity.ServiceError) {
+	attachment, err := a.repo.GetById(ctx, attachmentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ResponseEntity.NewCustomServiceError(ErrNotFound, "attachment does not exist")
+		}
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return attachment, nil
+}
+
+func (a *attachmentSrv) checkAttachmentAccess(ctx context.Context, userId string, attachment *attachmentEntity.AttachmentRes) *ResponseEntity.ServiceError {
+	if attachment.UploaderId == userId {
+		return nil
+	}
+	taskId := attachment.TaskId
+	if taskId == "" {
+		var err error
+		taskId, err = a.repo.GetCommentTaskId(ctx, attachment.CommentId)
+		if err != nil {
+			log.Println(err)
+			return ResponseEntity.NewInternalServiceError(err)
+		}
+	}
+	return a.checkTaskAccess(ctx, userId, taskId)
+}
+
+// checkTaskAccess allows the owner of a task and the VA assigned to it.
+func (a *attachmentSrv) checkTaskAccess(ctx context.Context, userId, taskId string) *ResponseEntity.ServiceError {
+	task, err := a.taskRepo.GetTaskByID(ctx, taskId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ResponseEntity.NewCustomServiceError(ErrNotFound, "task does not exist")
+		}
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	if task.UserId != userId && task.VaId != userId {
+		return ResponseEntity.NewCustomServiceError(ErrForbidden, "you do not have access to this task")
+	}
+	return nil
+}
+
+func verifyObject(attachment *attachmentEntity.AttachmentRes, info *storageService.ObjectInfo) string {
+	switch {
+	case info.Size != attachment.Size:
+		return fmt.Sprintf("uploaded size %d does not match declared size %d", info.Size, attachment.Size)
+	case info.MimeType != "" && info.MimeType != attachment.MimeType:
+		return fmt.Sprintf("uploaded type %s does not match declared type %s", info.MimeType, attachment.MimeType)
+	case info.Checksum != "" && info.Checksum != attachment.Checksum:
+		return "uploaded file checksum does not match"
+	}
+	return ""
+}
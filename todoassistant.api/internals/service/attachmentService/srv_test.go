@@ -0,0 +1,252 @@
+package attachmentService
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"test-va/internals/entity/attachmentEntity"
+	"test-va/internals/entity/taskEntity"
+	"test-va/internals/service/storageService/localStorage"
+	"test-va/internals/service/timeSrv"
+	"test-va/internals/service/validationService"
+	"testing"
+)
+
+type memoryRepo struct {
+	attachments map[string]*attachmentEntity.AttachmentRes
+	comments    map[string]string
+}
+
+func (m *memoryRepo) Persist(ctx context.Context, req *attachmentEntity.CreateUploadReq) error {
+	m.attachments[req.AttachmentId] = &attachmentEntity.AttachmentRes{
+		AttachmentId: req.AttachmentId,
+		TaskId:       req.TaskId,
+		CommentId:    req.CommentId,
+		UploaderId:   req.UploaderId,
+		FileName:     req.FileName,
+		MimeType:     req.MimeType,
+		Size:         req.Size,
+		Checksum:     req.Checksum,
+		StorageKey:   req.StorageKey,
+		Status:       req.Status,
+		CreatedAt:    req.CreatedAt,
+	}
+	return nil
+}
+
+func (m *memoryRepo) GetById(ctx context.Context, attachmentId string) (*attachmentEntity.AttachmentRes, error) {
+	a, ok := m.attachments[attachmentId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	res := *a
+	return &res, nil
+}
+
+func (m *memoryRepo) MarkUploaded(ctx context.Context, attachmentId string) error {
+	m.attachments[attachmentId].Status = attachmentEntity.StatusUploaded
+	return nil
+}
+
+func (m *memoryRepo) ListByTask(ctx context.Context, taskId string) ([]*attachmentEntity.AttachmentRes, error) {
+	var res []*attachmentEntity.AttachmentRes
+	for _, a := range m.attachments {
+		if a.TaskId == taskId && a.Status == attachmentEntity.StatusUploaded {
+			res = append(res, a)
+		}
+	}
+	return res, nil
+}
+
+func (m *memoryRepo) ListByComment(ctx context.Context, commentId string) ([]*attachmentEntity.AttachmentRes, error) {
+	var res []*attachmentEntity.AttachmentRes
+	for _, a := range m.attachments {
+		if a.CommentId == commentId && a.Status == attachmentEntity.StatusUploaded {
+			res = append(res, a)
+		}
+	}
+	return res, nil
+}
+
+func (m *memoryRepo) GetCommentTaskId(ctx context.Context, commentId string) (string, error) {
+	taskId, ok := m.comments[commentId]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return taskId, nil
+}
+
+func (m *memoryRepo) Delete(ctx context.Context, attachmentId string) error {
+	delete(m.attachments, attachmentId)
+	return nil
+}
+
+type memoryTasks map[string]*taskEntity.GetTasksByIdRes
+
+func (m memoryTasks) GetTaskByID(ctx context.Context, taskId string) (*taskEntity.GetTasksByIdRes, error) {
+	task, ok := m[taskId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return task, nil
+}
+
+func newTestSrv(t *testing.T) (AttachmentService, localStorage.LocalStorage, *memoryRepo) {
+	repo := &memoryRepo{
+		attachments: map[string]*attachmentEntity.AttachmentRes{},
+		comments:    map[string]string{"comment-1": "task-1"},
+	}
+	tasks := memoryTasks{"task-1": {TaskId: "task-1", UserId: "owner", VaId: "va"}}
+	storage := localStorage.NewLocalStorage(t.TempDir(), "http://localhost/api/v1/files", "secret")
+	srv := NewAttachmentSrv(repo, tasks, storage, timeSrv.NewTimeStruct(), validationService.NewValidationStruct())
+	return srv, storage, repo
+}
+
+func upload(t *testing.T, storage localStorage.LocalStorage, uploadUrl, contentType string, body []byte) error {
+	u, err := url.Parse(uploadUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := strings.TrimPrefix(u.Path, "/api/v1/files/")
+	return storage.Save(key, u.Query(), contentType, bytes.NewReader(body))
+}
+
+func checksum(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestAttachmentLifecycle(t *testing.T) {
+	srv, storage, _ := newTestSrv(t)
+	body := []byte("%PDF-1.4 minutes of the meeting")
+
+	res, errRes := srv.CreateUpload(&attachmentEntity.CreateUploadReq{
+		TaskId:     "task-1",
+		UploaderId: "owner",
+		FileName:   "minutes.pdf",
+		MimeType:   "application/pdf",
+		Size:       int64(len(body)),
+		Checksum:   checksum(body),
+	})
+	if errRes != nil {
+		t.Fatalf("CreateUpload() error = %v", errRes)
+	}
+
+	if _, errRes := srv.CompleteUpload("owner", res.AttachmentId); errRes == nil {
+		t.Fatal("CompleteUpload() before upload should fail")
+	}
+	if err := upload(t, storage, res.UploadUrl, "application/pdf", body); err != nil {
+		t.Fatalf("upload error = %v", err)
+	}
+
+	attachment, errRes := srv.CompleteUpload("owner", res.AttachmentId)
+	if errRes != nil {
+		t.Fatalf("CompleteUpload() error = %v", errRes)
+	}
+	if attachment.Status != attachmentEntity.StatusUploaded {
+		t.Errorf("status = %s, want %s", attachment.Status, attachmentEntity.StatusUploaded)
+	}
+
+	list, errRes := srv.ListByTask("va", "task-1")
+	if errRes != nil || len(list) != 1 {
+		t.Fatalf("ListByTask() = %v, %v, want one attachment", list, errRes)
+	}
+
+	download, errRes := srv.GetDownloadUrl("va", res.AttachmentId)
+	if errRes != nil {
+		t.Fatalf("GetDownloadUrl() error = %v", errRes)
+	}
+	u, _ := url.Parse(download.DownloadUrl)
+	key := strings.TrimPrefix(u.Path, "/api/v1/files/")
+	if err := storage.Verify("GET", key, u.Query()); err != nil {
+		t.Errorf("download url does not verify: %v", err)
+	}
+	if err := storage.Verify("PUT", key, u.Query()); err == nil {
+		t.Error("download url should not verify for uploads")
+	}
+
+	if _, errRes := srv.GetDownloadUrl("stranger", res.AttachmentId); errRes == nil || errRes.Description != ErrForbidden {
+		t.Errorf("GetDownloadUrl() by stranger = %v, want forbidden", errRes)
+	}
+	if _, errRes := srv.DeleteAttachment("va", res.AttachmentId); errRes == nil {
+		t.Error("DeleteAttachment() by non uploader should fail")
+	}
+	if _, errRes := srv.DeleteAttachment("owner", res.AttachmentId); errRes != nil {
+		t.Fatalf("DeleteAttachment() error = %v", errRes)
+	}
+	if _, err := storage.Stat(key); err == nil {
+		t.Error("object should be removed from storage")
+	}
+}
+
+func TestCreateUploadRejects(t *testing.T) {
+	srv, _, _ := newTestSrv(t)
+	valid := attachmentEntity.CreateUploadReq{
+		TaskId:     "task-1",
+		UploaderId: "owner",
+		FileName:   "photo.png",
+		MimeType:   "image/png",
+		Size:       100,
+		Checksum:   checksum([]byte("x")),
+	}
+
+	tests := []struct {
+		name   string
+		modify func(r *attachmentEntity.CreateUploadReq)
+	}{
+		{"disallowed type", func(r *attachmentEntity.CreateUploadReq) { r.MimeType = "application/x-msdownload" }},
+		{"too large", func(r *attachmentEntity.CreateUploadReq) { r.Size = MaxAttachmentSize + 1 }},
+		{"bad checksum", func(r *attachmentEntity.CreateUploadReq) { r.Checksum = "abc" }},
+		{"no target", func(r *attachmentEntity.CreateUploadReq) { r.TaskId = "" }},
+		{"two targets", func(r *attachmentEntity.CreateUploadReq) { r.CommentId = "comment-1" }},
+		{"not a member", func(r *attachmentEntity.CreateUploadReq) { r.UploaderId = "stranger" }},
+		{"unknown comment", func(r *attachmentEntity.CreateUploadReq) { r.TaskId = ""; r.CommentId = "missing" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.modify(&req)
+			if _, errRes := srv.CreateUpload(&req); errRes == nil {
+				t.Error("CreateUpload() should fail")
+			}
+		})
+	}
+}
+
+func TestCompleteUploadMismatch(t *testing.T) {
+	srv, storage, repo := newTestSrv(t)
+	body := []byte("hello")
+
+	res, errRes := srv.CreateUpload(&attachmentEntity.CreateUploadReq{
+		CommentId:  "comment-1",
+		UploaderId: "va",
+		FileName:   "note.txt",
+		MimeType:   "text/plain",
+		Size:       int64(len(body)),
+		Checksum:   checksum([]byte("world")),
+	})
+	if errRes != nil {
+		t.Fatalf("CreateUpload() error = %v", errRes)
+	}
+
+	if err := upload(t, storage, res.UploadUrl, "image/png", body); err == nil {
+		t.Error("upload with a different content type should be rejected")
+	}
+	if err := upload(t, storage, res.UploadUrl, "text/plain", append(body, '!')); err == nil {
+		t.Error("upload with a different size should be rejected")
+	}
+	if err := upload(t, storage, res.UploadUrl, "text/plain", body); err != nil {
+		t.Fatalf("upload error = %v", err)
+	}
+
+	if _, errRes := srv.CompleteUpload("va", res.AttachmentId); errRes == nil {
+		t.Fatal("CompleteUpload() with wrong checksum should fail")
+	}
+	if _, ok := repo.attachments[res.AttachmentId]; ok {
+		t.Error("rejected attachment should be removed")
+	}
+}
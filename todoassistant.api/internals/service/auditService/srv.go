@@ -0,0 +1,170 @@
+package auditService
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"test-va/internals/Repository/auditRepo"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/loggerEntity"
+	"test-va/internals/service/loggerService"
+	"test-va/internals/service/validationService"
+	"time"
+)
+
+const (
+	defaultLimit = 100
+	// exportBatch is how many logs an export reads at a time
+	exportBatch  = 500
+	maxUserAgent = 512
+)
+
+var csvHeader = []string{"id", "created_at", "action", "outcome", "actor_id", "actor_role", "target_type", "target_id",
+	"detail", "ip_address", "user_agent", "status_code"}
+
+type AuditSrv interface {
+	// Record adds a log. It never fails the request it records, a log that cannot be stored is written to the log file.
+	Record(log *loggerEntity.AuditLog)
+	GetLogs(filter *loggerEntity.AuditFilter) (*loggerEntity.AuditLogsRes, *ResponseEntity.ServiceError)
+	// ExportLogs writes every log matching filter to w as csv, newest first.
+	ExportLogs(filter *loggerEntity.AuditFilter, w io.Writer) *ResponseEntity.ServiceError
+}
+
+type auditSrv struct {
+	repo      auditRepo.AuditRepository
+	logger    loggerService.LogSrv
+	validator validationService.ValidationSrv
+	now       func() time.Time
+}
+
+func NewAuditSrv(repo auditRepo.AuditRepository, logger loggerService.LogSrv, validator validationService.ValidationSrv) AuditSrv {
+	return &auditSrv{repo: repo, logger: logger, validator: validator, now: time.Now}
+}
+
+func (a *auditSrv) Record(record *loggerEntity.AuditLog) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*10)
+	defer cancelFunc()
+
+	record.CreatedAt = a.now().UTC().Format(time.RFC3339)
+	if len(record.UserAgent) > maxUserAgent {
+		record.UserAgent = record.UserAgent[:maxUserAgent]
+	}
+	err := a.repo.AddLog(ctx, record)
+	if err != nil {
+		log.Println("could not store audit log:", err)
+		a.logger.Audit(record)
+	}
+}
+
+// Get Audit Logs godoc
+// @Summary	List the audit log
+// @Description	Logins, failed logins, password changes, token refreshes and admin actions, newest first. Only masters can read the audit log.
+// @Tags	VA - Master
+// @Produce	json
+// @Param	action	query	string	false	"Action, as login or va.delete"
+// @Param	outcome	query	string	false	"success or failure"
+// @Param	actor_id	query	string	false	"Id of the account that acted"
+// @Param	target_id	query	string	false	"Id or email the action was done to"
+// @Param	ip_address	query	string	false	"IP address the request came from"
+// @Param	from	query	string	false	"First day, YYYY-MM-DD"
+// @Param	to	query	string	false	"Last day, YYYY-MM-DD"
+// @Param	before	query	int	false	"next_before of the previous page"
+// @Param	limit	query	int	false	"Logs per page, up to 500, defaults to 100"
+// @Success	200  {object}  loggerEntity.AuditLogsRes
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/va/audit [get]
+func (a *auditSrv) GetLogs(filter *loggerEntity.AuditFilter) (*loggerEntity.AuditLogsRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	err := a.validator.Validate(filter)
+	if err != nil {
+		return nil, ResponseEntity.NewValidatingError(err)
+	}
+	limit := filter.Limit
+	if limit == 0 {
+		limit = defaultLimit
+	}
+
+	logs, err := a.repo.GetLogs(ctx, filter, limit)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	res := &loggerEntity.AuditLogsRes{Logs: logs}
+	if len(logs) == limit {
+		res.NextBefore = logs[len(logs)-1].Id
+	}
+	return res, nil
+}
+
+// Export Audit Logs godoc
+// @Summary	Export the audit log as csv
+// @Description	Every log matching the filters, newest first. Only masters can export the audit log, and exporting it is logged too.
+// @Tags	VA - Master
+// @Produce	text/csv
+// @Param	action	query	string	false	"Action, as login or va.delete"
+// @Param	outcome	query	string	false	"success or failure"
+// @Param	actor_id	query	string	false	"Id of the account that acted"
+// @Param	target_id	query	string	false	"Id or email the action was done to"
+// @Param	ip_address	query	string	false	"IP address the request came from"
+// @Param	from	query	string	false	"First day, YYYY-MM-DD"
+// @Param	to	query	string	false	"Last day, YYYY-MM-DD"
+// @Success	200  {string}  string  "csv"
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/va/audit/export [get]
+func (a *auditSrv) ExportLogs(filter *loggerEntity.AuditFilter, w io.Writer) *ResponseEntity.ServiceError {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*5)
+	defer cancelFunc()
+
+	err := a.validator.Validate(filter)
+	if err != nil {
+		return ResponseEntity.NewValidatingError(err)
+	}
+
+	// the first batch is read before anything is written, so a failing query can still be answered with an error
+	page := *filter
+	logs, err := a.repo.GetLogs(ctx, &page, exportBatch)
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+
+	out := csv.NewWriter(w)
+	out.Write(csvHeader)
+	for len(logs) > 0 {
+		for _, record := range logs {
+			out.Write([]string{strconv.FormatInt(record.Id, 10), record.CreatedAt, record.Action, record.Outcome,
+				cell(record.ActorId), record.ActorRole, record.TargetType, cell(record.TargetId),
+				cell(record.Detail), cell(record.IPAddress), cell(record.UserAgent), strconv.Itoa(record.StatusCode)})
+		}
+		if len(logs) < exportBatch {
+			break
+		}
+		page.Before = logs[len(logs)-1].Id
+		logs, err = a.repo.GetLogs(ctx, &page, exportBatch)
+		if err != nil {
+			// the csv is cut short, there is no way to tell the client anymore but to stop
+			log.Println("audit export failed:", err)
+			break
+		}
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		log.Println(err)
+	}
+	return nil
+}
+
+// cell keeps a value sent by a client from being run as a formula when the csv is opened in a spreadsheet.
+func cell(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
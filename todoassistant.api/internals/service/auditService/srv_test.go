@@ -0,0 +1,159 @@
+package auditService
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+	"test-va/internals/Repository/auditRepo"
+	"test-va/internals/entity/loggerEntity"
+	"test-va/internals/service/loggerService"
+	"test-va/internals/service/validationService"
+	"testing"
+	"time"
+)
+
+// memoryRepo filters on the action and the page only, the rest of the filter is up to the sql.
+type memoryRepo struct {
+	auditRepo.AuditRepository
+	logs []*loggerEntity.AuditLog
+	err  error
+}
+
+func (m *memoryRepo) AddLog(ctx context.Context, log *loggerEntity.AuditLog) error {
+	if m.err != nil {
+		return m.err
+	}
+	stored := *log
+	stored.Id = int64(len(m.logs) + 1)
+	m.logs = append(m.logs, &stored)
+	return nil
+}
+
+func (m *memoryRepo) GetLogs(ctx context.Context, filter *loggerEntity.AuditFilter, limit int) ([]*loggerEntity.AuditLog, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	logs := []*loggerEntity.AuditLog{}
+	for i := len(m.logs) - 1; i >= 0 && len(logs) < limit; i-- {
+		log := m.logs[i]
+		if filter.Action != "" && log.Action != filter.Action {
+			continue
+		}
+		if filter.Before != 0 && log.Id >= filter.Before {
+			continue
+		}
+		logs = append(logs, log)
+	}
+	return logs, nil
+}
+
+type memoryLogger struct {
+	loggerService.LogSrv
+	audits []*loggerEntity.AuditLog
+}
+
+func (m *memoryLogger) Audit(record *loggerEntity.AuditLog) {
+	m.audits = append(m.audits, record)
+}
+
+func newTestSrv(repo *memoryRepo, logger *memoryLogger) *auditSrv {
+	now := time.Date(2023, 3, 7, 12, 0, 0, 0, time.UTC)
+	return &auditSrv{repo: repo, logger: logger, validator: validationService.NewValidationStruct(),
+		now: func() time.Time { return now }}
+}
+
+func TestRecordFallsBackToLogFile(t *testing.T) {
+	repo := &memoryRepo{}
+	logger := &memoryLogger{}
+	srv := newTestSrv(repo, logger)
+
+	srv.Record(&loggerEntity.AuditLog{Action: loggerEntity.AuditLogin, UserAgent: strings.Repeat("a", 600)})
+	if len(repo.logs) != 1 || repo.logs[0].CreatedAt != "2023-03-07T12:00:00Z" || len(repo.logs[0].UserAgent) != maxUserAgent {
+		t.Fatalf("stored logs = %+v", repo.logs)
+	}
+
+	repo.err = errors.New("db down")
+	srv.Record(&loggerEntity.AuditLog{Action: loggerEntity.AuditVADelete})
+	if len(logger.audits) != 1 || logger.audits[0].Action != loggerEntity.AuditVADelete {
+		t.Errorf("a log that cannot be stored should go to the log file, got %+v", logger.audits)
+	}
+}
+
+func TestGetLogsPages(t *testing.T) {
+	repo := &memoryRepo{}
+	srv := newTestSrv(repo, &memoryLogger{})
+	for i := 0; i < 5; i++ {
+		srv.Record(&loggerEntity.AuditLog{Action: loggerEntity.AuditLogin})
+	}
+
+	res, errRes := srv.GetLogs(&loggerEntity.AuditFilter{Limit: 3})
+	if errRes != nil {
+		t.Fatalf("GetLogs() error = %v", errRes)
+	}
+	if len(res.Logs) != 3 || res.Logs[0].Id != 5 || res.NextBefore != 3 {
+		t.Fatalf("first page = %d logs from %d, next %d", len(res.Logs), res.Logs[0].Id, res.NextBefore)
+	}
+	res, _ = srv.GetLogs(&loggerEntity.AuditFilter{Limit: 3, Before: res.NextBefore})
+	if len(res.Logs) != 2 || res.NextBefore != 0 {
+		t.Errorf("last page = %d logs, next %d", len(res.Logs), res.NextBefore)
+	}
+
+	for _, filter := range []*loggerEntity.AuditFilter{{Outcome: "maybe"}, {From: "07/03/2023"}, {Limit: 501}} {
+		if _, errRes := srv.GetLogs(filter); errRes == nil || errRes.Description != "BadInput Request" {
+			t.Errorf("GetLogs(%+v) = %v, want a bad input", filter, errRes)
+		}
+	}
+}
+
+func TestExportLogs(t *testing.T) {
+	repo := &memoryRepo{}
+	srv := newTestSrv(repo, &memoryLogger{})
+	for i := 0; i < exportBatch+1; i++ {
+		srv.Record(&loggerEntity.AuditLog{Action: loggerEntity.AuditLogin, Outcome: loggerEntity.OutcomeFailure,
+			TargetType: loggerEntity.TargetEmail, TargetId: fmt.Sprintf("user%d@example.com", i)})
+	}
+	srv.Record(&loggerEntity.AuditLog{Action: loggerEntity.AuditLogin, TargetId: "=HYPERLINK(\"http://evil.test\")"})
+	srv.Record(&loggerEntity.AuditLog{Action: loggerEntity.AuditVADelete})
+	// a suspension reason is typed by a master, it is not trusted either
+	srv.Record(&loggerEntity.AuditLog{Action: loggerEntity.AuditUserSuspend, TargetType: loggerEntity.TargetUser, TargetId: "u1",
+		Detail: "@SUM(1+1)*cmd|' /C calc'!A0"})
+
+	var out strings.Builder
+	if errRes := srv.ExportLogs(&loggerEntity.AuditFilter{Action: loggerEntity.AuditLogin}, &out); errRes != nil {
+		t.Fatalf("ExportLogs() error = %v", errRes)
+	}
+	rows, err := csv.NewReader(strings.NewReader(out.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the header, then every login across batches
+	if len(rows) != exportBatch+3 {
+		t.Fatalf("exported %d rows, want %d", len(rows), exportBatch+3)
+	}
+	if rows[1][7] != "'=HYPERLINK(\"http://evil.test\")" {
+		t.Errorf("target = %q, want it escaped", rows[1][7])
+	}
+	if rows[len(rows)-1][7] != "user0@example.com" {
+		t.Errorf("last row target = %q, want the oldest login", rows[len(rows)-1][7])
+	}
+
+	out.Reset()
+	if errRes := srv.ExportLogs(&loggerEntity.AuditFilter{Action: loggerEntity.AuditUserSuspend}, &out); errRes != nil {
+		t.Fatalf("ExportLogs() error = %v", errRes)
+	}
+	rows, err = csv.NewReader(strings.NewReader(out.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1][8] != "'@SUM(1+1)*cmd|' /C calc'!A0" {
+		t.Errorf("suspension rows = %q, want the reason escaped", rows)
+	}
+
+	repo.err = errors.New("db down")
+	out.Reset()
+	if errRes := srv.ExportLogs(&loggerEntity.AuditFilter{}, &out); errRes == nil || out.Len() != 0 {
+		t.Errorf("a failing export should error before writing, got %v and %q", errRes, out.String())
+	}
+}
@@ -9,6 +9,8 @@ import (
 
 type AWSService interface {
 	UploadImage(file multipart.File, filename string) error
+	// DeleteImages removes every image stored under prefix, such as all the avatars of a user.
+	DeleteImages(prefix string) (int, error)
 }
 
 type awsSrv struct {
@@ -32,6 +34,36 @@ func (a *awsSrv) UploadImage(file multipart.File, filename string) error {
 	return nil
 }
 
+func (a *awsSrv) DeleteImages(prefix string) (int, error) {
+	deleted := 0
+	var deleteErr error
+	err := a.s3session.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String("ticked-v1-backend-bucket"),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		if len(page.Contents) == 0 {
+			return !lastPage
+		}
+		objects := make([]*s3.ObjectIdentifier, 0, len(page.Contents))
+		for _, object := range page.Contents {
+			objects = append(objects, &s3.ObjectIdentifier{Key: object.Key})
+		}
+		_, deleteErr = a.s3session.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String("ticked-v1-backend-bucket"),
+			Delete: &s3.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if deleteErr != nil {
+			return false
+		}
+		deleted += len(objects)
+		return true
+	})
+	if err == nil {
+		err = deleteErr
+	}
+	return deleted, err
+}
+
 func NewAWSSrv(s *s3.S3) AWSService {
 	return &awsSrv{s3session: s}
 }
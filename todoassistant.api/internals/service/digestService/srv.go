@@ -0,0 +1,206 @@
+package digestService
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"log"
+	"test-va/internals/Repository/digestRepo"
+	"test-va/internals/entity/digestEntity"
+	"test-va/internals/entity/eventEntity"
+	"test-va/internals/msg-queue/Emitter"
+	"test-va/internals/service/timeSrv"
+	"time"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	// sendWindow is how long after a user's reminder time the digest may still go out,
+	// so a missed run does not send yesterday's digest late in the evening.
+	sendWindow          = time.Hour
+	defaultReminderTime = "08:00"
+)
+
+type DigestService interface {
+	// SendDueDigests sends the digest of every opted in user whose reminder time has just passed.
+	SendDueDigests()
+}
+
+type digestSrv struct {
+	repo    digestRepo.DigestRepository
+	timeSrv timeSrv.TimeService
+	emitter Emitter.Emitter
+}
+
+func NewDigestSrv(repo digestRepo.DigestRepository, timeSrv timeSrv.TimeService, emitter Emitter.Emitter) DigestService {
+	return &digestSrv{repo: repo, timeSrv: timeSrv, emitter: emitter}
+}
+
+// Digest is what goes into one user's email.
+type Digest struct {
+	FirstName          string
+	Date               string
+	DueToday           []*digestEntity.DigestTask
+	Overdue            []*digestEntity.DigestTask
+	CompletedYesterday []*digestEntity.DigestTask
+	Comments           []*digestEntity.DigestComment
+}
+
+func (d *Digest) Empty() bool {
+	return len(d.DueToday) == 0 && len(d.Overdue) == 0 && len(d.CompletedYesterday) == 0 && len(d.Comments) == 0
+}
+
+func (d *digestSrv) SendDueDigests() {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*5)
+	defer cancelFunc()
+
+	recipients, err := d.repo.GetRecipients(ctx)
+	if err != nil {
+		log.Println("digest: could not get recipients", err)
+		return
+	}
+
+	now := d.timeSrv.CurrentTime()
+	for _, recipient := range recipients {
+		loc, err := time.LoadLocation(recipient.TimeZone)
+		if err != nil {
+			loc = time.UTC
+		}
+		local := now.In(loc)
+		if !IsDue(recipient.ReminderTime, recipient.LastSentOn, local) {
+			continue
+		}
+		if err := d.send(ctx, recipient, local); err != nil {
+			log.Println("digest: could not send to", recipient.UserId, err)
+		}
+	}
+}
+
+func (d *digestSrv) send(ctx context.Context, recipient *digestEntity.Recipient, local time.Time) error {
+	yesterday := startOfDay(local).AddDate(0, 0, -1)
+	tasks, err := d.repo.GetTasks(ctx, recipient.UserId, yesterday.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	comments, err := d.repo.GetVAComments(ctx, recipient.UserId, local.Add(-24*time.Hour).UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+
+	digest := BuildDigest(recipient, tasks, comments, local)
+	if digest.Empty() {
+		return nil
+	}
+	body, err := RenderDigest(digest)
+	if err != nil {
+		return err
+	}
+
+	// claim the day first so two instances of the api never both send
+	today := local.Format(dateLayout)
+	claimed, err := d.repo.ClaimDigest(ctx, recipient.UserId, today, d.timeSrv.CurrentTimeString())
+	if err != nil || !claimed {
+		return err
+	}
+
+	payload := eventEntity.Payload{
+		Action:    "email",
+		SubAction: "daily_digest",
+		Data: map[string]string{
+			"email_address": recipient.Email,
+			"email_subject": fmt.Sprintf("Subject: Your Ticked digest for %s\nMIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n", digest.Date),
+			"email_body":    body,
+		},
+	}
+	err = d.emitter.Push(payload, "info")
+	if err != nil {
+		// let the next run try again
+		if releaseErr := d.repo.ReleaseDigest(ctx, recipient.UserId, today); releaseErr != nil {
+			log.Println(releaseErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// IsDue reports whether a digest should go out at local, the current time in the user's time zone.
+func IsDue(reminderTime, lastSentOn string, local time.Time) bool {
+	if lastSentOn >= local.Format(dateLayout) {
+		return false
+	}
+	at := reminderAt(reminderTime, local)
+	return !local.Before(at) && local.Sub(at) < sendWindow
+}
+
+// reminderAt returns the reminder time on the day of local. Unreadable values fall back to the default.
+func reminderAt(reminderTime string, local time.Time) time.Time {
+	for _, value := range []string{reminderTime, defaultReminderTime} {
+		for _, layout := range []string{"15:04", "15:04:05", "3:04 PM", "3:04PM", time.RFC3339} {
+			t, err := time.Parse(layout, value)
+			if err != nil {
+				continue
+			}
+			if layout == time.RFC3339 {
+				t = t.In(local.Location())
+			}
+			return time.Date(local.Year(), local.Month(), local.Day(), t.Hour(), t.Minute(), 0, 0, local.Location())
+		}
+	}
+	return startOfDay(local)
+}
+
+// BuildDigest sorts the user's tasks into due today, overdue and completed yesterday, all in the user's time zone.
+func BuildDigest(recipient *digestEntity.Recipient, tasks []*digestEntity.DigestTask, comments []*digestEntity.DigestComment, local time.Time) *Digest {
+	today := startOfDay(local)
+	tomorrow := today.AddDate(0, 0, 1)
+	yesterday := today.AddDate(0, 0, -1)
+
+	digest := &Digest{
+		FirstName: recipient.FirstName,
+		Date:      local.Format("Mon, 2 Jan 2006"),
+		Comments:  comments,
+	}
+	for _, task := range tasks {
+		if task.Status == "COMPLETED" {
+			completed, err := time.Parse(time.RFC3339, task.CompletedAt)
+			if err == nil && !completed.Before(yesterday) && completed.Before(today) {
+				digest.CompletedYesterday = append(digest.CompletedYesterday, task)
+			}
+			continue
+		}
+		due, err := time.Parse(time.RFC3339, task.EndTime)
+		if err != nil {
+			continue
+		}
+		switch {
+		case due.Before(local):
+			digest.Overdue = append(digest.Overdue, task)
+		case due.Before(tomorrow):
+			digest.DueToday = append(digest.DueToday, task)
+		}
+	}
+	return digest
+}
+
+var digestTemplate = template.Must(template.New("digest").Parse(`<html><body style="font-family: sans-serif;">
+<h2>Good morning{{if .FirstName}} {{.FirstName}}{{end}},</h2>
+<p>Here is your Ticked digest for {{.Date}}.</p>
+{{if .Overdue}}<h3>Overdue</h3><ul>{{range .Overdue}}<li>{{.Title}}</li>{{end}}</ul>{{end}}
+{{if .DueToday}}<h3>Due today</h3><ul>{{range .DueToday}}<li>{{.Title}}</li>{{end}}</ul>{{end}}
+{{if .CompletedYesterday}}<h3>Completed yesterday</h3><ul>{{range .CompletedYesterday}}<li>{{.Title}}</li>{{end}}</ul>{{end}}
+{{if .Comments}}<h3>From your assistant</h3><ul>{{range .Comments}}<li><b>{{.VaName}}</b> on <i>{{.TaskTitle}}</i>: {{.Comment}}</li>{{end}}</ul>{{end}}
+<p>You can turn this email off under Settings, Product emails.</p>
+</body></html>`))
+
+func RenderDigest(digest *Digest) (string, error) {
+	var buf bytes.Buffer
+	if err := digestTemplate.Execute(&buf, digest); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
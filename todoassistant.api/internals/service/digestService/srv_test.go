@@ -0,0 +1,144 @@
+package digestService
+
+import (
+	"context"
+	"strings"
+	"test-va/internals/entity/digestEntity"
+	"test-va/internals/entity/eventEntity"
+	"test-va/internals/service/timeSrv"
+	"testing"
+	"time"
+)
+
+func TestIsDue(t *testing.T) {
+	lagos, _ := time.LoadLocation("Africa/Lagos")
+	at := func(hour, min int) time.Time { return time.Date(2023, 3, 7, hour, min, 0, 0, lagos) }
+
+	tests := []struct {
+		name         string
+		reminderTime string
+		lastSentOn   string
+		local        time.Time
+		want         bool
+	}{
+		{"before reminder time", "09:00", "", at(8, 59), false},
+		{"at reminder time", "09:00", "", at(9, 0), true},
+		{"within the window", "09:00", "2023-03-06", at(9, 40), true},
+		{"after the window", "09:00", "", at(10, 5), false},
+		{"already sent today", "09:00", "2023-03-07", at(9, 5), false},
+		{"twelve hour clock", "6:30 PM", "", at(18, 45), true},
+		{"unreadable time uses the default", "soon", "", at(8, 10), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsDue(tt.reminderTime, tt.lastSentOn, tt.local); got != tt.want {
+				t.Errorf("IsDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildDigest(t *testing.T) {
+	local := time.Date(2023, 3, 7, 9, 0, 0, 0, time.UTC)
+	tasks := []*digestEntity.DigestTask{
+		{TaskId: "1", Title: "Overdue <report>", Status: "PENDING", EndTime: "2023-03-06T17:00:00Z"},
+		{TaskId: "2", Title: "Call bank", Status: "PENDING", EndTime: "2023-03-07T15:00:00Z"},
+		{TaskId: "3", Title: "Next week", Status: "PENDING", EndTime: "2023-03-14T15:00:00Z"},
+		{TaskId: "4", Title: "Filed taxes", Status: "COMPLETED", CompletedAt: "2023-03-06T12:00:00Z"},
+		{TaskId: "5", Title: "Done today", Status: "COMPLETED", CompletedAt: "2023-03-07T08:00:00Z"},
+	}
+	comments := []*digestEntity.DigestComment{{TaskTitle: "Call bank", VaName: "Ada L", Comment: "Booked for 3pm"}}
+
+	digest := BuildDigest(&digestEntity.Recipient{FirstName: "Sam"}, tasks, comments, local)
+	if len(digest.Overdue) != 1 || len(digest.DueToday) != 1 || len(digest.CompletedYesterday) != 1 {
+		t.Fatalf("unexpected digest %+v", digest)
+	}
+
+	body, err := RenderDigest(digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Sam", "Overdue &lt;report&gt;", "Call bank", "Filed taxes", "Booked for 3pm"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("digest body is missing %q", want)
+		}
+	}
+	if strings.Contains(body, "Next week") || strings.Contains(body, "Done today") {
+		t.Error("digest body contains tasks it should not")
+	}
+}
+
+type memoryRepo struct {
+	recipients []*digestEntity.Recipient
+	tasks      []*digestEntity.DigestTask
+	sent       map[string]bool
+}
+
+func (m *memoryRepo) GetRecipients(ctx context.Context) ([]*digestEntity.Recipient, error) {
+	return m.recipients, nil
+}
+
+func (m *memoryRepo) GetTasks(ctx context.Context, userId, completedSince string) ([]*digestEntity.DigestTask, error) {
+	return m.tasks, nil
+}
+
+func (m *memoryRepo) GetVAComments(ctx context.Context, userId, since string) ([]*digestEntity.DigestComment, error) {
+	return nil, nil
+}
+
+func (m *memoryRepo) ClaimDigest(ctx context.Context, userId, date, sentAt string) (bool, error) {
+	if m.sent[userId+date] {
+		return false, nil
+	}
+	m.sent[userId+date] = true
+	return true, nil
+}
+
+func (m *memoryRepo) ReleaseDigest(ctx context.Context, userId, date string) error {
+	delete(m.sent, userId+date)
+	return nil
+}
+
+type memoryEmitter struct {
+	payloads []eventEntity.Payload
+}
+
+func (m *memoryEmitter) Push(payload eventEntity.Payload, severity string) error {
+	m.payloads = append(m.payloads, payload)
+	return nil
+}
+
+type fixedTime struct {
+	timeSrv.TimeService
+	now time.Time
+}
+
+func (f fixedTime) CurrentTime() time.Time    { return f.now }
+func (f fixedTime) CurrentTimeString() string { return f.now.Format(time.RFC3339) }
+
+func TestSendDueDigestsOncePerDay(t *testing.T) {
+	repo := &memoryRepo{
+		recipients: []*digestEntity.Recipient{
+			// 08:00 UTC is 09:00 in Lagos
+			{UserId: "due", Email: "due@example.com", ReminderTime: "09:00", TimeZone: "Africa/Lagos"},
+			{UserId: "later", Email: "later@example.com", ReminderTime: "17:00", TimeZone: "UTC"},
+		},
+		tasks: []*digestEntity.DigestTask{{Title: "Call bank", Status: "PENDING", EndTime: "2023-03-07T15:00:00Z"}},
+		sent:  map[string]bool{},
+	}
+	emitter := &memoryEmitter{}
+	srv := NewDigestSrv(repo, fixedTime{now: time.Date(2023, 3, 7, 8, 10, 0, 0, time.UTC)}, emitter)
+
+	srv.SendDueDigests()
+	srv.SendDueDigests()
+
+	if len(emitter.payloads) != 1 {
+		t.Fatalf("sent %d digests, want 1", len(emitter.payloads))
+	}
+	if got := emitter.payloads[0].Data["email_address"]; got != "due@example.com" {
+		t.Errorf("sent to %s, want due@example.com", got)
+	}
+	if !strings.Contains(emitter.payloads[0].Data["email_subject"], "text/html") {
+		t.Error("digest should be sent as html")
+	}
+}
@@ -0,0 +1,182 @@
+package loginAlertService
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"test-va/internals/Repository/userRepo"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/eventEntity"
+	"test-va/internals/entity/tokenEntity"
+	"test-va/internals/entity/userEntity"
+	"test-va/internals/msg-queue/Emitter"
+	tokenservice "test-va/internals/service/tokenService"
+	"test-va/internals/service/validationService"
+
+	"github.com/google/uuid"
+)
+
+// the "this wasn't me" link of an alert works this long
+const disownLinkLifetime = time.Hour * 24 * 7
+
+type LoginAlertSrv interface {
+	// Notify emails the user when the session a login just started is from a device they have not used
+	// before, unless they turned login alerts off. It never fails the login, errors are only logged.
+	Notify(userId, email, firstName string, session *tokenEntity.SessionInfo)
+	// Disown logs out the session an alert was sent for and requires a password reset.
+	Disown(req *userEntity.DisownLoginReq) *ResponseEntity.ServiceError
+	// PurgeExpired forgets alerts whose link cannot be used anymore.
+	PurgeExpired()
+}
+
+type loginAlertSrv struct {
+	repo      userRepo.UserRepository
+	tokenSrv  tokenservice.TokenSrv
+	validator validationService.ValidationSrv
+	emitter   Emitter.Emitter
+	// appBaseUrl is where the links sent by email point to
+	appBaseUrl string
+	now        func() time.Time
+}
+
+func NewLoginAlertSrv(repo userRepo.UserRepository, tokenSrv tokenservice.TokenSrv, validator validationService.ValidationSrv,
+	emitter Emitter.Emitter, appBaseUrl string) LoginAlertSrv {
+	return &loginAlertSrv{repo: repo, tokenSrv: tokenSrv, validator: validator, emitter: emitter, appBaseUrl: appBaseUrl, now: time.Now}
+}
+
+func (l *loginAlertSrv) Notify(userId, email, firstName string, session *tokenEntity.SessionInfo) {
+	if session == nil || session.SessionId == "" {
+		return
+	}
+	// without a settings row the alert is sent, it is only off when turned off
+	settings, err := l.repo.GetProductEmailSettingsById(userId)
+	if err == nil && !settings.LoginAlert {
+		return
+	}
+	isNew, err := l.tokenSrv.IsNewDevice(userId, session.SessionId)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if !isNew {
+		return
+	}
+
+	now := l.now().UTC()
+	token := uuid.New().String()
+	err = l.repo.AddLoginAlert(&userEntity.LoginAlert{
+		TokenHash: hashToken(token),
+		UserId:    userId,
+		SessionId: session.SessionId,
+		ExpiresAt: now.Add(disownLinkLifetime).Format(time.RFC3339),
+		CreatedAt: now.Format(time.RFC3339),
+	})
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	payload := eventEntity.Payload{
+		Action:    "email",
+		SubAction: "login_alert",
+		Data: map[string]string{
+			"email_address": email,
+			"email_subject": "Subject: New sign-in to your getticked account\n",
+			"email_body":    createAlertBody(firstName, session, now, l.appLink("not-me", token)),
+		},
+	}
+	err = l.emitter.Push(payload, "info")
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// Disown Login godoc
+// @Summary	Report a login as not yours
+// @Description	Takes the token of the link in a new sign-in email. The session of that login is logged out and the password cannot be used to log in until it is reset through /user/reset-password. A link works once and for 7 days.
+// @Tags	Users
+// @Accept	json
+// @Produce	json
+// @Param	request	body	userEntity.DisownLoginReq	true	"Token from the link"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Router	/user/login-alert/disown [post]
+func (l *loginAlertSrv) Disown(req *userEntity.DisownLoginReq) *ResponseEntity.ServiceError {
+	err := l.validator.Validate(req)
+	if err != nil {
+		return ResponseEntity.NewValidatingError(err)
+	}
+
+	alert, err := l.repo.GetLoginAlert(hashToken(req.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ResponseEntity.NewValidatingError("link is invalid or has expired")
+	}
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	now := l.now().UTC().Format(time.RFC3339)
+	if alert.UsedAt != "" || alert.ExpiresAt < now {
+		return ResponseEntity.NewValidatingError("link is invalid or has expired")
+	}
+	used, err := l.repo.UseLoginAlert(alert.TokenHash, now)
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	if !used {
+		return ResponseEntity.NewValidatingError("link is invalid or has expired")
+	}
+
+	// the session may be gone already, whoever logged in may still know the password
+	err = l.tokenSrv.DisownSession(alert.UserId, alert.SessionId)
+	if err != nil && !errors.Is(err, tokenservice.ErrSessionNotFound) {
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	err = l.repo.RequirePasswordReset(alert.UserId)
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	return nil
+}
+
+func (l *loginAlertSrv) PurgeExpired() {
+	err := l.repo.DeleteExpiredLoginAlerts(l.now().UTC().Format(time.RFC3339))
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// appLink is the page of the web app that handles token, or only the token when there is no web app configured.
+func (l *loginAlertSrv) appLink(page, token string) string {
+	if l.appBaseUrl == "" {
+		return token
+	}
+	return fmt.Sprintf("%s/%s?token=%s", strings.TrimSuffix(l.appBaseUrl, "/"), page, token)
+}
+
+// hashToken is how alert links are stored, so the table cannot be used to log anyone out.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func createAlertBody(firstName string, session *tokenEntity.SessionInfo, at time.Time, link string) string {
+	device := session.DeviceName
+	if device == "" {
+		device = session.UserAgent
+	}
+	if device == "" {
+		device = "unknown"
+	}
+	subject := fmt.Sprintf("Hi %v, \n\n", firstName)
+	mainBody := fmt.Sprintf("Your getticked account was just signed in to from a device you have not used before.\n\nDevice: %v\nIP address: %v\nTime: %v UTC\n\nIf this was you, you can ignore this email. If it was not, this link logs that device out and you will have to reset your password before logging in again:\n%v\n\nLink expires in 7 days!",
+		device, session.IPAddress, at.Format("2 Jan 2006 15:04"), link)
+	return subject + mainBody
+}
@@ -0,0 +1,152 @@
+package loginAlertService
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+	"test-va/internals/Repository/userRepo"
+	"test-va/internals/entity/eventEntity"
+	"test-va/internals/entity/tokenEntity"
+	"test-va/internals/entity/userEntity"
+	tokenservice "test-va/internals/service/tokenService"
+	"test-va/internals/service/validationService"
+	"testing"
+	"time"
+)
+
+type memoryRepo struct {
+	userRepo.UserRepository
+	alertsOff     map[string]bool
+	alerts        map[string]*userEntity.LoginAlert
+	resetRequired map[string]bool
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{alertsOff: map[string]bool{}, alerts: map[string]*userEntity.LoginAlert{}, resetRequired: map[string]bool{}}
+}
+
+func (m *memoryRepo) GetProductEmailSettingsById(userId string) (*userEntity.ProductEmailSettingsRes, error) {
+	return &userEntity.ProductEmailSettingsRes{LoginAlert: !m.alertsOff[userId]}, nil
+}
+
+func (m *memoryRepo) AddLoginAlert(req *userEntity.LoginAlert) error {
+	m.alerts[req.TokenHash] = req
+	return nil
+}
+
+func (m *memoryRepo) GetLoginAlert(tokenHash string) (*userEntity.LoginAlert, error) {
+	alert, ok := m.alerts[tokenHash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	stored := *alert
+	return &stored, nil
+}
+
+func (m *memoryRepo) UseLoginAlert(tokenHash, usedAt string) (bool, error) {
+	alert, ok := m.alerts[tokenHash]
+	if !ok || alert.UsedAt != "" {
+		return false, nil
+	}
+	alert.UsedAt = usedAt
+	return true, nil
+}
+
+func (m *memoryRepo) RequirePasswordReset(userId string) error {
+	m.resetRequired[userId] = true
+	return nil
+}
+
+// memoryTokens knows which sessions are from new devices and remembers the disowned ones.
+type memoryTokens struct {
+	tokenservice.TokenSrv
+	newDevices map[string]bool
+	disowned   []string
+}
+
+func (m *memoryTokens) IsNewDevice(userId, sessionId string) (bool, error) {
+	return m.newDevices[sessionId], nil
+}
+
+func (m *memoryTokens) DisownSession(userId, sessionId string) error {
+	m.disowned = append(m.disowned, userId+" "+sessionId)
+	return nil
+}
+
+type memoryEmitter struct {
+	payloads []eventEntity.Payload
+}
+
+func (m *memoryEmitter) Push(payload eventEntity.Payload, severity string) error {
+	m.payloads = append(m.payloads, payload)
+	return nil
+}
+
+var tokenInLink = regexp.MustCompile(`\?token=(\S+)`)
+
+func newTestSrv() (*loginAlertSrv, *memoryRepo, *memoryTokens, *memoryEmitter, *time.Time) {
+	now := time.Date(2023, 3, 7, 12, 0, 0, 0, time.UTC)
+	repo, tokens, emitter := newMemoryRepo(), &memoryTokens{newDevices: map[string]bool{"new": true}}, &memoryEmitter{}
+	srv := &loginAlertSrv{repo: repo, tokenSrv: tokens, validator: validationService.NewValidationStruct(), emitter: emitter,
+		appBaseUrl: "https://app.test", now: func() time.Time { return now }}
+	return srv, repo, tokens, emitter, &now
+}
+
+func TestNotify(t *testing.T) {
+	srv, repo, _, emitter, _ := newTestSrv()
+
+	srv.Notify("u1", "sam@example.com", "Sam", &tokenEntity.SessionInfo{SessionId: "known"})
+	srv.Notify("u1", "sam@example.com", "Sam", nil)
+	if len(emitter.payloads) != 0 {
+		t.Fatalf("want no alert for a known device, got %+v", emitter.payloads)
+	}
+
+	srv.Notify("u1", "sam@example.com", "Sam", &tokenEntity.SessionInfo{SessionId: "new", DeviceName: "Pixel 7", IPAddress: "203.0.113.9"})
+	if len(emitter.payloads) != 1 {
+		t.Fatalf("want one alert for a new device, got %d", len(emitter.payloads))
+	}
+	alert := emitter.payloads[0]
+	if alert.SubAction != "login_alert" || alert.Data["email_address"] != "sam@example.com" {
+		t.Errorf("unexpected alert %+v", alert)
+	}
+	body := alert.Data["email_body"]
+	if !strings.Contains(body, "Pixel 7") || !strings.Contains(body, "203.0.113.9") || !tokenInLink.MatchString(body) {
+		t.Errorf("alert should name the device, its address and have a link, got %q", body)
+	}
+
+	repo.alertsOff["u1"] = true
+	srv.Notify("u1", "sam@example.com", "Sam", &tokenEntity.SessionInfo{SessionId: "new"})
+	if len(emitter.payloads) != 1 {
+		t.Errorf("want no alert with login alerts off, got %d", len(emitter.payloads))
+	}
+}
+
+func TestDisown(t *testing.T) {
+	srv, repo, tokens, emitter, now := newTestSrv()
+
+	srv.Notify("u1", "sam@example.com", "Sam", &tokenEntity.SessionInfo{SessionId: "new"})
+	token := tokenInLink.FindStringSubmatch(emitter.payloads[0].Data["email_body"])[1]
+
+	if errRes := srv.Disown(&userEntity.DisownLoginReq{Token: "made-up"}); errRes == nil || errRes.Description != "BadInput Request" {
+		t.Errorf("Disown() with an unknown token = %v, want a bad input", errRes)
+	}
+	if errRes := srv.Disown(&userEntity.DisownLoginReq{Token: token}); errRes != nil {
+		t.Fatalf("Disown() = %v", errRes.Error)
+	}
+	if len(tokens.disowned) != 1 || tokens.disowned[0] != "u1 new" {
+		t.Errorf("want the session of the alert disowned, got %v", tokens.disowned)
+	}
+	if !repo.resetRequired["u1"] {
+		t.Error("want a password reset required")
+	}
+	if errRes := srv.Disown(&userEntity.DisownLoginReq{Token: token}); errRes == nil {
+		t.Error("a link should only work once")
+	}
+
+	srv.Notify("u1", "sam@example.com", "Sam", &tokenEntity.SessionInfo{SessionId: "new"})
+	token = tokenInLink.FindStringSubmatch(emitter.payloads[1].Data["email_body"])[1]
+	*now = now.Add(disownLinkLifetime + time.Second)
+	if errRes := srv.Disown(&userEntity.DisownLoginReq{Token: token}); errRes == nil || errRes.Description != "BadInput Request" {
+		t.Errorf("Disown() with an expired link = %v, want a bad input", errRes)
+	}
+}
@@ -0,0 +1,241 @@
+package loginGuardService
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"test-va/internals/Repository/loginAttemptRepo"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/eventEntity"
+	"test-va/internals/entity/loginAttemptEntity"
+	"test-va/internals/msg-queue/Emitter"
+	"test-va/internals/service/validationService"
+)
+
+const (
+	ErrTooManyRequests = "Too Many Requests"
+	ErrLocked          = "Locked"
+	ErrNotFound        = "Not Found"
+)
+
+// Account types, as the keys of their failures start with
+const (
+	AccountUser = "user"
+	AccountVA   = "va"
+)
+
+const (
+	// failures are forgotten a day after the last one
+	failureWindow = time.Hour * 24
+	// an account can be tried this often before each try has to wait, twice as long every time
+	freeAccountFailures = 3
+	// an IP address gets more tries, a few people can log in from behind one
+	freeIPFailures = 10
+	firstBackoff   = time.Second
+	maxBackoff     = time.Minute * 15
+	// an account is locked after this many failures in a row, and again after as many more,
+	// until a master unlocks it or the lock runs out
+	lockoutFailures = 10
+	lockoutDuration = time.Minute * 30
+)
+
+type LoginGuardSrv interface {
+	// Check fails while the account or the IP address has to wait before the next try, or the account is locked.
+	// It is called before the password is compared, so refused tries cost no hashing.
+	Check(accountType, email, ipAddress string) *ResponseEntity.ServiceError
+	// Failed records a wrong password. name addresses the lockout email, it is empty when no account has the email.
+	Failed(accountType, email, ipAddress, name string)
+	// Succeeded forgets the failures of the account.
+	Succeeded(accountType, email string)
+	// Unlock lifts the lockout of an account.
+	Unlock(req *loginAttemptEntity.UnlockReq) *ResponseEntity.ServiceError
+	// PurgeStale forgets failures that do not count anymore.
+	PurgeStale()
+}
+
+type loginGuardSrv struct {
+	repo      loginAttemptRepo.LoginAttemptRepository
+	validator validationService.ValidationSrv
+	emitter   Emitter.Emitter
+	now       func() time.Time
+}
+
+func NewLoginGuardSrv(repo loginAttemptRepo.LoginAttemptRepository, validator validationService.ValidationSrv, emitter Emitter.Emitter) LoginGuardSrv {
+	return &loginGuardSrv{repo: repo, validator: validator, emitter: emitter, now: time.Now}
+}
+
+func (l *loginGuardSrv) Check(accountType, email, ipAddress string) *ResponseEntity.ServiceError {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	now := l.now().UTC().Format(time.RFC3339)
+	for _, key := range []string{accountKey(accountType, email), ipKey(ipAddress)} {
+		attempts, err := l.repo.Get(ctx, key)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			log.Println(err)
+			return ResponseEntity.NewInternalServiceError(err)
+		}
+		if attempts.LockedUntil > now {
+			return ResponseEntity.NewCustomServiceError(ErrLocked,
+				fmt.Sprintf("the account is locked after too many failed logins until %s, reset the password or ask support to unlock it", attempts.LockedUntil))
+		}
+		if attempts.BlockedUntil > now {
+			return ResponseEntity.NewCustomServiceError(ErrTooManyRequests,
+				fmt.Sprintf("too many failed logins, try again after %s", attempts.BlockedUntil))
+		}
+	}
+	return nil
+}
+
+func (l *loginGuardSrv) Failed(accountType, email, ipAddress, name string) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	now := l.now().UTC()
+	failures, err := l.record(ctx, accountKey(accountType, email), now, freeAccountFailures)
+	if err != nil {
+		log.Println(err)
+	}
+	if failures > 0 && failures%lockoutFailures == 0 {
+		lockedUntil := now.Add(lockoutDuration)
+		err = l.repo.Block(ctx, accountKey(accountType, email), "", lockedUntil.Format(time.RFC3339))
+		if err != nil {
+			log.Println(err)
+		}
+		// only accounts that exist are told, and only when the lock starts
+		if name != "" {
+			l.notifyLocked(email, name, lockedUntil)
+		}
+	}
+
+	if ipAddress == "" {
+		return
+	}
+	_, err = l.record(ctx, ipKey(ipAddress), now, freeIPFailures)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (l *loginGuardSrv) Succeeded(accountType, email string) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	// the failures of the IP address stay, or logging into an own account would reset them
+	err := l.repo.Delete(ctx, accountKey(accountType, email))
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// Unlock Account godoc
+// @Summary	Unlock an account locked after failed logins
+// @Description	Lifts the lockout and forgets the failed logins of a user or VA account. Only masters can unlock accounts.
+// @Tags	VA - Master
+// @Accept	json
+// @Produce	json
+// @Param	request	body	loginAttemptEntity.UnlockReq	true	"Account to unlock"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/va/unlock [post]
+func (l *loginGuardSrv) Unlock(req *loginAttemptEntity.UnlockReq) *ResponseEntity.ServiceError {
+	err := l.validator.Validate(req)
+	if err != nil {
+		return ResponseEntity.NewValidatingError(err)
+	}
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	key := accountKey(req.AccountType, req.Email)
+	_, err = l.repo.Get(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ResponseEntity.NewCustomServiceError(ErrNotFound, "the account has no failed logins")
+	}
+	if err != nil {
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	err = l.repo.Delete(ctx, key)
+	if err != nil {
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	return nil
+}
+
+func (l *loginGuardSrv) PurgeStale() {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	err := l.repo.DeleteStale(ctx, l.now().UTC().Add(-failureWindow).Format(time.RFC3339))
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// record counts a failure of key and makes the next try wait once more than free failures were made.
+func (l *loginGuardSrv) record(ctx context.Context, key string, now time.Time, free int) (int, error) {
+	failures, err := l.repo.RecordFailure(ctx, key, now.Format(time.RFC3339), now.Add(-failureWindow).Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	if failures <= free {
+		return failures, nil
+	}
+	blockedUntil := now.Add(backoff(failures - free))
+	return failures, l.repo.Block(ctx, key, blockedUntil.Format(time.RFC3339), "")
+}
+
+func (l *loginGuardSrv) notifyLocked(email, name string, lockedUntil time.Time) {
+	payload := eventEntity.Payload{
+		Action:    "email",
+		SubAction: "account_locked",
+		Data: map[string]string{
+			"email_address": email,
+			"email_subject": "Subject: Your getticked account was locked\n",
+			"email_body":    createLockedBody(name, lockedUntil),
+		},
+	}
+	err := l.emitter.Push(payload, "info")
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// backoff is how long the nth try past the free ones has to wait.
+func backoff(n int) time.Duration {
+	wait := firstBackoff
+	for i := 1; i < n && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		return maxBackoff
+	}
+	return wait
+}
+
+func accountKey(accountType, email string) string {
+	return accountType + ":" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ipAddress string) string {
+	return "ip:" + ipAddress
+}
+
+func createLockedBody(name string, lockedUntil time.Time) string {
+	subject := fmt.Sprintf("Hi %v, \n\n", name)
+	mainBody := fmt.Sprintf("Someone entered a wrong password for your getticked account %v times in a row, so we locked it until %v UTC.\n\nIf this was you, you can log in again after that, or reset your password now. If it was not, reset your password and turn on two-factor authentication.",
+		lockoutFailures, lockedUntil.Format("2 Jan 2006 15:04"))
+	return subject + mainBody
+}
@@ -0,0 +1,183 @@
+package loginGuardService
+
+import (
+	"test-va/internals/Repository/loginAttemptRepo/memoryRepo"
+	"test-va/internals/entity/eventEntity"
+	"test-va/internals/entity/loginAttemptEntity"
+	"test-va/internals/service/validationService"
+	"testing"
+	"time"
+)
+
+type memoryEmitter struct {
+	payloads []eventEntity.Payload
+}
+
+func (m *memoryEmitter) Push(payload eventEntity.Payload, severity string) error {
+	m.payloads = append(m.payloads, payload)
+	return nil
+}
+
+// newTestSrv returns a guard whose clock only moves when the test moves it.
+func newTestSrv() (*loginGuardSrv, *memoryEmitter, *time.Time) {
+	now := time.Date(2023, 3, 7, 12, 0, 0, 0, time.UTC)
+	emitter := &memoryEmitter{}
+	srv := &loginGuardSrv{repo: memoryRepo.NewLoginAttemptMemoryRepo(), validator: validationService.NewValidationStruct(),
+		emitter: emitter, now: func() time.Time { return now }}
+	return srv, emitter, &now
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{1, time.Second},
+		{2, time.Second * 2},
+		{5, time.Second * 16},
+		{10, time.Second * 512},
+		{11, maxBackoff},
+		{100, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.n); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestAccountBackoff(t *testing.T) {
+	srv, _, now := newTestSrv()
+
+	for i := 0; i < freeAccountFailures; i++ {
+		if errRes := srv.Check(AccountUser, "Sam@example.com", ""); errRes != nil {
+			t.Fatalf("try %d refused: %v", i+1, errRes.Error)
+		}
+		srv.Failed(AccountUser, "sam@example.com", "", "Sam")
+	}
+	if errRes := srv.Check(AccountUser, "sam@example.com", ""); errRes != nil {
+		t.Fatalf("free tries should not make the next one wait, got %v", errRes.Error)
+	}
+
+	srv.Failed(AccountUser, "sam@example.com", "", "Sam")
+	errRes := srv.Check(AccountUser, "SAM@example.com", "")
+	if errRes == nil || errRes.Description != ErrTooManyRequests {
+		t.Fatalf("want %s after a failure past the free ones, got %v", ErrTooManyRequests, errRes)
+	}
+	if errRes := srv.Check(AccountVA, "sam@example.com", ""); errRes != nil {
+		t.Errorf("a VA with the same email should not wait, got %v", errRes.Error)
+	}
+
+	*now = now.Add(time.Second)
+	if errRes := srv.Check(AccountUser, "sam@example.com", ""); errRes != nil {
+		t.Fatalf("want the try let through once the backoff ran out, got %v", errRes.Error)
+	}
+	srv.Failed(AccountUser, "sam@example.com", "", "Sam")
+	*now = now.Add(time.Second)
+	if errRes := srv.Check(AccountUser, "sam@example.com", ""); errRes == nil {
+		t.Errorf("want the second backoff to last two seconds")
+	}
+
+	srv.Succeeded(AccountUser, "sam@example.com")
+	if errRes := srv.Check(AccountUser, "sam@example.com", ""); errRes != nil {
+		t.Errorf("want the failures forgotten after a login, got %v", errRes.Error)
+	}
+}
+
+func TestLockout(t *testing.T) {
+	srv, emitter, now := newTestSrv()
+
+	for i := 0; i < lockoutFailures; i++ {
+		srv.Failed(AccountVA, "va@example.com", "", "Alex")
+		*now = now.Add(maxBackoff)
+	}
+	errRes := srv.Check(AccountVA, "va@example.com", "")
+	if errRes == nil || errRes.Description != ErrLocked {
+		t.Fatalf("want %s after %d failures, got %v", ErrLocked, lockoutFailures, errRes)
+	}
+	if len(emitter.payloads) != 1 || emitter.payloads[0].SubAction != "account_locked" ||
+		emitter.payloads[0].Data["email_address"] != "va@example.com" {
+		t.Errorf("want one lockout email, got %+v", emitter.payloads)
+	}
+
+	*now = now.Add(lockoutDuration)
+	if errRes := srv.Check(AccountVA, "va@example.com", ""); errRes != nil {
+		t.Errorf("want the lock over after %s, got %v", lockoutDuration, errRes.Error)
+	}
+
+	// the failures still count, the next one waits again
+	srv.Failed(AccountVA, "va@example.com", "", "Alex")
+	if errRes := srv.Check(AccountVA, "va@example.com", ""); errRes == nil || errRes.Description != ErrTooManyRequests {
+		t.Errorf("want %s after the lock, got %v", ErrTooManyRequests, errRes)
+	}
+}
+
+func TestUnknownAccountIsNotEmailed(t *testing.T) {
+	srv, emitter, now := newTestSrv()
+
+	for i := 0; i < lockoutFailures; i++ {
+		srv.Failed(AccountUser, "nobody@example.com", "", "")
+		*now = now.Add(maxBackoff)
+	}
+	if errRes := srv.Check(AccountUser, "nobody@example.com", ""); errRes == nil || errRes.Description != ErrLocked {
+		t.Errorf("unknown emails should lock like known ones, got %v", errRes)
+	}
+	if len(emitter.payloads) != 0 {
+		t.Errorf("want no email for an unknown account, got %+v", emitter.payloads)
+	}
+}
+
+func TestIPBackoff(t *testing.T) {
+	srv, _, now := newTestSrv()
+
+	// a different account every time, so only the IP address adds up
+	for i := 0; i < freeIPFailures; i++ {
+		srv.Failed(AccountUser, string(rune('a'+i))+"@example.com", "10.0.0.1", "")
+	}
+	if errRes := srv.Check(AccountUser, "new@example.com", "10.0.0.1"); errRes != nil {
+		t.Fatalf("free tries should not make the next one wait, got %v", errRes.Error)
+	}
+	srv.Failed(AccountUser, "z@example.com", "10.0.0.1", "")
+	if errRes := srv.Check(AccountUser, "new@example.com", "10.0.0.1"); errRes == nil || errRes.Description != ErrTooManyRequests {
+		t.Errorf("want %s from the IP address, got %v", ErrTooManyRequests, errRes)
+	}
+	if errRes := srv.Check(AccountUser, "new@example.com", "10.0.0.2"); errRes != nil {
+		t.Errorf("another IP address should not wait, got %v", errRes.Error)
+	}
+
+	srv.Succeeded(AccountUser, "new@example.com")
+	if errRes := srv.Check(AccountUser, "new@example.com", "10.0.0.1"); errRes == nil {
+		t.Errorf("a login should not forget the failures of the IP address")
+	}
+
+	*now = now.Add(failureWindow + maxBackoff)
+	srv.PurgeStale()
+	if errRes := srv.Check(AccountUser, "new@example.com", "10.0.0.1"); errRes != nil {
+		t.Errorf("want stale failures purged, got %v", errRes.Error)
+	}
+}
+
+func TestUnlock(t *testing.T) {
+	srv, _, _ := newTestSrv()
+
+	req := &loginAttemptEntity.UnlockReq{AccountType: AccountVA, Email: "va@example.com"}
+	if errRes := srv.Unlock(req); errRes == nil || errRes.Description != ErrNotFound {
+		t.Errorf("want %s without failures, got %v", ErrNotFound, errRes)
+	}
+	if errRes := srv.Unlock(&loginAttemptEntity.UnlockReq{AccountType: "admin", Email: "va@example.com"}); errRes == nil || errRes.Description != "BadInput Request" {
+		t.Errorf("want the account type validated, got %v", errRes)
+	}
+
+	for i := 0; i < lockoutFailures; i++ {
+		srv.Failed(AccountVA, "va@example.com", "", "Alex")
+	}
+	if errRes := srv.Check(AccountVA, "va@example.com", ""); errRes == nil || errRes.Description != ErrLocked {
+		t.Fatalf("want the account locked, got %v", errRes)
+	}
+	if errRes := srv.Unlock(req); errRes != nil {
+		t.Fatalf("Unlock() = %v", errRes.Error)
+	}
+	if errRes := srv.Check(AccountVA, "va@example.com", ""); errRes != nil {
+		t.Errorf("want the account unlocked, got %v", errRes.Error)
+	}
+}
@@ -0,0 +1,528 @@
+package oauthService
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"test-va/internals/Repository/oauthRepo"
+	"test-va/internals/Repository/userRepo"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/oauthEntity"
+	"test-va/internals/entity/tokenEntity"
+	tokenservice "test-va/internals/service/tokenService"
+	"test-va/internals/service/validationService"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// an authorization code has to be traded for tokens this soon
+	codeLifetime = time.Minute * 10
+	// the only PKCE method accepted, plain challenges would leak the verifier with the code
+	challengeMethod = "S256"
+)
+
+// service error descriptions the handler maps to status codes, the OAuth ones are in oauthEntity
+const (
+	ErrNotFound = "Not Found"
+)
+
+// Scopes an app can ask for, the same ones personal access tokens are limited with
+var Scopes = []string{tokenEntity.ScopeTasksRead, tokenEntity.ScopeTasksWrite, tokenEntity.ScopeProjects,
+	tokenEntity.ScopeNotifications}
+
+type OAuthSrv interface {
+	// RegisterClient registers an app of the user's. The secret of a confidential client is only returned this once.
+	RegisterClient(ownerId string, req *oauthEntity.CreateClientReq) (*oauthEntity.CreateClientRes, *ResponseEntity.ServiceError)
+	GetClients(ownerId string) ([]*oauthEntity.Client, *ResponseEntity.ServiceError)
+	// DeleteClient deletes an app and signs it out of the accounts of all its users.
+	DeleteClient(ownerId, clientId string) *ResponseEntity.ServiceError
+	// GetConsent checks an authorization request and describes what the app asks for, for the user to approve.
+	GetConsent(userId string, req *oauthEntity.AuthorizeReq) (*oauthEntity.ConsentRes, *ResponseEntity.ServiceError)
+	// Authorize records the user's answer and returns where to send them back to the app,
+	// with an authorization code when they approved.
+	Authorize(userId string, req *oauthEntity.ApproveReq) (*oauthEntity.ApproveRes, *ResponseEntity.ServiceError)
+	// Token trades an authorization code or a refresh token for tokens.
+	Token(req *oauthEntity.TokenReq) (*oauthEntity.TokenRes, *ResponseEntity.ServiceError)
+	// Revoke revokes the session of a token issued to the client, unknown tokens are ignored.
+	Revoke(req *oauthEntity.RevokeReq) *ResponseEntity.ServiceError
+	// PurgeCodes deletes expired authorization codes.
+	PurgeCodes()
+}
+
+type oauthSrv struct {
+	repo          oauthRepo.OAuthRepository
+	userRepo      userRepo.UserRepository
+	tokenSrv      tokenservice.TokenSrv
+	validationSrv validationService.ValidationSrv
+	now           func() time.Time
+}
+
+func NewOAuthSrv(repo oauthRepo.OAuthRepository, userRepo userRepo.UserRepository, tokenSrv tokenservice.TokenSrv,
+	validationSrv validationService.ValidationSrv) OAuthSrv {
+	return &oauthSrv{repo: repo, userRepo: userRepo, tokenSrv: tokenSrv, validationSrv: validationSrv, now: time.Now}
+}
+
+// Register Client godoc
+// @Summary	Register an OAuth app
+// @Description	Registers an app that can ask users for access to their account. Confidential apps get a secret that cannot be shown again, public apps such as mobile apps and extensions have none and rely on PKCE.
+// @Tags	OAuth
+// @Accept	json
+// @Produce	json
+// @Param	request	body	oauthEntity.CreateClientReq	true	"Name and redirect uris"
+// @Success	201  {object}  oauthEntity.CreateClientRes
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security BearerAuth
+// @Router	/oauth/clients [post]
+func (o *oauthSrv) RegisterClient(ownerId string, req *oauthEntity.CreateClientReq) (*oauthEntity.CreateClientRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	err := o.validationSrv.Validate(req)
+	if err != nil {
+		return nil, ResponseEntity.NewValidatingError(err)
+	}
+	for _, redirectUri := range req.RedirectUris {
+		if err := checkRedirectUri(redirectUri); err != nil {
+			return nil, ResponseEntity.NewValidatingError(err)
+		}
+	}
+
+	client := &oauthEntity.Client{
+		ClientId:     uuid.New().String(),
+		OwnerId:      ownerId,
+		Name:         req.Name,
+		RedirectUris: req.RedirectUris,
+		Confidential: req.Confidential,
+		CreatedAt:    o.now().UTC().Format(time.RFC3339),
+	}
+	var secret string
+	if req.Confidential {
+		secret, err = randomString()
+		if err != nil {
+			return nil, ResponseEntity.NewInternalServiceError(err)
+		}
+		client.SecretHash = hash(secret)
+	}
+	err = o.repo.AddClient(ctx, client)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return &oauthEntity.CreateClientRes{Client: client, ClientSecret: secret}, nil
+}
+
+// Get Clients godoc
+// @Summary	List your OAuth apps
+// @Tags	OAuth
+// @Produce	json
+// @Success	200  {object}  []oauthEntity.Client
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security BearerAuth
+// @Router	/oauth/clients [get]
+func (o *oauthSrv) GetClients(ownerId string) ([]*oauthEntity.Client, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	clients, err := o.repo.GetClients(ctx, ownerId)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return clients, nil
+}
+
+// Delete Client godoc
+// @Summary	Delete an OAuth app
+// @Description	Deletes the app and revokes every token issued to it
+// @Tags	OAuth
+// @Produce	json
+// @Param	clientId	path	string	true	"Client Id"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security BearerAuth
+// @Router	/oauth/clients/{clientId} [delete]
+func (o *oauthSrv) DeleteClient(ownerId, clientId string) *ResponseEntity.ServiceError {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	deleted, err := o.repo.DeleteClient(ctx, ownerId, clientId)
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	if !deleted {
+		return ResponseEntity.NewCustomServiceError(ErrNotFound, errors.New("app not found"))
+	}
+	err = o.tokenSrv.RevokeClient(clientId)
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	return nil
+}
+
+// Get Consent godoc
+// @Summary	Check an authorization request
+// @Description	The web app calls this with the query an app sent the user with, and shows the app's name and the scopes it asks for. Errors here must be shown to the user, not sent back to the redirect uri.
+// @Tags	OAuth
+// @Produce	json
+// @Param	response_type	query	string	true	"code"
+// @Param	client_id	query	string	true	"Client Id"
+// @Param	redirect_uri	query	string	true	"One of the app's redirect uris"
+// @Param	scope	query	string	true	"Space separated scopes"
+// @Param	state	query	string	false	"Returned to the app as is"
+// @Param	code_challenge	query	string	true	"base64url of the sha256 of the code verifier"
+// @Param	code_challenge_method	query	string	true	"S256"
+// @Success	200  {object}  oauthEntity.ConsentRes
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security BearerAuth
+// @Router	/oauth/authorize [get]
+func (o *oauthSrv) GetConsent(userId string, req *oauthEntity.AuthorizeReq) (*oauthEntity.ConsentRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	client, scopes, svcErr := o.checkAuthorize(ctx, req)
+	if svcErr != nil {
+		return nil, svcErr
+	}
+	return &oauthEntity.ConsentRes{
+		ClientId:    client.ClientId,
+		ClientName:  client.Name,
+		RedirectUri: req.RedirectUri,
+		Scopes:      scopes,
+	}, nil
+}
+
+// Authorize godoc
+// @Summary	Approve or deny an app
+// @Description	Records the user's answer to an authorization request. Send the user to redirect_to, it carries an authorization code that works once for 10 minutes, or error=access_denied.
+// @Tags	OAuth
+// @Accept	json
+// @Produce	json
+// @Param	request	body	oauthEntity.ApproveReq	true	"The authorization request and the answer"
+// @Success	200  {object}  oauthEntity.ApproveRes
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security BearerAuth
+// @Router	/oauth/authorize [post]
+func (o *oauthSrv) Authorize(userId string, req *oauthEntity.ApproveReq) (*oauthEntity.ApproveRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	_, scopes, svcErr := o.checkAuthorize(ctx, &req.AuthorizeReq)
+	if svcErr != nil {
+		return nil, svcErr
+	}
+
+	query := url.Values{}
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	if !req.Approve {
+		query.Set("error", oauthEntity.ErrAccessDenied)
+		return &oauthEntity.ApproveRes{RedirectTo: withQuery(req.RedirectUri, query)}, nil
+	}
+
+	code, err := randomString()
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	now := o.now().UTC()
+	err = o.repo.AddCode(ctx, &oauthEntity.AuthCode{
+		CodeHash:      hash(code),
+		ClientId:      req.ClientId,
+		UserId:        userId,
+		RedirectUri:   req.RedirectUri,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     now.Add(codeLifetime).Format(time.RFC3339),
+		CreatedAt:     now.Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	query.Set("code", code)
+	return &oauthEntity.ApproveRes{RedirectTo: withQuery(req.RedirectUri, query)}, nil
+}
+
+// checkAuthorize checks an authorization request came from a registered client with one of its
+// redirect uris, and returns the scopes asked for.
+func (o *oauthSrv) checkAuthorize(ctx context.Context, req *oauthEntity.AuthorizeReq) (*oauthEntity.Client, []string, *ResponseEntity.ServiceError) {
+	err := o.validationSrv.Validate(req)
+	if err != nil {
+		return nil, nil, ResponseEntity.NewCustomServiceError(oauthEntity.ErrInvalidRequest, err)
+	}
+	client, err := o.repo.GetClient(ctx, req.ClientId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ResponseEntity.NewCustomServiceError(oauthEntity.ErrInvalidClient, errors.New("unknown client_id"))
+	}
+	if err != nil {
+		return nil, nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if !contains(client.RedirectUris, req.RedirectUri) {
+		return nil, nil, ResponseEntity.NewCustomServiceError(oauthEntity.ErrInvalidRequest,
+			errors.New("redirect_uri is not registered for this client"))
+	}
+	if req.ResponseType != "code" {
+		return nil, nil, ResponseEntity.NewCustomServiceError(oauthEntity.ErrInvalidRequest,
+			errors.New("response_type must be code"))
+	}
+	if req.CodeChallengeMethod != challengeMethod {
+		return nil, nil, ResponseEntity.NewCustomServiceError(oauthEntity.ErrInvalidRequest,
+			errors.New("code_challenge_method must be S256"))
+	}
+
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 {
+		return nil, nil, ResponseEntity.NewCustomServiceError(oauthEntity.ErrInvalidScope, errors.New("scope is required"))
+	}
+	for _, scope := range scopes {
+		if !contains(Scopes, scope) {
+			return nil, nil, ResponseEntity.NewCustomServiceError(oauthEntity.ErrInvalidScope,
+				errors.New("unknown scope "+scope))
+		}
+	}
+	return client, uniqueScopes(scopes), nil
+}
+
+// Token godoc
+// @Summary	Get tokens for an app
+// @Description	Trades an authorization code, with its PKCE code verifier, or a refresh token for an access token and a new refresh token. Confidential clients authenticate with their secret, in the form or with basic auth. Errors are shaped as in RFC 6749.
+// @Tags	OAuth
+// @Accept	x-www-form-urlencoded
+// @Produce	json
+// @Param	grant_type	formData	string	true	"authorization_code or refresh_token"
+// @Param	code	formData	string	false	"The authorization code"
+// @Param	redirect_uri	formData	string	false	"The redirect uri the code was sent to"
+// @Param	code_verifier	formData	string	false	"The PKCE code verifier"
+// @Param	refresh_token	formData	string	false	"The refresh token"
+// @Param	client_id	formData	string	true	"Client Id"
+// @Param	client_secret	formData	string	false	"Secret of a confidential client"
+// @Success	200  {object}  oauthEntity.TokenRes
+// @Failure	400  {object}  oauthEntity.ErrorRes
+// @Failure	401  {object}  oauthEntity.ErrorRes
+// @Router	/oauth/token [post]
+func (o *oauthSrv) Token(req *oauthEntity.TokenReq) (*oauthEntity.TokenRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	client, svcErr := o.authenticate(ctx, req.ClientId, req.ClientSecret)
+	if svcErr != nil {
+		return nil, svcErr
+	}
+	switch req.GrantType {
+	case "authorization_code":
+		return o.exchangeCode(ctx, client, req)
+	case "refresh_token":
+		return o.refresh(client, req)
+	default:
+		return nil, ResponseEntity.NewCustomServiceError(oauthEntity.ErrUnsupportedGrantType,
+			errors.New("grant_type must be authorization_code or refresh_token"))
+	}
+}
+
+func (o *oauthSrv) exchangeCode(ctx context.Context, client *oauthEntity.Client, req *oauthEntity.TokenReq) (*oauthEntity.TokenRes, *ResponseEntity.ServiceError) {
+	invalidGrant := ResponseEntity.NewCustomServiceError(oauthEntity.ErrInvalidGrant,
+		errors.New("authorization code is invalid, expired or was already used"))
+
+	code, err := o.repo.GetCode(ctx, hash(req.Code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, invalidGrant
+	}
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	now := o.now().UTC().Format(time.RFC3339)
+	if code.ClientId != client.ClientId || code.UsedAt != "" || code.ExpiresAt <= now {
+		return nil, invalidGrant
+	}
+	if code.RedirectUri != req.RedirectUri {
+		return nil, ResponseEntity.NewCustomServiceError(oauthEntity.ErrInvalidGrant,
+			errors.New("redirect_uri does not match the authorization request"))
+	}
+	if !verifyChallenge(code.CodeChallenge, req.CodeVerifier) {
+		return nil, ResponseEntity.NewCustomServiceError(oauthEntity.ErrInvalidGrant,
+			errors.New("code_verifier does not match the code_challenge"))
+	}
+
+	// two requests racing with the same code get one set of tokens
+	used, err := o.repo.UseCode(ctx, code.CodeHash, now)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if !used {
+		return nil, invalidGrant
+	}
+
+	user, err := o.userRepo.GetById(code.UserId)
+	if err != nil {
+		return nil, invalidGrant
+	}
+	accessToken, refreshToken, err := o.tokenSrv.CreateToken(user.UserId, "user", user.Email,
+		&tokenEntity.SessionInfo{DeviceName: client.Name, ClientId: client.ClientId, Scopes: code.Scopes})
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return tokenRes(accessToken, refreshToken, code.Scopes), nil
+}
+
+func (o *oauthSrv) refresh(client *oauthEntity.Client, req *oauthEntity.TokenReq) (*oauthEntity.TokenRes, *ResponseEntity.ServiceError) {
+	accessToken, refreshToken, err := o.tokenSrv.RefreshClientToken(client.ClientId, req.RefreshToken)
+	if err != nil {
+		return nil, ResponseEntity.NewCustomServiceError(oauthEntity.ErrInvalidGrant, err)
+	}
+	claims, err := o.tokenSrv.ValidateToken(accessToken)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return tokenRes(accessToken, refreshToken, claims.Scopes), nil
+}
+
+// Revoke godoc
+// @Summary	Revoke an app's token
+// @Description	Revokes an access or refresh token and every token of the same authorization, as in RFC 7009. Unknown tokens are ignored.
+// @Tags	OAuth
+// @Accept	x-www-form-urlencoded
+// @Produce	json
+// @Param	token	formData	string	true	"The access or refresh token"
+// @Param	client_id	formData	string	true	"Client Id"
+// @Param	client_secret	formData	string	false	"Secret of a confidential client"
+// @Success	200
+// @Failure	400  {object}  oauthEntity.ErrorRes
+// @Failure	401  {object}  oauthEntity.ErrorRes
+// @Router	/oauth/revoke [post]
+func (o *oauthSrv) Revoke(req *oauthEntity.RevokeReq) *ResponseEntity.ServiceError {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	client, svcErr := o.authenticate(ctx, req.ClientId, req.ClientSecret)
+	if svcErr != nil {
+		return svcErr
+	}
+	if req.Token == "" {
+		return ResponseEntity.NewCustomServiceError(oauthEntity.ErrInvalidRequest, errors.New("token is required"))
+	}
+	err := o.tokenSrv.RevokeClientToken(client.ClientId, req.Token)
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	return nil
+}
+
+func (o *oauthSrv) PurgeCodes() {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	err := o.repo.DeleteExpiredCodes(ctx, o.now().UTC().Format(time.RFC3339))
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// authenticate finds the client, checking the secret of confidential ones.
+func (o *oauthSrv) authenticate(ctx context.Context, clientId, secret string) (*oauthEntity.Client, *ResponseEntity.ServiceError) {
+	invalidClient := ResponseEntity.NewCustomServiceError(oauthEntity.ErrInvalidClient,
+		errors.New("client authentication failed"))
+	if clientId == "" {
+		return nil, invalidClient
+	}
+	client, err := o.repo.GetClient(ctx, clientId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, invalidClient
+	}
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if client.Confidential && subtle.ConstantTimeCompare([]byte(hash(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, invalidClient
+	}
+	return client, nil
+}
+
+func tokenRes(accessToken, refreshToken string, scopes []string) *oauthEntity.TokenRes {
+	return &oauthEntity.TokenRes{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(tokenservice.AccessLifetime.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(scopes, " "),
+	}
+}
+
+// checkRedirectUri allows https uris, http on localhost for development and custom schemes of native apps.
+// Fragments are not allowed, the code is added to the query.
+func checkRedirectUri(redirectUri string) error {
+	u, err := url.Parse(redirectUri)
+	if err != nil || u.Scheme == "" {
+		return errors.New("redirect uri " + redirectUri + " is not an absolute url")
+	}
+	if u.Fragment != "" {
+		return errors.New("redirect uri " + redirectUri + " cannot have a fragment")
+	}
+	if u.Scheme == "http" && u.Hostname() != "localhost" && u.Hostname() != "127.0.0.1" {
+		return errors.New("redirect uri " + redirectUri + " must use https")
+	}
+	return nil
+}
+
+func withQuery(redirectUri string, query url.Values) string {
+	u, _ := url.Parse(redirectUri)
+	q := u.Query()
+	for key, values := range query {
+		q[key] = values
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// verifyChallenge checks an S256 PKCE code verifier against its challenge.
+func verifyChallenge(challenge, verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueScopes(scopes []string) []string {
+	var unique []string
+	for _, scope := range scopes {
+		if !contains(unique, scope) {
+			unique = append(unique, scope)
+		}
+	}
+	return unique
+}
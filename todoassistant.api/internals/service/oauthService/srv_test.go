@@ -0,0 +1,391 @@
+package oauthService
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"test-va/internals/Repository/userRepo"
+	"test-va/internals/entity/oauthEntity"
+	"test-va/internals/entity/tokenEntity"
+	"test-va/internals/entity/userEntity"
+	tokenservice "test-va/internals/service/tokenService"
+	"test-va/internals/service/validationService"
+	"testing"
+	"time"
+)
+
+type memoryRepo struct {
+	clients map[string]*oauthEntity.Client
+	codes   map[string]*oauthEntity.AuthCode
+}
+
+func (m *memoryRepo) AddClient(ctx context.Context, client *oauthEntity.Client) error {
+	stored := *client
+	m.clients[client.ClientId] = &stored
+	return nil
+}
+
+func (m *memoryRepo) GetClient(ctx context.Context, clientId string) (*oauthEntity.Client, error) {
+	client, ok := m.clients[clientId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	stored := *client
+	return &stored, nil
+}
+
+func (m *memoryRepo) GetClients(ctx context.Context, ownerId string) ([]*oauthEntity.Client, error) {
+	clients := []*oauthEntity.Client{}
+	for _, client := range m.clients {
+		if client.OwnerId == ownerId {
+			clients = append(clients, client)
+		}
+	}
+	return clients, nil
+}
+
+func (m *memoryRepo) DeleteClient(ctx context.Context, ownerId, clientId string) (bool, error) {
+	client, ok := m.clients[clientId]
+	if !ok || client.OwnerId != ownerId {
+		return false, nil
+	}
+	delete(m.clients, clientId)
+	return true, nil
+}
+
+func (m *memoryRepo) AddCode(ctx context.Context, code *oauthEntity.AuthCode) error {
+	stored := *code
+	m.codes[code.CodeHash] = &stored
+	return nil
+}
+
+func (m *memoryRepo) GetCode(ctx context.Context, codeHash string) (*oauthEntity.AuthCode, error) {
+	code, ok := m.codes[codeHash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	stored := *code
+	return &stored, nil
+}
+
+func (m *memoryRepo) UseCode(ctx context.Context, codeHash, usedAt string) (bool, error) {
+	code, ok := m.codes[codeHash]
+	if !ok || code.UsedAt != "" {
+		return false, nil
+	}
+	code.UsedAt = usedAt
+	return true, nil
+}
+
+func (m *memoryRepo) DeleteExpiredCodes(ctx context.Context, before string) error {
+	for hash, code := range m.codes {
+		if code.ExpiresAt < before {
+			delete(m.codes, hash)
+		}
+	}
+	return nil
+}
+
+type fakeUsers struct {
+	userRepo.UserRepository
+}
+
+func (f *fakeUsers) GetById(userId string) (*userEntity.GetByIdRes, error) {
+	return &userEntity.GetByIdRes{UserId: userId, Email: userId + "@example.com"}, nil
+}
+
+// fakeTokens issues "access:" and "refresh:" tokens carrying the session they were issued for
+type fakeTokens struct {
+	tokenservice.TokenSrv
+	sessions       map[string]*tokenEntity.SessionInfo
+	revokedClients []string
+	revokedTokens  []string
+}
+
+func (f *fakeTokens) CreateToken(id, status, email string, session *tokenEntity.SessionInfo) (string, string, error) {
+	sessionId := "s" + string(rune('0'+len(f.sessions)))
+	f.sessions[sessionId] = session
+	return "access:" + sessionId, "refresh:" + sessionId, nil
+}
+
+func (f *fakeTokens) ValidateToken(token string) (*tokenservice.Token, error) {
+	session, ok := f.sessions[strings.TrimPrefix(token, "access:")]
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+	return &tokenservice.Token{ClientId: session.ClientId, Scopes: session.Scopes}, nil
+}
+
+func (f *fakeTokens) RefreshClientToken(clientId, refreshToken string) (string, string, error) {
+	sessionId := strings.TrimPrefix(refreshToken, "refresh:")
+	session, ok := f.sessions[sessionId]
+	if !ok {
+		return "", "", tokenservice.ErrInvalidRefreshToken
+	}
+	if session.ClientId != clientId {
+		return "", "", tokenservice.ErrWrongClient
+	}
+	return "access:" + sessionId, "refresh:" + sessionId, nil
+}
+
+func (f *fakeTokens) RevokeClientToken(clientId, token string) error {
+	f.revokedTokens = append(f.revokedTokens, token)
+	return nil
+}
+
+func (f *fakeTokens) RevokeClient(clientId string) error {
+	f.revokedClients = append(f.revokedClients, clientId)
+	return nil
+}
+
+type fixture struct {
+	srv    *oauthSrv
+	repo   *memoryRepo
+	tokens *fakeTokens
+	now    time.Time
+}
+
+func newFixture() *fixture {
+	f := &fixture{
+		repo:   &memoryRepo{clients: map[string]*oauthEntity.Client{}, codes: map[string]*oauthEntity.AuthCode{}},
+		tokens: &fakeTokens{sessions: map[string]*tokenEntity.SessionInfo{}},
+		now:    time.Date(2023, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	f.srv = NewOAuthSrv(f.repo, &fakeUsers{}, f.tokens, validationService.NewValidationStruct()).(*oauthSrv)
+	f.srv.now = func() time.Time { return f.now }
+	return f
+}
+
+const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk-and-some-more"
+
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (f *fixture) register(t *testing.T, confidential bool) *oauthEntity.CreateClientRes {
+	t.Helper()
+	res, errRes := f.srv.RegisterClient("owner", &oauthEntity.CreateClientReq{
+		Name:         "Calendar Sync",
+		RedirectUris: []string{"https://app.example.com/callback"},
+		Confidential: confidential,
+	})
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	return res
+}
+
+func authorizeReq(clientId string) oauthEntity.AuthorizeReq {
+	return oauthEntity.AuthorizeReq{
+		ResponseType:        "code",
+		ClientId:            clientId,
+		RedirectUri:         "https://app.example.com/callback",
+		Scope:               "tasks:read projects",
+		State:               "xyz",
+		CodeChallenge:       challenge(verifier),
+		CodeChallengeMethod: "S256",
+	}
+}
+
+// approve has user1 approve the client and returns the code it was redirected with
+func (f *fixture) approve(t *testing.T, clientId string) string {
+	t.Helper()
+	res, errRes := f.srv.Authorize("user1", &oauthEntity.ApproveReq{AuthorizeReq: authorizeReq(clientId), Approve: true})
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	redirect, err := url.Parse(res.RedirectTo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if redirect.Query().Get("state") != "xyz" {
+		t.Fatalf("state not returned: %s", res.RedirectTo)
+	}
+	return redirect.Query().Get("code")
+}
+
+func TestAuthorizationCode(t *testing.T) {
+	f := newFixture()
+	client := f.register(t, false)
+	if client.ClientSecret != "" {
+		t.Fatal("public client was given a secret")
+	}
+
+	consent, errRes := f.srv.GetConsent("user1", &oauthEntity.AuthorizeReq{})
+	if errRes == nil {
+		t.Fatal("empty authorization request accepted")
+	}
+	req := authorizeReq(client.ClientId)
+	consent, errRes = f.srv.GetConsent("user1", &req)
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	if consent.ClientName != "Calendar Sync" || strings.Join(consent.Scopes, " ") != "tasks:read projects" {
+		t.Fatalf("consent %+v", consent)
+	}
+
+	code := f.approve(t, client.ClientId)
+	tokenReq := &oauthEntity.TokenReq{
+		GrantType:    "authorization_code",
+		Code:         code,
+		RedirectUri:  "https://app.example.com/callback",
+		CodeVerifier: "wrong-verifier-that-is-long-enough-to-be-checked-at-all",
+		ClientId:     client.ClientId,
+	}
+	_, errRes = f.srv.Token(tokenReq)
+	if errRes == nil || errRes.Description != oauthEntity.ErrInvalidGrant {
+		t.Fatalf("wrong verifier: got %v, want invalid_grant", errRes)
+	}
+
+	tokenReq.CodeVerifier = verifier
+	res, errRes := f.srv.Token(tokenReq)
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	if res.TokenType != "Bearer" || res.Scope != "tasks:read projects" || res.ExpiresIn != 86400 {
+		t.Fatalf("token response %+v", res)
+	}
+	session := f.tokens.sessions[strings.TrimPrefix(res.AccessToken, "access:")]
+	if session.ClientId != client.ClientId || session.DeviceName != "Calendar Sync" || len(session.Scopes) != 2 {
+		t.Fatalf("session %+v", session)
+	}
+
+	_, errRes = f.srv.Token(tokenReq)
+	if errRes == nil || errRes.Description != oauthEntity.ErrInvalidGrant {
+		t.Fatalf("code used twice: got %v, want invalid_grant", errRes)
+	}
+
+	refreshed, errRes := f.srv.Token(&oauthEntity.TokenReq{GrantType: "refresh_token", RefreshToken: res.RefreshToken,
+		ClientId: client.ClientId})
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	if refreshed.Scope != "tasks:read projects" {
+		t.Fatalf("refreshed scope %q", refreshed.Scope)
+	}
+}
+
+func TestAuthorizationCodeExpires(t *testing.T) {
+	f := newFixture()
+	client := f.register(t, false)
+	code := f.approve(t, client.ClientId)
+
+	f.now = f.now.Add(codeLifetime)
+	_, errRes := f.srv.Token(&oauthEntity.TokenReq{GrantType: "authorization_code", Code: code,
+		RedirectUri: "https://app.example.com/callback", CodeVerifier: verifier, ClientId: client.ClientId})
+	if errRes == nil || errRes.Description != oauthEntity.ErrInvalidGrant {
+		t.Fatalf("got %v, want invalid_grant", errRes)
+	}
+
+	f.now = f.now.Add(time.Second)
+	f.srv.PurgeCodes()
+	if len(f.repo.codes) != 0 {
+		t.Fatal("expired code not purged")
+	}
+}
+
+func TestAuthorizeRejects(t *testing.T) {
+	f := newFixture()
+	client := f.register(t, false)
+
+	tests := []struct {
+		name   string
+		modify func(req *oauthEntity.AuthorizeReq)
+		want   string
+	}{
+		{"unknown client", func(req *oauthEntity.AuthorizeReq) { req.ClientId = "other" }, oauthEntity.ErrInvalidClient},
+		{"unregistered redirect", func(req *oauthEntity.AuthorizeReq) { req.RedirectUri = "https://evil.example.com" }, oauthEntity.ErrInvalidRequest},
+		{"plain challenge", func(req *oauthEntity.AuthorizeReq) { req.CodeChallengeMethod = "plain" }, oauthEntity.ErrInvalidRequest},
+		{"implicit grant", func(req *oauthEntity.AuthorizeReq) { req.ResponseType = "token" }, oauthEntity.ErrInvalidRequest},
+		{"unknown scope", func(req *oauthEntity.AuthorizeReq) { req.Scope = "tasks:read admin" }, oauthEntity.ErrInvalidScope},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := authorizeReq(client.ClientId)
+			tt.modify(&req)
+			_, errRes := f.srv.Authorize("user1", &oauthEntity.ApproveReq{AuthorizeReq: req, Approve: true})
+			if errRes == nil || errRes.Description != tt.want {
+				t.Fatalf("got %v, want %s", errRes, tt.want)
+			}
+		})
+	}
+	if len(f.repo.codes) != 0 {
+		t.Fatal("code issued for a rejected request")
+	}
+
+	res, errRes := f.srv.Authorize("user1", &oauthEntity.ApproveReq{AuthorizeReq: authorizeReq(client.ClientId)})
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	if res.RedirectTo != "https://app.example.com/callback?error=access_denied&state=xyz" {
+		t.Fatalf("denied redirect %s", res.RedirectTo)
+	}
+}
+
+func TestConfidentialClient(t *testing.T) {
+	f := newFixture()
+	client := f.register(t, true)
+	if client.ClientSecret == "" {
+		t.Fatal("confidential client has no secret")
+	}
+	code := f.approve(t, client.ClientId)
+
+	req := &oauthEntity.TokenReq{GrantType: "authorization_code", Code: code,
+		RedirectUri: "https://app.example.com/callback", CodeVerifier: verifier, ClientId: client.ClientId}
+	for _, secret := range []string{"", "wrong"} {
+		req.ClientSecret = secret
+		_, errRes := f.srv.Token(req)
+		if errRes == nil || errRes.Description != oauthEntity.ErrInvalidClient {
+			t.Fatalf("secret %q: got %v, want invalid_client", secret, errRes)
+		}
+	}
+
+	req.ClientSecret = client.ClientSecret
+	res, errRes := f.srv.Token(req)
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+
+	// another app cannot use the refresh token
+	other := f.register(t, false)
+	_, errRes = f.srv.Token(&oauthEntity.TokenReq{GrantType: "refresh_token", RefreshToken: res.RefreshToken,
+		ClientId: other.ClientId})
+	if errRes == nil || errRes.Description != oauthEntity.ErrInvalidGrant {
+		t.Fatalf("got %v, want invalid_grant", errRes)
+	}
+
+	errRes = f.srv.Revoke(&oauthEntity.RevokeReq{Token: res.RefreshToken, ClientId: client.ClientId})
+	if errRes == nil || errRes.Description != oauthEntity.ErrInvalidClient {
+		t.Fatalf("revoke without secret: got %v, want invalid_client", errRes)
+	}
+	errRes = f.srv.Revoke(&oauthEntity.RevokeReq{Token: res.RefreshToken, ClientId: client.ClientId,
+		ClientSecret: client.ClientSecret})
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	if len(f.tokens.revokedTokens) != 1 {
+		t.Fatal("token not revoked")
+	}
+}
+
+func TestDeleteClient(t *testing.T) {
+	f := newFixture()
+	client := f.register(t, false)
+
+	errRes := f.srv.DeleteClient("someone", client.ClientId)
+	if errRes == nil || errRes.Description != ErrNotFound {
+		t.Fatalf("got %v, want not found", errRes)
+	}
+	errRes = f.srv.DeleteClient("owner", client.ClientId)
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	if len(f.tokens.revokedClients) != 1 || f.tokens.revokedClients[0] != client.ClientId {
+		t.Fatalf("revoked %v", f.tokens.revokedClients)
+	}
+}
@@ -0,0 +1,442 @@
+package passkeyService
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+
+	"test-va/internals/Repository/passkeyRepo"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/passkeyEntity"
+)
+
+const (
+	ErrUnauthorized = "Unauthorized"
+	ErrNotFound     = "Not Found"
+)
+
+const (
+	challengeSize      = 32
+	ceremonyTimeout    = time.Minute * 5
+	maxPasskeyName     = 255
+	defaultPasskeyName = "Passkey"
+)
+
+type PasskeySrv interface {
+	BeginRegistration(accountType, accountId, email string) (*passkeyEntity.CreationOptions, *ResponseEntity.ServiceError)
+	FinishRegistration(accountType, accountId string, req *passkeyEntity.RegistrationReq) (*passkeyEntity.Passkey, *ResponseEntity.ServiceError)
+	// BeginLogin starts a login into accountId, or into any account of accountType when it is empty.
+	BeginLogin(accountType, accountId string) (*passkeyEntity.RequestOptions, *ResponseEntity.ServiceError)
+	FinishLogin(accountType string, req *passkeyEntity.AssertionReq) (*passkeyEntity.Assertion, *ResponseEntity.ServiceError)
+	GetPasskeys(accountType, accountId string) ([]*passkeyEntity.Passkey, *ResponseEntity.ServiceError)
+	RenamePasskey(accountType, accountId, credentialId, name string) *ResponseEntity.ServiceError
+	DeletePasskey(accountType, accountId, credentialId string) *ResponseEntity.ServiceError
+	PurgeChallenges()
+}
+
+type passkeySrv struct {
+	repo passkeyRepo.PasskeyRepository
+	// rpId is the domain passkeys are scoped to, origins are the pages allowed to use them
+	rpId    string
+	rpName  string
+	origins []string
+}
+
+// Begin Passkey Registration godoc
+// @Summary	Start registering a passkey
+// @Description	Returns the options for navigator.credentials.create, binary values are base64url encoded
+// @Tags	Passkeys
+// @Produce	json
+// @Success	200  {object}  passkeyEntity.CreationOptions
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/user/passkeys/register/begin [post]
+// @Router	/va/passkeys/register/begin [post]
+func (p *passkeySrv) BeginRegistration(accountType, accountId, email string) (*passkeyEntity.CreationOptions, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	passkeys, err := p.repo.GetByAccount(ctx, accountId, accountType)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	challenge, err := p.newChallenge(ctx, accountType, accountId, passkeyEntity.CeremonyRegistration)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	return &passkeyEntity.CreationOptions{
+		Challenge: challenge,
+		RP:        passkeyEntity.RelyingParty{Id: p.rpId, Name: p.rpName},
+		User:      passkeyEntity.UserInfo{Id: encoding.EncodeToString([]byte(accountId)), Name: email, DisplayName: email},
+		PubKeyCredParams: []passkeyEntity.CredentialParameter{
+			{Type: "public-key", Alg: algES256},
+			{Type: "public-key", Alg: algRS256},
+		},
+		Timeout:                ceremonyTimeout.Milliseconds(),
+		ExcludeCredentials:     descriptors(passkeys),
+		AuthenticatorSelection: passkeyEntity.AuthenticatorSelection{ResidentKey: "preferred", UserVerification: "preferred"},
+		Attestation:            "none",
+	}, nil
+}
+
+// Finish Passkey Registration godoc
+// @Summary	Register a passkey
+// @Description	Stores the credential navigator.credentials.create returned for the options from begin
+// @Tags	Passkeys
+// @Accept	json
+// @Produce	json
+// @Param	request	body	passkeyEntity.RegistrationReq	true	"New credential"
+// @Success	200  {object}  passkeyEntity.Passkey
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/user/passkeys/register/finish [post]
+// @Router	/va/passkeys/register/finish [post]
+func (p *passkeySrv) FinishRegistration(accountType, accountId string, req *passkeyEntity.RegistrationReq) (*passkeyEntity.Passkey, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	rawClientData, err1 := decodeBase64(req.Response.ClientDataJSON)
+	rawAttestation, err2 := decodeBase64(req.Response.AttestationObject)
+	if err1 != nil || err2 != nil {
+		return nil, ResponseEntity.NewValidatingError(errMalformed.Error())
+	}
+	if _, errRes := p.checkClientData(ctx, rawClientData, "webauthn.create", passkeyEntity.CeremonyRegistration, accountType, accountId); errRes != nil {
+		return nil, errRes
+	}
+
+	authData, err := parseAttestationObject(rawAttestation)
+	if err != nil {
+		return nil, ResponseEntity.NewValidatingError(err.Error())
+	}
+	if errRes := p.checkAuthenticatorData(authData); errRes != nil {
+		return nil, errRes
+	}
+	credentialId := encoding.EncodeToString(authData.CredentialId)
+	if credentialId != req.Id {
+		return nil, ResponseEntity.NewValidatingError("credential id does not match the authenticator data")
+	}
+	key, err := parseCOSEKey(authData.PublicKey)
+	if err != nil {
+		return nil, ResponseEntity.NewValidatingError(err.Error())
+	}
+	if _, err := key.publicKey(); err != nil {
+		return nil, ResponseEntity.NewValidatingError(err.Error())
+	}
+	rawKey, err := encodeCOSEKey(key)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	name := req.Name
+	if name == "" {
+		name = defaultPasskeyName
+	}
+	if len(name) > maxPasskeyName {
+		name = name[:maxPasskeyName]
+	}
+	passkey := &passkeyEntity.Passkey{
+		CredentialId: credentialId,
+		AccountId:    accountId,
+		AccountType:  accountType,
+		Name:         name,
+		PublicKey:    rawKey,
+		Algorithm:    key.algorithm(),
+		SignCount:    authData.SignCount,
+		CreatedAt:    time.Now().UTC().Format(time.RFC3339),
+	}
+	if existing, err := p.repo.Get(ctx, credentialId); err == nil && existing != nil {
+		return nil, ResponseEntity.NewValidatingError("passkey is already registered")
+	}
+	err = p.repo.Persist(ctx, passkey)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return passkey, nil
+}
+
+func (p *passkeySrv) BeginLogin(accountType, accountId string) (*passkeyEntity.RequestOptions, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	allowed := []passkeyEntity.CredentialDescriptor{}
+	if accountId != "" {
+		passkeys, err := p.repo.GetByAccount(ctx, accountId, accountType)
+		if err != nil {
+			log.Println(err)
+			return nil, ResponseEntity.NewInternalServiceError(err)
+		}
+		allowed = descriptors(passkeys)
+	}
+	challenge, err := p.newChallenge(ctx, accountType, accountId, passkeyEntity.CeremonyLogin)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	return &passkeyEntity.RequestOptions{
+		Challenge:        challenge,
+		RPId:             p.rpId,
+		Timeout:          ceremonyTimeout.Milliseconds(),
+		AllowCredentials: allowed,
+		UserVerification: "preferred",
+	}, nil
+}
+
+func (p *passkeySrv) FinishLogin(accountType string, req *passkeyEntity.AssertionReq) (*passkeyEntity.Assertion, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	rawClientData, err1 := decodeBase64(req.Response.ClientDataJSON)
+	rawAuthData, err2 := decodeBase64(req.Response.AuthenticatorData)
+	signature, err3 := decodeBase64(req.Response.Signature)
+	userHandle, err4 := decodeBase64(req.Response.UserHandle)
+	if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+		return nil, ResponseEntity.NewValidatingError(errMalformed.Error())
+	}
+
+	challenge, errRes := p.checkClientData(ctx, rawClientData, "webauthn.get", passkeyEntity.CeremonyLogin, accountType, "")
+	if errRes != nil {
+		return nil, errRes
+	}
+
+	passkey, err := p.repo.Get(ctx, req.Id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ResponseEntity.NewCustomServiceError(ErrUnauthorized, "passkey is not registered")
+	}
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if passkey.AccountType != accountType || (challenge.AccountId != "" && passkey.AccountId != challenge.AccountId) {
+		return nil, ResponseEntity.NewCustomServiceError(ErrUnauthorized, "passkey is not registered")
+	}
+	if len(userHandle) > 0 && string(userHandle) != passkey.AccountId {
+		return nil, ResponseEntity.NewCustomServiceError(ErrUnauthorized, "passkey belongs to another account")
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, ResponseEntity.NewValidatingError(err.Error())
+	}
+	if errRes := p.checkAuthenticatorData(authData); errRes != nil {
+		return nil, errRes
+	}
+	if err := verifySignature(passkey.PublicKey, rawAuthData, rawClientData, signature); err != nil {
+		return nil, ResponseEntity.NewCustomServiceError(ErrUnauthorized, "passkey signature is invalid")
+	}
+
+	// a counter that did not grow means the key was copied and both copies are in use
+	if (authData.SignCount != 0 || passkey.SignCount != 0) && authData.SignCount <= passkey.SignCount {
+		log.Printf("passkey %s sign count went from %d to %d, it may be cloned", passkey.CredentialId, passkey.SignCount, authData.SignCount)
+		return nil, ResponseEntity.NewCustomServiceError(ErrUnauthorized, "passkey may have been cloned, use another login method")
+	}
+	updated, err := p.repo.UpdateSignCount(ctx, passkey.CredentialId, authData.SignCount, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if !updated {
+		return nil, ResponseEntity.NewCustomServiceError(ErrUnauthorized, "passkey may have been cloned, use another login method")
+	}
+
+	return &passkeyEntity.Assertion{AccountId: passkey.AccountId, UserVerified: authData.userVerified()}, nil
+}
+
+// Get Passkeys godoc
+// @Summary	List the passkeys of the account
+// @Tags	Passkeys
+// @Produce	json
+// @Success	200  {object}  []passkeyEntity.Passkey
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/user/passkeys [get]
+// @Router	/va/passkeys [get]
+func (p *passkeySrv) GetPasskeys(accountType, accountId string) ([]*passkeyEntity.Passkey, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	passkeys, err := p.repo.GetByAccount(ctx, accountId, accountType)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return passkeys, nil
+}
+
+// Rename Passkey godoc
+// @Summary	Rename a passkey
+// @Tags	Passkeys
+// @Accept	json
+// @Produce	json
+// @Param	credentialId	path	string	true	"Credential Id"
+// @Param	request	body	passkeyEntity.RenameReq	true	"New name"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/user/passkeys/{credentialId} [patch]
+// @Router	/va/passkeys/{credentialId} [patch]
+func (p *passkeySrv) RenamePasskey(accountType, accountId, credentialId, name string) *ResponseEntity.ServiceError {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	if len(name) > maxPasskeyName {
+		return ResponseEntity.NewValidatingError("name is too long")
+	}
+	if errRes := p.owned(ctx, accountType, accountId, credentialId); errRes != nil {
+		return errRes
+	}
+	err := p.repo.Rename(ctx, credentialId, name)
+	if err != nil {
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	return nil
+}
+
+// Delete Passkey godoc
+// @Summary	Remove a passkey
+// @Tags	Passkeys
+// @Produce	json
+// @Param	credentialId	path	string	true	"Credential Id"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/user/passkeys/{credentialId} [delete]
+// @Router	/va/passkeys/{credentialId} [delete]
+func (p *passkeySrv) DeletePasskey(accountType, accountId, credentialId string) *ResponseEntity.ServiceError {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	if errRes := p.owned(ctx, accountType, accountId, credentialId); errRes != nil {
+		return errRes
+	}
+	err := p.repo.Delete(ctx, credentialId)
+	if err != nil {
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	return nil
+}
+
+func (p *passkeySrv) PurgeChallenges() {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	err := p.repo.DeleteExpiredChallenges(ctx, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (p *passkeySrv) owned(ctx context.Context, accountType, accountId, credentialId string) *ResponseEntity.ServiceError {
+	passkey, err := p.repo.Get(ctx, credentialId)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && (passkey.AccountId != accountId || passkey.AccountType != accountType)) {
+		return ResponseEntity.NewCustomServiceError(ErrNotFound, "passkey not found")
+	}
+	if err != nil {
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	return nil
+}
+
+// newChallenge stores a random challenge for a ceremony and returns it base64url encoded.
+func (p *passkeySrv) newChallenge(ctx context.Context, accountType, accountId, ceremony string) (string, error) {
+	raw := make([]byte, challengeSize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	err := p.repo.AddChallenge(ctx, &passkeyEntity.Challenge{
+		ChallengeHash: hashChallenge(raw),
+		AccountId:     accountId,
+		AccountType:   accountType,
+		Ceremony:      ceremony,
+		ExpiresAt:     time.Now().UTC().Add(ceremonyTimeout).Format(time.RFC3339),
+	})
+	if err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(raw), nil
+}
+
+// checkClientData checks what the browser signed: the ceremony, that the challenge is ours and unused, and the origin.
+func (p *passkeySrv) checkClientData(ctx context.Context, raw []byte, clientDataType, ceremony, accountType, accountId string) (*passkeyEntity.Challenge, *ResponseEntity.ServiceError) {
+	data, err := parseClientData(raw)
+	if err != nil || data.Type != clientDataType {
+		return nil, ResponseEntity.NewValidatingError(errMalformed.Error())
+	}
+	if !p.allowedOrigin(data.Origin) {
+		return nil, ResponseEntity.NewCustomServiceError(ErrUnauthorized, "passkey was used on another site")
+	}
+
+	rawChallenge, err := decodeBase64(data.Challenge)
+	if err != nil {
+		return nil, ResponseEntity.NewValidatingError(errMalformed.Error())
+	}
+	challenge, err := p.repo.TakeChallenge(ctx, hashChallenge(rawChallenge))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ResponseEntity.NewCustomServiceError(ErrUnauthorized, "the passkey request has expired, start again")
+	}
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if challenge.Ceremony != ceremony || challenge.AccountType != accountType ||
+		(accountId != "" && challenge.AccountId != accountId) ||
+		challenge.ExpiresAt < time.Now().UTC().Format(time.RFC3339) {
+		return nil, ResponseEntity.NewCustomServiceError(ErrUnauthorized, "the passkey request has expired, start again")
+	}
+	return challenge, nil
+}
+
+func (p *passkeySrv) checkAuthenticatorData(data *authenticatorData) *ResponseEntity.ServiceError {
+	rpIdHash := sha256.Sum256([]byte(p.rpId))
+	if !bytes.Equal(data.RPIdHash, rpIdHash[:]) {
+		return ResponseEntity.NewCustomServiceError(ErrUnauthorized, "passkey was made for another site")
+	}
+	if !data.userPresent() {
+		return ResponseEntity.NewValidatingError("the authenticator did not check the user was present")
+	}
+	return nil
+}
+
+func (p *passkeySrv) allowedOrigin(origin string) bool {
+	for _, allowed := range p.origins {
+		if origin == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func descriptors(passkeys []*passkeyEntity.Passkey) []passkeyEntity.CredentialDescriptor {
+	list := make([]passkeyEntity.CredentialDescriptor, 0, len(passkeys))
+	for _, passkey := range passkeys {
+		list = append(list, passkeyEntity.CredentialDescriptor{Type: "public-key", Id: passkey.CredentialId})
+	}
+	return list
+}
+
+func hashChallenge(challenge []byte) string {
+	sum := sha256.Sum256(challenge)
+	return hex.EncodeToString(sum[:])
+}
+
+// NewPasskeySrv scopes passkeys to rpId, a domain, and accepts them from origins such as https://app.example.com.
+func NewPasskeySrv(repo passkeyRepo.PasskeyRepository, rpId, rpName string, origins []string) PasskeySrv {
+	return &passkeySrv{repo: repo, rpId: rpId, rpName: rpName, origins: origins}
+}
@@ -0,0 +1,250 @@
+package passkeyService
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"test-va/internals/Repository/passkeyRepo"
+	"test-va/internals/entity/passkeyEntity"
+	"testing"
+
+	"github.com/ugorji/go/codec"
+)
+
+const (
+	testRPId   = "app.test"
+	testOrigin = "https://app.test"
+)
+
+type memoryRepo struct {
+	passkeyRepo.PasskeyRepository
+	passkeys   map[string]*passkeyEntity.Passkey
+	challenges map[string]*passkeyEntity.Challenge
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{passkeys: map[string]*passkeyEntity.Passkey{}, challenges: map[string]*passkeyEntity.Challenge{}}
+}
+
+func (m *memoryRepo) Persist(ctx context.Context, passkey *passkeyEntity.Passkey) error {
+	m.passkeys[passkey.CredentialId] = passkey
+	return nil
+}
+
+func (m *memoryRepo) Get(ctx context.Context, credentialId string) (*passkeyEntity.Passkey, error) {
+	passkey, ok := m.passkeys[credentialId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	stored := *passkey
+	return &stored, nil
+}
+
+func (m *memoryRepo) GetByAccount(ctx context.Context, accountId, accountType string) ([]*passkeyEntity.Passkey, error) {
+	passkeys := []*passkeyEntity.Passkey{}
+	for _, passkey := range m.passkeys {
+		if passkey.AccountId == accountId && passkey.AccountType == accountType {
+			passkeys = append(passkeys, passkey)
+		}
+	}
+	return passkeys, nil
+}
+
+func (m *memoryRepo) UpdateSignCount(ctx context.Context, credentialId string, signCount uint32, usedAt string) (bool, error) {
+	passkey := m.passkeys[credentialId]
+	if passkey.SignCount >= signCount && signCount != 0 {
+		return false, nil
+	}
+	passkey.SignCount, passkey.LastUsedAt = signCount, usedAt
+	return true, nil
+}
+
+func (m *memoryRepo) Delete(ctx context.Context, credentialId string) error {
+	delete(m.passkeys, credentialId)
+	return nil
+}
+
+func (m *memoryRepo) AddChallenge(ctx context.Context, challenge *passkeyEntity.Challenge) error {
+	m.challenges[challenge.ChallengeHash] = challenge
+	return nil
+}
+
+func (m *memoryRepo) TakeChallenge(ctx context.Context, challengeHash string) (*passkeyEntity.Challenge, error) {
+	challenge, ok := m.challenges[challengeHash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	delete(m.challenges, challengeHash)
+	return challenge, nil
+}
+
+// softAuthenticator is a platform authenticator in software, with one ES256 passkey.
+type softAuthenticator struct {
+	t            *testing.T
+	key          *ecdsa.PrivateKey
+	credentialId []byte
+	userHandle   []byte
+	signCount    uint32
+	origin       string
+	rpId         string
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialId := make([]byte, 16)
+	rand.Read(credentialId)
+	return &softAuthenticator{t: t, key: key, credentialId: credentialId, origin: testOrigin, rpId: testRPId}
+}
+
+func (a *softAuthenticator) clientData(clientDataType, challenge string) []byte {
+	raw, _ := json.Marshal(clientData{Type: clientDataType, Challenge: challenge, Origin: a.origin})
+	return raw
+}
+
+func (a *softAuthenticator) authData(flags byte, attested []byte) []byte {
+	rpIdHash := sha256.Sum256([]byte(a.rpId))
+	data := append([]byte{}, rpIdHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+// create answers navigator.credentials.create.
+func (a *softAuthenticator) create(options *passkeyEntity.CreationOptions) *passkeyEntity.RegistrationReq {
+	a.userHandle, _ = decodeBase64(options.User.Id)
+
+	var publicKey []byte
+	key := map[int]interface{}{1: 2, 3: algES256, -1: 1, -2: a.key.X.FillBytes(make([]byte, 32)), -3: a.key.Y.FillBytes(make([]byte, 32))}
+	if err := codec.NewEncoderBytes(&publicKey, &cborHandle).Encode(key); err != nil {
+		a.t.Fatal(err)
+	}
+	attested := make([]byte, 16)
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialId)))
+	attested = append(append(attested, a.credentialId...), publicKey...)
+
+	var attestation []byte
+	err := codec.NewEncoderBytes(&attestation, &cborHandle).Encode(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authData(flagUserPresent|flagUserVerified|flagAttestedCredData, attested),
+	})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return &passkeyEntity.RegistrationReq{
+		Id:   encoding.EncodeToString(a.credentialId),
+		Name: "Laptop",
+		Response: passkeyEntity.AttestationResponse{
+			ClientDataJSON:    encoding.EncodeToString(a.clientData("webauthn.create", options.Challenge)),
+			AttestationObject: encoding.EncodeToString(attestation),
+		},
+	}
+}
+
+// get answers navigator.credentials.get, counting the signature.
+func (a *softAuthenticator) get(options *passkeyEntity.RequestOptions) *passkeyEntity.AssertionReq {
+	a.signCount++
+	clientDataJSON := a.clientData("webauthn.get", options.Challenge)
+	authData := a.authData(flagUserPresent|flagUserVerified, nil)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return &passkeyEntity.AssertionReq{
+		Id: encoding.EncodeToString(a.credentialId),
+		Response: passkeyEntity.AssertionResponse{
+			ClientDataJSON:    encoding.EncodeToString(clientDataJSON),
+			AuthenticatorData: encoding.EncodeToString(authData),
+			Signature:         encoding.EncodeToString(signature),
+			UserHandle:        encoding.EncodeToString(a.userHandle),
+		},
+	}
+}
+
+func TestPasskeyRegistrationAndLogin(t *testing.T) {
+	repo := newMemoryRepo()
+	srv := NewPasskeySrv(repo, testRPId, "Ticked", []string{testOrigin})
+	authenticator := newSoftAuthenticator(t)
+
+	creation, errRes := srv.BeginRegistration(passkeyEntity.AccountUser, "u1", "sam@example.com")
+	if errRes != nil {
+		t.Fatalf("BeginRegistration() error = %v", errRes)
+	}
+	registration := authenticator.create(creation)
+	passkey, errRes := srv.FinishRegistration(passkeyEntity.AccountUser, "u1", registration)
+	if errRes != nil {
+		t.Fatalf("FinishRegistration() error = %v", errRes)
+	}
+	if passkey.Name != "Laptop" || passkey.Algorithm != algES256 {
+		t.Errorf("FinishRegistration() = %+v", passkey)
+	}
+	if _, errRes := srv.FinishRegistration(passkeyEntity.AccountUser, "u1", registration); errRes == nil {
+		t.Error("a registration challenge should only work once")
+	}
+
+	// discoverable login, the authenticator picks the passkey
+	options, errRes := srv.BeginLogin(passkeyEntity.AccountUser, "")
+	if errRes != nil {
+		t.Fatalf("BeginLogin() error = %v", errRes)
+	}
+	assertion, errRes := srv.FinishLogin(passkeyEntity.AccountUser, authenticator.get(options))
+	if errRes != nil {
+		t.Fatalf("FinishLogin() error = %v", errRes)
+	}
+	if assertion.AccountId != "u1" || !assertion.UserVerified {
+		t.Errorf("FinishLogin() = %+v", assertion)
+	}
+	if repo.passkeys[passkey.CredentialId].SignCount != 1 {
+		t.Errorf("sign count = %d, want 1", repo.passkeys[passkey.CredentialId].SignCount)
+	}
+
+	// a user passkey does not log into a VA account
+	options, _ = srv.BeginLogin(passkeyEntity.AccountVA, "")
+	if _, errRes := srv.FinishLogin(passkeyEntity.AccountVA, authenticator.get(options)); errRes == nil {
+		t.Error("FinishLogin() as a VA with a user passkey should fail")
+	}
+
+	// a copy of the key that signs with an older count is rejected
+	options, _ = srv.BeginLogin(passkeyEntity.AccountUser, "u1")
+	authenticator.signCount = 0
+	if _, errRes := srv.FinishLogin(passkeyEntity.AccountUser, authenticator.get(options)); errRes == nil || errRes.Description != ErrUnauthorized {
+		t.Errorf("FinishLogin() with a lower sign count = %v, want unauthorized", errRes)
+	}
+	authenticator.signCount = 10
+
+	phishing := newSoftAuthenticator(t)
+	*phishing = *authenticator
+	phishing.origin = "https://app.test.evil"
+	options, _ = srv.BeginLogin(passkeyEntity.AccountUser, "u1")
+	if _, errRes := srv.FinishLogin(passkeyEntity.AccountUser, phishing.get(options)); errRes == nil {
+		t.Error("FinishLogin() from another origin should fail")
+	}
+
+	options, _ = srv.BeginLogin(passkeyEntity.AccountUser, "u1")
+	forged := authenticator.get(options)
+	forged.Response.Signature = encoding.EncodeToString([]byte("not a signature"))
+	if _, errRes := srv.FinishLogin(passkeyEntity.AccountUser, forged); errRes == nil {
+		t.Error("FinishLogin() with a bad signature should fail")
+	}
+
+	if errRes := srv.DeletePasskey(passkeyEntity.AccountUser, "u2", passkey.CredentialId); errRes == nil || errRes.Description != ErrNotFound {
+		t.Errorf("DeletePasskey() of another account = %v, want not found", errRes)
+	}
+	if errRes := srv.DeletePasskey(passkeyEntity.AccountUser, "u1", passkey.CredentialId); errRes != nil {
+		t.Fatalf("DeletePasskey() error = %v", errRes)
+	}
+	options, _ = srv.BeginLogin(passkeyEntity.AccountUser, "")
+	if _, errRes := srv.FinishLogin(passkeyEntity.AccountUser, authenticator.get(options)); errRes == nil {
+		t.Error("FinishLogin() with a removed passkey should fail")
+	}
+}
@@ -0,0 +1,205 @@
+package passkeyService
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ugorji/go/codec"
+)
+
+// The parts of WebAuthn Level 2 a relying party needs, https://www.w3.org/TR/webauthn-2/.
+// Attestation is not checked, the options ask for none.
+
+// COSE algorithms that are accepted, ES256 covers nearly all passkeys and RS256 Windows Hello.
+const (
+	algES256 int64 = -7
+	algRS256 int64 = -257
+)
+
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagAttestedCredData = 0x40
+)
+
+var (
+	errMalformed = errors.New("malformed credential")
+	cborHandle   codec.CborHandle
+	encoding     = base64.RawURLEncoding
+)
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type authenticatorData struct {
+	RPIdHash  []byte
+	Flags     byte
+	SignCount uint32
+	// set during registration only
+	CredentialId []byte
+	PublicKey    []byte
+}
+
+func (a *authenticatorData) userPresent() bool  { return a.Flags&flagUserPresent != 0 }
+func (a *authenticatorData) userVerified() bool { return a.Flags&flagUserVerified != 0 }
+
+// decodeBase64 accepts base64url with or without padding, which is what browsers and libraries send.
+func decodeBase64(value string) ([]byte, error) {
+	return encoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+func parseClientData(raw []byte) (*clientData, error) {
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, errMalformed
+	}
+	return &data, nil
+}
+
+func parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	// rpIdHash(32) flags(1) signCount(4)
+	if len(raw) < 37 {
+		return nil, errMalformed
+	}
+	data := &authenticatorData{RPIdHash: raw[:32], Flags: raw[32], SignCount: binary.BigEndian.Uint32(raw[33:37])}
+	if data.Flags&flagAttestedCredData == 0 {
+		return data, nil
+	}
+
+	// aaguid(16) credentialIdLength(2) credentialId publicKey
+	rest := raw[37:]
+	if len(rest) < 18 {
+		return nil, errMalformed
+	}
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if len(rest) < idLength {
+		return nil, errMalformed
+	}
+	data.CredentialId = rest[:idLength]
+	// extensions may follow the key, decoding stops at the end of the key
+	data.PublicKey = rest[idLength:]
+	if len(data.PublicKey) == 0 {
+		return nil, errMalformed
+	}
+	return data, nil
+}
+
+// parseAttestationObject returns the authenticator data of a new credential.
+func parseAttestationObject(raw []byte) (*authenticatorData, error) {
+	var attestation struct {
+		AuthData []byte `codec:"authData"`
+	}
+	if err := codec.NewDecoderBytes(raw, &cborHandle).Decode(&attestation); err != nil {
+		return nil, errMalformed
+	}
+	data, err := parseAuthenticatorData(attestation.AuthData)
+	if err != nil {
+		return nil, err
+	}
+	if data.CredentialId == nil {
+		return nil, errMalformed
+	}
+	return data, nil
+}
+
+// coseKey is a public key in the COSE_Key format, https://www.rfc-editor.org/rfc/rfc8152#section-7.
+type coseKey map[int]interface{}
+
+func parseCOSEKey(raw []byte) (coseKey, error) {
+	var key coseKey
+	if err := codec.NewDecoderBytes(raw, &cborHandle).Decode(&key); err != nil {
+		return nil, errMalformed
+	}
+	return key, nil
+}
+
+// encodeCOSEKey is how keys are stored, without the extensions that can follow them in the authenticator data.
+func encodeCOSEKey(key coseKey) ([]byte, error) {
+	var raw []byte
+	err := codec.NewEncoderBytes(&raw, &cborHandle).Encode(map[int]interface{}(key))
+	return raw, err
+}
+
+func (k coseKey) int(label int) (int64, bool) {
+	switch v := k[label].(type) {
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), true
+	}
+	return 0, false
+}
+
+func (k coseKey) bytes(label int) []byte {
+	b, _ := k[label].([]byte)
+	return b
+}
+
+func (k coseKey) algorithm() int64 {
+	alg, _ := k.int(3)
+	return alg
+}
+
+// publicKey returns the key as an *ecdsa.PublicKey or *rsa.PublicKey.
+func (k coseKey) publicKey() (crypto.PublicKey, error) {
+	kty, _ := k.int(1)
+	switch k.algorithm() {
+	case algES256:
+		crv, _ := k.int(-1)
+		x, y := k.bytes(-2), k.bytes(-3)
+		// EC2 on P-256
+		if kty != 2 || crv != 1 || len(x) != 32 || len(y) != 32 {
+			return nil, errMalformed
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errMalformed
+		}
+		return key, nil
+	case algRS256:
+		n, e := k.bytes(-1), k.bytes(-2)
+		if kty != 3 || len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, errMalformed
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	}
+	return nil, fmt.Errorf("unsupported algorithm %d", k.algorithm())
+}
+
+// verifySignature checks an assertion signature, made over the authenticator data and the hash of the client data.
+func verifySignature(rawKey, authData, clientDataJSON, signature []byte) error {
+	key, err := parseCOSEKey(rawKey)
+	if err != nil {
+		return err
+	}
+	publicKey, err := key.publicKey()
+	if err != nil {
+		return err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	switch publicKey := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(publicKey, digest[:], signature) {
+			return errors.New("invalid signature")
+		}
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature)
+	}
+	return nil
+}
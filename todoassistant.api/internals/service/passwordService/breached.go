@@ -0,0 +1,65 @@
+package passwordService
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"strings"
+)
+
+// BreachedPasswords answers k-anonymity range queries: given the first 5 characters of the upper case
+// hex SHA-1 of a password, it returns the other 35 of every breached password starting with them.
+// Only the prefix leaves the service, so a remote list never learns which password was checked.
+type BreachedPasswords interface {
+	Range(prefix string) ([]string, error)
+}
+
+const prefixLength = 5
+
+//go:embed breached_passwords.txt
+var bundledList []byte
+
+type offlineList struct {
+	ranges map[string][]string
+}
+
+// NewOfflineList returns the breached passwords bundled with the service.
+func NewOfflineList() BreachedPasswords {
+	return newOfflineList(bundledList)
+}
+
+func newOfflineList(list []byte) *offlineList {
+	l := &offlineList{ranges: map[string][]string{}}
+	scanner := bufio.NewScanner(bytes.NewReader(list))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) != sha1.Size*2 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.ToUpper(line)
+		l.ranges[line[:prefixLength]] = append(l.ranges[line[:prefixLength]], line[prefixLength:])
+	}
+	return l
+}
+
+func (l *offlineList) Range(prefix string) ([]string, error) {
+	return l.ranges[strings.ToUpper(prefix)], nil
+}
+
+// breached reports whether the password is on the list, asking it for the range of its hash.
+func breached(list BreachedPasswords, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	suffixes, err := list.Range(hash[:prefixLength])
+	if err != nil {
+		return false, err
+	}
+	for _, suffix := range suffixes {
+		if strings.EqualFold(suffix, hash[prefixLength:]) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,144 @@
+package passwordService
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"test-va/internals/entity/ResponseEntity"
+)
+
+// Rules a password can break, as the rule of its field error
+const (
+	RuleMinLength = "min"
+	RuleMaxLength = "max"
+	RulePattern   = "pattern"
+	RulePersonal  = "personal"
+	RuleBreached  = "breached"
+)
+
+const (
+	DefaultMinLength = 8
+	// bcrypt only hashes the first 72 bytes, anything after them would be ignored
+	maxLength = 72
+)
+
+// DefaultBannedPatterns are refused unless the config names others, they match passwords that are
+// easy to guess without being on a breach list.
+var DefaultBannedPatterns = []string{
+	`^[0-9]+$`,
+	`(?i)ticked`,
+	`(?i)^(password|passw0rd|p@ssw0rd|qwerty|letmein|welcome|iloveyou|admin)[0-9!@#$%^&*.]*$`,
+}
+
+// Policy is what a password has to be.
+type Policy struct {
+	MinLength int
+	// BannedPatterns are regular expressions no password may match
+	BannedPatterns []*regexp.Regexp
+}
+
+// NewPolicy builds a policy, a minLength of 0 and no patterns take the defaults.
+func NewPolicy(minLength int, bannedPatterns []string) (Policy, error) {
+	if minLength <= 0 {
+		minLength = DefaultMinLength
+	}
+	if minLength > maxLength {
+		return Policy{}, fmt.Errorf("password min length %d is more than %d", minLength, maxLength)
+	}
+	if len(bannedPatterns) == 0 {
+		bannedPatterns = DefaultBannedPatterns
+	}
+	policy := Policy{MinLength: minLength}
+	for _, pattern := range bannedPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return Policy{}, fmt.Errorf("banned password pattern %q: %w", pattern, err)
+		}
+		policy.BannedPatterns = append(policy.BannedPatterns, re)
+	}
+	return policy, nil
+}
+
+// Account is who a password is for, their email and names cannot be their password.
+type Account struct {
+	Email     string
+	FirstName string
+	LastName  string
+}
+
+type PasswordSrv interface {
+	// Check returns the rules the password breaks as field errors of field, nil when it can be used.
+	Check(field, password string, account Account) *ResponseEntity.ServiceError
+}
+
+type passwordSrv struct {
+	policy   Policy
+	breached BreachedPasswords
+}
+
+func NewPasswordSrv(policy Policy, breached BreachedPasswords) PasswordSrv {
+	return &passwordSrv{policy: policy, breached: breached}
+}
+
+func (p *passwordSrv) Check(field, password string, account Account) *ResponseEntity.ServiceError {
+	var errs []ResponseEntity.FieldError
+	fail := func(rule, message string) {
+		errs = append(errs, ResponseEntity.FieldError{Field: field, Rule: rule, Message: message})
+	}
+
+	if utf8.RuneCountInString(password) < p.policy.MinLength {
+		fail(RuleMinLength, fmt.Sprintf("must be at least %d characters long", p.policy.MinLength))
+	}
+	if len(password) > maxLength {
+		fail(RuleMaxLength, fmt.Sprintf("must be at most %d bytes long", maxLength))
+	}
+	for _, pattern := range p.policy.BannedPatterns {
+		if pattern.MatchString(password) {
+			fail(RulePattern, "is too easy to guess")
+			break
+		}
+	}
+	if personal(password, account) {
+		fail(RulePersonal, "cannot be your email or name")
+	}
+
+	// a password that breaks a rule already is not looked up
+	if len(errs) == 0 && p.breached != nil {
+		found, err := breached(p.breached, password)
+		if err != nil {
+			// an unreachable list does not keep anyone from signing up
+			log.Println("could not check breached passwords", err)
+		}
+		if found {
+			fail(RuleBreached, "has appeared in a data breach, choose another")
+		}
+	}
+
+	if len(errs) > 0 {
+		return ResponseEntity.NewValidatingError(errs)
+	}
+	return nil
+}
+
+// personal reports whether the password is the account's email, the name before its @, or its names.
+func personal(password string, account Account) bool {
+	password = normalize(password)
+	if password == "" {
+		return false
+	}
+	local, _, _ := strings.Cut(account.Email, "@")
+	for _, value := range []string{account.Email, local, account.FirstName, account.LastName,
+		account.FirstName + account.LastName, account.LastName + account.FirstName} {
+		if v := normalize(value); v != "" && v == password {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), ""))
+}
@@ -0,0 +1,116 @@
+package passwordService
+
+import (
+	"errors"
+	"test-va/internals/entity/ResponseEntity"
+	"testing"
+)
+
+type failingList struct{}
+
+func (failingList) Range(prefix string) ([]string, error) {
+	return nil, errors.New("unreachable")
+}
+
+func rules(t *testing.T, errRes *ResponseEntity.ServiceError) []string {
+	t.Helper()
+	if errRes == nil {
+		return nil
+	}
+	if errRes.Description != "BadInput Request" {
+		t.Fatalf("description %q", errRes.Description)
+	}
+	var broken []string
+	for _, fieldErr := range errRes.Error.([]ResponseEntity.FieldError) {
+		if fieldErr.Field != "password" {
+			t.Errorf("field %q", fieldErr.Field)
+		}
+		broken = append(broken, fieldErr.Rule)
+	}
+	return broken
+}
+
+func TestCheck(t *testing.T) {
+	policy, err := NewPolicy(0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewPasswordSrv(policy, NewOfflineList())
+	account := Account{Email: "Ada.Lovelace@example.com", FirstName: "Ada", LastName: "Lovelace"}
+
+	tests := []struct {
+		password string
+		want     []string
+	}{
+		{"short", []string{RuleMinLength}},
+		{"correct horse battery staple", nil},
+		{"20240601123", []string{RulePattern}},
+		{"Password2024!", []string{RulePattern}},
+		{"my getticked login", []string{RulePattern}},
+		{"ada.lovelace", []string{RulePersonal}},
+		{"ada.lovelace@example.com", []string{RulePersonal}},
+		{"Ada Lovelace", []string{RulePersonal}},
+		{"lovelaceada", []string{RulePersonal}},
+		{"sunshine123", []string{RuleBreached}},
+		{"Football1!", []string{RuleBreached}},
+		{"aaaaaaaaaa", []string{RuleBreached}},
+		{"1234", []string{RuleMinLength, RulePattern}},
+		{string(make([]byte, 73)) + "x", []string{RuleMaxLength}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			got := rules(t, srv.Check("password", tt.password, account))
+			if len(got) != len(tt.want) {
+				t.Fatalf("broke %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("broke %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfiguredPolicy(t *testing.T) {
+	if _, err := NewPolicy(0, []string{"("}); err == nil {
+		t.Fatal("invalid pattern accepted")
+	}
+	if _, err := NewPolicy(100, nil); err == nil {
+		t.Fatal("min length longer than bcrypt hashes accepted")
+	}
+
+	policy, err := NewPolicy(12, []string{`(?i)acme`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewPasswordSrv(policy, failingList{})
+	if got := rules(t, srv.Check("password", "eleven char", Account{})); len(got) != 1 || got[0] != RuleMinLength {
+		t.Errorf("broke %v, want min", got)
+	}
+	if got := rules(t, srv.Check("password", "acme rockets forever", Account{})); len(got) != 1 || got[0] != RulePattern {
+		t.Errorf("broke %v, want pattern", got)
+	}
+	// the defaults are replaced, not added to
+	if got := rules(t, srv.Check("password", "123456789012", Account{})); got != nil {
+		t.Errorf("broke %v, want none", got)
+	}
+	// a list that cannot be reached lets the password through
+	if errRes := srv.Check("password", "sunshine12345", Account{}); errRes != nil {
+		t.Errorf("Check() = %v", errRes.Error)
+	}
+}
+
+func TestOfflineList(t *testing.T) {
+	list := newOfflineList([]byte("# comment\n5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8\nnot a hash\n"))
+	suffixes, _ := list.Range("5baa6")
+	if len(suffixes) != 1 || suffixes[0] != "1E4C9B93F3F0682250B6CF8331B7EE68FD8" {
+		t.Fatalf("Range() = %v", suffixes)
+	}
+	if found, _ := breached(list, "password"); !found {
+		t.Error("password not found")
+	}
+	if found, _ := breached(list, "Password"); found {
+		t.Error("Password found")
+	}
+}
@@ -0,0 +1,235 @@
+package policyService
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"test-va/internals/Repository/projectRepo"
+	"test-va/internals/Repository/taskRepo"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/taskEntity"
+	"test-va/internals/service/projectService"
+)
+
+// Roles are the statuses tokens are issued with
+const (
+	RoleUser   = "user"
+	RoleVA     = "VA"
+	RoleMaster = "MASTER"
+)
+
+const (
+	ErrForbidden = "Forbidden"
+	ErrNotFound  = "Not Found"
+)
+
+// Actor is the account a request is made by, as told by its token.
+type Actor struct {
+	Id   string
+	Role string
+}
+
+// HasRole reports whether the actor has one of the roles.
+func (a *Actor) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if a.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// A Check fails unless the actor may access the resource with the given id.
+type Check func(actor *Actor, resourceId string) *ResponseEntity.ServiceError
+
+// PolicySrv decides who may access which resource. Every method is a Check.
+type PolicySrv interface {
+	// CanViewUser allows the user, the VA assigned to them and masters.
+	CanViewUser(actor *Actor, userId string) *ResponseEntity.ServiceError
+	// CanManageUser allows the user and masters.
+	CanManageUser(actor *Actor, userId string) *ResponseEntity.ServiceError
+	// CanViewVA allows the VA, the users they are assigned to and masters.
+	CanViewVA(actor *Actor, vaId string) *ResponseEntity.ServiceError
+	// CanManageVA allows the VA and masters.
+	CanManageVA(actor *Actor, vaId string) *ResponseEntity.ServiceError
+	// CanReadTask allows the owner of the task, the VA it is assigned to and members of its project.
+	CanReadTask(actor *Actor, taskId string) *ResponseEntity.ServiceError
+	// CanEditTask is CanReadTask without the viewers of the project.
+	CanEditTask(actor *Actor, taskId string) *ResponseEntity.ServiceError
+	// CanOwnTask allows the owner of the task only.
+	CanOwnTask(actor *Actor, taskId string) *ResponseEntity.ServiceError
+	// CanReadProject allows the owner and the members of a project.
+	CanReadProject(actor *Actor, projectId string) *ResponseEntity.ServiceError
+	// CanDeleteComment allows whoever wrote the comment and the owner of its task.
+	CanDeleteComment(actor *Actor, commentId string) *ResponseEntity.ServiceError
+}
+
+type policySrv struct {
+	taskRepo    taskRepo.TaskRepository
+	projectRepo projectRepo.ProjectRepository
+}
+
+func NewPolicySrv(taskRepo taskRepo.TaskRepository, projectRepo projectRepo.ProjectRepository) PolicySrv {
+	return &policySrv{taskRepo: taskRepo, projectRepo: projectRepo}
+}
+
+func (p *policySrv) CanViewUser(actor *Actor, userId string) *ResponseEntity.ServiceError {
+	if actor.Id == userId || actor.Role == RoleMaster {
+		return nil
+	}
+	if actor.Role != RoleVA {
+		return forbidden("you do not have access to this user")
+	}
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	return p.requireAssigned(ctx, userId, actor.Id, "you are not assigned to this user")
+}
+
+func (p *policySrv) CanManageUser(actor *Actor, userId string) *ResponseEntity.ServiceError {
+	if actor.Id == userId || actor.Role == RoleMaster {
+		return nil
+	}
+	return forbidden("you can only change your own account")
+}
+
+func (p *policySrv) CanViewVA(actor *Actor, vaId string) *ResponseEntity.ServiceError {
+	if actor.Id == vaId || actor.Role == RoleMaster {
+		return nil
+	}
+	if actor.Role != RoleUser {
+		return forbidden("you do not have access to this VA")
+	}
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	return p.requireAssigned(ctx, actor.Id, vaId, "this VA is not assigned to you")
+}
+
+func (p *policySrv) CanManageVA(actor *Actor, vaId string) *ResponseEntity.ServiceError {
+	if actor.Id == vaId || actor.Role == RoleMaster {
+		return nil
+	}
+	return forbidden("you can only change your own account")
+}
+
+func (p *policySrv) CanReadTask(actor *Actor, taskId string) *ResponseEntity.ServiceError {
+	return p.checkTask(actor, taskId, false)
+}
+
+func (p *policySrv) CanEditTask(actor *Actor, taskId string) *ResponseEntity.ServiceError {
+	return p.checkTask(actor, taskId, true)
+}
+
+func (p *policySrv) CanOwnTask(actor *Actor, taskId string) *ResponseEntity.ServiceError {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	task, errRes := p.getTask(ctx, taskId)
+	if errRes != nil {
+		return errRes
+	}
+	if task.UserId != actor.Id {
+		return forbidden("only the owner of the task can do this")
+	}
+	return nil
+}
+
+func (p *policySrv) CanReadProject(actor *Actor, projectId string) *ResponseEntity.ServiceError {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	return p.requireProjectRole(ctx, projectId, actor.Id, false)
+}
+
+func (p *policySrv) CanDeleteComment(actor *Actor, commentId string) *ResponseEntity.ServiceError {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	comment, err := p.taskRepo.GetCommentByID(ctx, commentId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ResponseEntity.NewCustomServiceError(ErrNotFound, "No comment with that ID")
+		}
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	if comment.SenderId == actor.Id {
+		return nil
+	}
+	task, errRes := p.getTask(ctx, comment.TaskId)
+	if errRes != nil {
+		return errRes
+	}
+	if task.UserId != actor.Id {
+		return forbidden("you can only delete your own comments")
+	}
+	return nil
+}
+
+// checkTask allows the owner and the VA of a task, and members of its project according to their role.
+func (p *policySrv) checkTask(actor *Actor, taskId string, write bool) *ResponseEntity.ServiceError {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	task, errRes := p.getTask(ctx, taskId)
+	if errRes != nil {
+		return errRes
+	}
+	if task.UserId == actor.Id || (task.VaId != "" && task.VaId == actor.Id) {
+		return nil
+	}
+	if task.ProjectId == "" {
+		return forbidden("you do not have access to this task")
+	}
+	return p.requireProjectRole(ctx, task.ProjectId, actor.Id, write)
+}
+
+func (p *policySrv) getTask(ctx context.Context, taskId string) (*taskEntity.GetTasksByIdRes, *ResponseEntity.ServiceError) {
+	task, err := p.taskRepo.GetTaskByID(ctx, taskId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ResponseEntity.NewCustomServiceError(ErrNotFound, "No task with that ID")
+		}
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return task, nil
+}
+
+// requireProjectRole fails unless userId is a member of the project with a role allowing the access.
+func (p *policySrv) requireProjectRole(ctx context.Context, projectId, userId string, write bool) *ResponseEntity.ServiceError {
+	role, err := p.projectRepo.GetMemberRole(ctx, projectId, userId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	allowed := projectService.CanRead(role)
+	if write {
+		allowed = projectService.CanWrite(role)
+	}
+	if !allowed {
+		return forbidden("you do not have access to this project")
+	}
+	return nil
+}
+
+// requireAssigned fails unless vaId is the VA assigned to userId.
+func (p *policySrv) requireAssigned(ctx context.Context, userId, vaId, message string) *ResponseEntity.ServiceError {
+	assigned, err := p.taskRepo.GetVADetails(ctx, userId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	if assigned == "" || assigned != vaId {
+		return forbidden(message)
+	}
+	return nil
+}
+
+func forbidden(message string) *ResponseEntity.ServiceError {
+	return ResponseEntity.NewCustomServiceError(ErrForbidden, message)
+}
@@ -0,0 +1,506 @@
+package privacyService
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"time"
+
+	"test-va/internals/Repository/privacyRepo"
+	"test-va/internals/Repository/userRepo"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/eventEntity"
+	"test-va/internals/entity/privacyEntity"
+	"test-va/internals/msg-queue/Emitter"
+	"test-va/internals/service/awsService"
+	"test-va/internals/service/storageService"
+	tokenservice "test-va/internals/service/tokenService"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// an erasure can be cancelled for this long before it runs
+	erasureGracePeriod = time.Hour * 24 * 30
+	// the archive of an export is deleted this long after it is ready
+	exportLifetime = time.Hour * 24 * 7
+	downloadExpiry = time.Hour
+)
+
+// service error descriptions the handler maps to status codes
+const (
+	ErrNotFound = "Not Found"
+)
+
+type PrivacySrv interface {
+	// RequestExport starts building an archive of everything stored about the user, or returns the one being built.
+	RequestExport(userId string) (*privacyEntity.ExportRes, *ResponseEntity.ServiceError)
+	// GetExport tells whether an export is ready, with a link to download it once it is.
+	GetExport(userId, requestId string) (*privacyEntity.ExportRes, *ResponseEntity.ServiceError)
+	// ProcessExports builds the archives of pending exports and deletes expired ones.
+	ProcessExports()
+	// RequestErasure schedules the erasure of the user after the grace period, or returns the one scheduled.
+	// requestedBy is the user or the master asking for it.
+	RequestErasure(userId, requestedBy string) (*privacyEntity.ErasureRes, *ResponseEntity.ServiceError)
+	// CancelErasure stops a scheduled erasure during its grace period.
+	CancelErasure(userId string) (*privacyEntity.ErasureRes, *ResponseEntity.ServiceError)
+	// ProcessErasures erases the users whose grace period is over.
+	ProcessErasures()
+}
+
+type privacySrv struct {
+	repo     privacyRepo.PrivacyRepository
+	userRepo userRepo.UserRepository
+	// storage holds attachments and export archives, images holds avatars
+	storage  storageService.StorageSrv
+	images   awsService.AWSService
+	tokenSrv tokenservice.TokenSrv
+	emitter  Emitter.Emitter
+	now      func() time.Time
+}
+
+func NewPrivacySrv(repo privacyRepo.PrivacyRepository, userRepo userRepo.UserRepository, storage storageService.StorageSrv,
+	images awsService.AWSService, tokenSrv tokenservice.TokenSrv, emitter Emitter.Emitter) PrivacySrv {
+	return &privacySrv{repo: repo, userRepo: userRepo, storage: storage, images: images, tokenSrv: tokenSrv,
+		emitter: emitter, now: time.Now}
+}
+
+// Request Export godoc
+// @Summary	Export all of your data
+// @Description	Starts building a zip archive of everything stored about the user, with the files they attached. Poll /user/privacy/export/{requestId} until it is READY, an email is also sent then. The archive can be downloaded for 7 days.
+// @Tags	Privacy
+// @Produce	json
+// @Success	202  {object}  privacyEntity.ExportRes
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security BearerAuth
+// @Router	/user/privacy/export [post]
+func (p *privacySrv) RequestExport(userId string) (*privacyEntity.ExportRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	req, err := p.repo.GetOpenRequest(ctx, userId, privacyEntity.KindExport)
+	if err == nil {
+		return exportRes(req, ""), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	now := p.now().UTC().Format(time.RFC3339)
+	req = &privacyEntity.Request{
+		RequestId:    uuid.New().String(),
+		UserId:       userId,
+		Kind:         privacyEntity.KindExport,
+		Status:       privacyEntity.StatusPending,
+		RequestedBy:  userId,
+		RequestedAt:  now,
+		ScheduledFor: now,
+	}
+	err = p.repo.AddRequest(ctx, req)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return exportRes(req, ""), nil
+}
+
+// Get Export godoc
+// @Summary	Check on a data export
+// @Description	Returns the status of an export, with a download url that works for an hour once it is READY
+// @Tags	Privacy
+// @Produce	json
+// @Param	requestId	path	string	true	"Export Id"
+// @Success	200  {object}  privacyEntity.ExportRes
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security BearerAuth
+// @Router	/user/privacy/export/{requestId} [get]
+func (p *privacySrv) GetExport(userId, requestId string) (*privacyEntity.ExportRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	req, err := p.repo.GetRequest(ctx, requestId)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && (req.UserId != userId || req.Kind != privacyEntity.KindExport)) {
+		return nil, ResponseEntity.NewCustomServiceError(ErrNotFound, errors.New("export not found"))
+	}
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if req.Status != privacyEntity.StatusReady {
+		return exportRes(req, ""), nil
+	}
+
+	presigned, err := p.storage.PresignDownload(req.ArchiveKey, "getticked-export.zip", downloadExpiry)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return exportRes(req, presigned.Url), nil
+}
+
+func (p *privacySrv) ProcessExports() {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*10)
+	defer cancelFunc()
+
+	now := p.now().UTC()
+	due, err := p.repo.DueRequests(ctx, privacyEntity.KindExport, now.Format(time.RFC3339))
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, req := range due {
+		err := p.export(ctx, req)
+		if err != nil {
+			log.Println("export", req.RequestId, err)
+			req.Status = privacyEntity.StatusFailed
+			req.CompletedAt = p.now().UTC().Format(time.RFC3339)
+			if err := p.repo.UpdateRequest(ctx, req); err != nil {
+				log.Println(err)
+			}
+		}
+	}
+
+	expired, err := p.repo.ExpiredExports(ctx, now.Format(time.RFC3339))
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, req := range expired {
+		err := p.storage.Delete(req.ArchiveKey)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		req.Status = privacyEntity.StatusExpired
+		req.ArchiveKey = ""
+		if err := p.repo.UpdateRequest(ctx, req); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// export builds the archive of a request, stores it and emails the user it is ready.
+func (p *privacySrv) export(ctx context.Context, req *privacyEntity.Request) error {
+	data, err := p.repo.ExportUserData(ctx, req.UserId)
+	if err != nil {
+		return err
+	}
+
+	archive, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	err = p.writeArchive(archive, data)
+	if err != nil {
+		return err
+	}
+	_, err = archive.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("exports/%s/%s.zip", req.UserId, req.RequestId)
+	err = p.storage.Put(key, "application/zip", archive)
+	if err != nil {
+		return err
+	}
+
+	now := p.now().UTC()
+	req.Status = privacyEntity.StatusReady
+	req.ArchiveKey = key
+	req.CompletedAt = now.Format(time.RFC3339)
+	req.ExpiresAt = now.Add(exportLifetime).Format(time.RFC3339)
+	err = p.repo.UpdateRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if users := data["Users"]; len(users) > 0 {
+		email, _ := users[0]["email"].(string)
+		firstName, _ := users[0]["first_name"].(string)
+		p.sendEmail("export_ready", email, "Subject: Your getticked data export is ready\n",
+			createExportBody(firstName, now.Add(exportLifetime)))
+	}
+	return nil
+}
+
+// writeArchive zips data.json with every table, and the files of the user's attachments under files/.
+func (p *privacySrv) writeArchive(w io.Writer, data map[string]privacyEntity.Table) error {
+	archive := zip.NewWriter(w)
+
+	file, err := archive.Create("data.json")
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(data)
+	if err != nil {
+		return err
+	}
+
+	for _, attachment := range data["Attachments"] {
+		key, _ := attachment["storage_key"].(string)
+		if key == "" || attachment["status"] != "UPLOADED" {
+			continue
+		}
+		err := p.addFile(archive, key, fmt.Sprintf("files/%v-%v", attachment["attachment_id"], attachment["file_name"]))
+		if err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+func (p *privacySrv) addFile(archive *zip.Writer, key, name string) error {
+	body, err := p.storage.Get(key)
+	if errors.Is(err, storageService.ErrObjectNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	file, err := archive.Create(path.Clean(name))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, body)
+	return err
+}
+
+// Request Erasure godoc
+// @Summary	Erase your account and all of your data
+// @Description	Schedules the erasure of the user in 30 days. Until then it can be cancelled with DELETE /user/privacy/erasure, after that every task, project, comment, attachment and setting of the user is deleted for good. DELETE /user/{userId} does the same.
+// @Tags	Privacy
+// @Produce	json
+// @Success	202  {object}  privacyEntity.ErasureRes
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security BearerAuth
+// @Router	/user/privacy/erasure [post]
+func (p *privacySrv) RequestErasure(userId, requestedBy string) (*privacyEntity.ErasureRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	user, err := p.userRepo.GetById(userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ResponseEntity.NewCustomServiceError(ErrNotFound, errors.New("user not found"))
+	}
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	req, err := p.repo.GetOpenRequest(ctx, userId, privacyEntity.KindErasure)
+	if err == nil {
+		return erasureRes(req), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	now := p.now().UTC()
+	req = &privacyEntity.Request{
+		RequestId:    uuid.New().String(),
+		UserId:       userId,
+		Kind:         privacyEntity.KindErasure,
+		Status:       privacyEntity.StatusPending,
+		RequestedBy:  requestedBy,
+		RequestedAt:  now.Format(time.RFC3339),
+		ScheduledFor: now.Add(erasureGracePeriod).Format(time.RFC3339),
+	}
+	err = p.repo.AddRequest(ctx, req)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	p.sendEmail("erasure_scheduled", user.Email, "Subject: Your getticked account will be deleted\n",
+		createScheduledBody(user.FirstName, now.Add(erasureGracePeriod)))
+	return erasureRes(req), nil
+}
+
+// Cancel Erasure godoc
+// @Summary	Keep your account
+// @Description	Cancels an erasure during its grace period
+// @Tags	Privacy
+// @Produce	json
+// @Success	200  {object}  privacyEntity.ErasureRes
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security BearerAuth
+// @Router	/user/privacy/erasure [delete]
+func (p *privacySrv) CancelErasure(userId string) (*privacyEntity.ErasureRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	req, err := p.repo.GetOpenRequest(ctx, userId, privacyEntity.KindErasure)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ResponseEntity.NewCustomServiceError(ErrNotFound, errors.New("no erasure is scheduled"))
+	}
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	req.Status = privacyEntity.StatusCancelled
+	req.CompletedAt = p.now().UTC().Format(time.RFC3339)
+	err = p.repo.UpdateRequest(ctx, req)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return erasureRes(req), nil
+}
+
+func (p *privacySrv) ProcessErasures() {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*10)
+	defer cancelFunc()
+
+	due, err := p.repo.DueRequests(ctx, privacyEntity.KindErasure, p.now().UTC().Format(time.RFC3339))
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, req := range due {
+		err := p.erase(ctx, req)
+		if err != nil {
+			// left pending, the next run tries again
+			log.Println("erasure", req.RequestId, err)
+		}
+	}
+}
+
+// erase deletes the stored files of the user, then every row, and records that it happened.
+// Files go first, a failure leaves the rows that point to what is left so a later run finds it.
+func (p *privacySrv) erase(ctx context.Context, req *privacyEntity.Request) error {
+	user, err := p.userRepo.GetById(req.UserId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	// the tokens are refused from now on, revocations outlive the erasure
+	err = p.tokenSrv.LogoutAll(req.UserId)
+	if err != nil {
+		return err
+	}
+
+	keys, err := p.repo.StorageKeys(ctx, req.UserId)
+	if err != nil {
+		return err
+	}
+	objects := 0
+	for _, key := range keys {
+		err := p.storage.Delete(key)
+		if err != nil {
+			return err
+		}
+		objects++
+	}
+	images, err := p.images.DeleteImages(req.UserId + "/")
+	if err != nil {
+		return err
+	}
+	objects += images
+
+	deleted, err := p.repo.EraseUser(ctx, req.UserId)
+	if err != nil {
+		return err
+	}
+	rows, err := json.Marshal(deleted)
+	if err != nil {
+		return err
+	}
+
+	now := p.now().UTC().Format(time.RFC3339)
+	err = p.repo.AddErasureRecord(ctx, &privacyEntity.ErasureRecord{
+		RecordId:       uuid.New().String(),
+		UserId:         req.UserId,
+		RequestId:      req.RequestId,
+		RequestedBy:    req.RequestedBy,
+		RequestedAt:    req.RequestedAt,
+		ErasedAt:       now,
+		Rows:           string(rows),
+		StorageObjects: objects,
+	})
+	if err != nil {
+		return err
+	}
+
+	req.Status = privacyEntity.StatusDone
+	req.CompletedAt = now
+	err = p.repo.UpdateRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if user != nil {
+		p.sendEmail("erasure_done", user.Email, "Subject: Your getticked account was deleted\n",
+			createErasedBody(user.FirstName))
+	}
+	return nil
+}
+
+func (p *privacySrv) sendEmail(subAction, email, subject, body string) {
+	if email == "" {
+		return
+	}
+	payload := eventEntity.Payload{
+		Action:    "email",
+		SubAction: subAction,
+		Data: map[string]string{
+			"email_address": email,
+			"email_subject": subject,
+			"email_body":    body,
+		},
+	}
+	err := p.emitter.Push(payload, "info")
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func exportRes(req *privacyEntity.Request, downloadUrl string) *privacyEntity.ExportRes {
+	return &privacyEntity.ExportRes{
+		RequestId:   req.RequestId,
+		Status:      req.Status,
+		RequestedAt: req.RequestedAt,
+		DownloadUrl: downloadUrl,
+		ExpiresAt:   req.ExpiresAt,
+	}
+}
+
+func erasureRes(req *privacyEntity.Request) *privacyEntity.ErasureRes {
+	return &privacyEntity.ErasureRes{
+		RequestId:    req.RequestId,
+		Status:       req.Status,
+		RequestedAt:  req.RequestedAt,
+		ScheduledFor: req.ScheduledFor,
+	}
+}
+
+func createExportBody(firstName string, expiresAt time.Time) string {
+	subject := fmt.Sprintf("Hi %v, \n\n", firstName)
+	mainBody := fmt.Sprintf("The export of your getticked data you asked for is ready. Download it from the privacy settings of the app before %v UTC, it is deleted after that.",
+		expiresAt.Format("2 Jan 2006 15:04"))
+	return subject + mainBody
+}
+
+func createScheduledBody(firstName string, scheduledFor time.Time) string {
+	subject := fmt.Sprintf("Hi %v, \n\n", firstName)
+	mainBody := fmt.Sprintf("Your getticked account and all of its data will be deleted on %v UTC, as was asked for.\n\nIf you want to keep your account, log in and cancel the deletion from the privacy settings before then. After that it cannot be undone.",
+		scheduledFor.Format("2 Jan 2006 15:04"))
+	return subject + mainBody
+}
+
+func createErasedBody(firstName string) string {
+	subject := fmt.Sprintf("Hi %v, \n\n", firstName)
+	mainBody := "Your getticked account and all of its data were deleted. Thank you for using getticked."
+	return subject + mainBody
+}
@@ -0,0 +1,366 @@
+package privacyService
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"strings"
+	"test-va/internals/Repository/userRepo"
+	"test-va/internals/entity/eventEntity"
+	"test-va/internals/entity/privacyEntity"
+	"test-va/internals/entity/userEntity"
+	"test-va/internals/service/awsService"
+	"test-va/internals/service/storageService"
+	"test-va/internals/service/storageService/localStorage"
+	tokenservice "test-va/internals/service/tokenService"
+	"testing"
+	"time"
+)
+
+type memoryRepo struct {
+	requests map[string]*privacyEntity.Request
+	data     map[string]privacyEntity.Table
+	keys     []string
+	erased   []string
+	records  []*privacyEntity.ErasureRecord
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{requests: map[string]*privacyEntity.Request{}}
+}
+
+func (m *memoryRepo) AddRequest(ctx context.Context, req *privacyEntity.Request) error {
+	stored := *req
+	m.requests[req.RequestId] = &stored
+	return nil
+}
+
+func (m *memoryRepo) GetRequest(ctx context.Context, requestId string) (*privacyEntity.Request, error) {
+	req, ok := m.requests[requestId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	stored := *req
+	return &stored, nil
+}
+
+func (m *memoryRepo) GetOpenRequest(ctx context.Context, userId, kind string) (*privacyEntity.Request, error) {
+	for _, req := range m.requests {
+		if req.UserId == userId && req.Kind == kind && req.Status == privacyEntity.StatusPending {
+			stored := *req
+			return &stored, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryRepo) DueRequests(ctx context.Context, kind, now string) ([]*privacyEntity.Request, error) {
+	var due []*privacyEntity.Request
+	for _, req := range m.requests {
+		if req.Kind == kind && req.Status == privacyEntity.StatusPending && req.ScheduledFor <= now {
+			stored := *req
+			due = append(due, &stored)
+		}
+	}
+	return due, nil
+}
+
+func (m *memoryRepo) ExpiredExports(ctx context.Context, now string) ([]*privacyEntity.Request, error) {
+	var expired []*privacyEntity.Request
+	for _, req := range m.requests {
+		if req.Kind == privacyEntity.KindExport && req.Status == privacyEntity.StatusReady && req.ExpiresAt < now {
+			stored := *req
+			expired = append(expired, &stored)
+		}
+	}
+	return expired, nil
+}
+
+func (m *memoryRepo) UpdateRequest(ctx context.Context, req *privacyEntity.Request) error {
+	stored := *req
+	m.requests[req.RequestId] = &stored
+	return nil
+}
+
+func (m *memoryRepo) ExportUserData(ctx context.Context, userId string) (map[string]privacyEntity.Table, error) {
+	return m.data, nil
+}
+
+func (m *memoryRepo) StorageKeys(ctx context.Context, userId string) ([]string, error) {
+	return m.keys, nil
+}
+
+func (m *memoryRepo) EraseUser(ctx context.Context, userId string) (map[string]int64, error) {
+	m.erased = append(m.erased, userId)
+	return map[string]int64{"Users": 1, "Tasks": 3}, nil
+}
+
+func (m *memoryRepo) AddErasureRecord(ctx context.Context, record *privacyEntity.ErasureRecord) error {
+	m.records = append(m.records, record)
+	return nil
+}
+
+type memoryUsers struct {
+	userRepo.UserRepository
+}
+
+func (m *memoryUsers) GetById(userId string) (*userEntity.GetByIdRes, error) {
+	if userId != "u1" {
+		return nil, sql.ErrNoRows
+	}
+	return &userEntity.GetByIdRes{UserId: "u1", Email: "ada@example.com", FirstName: "Ada"}, nil
+}
+
+type memoryTokens struct {
+	tokenservice.TokenSrv
+	loggedOut []string
+}
+
+func (m *memoryTokens) LogoutAll(userId string) error {
+	m.loggedOut = append(m.loggedOut, userId)
+	return nil
+}
+
+// memoryImages pretends every user has two avatars stored.
+type memoryImages struct {
+	awsService.AWSService
+	prefixes []string
+	err      error
+}
+
+func (m *memoryImages) DeleteImages(prefix string) (int, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	m.prefixes = append(m.prefixes, prefix)
+	return 2, nil
+}
+
+type memoryEmitter struct {
+	payloads []eventEntity.Payload
+}
+
+func (m *memoryEmitter) Push(payload eventEntity.Payload, severity string) error {
+	m.payloads = append(m.payloads, payload)
+	return nil
+}
+
+type fixture struct {
+	srv     *privacySrv
+	repo    *memoryRepo
+	storage localStorage.LocalStorage
+	tokens  *memoryTokens
+	images  *memoryImages
+	emitter *memoryEmitter
+	now     time.Time
+}
+
+func newFixture(t *testing.T) *fixture {
+	f := &fixture{
+		repo:    newMemoryRepo(),
+		storage: localStorage.NewLocalStorage(t.TempDir(), "http://localhost/api/v1/files", "secret"),
+		tokens:  &memoryTokens{},
+		images:  &memoryImages{},
+		emitter: &memoryEmitter{},
+		now:     time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC),
+	}
+	f.srv = NewPrivacySrv(f.repo, &memoryUsers{}, f.storage, f.images, f.tokens, f.emitter).(*privacySrv)
+	f.srv.now = func() time.Time { return f.now }
+	return f
+}
+
+func TestExport(t *testing.T) {
+	f := newFixture(t)
+	err := f.storage.Put("attachments/u1/a1", "text/plain", strings.NewReader("meeting notes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.repo.data = map[string]privacyEntity.Table{
+		"Users": {{"user_id": "u1", "email": "ada@example.com", "first_name": "Ada"}},
+		"Attachments": {
+			{"attachment_id": "a1", "file_name": "notes.txt", "storage_key": "attachments/u1/a1", "status": "UPLOADED"},
+			{"attachment_id": "a2", "file_name": "draft.txt", "storage_key": "attachments/u1/a2", "status": "PENDING"},
+		},
+	}
+
+	res, errRes := f.srv.RequestExport("u1")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	again, errRes := f.srv.RequestExport("u1")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	if again.RequestId != res.RequestId {
+		t.Fatal("a second export was started while the first was pending")
+	}
+
+	f.srv.ProcessExports()
+
+	_, errRes = f.srv.GetExport("u2", res.RequestId)
+	if errRes == nil || errRes.Description != ErrNotFound {
+		t.Fatalf("another user got the export: %v", errRes)
+	}
+	res, errRes = f.srv.GetExport("u1", res.RequestId)
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	if res.Status != privacyEntity.StatusReady || res.DownloadUrl == "" {
+		t.Fatalf("export is not ready: %+v", res)
+	}
+
+	archive := f.readArchive(t, f.repo.requests[res.RequestId].ArchiveKey)
+	if !strings.Contains(archive["data.json"], "ada@example.com") {
+		t.Fatalf("data.json is missing the user: %s", archive["data.json"])
+	}
+	if archive["files/a1-notes.txt"] != "meeting notes" {
+		t.Fatalf("attachment is missing from the archive: %v", archive)
+	}
+	if len(archive) != 2 {
+		t.Fatalf("archive has %d files, want 2", len(archive))
+	}
+	if len(f.emitter.payloads) != 1 || f.emitter.payloads[0].SubAction != "export_ready" {
+		t.Fatalf("ready email not sent: %+v", f.emitter.payloads)
+	}
+
+	// the archive is deleted once it expires
+	key := f.repo.requests[res.RequestId].ArchiveKey
+	f.now = f.now.Add(exportLifetime + time.Minute)
+	f.srv.ProcessExports()
+	if status := f.repo.requests[res.RequestId].Status; status != privacyEntity.StatusExpired {
+		t.Fatalf("status = %s, want EXPIRED", status)
+	}
+	if _, err := f.storage.Get(key); !errors.Is(err, storageService.ErrObjectNotFound) {
+		t.Fatalf("archive not deleted: %v", err)
+	}
+}
+
+func (f *fixture) readArchive(t *testing.T, key string) map[string]string {
+	body, err := f.storage.Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	b, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, file := range r.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name] = string(content)
+	}
+	return files
+}
+
+func TestErasureGracePeriod(t *testing.T) {
+	f := newFixture(t)
+
+	_, errRes := f.srv.RequestErasure("missing", "missing")
+	if errRes == nil || errRes.Description != ErrNotFound {
+		t.Fatalf("erasure of an unknown user: %v", errRes)
+	}
+
+	res, errRes := f.srv.RequestErasure("u1", "u1")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	if want := f.now.Add(erasureGracePeriod).Format(time.RFC3339); res.ScheduledFor != want {
+		t.Fatalf("scheduled for %s, want %s", res.ScheduledFor, want)
+	}
+	if len(f.emitter.payloads) != 1 || f.emitter.payloads[0].SubAction != "erasure_scheduled" {
+		t.Fatalf("scheduled email not sent: %+v", f.emitter.payloads)
+	}
+
+	f.now = f.now.Add(erasureGracePeriod - time.Hour)
+	f.srv.ProcessErasures()
+	if len(f.repo.erased) != 0 {
+		t.Fatal("user erased during the grace period")
+	}
+
+	res, errRes = f.srv.CancelErasure("u1")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+	if res.Status != privacyEntity.StatusCancelled {
+		t.Fatalf("status = %s, want CANCELLED", res.Status)
+	}
+	_, errRes = f.srv.CancelErasure("u1")
+	if errRes == nil || errRes.Description != ErrNotFound {
+		t.Fatalf("cancelled twice: %v", errRes)
+	}
+
+	f.now = f.now.Add(time.Hour * 2)
+	f.srv.ProcessErasures()
+	if len(f.repo.erased) != 0 {
+		t.Fatal("cancelled erasure ran")
+	}
+}
+
+func TestErasure(t *testing.T) {
+	f := newFixture(t)
+	err := f.storage.Put("attachments/u1/a1", "text/plain", strings.NewReader("meeting notes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.repo.keys = []string{"attachments/u1/a1", "attachments/u1/gone"}
+
+	res, errRes := f.srv.RequestErasure("u1", "master")
+	if errRes != nil {
+		t.Fatal(errRes)
+	}
+
+	// a failure leaves the erasure pending for the next run
+	f.images.err = errors.New("s3 is down")
+	f.now = f.now.Add(erasureGracePeriod)
+	f.srv.ProcessErasures()
+	if len(f.repo.erased) != 0 || f.repo.requests[res.RequestId].Status != privacyEntity.StatusPending {
+		t.Fatal("erasure finished without deleting the avatars")
+	}
+
+	f.images.err = nil
+	f.srv.ProcessErasures()
+
+	if len(f.repo.erased) != 1 || f.repo.erased[0] != "u1" {
+		t.Fatalf("erased %v, want u1", f.repo.erased)
+	}
+	if _, err := f.storage.Get("attachments/u1/a1"); !errors.Is(err, storageService.ErrObjectNotFound) {
+		t.Fatalf("attachment not deleted: %v", err)
+	}
+	if len(f.images.prefixes) != 1 || f.images.prefixes[0] != "u1/" {
+		t.Fatalf("avatars deleted under %v, want u1/", f.images.prefixes)
+	}
+	if len(f.tokens.loggedOut) != 2 {
+		t.Fatalf("tokens revoked %d times, want once per run", len(f.tokens.loggedOut))
+	}
+	if f.repo.requests[res.RequestId].Status != privacyEntity.StatusDone {
+		t.Fatalf("status = %s, want DONE", f.repo.requests[res.RequestId].Status)
+	}
+
+	if len(f.repo.records) != 1 {
+		t.Fatalf("%d erasure records, want 1", len(f.repo.records))
+	}
+	record := f.repo.records[0]
+	if record.RequestedBy != "master" || record.StorageObjects != 4 || !strings.Contains(record.Rows, `"Tasks":3`) {
+		t.Fatalf("unexpected record %+v", record)
+	}
+	last := f.emitter.payloads[len(f.emitter.payloads)-1]
+	if last.SubAction != "erasure_done" || last.Data["email_address"] != "ada@example.com" {
+		t.Fatalf("erased email not sent: %+v", last)
+	}
+}
@@ -2,11 +2,19 @@ package projectService
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"sort"
+	"strings"
 	"test-va/internals/Repository/projectRepo"
 	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/eventEntity"
 	"test-va/internals/entity/projectEntity"
+	"test-va/internals/entity/taskEntity"
+	"test-va/internals/msg-queue/Emitter"
 	"test-va/internals/service/loggerService"
 	"test-va/internals/service/timeSrv"
 	"test-va/internals/service/validationService"
@@ -17,9 +25,55 @@ import (
 
 type ProjectService interface {
 	PersistProject(req *projectEntity.CreateProjectReq) (*projectEntity.CreateProjectRes, *ResponseEntity.ServiceError)
-	GetListOfUsersProjects(userId string) ([]*projectEntity.GetProjectRes, *ResponseEntity.ServiceError)
+	GetListOfUsersProjects(userId string, includeArchived bool, view string) ([]*projectEntity.GetProjectRes, *ResponseEntity.ServiceError)
 	EditProjectByID(req *projectEntity.EditProjectReq) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError)
-	DeleteProjectByID(projectId string) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError)
+	DeleteProjectByID(projectId, userId, children string) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError)
+	ArchiveProject(projectId, userId string, archive bool) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError)
+	MoveProject(req *projectEntity.MoveProjectReq) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError)
+
+	//sections
+	GetSections(projectId, userId string) ([]*projectEntity.Section, *ResponseEntity.ServiceError)
+	CreateSection(req *projectEntity.CreateSectionReq) (*projectEntity.Section, *ResponseEntity.ServiceError)
+	RenameSection(req *projectEntity.RenameSectionReq) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError)
+	ReorderSections(req *projectEntity.ReorderSectionsReq) ([]*projectEntity.Section, *ResponseEntity.ServiceError)
+	DeleteSection(projectId, sectionId, userId string) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError)
+
+	//templates
+	SaveAsTemplate(req *projectEntity.SaveTemplateReq) (*projectEntity.ProjectTemplate, *ResponseEntity.ServiceError)
+	GetTemplates(userId string) ([]*projectEntity.ProjectTemplate, *ResponseEntity.ServiceError)
+	InstantiateTemplate(req *projectEntity.InstantiateTemplateReq) (*projectEntity.InstantiateTemplateRes, *ResponseEntity.ServiceError)
+
+	//members
+	InviteMember(req *projectEntity.InviteMemberReq) (*projectEntity.Invitation, *ResponseEntity.ServiceError)
+	GetInvitations(userId string) ([]*projectEntity.Invitation, *ResponseEntity.ServiceError)
+	RespondToInvitation(invitationId, userId string, accept bool) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError)
+	GetMembers(projectId, userId string) ([]*projectEntity.ProjectMember, *ResponseEntity.ServiceError)
+	UpdateMemberRole(req *projectEntity.UpdateMemberReq) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError)
+	RemoveMember(projectId, userId, memberId string) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError)
+}
+
+const (
+	ErrNotFound  = "Not Found"
+	ErrForbidden = "Forbidden"
+)
+
+// Project listing views
+const (
+	ViewFlat = "flat"
+	ViewTree = "tree"
+)
+
+// maxDepth bounds how far up the project tree a move is checked.
+const maxDepth = 100
+
+// CanRead reports whether a project role may see the project's tasks and comment on them.
+func CanRead(role string) bool {
+	return role == projectEntity.RoleOwner || role == projectEntity.RoleEditor || role == projectEntity.RoleViewer
+}
+
+// CanWrite reports whether a project role may create and change the project's tasks.
+func CanWrite(role string) bool {
+	return role == projectEntity.RoleOwner || role == projectEntity.RoleEditor
 }
 
 type projectSrv struct {
@@ -27,6 +81,7 @@ type projectSrv struct {
 	timeSrv       timeSrv.TimeService
 	validationSrv validationService.ValidationSrv
 	logger        loggerService.LogSrv
+	emitter       Emitter.Emitter
 }
 
 func (p *projectSrv) PersistProject(req *projectEntity.CreateProjectReq) (*projectEntity.CreateProjectRes, *ResponseEntity.ServiceError) {
@@ -42,6 +97,13 @@ func (p *projectSrv) PersistProject(req *projectEntity.CreateProjectReq) (*proje
 	req.CreatedAt = p.timeSrv.CurrentTimeString() //.Format(time.RFC3339)
 	req.ProjectId = uuid.New().String()
 
+	// only the owner of a project can nest projects under it
+	if req.ParentId != "" {
+		if errRes := p.requireRole(ctx, req.ParentId, req.UserId, projectEntity.RoleOwner); errRes != nil {
+			return nil, errRes
+		}
+	}
+
 	err = p.repo.PersistProject(ctx, req)
 	if err != nil {
 		log.Println(err)
@@ -53,26 +115,65 @@ func (p *projectSrv) PersistProject(req *projectEntity.CreateProjectReq) (*proje
 		UserId:    req.UserId,
 		Title:     req.Title,
 		Color:     req.Color,
+		ParentId:  req.ParentId,
 	}
 	return &data, nil
 }
 
-func (p *projectSrv) GetListOfUsersProjects(userId string) ([]*projectEntity.GetProjectRes, *ResponseEntity.ServiceError) {
+// Get Projects godoc
+// @Summary	Get the projects of the logged in user
+// @Description	Projects the user owns or is a member of, each with open, completed and overdue task counts and the next due date. Archived projects are left out unless asked for.
+// @Description	The flat view lists every project after its parent with its depth and path, the tree view nests sub projects under children.
+// @Tags	Projects
+// @Produce	json
+// @Param	archived	query	bool	false	"Include archived projects"
+// @Param	view	query	string	false	"flat (default) or tree"
+// @Success	200  {object}  []projectEntity.GetProjectRes
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/project/ [get]
+func (p *projectSrv) GetListOfUsersProjects(userId string, includeArchived bool, view string) ([]*projectEntity.GetProjectRes, *ResponseEntity.ServiceError) {
 
 	// create context of 1 minute
 	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
 	defer cancelFunc()
 
-	projects, err := p.repo.GetListOfProjects(ctx, userId)
+	if view == "" {
+		view = ViewFlat
+	}
+	if view != ViewFlat && view != ViewTree {
+		return nil, ResponseEntity.NewValidatingError("view must be flat or tree")
+	}
+
+	projects, err := p.repo.GetListOfProjects(ctx, userId, includeArchived)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
 	if projects == nil {
 		// log.Println("no rows returned")
-		return nil, ResponseEntity.NewInternalServiceError(err)
+		return nil, nil
 	}
+
+	projectIds := make([]string, len(projects))
+	for i, project := range projects {
+		projectIds[i] = project.ProjectId
+	}
+	summaries, err := p.repo.GetTaskSummaries(ctx, projectIds)
 	if err != nil {
 		log.Println(err)
 		return nil, ResponseEntity.NewInternalServiceError(err)
 	}
-	return projects, nil
+	stats := ComputeStats(summaries, p.timeSrv.CurrentTime())
+	for _, project := range projects {
+		project.Stats = stats[project.ProjectId]
+	}
+
+	roots := BuildTree(projects)
+	if view == ViewTree {
+		return roots, nil
+	}
+	return Flatten(roots), nil
 }
 
 func (p *projectSrv) EditProjectByID(req *projectEntity.EditProjectReq) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError) {
@@ -101,12 +202,35 @@ func (p *projectSrv) EditProjectByID(req *projectEntity.EditProjectReq) (*Respon
 	return ResponseEntity.BuildSuccessResponse(http.StatusOK, "Project updated successfully", result, nil), nil
 }
 
-func (p *projectSrv) DeleteProjectByID(projectId string) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError) {
+// Delete Project godoc
+// @Summary	Delete a project
+// @Description	Only the owner can delete a project. Its sub projects either move up to its parent (reparent, the default) or are deleted with it (cascade).
+// @Tags	Projects
+// @Produce	json
+// @Param	projectId	path	string	true	"Project Id"
+// @Param	children	query	string	false	"reparent (default) or cascade"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/project/{projectId} [delete]
+func (p *projectSrv) DeleteProjectByID(projectId, userId, children string) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError) {
 	// create context of 1 minute
 	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
 	defer cancelFunc()
 
-	err := p.repo.DeleteProjectByID(ctx, projectId)
+	if children == "" {
+		children = projectEntity.DeleteReparent
+	}
+	if children != projectEntity.DeleteReparent && children != projectEntity.DeleteCascade {
+		return nil, ResponseEntity.NewValidatingError("children must be reparent or cascade")
+	}
+	if errRes := p.requireRole(ctx, projectId, userId, projectEntity.RoleOwner); errRes != nil {
+		return nil, errRes
+	}
+
+	err := p.repo.DeleteProjectByID(ctx, projectId, children == projectEntity.DeleteCascade)
 	if err != nil {
 		log.Println(err)
 		return nil, ResponseEntity.NewInternalServiceError(err)
@@ -114,8 +238,903 @@ func (p *projectSrv) DeleteProjectByID(projectId string) (*ResponseEntity.Respon
 	return ResponseEntity.BuildSuccessResponse(http.StatusOK, "project Deleted successfully", nil, nil), nil
 }
 
-func NewProjectSrv(repo projectRepo.ProjectRepository, timeSrv timeSrv.TimeService, validationSrv validationService.ValidationSrv, logger loggerService.LogSrv) ProjectService {
-	return &projectSrv{repo: repo, timeSrv: timeSrv, validationSrv: validationSrv, logger: logger}
+// Move Project godoc
+// @Summary	Move a project under another project or to the top level
+// @Description	The user must own both the project and its new parent. A project cannot be moved below itself.
+// @Tags	Projects
+// @Accept	json
+// @Produce	json
+// @Param	projectId	path	string	true	"Project Id"
+// @Param	request	body	projectEntity.MoveProjectReq	true	"New parent, empty for the top level"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/project/{projectId}/move [patch]
+func (p *projectSrv) MoveProject(req *projectEntity.MoveProjectReq) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	if errRes := p.requireRole(ctx, req.ProjectId, req.UserId, projectEntity.RoleOwner); errRes != nil {
+		return nil, errRes
+	}
+	if req.ParentId != "" {
+		if errRes := p.requireRole(ctx, req.ParentId, req.UserId, projectEntity.RoleOwner); errRes != nil {
+			return nil, errRes
+		}
+		if errRes := p.checkCycle(ctx, req.ProjectId, req.ParentId); errRes != nil {
+			return nil, errRes
+		}
+	}
+
+	err := p.repo.SetParent(ctx, req.ProjectId, req.ParentId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return ResponseEntity.BuildSuccessResponse(http.StatusOK, "Project moved successfully", req, nil), nil
+}
+
+// checkCycle fails when parentId is the project itself or one of its sub projects.
+func (p *projectSrv) checkCycle(ctx context.Context, projectId, parentId string) *ResponseEntity.ServiceError {
+	for depth := 0; parentId != ""; depth++ {
+		if parentId == projectId {
+			return ResponseEntity.NewValidatingError("a project cannot be moved under itself or one of its sub projects")
+		}
+		if depth == maxDepth {
+			return ResponseEntity.NewValidatingError("projects cannot be nested this deep")
+		}
+		var err error
+		parentId, err = p.repo.GetParentId(ctx, parentId)
+		if err != nil {
+			log.Println(err)
+			return ResponseEntity.NewInternalServiceError(err)
+		}
+	}
+	return nil
+}
+
+// BuildTree nests projects under their parents and sets each project's depth and path.
+// A project whose parent is not in the list, e.g. shared without its parent, is a root.
+func BuildTree(projects []*projectEntity.GetProjectRes) []*projectEntity.GetProjectRes {
+	byId := make(map[string]*projectEntity.GetProjectRes, len(projects))
+	for _, project := range projects {
+		project.Children = nil
+		byId[project.ProjectId] = project
+	}
+
+	var roots []*projectEntity.GetProjectRes
+	for _, project := range projects {
+		parent, ok := byId[project.ParentId]
+		if project.ParentId == "" || !ok {
+			roots = append(roots, project)
+			continue
+		}
+		parent.Children = append(parent.Children, project)
+	}
+
+	var walk func(nodes []*projectEntity.GetProjectRes, depth int, path string)
+	walk = func(nodes []*projectEntity.GetProjectRes, depth int, path string) {
+		for _, node := range nodes {
+			node.Depth = depth
+			node.Path = node.Title
+			if path != "" {
+				node.Path = path + " / " + node.Title
+			}
+			walk(node.Children, depth+1, node.Path)
+		}
+	}
+	walk(roots, 0, "")
+	return roots
+}
+
+// Flatten lists a tree depth first, every project straight after its parent, without the nested children.
+func Flatten(roots []*projectEntity.GetProjectRes) []*projectEntity.GetProjectRes {
+	var projects []*projectEntity.GetProjectRes
+	for _, root := range roots {
+		children := root.Children
+		root.Children = nil
+		projects = append(projects, root)
+		projects = append(projects, Flatten(children)...)
+	}
+	return projects
+}
+
+// Archive Project godoc
+// @Summary	Archive or restore a project
+// @Description	Archived projects and their tasks are hidden from the default lists but nothing is deleted. Only the owner can archive.
+// @Tags	Projects
+// @Produce	json
+// @Param	projectId	path	string	true	"Project Id"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/project/{projectId}/archive [post]
+// @Router	/project/{projectId}/unarchive [post]
+func (p *projectSrv) ArchiveProject(projectId, userId string, archive bool) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	if errRes := p.requireRole(ctx, projectId, userId, projectEntity.RoleOwner); errRes != nil {
+		return nil, errRes
+	}
+
+	archivedAt, message := "", "Project restored successfully"
+	if archive {
+		archivedAt, message = p.timeSrv.CurrentTimeString(), "Project archived successfully"
+	}
+	err := p.repo.SetArchived(ctx, projectId, archivedAt)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return ResponseEntity.BuildSuccessResponse(http.StatusOK, message, nil, nil), nil
+}
+
+// Save As Template godoc
+// @Summary	Save a project and its tasks as a template
+// @Description	Task times are kept relative to the day the earliest task starts, so the template can be started on any date
+// @Tags	Projects
+// @Accept	json
+// @Produce	json
+// @Param	projectId	path	string	true	"Project Id"
+// @Param	request	body	projectEntity.SaveTemplateReq	false	"Template title, defaults to the project title"
+// @Success	200  {object}  projectEntity.ProjectTemplate
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/project/{projectId}/template [post]
+func (p *projectSrv) SaveAsTemplate(req *projectEntity.SaveTemplateReq) (*projectEntity.ProjectTemplate, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	err := p.validationSrv.Validate(req)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewValidatingError("Bad Data Input")
+	}
+	if errRes := p.requireRole(ctx, req.ProjectId, req.UserId, projectEntity.RoleViewer); errRes != nil {
+		return nil, errRes
+	}
+
+	members, err := p.repo.GetMembers(ctx, req.ProjectId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	ownerId := req.UserId
+	for _, member := range members {
+		if member.Role == projectEntity.RoleOwner {
+			ownerId = member.UserId
+		}
+	}
+	project, err := p.repo.GetProject(ctx, req.ProjectId, ownerId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	source, err := p.repo.GetTemplateSource(ctx, req.ProjectId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	template := &projectEntity.ProjectTemplate{
+		TemplateId: uuid.New().String(),
+		UserId:     req.UserId,
+		Title:      req.Title,
+		Color:      project.Color,
+		CreatedAt:  p.timeSrv.CurrentTimeString(),
+		Tasks:      TemplateTasks(source),
+	}
+	if template.Title == "" {
+		template.Title = project.Title
+	}
+	err = p.repo.PersistTemplate(ctx, template)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return template, nil
+}
+
+// Get Templates godoc
+// @Summary	Get the project templates of the logged in user
+// @Description	Get templates route
+// @Tags	Projects
+// @Produce	json
+// @Success	200  {object}  []projectEntity.ProjectTemplate
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/project/templates [get]
+func (p *projectSrv) GetTemplates(userId string) ([]*projectEntity.ProjectTemplate, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	templates, err := p.repo.GetTemplates(ctx, userId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return templates, nil
+}
+
+// Instantiate Template godoc
+// @Summary	Create a new project from a template
+// @Description	Creates the project and its tasks, placing each task relative to the start date (midnight UTC)
+// @Tags	Projects
+// @Accept	json
+// @Produce	json
+// @Param	templateId	path	string	true	"Template Id"
+// @Param	request	body	projectEntity.InstantiateTemplateReq	true	"Start date and optional project title"
+// @Success	200  {object}  projectEntity.InstantiateTemplateRes
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/project/templates/{templateId}/instantiate [post]
+func (p *projectSrv) InstantiateTemplate(req *projectEntity.InstantiateTemplateReq) (*projectEntity.InstantiateTemplateRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	err := p.validationSrv.Validate(req)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewValidatingError("Bad Data Input")
+	}
+	startDate, _ := time.Parse("2006-01-02", req.StartDate)
+
+	template, err := p.repo.GetTemplate(ctx, req.TemplateId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	// someone else's template is reported as missing
+	if err != nil || template.UserId != req.UserId {
+		return nil, ResponseEntity.NewCustomServiceError(ErrNotFound, "template does not exist")
+	}
+
+	now := p.timeSrv.CurrentTimeString()
+	project := &projectEntity.CreateProjectReq{
+		ProjectId: uuid.New().String(),
+		Title:     req.Title,
+		Color:     template.Color,
+		UserId:    req.UserId,
+		CreatedAt: now,
+	}
+	if project.Title == "" {
+		project.Title = template.Title
+	}
+
+	res := &projectEntity.InstantiateTemplateRes{ProjectId: project.ProjectId, Title: project.Title, Color: project.Color, TaskIds: []string{}}
+	tasks := make([]*taskEntity.CreateTaskReq, len(template.Tasks))
+	for i, task := range template.Tasks {
+		start, end := ScheduleTask(task, startDate)
+		tasks[i] = &taskEntity.CreateTaskReq{
+			TaskId:      uuid.New().String(),
+			UserId:      req.UserId,
+			Title:       task.Title,
+			Description: task.Description,
+			Repeat:      task.Repeat,
+			StartTime:   start.Format(time.RFC3339),
+			EndTime:     end.Format(time.RFC3339),
+			ProjectId:   project.ProjectId,
+			Status:      "PENDING",
+			CreatedAt:   now,
+		}
+		res.TaskIds = append(res.TaskIds, tasks[i].TaskId)
+	}
+
+	err = p.repo.PersistProjectWithTasks(ctx, project, tasks)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return res, nil
+}
+
+// ComputeStats counts the open, completed and overdue tasks of each project and finds its next open deadline.
+func ComputeStats(summaries []*projectEntity.TaskSummary, now time.Time) map[string]projectEntity.ProjectStats {
+	stats := map[string]projectEntity.ProjectStats{}
+	next := map[string]time.Time{}
+	for _, task := range summaries {
+		stat := stats[task.ProjectId]
+		if task.Status == "COMPLETED" {
+			stat.CompletedTasks++
+			stats[task.ProjectId] = stat
+			continue
+		}
+		stat.OpenTasks++
+		if due, err := time.Parse(time.RFC3339, task.EndTime); err == nil {
+			if due.Before(now) {
+				stat.OverdueTasks++
+			} else if first, ok := next[task.ProjectId]; !ok || due.Before(first) {
+				next[task.ProjectId] = due
+				stat.NextDueDate = due.UTC().Format(time.RFC3339)
+			}
+		}
+		stats[task.ProjectId] = stat
+	}
+	return stats
+}
+
+// TemplateTasks turns a project's tasks into offsets from midnight UTC of the day the earliest task starts.
+func TemplateTasks(source []*projectEntity.TemplateSourceTask) []projectEntity.TemplateTask {
+	type timed struct {
+		task       *projectEntity.TemplateSourceTask
+		start, end time.Time
+	}
+	var tasks []timed
+	var anchor time.Time
+	for _, task := range source {
+		end, err := time.Parse(time.RFC3339, task.EndTime)
+		if err != nil {
+			continue
+		}
+		start, err := time.Parse(time.RFC3339, task.StartTime)
+		if err != nil || start.After(end) {
+			start = end
+		}
+		if anchor.IsZero() || start.Before(anchor) {
+			anchor = start
+		}
+		tasks = append(tasks, timed{task, start, end})
+	}
+	anchor = anchor.UTC().Truncate(24 * time.Hour)
+
+	sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].start.Before(tasks[j].start) })
+	res := make([]projectEntity.TemplateTask, 0, len(tasks))
+	for _, t := range tasks {
+		res = append(res, projectEntity.TemplateTask{
+			Title:              t.task.Title,
+			Description:        t.task.Description,
+			Repeat:             t.task.Repeat,
+			StartOffsetMinutes: int(t.start.Sub(anchor).Minutes()),
+			DueOffsetMinutes:   int(t.end.Sub(anchor).Minutes()),
+		})
+	}
+	return res
+}
+
+// ScheduleTask returns the start and due time of a template task started on startDate.
+func ScheduleTask(task projectEntity.TemplateTask, startDate time.Time) (time.Time, time.Time) {
+	day := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.UTC)
+	return day.Add(time.Duration(task.StartOffsetMinutes) * time.Minute), day.Add(time.Duration(task.DueOffsetMinutes) * time.Minute)
+}
+
+// Invite Member godoc
+// @Summary	Invite someone to a project by email
+// @Description	Only the owner can invite. The invitation is emailed and shows up for the invitee once they sign in with that email.
+// @Tags	Projects
+// @Accept	json
+// @Produce	json
+// @Param	projectId	path	string	true	"Project Id"
+// @Param	request	body	projectEntity.InviteMemberReq	true	"Email and role, editor or viewer"
+// @Success	200  {object}  projectEntity.Invitation
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/project/{projectId}/invitations [post]
+func (p *projectSrv) InviteMember(req *projectEntity.InviteMemberReq) (*projectEntity.Invitation, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	err := p.validationSrv.Validate(req)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewValidatingError("Bad Data Input")
+	}
+	if errRes := p.requireRole(ctx, req.ProjectId, req.UserId, projectEntity.RoleOwner); errRes != nil {
+		return nil, errRes
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	members, err := p.repo.GetMembers(ctx, req.ProjectId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	for _, member := range members {
+		if strings.EqualFold(member.Email, email) {
+			return nil, ResponseEntity.NewValidatingError("user is already a member of this project")
+		}
+	}
+
+	project, err := p.repo.GetProject(ctx, req.ProjectId, req.UserId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	invitation := &projectEntity.Invitation{
+		InvitationId: uuid.New().String(),
+		ProjectId:    req.ProjectId,
+		ProjectTitle: project.Title,
+		Email:        email,
+		Role:         req.Role,
+		InvitedBy:    req.UserId,
+		Status:       projectEntity.InvitationPending,
+		CreatedAt:    p.timeSrv.CurrentTimeString(),
+	}
+	err = p.repo.PersistInvitation(ctx, invitation)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	payload := eventEntity.Payload{
+		Action:    "email",
+		SubAction: "project_invitation",
+		Data: map[string]string{
+			"email_address": email,
+			"email_subject": fmt.Sprintf("Subject: You have been invited to %s on Ticked\n", project.Title),
+			"email_body": fmt.Sprintf("Hi,\n\nyou have been invited to join the project %s as %s.\n"+
+				"Sign in to Ticked with this email address to accept or decline the invitation.\n", project.Title, req.Role),
+		},
+	}
+	if err := p.emitter.Push(payload, "info"); err != nil {
+		log.Println(err)
+	}
+	return invitation, nil
+}
+
+// Get Invitations godoc
+// @Summary	Get the pending project invitations of the logged in user
+// @Description	Invitations sent to the email of the logged in user that have not been answered, the email has to be verified
+// @Tags	Projects
+// @Produce	json
+// @Success	200  {object}  []projectEntity.Invitation
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/project/invitations [get]
+func (p *projectSrv) GetInvitations(userId string) ([]*projectEntity.Invitation, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	email, errRes := p.verifiedEmail(ctx, userId)
+	if errRes != nil {
+		return nil, errRes
+	}
+	invitations, err := p.repo.GetPendingInvitations(ctx, strings.ToLower(email))
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return invitations, nil
+}
+
+// verifiedEmail is the address invitations are matched against, an address nobody proved to own matches nothing.
+func (p *projectSrv) verifiedEmail(ctx context.Context, userId string) (string, *ResponseEntity.ServiceError) {
+	email, err := p.repo.GetUserEmail(ctx, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ResponseEntity.NewCustomServiceError(ErrForbidden, "verify your email address to see the invitations sent to it")
+	}
+	if err != nil {
+		log.Println(err)
+		return "", ResponseEntity.NewInternalServiceError(err)
+	}
+	return email, nil
+}
+
+// Respond To Invitation godoc
+// @Summary	Accept or decline a project invitation
+// @Description	Only the user the invitation was sent to can answer it
+// @Tags	Projects
+// @Produce	json
+// @Param	invitationId	path	string	true	"Invitation Id"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/project/invitations/{invitationId}/accept [post]
+// @Router	/project/invitations/{invitationId}/decline [post]
+func (p *projectSrv) RespondToInvitation(invitationId, userId string, accept bool) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	invitation, err := p.repo.GetInvitation(ctx, invitationId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ResponseEntity.NewCustomServiceError(ErrNotFound, "invitation does not exist")
+		}
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	email, errRes := p.verifiedEmail(ctx, userId)
+	if errRes != nil {
+		return nil, errRes
+	}
+	if !strings.EqualFold(email, invitation.Email) {
+		return nil, ResponseEntity.NewCustomServiceError(ErrForbidden, "this invitation was sent to someone else")
+	}
+	if invitation.Status != projectEntity.InvitationPending {
+		return nil, ResponseEntity.NewValidatingError("invitation has already been answered")
+	}
+
+	now := p.timeSrv.CurrentTimeString()
+	if !accept {
+		err = p.repo.DeclineInvitation(ctx, invitationId, now)
+		if err != nil {
+			log.Println(err)
+			return nil, ResponseEntity.NewInternalServiceError(err)
+		}
+		return ResponseEntity.BuildSuccessResponse(http.StatusOK, "Invitation declined", nil, nil), nil
+	}
+
+	err = p.repo.AcceptInvitation(ctx, invitation, userId, now)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return ResponseEntity.BuildSuccessResponse(http.StatusOK, "Invitation accepted", invitation, nil), nil
+}
+
+// Get Members godoc
+// @Summary	Get the members of a project
+// @Description	Open to every member of the project
+// @Tags	Projects
+// @Produce	json
+// @Param	projectId	path	string	true	"Project Id"
+// @Success	200  {object}  []projectEntity.ProjectMember
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/project/{projectId}/members [get]
+func (p *projectSrv) GetMembers(projectId, userId string) ([]*projectEntity.ProjectMember, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	if errRes := p.requireRole(ctx, projectId, userId, projectEntity.RoleViewer); errRes != nil {
+		return nil, errRes
+	}
+	members, err := p.repo.GetMembers(ctx, projectId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return members, nil
+}
+
+// Update Member Role godoc
+// @Summary	Change the role of a project member
+// @Description	Only the owner can change roles, the owner role itself cannot be given away
+// @Tags	Projects
+// @Accept	json
+// @Produce	json
+// @Param	projectId	path	string	true	"Project Id"
+// @Param	memberId	path	string	true	"Member user Id"
+// @Param	request	body	projectEntity.UpdateMemberReq	true	"New role, editor or viewer"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/project/{projectId}/members/{memberId} [patch]
+func (p *projectSrv) UpdateMemberRole(req *projectEntity.UpdateMemberReq) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	err := p.validationSrv.Validate(req)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewValidatingError("Bad Data Input")
+	}
+	if errRes := p.requireRole(ctx, req.ProjectId, req.UserId, projectEntity.RoleOwner); errRes != nil {
+		return nil, errRes
+	}
+	if errRes := p.requireMember(ctx, req.ProjectId, req.MemberId); errRes != nil {
+		return nil, errRes
+	}
+
+	err = p.repo.UpdateMemberRole(ctx, req.ProjectId, req.MemberId, req.Role)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return ResponseEntity.BuildSuccessResponse(http.StatusOK, "Member updated successfully", req, nil), nil
+}
+
+// Remove Member godoc
+// @Summary	Remove a member from a project
+// @Description	The owner can remove anyone else, every other member can only remove themselves to leave the project
+// @Tags	Projects
+// @Produce	json
+// @Param	projectId	path	string	true	"Project Id"
+// @Param	memberId	path	string	true	"Member user Id"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/project/{projectId}/members/{memberId} [delete]
+func (p *projectSrv) RemoveMember(projectId, userId, memberId string) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	if memberId != userId {
+		if errRes := p.requireRole(ctx, projectId, userId, projectEntity.RoleOwner); errRes != nil {
+			return nil, errRes
+		}
+	}
+	if errRes := p.requireMember(ctx, projectId, memberId); errRes != nil {
+		return nil, errRes
+	}
+
+	err := p.repo.RemoveMember(ctx, projectId, memberId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return ResponseEntity.BuildSuccessResponse(http.StatusOK, "Member removed successfully", nil, nil), nil
+}
+
+// Get Sections godoc
+// @Summary	Get the sections of a project in order
+// @Tags	Projects
+// @Produce	json
+// @Param	projectId	path	string	true	"Project Id"
+// @Success	200  {object}  []projectEntity.Section
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/project/{projectId}/sections [get]
+func (p *projectSrv) GetSections(projectId, userId string) ([]*projectEntity.Section, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	if errRes := p.requireRole(ctx, projectId, userId, projectEntity.RoleViewer); errRes != nil {
+		return nil, errRes
+	}
+	sections, err := p.repo.GetSections(ctx, projectId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if sections == nil {
+		sections = []*projectEntity.Section{}
+	}
+	return sections, nil
+}
+
+// Create Section godoc
+// @Summary	Add a section to the end of a project
+// @Description	Owners and editors can manage sections
+// @Tags	Projects
+// @Accept	json
+// @Produce	json
+// @Param	projectId	path	string	true	"Project Id"
+// @Param	request	body	projectEntity.CreateSectionReq	true	"Section title"
+// @Success	200  {object}  projectEntity.Section
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/project/{projectId}/sections [post]
+func (p *projectSrv) CreateSection(req *projectEntity.CreateSectionReq) (*projectEntity.Section, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	err := p.validationSrv.Validate(req)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewValidatingError("Bad Data Input")
+	}
+	if errRes := p.requireRole(ctx, req.ProjectId, req.UserId, projectEntity.RoleEditor); errRes != nil {
+		return nil, errRes
+	}
+
+	section := &projectEntity.Section{
+		SectionId: uuid.New().String(),
+		ProjectId: req.ProjectId,
+		Title:     strings.TrimSpace(req.Title),
+		CreatedAt: p.timeSrv.CurrentTimeString(),
+	}
+	err = p.repo.PersistSection(ctx, section)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return section, nil
+}
+
+// Rename Section godoc
+// @Summary	Rename a section
+// @Tags	Projects
+// @Accept	json
+// @Produce	json
+// @Param	projectId	path	string	true	"Project Id"
+// @Param	sectionId	path	string	true	"Section Id"
+// @Param	request	body	projectEntity.RenameSectionReq	true	"New title"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/project/{projectId}/sections/{sectionId} [patch]
+func (p *projectSrv) RenameSection(req *projectEntity.RenameSectionReq) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	err := p.validationSrv.Validate(req)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewValidatingError("Bad Data Input")
+	}
+	if errRes := p.requireSection(ctx, req.ProjectId, req.SectionId, req.UserId); errRes != nil {
+		return nil, errRes
+	}
+
+	req.Title = strings.TrimSpace(req.Title)
+	err = p.repo.RenameSection(ctx, req.SectionId, req.Title)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return ResponseEntity.BuildSuccessResponse(http.StatusOK, "Section renamed successfully", req, nil), nil
+}
+
+// Reorder Sections godoc
+// @Summary	Change the order of a project's sections
+// @Description	section_ids must list every section of the project exactly once, in the new order
+// @Tags	Projects
+// @Accept	json
+// @Produce	json
+// @Param	projectId	path	string	true	"Project Id"
+// @Param	request	body	projectEntity.ReorderSectionsReq	true	"Section ids in order"
+// @Success	200  {object}  []projectEntity.Section
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/project/{projectId}/sections/order [put]
+func (p *projectSrv) ReorderSections(req *projectEntity.ReorderSectionsReq) ([]*projectEntity.Section, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	err := p.validationSrv.Validate(req)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewValidatingError("Bad Data Input")
+	}
+	if errRes := p.requireRole(ctx, req.ProjectId, req.UserId, projectEntity.RoleEditor); errRes != nil {
+		return nil, errRes
+	}
+
+	sections, err := p.repo.GetSections(ctx, req.ProjectId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if !SameSections(sections, req.SectionIds) {
+		return nil, ResponseEntity.NewValidatingError("section_ids must list every section of the project once")
+	}
+
+	err = p.repo.ReorderSections(ctx, req.ProjectId, req.SectionIds)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	position := make(map[string]int, len(req.SectionIds))
+	for i, sectionId := range req.SectionIds {
+		position[sectionId] = i
+	}
+	for _, section := range sections {
+		section.Position = position[section.SectionId]
+	}
+	sort.Slice(sections, func(i, j int) bool { return sections[i].Position < sections[j].Position })
+	return sections, nil
+}
+
+// SameSections reports whether sectionIds holds exactly the ids of sections.
+func SameSections(sections []*projectEntity.Section, sectionIds []string) bool {
+	if len(sections) != len(sectionIds) {
+		return false
+	}
+	ids := make(map[string]bool, len(sectionIds))
+	for _, sectionId := range sectionIds {
+		ids[sectionId] = true
+	}
+	for _, section := range sections {
+		if !ids[section.SectionId] {
+			return false
+		}
+	}
+	return true
+}
+
+// Delete Section godoc
+// @Summary	Delete a section
+// @Description	The section's tasks stay in the project without a section
+// @Tags	Projects
+// @Produce	json
+// @Param	projectId	path	string	true	"Project Id"
+// @Param	sectionId	path	string	true	"Section Id"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/project/{projectId}/sections/{sectionId} [delete]
+func (p *projectSrv) DeleteSection(projectId, sectionId, userId string) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	if errRes := p.requireSection(ctx, projectId, sectionId, userId); errRes != nil {
+		return nil, errRes
+	}
+	err := p.repo.DeleteSection(ctx, sectionId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return ResponseEntity.BuildSuccessResponse(http.StatusOK, "Section deleted successfully", nil, nil), nil
+}
+
+// requireSection fails unless the section belongs to the project and the user can edit it.
+func (p *projectSrv) requireSection(ctx context.Context, projectId, sectionId, userId string) *ResponseEntity.ServiceError {
+	if errRes := p.requireRole(ctx, projectId, userId, projectEntity.RoleEditor); errRes != nil {
+		return errRes
+	}
+	section, err := p.repo.GetSection(ctx, sectionId)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && section.ProjectId != projectId) {
+		return ResponseEntity.NewCustomServiceError(ErrNotFound, "section not found in this project")
+	}
+	if err != nil {
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	return nil
+}
+
+// requireRole fails unless the user has at least the given role on the project.
+func (p *projectSrv) requireRole(ctx context.Context, projectId, userId, role string) *ResponseEntity.ServiceError {
+	got, err := p.repo.GetMemberRole(ctx, projectId, userId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+
+	allowed := got == projectEntity.RoleOwner
+	switch role {
+	case projectEntity.RoleEditor:
+		allowed = CanWrite(got)
+	case projectEntity.RoleViewer:
+		allowed = CanRead(got)
+	}
+	if !allowed {
+		return ResponseEntity.NewCustomServiceError(ErrForbidden, "you do not have access to this project")
+	}
+	return nil
+}
+
+// requireMember fails unless memberId is a member of the project other than its owner.
+func (p *projectSrv) requireMember(ctx context.Context, projectId, memberId string) *ResponseEntity.ServiceError {
+	role, err := p.repo.GetMemberRole(ctx, projectId, memberId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ResponseEntity.NewCustomServiceError(ErrNotFound, "user is not a member of this project")
+	}
+	if err != nil {
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	if role == projectEntity.RoleOwner {
+		return ResponseEntity.NewValidatingError("the owner of a project cannot be changed or removed")
+	}
+	return nil
+}
+
+func NewProjectSrv(repo projectRepo.ProjectRepository, timeSrv timeSrv.TimeService, validationSrv validationService.ValidationSrv, logger loggerService.LogSrv, emitter Emitter.Emitter) ProjectService {
+	return &projectSrv{repo: repo, timeSrv: timeSrv, validationSrv: validationSrv, logger: logger, emitter: emitter}
 }
 
 func Check(req *projectEntity.EditProjectReq, color, title string) *projectEntity.EditProjectReq {
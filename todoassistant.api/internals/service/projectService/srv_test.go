@@ -0,0 +1,311 @@
+package projectService
+
+import (
+	"context"
+	"database/sql"
+	"test-va/internals/Repository/projectRepo"
+	"test-va/internals/entity/eventEntity"
+	"test-va/internals/entity/projectEntity"
+	"test-va/internals/service/timeSrv"
+	"test-va/internals/service/validationService"
+	"testing"
+	"time"
+)
+
+type memoryRepo struct {
+	projectRepo.ProjectRepository
+	owner       string
+	roles       map[string]string
+	emails      map[string]string
+	invitations map[string]*projectEntity.Invitation
+}
+
+func (m *memoryRepo) GetMemberRole(ctx context.Context, projectId, userId string) (string, error) {
+	if userId == m.owner {
+		return projectEntity.RoleOwner, nil
+	}
+	role, ok := m.roles[userId]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return role, nil
+}
+
+func (m *memoryRepo) GetMembers(ctx context.Context, projectId string) ([]*projectEntity.ProjectMember, error) {
+	members := []*projectEntity.ProjectMember{{UserId: m.owner, Email: m.emails[m.owner], Role: projectEntity.RoleOwner}}
+	for userId, role := range m.roles {
+		members = append(members, &projectEntity.ProjectMember{UserId: userId, Email: m.emails[userId], Role: role})
+	}
+	return members, nil
+}
+
+func (m *memoryRepo) GetProject(ctx context.Context, projectId, userId string) (*projectEntity.GetProjectRes, error) {
+	return &projectEntity.GetProjectRes{ProjectId: projectId, Title: "Groceries", UserId: m.owner}, nil
+}
+
+// GetUserEmail treats users without an email as unverified
+func (m *memoryRepo) GetUserEmail(ctx context.Context, userId string) (string, error) {
+	email, ok := m.emails[userId]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return email, nil
+}
+
+func (m *memoryRepo) PersistInvitation(ctx context.Context, invitation *projectEntity.Invitation) error {
+	m.invitations[invitation.InvitationId] = invitation
+	return nil
+}
+
+func (m *memoryRepo) GetInvitation(ctx context.Context, invitationId string) (*projectEntity.Invitation, error) {
+	invitation, ok := m.invitations[invitationId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return invitation, nil
+}
+
+func (m *memoryRepo) AcceptInvitation(ctx context.Context, invitation *projectEntity.Invitation, userId, acceptedAt string) error {
+	m.roles[userId] = invitation.Role
+	invitation.Status = projectEntity.InvitationAccepted
+	return nil
+}
+
+func (m *memoryRepo) RemoveMember(ctx context.Context, projectId, memberId string) error {
+	delete(m.roles, memberId)
+	return nil
+}
+
+type memoryEmitter struct {
+	payloads []eventEntity.Payload
+}
+
+func (m *memoryEmitter) Push(payload eventEntity.Payload, severity string) error {
+	m.payloads = append(m.payloads, payload)
+	return nil
+}
+
+type fixedTime struct {
+	timeSrv.TimeService
+}
+
+func (fixedTime) CurrentTimeString() string {
+	return time.Date(2023, 3, 7, 9, 0, 0, 0, time.UTC).Format(time.RFC3339)
+}
+
+func TestRoles(t *testing.T) {
+	tests := []struct {
+		role              string
+		canRead, canWrite bool
+	}{
+		{projectEntity.RoleOwner, true, true},
+		{projectEntity.RoleEditor, true, true},
+		{projectEntity.RoleViewer, true, false},
+		{"", false, false},
+	}
+	for _, tt := range tests {
+		if CanRead(tt.role) != tt.canRead || CanWrite(tt.role) != tt.canWrite {
+			t.Errorf("role %q: read/write = %v/%v, want %v/%v", tt.role, CanRead(tt.role), CanWrite(tt.role), tt.canRead, tt.canWrite)
+		}
+	}
+}
+
+func TestInvitationFlow(t *testing.T) {
+	repo := &memoryRepo{
+		owner:       "owner",
+		roles:       map[string]string{"viewer": projectEntity.RoleViewer},
+		emails:      map[string]string{"owner": "owner@example.com", "viewer": "viewer@example.com", "sam": "Sam@Example.com", "other": "other@example.com"},
+		invitations: map[string]*projectEntity.Invitation{},
+	}
+	emitter := &memoryEmitter{}
+	srv := NewProjectSrv(repo, fixedTime{}, validationService.NewValidationStruct(), nil, emitter)
+
+	req := &projectEntity.InviteMemberReq{ProjectId: "p1", UserId: "viewer", Email: "sam@example.com", Role: projectEntity.RoleEditor}
+	if _, errRes := srv.InviteMember(req); errRes == nil || errRes.Description != ErrForbidden {
+		t.Fatalf("InviteMember() by a viewer = %v, want forbidden", errRes)
+	}
+
+	req.UserId = "owner"
+	invitation, errRes := srv.InviteMember(req)
+	if errRes != nil {
+		t.Fatalf("InviteMember() error = %v", errRes)
+	}
+	if len(emitter.payloads) != 1 || emitter.payloads[0].Data["email_address"] != "sam@example.com" {
+		t.Errorf("invitation email not sent, got %+v", emitter.payloads)
+	}
+
+	req.Email = "viewer@example.com"
+	if _, errRes := srv.InviteMember(req); errRes == nil {
+		t.Error("InviteMember() for an existing member should fail")
+	}
+
+	if _, errRes := srv.RespondToInvitation(invitation.InvitationId, "unverified", true); errRes == nil || errRes.Description != ErrForbidden {
+		t.Errorf("RespondToInvitation() before verifying the email = %v, want forbidden", errRes)
+	}
+	if _, errRes := srv.RespondToInvitation(invitation.InvitationId, "other", true); errRes == nil || errRes.Description != ErrForbidden {
+		t.Errorf("RespondToInvitation() by someone else = %v, want forbidden", errRes)
+	}
+	if _, errRes := srv.RespondToInvitation(invitation.InvitationId, "sam", true); errRes != nil {
+		t.Fatalf("RespondToInvitation() error = %v", errRes)
+	}
+	if repo.roles["sam"] != projectEntity.RoleEditor {
+		t.Errorf("sam has role %q, want editor", repo.roles["sam"])
+	}
+	if _, errRes := srv.RespondToInvitation(invitation.InvitationId, "sam", false); errRes == nil {
+		t.Error("answering an invitation twice should fail")
+	}
+
+	// members can leave but only the owner removes others, and the owner cannot be removed
+	if _, errRes := srv.RemoveMember("p1", "sam", "viewer"); errRes == nil || errRes.Description != ErrForbidden {
+		t.Errorf("RemoveMember() by an editor = %v, want forbidden", errRes)
+	}
+	if _, errRes := srv.RemoveMember("p1", "owner", "owner"); errRes == nil {
+		t.Error("RemoveMember() of the owner should fail")
+	}
+	if _, errRes := srv.RemoveMember("p1", "sam", "sam"); errRes != nil {
+		t.Errorf("leaving a project error = %v", errRes)
+	}
+}
+
+func TestComputeStats(t *testing.T) {
+	now := time.Date(2023, 3, 7, 12, 0, 0, 0, time.UTC)
+	summaries := []*projectEntity.TaskSummary{
+		{ProjectId: "p1", Status: "COMPLETED", EndTime: "2023-03-01T12:00:00Z"},
+		{ProjectId: "p1", Status: "PENDING", EndTime: "2023-03-06T12:00:00Z"},
+		{ProjectId: "p1", Status: "PENDING", EndTime: "2023-03-10T12:00:00Z"},
+		{ProjectId: "p1", Status: "PENDING", EndTime: "2023-03-08T09:00:00+01:00"},
+		{ProjectId: "p2", Status: "COMPLETED", EndTime: "2023-03-10T12:00:00Z"},
+	}
+
+	stats := ComputeStats(summaries, now)
+	want := projectEntity.ProjectStats{OpenTasks: 3, CompletedTasks: 1, OverdueTasks: 1, NextDueDate: "2023-03-08T08:00:00Z"}
+	if stats["p1"] != want {
+		t.Errorf("p1 stats = %+v, want %+v", stats["p1"], want)
+	}
+	if got := stats["p2"]; got.CompletedTasks != 1 || got.OpenTasks != 0 || got.NextDueDate != "" {
+		t.Errorf("unexpected p2 stats %+v", got)
+	}
+}
+
+func TestTemplateRoundTrip(t *testing.T) {
+	source := []*projectEntity.TemplateSourceTask{
+		{Title: "Book venue", StartTime: "2023-03-08T09:00:00Z", EndTime: "2023-03-09T17:00:00Z"},
+		{Title: "Send invites", StartTime: "2023-03-06T10:30:00Z", EndTime: "2023-03-06T12:00:00Z"},
+		{Title: "No dates", StartTime: "", EndTime: "soon"},
+	}
+
+	tasks := TemplateTasks(source)
+	if len(tasks) != 2 || tasks[0].Title != "Send invites" {
+		t.Fatalf("unexpected template tasks %+v", tasks)
+	}
+	if tasks[0].StartOffsetMinutes != 630 || tasks[0].DueOffsetMinutes != 720 {
+		t.Errorf("first task offsets = %d/%d, want 630/720", tasks[0].StartOffsetMinutes, tasks[0].DueOffsetMinutes)
+	}
+
+	start, due := ScheduleTask(tasks[1], time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC))
+	if start.Format(time.RFC3339) != "2023-06-03T09:00:00Z" || due.Format(time.RFC3339) != "2023-06-04T17:00:00Z" {
+		t.Errorf("scheduled at %s to %s", start.Format(time.RFC3339), due.Format(time.RFC3339))
+	}
+}
+
+func TestBuildTreeAndFlatten(t *testing.T) {
+	projects := []*projectEntity.GetProjectRes{
+		{ProjectId: "launch", Title: "Q3 launch", ParentId: "client"},
+		{ProjectId: "work", Title: "Work"},
+		{ProjectId: "client", Title: "Client A", ParentId: "work"},
+		{ProjectId: "shared", Title: "Shared", ParentId: "hidden"},
+		{ProjectId: "home", Title: "Home"},
+	}
+
+	roots := BuildTree(projects)
+	if len(roots) != 3 || roots[0].ProjectId != "work" || roots[1].ProjectId != "shared" || roots[2].ProjectId != "home" {
+		t.Fatalf("unexpected roots %+v", roots)
+	}
+	if len(roots[0].Children) != 1 || len(roots[0].Children[0].Children) != 1 {
+		t.Fatalf("Work should hold Client A which holds Q3 launch, got %+v", roots[0].Children)
+	}
+
+	flat := Flatten(roots)
+	want := []struct {
+		id    string
+		depth int
+		path  string
+	}{
+		{"work", 0, "Work"},
+		{"client", 1, "Work / Client A"},
+		{"launch", 2, "Work / Client A / Q3 launch"},
+		{"shared", 0, "Shared"},
+		{"home", 0, "Home"},
+	}
+	if len(flat) != len(want) {
+		t.Fatalf("Flatten() returned %d projects, want %d", len(flat), len(want))
+	}
+	for i, w := range want {
+		if flat[i].ProjectId != w.id || flat[i].Depth != w.depth || flat[i].Path != w.path || flat[i].Children != nil {
+			t.Errorf("flat[%d] = %s depth %d path %q, want %s depth %d path %q", i, flat[i].ProjectId, flat[i].Depth, flat[i].Path, w.id, w.depth, w.path)
+		}
+	}
+}
+
+type treeRepo struct {
+	memoryRepo
+	parents map[string]string
+}
+
+func (m *treeRepo) GetParentId(ctx context.Context, projectId string) (string, error) {
+	parentId, ok := m.parents[projectId]
+	if !ok {
+		return "", sql.ErrNoRows
+	}
+	return parentId, nil
+}
+
+func (m *treeRepo) SetParent(ctx context.Context, projectId, parentId string) error {
+	m.parents[projectId] = parentId
+	return nil
+}
+
+func TestMoveProjectPreventsCycles(t *testing.T) {
+	repo := &treeRepo{
+		memoryRepo: memoryRepo{owner: "owner", roles: map[string]string{"editor": projectEntity.RoleEditor}},
+		parents:    map[string]string{"work": "", "client": "work", "launch": "client", "home": ""},
+	}
+	srv := NewProjectSrv(repo, fixedTime{}, validationService.NewValidationStruct(), nil, &memoryEmitter{})
+
+	for _, parentId := range []string{"work", "client", "launch"} {
+		_, errRes := srv.MoveProject(&projectEntity.MoveProjectReq{ProjectId: "work", UserId: "owner", ParentId: parentId})
+		if errRes == nil {
+			t.Errorf("moving work under %s should fail", parentId)
+		}
+	}
+	if _, errRes := srv.MoveProject(&projectEntity.MoveProjectReq{ProjectId: "launch", UserId: "editor", ParentId: "home"}); errRes == nil || errRes.Description != ErrForbidden {
+		t.Errorf("MoveProject() by an editor = %v, want forbidden", errRes)
+	}
+	if _, errRes := srv.MoveProject(&projectEntity.MoveProjectReq{ProjectId: "client", UserId: "owner", ParentId: "home"}); errRes != nil {
+		t.Fatalf("MoveProject() error = %v", errRes)
+	}
+	if _, errRes := srv.MoveProject(&projectEntity.MoveProjectReq{ProjectId: "home", UserId: "owner", ParentId: "work"}); errRes != nil {
+		t.Fatalf("MoveProject() error = %v", errRes)
+	}
+	if repo.parents["client"] != "home" || repo.parents["home"] != "work" {
+		t.Errorf("unexpected parents %v", repo.parents)
+	}
+}
+
+func TestSameSections(t *testing.T) {
+	sections := []*projectEntity.Section{{SectionId: "a"}, {SectionId: "b"}, {SectionId: "c"}}
+	tests := []struct {
+		ids  []string
+		want bool
+	}{
+		{[]string{"c", "a", "b"}, true},
+		{[]string{"a", "b"}, false},
+		{[]string{"a", "b", "d"}, false},
+		{[]string{"a", "b", "c", "a"}, false},
+	}
+	for _, tt := range tests {
+		if got := SameSections(sections, tt.ids); got != tt.want {
+			t.Errorf("SameSections(%v) = %v, want %v", tt.ids, got, tt.want)
+		}
+	}
+}
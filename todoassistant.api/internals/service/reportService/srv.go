@@ -0,0 +1,252 @@
+package reportService
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"test-va/internals/Repository/reportRepo"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/eventEntity"
+	"test-va/internals/entity/reportEntity"
+	"test-va/internals/msg-queue/Emitter"
+	"test-va/internals/service/timeSrv"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+type ReportService interface {
+	GetWeeklyReport(req *reportEntity.WeeklyReportReq) (*reportEntity.WeeklyReport, *ResponseEntity.ServiceError)
+	// SendWeeklyReports stores and emails last week's review to every user and VA.
+	SendWeeklyReports()
+}
+
+type reportSrv struct {
+	repo    reportRepo.ReportRepository
+	timeSrv timeSrv.TimeService
+	emitter Emitter.Emitter
+}
+
+func NewReportSrv(repo reportRepo.ReportRepository, timeSrv timeSrv.TimeService, emitter Emitter.Emitter) ReportService {
+	return &reportSrv{repo: repo, timeSrv: timeSrv, emitter: emitter}
+}
+
+// Get Weekly Report godoc
+// @Summary	Get the weekly review of the logged in user or VA
+// @Description	Completed work, slipped items, upcoming deadlines, time logged and comments awaiting a reply for one week. A VA's review covers all of their users.
+// @Tags	Reports
+// @Produce	json
+// @Param	week	query	string	false	"ISO week (2023-W10) or any date in the week (2023-03-08), defaults to last week"
+// @Success	200  {object}  reportEntity.WeeklyReport
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security BearerAuth
+// @Router	/reports/weekly [get]
+func (r *reportSrv) GetWeeklyReport(req *reportEntity.WeeklyReportReq) (*reportEntity.WeeklyReport, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	now := r.timeSrv.CurrentTime().UTC()
+	weekStart, err := ParseWeek(req.Week, now)
+	if err != nil {
+		return nil, ResponseEntity.NewValidatingError(err.Error())
+	}
+	if weekStart.After(now) {
+		return nil, ResponseEntity.NewValidatingError("week has not started yet")
+	}
+
+	report, err := r.repo.GetReport(ctx, req.OwnerId, weekStart.Format(dateLayout))
+	if err == nil {
+		return report, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	// nothing stored yet, this week or a week before reports existed
+	report, err = r.build(ctx, req.OwnerId, req.Role, weekStart, now)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return report, nil
+}
+
+func (r *reportSrv) SendWeeklyReports() {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*10)
+	defer cancelFunc()
+
+	owners, err := r.repo.GetOwners(ctx)
+	if err != nil {
+		log.Println("weekly report: could not get owners", err)
+		return
+	}
+
+	now := r.timeSrv.CurrentTime().UTC()
+	weekStart := StartOfWeek(now).AddDate(0, 0, -7)
+	for _, owner := range owners {
+		if err := r.send(ctx, owner, weekStart, now); err != nil {
+			log.Println("weekly report: could not send to", owner.Id, err)
+		}
+	}
+}
+
+func (r *reportSrv) send(ctx context.Context, owner *reportEntity.ReportOwner, weekStart, now time.Time) error {
+	report, err := r.build(ctx, owner.Id, owner.Role, weekStart, now)
+	if err != nil {
+		return err
+	}
+
+	// only the instance that stores the report sends it, so a rerun never mails twice
+	inserted, err := r.repo.SaveReport(ctx, report)
+	if err != nil || !inserted {
+		return err
+	}
+
+	body, err := RenderReport(owner.FirstName, report)
+	if err != nil {
+		return err
+	}
+	payload := eventEntity.Payload{
+		Action:    "email",
+		SubAction: "weekly_report",
+		Data: map[string]string{
+			"email_address": owner.Email,
+			"email_subject": fmt.Sprintf("Subject: Your Ticked week in review, %s to %s\nMIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n", report.WeekStart, report.WeekEnd),
+			"email_body":    body,
+		},
+	}
+	return r.emitter.Push(payload, "info")
+}
+
+func (r *reportSrv) build(ctx context.Context, ownerId, role string, weekStart, now time.Time) (*reportEntity.WeeklyReport, error) {
+	weekEnd := weekStart.AddDate(0, 0, 7)
+	tasks, err := r.repo.GetTasks(ctx, ownerId, role, weekStart.Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	minutes, err := r.repo.GetMinutesLogged(ctx, ownerId, role, weekStart.Format(time.RFC3339), weekEnd.Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	comments, err := r.repo.GetLatestComments(ctx, ownerId, role)
+	if err != nil {
+		return nil, err
+	}
+
+	report := BuildWeeklyReport(ownerId, role, tasks, comments, weekStart, now)
+	report.MinutesLogged = minutes
+	report.GeneratedAt = now.Format(time.RFC3339)
+	return report, nil
+}
+
+// BuildWeeklyReport sorts tasks into completed during the week, slipped (past their deadline and still open)
+// and upcoming (due within the next seven days), and keeps the comments the owner has not answered.
+func BuildWeeklyReport(ownerId, role string, tasks []*reportEntity.ReportTask, comments []*reportEntity.ReportComment, weekStart, now time.Time) *reportEntity.WeeklyReport {
+	weekEnd := weekStart.AddDate(0, 0, 7)
+	// for the current week deadlines only slip up to now
+	cutoff := weekEnd
+	if now.Before(cutoff) {
+		cutoff = now
+	}
+
+	report := &reportEntity.WeeklyReport{
+		OwnerId:       ownerId,
+		Role:          role,
+		WeekStart:     weekStart.Format(dateLayout),
+		WeekEnd:       weekEnd.AddDate(0, 0, -1).Format(dateLayout),
+		Completed:     []reportEntity.ReportTask{},
+		Slipped:       []reportEntity.ReportTask{},
+		Upcoming:      []reportEntity.ReportTask{},
+		AwaitingReply: []reportEntity.ReportComment{},
+	}
+	for _, task := range tasks {
+		if task.Status == "COMPLETED" {
+			completed, err := time.Parse(time.RFC3339, task.CompletedAt)
+			if err == nil && !completed.Before(weekStart) && completed.Before(weekEnd) {
+				report.Completed = append(report.Completed, *task)
+			}
+			continue
+		}
+		due, err := time.Parse(time.RFC3339, task.EndTime)
+		if err != nil {
+			continue
+		}
+		switch {
+		case due.Before(cutoff):
+			report.Slipped = append(report.Slipped, *task)
+		case due.Before(cutoff.AddDate(0, 0, 7)):
+			report.Upcoming = append(report.Upcoming, *task)
+		}
+	}
+	for _, comment := range comments {
+		if comment.SenderId != ownerId {
+			report.AwaitingReply = append(report.AwaitingReply, *comment)
+		}
+	}
+	return report
+}
+
+// ParseWeek returns the Monday the requested week starts on, in UTC.
+// An empty week is the last full week before now.
+func ParseWeek(week string, now time.Time) (time.Time, error) {
+	if week == "" {
+		return StartOfWeek(now).AddDate(0, 0, -7), nil
+	}
+
+	var year, number int
+	if n, err := fmt.Sscanf(week, "%d-W%d", &year, &number); err == nil && n == 2 {
+		if number < 1 || number > 53 {
+			return time.Time{}, fmt.Errorf("invalid week %s", week)
+		}
+		// the 4th of January is always in week one
+		start := StartOfWeek(time.Date(year, 1, 4, 0, 0, 0, 0, time.UTC)).AddDate(0, 0, (number-1)*7)
+		if y, _ := start.ISOWeek(); y != year {
+			return time.Time{}, fmt.Errorf("invalid week %s", week)
+		}
+		return start, nil
+	}
+
+	date, err := time.Parse(dateLayout, week)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid week %s, use 2006-W01 or 2006-01-02", week)
+	}
+	return StartOfWeek(date), nil
+}
+
+// StartOfWeek returns midnight UTC on the Monday of t's week.
+func StartOfWeek(t time.Time) time.Time {
+	t = t.UTC()
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC)
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<html><body style="font-family: sans-serif;">
+<h2>Hi{{if .FirstName}} {{.FirstName}}{{end}},</h2>
+<p>Here is your Ticked week in review for {{.Report.WeekStart}} to {{.Report.WeekEnd}}.</p>
+{{$va := eq .Report.Role "VA"}}
+<h3>Completed ({{len .Report.Completed}})</h3>{{if .Report.Completed}}<ul>{{range .Report.Completed}}<li>{{.Title}}{{if $va}} for {{.UserName}}{{end}}</li>{{end}}</ul>{{end}}
+{{if .Report.Slipped}}<h3>Slipped</h3><ul>{{range .Report.Slipped}}<li>{{.Title}}{{if $va}} for {{.UserName}}{{end}}, due {{.EndTime}}</li>{{end}}</ul>{{end}}
+{{if .Report.Upcoming}}<h3>Coming up</h3><ul>{{range .Report.Upcoming}}<li>{{.Title}}{{if $va}} for {{.UserName}}{{end}}, due {{.EndTime}}</li>{{end}}</ul>{{end}}
+<h3>Time logged</h3><p>{{.Hours}}h {{.Minutes}}m</p>
+{{if .Report.AwaitingReply}}<h3>Awaiting your reply</h3><ul>{{range .Report.AwaitingReply}}<li><i>{{.TaskTitle}}</i>: {{.Comment}}</li>{{end}}</ul>{{end}}
+</body></html>`))
+
+func RenderReport(firstName string, report *reportEntity.WeeklyReport) (string, error) {
+	var buf bytes.Buffer
+	err := reportTemplate.Execute(&buf, struct {
+		FirstName string
+		Report    *reportEntity.WeeklyReport
+		Hours     int
+		Minutes   int
+	}{firstName, report, report.MinutesLogged / 60, report.MinutesLogged % 60})
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
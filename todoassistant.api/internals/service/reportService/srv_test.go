@@ -0,0 +1,169 @@
+package reportService
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"test-va/internals/entity/eventEntity"
+	"test-va/internals/entity/reportEntity"
+	"test-va/internals/service/timeSrv"
+	"testing"
+	"time"
+)
+
+func TestParseWeek(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2023, 3, 8, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		week    string
+		want    string
+		wantErr bool
+	}{
+		{"", "2023-02-27", false},
+		{"2023-03-08", "2023-03-06", false},
+		{"2023-03-12", "2023-03-06", false},
+		{"2023-W10", "2023-03-06", false},
+		{"2021-W01", "2021-01-04", false},
+		{"2020-W53", "2020-12-28", false},
+		{"2023-W53", "", true},
+		{"2023-W00", "", true},
+		{"last week", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.week, func(t *testing.T) {
+			got, err := ParseWeek(tt.week, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWeek() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Format(dateLayout) != tt.want {
+				t.Errorf("ParseWeek() = %s, want %s", got.Format(dateLayout), tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildWeeklyReport(t *testing.T) {
+	weekStart := time.Date(2023, 3, 6, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2023, 3, 13, 6, 0, 0, 0, time.UTC)
+	tasks := []*reportEntity.ReportTask{
+		{TaskId: "1", Title: "Filed taxes", Status: "COMPLETED", CompletedAt: "2023-03-07T12:00:00Z"},
+		{TaskId: "2", Title: "Done last week", Status: "COMPLETED", CompletedAt: "2023-03-03T12:00:00Z"},
+		{TaskId: "3", Title: "Call bank", Status: "PENDING", EndTime: "2023-03-10T15:00:00Z"},
+		{TaskId: "4", Title: "Book flights", Status: "PENDING", EndTime: "2023-03-15T15:00:00Z"},
+		{TaskId: "5", Title: "Next month", Status: "PENDING", EndTime: "2023-04-15T15:00:00Z"},
+	}
+	comments := []*reportEntity.ReportComment{
+		{TaskId: "3", TaskTitle: "Call bank", SenderId: "va", Comment: "Which branch?"},
+		{TaskId: "4", TaskTitle: "Book flights", SenderId: "user", Comment: "Window seat please"},
+	}
+
+	report := BuildWeeklyReport("user", reportEntity.RoleUser, tasks, comments, weekStart, now)
+	if report.WeekEnd != "2023-03-12" {
+		t.Errorf("WeekEnd = %s, want 2023-03-12", report.WeekEnd)
+	}
+	if len(report.Completed) != 1 || report.Completed[0].TaskId != "1" {
+		t.Errorf("unexpected completed %+v", report.Completed)
+	}
+	if len(report.Slipped) != 1 || report.Slipped[0].TaskId != "3" {
+		t.Errorf("unexpected slipped %+v", report.Slipped)
+	}
+	if len(report.Upcoming) != 1 || report.Upcoming[0].TaskId != "4" {
+		t.Errorf("unexpected upcoming %+v", report.Upcoming)
+	}
+	if len(report.AwaitingReply) != 1 || report.AwaitingReply[0].SenderId != "va" {
+		t.Errorf("unexpected awaiting reply %+v", report.AwaitingReply)
+	}
+}
+
+type memoryRepo struct {
+	owners []*reportEntity.ReportOwner
+	tasks  []*reportEntity.ReportTask
+	saved  map[string]*reportEntity.WeeklyReport
+}
+
+func (m *memoryRepo) GetOwners(ctx context.Context) ([]*reportEntity.ReportOwner, error) {
+	return m.owners, nil
+}
+
+func (m *memoryRepo) GetTasks(ctx context.Context, ownerId, role, completedSince string) ([]*reportEntity.ReportTask, error) {
+	return m.tasks, nil
+}
+
+func (m *memoryRepo) GetMinutesLogged(ctx context.Context, ownerId, role, from, to string) (int, error) {
+	return 135, nil
+}
+
+func (m *memoryRepo) GetLatestComments(ctx context.Context, ownerId, role string) ([]*reportEntity.ReportComment, error) {
+	return nil, nil
+}
+
+func (m *memoryRepo) SaveReport(ctx context.Context, report *reportEntity.WeeklyReport) (bool, error) {
+	key := report.OwnerId + report.WeekStart
+	if _, ok := m.saved[key]; ok {
+		return false, nil
+	}
+	m.saved[key] = report
+	return true, nil
+}
+
+func (m *memoryRepo) GetReport(ctx context.Context, ownerId, weekStart string) (*reportEntity.WeeklyReport, error) {
+	report, ok := m.saved[ownerId+weekStart]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return report, nil
+}
+
+type memoryEmitter struct {
+	payloads []eventEntity.Payload
+}
+
+func (m *memoryEmitter) Push(payload eventEntity.Payload, severity string) error {
+	m.payloads = append(m.payloads, payload)
+	return nil
+}
+
+type fixedTime struct {
+	timeSrv.TimeService
+	now time.Time
+}
+
+func (f fixedTime) CurrentTime() time.Time    { return f.now }
+func (f fixedTime) CurrentTimeString() string { return f.now.Format(time.RFC3339) }
+
+func TestSendWeeklyReportsOncePerWeek(t *testing.T) {
+	repo := &memoryRepo{
+		owners: []*reportEntity.ReportOwner{
+			{Id: "user", Email: "user@example.com", FirstName: "Sam", Role: reportEntity.RoleUser},
+			{Id: "va", Email: "va@example.com", FirstName: "Ada", Role: reportEntity.RoleVA},
+		},
+		tasks: []*reportEntity.ReportTask{{Title: "Filed taxes", UserName: "Sam Doe", Status: "COMPLETED", CompletedAt: "2023-03-07T12:00:00Z"}},
+		saved: map[string]*reportEntity.WeeklyReport{},
+	}
+	emitter := &memoryEmitter{}
+	srv := NewReportSrv(repo, fixedTime{now: time.Date(2023, 3, 13, 6, 0, 0, 0, time.UTC)}, emitter)
+
+	srv.SendWeeklyReports()
+	srv.SendWeeklyReports()
+
+	if len(emitter.payloads) != 2 {
+		t.Fatalf("sent %d reports, want 2", len(emitter.payloads))
+	}
+	body := emitter.payloads[1].Data["email_body"]
+	for _, want := range []string{"Ada", "Filed taxes for Sam Doe", "2h 15m"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("report body is missing %q", want)
+		}
+	}
+
+	report, errRes := srv.GetWeeklyReport(&reportEntity.WeeklyReportReq{OwnerId: "va", Role: reportEntity.RoleVA, Week: "2023-W10"})
+	if errRes != nil {
+		t.Fatalf("GetWeeklyReport() error = %v", errRes)
+	}
+	if report != repo.saved["va2023-03-06"] {
+		t.Error("GetWeeklyReport() should return the stored report")
+	}
+	if _, errRes := srv.GetWeeklyReport(&reportEntity.WeeklyReportReq{OwnerId: "va", Week: "2023-03-20"}); errRes == nil {
+		t.Error("GetWeeklyReport() for a future week should fail")
+	}
+}
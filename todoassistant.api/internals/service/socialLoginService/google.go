@@ -0,0 +1,167 @@
+package socialLoginService
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	googleCertsURL = "https://www.googleapis.com/oauth2/v3/certs"
+	// certsLifetime is used when Google does not say how long to cache its keys
+	certsLifetime = time.Hour
+	// certsRefetchAfter stops tokens with unknown key ids from fetching the keys on every request
+	certsRefetchAfter = time.Minute
+)
+
+var (
+	googleIssuers = map[string]bool{"accounts.google.com": true, "https://accounts.google.com": true}
+	maxAge        = regexp.MustCompile(`max-age=(\d+)`)
+)
+
+type googleClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
+	jwt.RegisteredClaims
+}
+
+type googleVerifier struct {
+	clientId string
+	certsURL string
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	expiresAt time.Time
+	fetchedAt time.Time
+}
+
+// NewGoogleVerifier verifies Google ID tokens issued to clientId, against Google's signing keys which are cached.
+func NewGoogleVerifier(clientId string) IdentityVerifier {
+	return &googleVerifier{clientId: clientId, certsURL: googleCertsURL}
+}
+
+func (g *googleVerifier) Verify(ctx context.Context, idToken string) (*Identity, error) {
+	if g.clientId == "" {
+		return nil, ErrProviderNotConfigured
+	}
+
+	var claims googleClaims
+	_, err := jwt.ParseWithClaims(idToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodRS256 {
+			return nil, fmt.Errorf("%w: unexpected signing method %v", ErrInvalidToken, token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return g.key(ctx, kid)
+	})
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) && validationErr.Errors&jwt.ValidationErrorUnverifiable != 0 && validationErr.Inner != nil {
+		// the keys could not be fetched, or the token is not signed by Google
+		return nil, validationErr.Inner
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if !claims.VerifyAudience(g.clientId, true) || !googleIssuers[claims.Issuer] || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+
+	return &Identity{
+		Provider:      ProviderGoogle,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		FirstName:     claims.GivenName,
+		LastName:      claims.FamilyName,
+	}, nil
+}
+
+// key returns the signing key with kid, fetching the keys again when they expired or Google rotated them.
+func (g *googleVerifier) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	key, ok := g.keys[kid]
+	if ok && now.Before(g.expiresAt) {
+		return key, nil
+	}
+	if !ok && now.Before(g.expiresAt) && now.Sub(g.fetchedAt) < certsRefetchAfter {
+		return nil, fmt.Errorf("%w: unknown key id %q", ErrInvalidToken, kid)
+	}
+
+	keys, lifetime, err := fetchGoogleKeys(ctx, g.certsURL)
+	if err != nil {
+		return nil, err
+	}
+	g.keys, g.fetchedAt, g.expiresAt = keys, now, now.Add(lifetime)
+
+	key, ok = g.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key id %q", ErrInvalidToken, kid)
+	}
+	return key, nil
+}
+
+func fetchGoogleKeys(ctx context.Context, certsURL string) (map[string]*rsa.PublicKey, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, certsURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("fetching Google signing keys: %s", res.Status)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&jwks); err != nil {
+		return nil, 0, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, 0, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, 0, err
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	lifetime := certsLifetime
+	if match := maxAge.FindStringSubmatch(res.Header.Get("Cache-Control")); match != nil {
+		if seconds, err := strconv.Atoi(match[1]); err == nil {
+			lifetime = time.Duration(seconds) * time.Second
+		}
+	}
+	return keys, lifetime, nil
+}
@@ -1,142 +1,207 @@
 package socialLoginService
 
 import (
-	"strings"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
 	"test-va/internals/Repository/userRepo"
 	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/tokenEntity"
 	"test-va/internals/entity/userEntity"
+	"test-va/internals/service/loginAlertService"
 	"test-va/internals/service/timeSrv"
 	tokenservice "test-va/internals/service/tokenService"
+	"test-va/internals/service/twoFactorService"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	ErrUnauthorized = "Unauthorized"
+	ErrForbidden    = "Forbidden"
+)
+
 type LoginSrv interface {
 	LoginResponse(req *userEntity.GoogleLoginReq) (*userEntity.LoginRes, *ResponseEntity.ServiceError)
 	FacebookLoginResponse(req *userEntity.FacebookLoginReq) (*userEntity.LoginRes, *ResponseEntity.ServiceError)
 }
 
 type loginSrv struct {
-	repo     userRepo.UserRepository
-	timeSrv  timeSrv.TimeService
-	tokenSrv tokenservice.TokenSrv
+	repo         userRepo.UserRepository
+	timeSrv      timeSrv.TimeService
+	tokenSrv     tokenservice.TokenSrv
+	twoFactorSrv twoFactorService.TwoFactorSrv
+	loginAlert   loginAlertService.LoginAlertSrv
+	google       IdentityVerifier
+	facebook     IdentityVerifier
 }
 
 // Google login godoc
 // @Summary	Login user using google account
-// @Description	Google login route
+// @Description	Logs in with the ID token from Google Sign-In, an account is created for new users. With two-factor authentication on, no tokens are returned but a challenge token for /user/login/2fa.
 // @Tags	Social Login
 // @Accept	json
 // @Produce	json
 // @Param	request	body	userEntity.GoogleLoginReq	true "Google login"
 // @Success	200  {object}  userEntity.LoginRes
 // @Failure	400  {object}  ResponseEntity.ServiceError
-// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	401  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
 // @Failure	500  {object}  ResponseEntity.ServiceError
 // @Router	/googlelogin [post]
 func (l *loginSrv) LoginResponse(req *userEntity.GoogleLoginReq) (*userEntity.LoginRes, *ResponseEntity.ServiceError) {
-	user, _ := l.repo.GetByEmail(req.Email)
-	if user == nil {
-
-		resData := &userEntity.CreateUserReq{
-			UserId:        uuid.New().String(),
-			FirstName:     req.FirstName,
-			LastName:      req.LastName,
-			Email:         req.Email,
-			AccountStatus: "ACTIVE",
-			DateCreated:   l.timeSrv.CurrentTime().Format(time.RFC3339),
-		}
+	return l.login(l.google, req.IdToken, &req.SessionInfo)
+}
+
+// Facebook login godoc
+// @Summary	Login user using facebook account
+// @Description	Logs in with the user access token from Facebook Login, an account is created for new users. With two-factor authentication on, no tokens are returned but a challenge token for /user/login/2fa.
+// @Tags	Social Login
+// @Accept	json
+// @Produce	json
+// @Param	request	body	userEntity.FacebookLoginReq	true "Facebook login"
+// @Success	200  {object}  userEntity.LoginRes
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	401  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Router	/facebooklogin [post]
+func (l *loginSrv) FacebookLoginResponse(req *userEntity.FacebookLoginReq) (*userEntity.LoginRes, *ResponseEntity.ServiceError) {
+	return l.login(l.facebook, req.AccessToken, &req.SessionInfo)
+}
+
+func (l *loginSrv) login(verifier IdentityVerifier, token string, session *tokenEntity.SessionInfo) (*userEntity.LoginRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
 
-		err := l.repo.Persist(resData)
+	identity, err := verifier.Verify(ctx, token)
+	if errors.Is(err, ErrInvalidToken) {
+		return nil, ResponseEntity.NewCustomServiceError(ErrUnauthorized, "the login could not be verified with the provider")
+	}
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
 
+	user, errRes := l.linkedUser(identity)
+	if errRes != nil {
+		return nil, errRes
+	}
+	if user.AccountStatus == userEntity.AccountSuspended {
+		return nil, ResponseEntity.NewCustomServiceError(ErrForbidden, "this account is suspended, contact support")
+	}
+	if user.PasswordResetRequired {
+		return nil, ResponseEntity.NewCustomServiceError(ErrForbidden,
+			"a login to this account was reported as not yours, reset your password to log in again")
+	}
+
+	enabled, err := l.twoFactorSrv.IsEnabled(user.UserId)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if enabled {
+		challenge, err := l.twoFactorSrv.Challenge(user.UserId, "user", user.Email)
 		if err != nil {
 			return nil, ResponseEntity.NewInternalServiceError(err)
 		}
+		return &userEntity.LoginRes{UserId: user.UserId, Email: user.Email, TwoFactorRequired: true, ChallengeToken: challenge}, nil
 	}
 
-	user, _ = l.repo.GetByEmail(req.Email)
-	// tokenSrv := tokenservice.NewTokenSrv("fvmvmvmvf")
-
-	accessToken, refreshToken, err := l.tokenSrv.CreateToken(user.Email, "user", user.UserId)
-
+	accessToken, refreshToken, err := l.tokenSrv.CreateToken(user.UserId, "user", user.Email, session)
 	if err != nil {
 		return nil, ResponseEntity.NewInternalServiceError(err)
 	}
+	l.loginAlert.Notify(user.UserId, user.Email, user.FirstName, session)
 
 	loginUser := &userEntity.LoginRes{
-		UserId:       user.UserId,
-		Email:        user.Email,
-		FirstName:    user.FirstName,
-		LastName:     user.LastName,
-		Phone:        user.Phone,
-		Gender:       user.Gender,
-		Token:        accessToken,
-		RefreshToken: refreshToken,
+		UserId:        user.UserId,
+		Email:         user.Email,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Phone:         user.Phone,
+		Gender:        user.Gender,
+		Avatar:        user.Avatar,
+		EmailVerified: user.AccountStatus != userEntity.AccountUnverified,
+		Token:         accessToken,
+		RefreshToken:  refreshToken,
 	}
 
 	return loginUser, nil
 }
 
-// Facebook login godoc
-// @Summary	Login user using facebook account
-// @Description	Facebook login route
-// @Tags	Social Login
-// @Accept	json
-// @Produce	json
-// @Param	request	body	userEntity.FacebookLoginReq	true "Facebook login"
-// @Success	200  {object}  userEntity.LoginRes
-// @Failure	400  {object}  ResponseEntity.ServiceError
-// @Failure	404  {object}  ResponseEntity.ServiceError
-// @Failure	500  {object}  ResponseEntity.ServiceError
-// @Router	/facebooklogin [post]
-func (l *loginSrv) FacebookLoginResponse(req *userEntity.FacebookLoginReq) (*userEntity.LoginRes, *ResponseEntity.ServiceError) {
-	user, _ := l.repo.GetByEmail(req.Email)
-	name := strings.Split(req.Name, " ")
-
-	firstName := name[0]
-	lastName := name[1]
-
-	if user == nil {
-		resData := &userEntity.CreateUserReq{
-			UserId:        uuid.New().String(),
-			FirstName:     firstName,
-			LastName:      lastName,
-			Email:         req.Email,
-			AccountStatus: "ACTIVE",
-			DateCreated:   l.timeSrv.CurrentTime().Format(time.RFC3339),
+// linkedUser returns the user the identity is linked to. An identity seen for the first time is linked
+// to the user with its email, or to a new user, but only when the provider verified the email.
+func (l *loginSrv) linkedUser(identity *Identity) (*userEntity.GetByIdRes, *ResponseEntity.ServiceError) {
+	linked, err := l.repo.GetSocialIdentity(identity.Provider, identity.Subject)
+	if err == nil {
+		user, err := l.repo.GetById(linked.UserId)
+		if err != nil {
+			log.Println(err)
+			return nil, ResponseEntity.NewInternalServiceError(err)
 		}
+		return user, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
 
-		err := l.repo.Persist(resData)
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ResponseEntity.NewCustomServiceError(ErrForbidden, "the provider did not share a verified email for this account")
+	}
 
+	var userId string
+	existing, err := l.repo.GetByEmail(identity.Email)
+	switch {
+	case err == nil:
+		// whoever signed up with the email never proved they own it, so the account is not theirs to take over
+		if existing.AccountStatus == userEntity.AccountUnverified {
+			return nil, ResponseEntity.NewCustomServiceError(ErrForbidden,
+				"an account with this email is waiting for verification, verify the email or log in with the password first")
+		}
+		userId = existing.UserId
+	case errors.Is(err, sql.ErrNoRows):
+		userId = uuid.New().String()
+		err = l.repo.Persist(&userEntity.CreateUserReq{
+			UserId:        userId,
+			FirstName:     identity.FirstName,
+			LastName:      identity.LastName,
+			Email:         identity.Email,
+			AccountStatus: userEntity.AccountActive,
+			DateCreated:   l.timeSrv.CurrentTime().Format(time.RFC3339),
+		})
 		if err != nil {
+			log.Println(err)
 			return nil, ResponseEntity.NewInternalServiceError(err)
 		}
+	default:
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
 	}
 
-	user, _ = l.repo.GetByEmail(req.Email)
-	// tokenSrv := tokenservice.NewTokenSrv("fvmvmvmvf")
-
-	accessToken, refreshToken, err := l.tokenSrv.CreateToken(user.Email, "user", user.UserId)
-
+	err = l.repo.LinkSocialIdentity(&userEntity.SocialIdentity{
+		Provider:  identity.Provider,
+		Subject:   identity.Subject,
+		UserId:    userId,
+		Email:     identity.Email,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	})
 	if err != nil {
+		log.Println(err)
 		return nil, ResponseEntity.NewInternalServiceError(err)
 	}
 
-	loginUser := &userEntity.LoginRes{
-		UserId:       user.UserId,
-		Email:        user.Email,
-		FirstName:    user.FirstName,
-		LastName:     user.LastName,
-		Phone:        user.Phone,
-		Gender:       user.Gender,
-		Token:        accessToken,
-		RefreshToken: refreshToken,
+	user, err := l.repo.GetById(userId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
 	}
-
-	return loginUser, nil
+	return user, nil
 }
 
-func NewLoginSrv(repo userRepo.UserRepository, timeSrv timeSrv.TimeService, tokenSrv tokenservice.TokenSrv) LoginSrv {
-	return &loginSrv{repo, timeSrv, tokenSrv}
+func NewLoginSrv(repo userRepo.UserRepository, timeSrv timeSrv.TimeService, tokenSrv tokenservice.TokenSrv,
+	twoFactorSrv twoFactorService.TwoFactorSrv, loginAlert loginAlertService.LoginAlertSrv, google, facebook IdentityVerifier) LoginSrv {
+	return &loginSrv{repo, timeSrv, tokenSrv, twoFactorSrv, loginAlert, google, facebook}
 }
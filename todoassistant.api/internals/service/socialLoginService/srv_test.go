@@ -0,0 +1,267 @@
+package socialLoginService
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"test-va/internals/Repository/userRepo"
+	"test-va/internals/entity/tokenEntity"
+	"test-va/internals/entity/userEntity"
+	"test-va/internals/service/loginAlertService"
+	"test-va/internals/service/timeSrv"
+	tokenservice "test-va/internals/service/tokenService"
+	"test-va/internals/service/twoFactorService"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+type memoryRepo struct {
+	userRepo.UserRepository
+	users      map[string]*userEntity.GetByIdRes
+	identities map[string]*userEntity.SocialIdentity
+}
+
+func newMemoryRepo(users ...*userEntity.GetByIdRes) *memoryRepo {
+	repo := &memoryRepo{users: map[string]*userEntity.GetByIdRes{}, identities: map[string]*userEntity.SocialIdentity{}}
+	for _, user := range users {
+		repo.users[user.UserId] = user
+	}
+	return repo
+}
+
+func (m *memoryRepo) GetById(userId string) (*userEntity.GetByIdRes, error) {
+	user, ok := m.users[userId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	stored := *user
+	return &stored, nil
+}
+
+func (m *memoryRepo) GetByEmail(email string) (*userEntity.GetByEmailRes, error) {
+	for _, user := range m.users {
+		if strings.EqualFold(user.Email, email) {
+			return &userEntity.GetByEmailRes{UserId: user.UserId, Email: user.Email, AccountStatus: user.AccountStatus}, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryRepo) Persist(req *userEntity.CreateUserReq) error {
+	m.users[req.UserId] = &userEntity.GetByIdRes{UserId: req.UserId, Email: req.Email,
+		FirstName: req.FirstName, LastName: req.LastName, AccountStatus: req.AccountStatus}
+	return nil
+}
+
+func (m *memoryRepo) GetSocialIdentity(provider, subject string) (*userEntity.SocialIdentity, error) {
+	identity, ok := m.identities[provider+"/"+subject]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return identity, nil
+}
+
+func (m *memoryRepo) LinkSocialIdentity(req *userEntity.SocialIdentity) error {
+	m.identities[req.Provider+"/"+req.Subject] = req
+	return nil
+}
+
+// stubVerifier stands in for a provider, it knows the identity behind each token.
+type stubVerifier map[string]*Identity
+
+func (s stubVerifier) Verify(ctx context.Context, token string) (*Identity, error) {
+	identity, ok := s[token]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return identity, nil
+}
+
+// recordingTokens remembers who tokens were created for.
+type recordingTokens struct {
+	tokenservice.TokenSrv
+	created []string
+}
+
+func (r *recordingTokens) CreateToken(id, status, email string, session *tokenEntity.SessionInfo) (string, string, error) {
+	r.created = append(r.created, id+" "+status+" "+email)
+	return "access", "refresh", nil
+}
+
+type noAlerts struct {
+	loginAlertService.LoginAlertSrv
+}
+
+func (noAlerts) Notify(userId, email, firstName string, session *tokenEntity.SessionInfo) {}
+
+type noTwoFactor struct {
+	twoFactorService.TwoFactorSrv
+}
+
+func (noTwoFactor) IsEnabled(accountId string) (bool, error) {
+	return false, nil
+}
+
+func TestSocialLogin(t *testing.T) {
+	repo := newMemoryRepo(
+		&userEntity.GetByIdRes{UserId: "u1", Email: "sam@example.com", AccountStatus: userEntity.AccountActive},
+		&userEntity.GetByIdRes{UserId: "u2", Email: "squatted@example.com", AccountStatus: userEntity.AccountUnverified},
+	)
+	google := stubVerifier{
+		"sam":      {Provider: ProviderGoogle, Subject: "g-sam", Email: "sam@example.com", EmailVerified: true},
+		"new":      {Provider: ProviderGoogle, Subject: "g-new", Email: "new@example.com", EmailVerified: true, FirstName: "New", LastName: "User"},
+		"squatted": {Provider: ProviderGoogle, Subject: "g-squat", Email: "squatted@example.com", EmailVerified: true},
+		"unproven": {Provider: ProviderGoogle, Subject: "g-unproven", Email: "other@example.com"},
+	}
+	tokenSrv := &recordingTokens{}
+	srv := NewLoginSrv(repo, timeSrv.NewTimeStruct(), tokenSrv, noTwoFactor{}, noAlerts{}, google, stubVerifier{})
+
+	if _, errRes := srv.LoginResponse(&userEntity.GoogleLoginReq{IdToken: "forged"}); errRes == nil || errRes.Description != ErrUnauthorized {
+		t.Errorf("login with a forged token = %v, want unauthorized", errRes)
+	}
+	if _, errRes := srv.LoginResponse(&userEntity.GoogleLoginReq{IdToken: "unproven"}); errRes == nil || errRes.Description != ErrForbidden {
+		t.Errorf("login with an unverified provider email = %v, want forbidden", errRes)
+	}
+	if _, errRes := srv.LoginResponse(&userEntity.GoogleLoginReq{IdToken: "squatted"}); errRes == nil || errRes.Description != ErrForbidden {
+		t.Errorf("login into an unverified account = %v, want forbidden", errRes)
+	}
+
+	res, errRes := srv.LoginResponse(&userEntity.GoogleLoginReq{IdToken: "sam"})
+	if errRes != nil {
+		t.Fatalf("LoginResponse() error = %v", errRes)
+	}
+	if res.UserId != "u1" {
+		t.Errorf("logged in as %s, want the account with the email", res.UserId)
+	}
+	if created := tokenSrv.created[len(tokenSrv.created)-1]; created != "u1 user sam@example.com" {
+		t.Errorf("token created for %q", created)
+	}
+
+	// the identity stays linked when the email at the provider changes
+	google["sam"].Email = "sam@elsewhere.com"
+	if res, errRes := srv.LoginResponse(&userEntity.GoogleLoginReq{IdToken: "sam"}); errRes != nil || res.UserId != "u1" {
+		t.Errorf("login after the provider email changed = %+v, %v", res, errRes)
+	}
+
+	res, errRes = srv.LoginResponse(&userEntity.GoogleLoginReq{IdToken: "new"})
+	if errRes != nil {
+		t.Fatalf("LoginResponse() for a new user error = %v", errRes)
+	}
+	if user := repo.users[res.UserId]; user == nil || user.FirstName != "New" || user.AccountStatus != userEntity.AccountActive {
+		t.Errorf("new user = %+v", user)
+	}
+
+	// a linked identity cannot get around a reset asked for by "this wasn't me"
+	repo.users["u1"].PasswordResetRequired = true
+	if _, errRes := srv.LoginResponse(&userEntity.GoogleLoginReq{IdToken: "sam"}); errRes == nil || errRes.Description != ErrForbidden {
+		t.Errorf("login while a password reset is required = %v, want forbidden", errRes)
+	}
+}
+
+func TestGoogleVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kid": "k1",
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	defer server.Close()
+
+	verifier := &googleVerifier{clientId: "client", certsURL: server.URL}
+	sign := func(kid string, claims googleClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	valid := googleClaims{
+		Email:         "sam@example.com",
+		EmailVerified: true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "https://accounts.google.com",
+			Subject:   "123",
+			Audience:  jwt.ClaimStrings{"client"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+
+	identity, err := verifier.Verify(context.Background(), sign("k1", valid))
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if identity.Subject != "123" || identity.Email != "sam@example.com" || !identity.EmailVerified {
+		t.Errorf("Verify() = %+v", identity)
+	}
+
+	otherApp := valid
+	otherApp.Audience = jwt.ClaimStrings{"someone-else"}
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	for name, token := range map[string]string{
+		"other audience": sign("k1", otherApp),
+		"expired":        sign("k1", expired),
+		"unknown key":    sign("k2", valid),
+	} {
+		if _, err := verifier.Verify(context.Background(), token); err == nil {
+			t.Errorf("Verify() with %s should fail", name)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("keys fetched %d times, want them cached", fetches)
+	}
+}
+
+func TestFacebookVerifier(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/debug_token":
+			if r.URL.Query().Get("access_token") != "app|secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			appId := "app"
+			if r.URL.Query().Get("input_token") == "other-app" {
+				appId = "other"
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{
+				"app_id": appId, "is_valid": true, "user_id": "fb1",
+			}})
+		case "/me":
+			json.NewEncoder(w).Encode(map[string]string{"id": "fb1", "email": "sam@example.com", "first_name": "Sam"})
+		}
+	}))
+	defer server.Close()
+
+	verifier := &facebookVerifier{appId: "app", appSecret: "secret", graphURL: server.URL}
+	identity, err := verifier.Verify(context.Background(), "user-token")
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if identity.Subject != "fb1" || identity.FirstName != "Sam" || identity.LastName != "" {
+		t.Errorf("Verify() = %+v", identity)
+	}
+	if _, err := verifier.Verify(context.Background(), "other-app"); err != ErrInvalidToken {
+		t.Errorf("Verify() with a token for another app = %v, want invalid", err)
+	}
+}
@@ -0,0 +1,36 @@
+package socialLoginService
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const (
+	ProviderGoogle   = "google"
+	ProviderFacebook = "facebook"
+)
+
+var (
+	ErrInvalidToken          = errors.New("invalid identity token")
+	ErrProviderNotConfigured = errors.New("login provider is not configured")
+)
+
+// Identity is who a login provider says signed in.
+type Identity struct {
+	Provider string
+	// Subject is the provider's id for the account, it never changes unlike the email
+	Subject       string
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+}
+
+// IdentityVerifier checks a token issued by a login provider and returns the identity it was issued for.
+type IdentityVerifier interface {
+	Verify(ctx context.Context, token string) (*Identity, error)
+}
+
+var httpClient = &http.Client{Timeout: time.Second * 10}
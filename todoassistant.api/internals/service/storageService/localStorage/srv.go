@@ -0,0 +1,269 @@
+package localStorage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"test-va/internals/service/storageService"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid or expired signature")
+	ErrInvalidKey       = errors.New("invalid object key")
+	ErrSizeMismatch     = errors.New("uploaded size does not match the signed size")
+	ErrTypeMismatch     = errors.New("content type does not match the signed type")
+)
+
+// LocalStorage keeps objects on disk and hands out HMAC signed urls that are served
+// by the api itself. It stands in for S3 in development and in tests.
+type LocalStorage interface {
+	storageService.StorageSrv
+	Verify(method, key string, query url.Values) error
+	Save(key string, query url.Values, contentType string, body io.Reader) error
+	Open(key string) (*os.File, string, error)
+}
+
+type localStorage struct {
+	dir     string
+	baseUrl string
+	secret  []byte
+	now     func() time.Time
+}
+
+type objectMeta struct {
+	MimeType string `json:"mime_type"`
+	FileName string `json:"file_name"`
+}
+
+func NewLocalStorage(dir, baseUrl, secret string) LocalStorage {
+	return &localStorage{dir: dir, baseUrl: strings.TrimRight(baseUrl, "/"), secret: []byte(secret), now: time.Now}
+}
+
+func (l *localStorage) PresignUpload(key, mimeType string, size int64, checksum string, expiry time.Duration) (*storageService.PresignedRequest, error) {
+	if _, err := l.objectPath(key); err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	query.Set("type", mimeType)
+	query.Set("size", strconv.FormatInt(size, 10))
+	query.Set("expires", strconv.FormatInt(l.now().Add(expiry).Unix(), 10))
+	query.Set("sig", l.sign(http.MethodPut, key, query))
+
+	return &storageService.PresignedRequest{
+		Url:     fmt.Sprintf("%s/%s?%s", l.baseUrl, key, query.Encode()),
+		Method:  http.MethodPut,
+		Headers: map[string]string{"Content-Type": mimeType},
+	}, nil
+}
+
+func (l *localStorage) PresignDownload(key, fileName string, expiry time.Duration) (*storageService.PresignedRequest, error) {
+	if _, err := l.objectPath(key); err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(l.now().Add(expiry).Unix(), 10))
+	query.Set("sig", l.sign(http.MethodGet, key, query))
+
+	return &storageService.PresignedRequest{
+		Url:    fmt.Sprintf("%s/%s?%s", l.baseUrl, key, query.Encode()),
+		Method: http.MethodGet,
+	}, nil
+}
+
+func (l *localStorage) Stat(key string) (*storageService.ObjectInfo, error) {
+	p, err := l.objectPath(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, storageService.ErrObjectNotFound
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := l.readMeta(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &storageService.ObjectInfo{
+		Size:     size,
+		MimeType: meta.MimeType,
+		Checksum: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+func (l *localStorage) Delete(key string) error {
+	p, err := l.objectPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(p + ".meta"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (l *localStorage) Put(key, mimeType string, body io.ReadSeeker) error {
+	p, err := l.objectPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, body)
+	file.Close()
+	if err != nil {
+		os.Remove(p)
+		return err
+	}
+
+	meta, err := json.Marshal(objectMeta{MimeType: mimeType})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p+".meta", meta, 0o644)
+}
+
+func (l *localStorage) Get(key string) (io.ReadCloser, error) {
+	file, _, err := l.Open(key)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Verify checks the signature and expiry of a url produced by PresignUpload or PresignDownload.
+func (l *localStorage) Verify(method, key string, query url.Values) error {
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if err != nil || l.now().Unix() > expires {
+		return ErrInvalidSignature
+	}
+	expected := l.sign(method, key, query)
+	if !hmac.Equal([]byte(expected), []byte(query.Get("sig"))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Save stores the body of a signed upload, enforcing the signed type and size.
+func (l *localStorage) Save(key string, query url.Values, contentType string, body io.Reader) error {
+	if err := l.Verify(http.MethodPut, key, query); err != nil {
+		return err
+	}
+	if contentType != query.Get("type") {
+		return ErrTypeMismatch
+	}
+	size, err := strconv.ParseInt(query.Get("size"), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	p, err := l.objectPath(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	written, err := io.Copy(file, io.LimitReader(body, size+1))
+	file.Close()
+	if err == nil && written != size {
+		err = ErrSizeMismatch
+	}
+	if err != nil {
+		os.Remove(p)
+		return err
+	}
+
+	meta, err := json.Marshal(objectMeta{MimeType: contentType})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p+".meta", meta, 0o644)
+}
+
+// Open returns the stored object and its content type. The caller must Verify the url first.
+func (l *localStorage) Open(key string) (*os.File, string, error) {
+	p, err := l.objectPath(key)
+	if err != nil {
+		return nil, "", err
+	}
+	file, err := os.Open(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", storageService.ErrObjectNotFound
+		}
+		return nil, "", err
+	}
+	meta, err := l.readMeta(p)
+	if err != nil {
+		file.Close()
+		return nil, "", err
+	}
+	return file, meta.MimeType, nil
+}
+
+func (l *localStorage) sign(method, key string, query url.Values) string {
+	mac := hmac.New(sha256.New, l.secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s", method, key, query.Get("expires"), query.Get("type"), query.Get("size"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (l *localStorage) objectPath(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned != "/"+key || strings.HasSuffix(key, ".meta") {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(cleaned)), nil
+}
+
+func (l *localStorage) readMeta(p string) (*objectMeta, error) {
+	var meta objectMeta
+	data, err := os.ReadFile(p + ".meta")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &meta, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
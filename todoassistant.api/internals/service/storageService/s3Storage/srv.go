@@ -0,0 +1,126 @@
+package s3Storage
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"test-va/internals/service/storageService"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+type s3Storage struct {
+	s3session *s3.S3
+	bucket    string
+}
+
+func NewS3Storage(s *s3.S3, bucket string) storageService.StorageSrv {
+	return &s3Storage{s3session: s, bucket: bucket}
+}
+
+// PresignUpload signs the content type, length and sha256 checksum into the url,
+// so S3 itself rejects an upload that does not match what was declared.
+func (s *s3Storage) PresignUpload(key, mimeType string, size int64, checksum string, expiry time.Duration) (*storageService.PresignedRequest, error) {
+	sum, err := hex.DecodeString(checksum)
+	if err != nil {
+		return nil, err
+	}
+	encodedSum := base64.StdEncoding.EncodeToString(sum)
+
+	req, _ := s.s3session.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:         aws.String(s.bucket),
+		Key:            aws.String(key),
+		ContentType:    aws.String(mimeType),
+		ContentLength:  aws.Int64(size),
+		ChecksumSHA256: aws.String(encodedSum),
+	})
+	url, err := req.Presign(expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &storageService.PresignedRequest{
+		Url:    url,
+		Method: http.MethodPut,
+		Headers: map[string]string{
+			"Content-Type":          mimeType,
+			"x-amz-checksum-sha256": encodedSum,
+		},
+	}, nil
+}
+
+func (s *s3Storage) PresignDownload(key, fileName string, expiry time.Duration) (*storageService.PresignedRequest, error) {
+	req, _ := s.s3session.GetObjectRequest(&s3.GetObjectInput{
+		Bucket:                     aws.String(s.bucket),
+		Key:                        aws.String(key),
+		ResponseContentDisposition: aws.String(fmt.Sprintf("attachment; filename=%q", fileName)),
+	})
+	url, err := req.Presign(expiry)
+	if err != nil {
+		return nil, err
+	}
+	return &storageService.PresignedRequest{Url: url, Method: http.MethodGet}, nil
+}
+
+func (s *s3Storage) Stat(key string) (*storageService.ObjectInfo, error) {
+	out, err := s.s3session.HeadObject(&s3.HeadObjectInput{
+		Bucket:       aws.String(s.bucket),
+		Key:          aws.String(key),
+		ChecksumMode: aws.String(s3.ChecksumModeEnabled),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NotFound" {
+			return nil, storageService.ErrObjectNotFound
+		}
+		return nil, err
+	}
+
+	info := &storageService.ObjectInfo{
+		Size:     aws.Int64Value(out.ContentLength),
+		MimeType: aws.StringValue(out.ContentType),
+	}
+	if out.ChecksumSHA256 != nil {
+		sum, err := base64.StdEncoding.DecodeString(*out.ChecksumSHA256)
+		if err == nil {
+			info.Checksum = hex.EncodeToString(sum)
+		}
+	}
+	return info, nil
+}
+
+func (s *s3Storage) Delete(key string) error {
+	_, err := s.s3session.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+func (s *s3Storage) Put(key, mimeType string, body io.ReadSeeker) error {
+	_, err := s.s3session.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(mimeType),
+		Body:        body,
+	})
+	return err
+}
+
+func (s *s3Storage) Get(key string) (io.ReadCloser, error) {
+	out, err := s.s3session.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, storageService.ErrObjectNotFound
+		}
+		return nil, err
+	}
+	return out.Body, nil
+}
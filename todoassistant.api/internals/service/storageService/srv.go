@@ -0,0 +1,34 @@
+package storageService
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrObjectNotFound = errors.New("object not found")
+
+// PresignedRequest is what a client needs to talk to the storage backend directly.
+type PresignedRequest struct {
+	Url     string
+	Method  string
+	Headers map[string]string
+}
+
+type ObjectInfo struct {
+	Size     int64
+	MimeType string
+	// Checksum is the hex encoded sha256 of the object, empty when the backend cannot tell.
+	Checksum string
+}
+
+type StorageSrv interface {
+	PresignUpload(key, mimeType string, size int64, checksum string, expiry time.Duration) (*PresignedRequest, error)
+	PresignDownload(key, fileName string, expiry time.Duration) (*PresignedRequest, error)
+	Stat(key string) (*ObjectInfo, error)
+	Delete(key string) error
+	// Put stores an object the api made itself, such as a data export.
+	Put(key, mimeType string, body io.ReadSeeker) error
+	// Get reads an object, the caller closes it.
+	Get(key string) (io.ReadCloser, error)
+}
@@ -3,17 +3,21 @@ package taskService
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
+	"test-va/internals/Repository/projectRepo"
 	"test-va/internals/Repository/taskRepo"
 	"test-va/internals/entity/ResponseEntity"
 	"test-va/internals/entity/notificationEntity"
 	"test-va/internals/entity/taskEntity"
 	"test-va/internals/entity/vaEntity"
+	"test-va/internals/service/analyticsService"
 	"test-va/internals/service/loggerService"
 	"test-va/internals/service/notificationService"
+	"test-va/internals/service/projectService"
 	"test-va/internals/service/reminderService"
 	"test-va/internals/service/timeSrv"
 	"test-va/internals/service/validationService"
@@ -28,12 +32,14 @@ type TaskService interface {
 	SearchTask(req *taskEntity.SearchTitleParams) ([]*taskEntity.SearchTaskRes, *ResponseEntity.ServiceError)
 	GetListOfExpiredTasks() ([]*taskEntity.GetAllExpiredRes, *ResponseEntity.ServiceError)
 	GetListOfPendingTasks() ([]*taskEntity.GetAllPendingRes, *ResponseEntity.ServiceError)
-	DeleteTaskByID(taskId string) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError)
+	DeleteTaskByID(taskId, userId string) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError)
 	GetAllTask(userId string) ([]*taskEntity.GetAllTaskRes, *ResponseEntity.ServiceError)
-	GetTaskByID(taskId string) (*taskEntity.GetTasksByIdRes, *ResponseEntity.ServiceError)
+	GetTaskByID(taskId, userId string) (*taskEntity.GetTasksByIdRes, *ResponseEntity.ServiceError)
+	GetProjectTasks(projectId, userId string) ([]*taskEntity.GetAllTaskRes, *ResponseEntity.ServiceError)
 	DeleteAllTask(userId string) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError)
-	UpdateTaskStatusByID(taskId string, req *taskEntity.UpdateTaskStatus) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError)
-	EditTaskByID(taskId string, req *taskEntity.EditTaskReq) (*taskEntity.EditTaskRes, *ResponseEntity.ServiceError)
+	UpdateTaskStatusByID(taskId, userId string, req *taskEntity.UpdateTaskStatus) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError)
+	EditTaskByID(taskId, userId string, req *taskEntity.EditTaskReq) (*taskEntity.EditTaskRes, *ResponseEntity.ServiceError)
+	SetTaskSection(taskId, userId string, req *taskEntity.TaskSectionReq) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError)
 
 	GetVADetails(userId string) (string, *ResponseEntity.ServiceError)
 	AssignTaskToVA(req *taskEntity.AssignReq) *ResponseEntity.ServiceError
@@ -42,11 +48,19 @@ type TaskService interface {
 
 	//comments
 	PersistComment(req *taskEntity.CreateCommentReq) (*taskEntity.CreateCommentRes, *ResponseEntity.ServiceError)
-	GetAllComments(taskId string) ([]*taskEntity.GetCommentRes, *ResponseEntity.ServiceError)
+	GetAllComments(taskId, userId string) ([]*taskEntity.GetCommentRes, *ResponseEntity.ServiceError)
 	DeleteCommentByID(commentId string) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError)
 	GetComments() ([]*taskEntity.GetCommentRes, *ResponseEntity.ServiceError)
+
+	//time log
+	LogTime(req *taskEntity.LogTimeReq) (*taskEntity.LogTimeReq, *ResponseEntity.ServiceError)
 }
 
+const (
+	ErrNotFound  = "Task Not Found"
+	ErrForbidden = "Forbidden"
+)
+
 type taskSrv struct {
 	repo          taskRepo.TaskRepository
 	timeSrv       timeSrv.TimeService
@@ -54,14 +68,19 @@ type taskSrv struct {
 	logger        loggerService.LogSrv
 	remindSrv     reminderService.ReminderSrv
 	nSrv          notificationService.NotificationSrv
+	analyticsSrv  analyticsService.AnalyticsService
+	projectRepo   projectRepo.ProjectRepository
 }
 
 func NewTaskSrv(repo taskRepo.TaskRepository, timeSrv timeSrv.TimeService,
 	srv validationService.ValidationSrv, logSrv loggerService.LogSrv,
 	reminderSrv reminderService.ReminderSrv,
-	notificationSrv notificationService.NotificationSrv) TaskService {
+	notificationSrv notificationService.NotificationSrv,
+	analyticsSrv analyticsService.AnalyticsService,
+	projectRepo projectRepo.ProjectRepository) TaskService {
 	return &taskSrv{repo: repo, timeSrv: timeSrv, validationSrv: srv,
-		logger: logSrv, remindSrv: reminderSrv, nSrv: notificationSrv}
+		logger: logSrv, remindSrv: reminderSrv, nSrv: notificationSrv, analyticsSrv: analyticsSrv,
+		projectRepo: projectRepo}
 }
 
 // Get Tasks Assigned To VA godoc
@@ -154,6 +173,7 @@ func (t *taskSrv) AssignTaskToVA(req *taskEntity.AssignReq) *ResponseEntity.Serv
 		log.Println(" error here 2", err)
 		return ResponseEntity.NewInternalServiceError(err)
 	}
+	t.analyticsSrv.Invalidate(req.UserId)
 
 	// t.nSrv.SendNotificationToVA(req.UserId, "Task Assigned", fmt.Sprintf("%s Just Assigned a Task to You", req.UserId), data)
 
@@ -228,6 +248,16 @@ func (t *taskSrv) PersistTask(req *taskEntity.CreateTaskReq) (*taskEntity.Create
 		return nil, ResponseEntity.NewValidatingError("Bad Data Input")
 	}
 
+	// adding to a shared project needs editor rights on it
+	if req.ProjectId != "" {
+		if errRes := t.authorizeProject(ctx, req.ProjectId, req.UserId, true); errRes != nil {
+			return nil, errRes
+		}
+	}
+	if errRes := t.checkSection(ctx, req.ProjectId, req.SectionId); errRes != nil {
+		return nil, errRes
+	}
+
 	//set time
 	req.CreatedAt = t.timeSrv.CurrentTimeString()
 	req.UpdatedAt = t.timeSrv.CurrentTimeString()
@@ -337,6 +367,7 @@ func (t *taskSrv) PersistTask(req *taskEntity.CreateTaskReq) (*taskEntity.Create
 		TaskFeatures:  features,
 		CreatedAt:     req.CreatedAt,
 		ProjectId:     req.ProjectId,
+		SectionId:     req.SectionId,
 		ScheduledDate: req.ScheduledDate,
 	}
 
@@ -389,6 +420,8 @@ func (t *taskSrv) PersistTask(req *taskEntity.CreateTaskReq) (*taskEntity.Create
 		}
 
 	}
+	t.analyticsSrv.Invalidate(req.UserId)
+	t.notifyMembers(ctx, req.ProjectId, req.TaskId, req.UserId, "Task Created", fmt.Sprintf("%s was added to a shared project", req.Title))
 
 	return &data, nil
 }
@@ -396,7 +429,7 @@ func (t *taskSrv) PersistTask(req *taskEntity.CreateTaskReq) (*taskEntity.Create
 // Search task by name
 // Search task godoc
 // @Summary	Search task by title
-// @Description	Search task route
+// @Description	Searches the tasks of the user by the start of their title
 // @Tags	Tasks
 // @Accept	json
 // @Produce	json
@@ -438,22 +471,45 @@ func (t *taskSrv) SearchTask(title *taskEntity.SearchTitleParams) ([]*taskEntity
 // @Failure	500  {object}  ResponseEntity.ServiceError
 // @Security ApiKeyAuth
 // @Router	/task/{taskId} [get]
-func (t *taskSrv) GetTaskByID(taskId string) (*taskEntity.GetTasksByIdRes, *ResponseEntity.ServiceError) {
+func (t *taskSrv) GetTaskByID(taskId, userId string) (*taskEntity.GetTasksByIdRes, *ResponseEntity.ServiceError) {
 	// create context of 1 minute
 	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
 	defer cancelFunc()
 
-	task, err := t.repo.GetTaskByID(ctx, taskId)
+	task, errRes := t.getAuthorizedTask(ctx, taskId, userId, false)
+	if errRes != nil {
+		return nil, errRes
+	}
+	log.Println("From getByID", task)
+	return task, nil
+}
 
-	if task == nil {
-		log.Println("no rows returned")
+// Get Project Tasks godoc
+// @Summary	Get all tasks in a project
+// @Description	Open to the owner and every member of the project
+// @Tags	Tasks
+// @Accept	json
+// @Produce	json
+// @Param	projectId	path	string	true	"Project Id"
+// @Success	200  {object}  []taskEntity.GetAllTaskRes
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/task/project/{projectId} [get]
+func (t *taskSrv) GetProjectTasks(projectId, userId string) ([]*taskEntity.GetAllTaskRes, *ResponseEntity.ServiceError) {
+	// create context of 1 minute
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	if errRes := t.authorizeProject(ctx, projectId, userId, false); errRes != nil {
+		return nil, errRes
 	}
+	tasks, err := t.repo.GetProjectTasks(ctx, projectId)
 	if err != nil {
 		log.Println(err)
 		return nil, ResponseEntity.NewInternalServiceError(err)
 	}
-	log.Println("From getByID", task)
-	return task, nil
+	return tasks, nil
 }
 
 // Get Expired Tasks godoc
@@ -599,16 +655,23 @@ func (t *taskSrv) GetAllTask(userId string) ([]*taskEntity.GetAllTaskRes, *Respo
 // @Failure	500  {object}  ResponseEntity.ServiceError
 // @Security ApiKeyAuth
 // @Router	/task/{taskId} [delete]
-func (t *taskSrv) DeleteTaskByID(taskId string) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError) {
+func (t *taskSrv) DeleteTaskByID(taskId, userId string) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError) {
 	// create context of 1 minute
 	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
 	defer cancelFunc()
 
+	task, errRes := t.getAuthorizedTask(ctx, taskId, userId, true)
+	if errRes != nil {
+		return nil, errRes
+	}
+
+	t.analyticsSrv.Invalidate(task.UserId)
 	err := t.repo.DeleteTaskByID(ctx, taskId)
 	if err != nil {
 		log.Println(err)
 		return nil, ResponseEntity.NewInternalServiceError(err)
 	}
+	t.notifyMembers(ctx, task.ProjectId, taskId, userId, "Task Deleted", fmt.Sprintf("%s was deleted", task.Title))
 	return ResponseEntity.BuildSuccessResponse(http.StatusOK, "Deleted successfully", nil, nil), nil
 }
 
@@ -634,6 +697,7 @@ func (t *taskSrv) DeleteAllTask(userId string) (*ResponseEntity.ResponseMessage,
 		log.Println(err)
 		return nil, ResponseEntity.NewInternalServiceError(err)
 	}
+	t.analyticsSrv.Invalidate(userId)
 	return ResponseEntity.BuildSuccessResponse(http.StatusOK, "deleted user successfully", nil, nil), nil
 
 }
@@ -653,7 +717,7 @@ func (t *taskSrv) DeleteAllTask(userId string) (*ResponseEntity.ResponseMessage,
 // @Failure	500  {object}  ResponseEntity.ServiceError
 // @Security ApiKeyAuth
 // @Router	/task/{taskId}/status [post]
-func (t *taskSrv) UpdateTaskStatusByID(taskId string, req *taskEntity.UpdateTaskStatus) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError) {
+func (t *taskSrv) UpdateTaskStatusByID(taskId, userId string, req *taskEntity.UpdateTaskStatus) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError) {
 	// create context of 1 minute
 	//validating the struct
 	err := t.validationSrv.Validate(req)
@@ -664,11 +728,19 @@ func (t *taskSrv) UpdateTaskStatusByID(taskId string, req *taskEntity.UpdateTask
 	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
 	defer cancelFunc()
 
+	task, errRes := t.getAuthorizedTask(ctx, taskId, userId, true)
+	if errRes != nil {
+		return nil, errRes
+	}
+
+	req.UpdatedAt = t.timeSrv.CurrentTimeString()
 	err = t.repo.UpdateTaskStatusByID(ctx, taskId, req)
 	if err != nil {
 		log.Println(err)
 		return nil, ResponseEntity.NewInternalServiceError(err)
 	}
+	t.analyticsSrv.Invalidate(task.UserId)
+	t.notifyMembers(ctx, task.ProjectId, taskId, userId, "Task Updated", fmt.Sprintf("%s was marked %s", task.Title, strings.ToLower(req.Status)))
 	return ResponseEntity.BuildSuccessResponse(http.StatusOK, "Updated status successfully", nil, nil), nil
 
 }
@@ -687,7 +759,7 @@ func (t *taskSrv) UpdateTaskStatusByID(taskId string, req *taskEntity.UpdateTask
 // @Failure	500  {object}  ResponseEntity.ServiceError
 // @Security ApiKeyAuth
 // @Router	/task/{taskId} [put]
-func (t *taskSrv) EditTaskByID(taskId string, req *taskEntity.EditTaskReq) (*taskEntity.EditTaskRes, *ResponseEntity.ServiceError) {
+func (t *taskSrv) EditTaskByID(taskId, userId string, req *taskEntity.EditTaskReq) (*taskEntity.EditTaskRes, *ResponseEntity.ServiceError) {
 	// create context of 1 minute
 	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
 	defer cancelFunc()
@@ -700,14 +772,15 @@ func (t *taskSrv) EditTaskByID(taskId string, req *taskEntity.EditTaskReq) (*tas
 	}
 
 	// Get task by ID
-	task, err := t.repo.GetTaskByID(ctx, taskId)
-	if task == nil {
-		return nil, ResponseEntity.NewInternalServiceError("No task with that ID")
+	task, errRes := t.getAuthorizedTask(ctx, taskId, userId, true)
+	if errRes != nil {
+		return nil, errRes
 	}
-
-	if err != nil {
-		log.Println(err)
-		return nil, ResponseEntity.NewInternalServiceError(err)
+	// moving the task into another shared project needs editor rights there too
+	if req.ProjectId != "" && req.ProjectId != task.ProjectId {
+		if errRes := t.authorizeProject(ctx, req.ProjectId, userId, true); errRes != nil {
+			return nil, errRes
+		}
 	}
 
 	req1 := t.updateTask(req, task)
@@ -799,6 +872,7 @@ func (t *taskSrv) EditTaskByID(taskId string, req *taskEntity.EditTaskReq) (*tas
 		ProjectId:     req1.ProjectId,
 		ScheduledDate: req1.ScheduledDate,
 		Files:         req1.Files,
+		Labels:        req.Labels,
 		Assigned:      req1.Assigned,
 		UpdatedAt:     req1.UpdatedAt,
 		Status:        req1.Status,
@@ -842,6 +916,8 @@ func (t *taskSrv) EditTaskByID(taskId string, req *taskEntity.EditTaskReq) (*tas
 		log.Println(err, "error updating data")
 		return nil, ResponseEntity.NewInternalServiceError(err)
 	}
+	t.analyticsSrv.Invalidate(task.UserId)
+	t.notifyMembers(ctx, data.ProjectId, taskId, userId, "Task Updated", fmt.Sprintf("%s was updated", data.Title))
 
 	// updateAt := t.timeSrv.CurrentTime().Format(time.RFC3339)
 	// ndate := &taskEntity.CreateTaskReq{
@@ -964,6 +1040,12 @@ func (t *taskSrv) PersistComment(req *taskEntity.CreateCommentReq) (*taskEntity.
 		return nil, ResponseEntity.NewValidatingError("Bad Data Input")
 	}
 
+	// viewers of a shared project may comment too
+	task, errRes := t.getAuthorizedTask(ctx, req.TaskId, req.SenderId, false)
+	if errRes != nil {
+		return nil, errRes
+	}
+
 	//set time
 	req.CreatedAt = t.timeSrv.CurrentTimeString() // Format(time.RFC3339)
 
@@ -973,6 +1055,9 @@ func (t *taskSrv) PersistComment(req *taskEntity.CreateCommentReq) (*taskEntity.
 		log.Println(err)
 		return nil, ResponseEntity.NewInternalServiceError(err)
 	}
+	// a VA reply changes the owner's turnaround figures
+	t.analyticsSrv.Invalidate(task.UserId)
+	t.notifyMembers(ctx, task.ProjectId, task.TaskId, req.SenderId, "New Comment", fmt.Sprintf("New comment on %s", task.Title))
 	data := taskEntity.CreateCommentRes{
 		TaskId:  req.TaskId,
 		Comment: req.Comment,
@@ -995,10 +1080,14 @@ func (t *taskSrv) PersistComment(req *taskEntity.CreateCommentReq) (*taskEntity.
 // @Failure	500  {object}  ResponseEntity.ServiceError
 // @Security ApiKeyAuth
 // @Router	/comment/{taskId} [get]
-func (t *taskSrv) GetAllComments(taskId string) ([]*taskEntity.GetCommentRes, *ResponseEntity.ServiceError) {
+func (t *taskSrv) GetAllComments(taskId, userId string) ([]*taskEntity.GetCommentRes, *ResponseEntity.ServiceError) {
 	// create context of 1 minute
 	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
 	defer cancelFunc()
+
+	if _, errRes := t.getAuthorizedTask(ctx, taskId, userId, false); errRes != nil {
+		return nil, errRes
+	}
 	comments, err := t.repo.GetAllComments(ctx, taskId)
 
 	if comments == nil {
@@ -1067,6 +1156,176 @@ func (t *taskSrv) DeleteCommentByID(commentId string) (*ResponseEntity.ResponseM
 	return ResponseEntity.BuildSuccessResponse(http.StatusOK, "Deleted successfully", nil, nil), nil
 }
 
+// Log time on a task godoc
+// @Summary	Log time spent on a task
+// @Description	Log time route, open to the owner of the task, their VA and editors of its project
+// @Tags	Tasks
+// @Accept	json
+// @Produce	json
+// @Param	taskId	path	string	true	"Task Id"
+// @Param	request	body	taskEntity.LogTimeReq	true	"Minutes spent"
+// @Success	200  {object}  taskEntity.LogTimeReq
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/task/{taskId}/time [post]
+func (t *taskSrv) LogTime(req *taskEntity.LogTimeReq) (*taskEntity.LogTimeReq, *ResponseEntity.ServiceError) {
+	// create context of 1 minute
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	err := t.validationSrv.Validate(req)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewValidatingError("Bad Data Input")
+	}
+
+	if _, errRes := t.getAuthorizedTask(ctx, req.TaskId, req.UserId, true); errRes != nil {
+		return nil, errRes
+	}
+
+	req.LoggedAt = t.timeSrv.CurrentTimeString()
+	err = t.repo.PersistTimeLog(ctx, req)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return req, nil
+}
+
+// Set Task Section godoc
+// @Summary	Move a task into a section of its project
+// @Description	The section must belong to the task's project, an empty section_id takes the task out of its section
+// @Tags	Tasks
+// @Accept	json
+// @Produce	json
+// @Param	taskId	path	string	true	"Task Id"
+// @Param	request	body	taskEntity.TaskSectionReq	true	"Section Id"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/task/{taskId}/section [patch]
+func (t *taskSrv) SetTaskSection(taskId, userId string, req *taskEntity.TaskSectionReq) (*ResponseEntity.ResponseMessage, *ResponseEntity.ServiceError) {
+	// create context of 1 minute
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	task, errRes := t.getAuthorizedTask(ctx, taskId, userId, true)
+	if errRes != nil {
+		return nil, errRes
+	}
+	if errRes := t.checkSection(ctx, task.ProjectId, req.SectionId); errRes != nil {
+		return nil, errRes
+	}
+
+	err := t.repo.SetSection(ctx, taskId, req.SectionId)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return ResponseEntity.BuildSuccessResponse(http.StatusOK, "Task moved successfully", req, nil), nil
+}
+
+// checkSection fails unless sectionId is empty or a section of the project.
+func (t *taskSrv) checkSection(ctx context.Context, projectId, sectionId string) *ResponseEntity.ServiceError {
+	if sectionId == "" {
+		return nil
+	}
+	section, err := t.projectRepo.GetSection(ctx, sectionId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	if err != nil || projectId == "" || section.ProjectId != projectId {
+		return ResponseEntity.NewValidatingError("section does not belong to the task's project")
+	}
+	return nil
+}
+
+// getAuthorizedTask loads a task and fails unless userId may read it, or with write also change it.
+// The owner and their VA always can, members of a shared project according to their role.
+func (t *taskSrv) getAuthorizedTask(ctx context.Context, taskId, userId string, write bool) (*taskEntity.GetTasksByIdRes, *ResponseEntity.ServiceError) {
+	task, err := t.repo.GetTaskByID(ctx, taskId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ResponseEntity.NewCustomServiceError(ErrNotFound, "No task with that ID")
+		}
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if task.UserId == userId || (task.VaId != "" && task.VaId == userId) {
+		return task, nil
+	}
+	if task.ProjectId == "" {
+		return nil, ResponseEntity.NewCustomServiceError(ErrForbidden, "you do not have access to this task")
+	}
+	if errRes := t.authorizeProject(ctx, task.ProjectId, userId, write); errRes != nil {
+		return nil, errRes
+	}
+	return task, nil
+}
+
+// authorizeProject fails unless userId is a member of the project with a role allowing the access.
+func (t *taskSrv) authorizeProject(ctx context.Context, projectId, userId string, write bool) *ResponseEntity.ServiceError {
+	role, err := t.projectRepo.GetMemberRole(ctx, projectId, userId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	allowed := projectService.CanRead(role)
+	if write {
+		allowed = projectService.CanWrite(role)
+	}
+	if !allowed {
+		return ResponseEntity.NewCustomServiceError(ErrForbidden, "you do not have access to this project")
+	}
+	return nil
+}
+
+// notifyMembers tells everyone on a shared project, except whoever made the change, that one of its tasks changed.
+func (t *taskSrv) notifyMembers(ctx context.Context, projectId, taskId, actorId, title, content string) {
+	if projectId == "" {
+		return
+	}
+	members, err := t.projectRepo.GetMembers(ctx, projectId)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	// a project nobody else has joined is not shared
+	if len(members) < 2 {
+		return
+	}
+
+	body := []notificationEntity.NotificationBody{
+		{
+			Content: content,
+			Color:   notificationEntity.CreatedColor,
+			Time:    t.timeSrv.CurrentTimeString(),
+		},
+	}
+	data := map[string]string{"task_id": taskId, "project_id": projectId}
+	for _, member := range members {
+		if member.UserId == actorId {
+			continue
+		}
+		err := t.nSrv.CreateNotification(member.UserId, title, t.timeSrv.CurrentTimeString(), content, notificationEntity.CreatedColor, taskId)
+		if err != nil {
+			fmt.Println("Error Uploading Notification to DB", err)
+		}
+		tokens, _, err := t.nSrv.GetUserToken(member.UserId)
+		if err != nil || len(tokens) == 0 {
+			continue
+		}
+		if err := t.nSrv.SendBatchNotifications(tokens, title, body, data); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
 // Auxillary function
 func (t *taskSrv) updateTask(req *taskEntity.EditTaskReq, task *taskEntity.GetTasksByIdRes) *taskEntity.CreateTaskReq {
 	log.Println(task)
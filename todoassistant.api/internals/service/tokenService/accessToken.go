@@ -0,0 +1,210 @@
+package tokenservice
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"test-va/internals/entity/tokenEntity"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AccessTokenPrefix starts every personal access token, it tells them apart from JWTs
+const AccessTokenPrefix = "tkd_"
+
+const (
+	maxAccessTokens = 20
+	// last used is only updated this often, a script calling in a loop does not write on every request
+	accessTokenTouchEvery = time.Minute
+)
+
+// Allows reports whether the token can be used on a route open to scopes. Only personal access tokens
+// and tokens of OAuth apps are limited, to routes with one of their scopes, and a ":read" scope only
+// opens requests that read.
+func (t *Token) Allows(method string, scopes ...string) bool {
+	if t.Type != TypePersonal && t.ClientId == "" {
+		return true
+	}
+	reading := method == http.MethodGet || method == http.MethodHead
+	for _, scope := range scopes {
+		if strings.HasSuffix(scope, ":read") && !reading {
+			continue
+		}
+		for _, held := range t.Scopes {
+			if held == scope {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Create Access Token godoc
+// @Summary	Make a personal access token
+// @Description	Returns a token to script against the api with, sent as a Bearer token like a JWT. It only works on the routes of its scopes: tasks:read and tasks:write for tasks and attachments, projects and notifications. The token cannot be shown again.
+// @Tags	Users
+// @Accept	json
+// @Produce	json
+// @Param	request	body	tokenEntity.CreateAccessTokenReq	true	"Name, scopes and expiry"
+// @Success	201  {object}  tokenEntity.CreateAccessTokenRes
+// @Failure	400  {object}  ResponseEntity.ResponseMessage
+// @Failure	401  {object}  ResponseEntity.ResponseMessage
+// @Failure	500  {object}  ResponseEntity.ResponseMessage
+// @Security ApiKeyAuth
+// @Router	/user/tokens [post]
+func (t *tokenSrv) CreateAccessToken(userId, status string, req *tokenEntity.CreateAccessTokenReq) (*tokenEntity.CreateAccessTokenRes, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	tokens, err := t.repo.GetAccessTokens(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) >= maxAccessTokens {
+		return nil, ErrTooManyAccessTokens
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	token := AccessTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	now := time.Now().UTC()
+	record := &tokenEntity.AccessToken{
+		TokenId:     uuid.New().String(),
+		UserId:      userId,
+		AccountType: status,
+		Name:        req.Name,
+		TokenHash:   hashId(token),
+		Hint:        token[len(token)-4:],
+		Scopes:      uniqueScopes(req.Scopes),
+		CreatedAt:   now.Format(time.RFC3339),
+	}
+	if req.ExpiresInDays > 0 {
+		record.ExpiresAt = now.Add(time.Hour * 24 * time.Duration(req.ExpiresInDays)).Format(time.RFC3339)
+	}
+	err = t.repo.PersistAccessToken(ctx, record)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenEntity.CreateAccessTokenRes{Token: token, AccessToken: record}, nil
+}
+
+// Get Access Tokens godoc
+// @Summary	List your personal access tokens
+// @Description	Returns the tokens that were not revoked, with when they were last used
+// @Tags	Users
+// @Produce	json
+// @Success	200  {object}  []tokenEntity.AccessToken
+// @Failure	401  {object}  ResponseEntity.ResponseMessage
+// @Failure	500  {object}  ResponseEntity.ResponseMessage
+// @Security ApiKeyAuth
+// @Router	/user/tokens [get]
+func (t *tokenSrv) GetAccessTokens(userId string) ([]*tokenEntity.AccessToken, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	return t.repo.GetAccessTokens(ctx, userId)
+}
+
+// Revoke Access Token godoc
+// @Summary	Revoke a personal access token
+// @Description	The token stops working at once
+// @Tags	Users
+// @Produce	json
+// @Param	tokenId	path	string	true	"Token Id"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	401  {object}  ResponseEntity.ResponseMessage
+// @Failure	404  {object}  ResponseEntity.ResponseMessage
+// @Failure	500  {object}  ResponseEntity.ResponseMessage
+// @Security ApiKeyAuth
+// @Router	/user/tokens/{tokenId} [delete]
+func (t *tokenSrv) RevokeAccessToken(userId, tokenId string) error {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	revoked, err := t.repo.RevokeAccessToken(ctx, userId, tokenId, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+// validateAccessToken looks up a personal access token. It is refused once revoked, expired or
+// issued before the user logged out of everything.
+func (t *tokenSrv) validateAccessToken(token string) (*Token, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*10)
+	defer cancelFunc()
+
+	record, err := t.repo.GetAccessToken(ctx, hashId(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidAccessToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if record.RevokedAt != "" {
+		return nil, ErrTokenRevoked
+	}
+
+	now := time.Now().UTC()
+	if record.ExpiresAt != "" && record.ExpiresAt <= now.Format(time.RFC3339) {
+		return nil, ErrAccessTokenExpired
+	}
+	createdAt, err := time.Parse(time.RFC3339, record.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := t.repo.IsRevoked(ctx, "", "", record.UserId, createdAt.Unix())
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	if record.LastUsedAt < now.Add(-accessTokenTouchEvery).Format(time.RFC3339) {
+		if err := t.repo.TouchAccessToken(ctx, record.TokenId, now.Format(time.RFC3339)); err != nil {
+			log.Println("could not update personal access token", err)
+		}
+	}
+
+	claims := &Token{
+		Id:     record.UserId,
+		Status: record.AccountType,
+		Type:   TypePersonal,
+		Scopes: record.Scopes,
+	}
+	claims.StandardClaims.Id = record.TokenId
+	claims.IssuedAt = createdAt.Unix()
+	if record.ExpiresAt != "" {
+		expiresAt, err := time.Parse(time.RFC3339, record.ExpiresAt)
+		if err != nil {
+			return nil, err
+		}
+		claims.ExpiresAt = expiresAt.Unix()
+	}
+	return claims, nil
+}
+
+func uniqueScopes(scopes []string) []string {
+	seen := map[string]bool{}
+	var unique []string
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			unique = append(unique, scope)
+		}
+	}
+	return unique
+}
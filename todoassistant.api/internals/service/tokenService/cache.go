@@ -0,0 +1,107 @@
+package tokenservice
+
+import (
+	"sync"
+	"time"
+)
+
+// revocationCache keeps revocation lookups in memory so most requests skip the database.
+// Revoked tokens are remembered until they expire. A token found valid is looked up again
+// after validFor, which bounds how long another instance accepts a token revoked elsewhere.
+type revocationCache struct {
+	mu       sync.Mutex
+	validFor time.Duration
+	// revoked maps a token id to the token's expiry
+	revoked map[string]int64
+	// valid maps a token id to when it has to be looked up again
+	valid map[string]time.Time
+	// cutoffs maps a user to the time before which their tokens are revoked
+	cutoffs map[string]int64
+	// sessions maps a revoked session to when it was revoked
+	sessions map[string]int64
+}
+
+func newRevocationCache(validFor time.Duration) *revocationCache {
+	return &revocationCache{
+		validFor: validFor,
+		revoked:  map[string]int64{},
+		valid:    map[string]time.Time{},
+		cutoffs:  map[string]int64{},
+		sessions: map[string]int64{},
+	}
+}
+
+// lookup returns whether the token is revoked, and false for known when the database has to be asked.
+func (r *revocationCache) lookup(jti, sessionId, userId string, issuedAt int64, now time.Time) (revoked, known bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cutoff, ok := r.cutoffs[userId]; ok && issuedAt < cutoff {
+		return true, true
+	}
+	if _, ok := r.sessions[sessionId]; ok && sessionId != "" {
+		return true, true
+	}
+	if _, ok := r.revoked[jti]; ok {
+		return true, true
+	}
+	if until, ok := r.valid[jti]; ok && now.Before(until) {
+		return false, true
+	}
+	return false, false
+}
+
+func (r *revocationCache) store(jti string, expiresAt int64, revoked bool, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if revoked {
+		r.revoked[jti] = expiresAt
+		delete(r.valid, jti)
+		return
+	}
+	r.valid[jti] = now.Add(r.validFor)
+}
+
+func (r *revocationCache) revokeUser(userId string, cutoff int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if cutoff > r.cutoffs[userId] {
+		r.cutoffs[userId] = cutoff
+	}
+}
+
+func (r *revocationCache) revokeSession(sessionId string, revokedAt int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sessions[sessionId] = revokedAt
+}
+
+// purge forgets tokens that expired, and cutoffs and sessions revoked before any token still alive was issued.
+func (r *revocationCache) purge(now time.Time, maxLifetime time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for jti, expiresAt := range r.revoked {
+		if expiresAt < now.Unix() {
+			delete(r.revoked, jti)
+		}
+	}
+	for jti, until := range r.valid {
+		if now.After(until) {
+			delete(r.valid, jti)
+		}
+	}
+	for userId, cutoff := range r.cutoffs {
+		if cutoff < now.Add(-maxLifetime).Unix() {
+			delete(r.cutoffs, userId)
+		}
+	}
+	for sessionId, revokedAt := range r.sessions {
+		if revokedAt < now.Add(-maxLifetime).Unix() {
+			delete(r.sessions, sessionId)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package tokenservice
+
+import (
+	"context"
+	"test-va/internals/entity/tokenEntity"
+	"time"
+)
+
+// Get Connected Apps godoc
+// @Summary	List the apps connected to the account
+// @Description	Returns a session for every time an OAuth app was given access that can still be refreshed, with the scopes it holds. Revoke one with DELETE /user/apps/{sessionId}.
+// @Tags	Users
+// @Produce	json
+// @Success	200  {object}  []tokenEntity.Session
+// @Failure	401  {object}  ResponseEntity.ResponseMessage
+// @Failure	500  {object}  ResponseEntity.ResponseMessage
+// @Security ApiKeyAuth
+// @Router	/user/apps [get]
+func (t *tokenSrv) GetConnectedApps(userId string) ([]*tokenEntity.Session, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	sessions, err := t.repo.GetSessions(ctx, userId, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	apps := []*tokenEntity.Session{}
+	for _, session := range sessions {
+		if session.ClientId != "" {
+			apps = append(apps, session)
+		}
+	}
+	return apps, nil
+}
+
+func (t *tokenSrv) RevokeClientToken(clientId, token string) error {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	// an expired or forged token has nothing left to revoke
+	claims, err := t.parse(token)
+	if err != nil {
+		return nil
+	}
+	if claims.ClientId != clientId || claims.Family == "" {
+		return nil
+	}
+	return t.revokeSession(ctx, claims.Family, time.Now())
+}
+
+func (t *tokenSrv) RevokeClient(clientId string) error {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	sessionIds, err := t.repo.GetClientSessionIds(ctx, clientId)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, sessionId := range sessionIds {
+		if err := t.revokeSession(ctx, sessionId, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
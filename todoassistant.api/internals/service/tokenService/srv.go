@@ -1,63 +1,560 @@
 package tokenservice
 
 import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"log"
+	"net"
+	"strings"
+	"test-va/internals/Repository/tokenRepo"
+	"test-va/internals/entity/tokenEntity"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+// Token types
+const (
+	TypeAccess  = "access"
+	TypeRefresh = "refresh"
+	// TypeChallenge tokens prove the password was right while a two-factor code is still needed
+	TypeChallenge = "2fa_challenge"
+	// TypePersonal tokens are personal access tokens, they are not JWTs and only work on routes of their scopes
+	TypePersonal = "personal"
+)
+
+const (
+	// AccessLifetime is how long an access token works, OAuth apps are told it as expires_in
+	AccessLifetime  = time.Hour * 24
+	refreshLifetime = time.Hour * 60
+	// a two-factor code has to be entered this soon after the password
+	challengeLifetime = time.Minute * 5
+	// how long a token found valid is trusted before the database is asked again
+	revocationCacheFor = time.Second * 30
+	maxUserAgentLength = 512
+)
+
+var (
+	ErrNotAccessToken      = errors.New("refresh tokens cannot be used to access resources")
+	ErrNotRefreshToken     = errors.New("not a refresh token")
+	ErrInvalidRefreshToken = errors.New("refresh token is invalid or has been revoked")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used, every session from that login has been signed out")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+	ErrNotRevocable        = errors.New("this token cannot be revoked on its own, log out of all devices instead")
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrNotChallengeToken   = errors.New("not a two-factor challenge token")
+	ErrInvalidAccessToken  = errors.New("personal access token is invalid")
+	ErrAccessTokenExpired  = errors.New("personal access token has expired")
+	ErrAccessTokenNotFound = errors.New("personal access token not found")
+	ErrTooManyAccessTokens = errors.New("too many personal access tokens, revoke one first")
+	ErrWrongClient         = errors.New("refresh token was issued to another client")
 )
 
 type Token struct {
 	Email  string
 	Id     string
 	Status string
+	// Type is access or refresh, tokens issued before refresh rotation have none
+	Type string
+	// Family links a token to the login it was issued for, it is also the id of the login's session
+	Family string
+	// TwoFactor is set on tokens from a login confirmed with a second factor
+	TwoFactor bool
+	// Scopes limit a personal access token or the tokens of an app to the routes of those scopes,
+	// tokens from a login have none and are not limited
+	Scopes []string `json:",omitempty"`
+	// ClientId is the OAuth app the token was issued to, empty for tokens from a login
+	ClientId string `json:",omitempty"`
 	jwt.StandardClaims
 }
 
 type TokenSrv interface {
-	CreateToken(id, status, email string) (string, string, error)
+	// CreateToken starts a session on the described device and issues its first tokens.
+	// A session for an OAuth app carries its client id and the scopes the user granted.
+	CreateToken(id, status, email string, session *tokenEntity.SessionInfo) (string, string, error)
+	// ValidateToken parses an access token or looks up a personal access token, refresh tokens are rejected.
 	ValidateToken(token string) (*Token, error)
+	// RefreshToken trades a refresh token for a new access and refresh token.
+	// A refresh token can only be traded once, presenting it again revokes every token from the same login.
+	// Refresh tokens of OAuth apps are refused, they are traded with RefreshClientToken.
+	RefreshToken(refreshToken string) (string, string, error)
+	// RefreshClientToken is RefreshToken for the refresh tokens issued to an OAuth app.
+	RefreshClientToken(clientId, refreshToken string) (string, string, error)
+	// Logout revokes an access token and the refresh tokens of its login.
+	Logout(token *Token) error
+	// LogoutAll revokes every token issued to the user so far.
+	LogoutAll(userId string) error
+	// GetSessions lists the devices the user is logged in on, marking the one with currentSessionId.
+	GetSessions(userId, currentSessionId string) ([]*tokenEntity.Session, error)
+	// GetConnectedApps lists the sessions of the OAuth apps the user connected, they are revoked like any session.
+	GetConnectedApps(userId string) ([]*tokenEntity.Session, error)
+	// RevokeClientToken revokes the session of an app's access or refresh token. Tokens that are invalid
+	// or belong to another client are ignored.
+	RevokeClientToken(clientId, token string) error
+	// RevokeClient revokes the sessions of every user of an app.
+	RevokeClient(clientId string) error
+	// RevokeSession logs the user out of one device and unregisters its push tokens.
+	RevokeSession(userId, sessionId string) error
+	// IsNewDevice reports whether the session is the user's first from its device. Users without an
+	// earlier session from a known device, such as on their first login, have no new devices.
+	IsNewDevice(userId, sessionId string) (bool, error)
+	// DisownSession revokes a session the user says was not theirs and forgets its device.
+	DisownSession(userId, sessionId string) error
+	// CreateChallenge issues a short lived token for the second step of a two-factor login.
+	CreateChallenge(id, status, email string) (string, error)
+	// ValidateChallenge parses a challenge token, any other token is rejected.
+	ValidateChallenge(token string) (*Token, error)
+	// PurgeRevocations forgets revocations of tokens that have expired anyway.
+	PurgeRevocations()
+	// CreateAccessToken makes a personal access token, the token itself is only returned this once.
+	CreateAccessToken(userId, status string, req *tokenEntity.CreateAccessTokenReq) (*tokenEntity.CreateAccessTokenRes, error)
+	GetAccessTokens(userId string) ([]*tokenEntity.AccessToken, error)
+	RevokeAccessToken(userId, tokenId string) error
 }
 
 type tokenSrv struct {
 	SecretKey string
+	repo      tokenRepo.TokenRepository
+	cache     *revocationCache
+}
+
+func (t *tokenSrv) CreateToken(id, status, email string, session *tokenEntity.SessionInfo) (string, string, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	// every login starts a new session and family of refresh tokens
+	if session == nil {
+		session = &tokenEntity.SessionInfo{}
+	}
+	token, refreshToken, record, err := t.mint(Token{Email: email, Id: id, Status: status, Family: uuid.New().String(),
+		TwoFactor: session.TwoFactor, Scopes: session.Scopes, ClientId: session.ClientId})
+	if err != nil {
+		return "", "", err
+	}
+	userAgent := session.UserAgent
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	err = t.repo.PersistSession(ctx, &tokenEntity.Session{
+		SessionId:   record.FamilyId,
+		UserId:      id,
+		AccountType: status,
+		DeviceName:  session.DeviceName,
+		UserAgent:   userAgent,
+		IPAddress:   session.IPAddress,
+		Fingerprint: deviceFingerprint(userAgent, session.IPAddress),
+		ClientId:    session.ClientId,
+		CreatedAt:   record.CreatedAt,
+		LastSeenAt:  record.CreatedAt,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	err = t.repo.PersistRefreshToken(ctx, record)
+	if err != nil {
+		return "", "", err
+	}
+	session.SessionId = record.FamilyId
+	return token, refreshToken, nil
+}
+
+func (t *tokenSrv) ValidateToken(tokenUrl string) (*Token, error) {
+	if strings.HasPrefix(tokenUrl, AccessTokenPrefix) {
+		return t.validateAccessToken(tokenUrl)
+	}
+	claims, err := t.parse(tokenUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	// refresh tokens issued before tokens were typed carry no status
+	if claims.Type != TypeAccess && (claims.Type != "" || claims.Status == "") {
+		return nil, ErrNotAccessToken
+	}
+
+	revoked, err := t.isRevoked(claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+func (t *tokenSrv) isRevoked(claims *Token) (bool, error) {
+	now := time.Now()
+	jti := claims.StandardClaims.Id
+	// tokens issued before they had an id can only be revoked by a cutoff, so are never cached
+	if jti != "" {
+		if revoked, known := t.cache.lookup(jti, claims.Family, claims.Id, claims.IssuedAt, now); known {
+			return revoked, nil
+		}
+	}
+
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Second*10)
+	defer cancelFunc()
+
+	revoked, err := t.repo.IsRevoked(ctx, jti, claims.Family, claims.Id, claims.IssuedAt)
+	if err != nil {
+		return false, err
+	}
+	if jti != "" {
+		t.cache.store(jti, claims.ExpiresAt, revoked, now)
+	}
+	// last seen is only as precise as the cache, which is enough to tell devices apart
+	if !revoked && claims.Family != "" {
+		if err := t.repo.TouchSession(ctx, claims.Family, now.UTC().Format(time.RFC3339)); err != nil {
+			log.Println("could not update session", err)
+		}
+	}
+	return revoked, nil
+}
+
+// Logout godoc
+// @Summary	Log out of the current session
+// @Description	Revokes the access token used for the request and the refresh tokens issued with it
+// @Tags	Users
+// @Produce	json
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	400  {object}  ResponseEntity.ResponseMessage
+// @Failure	401  {object}  ResponseEntity.ResponseMessage
+// @Security ApiKeyAuth
+// @Router	/user/logout [post]
+func (t *tokenSrv) Logout(token *Token) error {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	jti := token.StandardClaims.Id
+	if jti == "" {
+		return ErrNotRevocable
+	}
+	err := t.repo.RevokeToken(ctx, jti, token.Id, token.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	t.cache.store(jti, token.ExpiresAt, true, now)
+
+	if token.Family != "" {
+		return t.revokeSession(ctx, token.Family, now)
+	}
+	return nil
+}
+
+// Logout All godoc
+// @Summary	Log out of every device
+// @Description	Revokes every access, refresh and personal access token issued to the user so far
+// @Tags	Users
+// @Produce	json
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	401  {object}  ResponseEntity.ResponseMessage
+// @Failure	500  {object}  ResponseEntity.ResponseMessage
+// @Security ApiKeyAuth
+// @Router	/user/logout-all [post]
+func (t *tokenSrv) LogoutAll(userId string) error {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	// iat has second precision, tokens issued within the same second as the cutoff stay valid
+	now := time.Now()
+	err := t.repo.SetRevokedBefore(ctx, userId, now.Unix())
+	if err != nil {
+		return err
+	}
+	t.cache.revokeUser(userId, now.Unix())
+	return t.repo.RevokeUserSessions(ctx, userId, now.UTC().Format(time.RFC3339))
+}
+
+// Get Sessions godoc
+// @Summary	List the devices the account is logged in on
+// @Description	Returns every session that can still be refreshed, the session the request was made from is marked current
+// @Tags	Users
+// @Produce	json
+// @Success	200  {object}  []tokenEntity.Session
+// @Failure	401  {object}  ResponseEntity.ResponseMessage
+// @Failure	500  {object}  ResponseEntity.ResponseMessage
+// @Security ApiKeyAuth
+// @Router	/user/sessions [get]
+// @Router	/va/sessions [get]
+func (t *tokenSrv) GetSessions(userId, currentSessionId string) ([]*tokenEntity.Session, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	sessions, err := t.repo.GetSessions(ctx, userId, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	// apps are listed on their own
+	devices := []*tokenEntity.Session{}
+	for _, session := range sessions {
+		if session.ClientId != "" {
+			continue
+		}
+		session.Current = session.SessionId == currentSessionId
+		devices = append(devices, session)
+	}
+	return devices, nil
+}
+
+// Revoke Session godoc
+// @Summary	Log out of one device
+// @Description	Revokes every token of the session and unregisters the push notifications of its device
+// @Tags	Users
+// @Produce	json
+// @Param	sessionId	path	string	true	"Session Id"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	401  {object}  ResponseEntity.ResponseMessage
+// @Failure	404  {object}  ResponseEntity.ResponseMessage
+// @Failure	500  {object}  ResponseEntity.ResponseMessage
+// @Security ApiKeyAuth
+// @Router	/user/sessions/{sessionId} [delete]
+// @Router	/va/sessions/{sessionId} [delete]
+func (t *tokenSrv) RevokeSession(userId, sessionId string) error {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	session, err := t.repo.GetSession(ctx, sessionId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	// someone else's session is reported the same as a missing one
+	if session.UserId != userId || session.RevokedAt != "" {
+		return ErrSessionNotFound
+	}
+	return t.revokeSession(ctx, sessionId, time.Now())
+}
+
+func (t *tokenSrv) IsNewDevice(userId, sessionId string) (bool, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	session, err := t.repo.GetSession(ctx, sessionId)
+	if err != nil {
+		return false, err
+	}
+	if session.UserId != userId || session.Fingerprint == "" {
+		return false, nil
+	}
+	known, fingerprinted, err := t.repo.CountDeviceSessions(ctx, userId, session.Fingerprint, sessionId)
+	if err != nil {
+		return false, err
+	}
+	return fingerprinted > 0 && known == 0, nil
 }
 
-func (t *tokenSrv) CreateToken(id, status, email string) (string, string, error) {
-	tokenDetails := &Token{
+func (t *tokenSrv) DisownSession(userId, sessionId string) error {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	session, err := t.repo.GetSession(ctx, sessionId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if session.UserId != userId {
+		return ErrSessionNotFound
+	}
+	// the session may be logged out already, its device is still forgotten
+	if session.RevokedAt == "" {
+		err = t.revokeSession(ctx, sessionId, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+	return t.repo.ForgetSessionDevice(ctx, sessionId)
+}
+
+func (t *tokenSrv) revokeSession(ctx context.Context, sessionId string, now time.Time) error {
+	err := t.repo.RevokeSession(ctx, sessionId, now.UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	t.cache.revokeSession(sessionId, now.Unix())
+	return nil
+}
+
+func (t *tokenSrv) PurgeRevocations() {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	now := time.Now()
+	if err := t.repo.DeleteExpiredRevocations(ctx, now.Unix()); err != nil {
+		log.Println("could not purge revoked tokens", err)
+	}
+	t.cache.purge(now, refreshLifetime)
+}
+
+// Refresh Token godoc
+// @Summary	Get a new access token with a refresh token
+// @Description	Returns a new access token and a new refresh token, the old refresh token stops working. Using a refresh token a second time signs out every session started from the same login.
+// @Tags	Users
+// @Accept	json
+// @Produce	json
+// @Param	request	body	tokenEntity.RefreshReq	true	"Refresh token"
+// @Success	200  {object}  tokenEntity.TokenRes
+// @Failure	400  {object}  ResponseEntity.ResponseMessage
+// @Failure	401  {object}  ResponseEntity.ResponseMessage
+// @Router	/user/token/refresh [post]
+func (t *tokenSrv) RefreshToken(refreshToken string) (string, string, error) {
+	claims, err := t.parse(refreshToken)
+	if err != nil {
+		return "", "", err
+	}
+	if claims.ClientId != "" {
+		return "", "", ErrNotRefreshToken
+	}
+	return t.refresh(claims)
+}
+
+func (t *tokenSrv) RefreshClientToken(clientId, refreshToken string) (string, string, error) {
+	claims, err := t.parse(refreshToken)
+	if err != nil {
+		return "", "", err
+	}
+	if claims.ClientId != clientId {
+		return "", "", ErrWrongClient
+	}
+	return t.refresh(claims)
+}
+
+func (t *tokenSrv) refresh(claims *Token) (string, string, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	if claims.Type != TypeRefresh || claims.StandardClaims.Id == "" {
+		return "", "", ErrNotRefreshToken
+	}
+
+	stored, err := t.repo.GetRefreshToken(ctx, hashId(claims.StandardClaims.Id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	if stored.RevokedAt != "" {
+		return "", "", ErrInvalidRefreshToken
+	}
+	if stored.RotatedAt != "" {
+		return "", "", t.revokeFamily(ctx, stored.FamilyId, now)
+	}
+
+	token, nextToken, next, err := t.mint(Token{Email: claims.Email, Id: claims.Id, Status: claims.Status,
+		Family: stored.FamilyId, TwoFactor: claims.TwoFactor, Scopes: claims.Scopes, ClientId: claims.ClientId})
+	if err != nil {
+		return "", "", err
+	}
+	rotated, err := t.repo.RotateRefreshToken(ctx, stored.TokenHash, now.UTC().Format(time.RFC3339), next)
+	if err != nil {
+		return "", "", err
+	}
+	// another request traded the same token first
+	if !rotated {
+		return "", "", t.revokeFamily(ctx, stored.FamilyId, now)
+	}
+	if err := t.repo.TouchSession(ctx, stored.FamilyId, now.UTC().Format(time.RFC3339)); err != nil {
+		log.Println("could not update session", err)
+	}
+	return token, nextToken, nil
+}
+
+// revokeFamily signs out every token from the login a reused refresh token belongs to.
+func (t *tokenSrv) revokeFamily(ctx context.Context, familyId string, now time.Time) error {
+	if err := t.revokeSession(ctx, familyId, now); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func (t *tokenSrv) CreateChallenge(id, status, email string) (string, error) {
+	now := time.Now()
+	challenge := &Token{
 		Email:  email,
 		Id:     id,
 		Status: status,
+		Type:   TypeChallenge,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(24)).Unix(),
+			Id:        uuid.New().String(),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(challengeLifetime).Unix(),
 		},
 	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, challenge).SignedString([]byte(t.SecretKey))
+}
 
-	refreshTokenDetails := &Token{
-		Email: email,
-		Id:    id,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(60)).Unix(),
-		},
+func (t *tokenSrv) ValidateChallenge(tokenUrl string) (*Token, error) {
+	claims, err := t.parse(tokenUrl)
+	if err != nil {
+		return nil, err
 	}
+	if claims.Type != TypeChallenge {
+		return nil, ErrNotChallengeToken
+	}
+	return claims, nil
+}
 
-	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, tokenDetails).SignedString([]byte(t.SecretKey))
-	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshTokenDetails).SignedString([]byte(t.SecretKey))
+// mint signs an access and a refresh token carrying the claims of base, and returns the record to store for the refresh token.
+func (t *tokenSrv) mint(base Token) (string, string, *tokenEntity.RefreshToken, error) {
+	now := time.Now()
+	tokenDetails := base
+	tokenDetails.Type = TypeAccess
+	tokenDetails.StandardClaims = jwt.StandardClaims{
+		Id:        uuid.New().String(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(AccessLifetime).Unix(),
+	}
+
+	refreshId := uuid.New().String()
+	refreshExpiry := now.Add(refreshLifetime)
+	refreshTokenDetails := base
+	refreshTokenDetails.Type = TypeRefresh
+	refreshTokenDetails.StandardClaims = jwt.StandardClaims{
+		Id:        refreshId,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: refreshExpiry.Unix(),
+	}
 
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &tokenDetails).SignedString([]byte(t.SecretKey))
 	if err != nil {
-		log.Panic(err)
-		return "", "", err
+		return "", "", nil, err
+	}
+	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &refreshTokenDetails).SignedString([]byte(t.SecretKey))
+	if err != nil {
+		return "", "", nil, err
 	}
 
-	return token, refreshToken, err
+	record := &tokenEntity.RefreshToken{
+		TokenHash: hashId(refreshId),
+		FamilyId:  base.Family,
+		UserId:    base.Id,
+		ExpiresAt: refreshExpiry.UTC().Format(time.RFC3339),
+		CreatedAt: now.UTC().Format(time.RFC3339),
+	}
+	return token, refreshToken, record, nil
 }
 
-func (t *tokenSrv) ValidateToken(tokenUrl string) (*Token, error) {
+// parse checks the signature and expiry of any token issued by this service.
+func (t *tokenSrv) parse(tokenUrl string) (*Token, error) {
 	token, err := jwt.ParseWithClaims(
 		tokenUrl,
 		&Token{},
 		func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+			}
 			return []byte(t.SecretKey), nil
 		},
 	)
@@ -65,19 +562,42 @@ func (t *tokenSrv) ValidateToken(tokenUrl string) (*Token, error) {
 	if token == nil {
 		return nil, errors.New("check the provided token")
 	}
+	if err != nil {
+		return nil, err
+	}
 
 	claims, ok := token.Claims.(*Token)
 	if !ok {
-		return nil, err
+		return nil, errors.New("check the provided token")
 	}
 
 	if claims.ExpiresAt < time.Now().Local().Unix() {
-		return nil, err
+		return nil, errors.New("token has expired")
 	}
 
-	return claims, err
+	return claims, nil
+}
+
+// deviceFingerprint identifies the device a session is started from by its user agent and network,
+// the /24 of an IPv4 or the /48 of an IPv6 address, so a device is not new whenever its address changes a little.
+func deviceFingerprint(userAgent, ipAddress string) string {
+	network := ipAddress
+	if ip := net.ParseIP(ipAddress); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			network = ip4.Mask(net.CIDRMask(24, 32)).String()
+		} else {
+			network = ip.Mask(net.CIDRMask(48, 128)).String()
+		}
+	}
+	return hashId(userAgent + "\n" + network)
+}
+
+// hashId is how refresh token ids are stored, so a leaked table cannot be replayed.
+func hashId(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
 }
 
-func NewTokenSrv(secret string) TokenSrv {
-	return &tokenSrv{secret}
+func NewTokenSrv(secret string, repo tokenRepo.TokenRepository) TokenSrv {
+	return &tokenSrv{SecretKey: secret, repo: repo, cache: newRevocationCache(revocationCacheFor)}
 }
@@ -0,0 +1,350 @@
+package twoFactorService
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"test-va/internals/Repository/twoFactorRepo"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/twoFactorEntity"
+	tokenservice "test-va/internals/service/tokenService"
+)
+
+const (
+	ErrForbidden    = "Forbidden"
+	ErrUnauthorized = "Unauthorized"
+)
+
+const (
+	issuer            = "Ticked"
+	secretSize        = 20
+	recoveryCodeCount = 10
+	recoveryCodeSize  = 8
+	// a challenge is given up after this many codes, the password has to be entered again
+	maxChallengeAttempts = 5
+)
+
+type TwoFactorSrv interface {
+	Enroll(accountId, accountType, email string) (*twoFactorEntity.EnrollRes, *ResponseEntity.ServiceError)
+	Confirm(accountId, code string) (*twoFactorEntity.RecoveryCodesRes, *ResponseEntity.ServiceError)
+	Disable(accountId, accountType, code string) *ResponseEntity.ServiceError
+	IsEnabled(accountId string) (bool, error)
+	// Challenge starts the second step of a login, the token it returns is traded in CompleteChallenge.
+	Challenge(id, status, email string) (string, error)
+	// ChallengeAccount returns who is logging in with a challenge, without checking a code.
+	ChallengeAccount(challengeToken string) (*tokenservice.Token, *ResponseEntity.ServiceError)
+	// CompleteChallenge checks the code for a challenge and returns who is logging in.
+	// A challenge logs in once, and is given up after a few wrong codes.
+	CompleteChallenge(challengeToken, code string) (*tokenservice.Token, *ResponseEntity.ServiceError)
+	// PurgeChallenges forgets challenges whose token has expired.
+	PurgeChallenges()
+}
+
+type twoFactorSrv struct {
+	repo     twoFactorRepo.TwoFactorRepository
+	tokenSrv tokenservice.TokenSrv
+}
+
+// Enroll Two Factor godoc
+// @Summary	Start enrolling in two-factor authentication
+// @Description	Returns a new secret and an otpauth URI for authenticator apps. Two-factor authentication is only turned on once a code from the app is confirmed.
+// @Tags	Two Factor
+// @Produce	json
+// @Success	200  {object}  twoFactorEntity.EnrollRes
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/user/2fa/enroll [post]
+// @Router	/va/2fa/enroll [post]
+func (t *twoFactorSrv) Enroll(accountId, accountType, email string) (*twoFactorEntity.EnrollRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	existing, err := t.repo.Get(ctx, accountId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if existing != nil && existing.EnabledAt != "" {
+		return nil, ResponseEntity.NewValidatingError("two-factor authentication is already enabled")
+	}
+
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	encoded := secretEncoding.EncodeToString(secret)
+	err = t.repo.SaveSecret(ctx, &twoFactorEntity.TwoFactor{
+		AccountId:   accountId,
+		AccountType: accountType,
+		Secret:      encoded,
+		CreatedAt:   time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	return &twoFactorEntity.EnrollRes{Secret: encoded, URI: otpauthURI(email, encoded)}, nil
+}
+
+// Confirm Two Factor godoc
+// @Summary	Turn on two-factor authentication with a first code
+// @Description	Confirms enrollment with a code from the authenticator app and returns single use recovery codes, they are only shown once
+// @Tags	Two Factor
+// @Accept	json
+// @Produce	json
+// @Param	request	body	twoFactorEntity.CodeReq	true	"Code from the authenticator app"
+// @Success	200  {object}  twoFactorEntity.RecoveryCodesRes
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/user/2fa/confirm [post]
+// @Router	/va/2fa/confirm [post]
+func (t *twoFactorSrv) Confirm(accountId, code string) (*twoFactorEntity.RecoveryCodesRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	twoFactor, err := t.repo.Get(ctx, accountId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ResponseEntity.NewValidatingError("start enrolling in two-factor authentication first")
+	}
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if twoFactor.EnabledAt != "" {
+		return nil, ResponseEntity.NewValidatingError("two-factor authentication is already enabled")
+	}
+
+	secret, err := secretEncoding.DecodeString(twoFactor.Secret)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	step, ok := matchStep(secret, normalizeCode(code), time.Now())
+	if !ok {
+		return nil, ResponseEntity.NewValidatingError("invalid two-factor code")
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		codes[i], err = newRecoveryCode()
+		if err != nil {
+			return nil, ResponseEntity.NewInternalServiceError(err)
+		}
+		hashes[i] = hashCode(normalizeCode(codes[i]))
+	}
+	err = t.repo.Enable(ctx, accountId, time.Now().UTC().Format(time.RFC3339), step, hashes)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	return &twoFactorEntity.RecoveryCodesRes{RecoveryCodes: codes}, nil
+}
+
+// Disable Two Factor godoc
+// @Summary	Turn off two-factor authentication
+// @Description	Needs a code from the authenticator app or a recovery code. MASTER accounts cannot turn it off.
+// @Tags	Two Factor
+// @Accept	json
+// @Produce	json
+// @Param	request	body	twoFactorEntity.CodeReq	true	"Code from the authenticator app or a recovery code"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/user/2fa/disable [post]
+// @Router	/va/2fa/disable [post]
+func (t *twoFactorSrv) Disable(accountId, accountType, code string) *ResponseEntity.ServiceError {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	if accountType == "MASTER" {
+		return ResponseEntity.NewCustomServiceError(ErrForbidden, "MASTER accounts have to keep two-factor authentication on")
+	}
+
+	twoFactor, err := t.repo.Get(ctx, accountId)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && twoFactor.EnabledAt == "") {
+		return ResponseEntity.NewValidatingError("two-factor authentication is not enabled")
+	}
+	if err != nil {
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+
+	ok, err := t.verify(ctx, twoFactor, code)
+	if err != nil {
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	if !ok {
+		return ResponseEntity.NewValidatingError("invalid two-factor code")
+	}
+
+	err = t.repo.Delete(ctx, accountId)
+	if err != nil {
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	return nil
+}
+
+func (t *twoFactorSrv) IsEnabled(accountId string) (bool, error) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	twoFactor, err := t.repo.Get(ctx, accountId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return twoFactor.EnabledAt != "", nil
+}
+
+func (t *twoFactorSrv) Challenge(id, status, email string) (string, error) {
+	return t.tokenSrv.CreateChallenge(id, status, email)
+}
+
+func (t *twoFactorSrv) ChallengeAccount(challengeToken string) (*tokenservice.Token, *ResponseEntity.ServiceError) {
+	claims, err := t.tokenSrv.ValidateChallenge(challengeToken)
+	if err != nil {
+		return nil, ResponseEntity.NewCustomServiceError(ErrUnauthorized, "the login has expired, log in again")
+	}
+	return claims, nil
+}
+
+func (t *twoFactorSrv) CompleteChallenge(challengeToken, code string) (*tokenservice.Token, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	claims, err := t.tokenSrv.ValidateChallenge(challengeToken)
+	if err != nil {
+		return nil, ResponseEntity.NewCustomServiceError(ErrUnauthorized, "the login has expired, log in again")
+	}
+
+	// the try is counted before the code is checked, so guesses sent at once are counted too
+	challenge, err := t.repo.AttemptChallenge(ctx, &twoFactorEntity.Challenge{
+		Jti:       claims.StandardClaims.Id,
+		AccountId: claims.Id,
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0).UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if challenge.CompletedAt != "" {
+		return nil, ResponseEntity.NewCustomServiceError(ErrUnauthorized, "the login has expired, log in again")
+	}
+	if challenge.Attempts > maxChallengeAttempts {
+		return nil, ResponseEntity.NewCustomServiceError(ErrUnauthorized, "too many wrong codes, log in again")
+	}
+
+	twoFactor, err := t.repo.Get(ctx, claims.Id)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewCustomServiceError(ErrUnauthorized, "two-factor authentication is not enabled")
+	}
+	ok, err := t.verify(ctx, twoFactor, code)
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if !ok {
+		return nil, ResponseEntity.NewCustomServiceError(ErrUnauthorized, "invalid two-factor code")
+	}
+
+	completed, err := t.repo.CompleteChallenge(ctx, challenge.Jti, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		log.Println(err)
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if !completed {
+		return nil, ResponseEntity.NewCustomServiceError(ErrUnauthorized, "the login has expired, log in again")
+	}
+	return claims, nil
+}
+
+func (t *twoFactorSrv) PurgeChallenges() {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	err := t.repo.DeleteExpiredChallenges(ctx, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// verify accepts a code from the authenticator app once, or an unused recovery code.
+func (t *twoFactorSrv) verify(ctx context.Context, twoFactor *twoFactorEntity.TwoFactor, code string) (bool, error) {
+	if twoFactor.EnabledAt == "" {
+		return false, nil
+	}
+	code = normalizeCode(code)
+	if len(code) != totpDigits {
+		return t.repo.UseRecoveryCode(ctx, twoFactor.AccountId, hashCode(code), time.Now().UTC().Format(time.RFC3339))
+	}
+
+	secret, err := secretEncoding.DecodeString(twoFactor.Secret)
+	if err != nil {
+		return false, err
+	}
+	step, ok := matchStep(secret, code, time.Now())
+	if !ok || step <= twoFactor.LastUsedStep {
+		return false, nil
+	}
+	return t.repo.UseStep(ctx, twoFactor.AccountId, step)
+}
+
+func otpauthURI(email, secret string) string {
+	label := url.PathEscape(issuer + ":" + email)
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, query.Encode())
+}
+
+// newRecoveryCode returns a code grouped in fours for reading, such as 1a2b-3c4d-5e6f-7a8b.
+func newRecoveryCode() (string, error) {
+	raw := make([]byte, recoveryCodeSize)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	code := hex.EncodeToString(raw)
+	groups := make([]string, 0, len(code)/4)
+	for i := 0; i < len(code); i += 4 {
+		groups = append(groups, code[i:i+4])
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// normalizeCode drops the separators people type or copy along with a code.
+func normalizeCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// hashCode is how recovery codes are stored.
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewTwoFactorSrv(repo twoFactorRepo.TwoFactorRepository, tokenSrv tokenservice.TokenSrv) TwoFactorSrv {
+	return &twoFactorSrv{repo: repo, tokenSrv: tokenSrv}
+}
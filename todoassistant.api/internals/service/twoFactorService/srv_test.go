@@ -0,0 +1,232 @@
+package twoFactorService
+
+import (
+	"context"
+	"database/sql"
+	"test-va/internals/Repository/twoFactorRepo"
+	"test-va/internals/entity/twoFactorEntity"
+	tokenservice "test-va/internals/service/tokenService"
+	"testing"
+	"time"
+)
+
+type memoryRepo struct {
+	twoFactorRepo.TwoFactorRepository
+	accounts   map[string]*twoFactorEntity.TwoFactor
+	codes      map[string]string
+	challenges map[string]*twoFactorEntity.Challenge
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{accounts: map[string]*twoFactorEntity.TwoFactor{}, codes: map[string]string{},
+		challenges: map[string]*twoFactorEntity.Challenge{}}
+}
+
+func (m *memoryRepo) Get(ctx context.Context, accountId string) (*twoFactorEntity.TwoFactor, error) {
+	twoFactor, ok := m.accounts[accountId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	stored := *twoFactor
+	return &stored, nil
+}
+
+func (m *memoryRepo) SaveSecret(ctx context.Context, twoFactor *twoFactorEntity.TwoFactor) error {
+	if existing, ok := m.accounts[twoFactor.AccountId]; ok && existing.EnabledAt != "" {
+		return nil
+	}
+	m.accounts[twoFactor.AccountId] = twoFactor
+	return nil
+}
+
+func (m *memoryRepo) Enable(ctx context.Context, accountId, enabledAt string, step int64, recoveryCodeHashes []string) error {
+	m.accounts[accountId].EnabledAt = enabledAt
+	m.accounts[accountId].LastUsedStep = step
+	for _, hash := range recoveryCodeHashes {
+		m.codes[hash] = accountId
+	}
+	return nil
+}
+
+func (m *memoryRepo) UseStep(ctx context.Context, accountId string, step int64) (bool, error) {
+	if m.accounts[accountId].LastUsedStep >= step {
+		return false, nil
+	}
+	m.accounts[accountId].LastUsedStep = step
+	return true, nil
+}
+
+func (m *memoryRepo) UseRecoveryCode(ctx context.Context, accountId, codeHash, usedAt string) (bool, error) {
+	if m.codes[codeHash] != accountId {
+		return false, nil
+	}
+	delete(m.codes, codeHash)
+	return true, nil
+}
+
+func (m *memoryRepo) Delete(ctx context.Context, accountId string) error {
+	delete(m.accounts, accountId)
+	for hash, owner := range m.codes {
+		if owner == accountId {
+			delete(m.codes, hash)
+		}
+	}
+	return nil
+}
+
+func (m *memoryRepo) AttemptChallenge(ctx context.Context, challenge *twoFactorEntity.Challenge) (*twoFactorEntity.Challenge, error) {
+	stored, ok := m.challenges[challenge.Jti]
+	if !ok {
+		stored = challenge
+		m.challenges[challenge.Jti] = stored
+	}
+	stored.Attempts++
+	counted := *stored
+	return &counted, nil
+}
+
+func (m *memoryRepo) CompleteChallenge(ctx context.Context, jti, completedAt string) (bool, error) {
+	if m.challenges[jti].CompletedAt != "" {
+		return false, nil
+	}
+	m.challenges[jti].CompletedAt = completedAt
+	return true, nil
+}
+
+// currentCode returns the code an authenticator app shows for secret, steps away from now.
+func currentCode(t *testing.T, secret string, steps int64) string {
+	t.Helper()
+	raw, err := secretEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q is not base32: %v", secret, err)
+	}
+	return totpCode(raw, time.Now().Unix()/totpPeriod+steps)
+}
+
+func TestTOTPCode(t *testing.T) {
+	// test vector from RFC 6238, appendix B
+	secret := []byte("12345678901234567890")
+	if code := totpCode(secret, 59/totpPeriod); code != "287082" {
+		t.Errorf("totpCode() = %s, want 287082", code)
+	}
+	if code := totpCode(secret, 1111111109/totpPeriod); code != "081804" {
+		t.Errorf("totpCode() = %s, want 081804", code)
+	}
+	if _, ok := matchStep(secret, "287082", time.Unix(59+totpPeriod*3, 0)); ok {
+		t.Error("matchStep() accepted a code from three steps ago")
+	}
+}
+
+func TestEnrollAndChallenge(t *testing.T) {
+	repo := newMemoryRepo()
+	srv := NewTwoFactorSrv(repo, tokenservice.NewTokenSrv("secret", nil))
+
+	enrolled, errRes := srv.Enroll("u1", "user", "sam@example.com")
+	if errRes != nil {
+		t.Fatalf("Enroll() error = %v", errRes)
+	}
+	if enabled, _ := srv.IsEnabled("u1"); enabled {
+		t.Error("two-factor authentication should wait for a confirmed code")
+	}
+	if _, errRes := srv.Confirm("u1", "000000x"); errRes == nil {
+		t.Error("Confirm() with a wrong code should fail")
+	}
+
+	// the first code is spent by confirming, a later one logs in
+	recovery, errRes := srv.Confirm("u1", currentCode(t, enrolled.Secret, -1))
+	if errRes != nil {
+		t.Fatalf("Confirm() error = %v", errRes)
+	}
+	if len(recovery.RecoveryCodes) != recoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(recovery.RecoveryCodes), recoveryCodeCount)
+	}
+	if enabled, _ := srv.IsEnabled("u1"); !enabled {
+		t.Fatal("two-factor authentication should be on after confirming")
+	}
+
+	challenge := newChallenge(t, srv, "u1")
+	if _, errRes := srv.CompleteChallenge(challenge, currentCode(t, enrolled.Secret, -1)); errRes == nil {
+		t.Error("CompleteChallenge() accepted a code that was already used")
+	}
+	code := currentCode(t, enrolled.Secret, 0)
+	claims, errRes := srv.CompleteChallenge(challenge, code)
+	if errRes != nil {
+		t.Fatalf("CompleteChallenge() error = %v", errRes)
+	}
+	if claims.Id != "u1" || claims.Status != "user" {
+		t.Errorf("CompleteChallenge() claims = %+v", claims)
+	}
+	if _, errRes := srv.CompleteChallenge(newChallenge(t, srv, "u1"), code); errRes == nil || errRes.Description != ErrUnauthorized {
+		t.Errorf("replaying a code = %v, want unauthorized", errRes)
+	}
+
+	if _, errRes := srv.CompleteChallenge(challenge, recovery.RecoveryCodes[0]); errRes == nil || errRes.Description != ErrUnauthorized {
+		t.Errorf("completing a challenge twice = %v, want unauthorized", errRes)
+	}
+	if _, errRes := srv.CompleteChallenge(newChallenge(t, srv, "u1"), recovery.RecoveryCodes[0]); errRes != nil {
+		t.Fatalf("CompleteChallenge() with a recovery code error = %v", errRes)
+	}
+	if _, errRes := srv.CompleteChallenge(newChallenge(t, srv, "u1"), recovery.RecoveryCodes[0]); errRes == nil {
+		t.Error("a recovery code should only work once")
+	}
+
+	if _, errRes := srv.CompleteChallenge("not-a-token", recovery.RecoveryCodes[1]); errRes == nil || errRes.Description != ErrUnauthorized {
+		t.Errorf("CompleteChallenge() with a bad token = %v, want unauthorized", errRes)
+	}
+
+	if errRes := srv.Disable("u1", "user", recovery.RecoveryCodes[1]); errRes != nil {
+		t.Fatalf("Disable() error = %v", errRes)
+	}
+	if enabled, _ := srv.IsEnabled("u1"); enabled {
+		t.Error("two-factor authentication should be off after disabling")
+	}
+}
+
+func TestMasterCannotDisable(t *testing.T) {
+	repo := newMemoryRepo()
+	srv := NewTwoFactorSrv(repo, tokenservice.NewTokenSrv("secret", nil))
+
+	enrolled, errRes := srv.Enroll("m1", "MASTER", "master@example.com")
+	if errRes != nil {
+		t.Fatalf("Enroll() error = %v", errRes)
+	}
+	if _, errRes := srv.Confirm("m1", currentCode(t, enrolled.Secret, 0)); errRes != nil {
+		t.Fatalf("Confirm() error = %v", errRes)
+	}
+	if errRes := srv.Disable("m1", "MASTER", currentCode(t, enrolled.Secret, 1)); errRes == nil || errRes.Description != ErrForbidden {
+		t.Errorf("Disable() for a master = %v, want forbidden", errRes)
+	}
+}
+
+func TestChallengeLocksAfterWrongCodes(t *testing.T) {
+	repo := newMemoryRepo()
+	srv := NewTwoFactorSrv(repo, tokenservice.NewTokenSrv("secret", nil))
+
+	enrolled, _ := srv.Enroll("u1", "user", "sam@example.com")
+	if _, errRes := srv.Confirm("u1", currentCode(t, enrolled.Secret, -1)); errRes != nil {
+		t.Fatalf("Confirm() error = %v", errRes)
+	}
+
+	challenge := newChallenge(t, srv, "u1")
+	for i := 0; i < maxChallengeAttempts; i++ {
+		if _, errRes := srv.CompleteChallenge(challenge, "000000"); errRes == nil {
+			t.Fatal("CompleteChallenge() accepted a wrong code")
+		}
+	}
+	_, errRes := srv.CompleteChallenge(challenge, currentCode(t, enrolled.Secret, 0))
+	if errRes == nil || errRes.Description != ErrUnauthorized {
+		t.Fatalf("a right code after %d wrong ones = %v, want the challenge given up", maxChallengeAttempts, errRes)
+	}
+	if _, errRes := srv.CompleteChallenge(newChallenge(t, srv, "u1"), currentCode(t, enrolled.Secret, 0)); errRes != nil {
+		t.Errorf("a new challenge should log in, got %v", errRes)
+	}
+}
+
+func newChallenge(t *testing.T, srv TwoFactorSrv, accountId string) string {
+	t.Helper()
+	challenge, err := srv.Challenge(accountId, "user", "sam@example.com")
+	if err != nil {
+		t.Fatalf("Challenge() error = %v", err)
+	}
+	return challenge
+}
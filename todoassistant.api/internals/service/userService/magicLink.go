@@ -0,0 +1,150 @@
+package userService
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/eventEntity"
+	"test-va/internals/entity/userEntity"
+
+	"github.com/google/uuid"
+)
+
+const (
+	magicLinkLifetime = time.Minute * 15
+	// links asked for in the last hour, an IP address can ask for a few emails behind the same NAT
+	magicLinksPerEmail = 5
+	magicLinksPerIP    = 20
+)
+
+// Request Magic Link godoc
+// @Summary	Email a link to log in without a password
+// @Description	Sends a single use link that expires in 15 minutes. The same response is returned for emails without an account. The device code returned has to be sent with the token from the link, so only this device can use it. Five links an hour can be asked for an email and twenty from an IP address.
+// @Tags	Users
+// @Accept	json
+// @Produce	json
+// @Param	request	body	userEntity.MagicLinkReq	true	"Email of the account"
+// @Success	200  {object}  userEntity.MagicLinkRes
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	429  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Router	/user/magic-link [post]
+func (u *userSrv) RequestMagicLink(req *userEntity.MagicLinkReq) (*userEntity.MagicLinkRes, *ResponseEntity.ServiceError) {
+	err := u.validator.Validate(req)
+	if err != nil {
+		return nil, ResponseEntity.NewValidatingError(err)
+	}
+
+	now := time.Now().UTC()
+	byEmail, byIP, err := u.repo.CountMagicLinks(req.Email, req.IPAddress, now.Add(-time.Hour).Format(time.RFC3339))
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if byEmail >= magicLinksPerEmail || byIP >= magicLinksPerIP {
+		return nil, ResponseEntity.NewCustomServiceError(ErrTooManyRequests, "too many login links were asked for, try again later")
+	}
+
+	user, err := u.repo.GetByEmail(req.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		user = nil
+	} else if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	token, deviceCode := uuid.New().String(), uuid.New().String()
+	link := &userEntity.MagicLink{
+		TokenHash:  hashToken(token),
+		Email:      req.Email,
+		DeviceHash: hashToken(deviceCode),
+		IPAddress:  req.IPAddress,
+		ExpiresAt:  now.Add(magicLinkLifetime).Format(time.RFC3339),
+		CreatedAt:  now.Format(time.RFC3339),
+	}
+	if user != nil {
+		link.UserId = user.UserId
+	}
+	// requests for unknown emails are stored too, they count towards the limits
+	err = u.repo.AddMagicLink(link)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	if user != nil {
+		payload := eventEntity.Payload{
+			Action:    "email",
+			SubAction: "magic_link",
+			Data: map[string]string{
+				"email_address": user.Email,
+				"email_subject": "Subject: Your getticked login link\n",
+				"email_body":    createMagicLinkBody(user.FirstName, user.LastName, u.appLink("magic-link", token)),
+			},
+		}
+		err = u.Emitter.Push(payload, "info")
+		if err != nil {
+			return nil, ResponseEntity.NewInternalServiceError(err)
+		}
+	}
+
+	return &userEntity.MagicLinkRes{DeviceCode: deviceCode, ExpiresAt: link.ExpiresAt}, nil
+}
+
+// Redeem Magic Link godoc
+// @Summary	Log in with a magic link
+// @Description	Trades the token from the link and the device code returned when it was asked for, for the login tokens. With two-factor authentication on, no tokens are returned but a challenge token for /user/login/2fa.
+// @Tags	Users
+// @Accept	json
+// @Produce	json
+// @Param	request	body	userEntity.RedeemMagicLinkReq	true	"Token from the link and the device code"
+// @Success	200  {object}  userEntity.LoginRes
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Router	/user/magic-link/redeem [post]
+func (u *userSrv) RedeemMagicLink(req *userEntity.RedeemMagicLinkReq) (*userEntity.LoginRes, *ResponseEntity.ServiceError) {
+	err := u.validator.Validate(req)
+	if err != nil {
+		return nil, ResponseEntity.NewValidatingError(err)
+	}
+
+	link, err := u.repo.GetMagicLink(hashToken(req.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ResponseEntity.NewValidatingError("login link is invalid or has expired")
+	}
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if link.UserId == "" || link.UsedAt != "" || link.ExpiresAt < time.Now().UTC().Format(time.RFC3339) {
+		return nil, ResponseEntity.NewValidatingError("login link is invalid or has expired")
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(req.DeviceCode)), []byte(link.DeviceHash)) != 1 {
+		return nil, ResponseEntity.NewCustomServiceError(ErrForbidden, "open the login link on the device it was asked for from")
+	}
+
+	used, err := u.repo.UseMagicLink(link.TokenHash, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if !used {
+		return nil, ResponseEntity.NewValidatingError("login link is invalid or has expired")
+	}
+
+	user, err := u.repo.GetByEmail(link.Email)
+	if err != nil || user.UserId != link.UserId {
+		// the email was changed since the link was sent
+		log.Println(err)
+		return nil, ResponseEntity.NewValidatingError("login link is invalid or has expired")
+	}
+	return u.firstFactorPassed(user, &req.SessionInfo)
+}
+
+func createMagicLinkBody(firstName, lastName, link string) string {
+	subject := fmt.Sprintf("Hi %v %v, \n\n", firstName, lastName)
+	mainBody := fmt.Sprintf("Use this link to log in to getticked, on the device you asked for it from:\n%v\n\nIf you did not ask to log in, you can ignore this email, nobody can use the link without that device.\n\nLink expires in %v minutes!",
+		link, int(magicLinkLifetime.Minutes()))
+	return subject + mainBody
+}
@@ -1,6 +1,11 @@
 package userService
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"mime/multipart"
@@ -9,33 +14,39 @@ import (
 
 	"test-va/internals/Repository/userRepo"
 	"test-va/internals/entity/ResponseEntity"
-	"test-va/internals/entity/emailEntity"
 	"test-va/internals/entity/eventEntity"
+	"test-va/internals/entity/passkeyEntity"
+	"test-va/internals/entity/tokenEntity"
+	"test-va/internals/entity/twoFactorEntity"
 	"test-va/internals/entity/userEntity"
 	"test-va/internals/msg-queue/Emitter"
 	"test-va/internals/service/awsService"
 	"test-va/internals/service/cryptoService"
 	"test-va/internals/service/emailService"
+	"test-va/internals/service/loginAlertService"
+	"test-va/internals/service/loginGuardService"
+	"test-va/internals/service/passkeyService"
+	"test-va/internals/service/passwordService"
 	"test-va/internals/service/timeSrv"
 	tokenservice "test-va/internals/service/tokenService"
+	"test-va/internals/service/twoFactorService"
 	"test-va/internals/service/validationService"
 
 	"github.com/google/uuid"
-
-	"math/rand"
 )
 
 type UserSrv interface {
 	SaveUser(req *userEntity.CreateUserReq) (*userEntity.CreateUserRes, *ResponseEntity.ServiceError)
 	Login(req *userEntity.LoginReq) (*userEntity.LoginRes, *ResponseEntity.ServiceError)
+	LoginTwoFactor(req *twoFactorEntity.ChallengeReq) (*userEntity.LoginRes, *ResponseEntity.ServiceError)
 	GetUsers(page int) ([]*userEntity.UsersRes, error)
 	GetUser(user_id string) (*userEntity.GetByIdRes, error)
 	UpdateUser(req *userEntity.UpdateUserReq, userId string) (*userEntity.UpdateUserRes, *ResponseEntity.ServiceError)
 	UploadImage(file *multipart.FileHeader, userId string) (*userEntity.ProfileImageRes, error)
 	ChangePassword(req *userEntity.ChangePasswordReq) *ResponseEntity.ServiceError
 	ResetPassword(req *userEntity.ResetPasswordReq) (*userEntity.ResetPasswordRes, *ResponseEntity.ServiceError)
-	ResetPasswordWithToken(req *userEntity.ResetPasswordWithTokenReq, token, userId string) *ResponseEntity.ServiceError
-	DeleteUser(user_id string) error
+	// ResetPasswordWithToken returns the id of the user whose password was reset.
+	ResetPasswordWithToken(req *userEntity.ResetPasswordWithTokenReq) (string, *ResponseEntity.ServiceError)
 	AssignVAToUser(user_id, va_id string) *ResponseEntity.ServiceError
 	SetReminderSettings(req *userEntity.ReminderSettingsReq, userId string) (*userEntity.ReminderSettingsRes, *ResponseEntity.ServiceError)
 	GetReminderSettings(userId string) (*userEntity.ReminderSettingsRes, *ResponseEntity.ServiceError)
@@ -43,8 +54,35 @@ type UserSrv interface {
 	UpdateReminderSettings(req *userEntity.ReminderSettingsReq, userId string) (*userEntity.ReminderSettingsRes, *ResponseEntity.ServiceError)
 	UpdateProductEmailSettings(req *userEntity.ProductEmailSettingsReq, userId string) (*userEntity.ProductEmailSettingsRes, *ResponseEntity.ServiceError)
 	UpdateNotificationSettings(req *userEntity.NotificationSettingsReq, userId string) (*userEntity.NotificationSettingsRes, *ResponseEntity.ServiceError)
+	VerifyEmail(req *userEntity.VerifyEmailReq) *ResponseEntity.ServiceError
+	ResendVerification(userId string) *ResponseEntity.ServiceError
+	RequestMagicLink(req *userEntity.MagicLinkReq) (*userEntity.MagicLinkRes, *ResponseEntity.ServiceError)
+	RedeemMagicLink(req *userEntity.RedeemMagicLinkReq) (*userEntity.LoginRes, *ResponseEntity.ServiceError)
+	BeginPasskeyLogin(req *passkeyEntity.LoginBeginReq) (*passkeyEntity.RequestOptions, *ResponseEntity.ServiceError)
+	LoginPasskey(req *passkeyEntity.AssertionReq) (*userEntity.LoginRes, *ResponseEntity.ServiceError)
 }
 
+const (
+	ErrForbidden       = "Forbidden"
+	ErrTooManyRequests = "Too Many Requests"
+)
+
+const (
+	emailVerificationLifetime = time.Hour * 24
+	// a new verification link can be requested once a minute, and a few times an hour
+	verificationResendAfter = time.Minute
+	verificationsPerHour    = 5
+)
+
+const (
+	passwordResetLifetime = time.Minute * 30
+	// a reset link is spent after this many wrong tokens for it
+	passwordResetAttempts = 5
+	// links asked for in the last hour, as for magic links
+	passwordResetsPerEmail = 5
+	passwordResetsPerIP    = 20
+)
+
 type userSrv struct {
 	repo      userRepo.UserRepository
 	validator validationService.ValidationSrv
@@ -54,18 +92,30 @@ type userSrv struct {
 	awsSrv    awsService.AWSService
 	tokenSrv  tokenservice.TokenSrv
 	Emitter   Emitter.Emitter
+
+	twoFactorSrv twoFactorService.TwoFactorSrv
+	passkeySrv   passkeyService.PasskeySrv
+	loginGuard   loginGuardService.LoginGuardSrv
+	loginAlert   loginAlertService.LoginAlertSrv
+	passwordSrv  passwordService.PasswordSrv
+	// appBaseUrl is where the links sent by email point to
+	appBaseUrl string
 }
 
 // Login User godoc
 // @Summary	Provide email and password to be logged in
-// @Description	Login to the server
+// @Description	Login to the server. With two-factor authentication on, no tokens are returned but a challenge token for /user/login/2fa.
+// @Description	After a few wrong passwords each try has to wait twice as long as the one before, after ten the account is locked for 30 minutes.
 // @Tags	Users
 // @Accept	json
 // @Produce	json
 // @Param	request	body	userEntity.LoginReq	true "Login Details"
 // @Success	200  {object}  userEntity.LoginRes
 // @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
 // @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	423  {object}  ResponseEntity.ServiceError
+// @Failure	429  {object}  ResponseEntity.ServiceError
 // @Failure	500  {object}  ResponseEntity.ServiceError
 // @Router	/user/login [post]
 func (u *userSrv) Login(req *userEntity.LoginReq) (*userEntity.LoginRes, *ResponseEntity.ServiceError) {
@@ -73,21 +123,154 @@ func (u *userSrv) Login(req *userEntity.LoginReq) (*userEntity.LoginRes, *Respon
 	if err != nil {
 		return nil, ResponseEntity.NewValidatingError(err)
 	}
+	if errRes := u.loginGuard.Check(loginGuardService.AccountUser, req.Email, req.IPAddress); errRes != nil {
+		return nil, errRes
+	}
 	// FIND BY EMAIL
 	user, err := u.repo.GetByEmail(req.Email)
 	if err != nil {
+		u.loginGuard.Failed(loginGuardService.AccountUser, req.Email, req.IPAddress, "")
 		return nil, ResponseEntity.NewInternalServiceError("Invalid Login Credentials")
 	}
 	//compare password
 	err = u.cryptoSrv.ComparePassword(user.Password, req.Password)
 	if err != nil {
+		u.loginGuard.Failed(loginGuardService.AccountUser, req.Email, req.IPAddress, user.FirstName)
 		return nil, ResponseEntity.NewInternalServiceError("Passwords Don't Match")
 	}
+	return u.firstFactorPassed(user, &req.SessionInfo)
+}
 
-	token, refreshToken, errToken := u.tokenSrv.CreateToken(user.UserId, "user", req.Email)
+// Login Two Factor godoc
+// @Summary	Finish logging in with a two-factor code
+// @Description	Trades the challenge token returned by /user/login and a code from the authenticator app, or a recovery code, for the login tokens
+// @Tags	Users
+// @Accept	json
+// @Produce	json
+// @Param	request	body	twoFactorEntity.ChallengeReq	true	"Challenge token and code"
+// @Success	200  {object}  userEntity.LoginRes
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	401  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	423  {object}  ResponseEntity.ServiceError
+// @Failure	429  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Router	/user/login/2fa [post]
+func (u *userSrv) LoginTwoFactor(req *twoFactorEntity.ChallengeReq) (*userEntity.LoginRes, *ResponseEntity.ServiceError) {
+	challenge, errRes := u.twoFactorSrv.ChallengeAccount(req.ChallengeToken)
+	if errRes != nil {
+		return nil, errRes
+	}
+	if challenge.Status != "user" {
+		return nil, ResponseEntity.NewCustomServiceError(twoFactorService.ErrUnauthorized, "log in as a VA instead")
+	}
+	user, err := u.repo.GetByEmail(challenge.Email)
+	if err != nil || user.UserId != challenge.Id {
+		return nil, ResponseEntity.NewCustomServiceError(twoFactorService.ErrUnauthorized, "the login has expired, log in again")
+	}
+	// wrong codes count as failed logins, so a known password does not make the account free to guess at
+	if errRes := u.loginGuard.Check(loginGuardService.AccountUser, user.Email, req.IPAddress); errRes != nil {
+		return nil, errRes
+	}
+	_, errRes = u.twoFactorSrv.CompleteChallenge(req.ChallengeToken, req.Code)
+	if errRes != nil {
+		if errRes.Description == twoFactorService.ErrUnauthorized {
+			u.loginGuard.Failed(loginGuardService.AccountUser, user.Email, req.IPAddress, user.FirstName)
+		}
+		return nil, errRes
+	}
+
+	req.TwoFactor = true
+	return u.loginResponse(user, &req.SessionInfo)
+}
+
+// Begin Passkey Login godoc
+// @Summary	Start logging in with a passkey
+// @Description	Returns the options for navigator.credentials.get. With an email only the passkeys of that account are allowed, without one the authenticator offers its passkeys.
+// @Tags	Users
+// @Accept	json
+// @Produce	json
+// @Param	request	body	passkeyEntity.LoginBeginReq	false	"Email of the account"
+// @Success	200  {object}  passkeyEntity.RequestOptions
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Router	/user/passkeys/login/begin [post]
+func (u *userSrv) BeginPasskeyLogin(req *passkeyEntity.LoginBeginReq) (*passkeyEntity.RequestOptions, *ResponseEntity.ServiceError) {
+	accountId := ""
+	if req.Email != "" {
+		// unknown emails get the options of a login without an email, so they cannot be told apart
+		if user, err := u.repo.GetByEmail(req.Email); err == nil {
+			accountId = user.UserId
+		}
+	}
+	return u.passkeySrv.BeginLogin(passkeyEntity.AccountUser, accountId)
+}
+
+// Passkey Login godoc
+// @Summary	Log in with a passkey
+// @Description	Checks the credential navigator.credentials.get returned. A passkey that verified the user with a PIN or biometric counts as two factors, otherwise a two-factor code is still asked for when it is on.
+// @Tags	Users
+// @Accept	json
+// @Produce	json
+// @Param	request	body	passkeyEntity.AssertionReq	true	"Credential from the authenticator"
+// @Success	200  {object}  userEntity.LoginRes
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	401  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Router	/user/passkeys/login/finish [post]
+func (u *userSrv) LoginPasskey(req *passkeyEntity.AssertionReq) (*userEntity.LoginRes, *ResponseEntity.ServiceError) {
+	assertion, errRes := u.passkeySrv.FinishLogin(passkeyEntity.AccountUser, req)
+	if errRes != nil {
+		return nil, errRes
+	}
+	account, err := u.repo.GetById(assertion.AccountId)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	user, err := u.repo.GetByEmail(account.Email)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	if assertion.UserVerified {
+		req.TwoFactor = true
+		return u.loginResponse(user, &req.SessionInfo)
+	}
+	return u.firstFactorPassed(user, &req.SessionInfo)
+}
+
+// firstFactorPassed logs the user in, or asks for a two-factor code when it is on.
+func (u *userSrv) firstFactorPassed(user *userEntity.GetByEmailRes, session *tokenEntity.SessionInfo) (*userEntity.LoginRes, *ResponseEntity.ServiceError) {
+	if errRes := loginAllowed(user); errRes != nil {
+		return nil, errRes
+	}
+	enabled, err := u.twoFactorSrv.IsEnabled(user.UserId)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if enabled {
+		challenge, err := u.twoFactorSrv.Challenge(user.UserId, "user", user.Email)
+		if err != nil {
+			return nil, ResponseEntity.NewInternalServiceError("Cannot create access token!")
+		}
+		return &userEntity.LoginRes{UserId: user.UserId, Email: user.Email, TwoFactorRequired: true, ChallengeToken: challenge}, nil
+	}
+	return u.loginResponse(user, session)
+}
+
+// loginResponse starts a session for a user whose credentials were checked.
+func (u *userSrv) loginResponse(user *userEntity.GetByEmailRes, session *tokenEntity.SessionInfo) (*userEntity.LoginRes, *ResponseEntity.ServiceError) {
+	// the account can be suspended or reported between the first and the second factor
+	if errRes := loginAllowed(user); errRes != nil {
+		return nil, errRes
+	}
+	token, refreshToken, errToken := u.tokenSrv.CreateToken(user.UserId, "user", user.Email, session)
 	if errToken != nil {
 		return nil, ResponseEntity.NewInternalServiceError("Cannot create access token!")
 	}
+	// failures are only forgotten once a session is issued, not when the password alone matched
+	u.loginGuard.Succeeded(loginGuardService.AccountUser, user.Email)
+	u.loginAlert.Notify(user.UserId, user.Email, user.FirstName, session)
 	notificationSettings, _ := u.repo.GetNotificationSettingsById(user.UserId)
 	// if err != nil {
 	// 	return nil, ResponseEntity.NewInternalServiceError("unable to get notification settings")
@@ -106,6 +289,7 @@ func (u *userSrv) Login(req *userEntity.LoginReq) (*userEntity.LoginRes, *Respon
 		CountryId:            user.CountryId,
 		NotificationSettings: *notificationSettings,
 		ProductEmailSettings: *productEmailSettings,
+		EmailVerified:        user.AccountStatus != userEntity.AccountUnverified,
 		Token:                token,
 		RefreshToken:         refreshToken,
 	}
@@ -114,7 +298,8 @@ func (u *userSrv) Login(req *userEntity.LoginReq) (*userEntity.LoginRes, *Respon
 
 // Register User godoc
 // @Summary	Register route
-// @Description	Register route
+// @Description	Register route. The account stays unverified until the link sent to the email is followed, unverified accounts cannot be assigned a VA.
+// @Description	The password needs 8 characters, cannot be the email or a name, and cannot be a known breached password. Each rule it breaks is returned as a field error.
 // @Tags	Users
 // @Accept	json
 // @Produce	json
@@ -130,6 +315,11 @@ func (u *userSrv) SaveUser(req *userEntity.CreateUserReq) (*userEntity.CreateUse
 		log.Println(err)
 		return nil, ResponseEntity.NewValidatingError(err)
 	}
+	errRes := u.passwordSrv.Check("password", req.Password,
+		passwordService.Account{Email: req.Email, FirstName: req.FirstName, LastName: req.LastName})
+	if errRes != nil {
+		return nil, errRes
+	}
 	// check if user with that email exists already
 
 	_, err = u.repo.GetByEmail(req.Email)
@@ -146,7 +336,7 @@ func (u *userSrv) SaveUser(req *userEntity.CreateUserReq) (*userEntity.CreateUse
 	//set time and etc
 	req.UserId = uuid.New().String()
 	req.Password = password
-	req.AccountStatus = "ACTIVE"
+	req.AccountStatus = userEntity.AccountUnverified
 	//req.DateCreated = u.timeSrv.CurrentTime().Format(time.RFC3339)
 
 	// save to DB
@@ -156,7 +346,13 @@ func (u *userSrv) SaveUser(req *userEntity.CreateUserReq) (*userEntity.CreateUse
 		return nil, ResponseEntity.NewInternalServiceError(err)
 	}
 
-	token, refreshToken, errToken := u.tokenSrv.CreateToken(req.UserId, "user", req.Email)
+	// the user can ask for another link if this one is lost
+	err = u.sendVerification(req.UserId, req.FirstName, req.LastName, req.Email)
+	if err != nil {
+		log.Println("could not send verification email", err)
+	}
+
+	token, refreshToken, errToken := u.tokenSrv.CreateToken(req.UserId, "user", req.Email, &req.SessionInfo)
 	if errToken != nil {
 		return nil, ResponseEntity.NewInternalServiceError("Cannot create access token!")
 	}
@@ -177,7 +373,7 @@ func (u *userSrv) SaveUser(req *userEntity.CreateUserReq) (*userEntity.CreateUse
 
 // Update User godoc
 // @Summary	Update a user profile
-// @Description	Register route
+// @Description	Update profile route. A new email is only used once it is confirmed through the link sent to it, until then it is returned as pending_email.
 // @Tags	Users
 // @Accept	json
 // @Produce	json
@@ -186,6 +382,7 @@ func (u *userSrv) SaveUser(req *userEntity.CreateUserReq) (*userEntity.CreateUse
 // @Success	200  {object}  userEntity.UpdateUserRes
 // @Failure	400  {object}  ResponseEntity.ServiceError
 // @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	429  {object}  ResponseEntity.ServiceError
 // @Failure	500  {object}  ResponseEntity.ServiceError
 // @Security ApiKeyAuth
 // @Router	/user/{userId} [put]
@@ -195,19 +392,46 @@ func (u *userSrv) UpdateUser(req *userEntity.UpdateUserReq, userId string) (*use
 		return nil, ResponseEntity.NewValidatingError(err)
 	}
 
+	user, err := u.repo.GetById(userId)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+
+	// the email only changes once the new address is confirmed
+	pendingEmail := ""
+	if req.Email != "" && !strings.EqualFold(req.Email, user.Email) {
+		if _, err := u.repo.GetByEmail(req.Email); err == nil {
+			return nil, ResponseEntity.NewValidatingError("email is already in use")
+		}
+		if errRes := u.throttleVerification(userId); errRes != nil {
+			return nil, errRes
+		}
+		err = u.repo.SetPendingEmail(userId, req.Email)
+		if err != nil {
+			return nil, ResponseEntity.NewInternalServiceError(err)
+		}
+		err = u.sendVerification(userId, user.FirstName, user.LastName, req.Email)
+		if err != nil {
+			return nil, ResponseEntity.NewInternalServiceError(err)
+		}
+		pendingEmail = req.Email
+	}
+	req.Email = user.Email
+
 	err = u.repo.UpdateUser(req, userId)
 	if err != nil {
 		return nil, ResponseEntity.NewInternalServiceError(err)
 	}
 	data := &userEntity.UpdateUserRes{
-		FirstName:  req.FirstName,
-		LastName:   req.LastName,
-		Email:      req.Email,
-		Phone:      req.Phone,
-		Gender:     req.Gender,
-		Avatar:     req.Avatar,
-		Occupation: req.Occupation,
-		CountryId:  req.CountryId,
+		FirstName:    req.FirstName,
+		LastName:     req.LastName,
+		Email:        req.Email,
+		Phone:        req.Phone,
+		Gender:       req.Gender,
+		Avatar:       req.Avatar,
+		Occupation:   req.Occupation,
+		CountryId:    req.CountryId,
+		PendingEmail: pendingEmail,
 	}
 
 	return data, nil
@@ -257,7 +481,7 @@ func (u *userSrv) UploadImage(file *multipart.FileHeader, userId string) (*userE
 
 // Change Password godoc
 // @Summary	Change a user password
-// @Description	Change password route
+// @Description	Change password route. Every session of the user, the current one included, is logged out.
 // @Tags	Users
 // @Accept	json
 // @Produce	json
@@ -289,6 +513,11 @@ func (u *userSrv) ChangePassword(req *userEntity.ChangePasswordReq) *ResponseEnt
 		log.Println(err)
 		return ResponseEntity.NewInternalServiceError("Passwords do not match!")
 	}
+	errRes := u.passwordSrv.Check("new_password", req.NewPassword,
+		passwordService.Account{Email: user.Email, FirstName: user.FirstName, LastName: user.LastName})
+	if errRes != nil {
+		return errRes
+	}
 
 	// Check if new password is the same as old password
 	//err = u.cryptoSrv.ComparePassword(user.Password, req.NewPassword)
@@ -303,6 +532,10 @@ func (u *userSrv) ChangePassword(req *userEntity.ChangePasswordReq) *ResponseEnt
 	if err != nil {
 		return ResponseEntity.NewInternalServiceError("Could not change password!")
 	}
+	if err = u.tokenSrv.LogoutAll(req.UserId); err != nil {
+		log.Println(err)
+		return ResponseEntity.NewInternalServiceError("Password changed but could not log out other sessions!")
+	}
 
 	// send email to user
 	subject := fmt.Sprintf("Hi %v %v, \n\n", user.FirstName, user.LastName)
@@ -384,151 +617,157 @@ func (u *userSrv) GetUser(user_id string) (*userEntity.GetByIdRes, error) {
 	return user, nil
 }
 
-// Delete User godoc
-// @Summary	Delete a user from the database
-// @Description	Delete route
-// @Tags	Users
-// @Accept	json
-// @Produce	json
-// @Param	userId	path	string	true	"User Id"
-// @Success	200  {string}  string    "ok"
-// @Failure	400  {object}  ResponseEntity.ServiceError
-// @Failure	404  {object}  ResponseEntity.ServiceError
-// @Failure	500  {object}  ResponseEntity.ServiceError
-// @Security ApiKeyAuth
-// @Router	/user/{userId} [delete]
-func (u *userSrv) DeleteUser(user_id string) error {
-	_, idErr := u.repo.GetById(user_id)
-	if idErr != nil {
-		return idErr
-	}
-
-	delErr := u.repo.DeleteUser(user_id)
-	if delErr != nil {
-		return delErr
-	}
-
-	return nil
-}
-
 // Reset password godoc
-// @Summary	Generate a token to reset users password
-// @Description	Generate token
+// @Summary	Email a link to reset a forgotten password
+// @Description	Sends a single use link that expires in 30 minutes and replaces any link sent before. The same response is returned for emails without an account. Five links an hour can be asked for an email and twenty from an IP address.
 // @Tags	Users
 // @Accept	json
 // @Produce	json
 // @Param	request	body userEntity.ResetPasswordReq	true "Input your email"
 // @Success	200  {object}  userEntity.ResetPasswordRes
 // @Failure	400  {object}  ResponseEntity.ServiceError
-// @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	429  {object}  ResponseEntity.ServiceError
 // @Failure	500  {object}  ResponseEntity.ServiceError
 // @Router	/user/reset-password [post]
 func (u *userSrv) ResetPassword(req *userEntity.ResetPasswordReq) (*userEntity.ResetPasswordRes, *ResponseEntity.ServiceError) {
-	var token userEntity.ResetPasswordRes
-	var message emailEntity.SendEmailReq
-
 	err := u.validator.Validate(req)
 	if err != nil {
-		return nil, ResponseEntity.NewInternalServiceError(err)
+		return nil, ResponseEntity.NewValidatingError(err)
 	}
 
-	// Check if the user exists, if he/she doesn't return error
-	user, err := u.repo.GetByEmail(req.Email)
+	now := time.Now().UTC()
+	byEmail, byIP, err := u.repo.CountPasswordResets(req.Email, req.IPAddress, now.Add(-time.Hour).Format(time.RFC3339))
 	if err != nil {
 		return nil, ResponseEntity.NewInternalServiceError(err)
 	}
-
-	// Delete old tokens from system
-	err = u.repo.DeleteToken(user.UserId)
-	if err != nil {
-		return nil, ResponseEntity.NewInternalServiceError(err)
+	if byEmail >= passwordResetsPerEmail || byIP >= passwordResetsPerIP {
+		return nil, ResponseEntity.NewCustomServiceError(ErrTooManyRequests, "too many reset links were asked for, try again later")
 	}
 
-	// Create token, add to database and then send to user's email address
-	token.UserId = user.UserId
-	token.TokenId = uuid.New().String()
-	token.Token = generateToken(4)
-	token.Expiry = time.Now().Add(time.Minute * 30).Format(time.RFC3339)
-
-	err = u.repo.AddToken(&token)
-	if err != nil {
+	user, err := u.repo.GetByEmail(req.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		user = nil
+	} else if err != nil {
 		return nil, ResponseEntity.NewInternalServiceError(err)
 	}
 
-	// Send message to users email, if it exists
-	message.EmailAddress = user.Email
-	message.EmailSubject = "Subject: Reset Password Token\n"
-	message.EmailBody = createMessageBody(user.FirstName, user.LastName, token.Token)
-
-	// err = u.emailSrv.SendMail(message)
-	// if err != nil {
-	// 	return nil, ResponseEntity.NewInternalServiceError(err)
-	// }
-	// push event to queue
-	payload := eventEntity.Payload{
-		Action:    "email",
-		SubAction: "subscription",
-		Data: map[string]string{
-			"email_address": req.Email,
-			"email_subject": "Subject: Request to Reset Password\n",
-			"email_body":    createMessageBody(user.FirstName, user.LastName, token.Token),
-		},
+	// the token names its reset, so a wrong secret can be counted against it
+	secret := uuid.New().String()
+	reset := &userEntity.PasswordReset{
+		ResetId:    uuid.New().String(),
+		Email:      req.Email,
+		SecretHash: hashToken(secret),
+		IPAddress:  req.IPAddress,
+		ExpiresAt:  now.Add(passwordResetLifetime).Format(time.RFC3339),
+		CreatedAt:  now.Format(time.RFC3339),
 	}
-
-	err = u.Emitter.Push(payload, "info")
+	if user != nil {
+		reset.UserId = user.UserId
+	}
+	// requests for unknown emails are stored too, they count towards the limits
+	err = u.repo.AddPasswordReset(reset)
 	if err != nil {
 		return nil, ResponseEntity.NewInternalServiceError(err)
 	}
 
-	return &token, nil
+	if user != nil {
+		payload := eventEntity.Payload{
+			Action:    "email",
+			SubAction: "subscription",
+			Data: map[string]string{
+				"email_address": user.Email,
+				"email_subject": "Subject: Request to Reset Password\n",
+				"email_body":    createMessageBody(user.FirstName, user.LastName, u.appLink("reset-password", reset.ResetId+"."+secret)),
+			},
+		}
+		err = u.Emitter.Push(payload, "info")
+		if err != nil {
+			return nil, ResponseEntity.NewInternalServiceError(err)
+		}
+	}
+
+	return &userEntity.ResetPasswordRes{ExpiresAt: reset.ExpiresAt}, nil
 }
 
 // Reset password with token godoc
 // @Summary	Check the provided token and reset the user's password
-// @Description	Reset password
+// @Description	Reset password with the token from the emailed link. The link works once, and stops working after five wrong tokens for it. Every session of the user is logged out.
 // @Tags	Users
 // @Accept	json
 // @Produce	json
-// @Param	token	query	string	true	"Token"
-// @Param	user_id	query	string	true	"User Id"
+// @Param	request	body	userEntity.ResetPasswordWithTokenReq	true	"Token and new password"
+// @Param	token	query	string	false	"Token, when it is not in the body"
 // @Success	200  {string}  string    "ok"
 // @Failure	400  {object}  ResponseEntity.ServiceError
-// @Failure	404  {object}  ResponseEntity.ServiceError
 // @Failure	500  {object}  ResponseEntity.ServiceError
 // @Router	/reset-password-token [post]
-func (u *userSrv) ResetPasswordWithToken(req *userEntity.ResetPasswordWithTokenReq, token, userId string) *ResponseEntity.ServiceError {
+func (u *userSrv) ResetPasswordWithToken(req *userEntity.ResetPasswordWithTokenReq) (string, *ResponseEntity.ServiceError) {
 	err := u.validator.Validate(req)
 	if err != nil {
-		return ResponseEntity.NewInternalServiceError(err)
+		return "", ResponseEntity.NewValidatingError(err)
 	}
 
-	tokenDB, err := u.repo.GetTokenById(token, userId)
-	fmt.Println(tokenDB)
+	invalid := ResponseEntity.NewValidatingError("reset link is invalid or has expired")
+	resetId, secret, ok := strings.Cut(req.Token, ".")
+	if !ok {
+		return "", invalid
+	}
+	reset, err := u.repo.GetPasswordReset(resetId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", invalid
+	}
 	if err != nil {
-		return ResponseEntity.NewInternalServiceError("Invalid access token!")
+		return "", ResponseEntity.NewInternalServiceError(err)
 	}
-
-	timeNow := time.Now().Format(time.RFC3339)
-	if tokenDB.Expiry < timeNow {
-		return ResponseEntity.NewInternalServiceError("Token has expired!")
+	now := time.Now().UTC().Format(time.RFC3339)
+	if reset.UserId == "" || reset.UsedAt != "" || reset.ExpiresAt < now || reset.Attempts >= passwordResetAttempts {
+		return "", invalid
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(reset.SecretHash)) != 1 {
+		if err := u.repo.FailPasswordReset(reset.ResetId); err != nil {
+			log.Println(err)
+		}
+		return "", invalid
 	}
 
-	user, err := u.repo.GetById(tokenDB.UserId)
-	if err != nil {
-		return ResponseEntity.NewInternalServiceError("Check the user!")
+	user, err := u.repo.GetById(reset.UserId)
+	if err != nil || !strings.EqualFold(user.Email, reset.Email) {
+		// the email was changed since the link was sent
+		log.Println(err)
+		return "", invalid
 	}
 
 	err = u.cryptoSrv.ComparePassword(user.Password, req.Password)
 	if err == nil {
-		return ResponseEntity.NewInternalServiceError("The new password cannot be the same as your old password!")
+		return "", ResponseEntity.NewInternalServiceError("The new password cannot be the same as your old password!")
+	}
+	errRes := u.passwordSrv.Check("password", req.Password,
+		passwordService.Account{Email: user.Email, FirstName: user.FirstName, LastName: user.LastName})
+	if errRes != nil {
+		return "", errRes
+	}
+
+	// a password the policy refused leaves the link working for another try
+	used, err := u.repo.UsePasswordReset(reset.ResetId, now)
+	if err != nil {
+		return "", ResponseEntity.NewInternalServiceError(err)
+	}
+	if !used {
+		return "", invalid
 	}
 
 	// Create a new password hash
 	newPassword, _ := u.cryptoSrv.HashPassword(req.Password)
-	err = u.repo.ChangePassword(tokenDB.UserId, newPassword)
+	err = u.repo.ChangePassword(reset.UserId, newPassword)
 	if err != nil {
-		return ResponseEntity.NewInternalServiceError("Could not change password!")
+		return "", ResponseEntity.NewInternalServiceError("Could not change password!")
+	}
+	if err = u.tokenSrv.LogoutAll(reset.UserId); err != nil {
+		log.Println(err)
+		return "", ResponseEntity.NewInternalServiceError("Password changed but could not log out other sessions!")
 	}
+	// a new password lifts a lockout, guessing the old one is pointless now
+	u.loginGuard.Succeeded(loginGuardService.AccountUser, user.Email)
 	// send email to user
 	subject := fmt.Sprintf("Hi %v %v, \n\n", user.FirstName, user.LastName)
 	mainBody := subject + "your password has been changed successfully.\nBut if this action was not requested by you.\nPlease inform us.\nthank you. "
@@ -546,9 +785,9 @@ func (u *userSrv) ResetPasswordWithToken(req *userEntity.ResetPasswordWithTokenR
 	err = u.Emitter.Push(payload, "info")
 	if err != nil {
 		//an error can be returned from here but allow am first
-		return nil
+		return reset.UserId, nil
 	}
-	return nil
+	return reset.UserId, nil
 }
 
 // Assign VA To User godoc
@@ -560,12 +799,21 @@ func (u *userSrv) ResetPasswordWithToken(req *userEntity.ResetPasswordWithTokenR
 // @Param	vaId	path	string	true	"VA Id"
 // @Success	200  {string}	string	"Ok"
 // @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	403  {object}  ResponseEntity.ServiceError
 // @Failure	404  {object}  ResponseEntity.ServiceError
 // @Failure	500  {object}  ResponseEntity.ServiceError
 // @Security ApiKeyAuth
 // @Router	/assign-va/{vaId} [post]
 func (u *userSrv) AssignVAToUser(user_id, va_id string) *ResponseEntity.ServiceError {
-	err := u.repo.AssignVAToUser(user_id, va_id)
+	user, err := u.repo.GetById(user_id)
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError("Could Not Assign Va")
+	}
+	if user.AccountStatus == userEntity.AccountUnverified {
+		return ResponseEntity.NewCustomServiceError(ErrForbidden, "verify your email address before assigning a VA")
+	}
+
+	err = u.repo.AssignVAToUser(user_id, va_id)
 	if err != nil {
 		fmt.Println(err)
 		switch {
@@ -585,6 +833,9 @@ func (u *userSrv) SetReminderSettings(req *userEntity.ReminderSettingsReq, userI
 	if err != nil {
 		return nil, ResponseEntity.NewInternalServiceError(err)
 	}
+	if _, err = time.LoadLocation(req.TimeZone); err != nil {
+		return nil, ResponseEntity.NewValidatingError("Unknown time zone")
+	}
 	err = u.repo.SetReminderSettings(req, userId)
 	if err != nil {
 		log.Println(err)
@@ -596,6 +847,7 @@ func (u *userSrv) SetReminderSettings(req *userEntity.ReminderSettingsReq, userI
 		AutoReminder: req.AutoReminder,
 		ReminderTime: req.ReminderTime,
 		Refresh:      req.Refresh,
+		TimeZone:     req.TimeZone,
 	}
 
 	return data, nil
@@ -636,6 +888,9 @@ func (u *userSrv) UpdateReminderSettings(req *userEntity.ReminderSettingsReq, us
 	if err != nil {
 		return nil, ResponseEntity.NewValidatingError(err)
 	}
+	if _, err = time.LoadLocation(req.TimeZone); err != nil {
+		return nil, ResponseEntity.NewValidatingError("Unknown time zone")
+	}
 
 	err = u.repo.UpdateReminderSettings(req, userId)
 	if err != nil {
@@ -647,6 +902,7 @@ func (u *userSrv) UpdateReminderSettings(req *userEntity.ReminderSettingsReq, us
 		AutoReminder: req.AutoReminder,
 		ReminderTime: req.ReminderTime,
 		Refresh:      req.Refresh,
+		TimeZone:     req.TimeZone,
 	}
 
 	return data, nil
@@ -693,28 +949,181 @@ func (u *userSrv) UpdateNotificationSettings(req *userEntity.NotificationSetting
 	return data, nil
 }
 
-// Auxillary Function
-func generateToken(tokenLength int) string {
-	rand.Seed(time.Now().UnixNano())
-	const charset = "0123456789"
-	b := make([]byte, tokenLength)
-	for i := range b {
-		b[i] = charset[rand.Intn(len(charset))]
+// Verify Email godoc
+// @Summary	Confirm an email address with the token sent to it
+// @Description	Activates a new account, or switches the account to the email it was changed to
+// @Tags	Users
+// @Accept	json
+// @Produce	json
+// @Param	request	body	userEntity.VerifyEmailReq	true	"Token from the verification link"
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Router	/user/verify-email [post]
+func (u *userSrv) VerifyEmail(req *userEntity.VerifyEmailReq) *ResponseEntity.ServiceError {
+	err := u.validator.Validate(req)
+	if err != nil {
+		return ResponseEntity.NewValidatingError(err)
+	}
+
+	verification, err := u.repo.GetEmailVerification(hashToken(req.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ResponseEntity.NewValidatingError("verification link is invalid or has expired")
+	}
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	if verification.ExpiresAt < time.Now().UTC().Format(time.RFC3339) {
+		return ResponseEntity.NewValidatingError("verification link is invalid or has expired")
+	}
+
+	user, err := u.repo.GetById(verification.UserId)
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError(err)
 	}
-	return string(b)
+	switch {
+	case strings.EqualFold(verification.Email, user.PendingEmail):
+		// the address could have been taken since the change was asked for
+		if other, err := u.repo.GetByEmail(verification.Email); err == nil && other.UserId != user.UserId {
+			return ResponseEntity.NewValidatingError("email is already in use")
+		}
+	case strings.EqualFold(verification.Email, user.Email) && user.AccountStatus == userEntity.AccountUnverified:
+	default:
+		// the link was sent for an email change that has been replaced since
+		return ResponseEntity.NewValidatingError("verification link is invalid or has expired")
+	}
+
+	err = u.repo.VerifyEmail(user.UserId, verification.Email)
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	return nil
+}
+
+// Resend Verification godoc
+// @Summary	Send the email verification link again
+// @Description	Sends a new link to the pending email, or to the account email if it is not verified yet. Links can be requested once a minute and five times an hour.
+// @Tags	Users
+// @Produce	json
+// @Success	200  {object}  ResponseEntity.ResponseMessage
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	429  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Security ApiKeyAuth
+// @Router	/user/verify-email/resend [post]
+func (u *userSrv) ResendVerification(userId string) *ResponseEntity.ServiceError {
+	user, err := u.repo.GetById(userId)
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	email := user.PendingEmail
+	if email == "" {
+		if user.AccountStatus != userEntity.AccountUnverified {
+			return ResponseEntity.NewValidatingError("email is already verified")
+		}
+		email = user.Email
+	}
+
+	if errRes := u.throttleVerification(userId); errRes != nil {
+		return errRes
+	}
+	err = u.sendVerification(userId, user.FirstName, user.LastName, email)
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	return nil
+}
+
+// throttleVerification keeps verification links from being used to flood an inbox.
+func (u *userSrv) throttleVerification(userId string) *ResponseEntity.ServiceError {
+	now := time.Now().UTC()
+	count, latest, err := u.repo.CountEmailVerifications(userId, now.Add(-time.Hour).Format(time.RFC3339))
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError(err)
+	}
+	if count >= verificationsPerHour || latest > now.Add(-verificationResendAfter).Format(time.RFC3339) {
+		return ResponseEntity.NewCustomServiceError(ErrTooManyRequests, "a verification link was sent recently, try again later")
+	}
+	return nil
+}
+
+// sendVerification stores a new verification token for email and mails the link to it.
+func (u *userSrv) sendVerification(userId, firstName, lastName, email string) error {
+	token := uuid.New().String()
+	now := time.Now().UTC()
+	err := u.repo.AddEmailVerification(&userEntity.EmailVerification{
+		TokenHash: hashToken(token),
+		UserId:    userId,
+		Email:     email,
+		ExpiresAt: now.Add(emailVerificationLifetime).Format(time.RFC3339),
+		CreatedAt: now.Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	payload := eventEntity.Payload{
+		Action:    "email",
+		SubAction: "email_verification",
+		Data: map[string]string{
+			"email_address": email,
+			"email_subject": "Subject: Confirm your email address for getticked\n",
+			"email_body":    createVerificationBody(firstName, lastName, u.appLink("verify-email", token)),
+		},
+	}
+	return u.Emitter.Push(payload, "info")
 }
 
-func createMessageBody(firstName, lastName, token string) string {
+// appLink is the page of the web app that handles token, or only the token when there is no web app configured.
+func (u *userSrv) appLink(page, token string) string {
+	if u.appBaseUrl == "" {
+		return token
+	}
+	return fmt.Sprintf("%s/%s?token=%s", strings.TrimSuffix(u.appBaseUrl, "/"), page, token)
+}
+
+// Auxillary Function
+func createMessageBody(firstName, lastName, link string) string {
 	subject := fmt.Sprintf("Hi %v %v, \n\n", firstName, lastName)
-	mainBody := fmt.Sprintf("You have requested to reset your password, this is your otp code <b>%v</b>\nBut if you did not request for a change of password, you can ignore this email.\n\nLink expires in 30 minutes!", token)
+	mainBody := fmt.Sprintf("You have requested to reset your password, use this link to choose a new one:\n%v\nBut if you did not request for a change of password, you can ignore this email.\n\nLink expires in %v minutes!",
+		link, int(passwordResetLifetime.Minutes()))
 
 	message := subject + mainBody
 	return string(message)
 }
 
+func createVerificationBody(firstName, lastName, link string) string {
+	subject := fmt.Sprintf("Hi %v %v, \n\n", firstName, lastName)
+	mainBody := fmt.Sprintf("Please confirm this email address for your getticked account:\n%v\n\nIf you did not sign up or change your email, you can ignore this email.\n\nLink expires in 24 hours!", link)
+	return subject + mainBody
+}
+
+// loginAllowed refuses a login to a suspended account, or to one whose password has to be reset first.
+// Every way of logging in goes through it, and only once the credentials were checked.
+func loginAllowed(user *userEntity.GetByEmailRes) *ResponseEntity.ServiceError {
+	if user.AccountStatus == userEntity.AccountSuspended {
+		return ResponseEntity.NewCustomServiceError(ErrForbidden, "this account is suspended, contact support")
+	}
+	if user.PasswordResetRequired {
+		return ResponseEntity.NewCustomServiceError(ErrForbidden,
+			"a login to this account was reported as not yours, reset your password to log in again")
+	}
+	return nil
+}
+
+// hashToken is how email verification and magic link tokens are stored, so the tables cannot be used to log in.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func NewUserSrv(repo userRepo.UserRepository, validator validationService.ValidationSrv, timeSrv timeSrv.TimeService,
 	cryptoSrv cryptoService.CryptoSrv, emailSrv emailService.EmailService, awsSrv awsService.AWSService,
-	tokenSrv tokenservice.TokenSrv, emitter Emitter.Emitter) UserSrv {
+	tokenSrv tokenservice.TokenSrv, emitter Emitter.Emitter, twoFactorSrv twoFactorService.TwoFactorSrv,
+	passkeySrv passkeyService.PasskeySrv, loginGuard loginGuardService.LoginGuardSrv, loginAlert loginAlertService.LoginAlertSrv,
+	passwordSrv passwordService.PasswordSrv, appBaseUrl string) UserSrv {
 	return &userSrv{repo: repo, validator: validator, timeSrv: timeSrv,
-		cryptoSrv: cryptoSrv, emailSrv: emailSrv, awsSrv: awsSrv, tokenSrv: tokenSrv, Emitter: emitter}
+		cryptoSrv: cryptoSrv, emailSrv: emailSrv, awsSrv: awsSrv, tokenSrv: tokenSrv, Emitter: emitter,
+		twoFactorSrv: twoFactorSrv, passkeySrv: passkeySrv, loginGuard: loginGuard, loginAlert: loginAlert,
+		passwordSrv: passwordSrv, appBaseUrl: appBaseUrl}
 }
@@ -0,0 +1,640 @@
+package userService
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"test-va/internals/Repository/userRepo"
+	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/eventEntity"
+	"test-va/internals/entity/passkeyEntity"
+	"test-va/internals/entity/tokenEntity"
+	"test-va/internals/entity/twoFactorEntity"
+	"test-va/internals/entity/userEntity"
+	"test-va/internals/service/loginAlertService"
+	"test-va/internals/service/loginGuardService"
+	"test-va/internals/service/passkeyService"
+	"test-va/internals/service/passwordService"
+	tokenservice "test-va/internals/service/tokenService"
+	"test-va/internals/service/twoFactorService"
+	"test-va/internals/service/validationService"
+	"testing"
+)
+
+type memoryRepo struct {
+	userRepo.UserRepository
+	users         map[string]*userEntity.GetByIdRes
+	verifications map[string]*userEntity.EmailVerification
+	assigned      map[string]string
+	magicLinks    map[string]*userEntity.MagicLink
+	resets        map[string]*userEntity.PasswordReset
+}
+
+func newMemoryRepo(users ...*userEntity.GetByIdRes) *memoryRepo {
+	repo := &memoryRepo{
+		users:         map[string]*userEntity.GetByIdRes{},
+		verifications: map[string]*userEntity.EmailVerification{},
+		assigned:      map[string]string{},
+		magicLinks:    map[string]*userEntity.MagicLink{},
+		resets:        map[string]*userEntity.PasswordReset{},
+	}
+	for _, user := range users {
+		repo.users[user.UserId] = user
+	}
+	return repo
+}
+
+func (m *memoryRepo) GetById(userId string) (*userEntity.GetByIdRes, error) {
+	user, ok := m.users[userId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	stored := *user
+	return &stored, nil
+}
+
+func (m *memoryRepo) GetByEmail(email string) (*userEntity.GetByEmailRes, error) {
+	for _, user := range m.users {
+		if strings.EqualFold(user.Email, email) {
+			return &userEntity.GetByEmailRes{UserId: user.UserId, Email: user.Email, FirstName: user.FirstName,
+				Password: user.Password, AccountStatus: user.AccountStatus, PasswordResetRequired: user.PasswordResetRequired}, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryRepo) UpdateUser(req *userEntity.UpdateUserReq, userId string) error {
+	m.users[userId].Email = req.Email
+	m.users[userId].FirstName = req.FirstName
+	return nil
+}
+
+func (m *memoryRepo) SetPendingEmail(userId, email string) error {
+	m.users[userId].PendingEmail = email
+	return nil
+}
+
+func (m *memoryRepo) AddEmailVerification(req *userEntity.EmailVerification) error {
+	m.verifications[req.TokenHash] = req
+	return nil
+}
+
+func (m *memoryRepo) GetEmailVerification(tokenHash string) (*userEntity.EmailVerification, error) {
+	verification, ok := m.verifications[tokenHash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return verification, nil
+}
+
+func (m *memoryRepo) CountEmailVerifications(userId, since string) (int, string, error) {
+	count, latest := 0, ""
+	for _, verification := range m.verifications {
+		if verification.UserId == userId && verification.CreatedAt >= since {
+			count++
+			if verification.CreatedAt > latest {
+				latest = verification.CreatedAt
+			}
+		}
+	}
+	return count, latest, nil
+}
+
+func (m *memoryRepo) VerifyEmail(userId, email string) error {
+	user := m.users[userId]
+	user.Email = email
+	user.PendingEmail = ""
+	if user.AccountStatus == userEntity.AccountUnverified {
+		user.AccountStatus = userEntity.AccountActive
+	}
+	for hash, verification := range m.verifications {
+		if verification.UserId == userId {
+			delete(m.verifications, hash)
+		}
+	}
+	return nil
+}
+
+func (m *memoryRepo) AssignVAToUser(userId, vaId string) error {
+	m.assigned[userId] = vaId
+	return nil
+}
+
+func (m *memoryRepo) AddMagicLink(req *userEntity.MagicLink) error {
+	m.magicLinks[req.TokenHash] = req
+	return nil
+}
+
+func (m *memoryRepo) GetMagicLink(tokenHash string) (*userEntity.MagicLink, error) {
+	link, ok := m.magicLinks[tokenHash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	stored := *link
+	return &stored, nil
+}
+
+func (m *memoryRepo) UseMagicLink(tokenHash, usedAt string) (bool, error) {
+	if m.magicLinks[tokenHash].UsedAt != "" {
+		return false, nil
+	}
+	m.magicLinks[tokenHash].UsedAt = usedAt
+	return true, nil
+}
+
+func (m *memoryRepo) CountMagicLinks(email, ipAddress, since string) (int, int, error) {
+	byEmail, byIP := 0, 0
+	for _, link := range m.magicLinks {
+		if link.CreatedAt < since {
+			continue
+		}
+		if link.Email == email {
+			byEmail++
+		}
+		if link.IPAddress == ipAddress {
+			byIP++
+		}
+	}
+	return byEmail, byIP, nil
+}
+
+func (m *memoryRepo) AddPasswordReset(req *userEntity.PasswordReset) error {
+	for _, reset := range m.resets {
+		if req.UserId != "" && reset.UserId == req.UserId && reset.UsedAt == "" {
+			reset.UsedAt = req.CreatedAt
+		}
+	}
+	m.resets[req.ResetId] = req
+	return nil
+}
+
+func (m *memoryRepo) GetPasswordReset(resetId string) (*userEntity.PasswordReset, error) {
+	reset, ok := m.resets[resetId]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	stored := *reset
+	return &stored, nil
+}
+
+func (m *memoryRepo) FailPasswordReset(resetId string) error {
+	m.resets[resetId].Attempts++
+	return nil
+}
+
+func (m *memoryRepo) UsePasswordReset(resetId, usedAt string) (bool, error) {
+	if m.resets[resetId].UsedAt != "" {
+		return false, nil
+	}
+	m.resets[resetId].UsedAt = usedAt
+	return true, nil
+}
+
+func (m *memoryRepo) CountPasswordResets(email, ipAddress, since string) (int, int, error) {
+	byEmail, byIP := 0, 0
+	for _, reset := range m.resets {
+		if reset.CreatedAt < since {
+			continue
+		}
+		if reset.Email == email {
+			byEmail++
+		}
+		if reset.IPAddress == ipAddress {
+			byIP++
+		}
+	}
+	return byEmail, byIP, nil
+}
+
+func (m *memoryRepo) ChangePassword(userId, newPassword string) error {
+	m.users[userId].Password = newPassword
+	return nil
+}
+
+func (m *memoryRepo) GetNotificationSettingsById(userId string) (*userEntity.NotificationSettingsRes, error) {
+	return &userEntity.NotificationSettingsRes{}, nil
+}
+
+func (m *memoryRepo) GetProductEmailSettingsById(userId string) (*userEntity.ProductEmailSettingsRes, error) {
+	return &userEntity.ProductEmailSettingsRes{}, nil
+}
+
+// memoryTokens hands out the user id as the access token.
+type memoryTokens struct {
+	tokenservice.TokenSrv
+}
+
+func (memoryTokens) CreateToken(id, status, email string, session *tokenEntity.SessionInfo) (string, string, error) {
+	return id, "refresh-" + id, nil
+}
+
+func (memoryTokens) LogoutAll(userId string) error {
+	return nil
+}
+
+// plainCrypto keeps passwords as they are, bcrypt makes the tests slow.
+type plainCrypto struct{}
+
+func (plainCrypto) HashPassword(password string) (string, error) {
+	return password, nil
+}
+
+func (plainCrypto) ComparePassword(hashed, plain string) error {
+	if hashed != plain {
+		return fmt.Errorf("wrong password")
+	}
+	return nil
+}
+
+type noGuard struct {
+	loginGuardService.LoginGuardSrv
+}
+
+func (noGuard) Succeeded(accountType, email string) {}
+
+// countingGuard lets every try through and counts what it is told.
+type countingGuard struct {
+	loginGuardService.LoginGuardSrv
+	failed, succeeded int
+}
+
+func (g *countingGuard) Check(accountType, email, ipAddress string) *ResponseEntity.ServiceError {
+	return nil
+}
+
+func (g *countingGuard) Failed(accountType, email, ipAddress, name string) {
+	g.failed++
+}
+
+func (g *countingGuard) Succeeded(accountType, email string) {
+	g.succeeded++
+}
+
+type noAlerts struct {
+	loginAlertService.LoginAlertSrv
+}
+
+func (noAlerts) Notify(userId, email, firstName string, session *tokenEntity.SessionInfo) {}
+
+type noTwoFactor struct {
+	twoFactorService.TwoFactorSrv
+}
+
+func (noTwoFactor) IsEnabled(accountId string) (bool, error) {
+	return false, nil
+}
+
+// codeTwoFactor is on for every account and accepts a single code.
+type codeTwoFactor struct {
+	twoFactorService.TwoFactorSrv
+	code string
+}
+
+func (codeTwoFactor) IsEnabled(accountId string) (bool, error) {
+	return true, nil
+}
+
+func (codeTwoFactor) Challenge(id, status, email string) (string, error) {
+	return id + "|" + email, nil
+}
+
+func (codeTwoFactor) ChallengeAccount(challengeToken string) (*tokenservice.Token, *ResponseEntity.ServiceError) {
+	id, email, _ := strings.Cut(challengeToken, "|")
+	return &tokenservice.Token{Id: id, Email: email, Status: "user"}, nil
+}
+
+func (c codeTwoFactor) CompleteChallenge(challengeToken, code string) (*tokenservice.Token, *ResponseEntity.ServiceError) {
+	if code != c.code {
+		return nil, ResponseEntity.NewCustomServiceError(twoFactorService.ErrUnauthorized, "invalid two-factor code")
+	}
+	return c.ChallengeAccount(challengeToken)
+}
+
+// onePasskey verifies every assertion as the passkey of an account.
+type onePasskey struct {
+	passkeyService.PasskeySrv
+	assertion passkeyEntity.Assertion
+}
+
+func (o onePasskey) FinishLogin(accountType string, req *passkeyEntity.AssertionReq) (*passkeyEntity.Assertion, *ResponseEntity.ServiceError) {
+	assertion := o.assertion
+	return &assertion, nil
+}
+
+type memoryEmitter struct {
+	payloads []eventEntity.Payload
+}
+
+func (m *memoryEmitter) Push(payload eventEntity.Payload, severity string) error {
+	m.payloads = append(m.payloads, payload)
+	return nil
+}
+
+var tokenInLink = regexp.MustCompile(`\?token=(\S+)`)
+
+// lastToken returns the token of the last link sent to email.
+func (m *memoryEmitter) lastToken(t *testing.T, email string) string {
+	t.Helper()
+	for i := len(m.payloads) - 1; i >= 0; i-- {
+		if m.payloads[i].Data["email_address"] != email {
+			continue
+		}
+		match := tokenInLink.FindStringSubmatch(m.payloads[i].Data["email_body"])
+		if match == nil {
+			t.Fatalf("no link in %q", m.payloads[i].Data["email_body"])
+		}
+		return match[1]
+	}
+	t.Fatalf("no email sent to %s", email)
+	return ""
+}
+
+func newTestSrv(repo *memoryRepo, emitter *memoryEmitter) *userSrv {
+	return &userSrv{repo: repo, validator: validationService.NewValidationStruct(), Emitter: emitter,
+		tokenSrv: memoryTokens{}, twoFactorSrv: noTwoFactor{}, loginAlert: noAlerts{}, appBaseUrl: "https://app.test/",
+		cryptoSrv: plainCrypto{}, loginGuard: noGuard{},
+		passwordSrv: passwordService.NewPasswordSrv(policy, passwordService.NewOfflineList())}
+}
+
+var policy, _ = passwordService.NewPolicy(0, nil)
+
+func TestSignupPasswordPolicy(t *testing.T) {
+	repo := newMemoryRepo()
+	srv := newTestSrv(repo, &memoryEmitter{})
+
+	_, errRes := srv.SaveUser(&userEntity.CreateUserReq{FirstName: "Sam", LastName: "Jones",
+		Email: "sam@example.com", Password: "samjones"})
+	if errRes == nil || errRes.Description != "BadInput Request" {
+		t.Fatalf("SaveUser() = %v, want a bad input", errRes)
+	}
+	fieldErrs, ok := errRes.Error.([]ResponseEntity.FieldError)
+	if !ok || len(fieldErrs) != 1 || fieldErrs[0].Field != "password" || fieldErrs[0].Rule != passwordService.RulePersonal {
+		t.Fatalf("SaveUser() errors = %+v", errRes.Error)
+	}
+}
+
+func TestVerifyNewAccount(t *testing.T) {
+	repo := newMemoryRepo(&userEntity.GetByIdRes{UserId: "u1", Email: "sam@example.com", AccountStatus: userEntity.AccountUnverified})
+	emitter := &memoryEmitter{}
+	srv := newTestSrv(repo, emitter)
+
+	if errRes := srv.AssignVAToUser("u1", "va1"); errRes == nil || errRes.Description != ErrForbidden {
+		t.Errorf("AssignVAToUser() before verifying = %v, want forbidden", errRes)
+	}
+
+	if err := srv.sendVerification("u1", "Sam", "Doe", "sam@example.com"); err != nil {
+		t.Fatalf("sendVerification() error = %v", err)
+	}
+	token := emitter.lastToken(t, "sam@example.com")
+
+	if errRes := srv.VerifyEmail(&userEntity.VerifyEmailReq{Token: "not-a-token"}); errRes == nil {
+		t.Error("VerifyEmail() with an unknown token should fail")
+	}
+	if errRes := srv.VerifyEmail(&userEntity.VerifyEmailReq{Token: token}); errRes != nil {
+		t.Fatalf("VerifyEmail() error = %v", errRes)
+	}
+	if repo.users["u1"].AccountStatus != userEntity.AccountActive {
+		t.Errorf("account status = %q, want active", repo.users["u1"].AccountStatus)
+	}
+	if errRes := srv.VerifyEmail(&userEntity.VerifyEmailReq{Token: token}); errRes == nil {
+		t.Error("a verification link should only work once")
+	}
+	if errRes := srv.ResendVerification("u1"); errRes == nil {
+		t.Error("ResendVerification() for a verified email should fail")
+	}
+
+	if errRes := srv.AssignVAToUser("u1", "va1"); errRes != nil || repo.assigned["u1"] != "va1" {
+		t.Errorf("AssignVAToUser() after verifying = %v", errRes)
+	}
+}
+
+func TestEmailChangeWaitsForConfirmation(t *testing.T) {
+	repo := newMemoryRepo(
+		&userEntity.GetByIdRes{UserId: "u1", Email: "sam@example.com", AccountStatus: userEntity.AccountActive},
+		&userEntity.GetByIdRes{UserId: "u2", Email: "taken@example.com", AccountStatus: userEntity.AccountActive},
+	)
+	emitter := &memoryEmitter{}
+	srv := newTestSrv(repo, emitter)
+
+	if _, errRes := srv.UpdateUser(&userEntity.UpdateUserReq{Email: "taken@example.com"}, "u1"); errRes == nil {
+		t.Error("UpdateUser() to an email in use should fail")
+	}
+
+	res, errRes := srv.UpdateUser(&userEntity.UpdateUserReq{FirstName: "Sam", Email: "first@example.com"}, "u1")
+	if errRes != nil {
+		t.Fatalf("UpdateUser() error = %v", errRes)
+	}
+	if res.Email != "sam@example.com" || res.PendingEmail != "first@example.com" || repo.users["u1"].Email != "sam@example.com" {
+		t.Errorf("email changed before confirmation, got %+v", res)
+	}
+	first := emitter.lastToken(t, "first@example.com")
+
+	// a link was just sent, so another one has to wait
+	if _, errRes := srv.UpdateUser(&userEntity.UpdateUserReq{FirstName: "Sam", Email: "second@example.com"}, "u1"); errRes == nil || errRes.Description != ErrTooManyRequests {
+		t.Errorf("UpdateUser() right after sending a link = %v, want too many requests", errRes)
+	}
+	repo.verifications[hashToken(first)].CreatedAt = "2000-01-01T00:00:00Z"
+
+	// changing the email again replaces the pending one, so the first link is stale
+	if _, errRes := srv.UpdateUser(&userEntity.UpdateUserReq{FirstName: "Sam", Email: "second@example.com"}, "u1"); errRes != nil {
+		t.Fatalf("UpdateUser() error = %v", errRes)
+	}
+	if errRes := srv.VerifyEmail(&userEntity.VerifyEmailReq{Token: first}); errRes == nil {
+		t.Error("a link for a replaced email change should fail")
+	}
+
+	if errRes := srv.ResendVerification("u1"); errRes == nil || errRes.Description != ErrTooManyRequests {
+		t.Errorf("ResendVerification() right after sending = %v, want too many requests", errRes)
+	}
+
+	second := emitter.lastToken(t, "second@example.com")
+	if errRes := srv.VerifyEmail(&userEntity.VerifyEmailReq{Token: second}); errRes != nil {
+		t.Fatalf("VerifyEmail() error = %v", errRes)
+	}
+	if user := repo.users["u1"]; user.Email != "second@example.com" || user.PendingEmail != "" {
+		t.Errorf("after confirming, email = %q pending = %q", user.Email, user.PendingEmail)
+	}
+}
+
+func TestMagicLink(t *testing.T) {
+	repo := newMemoryRepo(&userEntity.GetByIdRes{UserId: "u1", Email: "sam@example.com", AccountStatus: userEntity.AccountActive})
+	emitter := &memoryEmitter{}
+	srv := newTestSrv(repo, emitter)
+
+	// unknown emails get the same answer but no email
+	if _, errRes := srv.RequestMagicLink(&userEntity.MagicLinkReq{Email: "nobody@example.com", IPAddress: "10.0.0.1"}); errRes != nil {
+		t.Fatalf("RequestMagicLink() for an unknown email error = %v", errRes)
+	}
+	if len(emitter.payloads) != 0 {
+		t.Errorf("an email was sent for an unknown address")
+	}
+
+	res, errRes := srv.RequestMagicLink(&userEntity.MagicLinkReq{Email: "sam@example.com", IPAddress: "10.0.0.1"})
+	if errRes != nil {
+		t.Fatalf("RequestMagicLink() error = %v", errRes)
+	}
+	token := emitter.lastToken(t, "sam@example.com")
+
+	if _, errRes := srv.RedeemMagicLink(&userEntity.RedeemMagicLinkReq{Token: token, DeviceCode: "another-device"}); errRes == nil || errRes.Description != ErrForbidden {
+		t.Errorf("RedeemMagicLink() from another device = %v, want forbidden", errRes)
+	}
+	login, errRes := srv.RedeemMagicLink(&userEntity.RedeemMagicLinkReq{Token: token, DeviceCode: res.DeviceCode})
+	if errRes != nil {
+		t.Fatalf("RedeemMagicLink() error = %v", errRes)
+	}
+	if login.UserId != "u1" || login.Token != "u1" {
+		t.Errorf("RedeemMagicLink() = %+v", login)
+	}
+	if _, errRes := srv.RedeemMagicLink(&userEntity.RedeemMagicLinkReq{Token: token, DeviceCode: res.DeviceCode}); errRes == nil {
+		t.Error("a login link should only work once")
+	}
+
+	expired, _ := srv.RequestMagicLink(&userEntity.MagicLinkReq{Email: "sam@example.com", IPAddress: "10.0.0.1"})
+	repo.magicLinks[hashToken(emitter.lastToken(t, "sam@example.com"))].ExpiresAt = "2000-01-01T00:00:00Z"
+	if _, errRes := srv.RedeemMagicLink(&userEntity.RedeemMagicLinkReq{Token: emitter.lastToken(t, "sam@example.com"), DeviceCode: expired.DeviceCode}); errRes == nil {
+		t.Error("an expired login link should fail")
+	}
+
+	for i := 0; i < magicLinksPerEmail; i++ {
+		srv.RequestMagicLink(&userEntity.MagicLinkReq{Email: "sam@example.com", IPAddress: "10.0.0.2"})
+	}
+	if _, errRes := srv.RequestMagicLink(&userEntity.MagicLinkReq{Email: "sam@example.com", IPAddress: "10.0.0.3"}); errRes == nil || errRes.Description != ErrTooManyRequests {
+		t.Errorf("RequestMagicLink() over the email limit = %v, want too many requests", errRes)
+	}
+	for i := 0; i < magicLinksPerIP; i++ {
+		srv.RequestMagicLink(&userEntity.MagicLinkReq{Email: fmt.Sprintf("user%d@example.com", i), IPAddress: "10.0.0.4"})
+	}
+	if _, errRes := srv.RequestMagicLink(&userEntity.MagicLinkReq{Email: "fresh@example.com", IPAddress: "10.0.0.4"}); errRes == nil || errRes.Description != ErrTooManyRequests {
+		t.Errorf("RequestMagicLink() over the IP limit = %v, want too many requests", errRes)
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	repo := newMemoryRepo(&userEntity.GetByIdRes{UserId: "u1", Email: "sam@example.com", FirstName: "Sam",
+		Password: "old-secret-words", AccountStatus: userEntity.AccountActive})
+	emitter := &memoryEmitter{}
+	srv := newTestSrv(repo, emitter)
+
+	// unknown emails get the same answer but no email
+	if _, errRes := srv.ResetPassword(&userEntity.ResetPasswordReq{Email: "nobody@example.com", IPAddress: "10.0.0.1"}); errRes != nil {
+		t.Fatalf("ResetPassword() for an unknown email error = %v", errRes)
+	}
+	if len(emitter.payloads) != 0 {
+		t.Errorf("an email was sent for an unknown address")
+	}
+
+	if _, errRes := srv.ResetPassword(&userEntity.ResetPasswordReq{Email: "sam@example.com", IPAddress: "10.0.0.1"}); errRes != nil {
+		t.Fatalf("ResetPassword() error = %v", errRes)
+	}
+	older := emitter.lastToken(t, "sam@example.com")
+	srv.ResetPassword(&userEntity.ResetPasswordReq{Email: "sam@example.com", IPAddress: "10.0.0.1"})
+	token := emitter.lastToken(t, "sam@example.com")
+	resetId, _, _ := strings.Cut(token, ".")
+	if strings.Contains(repo.resets[resetId].SecretHash, strings.TrimPrefix(token, resetId+".")) {
+		t.Error("the reset secret is stored in plain text")
+	}
+
+	if _, errRes := srv.ResetPasswordWithToken(&userEntity.ResetPasswordWithTokenReq{Token: older, Password: "brand-new-words"}); errRes == nil {
+		t.Error("a new reset link should invalidate the older one")
+	}
+	// a refused password leaves the link working
+	if _, errRes := srv.ResetPasswordWithToken(&userEntity.ResetPasswordWithTokenReq{Token: token, Password: "password"}); errRes == nil {
+		t.Error("ResetPasswordWithToken() should apply the password policy")
+	}
+	if _, errRes := srv.ResetPasswordWithToken(&userEntity.ResetPasswordWithTokenReq{Token: token, Password: "brand-new-words"}); errRes != nil {
+		t.Fatalf("ResetPasswordWithToken() error = %v", errRes)
+	}
+	if repo.users["u1"].Password != "brand-new-words" {
+		t.Errorf("password = %q, want it changed", repo.users["u1"].Password)
+	}
+	if _, errRes := srv.ResetPasswordWithToken(&userEntity.ResetPasswordWithTokenReq{Token: token, Password: "other-new-words"}); errRes == nil {
+		t.Error("a reset link should only work once")
+	}
+
+	// wrong secrets burn the link
+	srv.ResetPassword(&userEntity.ResetPasswordReq{Email: "sam@example.com", IPAddress: "10.0.0.1"})
+	token = emitter.lastToken(t, "sam@example.com")
+	resetId, _, _ = strings.Cut(token, ".")
+	for i := 0; i < passwordResetAttempts; i++ {
+		srv.ResetPasswordWithToken(&userEntity.ResetPasswordWithTokenReq{Token: resetId + ".guess", Password: "other-new-words"})
+	}
+	if _, errRes := srv.ResetPasswordWithToken(&userEntity.ResetPasswordWithTokenReq{Token: token, Password: "other-new-words"}); errRes == nil {
+		t.Error("a reset link should stop working after too many wrong tries")
+	}
+
+	srv.ResetPassword(&userEntity.ResetPasswordReq{Email: "sam@example.com", IPAddress: "10.0.0.2"})
+	token = emitter.lastToken(t, "sam@example.com")
+	resetId, _, _ = strings.Cut(token, ".")
+	repo.resets[resetId].ExpiresAt = "2000-01-01T00:00:00Z"
+	if _, errRes := srv.ResetPasswordWithToken(&userEntity.ResetPasswordWithTokenReq{Token: token, Password: "other-new-words"}); errRes == nil {
+		t.Error("an expired reset link should fail")
+	}
+
+	for len(repo.resets) <= passwordResetsPerEmail {
+		srv.ResetPassword(&userEntity.ResetPasswordReq{Email: "sam@example.com", IPAddress: "10.0.0.2"})
+	}
+	if _, errRes := srv.ResetPassword(&userEntity.ResetPasswordReq{Email: "sam@example.com", IPAddress: "10.0.0.3"}); errRes == nil || errRes.Description != ErrTooManyRequests {
+		t.Errorf("ResetPassword() over the email limit = %v, want too many requests", errRes)
+	}
+}
+
+func TestLoginRefusesSuspendedAccount(t *testing.T) {
+	srv := newTestSrv(newMemoryRepo(), &memoryEmitter{})
+
+	user := &userEntity.GetByEmailRes{UserId: "u1", Email: "sam@example.com", AccountStatus: userEntity.AccountSuspended}
+	_, errRes := srv.firstFactorPassed(user, &tokenEntity.SessionInfo{})
+	if errRes == nil || errRes.Description != ErrForbidden {
+		t.Errorf("firstFactorPassed() = %v, want forbidden for a suspended account", errRes)
+	}
+	_, errRes = srv.loginResponse(user, &tokenEntity.SessionInfo{})
+	if errRes == nil || errRes.Description != ErrForbidden {
+		t.Errorf("loginResponse() = %v, want forbidden once the account is suspended after the first factor", errRes)
+	}
+}
+
+func TestSecondFactorCountsAsLoginFailure(t *testing.T) {
+	repo := newMemoryRepo(&userEntity.GetByIdRes{UserId: "u1", FirstName: "Sam", Email: "sam@example.com", Password: "password",
+		AccountStatus: userEntity.AccountActive})
+	guard := &countingGuard{}
+	srv := newTestSrv(repo, &memoryEmitter{})
+	srv.loginGuard, srv.twoFactorSrv = guard, codeTwoFactor{code: "123456"}
+
+	res, errRes := srv.Login(&userEntity.LoginReq{Email: "sam@example.com", Password: "password"})
+	if errRes != nil {
+		t.Fatalf("Login() error = %v", errRes)
+	}
+	if !res.TwoFactorRequired || guard.succeeded != 0 {
+		t.Fatalf("the password alone should not clear the failures, succeeded = %d", guard.succeeded)
+	}
+
+	_, errRes = srv.LoginTwoFactor(&twoFactorEntity.ChallengeReq{ChallengeToken: res.ChallengeToken, Code: "000000"})
+	if errRes == nil || guard.failed != 1 {
+		t.Errorf("a wrong code = %v with %d failures, want it refused and counted", errRes, guard.failed)
+	}
+	if _, errRes := srv.LoginTwoFactor(&twoFactorEntity.ChallengeReq{ChallengeToken: res.ChallengeToken, Code: "123456"}); errRes != nil {
+		t.Fatalf("LoginTwoFactor() error = %v", errRes)
+	}
+	if guard.succeeded != 1 {
+		t.Errorf("succeeded = %d, want the failures cleared once the session is issued", guard.succeeded)
+	}
+}
+
+func TestPasskeyLoginNeedsReportedPasswordReset(t *testing.T) {
+	repo := newMemoryRepo(&userEntity.GetByIdRes{UserId: "u1", Email: "sam@example.com", AccountStatus: userEntity.AccountActive,
+		PasswordResetRequired: true})
+	srv := newTestSrv(repo, &memoryEmitter{})
+
+	for _, verified := range []bool{true, false} {
+		srv.passkeySrv = onePasskey{assertion: passkeyEntity.Assertion{AccountId: "u1", UserVerified: verified}}
+		_, errRes := srv.LoginPasskey(&passkeyEntity.AssertionReq{})
+		if errRes == nil || errRes.Description != ErrForbidden {
+			t.Errorf("passkey login with user verification %v = %v, want forbidden until the password is reset", verified, errRes)
+		}
+	}
+
+	repo.users["u1"].PasswordResetRequired = false
+	if _, errRes := srv.LoginPasskey(&passkeyEntity.AssertionReq{}); errRes != nil {
+		t.Errorf("LoginPasskey() after the reset error = %v", errRes)
+	}
+}
@@ -7,9 +7,15 @@ import (
 	"log"
 	"test-va/internals/Repository/vaRepo"
 	"test-va/internals/entity/ResponseEntity"
+	"test-va/internals/entity/passkeyEntity"
+	"test-va/internals/entity/twoFactorEntity"
 	"test-va/internals/entity/vaEntity"
 	"test-va/internals/service/cryptoService"
+	"test-va/internals/service/loginGuardService"
+	"test-va/internals/service/passkeyService"
+	"test-va/internals/service/passwordService"
 	"test-va/internals/service/timeSrv"
+	"test-va/internals/service/twoFactorService"
 	"test-va/internals/service/validationService"
 	"time"
 
@@ -19,6 +25,9 @@ import (
 type VAService interface {
 	SignUp(req *vaEntity.CreateVAReq) (*vaEntity.CreateVARes, *ResponseEntity.ServiceError)
 	Login(req *vaEntity.LoginReq) (*vaEntity.FindByEmailRes, *ResponseEntity.ServiceError)
+	LoginTwoFactor(req *twoFactorEntity.ChallengeReq) (*vaEntity.FindByEmailRes, *ResponseEntity.ServiceError)
+	BeginPasskeyLogin(req *passkeyEntity.LoginBeginReq) (*passkeyEntity.RequestOptions, *ResponseEntity.ServiceError)
+	LoginPasskey(req *passkeyEntity.AssertionReq) (*vaEntity.FindByEmailRes, *ResponseEntity.ServiceError)
 	GetVA(id string) (*vaEntity.FindByIdRes, *ResponseEntity.ServiceError)
 	FindByEmail(email string) (*vaEntity.FindByEmailRes, *ResponseEntity.ServiceError)
 	UpdateVA(req *vaEntity.EditVaReq, id string) (*vaEntity.EditVARes, *ResponseEntity.ServiceError)
@@ -32,6 +41,11 @@ type vaSrv struct {
 	validator validationService.ValidationSrv
 	timeSrv   timeSrv.TimeService
 	cryptoSrv cryptoService.CryptoSrv
+
+	twoFactorSrv twoFactorService.TwoFactorSrv
+	passkeySrv   passkeyService.PasskeySrv
+	loginGuard   loginGuardService.LoginGuardSrv
+	passwordSrv  passwordService.PasswordSrv
 }
 
 // Get All Users Assigned To VA godoc
@@ -62,7 +76,8 @@ func (v *vaSrv) GetAllUserToVa(vaId string) ([]*vaEntity.VAStruct, *ResponseEnti
 
 // Login Virtual Assistant godoc
 // @Summary	Provide email and password to be logged in
-// @Description	Login as a va
+// @Description	Login as a va. With two-factor authentication on, no tokens are returned but a challenge token for /va/login/2fa.
+// @Description	After a few wrong passwords each try has to wait twice as long as the one before, after ten the account is locked until a master unlocks it or 30 minutes pass.
 // @Tags	VA
 // @Accept	json
 // @Produce	json
@@ -70,6 +85,8 @@ func (v *vaSrv) GetAllUserToVa(vaId string) ([]*vaEntity.VAStruct, *ResponseEnti
 // @Success	200  {object}  vaEntity.FindByIdRes
 // @Failure	400  {object}  ResponseEntity.ServiceError
 // @Failure	404  {object}  ResponseEntity.ServiceError
+// @Failure	423  {object}  ResponseEntity.ServiceError
+// @Failure	429  {object}  ResponseEntity.ServiceError
 // @Failure	500  {object}  ResponseEntity.ServiceError
 // @Router	/va/login [post]
 func (v *vaSrv) Login(req *vaEntity.LoginReq) (*vaEntity.FindByEmailRes, *ResponseEntity.ServiceError) {
@@ -78,20 +95,151 @@ func (v *vaSrv) Login(req *vaEntity.LoginReq) (*vaEntity.FindByEmailRes, *Respon
 	if err != nil {
 		return nil, ResponseEntity.NewValidatingError(fmt.Sprintf("Bad Request: %v", err))
 	}
+	if errRes := v.loginGuard.Check(loginGuardService.AccountVA, req.Email, req.IPAddress); errRes != nil {
+		return nil, errRes
+	}
 
 	//find the user with email
 	user, errRes := v.FindByEmail(req.Email)
 	if errRes != nil {
 		log.Println("err")
+		v.loginGuard.Failed(loginGuardService.AccountVA, req.Email, req.IPAddress, "")
 		return nil, ResponseEntity.NewValidatingError("Email Not Found")
 	}
 
 	//compare passwords
 	err = v.cryptoSrv.ComparePassword(user.Password, req.Password)
 	if err != nil {
+		v.loginGuard.Failed(loginGuardService.AccountVA, req.Email, req.IPAddress, user.FirstName)
 		return nil, ResponseEntity.NewInternalServiceError("Passwords Don't Match")
 	}
 
+	enabled, err := v.twoFactorSrv.IsEnabled(user.VaId)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if enabled {
+		challenge, err := v.twoFactorSrv.Challenge(user.VaId, user.AccountType, user.Email)
+		if err != nil {
+			return nil, ResponseEntity.NewInternalServiceError(err)
+		}
+		return &vaEntity.FindByEmailRes{VaId: user.VaId, Email: user.Email, AccountType: user.AccountType,
+			TwoFactorRequired: true, ChallengeToken: challenge}, nil
+	}
+
+	// failures are only forgotten once a session is issued, not when the password alone matched
+	v.loginGuard.Succeeded(loginGuardService.AccountVA, req.Email)
+	return user, nil
+}
+
+// Login Two Factor godoc
+// @Summary	Finish logging in as a va with a two-factor code
+// @Description	Trades the challenge token returned by /va/login and a code from the authenticator app, or a recovery code, for the login tokens
+// @Tags	VA
+// @Accept	json
+// @Produce	json
+// @Param	request	body	twoFactorEntity.ChallengeReq	true	"Challenge token and code"
+// @Success	200  {object}  vaEntity.FindByEmailRes
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	401  {object}  ResponseEntity.ServiceError
+// @Failure	423  {object}  ResponseEntity.ServiceError
+// @Failure	429  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Router	/va/login/2fa [post]
+func (v *vaSrv) LoginTwoFactor(req *twoFactorEntity.ChallengeReq) (*vaEntity.FindByEmailRes, *ResponseEntity.ServiceError) {
+	challenge, errRes := v.twoFactorSrv.ChallengeAccount(req.ChallengeToken)
+	if errRes != nil {
+		return nil, errRes
+	}
+	user, errRes := v.FindByEmail(challenge.Email)
+	if errRes != nil || user.VaId != challenge.Id {
+		return nil, ResponseEntity.NewCustomServiceError(twoFactorService.ErrUnauthorized, "the login has expired, log in again")
+	}
+	// wrong codes count as failed logins, so a known password does not make the account free to guess at
+	if errRes := v.loginGuard.Check(loginGuardService.AccountVA, user.Email, req.IPAddress); errRes != nil {
+		return nil, errRes
+	}
+	_, errRes = v.twoFactorSrv.CompleteChallenge(req.ChallengeToken, req.Code)
+	if errRes != nil {
+		if errRes.Description == twoFactorService.ErrUnauthorized {
+			v.loginGuard.Failed(loginGuardService.AccountVA, user.Email, req.IPAddress, user.FirstName)
+		}
+		return nil, errRes
+	}
+	v.loginGuard.Succeeded(loginGuardService.AccountVA, user.Email)
+	return user, nil
+}
+
+// Begin Passkey Login godoc
+// @Summary	Start logging in as a va with a passkey
+// @Description	Returns the options for navigator.credentials.get. With an email only the passkeys of that account are allowed, without one the authenticator offers its passkeys.
+// @Tags	VA
+// @Accept	json
+// @Produce	json
+// @Param	request	body	passkeyEntity.LoginBeginReq	false	"Email of the account"
+// @Success	200  {object}  passkeyEntity.RequestOptions
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Router	/va/passkeys/login/begin [post]
+func (v *vaSrv) BeginPasskeyLogin(req *passkeyEntity.LoginBeginReq) (*passkeyEntity.RequestOptions, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	accountId := ""
+	if req.Email != "" {
+		if user, err := v.repo.FindByEmail(ctx, req.Email); err == nil {
+			accountId = user.VaId
+		}
+	}
+	return v.passkeySrv.BeginLogin(passkeyEntity.AccountVA, accountId)
+}
+
+// Passkey Login godoc
+// @Summary	Log in as a va with a passkey
+// @Description	Checks the credential navigator.credentials.get returned. A passkey that verified the user with a PIN or biometric counts as two factors, otherwise a two-factor code is still asked for when it is on.
+// @Tags	VA
+// @Accept	json
+// @Produce	json
+// @Param	request	body	passkeyEntity.AssertionReq	true	"Credential from the authenticator"
+// @Success	200  {object}  vaEntity.FindByEmailRes
+// @Failure	400  {object}  ResponseEntity.ServiceError
+// @Failure	401  {object}  ResponseEntity.ServiceError
+// @Failure	500  {object}  ResponseEntity.ServiceError
+// @Router	/va/passkeys/login/finish [post]
+func (v *vaSrv) LoginPasskey(req *passkeyEntity.AssertionReq) (*vaEntity.FindByEmailRes, *ResponseEntity.ServiceError) {
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	assertion, errRes := v.passkeySrv.FinishLogin(passkeyEntity.AccountVA, req)
+	if errRes != nil {
+		return nil, errRes
+	}
+	account, err := v.repo.FindById(ctx, assertion.AccountId)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	user, err := v.repo.FindByEmail(ctx, account.Email)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	user.Password = ""
+
+	if assertion.UserVerified {
+		// the handler marks the session as logged in with two factors
+		req.TwoFactor = true
+		return user, nil
+	}
+	enabled, err := v.twoFactorSrv.IsEnabled(user.VaId)
+	if err != nil {
+		return nil, ResponseEntity.NewInternalServiceError(err)
+	}
+	if enabled {
+		challenge, err := v.twoFactorSrv.Challenge(user.VaId, user.AccountType, user.Email)
+		if err != nil {
+			return nil, ResponseEntity.NewInternalServiceError(err)
+		}
+		return &vaEntity.FindByEmailRes{VaId: user.VaId, Email: user.Email, AccountType: user.AccountType,
+			TwoFactorRequired: true, ChallengeToken: challenge}, nil
+	}
 	return user, nil
 }
 
@@ -163,6 +311,19 @@ func (v *vaSrv) ChangePassword(req *vaEntity.ChangeVAPassword) *ResponseEntity.S
 		return ResponseEntity.NewValidatingError(fmt.Sprintf("Bad Request: %v", err))
 	}
 
+	// create context
+	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
+	defer cancelFunc()
+
+	user, err := v.repo.FindById(ctx, req.VaId)
+	if err != nil {
+		return ResponseEntity.NewInternalServiceError(fmt.Sprintf("Error Finding User: %v", err))
+	}
+	if errRes := v.passwordSrv.Check("new_password", req.NewPassword,
+		passwordService.Account{Email: user.Email, FirstName: user.FirstName, LastName: user.LastName}); errRes != nil {
+		return errRes
+	}
+
 	// hash new password
 	pass, err := v.cryptoSrv.HashPassword(req.NewPassword)
 	if err != nil {
@@ -170,10 +331,6 @@ func (v *vaSrv) ChangePassword(req *vaEntity.ChangeVAPassword) *ResponseEntity.S
 	}
 	req.NewPassword = pass
 
-	// create context
-	ctx, cancelFunc := context.WithTimeout(context.TODO(), time.Minute*1)
-	defer cancelFunc()
-
 	// change password
 	errRes := v.repo.ChangePassword(ctx, req)
 	if errRes != nil {
@@ -209,7 +366,7 @@ func (v *vaSrv) DeleteVA(id string) *ResponseEntity.ServiceError {
 
 // Register VA godoc
 // @Summary	Register a virtual assistant
-// @Description	Register va route
+// @Description	Register va route. The password has to pass the same policy as the passwords of users.
 // @Tags	VA
 // @Accept	json
 // @Produce	json
@@ -226,6 +383,12 @@ func (v *vaSrv) SignUp(req *vaEntity.CreateVAReq) (*vaEntity.CreateVARes, *Respo
 		return nil, ResponseEntity.NewValidatingError(fmt.Sprintf("Bad Request: %v", err))
 	}
 
+	errRes := v.passwordSrv.Check("password", req.Password,
+		passwordService.Account{Email: req.Email, FirstName: req.FirstName, LastName: req.LastName})
+	if errRes != nil {
+		return nil, errRes
+	}
+
 	//find the user with email
 	user, errRes := v.FindByEmail(req.Email)
 	if errRes == nil {
@@ -292,6 +455,8 @@ func (v *vaSrv) GetVA(id string) (*vaEntity.FindByIdRes, *ResponseEntity.Service
 }
 
 func NewVaService(repo vaRepo.VARepo, validator validationService.ValidationSrv,
-	timeSrv timeSrv.TimeService, cryptoSrv cryptoService.CryptoSrv) VAService {
-	return &vaSrv{repo: repo, validator: validator, timeSrv: timeSrv, cryptoSrv: cryptoSrv}
+	timeSrv timeSrv.TimeService, cryptoSrv cryptoService.CryptoSrv, twoFactorSrv twoFactorService.TwoFactorSrv,
+	passkeySrv passkeyService.PasskeySrv, loginGuard loginGuardService.LoginGuardSrv, passwordSrv passwordService.PasswordSrv) VAService {
+	return &vaSrv{repo: repo, validator: validator, timeSrv: timeSrv, cryptoSrv: cryptoSrv,
+		twoFactorSrv: twoFactorSrv, passkeySrv: passkeySrv, loginGuard: loginGuard, passwordSrv: passwordSrv}
 }
@@ -0,0 +1,17 @@
+-- Files attached to tasks or comments. Rows start PENDING when an upload url is issued
+-- and become UPLOADED once the stored object has been checked.
+CREATE TABLE IF NOT EXISTS Attachments (
+    attachment_id VARCHAR(255) NOT NULL PRIMARY KEY,
+    task_id       VARCHAR(255) NULL,
+    comment_id    VARCHAR(255) NULL,
+    uploader_id   VARCHAR(255) NOT NULL,
+    file_name     VARCHAR(255) NOT NULL,
+    mime_type     VARCHAR(255) NOT NULL,
+    size          BIGINT       NOT NULL,
+    checksum      CHAR(64)     NOT NULL,
+    storage_key   VARCHAR(512) NOT NULL,
+    status        VARCHAR(20)  NOT NULL DEFAULT 'PENDING',
+    created_at    VARCHAR(255) NOT NULL,
+    INDEX idx_attachments_task (task_id),
+    INDEX idx_attachments_comment (comment_id)
+);
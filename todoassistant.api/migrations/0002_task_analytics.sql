@@ -0,0 +1,9 @@
+-- When a task was last marked COMPLETED, cleared when it is reopened.
+ALTER TABLE Tasks ADD COLUMN completed_at VARCHAR(255) NULL;
+
+CREATE TABLE IF NOT EXISTS Task_Labels (
+    task_id VARCHAR(255) NOT NULL,
+    label   VARCHAR(100) NOT NULL,
+    PRIMARY KEY (task_id, label),
+    INDEX idx_task_labels_label (label)
+);
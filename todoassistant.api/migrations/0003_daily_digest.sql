@@ -0,0 +1,9 @@
+ALTER TABLE Reminder_Settings ADD COLUMN time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+-- One row per user and local day a digest went out, the primary key keeps it to one a day.
+CREATE TABLE IF NOT EXISTS Digest_Log (
+    user_id     VARCHAR(255) NOT NULL,
+    digest_date VARCHAR(10)  NOT NULL,
+    sent_at     VARCHAR(255) NOT NULL,
+    PRIMARY KEY (user_id, digest_date)
+);
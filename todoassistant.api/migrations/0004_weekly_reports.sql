@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS Task_Time_Logs (
+    id        INT          NOT NULL AUTO_INCREMENT,
+    task_id   VARCHAR(255) NOT NULL,
+    user_id   VARCHAR(255) NOT NULL,
+    minutes   INT          NOT NULL,
+    note      VARCHAR(255) NOT NULL DEFAULT '',
+    logged_at VARCHAR(255) NOT NULL,
+    PRIMARY KEY (id),
+    INDEX (task_id)
+);
+
+-- One row per user or VA and week, the primary key keeps the weekly email to one a week.
+CREATE TABLE IF NOT EXISTS Weekly_Reports (
+    owner_id   VARCHAR(255) NOT NULL,
+    week_start VARCHAR(10)  NOT NULL,
+    role       VARCHAR(10)  NOT NULL,
+    report     TEXT         NOT NULL,
+    created_at VARCHAR(255) NOT NULL,
+    PRIMARY KEY (owner_id, week_start)
+);
@@ -0,0 +1,23 @@
+-- The project owner stays in Projects.user_id, everyone else it is shared with is listed here.
+CREATE TABLE IF NOT EXISTS Project_Members (
+    project_id VARCHAR(255) NOT NULL,
+    user_id    VARCHAR(255) NOT NULL,
+    role       VARCHAR(10)  NOT NULL,
+    created_at VARCHAR(255) NOT NULL,
+    PRIMARY KEY (project_id, user_id),
+    INDEX (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS Project_Invitations (
+    invitation_id VARCHAR(255) NOT NULL,
+    project_id    VARCHAR(255) NOT NULL,
+    email         VARCHAR(255) NOT NULL,
+    role          VARCHAR(10)  NOT NULL,
+    invited_by    VARCHAR(255) NOT NULL,
+    status        VARCHAR(10)  NOT NULL DEFAULT 'PENDING',
+    created_at    VARCHAR(255) NOT NULL,
+    responded_at  VARCHAR(255) NOT NULL DEFAULT '',
+    PRIMARY KEY (invitation_id),
+    INDEX (email),
+    INDEX (project_id)
+);
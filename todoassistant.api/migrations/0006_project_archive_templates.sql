@@ -0,0 +1,24 @@
+-- Archived projects and their tasks are left out of the default lists.
+ALTER TABLE Projects ADD COLUMN archived_at VARCHAR(255) NULL;
+
+CREATE TABLE IF NOT EXISTS Project_Templates (
+    template_id VARCHAR(255) NOT NULL,
+    user_id     VARCHAR(255) NOT NULL,
+    title       VARCHAR(255) NOT NULL,
+    color       VARCHAR(255) NOT NULL,
+    created_at  VARCHAR(255) NOT NULL,
+    PRIMARY KEY (template_id),
+    INDEX (user_id)
+);
+
+-- Offsets are minutes after midnight of the day the template is started on.
+CREATE TABLE IF NOT EXISTS Project_Template_Tasks (
+    template_id          VARCHAR(255) NOT NULL,
+    position             INT          NOT NULL,
+    title                VARCHAR(255) NOT NULL,
+    description          TEXT         NOT NULL,
+    repeat_frequency     VARCHAR(255) NOT NULL,
+    start_offset_minutes INT          NOT NULL,
+    due_offset_minutes   INT          NOT NULL,
+    PRIMARY KEY (template_id, position)
+);
@@ -0,0 +1,14 @@
+ALTER TABLE Projects ADD COLUMN parent_id VARCHAR(255) NULL;
+CREATE INDEX idx_projects_parent_id ON Projects (parent_id);
+
+CREATE TABLE IF NOT EXISTS Project_Sections (
+    section_id VARCHAR(255) NOT NULL,
+    project_id VARCHAR(255) NOT NULL,
+    title      VARCHAR(100) NOT NULL,
+    position   INT          NOT NULL,
+    created_at VARCHAR(255) NOT NULL,
+    PRIMARY KEY (section_id),
+    INDEX (project_id)
+);
+
+ALTER TABLE Tasks ADD COLUMN section_id VARCHAR(255) NULL;
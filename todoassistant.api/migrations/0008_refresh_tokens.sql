@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS Refresh_Tokens (
+    token_hash CHAR(64)     NOT NULL,
+    family_id  VARCHAR(255) NOT NULL,
+    user_id    VARCHAR(255) NOT NULL,
+    expires_at VARCHAR(255) NOT NULL,
+    created_at VARCHAR(255) NOT NULL,
+    rotated_at VARCHAR(255) NULL,
+    revoked_at VARCHAR(255) NULL,
+    PRIMARY KEY (token_hash),
+    INDEX (family_id),
+    INDEX (user_id)
+);
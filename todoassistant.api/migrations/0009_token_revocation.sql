@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS Revoked_Tokens (
+    jti        VARCHAR(255) NOT NULL,
+    user_id    VARCHAR(255) NOT NULL,
+    expires_at BIGINT       NOT NULL,
+    PRIMARY KEY (jti),
+    INDEX (expires_at)
+);
+
+-- tokens of the user issued before revoked_before (unix seconds) are no longer accepted
+CREATE TABLE IF NOT EXISTS Token_Cutoffs (
+    user_id        VARCHAR(255) NOT NULL,
+    revoked_before BIGINT       NOT NULL,
+    PRIMARY KEY (user_id)
+);
@@ -0,0 +1,18 @@
+-- one row per login, session_id is the family id shared by the login's refresh tokens
+CREATE TABLE IF NOT EXISTS Sessions (
+    session_id   VARCHAR(255) NOT NULL,
+    user_id      VARCHAR(255) NOT NULL,
+    account_type VARCHAR(50)  NOT NULL,
+    device_name  VARCHAR(255) NOT NULL DEFAULT '',
+    user_agent   VARCHAR(512) NOT NULL DEFAULT '',
+    ip_address   VARCHAR(64)  NOT NULL DEFAULT '',
+    created_at   VARCHAR(255) NOT NULL,
+    last_seen_at VARCHAR(255) NOT NULL,
+    revoked_at   VARCHAR(255) NULL,
+    PRIMARY KEY (session_id),
+    INDEX (user_id)
+);
+
+-- push tokens registered from a session are removed when the session is revoked
+ALTER TABLE Notification_Tokens ADD COLUMN session_id VARCHAR(255) NULL;
+CREATE INDEX idx_notification_tokens_session_id ON Notification_Tokens (session_id);
@@ -0,0 +1,14 @@
+-- an email change only takes effect once the new address is confirmed
+ALTER TABLE Users ADD COLUMN pending_email VARCHAR(255) NULL;
+
+-- accounts created before verification existed keep their ACTIVE status,
+-- new accounts are UNVERIFIED until their email is confirmed
+CREATE TABLE IF NOT EXISTS Email_Verifications (
+    token_hash CHAR(64)     NOT NULL,
+    user_id    VARCHAR(255) NOT NULL,
+    email      VARCHAR(255) NOT NULL,
+    expires_at VARCHAR(255) NOT NULL,
+    created_at VARCHAR(255) NOT NULL,
+    PRIMARY KEY (token_hash),
+    INDEX (user_id, created_at)
+);
@@ -0,0 +1,19 @@
+-- one row per user or VA that started enrolling, enabled_at is set once a first code confirmed it
+CREATE TABLE IF NOT EXISTS Two_Factor (
+    account_id     VARCHAR(255) NOT NULL,
+    account_type   VARCHAR(50)  NOT NULL,
+    secret         VARCHAR(64)  NOT NULL,
+    -- the last time step a code was accepted for, so a code cannot be used twice
+    last_used_step BIGINT       NOT NULL DEFAULT 0,
+    created_at     VARCHAR(255) NOT NULL,
+    enabled_at     VARCHAR(255) NULL,
+    PRIMARY KEY (account_id)
+);
+
+CREATE TABLE IF NOT EXISTS Recovery_Codes (
+    code_hash  CHAR(64)     NOT NULL,
+    account_id VARCHAR(255) NOT NULL,
+    used_at    VARCHAR(255) NULL,
+    PRIMARY KEY (code_hash),
+    INDEX (account_id)
+);
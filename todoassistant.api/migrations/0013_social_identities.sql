@@ -0,0 +1,11 @@
+-- accounts at Google and Facebook linked to users by the provider's id for them,
+-- so a login no longer trusts an email sent by the client
+CREATE TABLE IF NOT EXISTS Social_Identities (
+    provider   VARCHAR(32)  NOT NULL,
+    subject    VARCHAR(255) NOT NULL,
+    user_id    VARCHAR(255) NOT NULL,
+    email      VARCHAR(255) NOT NULL,
+    created_at VARCHAR(255) NOT NULL,
+    PRIMARY KEY (provider, subject),
+    INDEX (user_id)
+);
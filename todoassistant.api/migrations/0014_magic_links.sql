@@ -0,0 +1,15 @@
+-- passwordless login links, every request is kept for an hour to rate limit by email and IP,
+-- requests for unknown emails have an empty user_id and are never sent
+CREATE TABLE IF NOT EXISTS Magic_Links (
+    token_hash  CHAR(64)     NOT NULL,
+    user_id     VARCHAR(255) NOT NULL,
+    email       VARCHAR(255) NOT NULL,
+    device_hash CHAR(64)     NOT NULL,
+    ip_address  VARCHAR(64)  NOT NULL,
+    expires_at  VARCHAR(255) NOT NULL,
+    created_at  VARCHAR(255) NOT NULL,
+    used_at     VARCHAR(255) NULL,
+    PRIMARY KEY (token_hash),
+    INDEX (email, created_at),
+    INDEX (ip_address, created_at)
+);
@@ -0,0 +1,24 @@
+-- WebAuthn credentials, account_type is "user" or "va" so a passkey only logs into the kind of account it was made for
+CREATE TABLE IF NOT EXISTS Passkeys (
+    credential_id VARCHAR(255)    NOT NULL,
+    account_id    VARCHAR(255)    NOT NULL,
+    account_type  VARCHAR(32)     NOT NULL,
+    name          VARCHAR(255)    NOT NULL,
+    public_key    BLOB            NOT NULL,
+    algorithm     INT             NOT NULL,
+    sign_count    BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    created_at    VARCHAR(255)    NOT NULL,
+    last_used_at  VARCHAR(255)    NULL,
+    PRIMARY KEY (credential_id),
+    INDEX (account_id, account_type)
+);
+
+-- challenges of registrations and logins in progress, each can be answered once
+CREATE TABLE IF NOT EXISTS Passkey_Challenges (
+    challenge_hash CHAR(64)     NOT NULL,
+    account_id     VARCHAR(255) NOT NULL,
+    account_type   VARCHAR(32)  NOT NULL,
+    ceremony       VARCHAR(32)  NOT NULL,
+    expires_at     VARCHAR(255) NOT NULL,
+    PRIMARY KEY (challenge_hash)
+);
@@ -0,0 +1,11 @@
+-- failed logins by account and by IP address, used to slow down and lock out password guessing.
+-- attempt_key is "user:<email>", "va:<email>" or "ip:<address>"
+CREATE TABLE IF NOT EXISTS Login_Attempts (
+    attempt_key     VARCHAR(320) NOT NULL,
+    failures        INT          NOT NULL DEFAULT 0,
+    last_failure_at VARCHAR(255) NOT NULL,
+    blocked_until   VARCHAR(255) NULL,
+    locked_until    VARCHAR(255) NULL,
+    PRIMARY KEY (attempt_key),
+    INDEX (last_failure_at)
+);
@@ -0,0 +1,19 @@
+-- sessions remember the device they were started from, logins from a device the user
+-- has not used before are told about by email when the login alert setting is on
+ALTER TABLE Sessions ADD COLUMN fingerprint CHAR(64) NOT NULL DEFAULT '';
+CREATE INDEX idx_sessions_user_fingerprint ON Sessions (user_id, fingerprint);
+
+-- set when a login is reported as not the user's, password logins are refused until it is reset
+ALTER TABLE Users ADD COLUMN password_reset_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- "this wasn't me" links of the alerts, each one logs out the session it was sent for
+CREATE TABLE IF NOT EXISTS Login_Alerts (
+    token_hash CHAR(64)     NOT NULL,
+    user_id    VARCHAR(255) NOT NULL,
+    session_id VARCHAR(255) NOT NULL,
+    expires_at VARCHAR(255) NOT NULL,
+    created_at VARCHAR(255) NOT NULL,
+    used_at    VARCHAR(255) NULL,
+    PRIMARY KEY (token_hash),
+    INDEX (expires_at)
+);
@@ -0,0 +1,30 @@
+-- exports and erasures of a user's data, an erasure runs once scheduled_for is past unless cancelled
+CREATE TABLE IF NOT EXISTS Privacy_Requests (
+    request_id    VARCHAR(255) NOT NULL,
+    user_id       VARCHAR(255) NOT NULL,
+    kind          VARCHAR(10)  NOT NULL,
+    status        VARCHAR(10)  NOT NULL,
+    requested_by  VARCHAR(255) NOT NULL,
+    archive_key   VARCHAR(512) NOT NULL DEFAULT '',
+    requested_at  VARCHAR(255) NOT NULL,
+    scheduled_for VARCHAR(255) NOT NULL,
+    completed_at  VARCHAR(255) NULL,
+    expires_at    VARCHAR(255) NULL,
+    PRIMARY KEY (request_id),
+    INDEX (user_id, kind),
+    INDEX (kind, status, scheduled_for)
+);
+
+-- proof an erasure happened, kept after the user is gone and without any personal data
+CREATE TABLE IF NOT EXISTS Erasure_Records (
+    record_id       VARCHAR(255) NOT NULL,
+    user_id         VARCHAR(255) NOT NULL,
+    request_id      VARCHAR(255) NOT NULL,
+    requested_by    VARCHAR(255) NOT NULL,
+    requested_at    VARCHAR(255) NOT NULL,
+    erased_at       VARCHAR(255) NOT NULL,
+    deleted_rows    TEXT         NOT NULL,
+    storage_objects INT          NOT NULL,
+    PRIMARY KEY (record_id),
+    INDEX (user_id)
+);
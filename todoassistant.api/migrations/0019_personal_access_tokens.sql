@@ -0,0 +1,18 @@
+-- tokens users make to script against the api, limited to the comma separated scopes.
+-- Only a sha256 of the token is kept, expires_at is NULL for tokens that work until revoked.
+CREATE TABLE IF NOT EXISTS Personal_Access_Tokens (
+    token_id     VARCHAR(255) NOT NULL,
+    user_id      VARCHAR(255) NOT NULL,
+    account_type VARCHAR(50)  NOT NULL,
+    name         VARCHAR(100) NOT NULL,
+    token_hash   CHAR(64)     NOT NULL,
+    hint         VARCHAR(8)   NOT NULL,
+    scopes       VARCHAR(255) NOT NULL,
+    created_at   VARCHAR(255) NOT NULL,
+    expires_at   VARCHAR(255) NULL,
+    last_used_at VARCHAR(255) NULL,
+    revoked_at   VARCHAR(255) NULL,
+    PRIMARY KEY (token_id),
+    UNIQUE INDEX (token_hash),
+    INDEX (user_id)
+);
@@ -0,0 +1,32 @@
+-- apps registered to use the api for their users. redirect_uris is newline separated,
+-- public clients have no secret.
+CREATE TABLE IF NOT EXISTS OAuth_Clients (
+    client_id     VARCHAR(255) NOT NULL,
+    owner_id      VARCHAR(255) NOT NULL,
+    name          VARCHAR(100) NOT NULL,
+    redirect_uris TEXT         NOT NULL,
+    confidential  BOOLEAN      NOT NULL DEFAULT FALSE,
+    secret_hash   CHAR(64)     NULL,
+    created_at    VARCHAR(255) NOT NULL,
+    PRIMARY KEY (client_id),
+    INDEX (owner_id)
+);
+
+-- authorization codes, used once within minutes. Only a sha256 of the code is kept.
+CREATE TABLE IF NOT EXISTS OAuth_Codes (
+    code_hash      CHAR(64)     NOT NULL,
+    client_id      VARCHAR(255) NOT NULL,
+    user_id        VARCHAR(255) NOT NULL,
+    redirect_uri   TEXT         NOT NULL,
+    scopes         VARCHAR(255) NOT NULL,
+    code_challenge VARCHAR(128) NOT NULL,
+    expires_at     VARCHAR(255) NOT NULL,
+    created_at     VARCHAR(255) NOT NULL,
+    used_at        VARCHAR(255) NULL,
+    PRIMARY KEY (code_hash),
+    INDEX (expires_at)
+);
+
+-- a connected app is a session with the client's id, its tokens are refreshed and revoked as any session's
+ALTER TABLE Sessions ADD COLUMN client_id VARCHAR(255) NULL;
+CREATE INDEX idx_sessions_client_id ON Sessions (client_id);
@@ -0,0 +1,21 @@
+-- password resets replace Reset_Token, which kept short tokens in plain text. The token sent is the
+-- reset_id and a secret, only a sha256 of the secret is kept. Every request is kept for an hour to
+-- rate limit by email and IP, requests for unknown emails have an empty user_id and are never sent.
+CREATE TABLE IF NOT EXISTS Password_Resets (
+    reset_id    VARCHAR(255) NOT NULL,
+    user_id     VARCHAR(255) NOT NULL,
+    email       VARCHAR(255) NOT NULL,
+    secret_hash CHAR(64)     NOT NULL,
+    ip_address  VARCHAR(64)  NOT NULL,
+    attempts    INT          NOT NULL DEFAULT 0,
+    expires_at  VARCHAR(255) NOT NULL,
+    created_at  VARCHAR(255) NOT NULL,
+    used_at     VARCHAR(255) NULL,
+    PRIMARY KEY (reset_id),
+    INDEX (user_id),
+    INDEX (email, created_at),
+    INDEX (ip_address, created_at)
+);
+
+-- tokens issued before cannot be trusted, they have to be asked for again
+DROP TABLE IF EXISTS Reset_Token;
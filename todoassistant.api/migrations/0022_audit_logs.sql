@@ -0,0 +1,27 @@
+-- authentication and admin events. The table is append-only, the triggers refuse to change or delete a log.
+-- Logs outlive the accounts they name, an erased user's id stays in them.
+CREATE TABLE IF NOT EXISTS Audit_Logs (
+    id          BIGINT       NOT NULL AUTO_INCREMENT,
+    action      VARCHAR(50)  NOT NULL,
+    outcome     VARCHAR(10)  NOT NULL,
+    actor_id    VARCHAR(255) NOT NULL DEFAULT '',
+    actor_role  VARCHAR(20)  NOT NULL DEFAULT '',
+    target_type VARCHAR(20)  NOT NULL DEFAULT '',
+    target_id   VARCHAR(255) NOT NULL DEFAULT '',
+    detail      VARCHAR(255) NOT NULL DEFAULT '',
+    ip_address  VARCHAR(45)  NOT NULL DEFAULT '',
+    user_agent  VARCHAR(512) NOT NULL DEFAULT '',
+    status_code INT          NOT NULL,
+    created_at  VARCHAR(255) NOT NULL,
+    PRIMARY KEY (id),
+    INDEX (action),
+    INDEX (actor_id),
+    INDEX (target_id),
+    INDEX (created_at)
+);
+
+CREATE TRIGGER audit_logs_no_update BEFORE UPDATE ON Audit_Logs
+    FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit logs are append-only';
+
+CREATE TRIGGER audit_logs_no_delete BEFORE DELETE ON Audit_Logs
+    FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit logs are append-only';
@@ -0,0 +1,15 @@
+-- suspensions of user accounts by masters. An account is suspended while it has a suspension that
+-- was not reactivated, Users.account_status is SUSPENDED meanwhile.
+CREATE TABLE IF NOT EXISTS Account_Suspensions (
+    suspension_id   VARCHAR(255) NOT NULL,
+    user_id         VARCHAR(255) NOT NULL,
+    reason          VARCHAR(255) NOT NULL,
+    previous_status VARCHAR(20)  NOT NULL,
+    suspended_by    VARCHAR(255) NOT NULL,
+    suspended_at    VARCHAR(255) NOT NULL,
+    reactivated_by  VARCHAR(255) NULL,
+    reactivated_at  VARCHAR(255) NULL,
+    PRIMARY KEY (suspension_id),
+    INDEX (user_id)
+);
+
@@ -0,0 +1,11 @@
+-- codes tried for each two-factor login challenge, a challenge is given up after a few wrong codes
+-- and only logs in once. Rows are deleted once the challenge token has expired.
+CREATE TABLE IF NOT EXISTS Two_Factor_Challenges (
+    jti          VARCHAR(255) NOT NULL,
+    account_id   VARCHAR(255) NOT NULL,
+    attempts     INT          NOT NULL DEFAULT 0,
+    expires_at   VARCHAR(255) NOT NULL,
+    completed_at VARCHAR(255) NULL,
+    PRIMARY KEY (jti),
+    INDEX (expires_at)
+);
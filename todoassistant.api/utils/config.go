@@ -1,6 +1,11 @@
 package utils
 
-import "github.com/spf13/viper"
+import (
+	"net/url"
+	"strings"
+
+	"github.com/spf13/viper"
+)
 
 // Config file stores configuration of application
 
@@ -24,6 +29,61 @@ type Config struct {
 	StripeKey      string `mapstructure:"STRIPE_KEY"`
 	AWSAccess      string `mapstructure:"AWS_ACCESS_KEY_ID"`
 	AWSSecret      string `mapstructure:"AWS_SECRET_ACCESS_KEY"`
+	// AttachmentStorage is either "s3" (default) or "local"
+	AttachmentStorage  string `mapstructure:"ATTACHMENT_STORAGE"`
+	AttachmentLocalDir string `mapstructure:"ATTACHMENT_LOCAL_DIR"`
+	AttachmentBaseUrl  string `mapstructure:"ATTACHMENT_BASE_URL"`
+	// AppBaseUrl is the web app that links in emails, such as email verification, open
+	AppBaseUrl string `mapstructure:"APP_BASE_URL"`
+	// Facebook Login app, access tokens from other apps are rejected
+	FacebookAppId     string `mapstructure:"FACEBOOK_APP_ID"`
+	FacebookAppSecret string `mapstructure:"FACEBOOK_APP_SECRET"`
+	// Passkeys are scoped to WEBAUTHN_RP_ID, a domain, and accepted from the comma separated
+	// WEBAUTHN_ORIGINS, both default to the web app at APP_BASE_URL
+	WebAuthnRPId    string `mapstructure:"WEBAUTHN_RP_ID"`
+	WebAuthnOrigins string `mapstructure:"WEBAUTHN_ORIGINS"`
+	// LoginAttemptStore keeps failed logins in "mysql" (default) or "memory", memory is
+	// lost on restart and not shared between instances
+	LoginAttemptStore string `mapstructure:"LOGIN_ATTEMPT_STORE"`
+	// Passwords have to be PASSWORD_MIN_LENGTH characters long, 8 by default, and match none of the
+	// regular expressions of PASSWORD_BANNED_PATTERNS, separated by semicolons
+	PasswordMinLength      int    `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordBannedPatterns string `mapstructure:"PASSWORD_BANNED_PATTERNS"`
+}
+
+// PasskeyScope returns the domain passkeys are scoped to and the origins they are accepted from.
+func (c Config) PasskeyScope() (string, []string) {
+	rpId := c.WebAuthnRPId
+	var origins []string
+	for _, origin := range strings.Split(c.WebAuthnOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, strings.TrimSuffix(origin, "/"))
+		}
+	}
+
+	appUrl, err := url.Parse(c.AppBaseUrl)
+	if err != nil || appUrl.Host == "" {
+		return rpId, origins
+	}
+	if rpId == "" {
+		rpId = appUrl.Hostname()
+	}
+	if len(origins) == 0 {
+		origins = []string{appUrl.Scheme + "://" + appUrl.Host}
+	}
+	return rpId, origins
+}
+
+// PasswordPolicy returns the min length of passwords and the patterns they cannot match,
+// zero and none when the defaults are to be used.
+func (c Config) PasswordPolicy() (int, []string) {
+	var patterns []string
+	for _, pattern := range strings.Split(c.PasswordBannedPatterns, ";") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return c.PasswordMinLength, patterns
 }
 
 func LoadConfig(path string) (config Config, err error) {